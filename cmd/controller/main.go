@@ -38,6 +38,7 @@ func main() {
 		op.AMIProvider,
 		op.SecurityGroupProvider,
 		op.CapacityReservationProvider,
+		op.Clock,
 	)
 	cloudProvider := metrics.Decorate(awsCloudProvider)
 	clusterState := state.NewCluster(op.Clock, op.GetClient(), cloudProvider)
@@ -69,6 +70,7 @@ func main() {
 			op.ValidationCache,
 			cloudProvider,
 			op.SubnetProvider,
+			op.VPCEndpointProvider,
 			op.SecurityGroupProvider,
 			op.InstanceProfileProvider,
 			op.InstanceProvider,
@@ -78,7 +80,13 @@ func main() {
 			op.VersionProvider,
 			op.InstanceTypesProvider,
 			op.CapacityReservationProvider,
+			op.ReservedInstanceProvider,
+			op.ZoneProvider,
+			op.AccountSettingsProvider,
 			op.AMIResolver,
+			op.AMIHashStore,
+			op.InvalidationBus,
+			op.HealthTracker,
 		)...).
 		Start(ctx)
 }
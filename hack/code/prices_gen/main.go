@@ -37,6 +37,7 @@ import (
 	controllerspricing "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 )
 
@@ -109,8 +110,8 @@ func main() {
 	// record prices for each region we are interested in
 	for _, region := range getAWSRegions(opts.partition) {
 		log.Println("fetching for", region)
-		pricingProvider := pricing.NewDefaultProvider(ctx, pricing.NewAPI(cfg), ec2api, region)
-		controller := controllerspricing.NewController(pricingProvider)
+		pricingProvider := pricing.NewDefaultProvider(ctx, pricing.NewAPI(cfg), ec2api, nil, zone.NewDefaultProvider(ec2api), region)
+		controller := controllerspricing.NewController(pricingProvider, nil, nil)
 		_, err := controller.Reconcile(ctx)
 		if err != nil {
 			log.Fatalf("failed to initialize pricing provider %s", err)
@@ -38,7 +38,9 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/reservedinstance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 )
 
@@ -57,9 +59,9 @@ func main() {
 	region := "us-west-2"
 	cfg := lo.Must(config.LoadDefaultConfig(ctx, config.WithRegion(region)))
 	ec2api := ec2.NewFromConfig(cfg)
-	subnetProvider := subnet.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval))
+	subnetProvider := subnet.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.ExhaustedSubnetsTTL, awscache.UnavailableOfferingsCleanupInterval))
 	instanceTypeProvider := instancetype.NewDefaultProvider(
-		cache.New(awscache.InstanceTypesZonesAndOfferingsTTL, awscache.DefaultCleanupInterval),
+		awscache.NewLRU(awscache.InstanceTypesCacheName, awscache.InstanceTypesZonesAndOfferingsTTL, 0, 0, nil),
 		cache.New(awscache.InstanceTypesZonesAndOfferingsTTL, awscache.DefaultCleanupInterval),
 		cache.New(awscache.DiscoveredCapacityCacheTTL, awscache.DefaultCleanupInterval),
 		ec2api,
@@ -68,13 +70,18 @@ func main() {
 			ctx,
 			pricing.NewAPI(cfg),
 			ec2api,
+			nil,
+			zone.NewDefaultProvider(ec2api),
 			cfg.Region,
 		),
 		nil,
-		awscache.NewUnavailableOfferings(),
+		reservedinstance.NewDefaultProvider(ec2api),
+		awscache.NewUnavailableOfferings(nil),
 		instancetype.NewDefaultResolver(
 			region,
 		),
+		amifamily.NewHashStore(),
+		nil,
 	)
 	if err := instanceTypeProvider.UpdateInstanceTypes(ctx); err != nil {
 		log.Fatalf("updating instance types, %s", err)
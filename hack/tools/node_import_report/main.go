@@ -0,0 +1,90 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/samber/lo"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	coreoperator "sigs.k8s.io/karpenter/pkg/operator"
+
+	"github.com/aws/karpenter-provider-aws/hack/tools/node_import_report/report"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/operator"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+var clusterName string
+var outFile string
+
+func init() {
+	flag.StringVar(&clusterName, "cluster-name", "", "cluster name to use when resolving the instance types of each NodePool")
+	flag.StringVar(&outFile, "out-file", "node-import-report.csv", "file to output the generated report")
+	flag.Parse()
+}
+
+// The node import report inspects the nodes in the cluster which weren't launched by Karpenter, and reports which
+// NodePool and EC2NodeClass, if any, each of them matches. A node matches when Karpenter could have launched its instance
+// with the NodePool and EC2NodeClass. Nothing in the cluster or the account is modified.
+func main() {
+	if clusterName == "" {
+		log.Fatalf("cluster name cannot be empty")
+	}
+	restConfig := config.GetConfigOrDie()
+	kubeClient := lo.Must(client.New(restConfig, client.Options{}))
+	ctx := context.Background()
+	ctx = options.ToContext(ctx, &options.Options{ClusterName: clusterName})
+
+	file := lo.Must(os.OpenFile(outFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777))
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	defer w.Flush()
+
+	ctx, op := operator.NewOperator(ctx, &coreoperator.Operator{
+		Manager:             lo.Must(manager.New(restConfig, manager.Options{})),
+		KubernetesInterface: kubernetes.NewForConfigOrDie(restConfig),
+	})
+	lo.Must0(op.InstanceTypesProvider.UpdateInstanceTypes(ctx))
+	lo.Must0(op.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx))
+	// The cloud provider resolves EC2NodeClasses with a client which reads from the API server directly, since the
+	// manager's cache isn't started
+	cloudProvider := cloudprovider.New(
+		op.InstanceTypesProvider,
+		op.InstanceProvider,
+		op.EventRecorder,
+		kubeClient,
+		op.AMIProvider,
+		op.SecurityGroupProvider,
+		op.CapacityReservationProvider,
+		op.Clock,
+	)
+
+	r := lo.Must(report.Generate(ctx, kubeClient, cloudProvider, op.InstanceProvider, op.InstanceTypesProvider))
+	lo.Must0(w.Write(report.Header))
+	for _, row := range r.Rows {
+		lo.Must0(w.Write(row.Record()))
+	}
+	log.Printf("%d of %d nodes not launched by Karpenter match a NodePool, wrote report to %s", r.Matched, r.Nodes, outFile)
+}
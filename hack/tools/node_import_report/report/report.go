@@ -0,0 +1,215 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	nodepoolutils "sigs.k8s.io/karpenter/pkg/utils/nodepool"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// Header is the header of the report's CSV output, with a column for each of the fields of a Row
+var Header = []string{"Node", "Instance ID", "Instance Type", "Zone", "Capacity Type", "NodePool", "EC2NodeClass", "Matches", "Reasons"}
+
+// InstanceProvider describes the EC2 instances of nodes
+type InstanceProvider interface {
+	Get(context.Context, string) (*instance.Instance, error)
+}
+
+// CloudProvider resolves the instance types which Karpenter can launch with a NodePool
+type CloudProvider interface {
+	GetInstanceTypes(context.Context, *karpv1.NodePool) ([]*cloudprovider.InstanceType, error)
+}
+
+// InstanceTypeProvider resolves the instance types which an EC2NodeClass excludes, and why
+type InstanceTypeProvider interface {
+	IncompatibleInstanceTypes(context.Context, *v1.EC2NodeClass) (map[string]string, error)
+}
+
+// Row reports whether Karpenter could have launched a node's instance with a NodePool and its EC2NodeClass
+type Row struct {
+	Node         string
+	InstanceID   string
+	InstanceType string
+	Zone         string
+	CapacityType string
+	NodePool     string
+	NodeClass    string
+	Reasons      []string
+}
+
+// Matches returns whether Karpenter could have launched the node's instance with the NodePool
+func (r Row) Matches() bool {
+	return r.NodePool != "" && len(r.Reasons) == 0
+}
+
+// Record returns the row as a CSV record, in the order of the Header
+func (r Row) Record() []string {
+	return []string{r.Node, r.InstanceID, r.InstanceType, r.Zone, r.CapacityType, r.NodePool, r.NodeClass, fmt.Sprint(r.Matches()), strings.Join(r.Reasons, "; ")}
+}
+
+// Report is the result of matching the nodes which weren't launched by Karpenter against the NodePools
+type Report struct {
+	Rows []Row
+	// Nodes is the number of nodes which weren't launched by Karpenter, and Matched is the number of them which match
+	// at least one NodePool
+	Nodes   int
+	Matched int
+}
+
+// Generate matches each node in the cluster which wasn't launched by Karpenter against each NodePool, in the order
+// Karpenter considers the NodePools, and returns a row for each combination of node and NodePool. Nodes which can't be
+// matched against any NodePool have a single row with the reason.
+func Generate(ctx context.Context, kubeClient client.Client, cloudProvider CloudProvider, instanceProvider InstanceProvider,
+	instanceTypeProvider InstanceTypeProvider) (*Report, error) {
+	nodeList := &corev1.NodeList{}
+	if err := kubeClient.List(ctx, nodeList); err != nil {
+		return nil, fmt.Errorf("listing nodes, %w", err)
+	}
+	nodePoolList := &karpv1.NodePoolList{}
+	if err := kubeClient.List(ctx, nodePoolList); err != nil {
+		return nil, fmt.Errorf("listing nodepools, %w", err)
+	}
+	nodePools := lo.ToSlicePtr(nodePoolList.Items)
+	nodepoolutils.OrderByWeight(nodePools)
+
+	// Nodes launched by Karpenter are labeled with their NodePool
+	nodes := lo.Reject(lo.ToSlicePtr(nodeList.Items), func(n *corev1.Node, _ int) bool {
+		return n.Labels[karpv1.NodePoolLabelKey] != ""
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	report := &Report{Nodes: len(nodes)}
+	for _, node := range nodes {
+		rows := nodeRows(ctx, kubeClient, cloudProvider, instanceProvider, instanceTypeProvider, node, nodePools)
+		if lo.ContainsBy(rows, Row.Matches) {
+			report.Matched++
+		}
+		report.Rows = append(report.Rows, rows...)
+	}
+	return report, nil
+}
+
+func nodeRows(ctx context.Context, kubeClient client.Client, cloudProvider CloudProvider, instanceProvider InstanceProvider,
+	instanceTypeProvider InstanceTypeProvider, node *corev1.Node, nodePools []*karpv1.NodePool) []Row {
+	id, err := utils.ParseInstanceID(node.Spec.ProviderID)
+	if err != nil {
+		return []Row{{Node: node.Name, Reasons: []string{"node isn't an EC2 instance"}}}
+	}
+	inst, err := instanceProvider.Get(ctx, id)
+	if err != nil {
+		return []Row{{Node: node.Name, InstanceID: id, Reasons: []string{fmt.Sprintf("describing instance, %s", err)}}}
+	}
+	row := Row{
+		Node:         node.Name,
+		InstanceID:   id,
+		InstanceType: string(inst.Type),
+		Zone:         inst.Zone,
+		CapacityType: inst.CapacityType,
+	}
+	if len(nodePools) == 0 {
+		row.Reasons = []string{"no NodePools exist"}
+		return []Row{row}
+	}
+	return lo.Map(nodePools, func(nodePool *karpv1.NodePool, _ int) Row {
+		row := row
+		row.NodePool = nodePool.Name
+		if nodeClassRef := nodePool.Spec.Template.Spec.NodeClassRef; nodeClassRef != nil {
+			row.NodeClass = nodeClassRef.Name
+		}
+		row.Reasons = mismatches(ctx, kubeClient, cloudProvider, instanceTypeProvider, node, inst, nodePool)
+		return row
+	})
+}
+
+// mismatches returns the reasons that Karpenter couldn't have launched the node's instance with the NodePool and its
+// EC2NodeClass, or none if it could have
+func mismatches(ctx context.Context, kubeClient client.Client, cloudProvider CloudProvider, instanceTypeProvider InstanceTypeProvider,
+	node *corev1.Node, inst *instance.Instance, nodePool *karpv1.NodePool) []string {
+	if nodePool.Spec.Template.Spec.NodeClassRef == nil {
+		return []string{"NodePool doesn't reference an EC2NodeClass"}
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		return []string{fmt.Sprintf("getting EC2NodeClass, %s", err)}
+	}
+	var reasons []string
+
+	// Instance type and NodePool requirements
+	instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+	if err != nil {
+		return []string{fmt.Sprintf("resolving instance types, %s", err)}
+	}
+	if it, ok := lo.Find(instanceTypes, func(it *cloudprovider.InstanceType) bool { return it.Name == string(inst.Type) }); ok {
+		requirements := scheduling.NewRequirements(it.Requirements.Values()...)
+		requirements.Add(
+			scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, inst.Zone),
+			scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, inst.CapacityType),
+		)
+		if err := requirements.Intersects(scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)); err != nil {
+			reasons = append(reasons, fmt.Sprintf("NodePool requirements aren't satisfied, %s", err))
+		}
+	} else {
+		incompatible, err := instanceTypeProvider.IncompatibleInstanceTypes(ctx, nodeClass)
+		if reason, ok := incompatible[string(inst.Type)]; err == nil && ok {
+			reasons = append(reasons, fmt.Sprintf("instance type %s is excluded by the EC2NodeClass, %s", inst.Type, reason))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("instance type %s isn't offered by the EC2NodeClass", inst.Type))
+		}
+	}
+
+	// EC2NodeClass subnets, security groups and AMIs
+	if !lo.ContainsBy(nodeClass.Status.Subnets, func(s v1.Subnet) bool { return s.ID == inst.SubnetID }) {
+		reasons = append(reasons, fmt.Sprintf("subnet %s isn't selected by the EC2NodeClass", inst.SubnetID))
+	}
+	if securityGroups := sets.New(lo.Map(nodeClass.Status.SecurityGroups, func(sg v1.SecurityGroup, _ int) string { return sg.ID })...); !securityGroups.Equal(sets.New(inst.SecurityGroupIDs...)) {
+		reasons = append(reasons, fmt.Sprintf("security groups %s don't match the EC2NodeClass's %s", strings.Join(sets.List(sets.New(inst.SecurityGroupIDs...)), ","), strings.Join(sets.List(securityGroups), ",")))
+	}
+	if !lo.ContainsBy(nodeClass.Status.AMIs, func(ami v1.AMI) bool { return ami.ID == inst.ImageID }) {
+		reasons = append(reasons, fmt.Sprintf("AMI %s isn't selected by the EC2NodeClass", inst.ImageID))
+	}
+
+	// NodePool labels and taints, which the pods scheduled to the NodePool's nodes depend on
+	keys := lo.Keys(nodePool.Spec.Template.Labels)
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value := nodePool.Spec.Template.Labels[key]; node.Labels[key] != value {
+			reasons = append(reasons, fmt.Sprintf("label %s=%s is missing", key, value))
+		}
+	}
+	for _, taint := range nodePool.Spec.Template.Spec.Taints {
+		if !lo.ContainsBy(node.Spec.Taints, func(t corev1.Taint) bool { return t.MatchTaint(&taint) }) {
+			reasons = append(reasons, fmt.Sprintf("taint %s is missing", taint.ToString()))
+		}
+	}
+	return reasons
+}
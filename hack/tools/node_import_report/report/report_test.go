@@ -0,0 +1,261 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/aws/karpenter-provider-aws/hack/tools/node_import_report/report"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var ctx context.Context
+
+func TestReport(t *testing.T) {
+	ctx = context.Background()
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodeImportReport")
+}
+
+type fakeInstanceProvider map[string]*instance.Instance
+
+func (f fakeInstanceProvider) Get(_ context.Context, id string) (*instance.Instance, error) {
+	if inst, ok := f[id]; ok {
+		return inst, nil
+	}
+	return nil, fmt.Errorf("instance %s not found", id)
+}
+
+type fakeCloudProvider []*cloudprovider.InstanceType
+
+func (f fakeCloudProvider) GetInstanceTypes(context.Context, *karpv1.NodePool) ([]*cloudprovider.InstanceType, error) {
+	return f, nil
+}
+
+type fakeInstanceTypeProvider map[string]string
+
+func (f fakeInstanceTypeProvider) IncompatibleInstanceTypes(context.Context, *v1.EC2NodeClass) (map[string]string, error) {
+	return f, nil
+}
+
+var _ = Describe("Report", func() {
+	var node *corev1.Node
+	var nodePool *karpv1.NodePool
+	var nodeClass *v1.EC2NodeClass
+	var instances fakeInstanceProvider
+	var instanceTypes fakeCloudProvider
+	var incompatible fakeInstanceTypeProvider
+
+	BeforeEach(func() {
+		// The node matches the NodePool unless a test changes it
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"team": "a"}},
+			Spec: corev1.NodeSpec{
+				ProviderID: "aws:///test-zone-1a/i-0123456789abcdef0",
+				Taints:     []corev1.Taint{{Key: "dedicated", Value: "a", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		}
+		instances = fakeInstanceProvider{
+			"i-0123456789abcdef0": {
+				ID:               "i-0123456789abcdef0",
+				Type:             "m5.large",
+				Zone:             "test-zone-1a",
+				CapacityType:     karpv1.CapacityTypeOnDemand,
+				SubnetID:         "subnet-a",
+				SecurityGroupIDs: []string{"sg-a", "sg-b"},
+				ImageID:          "ami-a",
+			},
+		}
+		nodeClass = &v1.EC2NodeClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Status: v1.EC2NodeClassStatus{
+				Subnets:        []v1.Subnet{{ID: "subnet-a", Zone: "test-zone-1a"}},
+				SecurityGroups: []v1.SecurityGroup{{ID: "sg-a"}, {ID: "sg-b"}},
+				AMIs:           []v1.AMI{{ID: "ami-a"}},
+			},
+		}
+		nodePool = &karpv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec: karpv1.NodePoolSpec{
+				Template: karpv1.NodeClaimTemplate{
+					ObjectMeta: karpv1.ObjectMeta{Labels: map[string]string{"team": "a"}},
+					Spec: karpv1.NodeClaimTemplateSpec{
+						NodeClassRef: &karpv1.NodeClassReference{Group: "karpenter.k8s.aws", Kind: "EC2NodeClass", Name: "default"},
+						Requirements: []karpv1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+							Key:      karpv1.CapacityTypeLabelKey,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{karpv1.CapacityTypeOnDemand},
+						}}},
+						Taints: []corev1.Taint{{Key: "dedicated", Value: "a", Effect: corev1.TaintEffectNoSchedule}},
+					},
+				},
+			},
+		}
+		instanceTypes = fakeCloudProvider{{
+			Name: "m5.large",
+			Requirements: scheduling.NewRequirements(
+				scheduling.NewRequirement(corev1.LabelInstanceTypeStable, corev1.NodeSelectorOpIn, "m5.large"),
+			),
+		}}
+		incompatible = fakeInstanceTypeProvider{}
+	})
+
+	generate := func(objects ...client.Object) *report.Report {
+		kubeClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+		r, err := report.Generate(ctx, kubeClient, instanceTypes, instances, incompatible)
+		Expect(err).ToNot(HaveOccurred())
+		return r
+	}
+
+	DescribeTable("should report why a node doesn't match a NodePool",
+		func(mutate func(), reasons ...string) {
+			mutate()
+			r := generate(node, nodePool, nodeClass)
+			Expect(r.Rows).To(HaveLen(1))
+			Expect(r.Rows[0].NodePool).To(Equal("default"))
+			if len(reasons) == 0 {
+				Expect(r.Rows[0].Reasons).To(BeEmpty())
+			} else {
+				Expect(r.Rows[0].Reasons).To(Equal(reasons))
+			}
+			Expect(r.Rows[0].Matches()).To(Equal(len(reasons) == 0))
+			Expect(r.Matched).To(Equal(lo.Ternary(len(reasons) == 0, 1, 0)))
+		},
+		Entry("when it matches", func() {}),
+		Entry("when the NodePool doesn't reference an EC2NodeClass", func() {
+			nodePool.Spec.Template.Spec.NodeClassRef = nil
+		}, "NodePool doesn't reference an EC2NodeClass"),
+		Entry("when the instance type isn't offered", func() {
+			instances["i-0123456789abcdef0"].Type = "c5.large"
+		}, "instance type c5.large isn't offered by the EC2NodeClass"),
+		Entry("when the instance type is excluded by the EC2NodeClass", func() {
+			instances["i-0123456789abcdef0"].Type = "c5.large"
+			incompatible["c5.large"] = "not supported by the AMI family"
+		}, "instance type c5.large is excluded by the EC2NodeClass, not supported by the AMI family"),
+		Entry("when the capacity type doesn't satisfy the NodePool's requirements", func() {
+			instances["i-0123456789abcdef0"].CapacityType = karpv1.CapacityTypeSpot
+		}, `NodePool requirements aren't satisfied, key karpenter.sh/capacity-type, karpenter.sh/capacity-type In [on-demand] not in karpenter.sh/capacity-type In [spot]`),
+		Entry("when the subnet isn't selected", func() {
+			instances["i-0123456789abcdef0"].SubnetID = "subnet-b"
+		}, "subnet subnet-b isn't selected by the EC2NodeClass"),
+		Entry("when the security groups don't match", func() {
+			instances["i-0123456789abcdef0"].SecurityGroupIDs = []string{"sg-a"}
+		}, "security groups sg-a don't match the EC2NodeClass's sg-a,sg-b"),
+		Entry("when the AMI isn't selected", func() {
+			instances["i-0123456789abcdef0"].ImageID = "ami-b"
+		}, "AMI ami-b isn't selected by the EC2NodeClass"),
+		Entry("when a label is missing", func() {
+			node.Labels = nil
+		}, "label team=a is missing"),
+		Entry("when a taint is missing", func() {
+			node.Spec.Taints = nil
+		}, "taint dedicated=a:NoSchedule is missing"),
+		Entry("with each reason it doesn't match", func() {
+			instances["i-0123456789abcdef0"].SubnetID = "subnet-b"
+			instances["i-0123456789abcdef0"].ImageID = "ami-b"
+			node.Labels = nil
+		}, "subnet subnet-b isn't selected by the EC2NodeClass", "AMI ami-b isn't selected by the EC2NodeClass", "label team=a is missing"),
+	)
+	It("should report when the EC2NodeClass doesn't exist", func() {
+		r := generate(node, nodePool)
+		Expect(r.Rows).To(HaveLen(1))
+		Expect(r.Rows[0].Reasons).To(HaveLen(1))
+		Expect(r.Rows[0].Reasons[0]).To(HavePrefix("getting EC2NodeClass"))
+	})
+	It("should report when the node isn't an EC2 instance", func() {
+		node.Spec.ProviderID = "kind://docker/kind/node-a"
+		r := generate(node, nodePool, nodeClass)
+		Expect(r.Rows).To(Equal([]report.Row{{Node: "node-a", Reasons: []string{"node isn't an EC2 instance"}}}))
+		Expect(r.Matched).To(Equal(0))
+	})
+	It("should report when the instance can't be described", func() {
+		delete(instances, "i-0123456789abcdef0")
+		r := generate(node, nodePool, nodeClass)
+		Expect(r.Rows).To(Equal([]report.Row{{
+			Node:       "node-a",
+			InstanceID: "i-0123456789abcdef0",
+			Reasons:    []string{"describing instance, instance i-0123456789abcdef0 not found"},
+		}}))
+	})
+	It("should report when no NodePools exist", func() {
+		r := generate(node, nodeClass)
+		Expect(r.Rows).To(HaveLen(1))
+		Expect(r.Rows[0].NodePool).To(BeEmpty())
+		Expect(r.Rows[0].Reasons).To(Equal([]string{"no NodePools exist"}))
+		Expect(r.Rows[0].Matches()).To(BeFalse())
+	})
+	It("should skip nodes launched by Karpenter", func() {
+		node.Labels[karpv1.NodePoolLabelKey] = "default"
+		r := generate(node, nodePool, nodeClass)
+		Expect(r.Rows).To(BeEmpty())
+		Expect(r.Nodes).To(Equal(0))
+	})
+	It("should report a row for each NodePool in weight order and count the node once", func() {
+		heavy := nodePool.DeepCopy()
+		heavy.Name = "heavy"
+		heavy.Spec.Weight = lo.ToPtr[int32](10)
+		heavy.Spec.Template.Labels = map[string]string{"team": "b"}
+		r := generate(node, nodePool, heavy, nodeClass)
+		Expect(lo.Map(r.Rows, func(row report.Row, _ int) string { return row.NodePool })).To(Equal([]string{"heavy", "default"}))
+		Expect(r.Rows[0].Reasons).To(Equal([]string{"label team=b is missing"}))
+		Expect(r.Rows[1].Matches()).To(BeTrue())
+		Expect(r.Nodes).To(Equal(1))
+		Expect(r.Matched).To(Equal(1))
+	})
+	It("should report nodes in name order", func() {
+		other := node.DeepCopy()
+		other.Name = "node-0"
+		other.Spec.ProviderID = "aws:///test-zone-1a/i-0123456789abcdef1"
+		instances["i-0123456789abcdef1"] = &instance.Instance{ID: "i-0123456789abcdef1", Type: ec2types.InstanceTypeM5Large}
+		r := generate(node, other, nodePool, nodeClass)
+		Expect(lo.Map(r.Rows, func(row report.Row, _ int) string { return row.Node })).To(Equal([]string{"node-0", "node-a"}))
+		Expect(r.Nodes).To(Equal(2))
+		Expect(r.Matched).To(Equal(1))
+	})
+	It("should format a row as a CSV record", func() {
+		row := report.Row{
+			Node:         "node-a",
+			InstanceID:   "i-0123456789abcdef0",
+			InstanceType: "m5.large",
+			Zone:         "test-zone-1a",
+			CapacityType: karpv1.CapacityTypeOnDemand,
+			NodePool:     "default",
+			NodeClass:    "default",
+			Reasons:      []string{"label team=a is missing", "taint dedicated=a:NoSchedule is missing"},
+		}
+		Expect(row.Record()).To(HaveLen(len(report.Header)))
+		Expect(row.Record()).To(Equal([]string{
+			"node-a", "i-0123456789abcdef0", "m5.large", "test-zone-1a", "on-demand", "default", "default", "false",
+			"label team=a is missing; taint dedicated=a:NoSchedule is missing",
+		}))
+	})
+})
@@ -28,12 +28,15 @@ var (
 	CompatibilityGroup = "compatibility." + Group
 	//go:embed crds/karpenter.k8s.aws_ec2nodeclasses.yaml
 	EC2NodeClassCRD []byte
+	//go:embed crds/karpenter.k8s.aws_nodeaccessrequests.yaml
+	NodeAccessRequestCRD []byte
 	//go:embed crds/karpenter.sh_nodepools.yaml
 	NodePoolCRD []byte
 	//go:embed crds/karpenter.sh_nodeclaims.yaml
 	NodeClaimCRD []byte
 	CRDs         = []*apiextensionsv1.CustomResourceDefinition{
 		object.Unmarshal[apiextensionsv1.CustomResourceDefinition](EC2NodeClassCRD),
+		object.Unmarshal[apiextensionsv1.CustomResourceDefinition](NodeAccessRequestCRD),
 		object.Unmarshal[apiextensionsv1.CustomResourceDefinition](NodeClaimCRD),
 		object.Unmarshal[apiextensionsv1.CustomResourceDefinition](NodePoolCRD),
 	}
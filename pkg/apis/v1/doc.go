@@ -33,6 +33,8 @@ func init() {
 	scheme.Scheme.AddKnownTypes(gv,
 		&EC2NodeClass{},
 		&EC2NodeClassList{},
+		&NodeAccessRequest{},
+		&NodeAccessRequestList{},
 	)
 
 	cloudprovider.ReservationIDLabel = LabelCapacityReservationID
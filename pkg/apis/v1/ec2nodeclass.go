@@ -18,9 +18,13 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/hashstructure/v2"
+	"github.com/robfig/cron/v3"
 	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -30,19 +34,36 @@ import (
 type EC2NodeClassSpec struct {
 	// SubnetSelectorTerms is a list of subnet selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="subnetSelectorTerms cannot be empty",rule="self.size() != 0"
-	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
-	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in a subnet selector term",rule="!self.all(x, has(x.id) && has(x.tags))"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'cidr']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.cidr))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in a subnet selector term",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.cidr)))"
 	// +kubebuilder:validation:MaxItems:=30
 	// +required
 	SubnetSelectorTerms []SubnetSelectorTerm `json:"subnetSelectorTerms" hash:"ignore"`
+	// SubnetExclusionTerms is a list of terms which exclude subnets that are selected by subnetSelectorTerms. The terms
+	// are ORed.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'cidr']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.cidr))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	SubnetExclusionTerms []SubnetExclusionTerm `json:"subnetExclusionTerms,omitempty" hash:"ignore"`
+	// MinSubnetAvailableIPAddresses is the number of available IP addresses below which a subnet isn't launched into.
+	// Nodes are launched into the subnet with the most available IP addresses in each zone, so a zone is only avoided
+	// when all of its subnets are below the minimum. Defaults to 0.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	MinSubnetAvailableIPAddresses *int32 `json:"minSubnetAvailableIPAddresses,omitempty" hash:"ignore"`
 	// SecurityGroupSelectorTerms is a list of security group selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="securityGroupSelectorTerms cannot be empty",rule="self.size() != 0"
 	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name))"
-	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in a security group selector term",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.name)))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in a security group selector term",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.name) || has(x.vpcID)))"
 	// +kubebuilder:validation:XValidation:message="'name' is mutually exclusive, cannot be set with a combination of other fields in a security group selector term",rule="!self.all(x, has(x.name) && (has(x.tags) || has(x.id)))"
 	// +kubebuilder:validation:MaxItems:=30
 	// +required
 	SecurityGroupSelectorTerms []SecurityGroupSelectorTerm `json:"securityGroupSelectorTerms" hash:"ignore"`
+	// SecurityGroupDriftPolicy determines how nodes are handled when the security groups attached to their primary
+	// network interface differ from the resolved security groups. Replace marks the nodes as drifted, while Reconcile
+	// attaches the resolved security groups to the network interface in place. Defaults to Replace.
+	// +optional
+	SecurityGroupDriftPolicy *SecurityGroupDriftPolicy `json:"securityGroupDriftPolicy,omitempty" hash:"ignore"`
 	// CapacityReservationSelectorTerms is a list of capacity reservation selector terms. Each term is ORed together to
 	// determine the set of eligible capacity reservations.
 	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
@@ -53,29 +74,147 @@ type EC2NodeClassSpec struct {
 	// AssociatePublicIPAddress controls if public IP addresses are assigned to instances that are launched with the nodeclass.
 	// +optional
 	AssociatePublicIPAddress *bool `json:"associatePublicIPAddress,omitempty"`
+	// IPv6 assigns IPv6 addresses and prefixes to the primary network interface of nodes at launch, so that they're
+	// available before pods start rather than being assigned by the CNI.
+	// +optional
+	IPv6 *IPv6 `json:"ipv6,omitempty"`
+	// SourceDestCheck controls if source/destination checking is enabled on the network interfaces of nodes. Disabling
+	// it allows nodes to forward traffic that they're neither the source nor the destination of, such as for NAT instances,
+	// routers or CNI overlays. It's disabled by Karpenter once the instance is launched. Defaults to true.
+	// +optional
+	SourceDestCheck *bool `json:"sourceDestCheck,omitempty"`
+	// NetworkInterfaces are additional network interfaces which are attached to nodes along with their primary network
+	// interface, such as for workloads which need a dedicated data plane interface. Nodes are only launched into the
+	// zones where every network interface has a subnet.
+	// +kubebuilder:validation:XValidation:message="deviceIndex must be unique",rule="self.all(x, self.exists_one(y, y.deviceIndex == x.deviceIndex))"
+	// +kubebuilder:validation:XValidation:message="network interfaces must not overlap in their device indexes",rule="self.all(x, self.all(y, x == y || x.deviceIndex + (has(x.count) ? x.count : 1) <= y.deviceIndex || y.deviceIndex + (has(y.count) ? y.count : 1) <= x.deviceIndex))"
+	// +kubebuilder:validation:MaxItems:=8
+	// +optional
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
+	// EFA configures the Elastic Fabric Adapter (EFA) interfaces that are attached to nodes which are launched for pods
+	// requesting the vpc.amazonaws.com/efa resource. By default, every EFA interface the instance type supports is attached.
+	// +optional
+	EFA *EFA `json:"efa,omitempty"`
+	// ElasticIP associates an Elastic IP address with each node once it's launched, for workloads that need a stable
+	// public egress address per node. Addresses are selected from a pool of existing addresses, or are allocated for each
+	// node and released once it's terminated.
+	// +optional
+	ElasticIP *ElasticIP `json:"elasticIP,omitempty" hash:"ignore"`
 	// AMISelectorTerms is a list of or ami selector terms. The terms are ORed.
-	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name', 'alias']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name) || has(x.alias))"
-	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.exists(x, has(x.id) && (has(x.alias) || has(x.tags) || has(x.name) || has(x.owner)))"
-	// +kubebuilder:validation:XValidation:message="'alias' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.exists(x, has(x.alias) && (has(x.id) || has(x.tags) || has(x.name) || has(x.owner)))"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name', 'namePattern', 'alias', 'imageBuilderARN', 'ssmParameter', 'ssmParameterPath']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name) || has(x.namePattern) || has(x.alias) || has(x.imageBuilderARN) || has(x.ssmParameter) || has(x.ssmParameterPath))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.exists(x, has(x.id) && (has(x.alias) || has(x.tags) || has(x.name) || has(x.namePattern) || has(x.owner) || has(x.newestCount) || has(x.minAge) || has(x.maxAge) || has(x.imageBuilderARN) || has(x.ssmParameter) || has(x.ssmParameterPath)))"
+	// +kubebuilder:validation:XValidation:message="'alias' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.exists(x, has(x.alias) && (has(x.id) || has(x.tags) || has(x.name) || has(x.namePattern) || has(x.owner) || has(x.newestCount) || has(x.minAge) || has(x.maxAge) || has(x.imageBuilderARN) || has(x.ssmParameter) || has(x.ssmParameterPath)))"
+	// +kubebuilder:validation:XValidation:message="'imageBuilderARN' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.exists(x, has(x.imageBuilderARN) && (has(x.id) || has(x.alias) || has(x.tags) || has(x.name) || has(x.namePattern) || has(x.owner) || has(x.newestCount) || has(x.minAge) || has(x.maxAge) || has(x.ssmParameter) || has(x.ssmParameterPath)))"
+	// +kubebuilder:validation:XValidation:message="'ssmParameter' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.exists(x, has(x.ssmParameter) && (has(x.id) || has(x.alias) || has(x.tags) || has(x.name) || has(x.namePattern) || has(x.owner) || has(x.newestCount) || has(x.minAge) || has(x.maxAge) || has(x.imageBuilderARN) || has(x.ssmParameterPath)))"
+	// +kubebuilder:validation:XValidation:message="'ssmParameterPath' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.exists(x, has(x.ssmParameterPath) && (has(x.id) || has(x.alias) || has(x.tags) || has(x.name) || has(x.namePattern) || has(x.owner) || has(x.newestCount) || has(x.minAge) || has(x.maxAge) || has(x.imageBuilderARN) || has(x.ssmParameter)))"
 	// +kubebuilder:validation:XValidation:message="'alias' is mutually exclusive, cannot be set with a combination of other amiSelectorTerms",rule="!(self.exists(x, has(x.alias)) && self.size() != 1)"
 	// +kubebuilder:validation:MinItems:=1
 	// +kubebuilder:validation:MaxItems:=30
 	// +required
 	AMISelectorTerms []AMISelectorTerm `json:"amiSelectorTerms" hash:"ignore"`
+	// AMIExclusionTerms is a list of terms which exclude AMIs that are selected by amiSelectorTerms. The terms are ORed.
+	// Excluded AMIs are never selected, so the newest AMI that isn't excluded is selected in their place.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name', 'namePattern']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name) || has(x.namePattern))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	AMIExclusionTerms []AMIExclusionTerm `json:"amiExclusionTerms,omitempty" hash:"ignore"`
 	// AMIFamily dictates the UserData format and default BlockDeviceMappings used when generating launch templates.
 	// This field is optional when using an alias amiSelectorTerm, and the value will be inferred from the alias'
 	// family. When an alias is specified, this field may only be set to its corresponding family or 'Custom'. If no
 	// alias is specified, this field is required.
 	// NOTE: We ignore the AMIFamily for hashing here because we hash the AMIFamily dynamically by using the alias using
 	// the AMIFamily() helper function
-	// +kubebuilder:validation:Enum:={AL2,AL2023,Bottlerocket,Custom,Windows2019,Windows2022}
+	// +kubebuilder:validation:Enum:={AL2,AL2023,Bottlerocket,Custom,Flatcar,Talos,Ubuntu,Windows2019,Windows2022}
 	// +optional
 	AMIFamily *string `json:"amiFamily,omitempty" hash:"ignore"`
+	// AMIVariants are the variants of the EKS optimized AMIs that are selected by an alias amiSelectorTerm. The standard
+	// variant is launched on instances without accelerators, the nvidia and nvidia-open variants, with NVIDIA's
+	// proprietary and open source kernel modules respectively, on instances with NVIDIA GPUs, and the neuron variant on
+	// instances with AWS Neuron accelerators. This can be used to pin the driver stack of a NodeClass's nodes, or to
+	// avoid launching accelerated AMIs altogether. Defaults to every variant other than nvidia-open. Variants that an
+	// AMI family doesn't publish are ignored.
+	// +kubebuilder:validation:XValidation:message="amiVariants must not contain both nvidia and nvidia-open",rule="!('nvidia' in self && 'nvidia-open' in self)"
+	// +kubebuilder:validation:MinItems:=1
+	// +kubebuilder:validation:MaxItems:=4
+	// +listType=set
+	// +optional
+	AMIVariants []AMIVariant `json:"amiVariants,omitempty" hash:"ignore"`
+	// FIPS selects the FIPS-enabled AMIs published for an alias amiSelectorTerm, whose kernel and cryptographic
+	// modules run in FIPS mode, in place of the regular ones. Only the AL2023 and Bottlerocket families publish FIPS
+	// AMIs, and only of their standard variant. The EC2NodeClass isn't ready if no FIPS AMI is found. Defaults to false.
+	// +optional
+	FIPS *bool `json:"fips,omitempty" hash:"ignore"`
 	// UserData to be applied to the provisioned nodes.
 	// It must be in the appropriate format based on the AMIFamily in use. Karpenter will merge certain fields into
 	// this UserData to ensure nodes are being provisioned with the correct configuration.
 	// +optional
 	UserData *string `json:"userData,omitempty"`
+	// TemplatedUserData renders userData as a Go template each time a launch template is generated, with the fields
+	// .ClusterName, .ClusterEndpoint, .NodeClass, .NodePool, .CapacityType, .Labels, the labels of the NodeClaim, and
+	// .InstanceTypes, the names of the instance types the launch template may be launched with. Defaults to false.
+	// +optional
+	TemplatedUserData *bool `json:"templatedUserData,omitempty"`
+	// UserDataDriftPolicy determines which changes to userData drift nodes. With the Semantic policy, changes that don't
+	// change what the node runs, such as comments, blank lines and trailing whitespace, the order of keys in TOML and
+	// NodeConfig documents, and the order of headers and boundaries in MIME multi-part documents, don't drift nodes.
+	// With the Strict policy, any change to userData drifts nodes. Defaults to Semantic.
+	// +kubebuilder:validation:Enum:={Semantic,Strict}
+	// +optional
+	UserDataDriftPolicy *UserDataDriftPolicy `json:"userDataDriftPolicy,omitempty" hash:"ignore"`
+	// UserDataMergeOrder determines whether the parts of userData run before or after the bootstrap script which
+	// Karpenter generates for the AL2 and Ubuntu AMI families, which starts the kubelet. A part of a MIME multi-part
+	// userData can override it with the X-Karpenter-Merge-Order header. Defaults to BeforeBootstrap.
+	// +kubebuilder:validation:Enum:={BeforeBootstrap,AfterBootstrap}
+	// +optional
+	UserDataMergeOrder *UserDataMergeOrder `json:"userDataMergeOrder,omitempty"`
+	// BootstrapMode determines how the Custom AMI family bootstraps the node. UserData, the default, launches the node
+	// with userData alone. Nodeadm generates a nodeadm NodeConfig, as for the AL2023 AMI family, for custom or hybrid
+	// images which have nodeadm installed, and merges userData with it.
+	// +kubebuilder:validation:Enum:={UserData,Nodeadm}
+	// +optional
+	BootstrapMode *BootstrapMode `json:"bootstrapMode,omitempty"`
+	// DeviceValidation checks that the instance's devices came up before the node bootstraps, and stops the instance if
+	// they didn't so that it's replaced rather than joining the cluster without them. It's supported by the AL2, AL2023
+	// and Ubuntu AMI families.
+	// +optional
+	DeviceValidation *DeviceValidation `json:"deviceValidation,omitempty"`
+	// BottlerocketSettings are merged into the settings table of the generated Bottlerocket UserData, e.g. kernel sysctls,
+	// container registry mirrors, or host-containers. Settings are written with the same structure as the TOML, and must
+	// not conflict with settings in userData or with the settings that Karpenter generates for the node. Kubelet
+	// settings that can be configured with the kubelet field must be configured there.
+	// +optional
+	BottlerocketSettings *BottlerocketSettings `json:"bottlerocketSettings,omitempty" hash:"string"`
+	// NodeConfig is merged into the nodeadm NodeConfig that Karpenter generates for AL2023 nodes, and nodes of the Custom
+	// AMI family with the Nodeadm bootstrapMode. It's applied after any NodeConfig in userData, so its settings take
+	// precedence.
+	// +optional
+	NodeConfig *NodeConfig `json:"nodeConfig,omitempty"`
+	// KubeletConfigDropIns are partial kubelet configurations which are merged over the kubelet's configuration in the
+	// order they're listed. They're written as drop-in files (https://kubernetes.io/docs/tasks/administer-cluster/kubelet-config-file/#kubelet-conf-d)
+	// on AL2023 nodes, and rendered as the equivalent settings on Bottlerocket nodes.
+	// +kubebuilder:validation:XValidation:message="kubeletConfigDropIns must not contain duplicate names",rule="self.all(x, self.exists_one(y, x.name == y.name))"
+	// +kubebuilder:validation:MaxItems:=10
+	// +optional
+	KubeletConfigDropIns []KubeletConfigDropIn `json:"kubeletConfigDropIns,omitempty"`
+	// RegistryMirrors configure the node's container runtime to pull images from mirrors of the listed registries, e.g.
+	// in clusters without internet access. They're supported by the AL2, AL2023, Bottlerocket, and Windows AMI families.
+	// +kubebuilder:validation:XValidation:message="registryMirrors must not contain duplicate registries",rule="self.all(x, self.exists_one(y, x.registry == y.registry))"
+	// +kubebuilder:validation:MaxItems:=20
+	// +optional
+	RegistryMirrors []RegistryMirror `json:"registryMirrors,omitempty"`
+	// Proxy configures the node to reach the network through an HTTP proxy, e.g. in clusters whose egress is only
+	// allowed through a proxy. It's supported by the AL2, AL2023, Bottlerocket, and Windows AMI families.
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+	// TrustedCABundle adds certificate authorities to the trust stores of the node's OS and container runtime, e.g. the
+	// certificate authority of a TLS-intercepting proxy. It's supported by the AL2, AL2023, Bottlerocket, and Windows AMI
+	// families.
+	// +optional
+	TrustedCABundle *TrustedCABundle `json:"trustedCABundle,omitempty"`
+	// Swap provisions swap space on the node and configures the kubelet to run with it. It's supported by the AL2023 AMI
+	// family, and the Custom AMI family with the Nodeadm bootstrapMode.
+	// +optional
+	Swap *Swap `json:"swap,omitempty"`
 	// Role is the AWS identity that nodes use. This field is immutable.
 	// This field is mutually exclusive from instanceProfile.
 	// Marking this field as immutable avoids concerns around terminating managed instance profiles from running instances.
@@ -99,8 +238,25 @@ type EC2NodeClassSpec struct {
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodepool",rule="self.all(k, k != 'karpenter.sh/nodepool')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodeclaim",rule="self.all(k, k !='karpenter.sh/nodeclaim')"
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.k8s.aws/ec2nodeclass",rule="self.all(k, k !='karpenter.k8s.aws/ec2nodeclass')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.k8s.aws/node-access",rule="self.all(k, !k.startsWith('karpenter.k8s.aws/node-access'))"
 	// +optional
 	Tags map[string]string `json:"tags,omitempty"`
+	// NameTagTemplate is a Go template for the Name tag of launched instances. The template is rendered once the
+	// instance's node has registered, with the fields .NodeName, .NodeClaim, .NodePool, .NodeClass, .Zone, .ZoneID,
+	// .InstanceType, .CapacityType, and .InstanceID. If not set, instances are named after their node.
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:MaxLength:=1024
+	// +optional
+	NameTagTemplate *string `json:"nameTagTemplate,omitempty" hash:"ignore"`
+	// Labels are applied to all nodes launched with this EC2NodeClass, along with the labels defined by the NodePool.
+	// A NodePool which uses this EC2NodeClass can't require a different value for any of these labels.
+	// +kubebuilder:validation:XValidation:message="label domain \"kubernetes.io\" is restricted",rule="self.all(k, k.find('^([^/]+)').endsWith('node.kubernetes.io') || k.find('^([^/]+)').endsWith('node-restriction.kubernetes.io') || !k.find('^([^/]+)').endsWith('kubernetes.io'))"
+	// +kubebuilder:validation:XValidation:message="label domain \"k8s.io\" is restricted",rule="self.all(k, !k.find('^([^/]+)').endsWith('k8s.io'))"
+	// +kubebuilder:validation:XValidation:message="label domain \"karpenter.sh\" is restricted",rule="self.all(k, !k.find('^([^/]+)').endsWith('karpenter.sh'))"
+	// +kubebuilder:validation:XValidation:message="label domain \"karpenter.k8s.aws\" is restricted",rule="self.all(k, !k.find('^([^/]+)').endsWith('karpenter.k8s.aws'))"
+	// +kubebuilder:validation:MaxProperties:=100
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
 	// Kubelet defines args to be used when configuring kubelet on provisioned nodes.
 	// They are a subset of the upstream types, recognizing not all options may be supported.
 	// Wherever possible, the types and names should reflect the upstream kubelet types.
@@ -120,6 +276,12 @@ type EC2NodeClassSpec struct {
 	// DetailedMonitoring controls if detailed monitoring is enabled for instances that are launched
 	// +optional
 	DetailedMonitoring *bool `json:"detailedMonitoring,omitempty"`
+	// SerialConsole controls if the EC2 serial console connection information for launched instances is published as a
+	// NodeClaim annotation, for debugging instances which fail to register. Serial console access is an account-level
+	// setting which must be enabled separately.
+	// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/configure-access-to-serial-console.html
+	// +optional
+	SerialConsole *bool `json:"serialConsole,omitempty" hash:"ignore"`
 	// MetadataOptions for the generated launch template of provisioned nodes.
 	//
 	// This specifies the exposure of the Instance Metadata Service to
@@ -131,12 +293,52 @@ type EC2NodeClassSpec struct {
 	// Refer to recommended, security best practices
 	// (https://aws.github.io/aws-eks-best-practices/security/docs/iam/#restrict-access-to-the-instance-profile-assigned-to-the-worker-node)
 	// for limiting exposure of Instance Metadata and User Data to pods.
-	// If omitted, defaults to httpEndpoint enabled, with httpProtocolIPv6
+	// If omitted, the account's instance metadata defaults are used, and
+	// otherwise defaults to httpEndpoint enabled, with httpProtocolIPv6
 	// disabled, with httpPutResponseLimit of 1, and with httpTokens
 	// required.
-	// +kubebuilder:default={"httpEndpoint":"enabled","httpProtocolIPv6":"disabled","httpPutResponseHopLimit":1,"httpTokens":"required"}
 	// +optional
 	MetadataOptions *MetadataOptions `json:"metadataOptions,omitempty"`
+	// PrivateDNSNameOptions for the generated launch template of provisioned nodes. These determine the hostname of the
+	// instance, and so the name of its node.
+	// +optional
+	PrivateDNSNameOptions *PrivateDNSNameOptions `json:"privateDnsNameOptions,omitempty"`
+	// CPUOptions for the generated launch template of provisioned nodes.
+	// +optional
+	CPUOptions *CPUOptions `json:"cpuOptions,omitempty"`
+	// ExtendedResources are added to the capacity of the instance types they select, e.g. the devices of a third-party
+	// device plugin which Karpenter doesn't know the instance types have. When several entries select an instance type,
+	// the quantities of later entries take precedence.
+	// +kubebuilder:validation:MaxItems:=20
+	// +optional
+	ExtendedResources []ExtendedResource `json:"extendedResources,omitempty" hash:"ignore"`
+	// BootOptions require provisioned nodes to boot with UEFI and NitroTPM, and register the measurements that their
+	// boot is attested against.
+	// +optional
+	BootOptions *BootOptions `json:"bootOptions,omitempty"`
+	// FleetOptions configure the allocation strategies which EC2 Fleet uses to choose an instance type and zone from
+	// the offerings that a NodeClaim can be launched with.
+	// +optional
+	FleetOptions *FleetOptions `json:"fleetOptions,omitempty" hash:"ignore"`
+	// AMIRollout gradually moves launches onto newly resolved AMIs. When the AMIs resolved by the amiSelectorTerms
+	// change, a share of new nodes continues to be launched with the previously resolved AMIs until the rollout
+	// completes. If not set, all new nodes are launched with the newly resolved AMIs immediately.
+	// +optional
+	AMIRollout *AMIRollout `json:"amiRollout,omitempty" hash:"ignore"`
+	// AMIMaintenanceWindows restrict when newly released AMIs are adopted. Outside of the windows, nodes continue to be
+	// launched with the AMIs that were last resolved while a window was open, and nodes aren't drifted because their
+	// AMI changed. Changes to the EC2NodeClass's spec are adopted immediately. If not set, newly released AMIs are
+	// adopted as soon as they're resolved.
+	// +kubebuilder:validation:MaxItems:=10
+	// +optional
+	AMIMaintenanceWindows []MaintenanceWindow `json:"amiMaintenanceWindows,omitempty" hash:"ignore"`
+	// AMIDeprecationThreshold excludes AMIs which are deprecated, or will be deprecated within the threshold, from the
+	// AMIs selected by the amiSelectorTerms. A threshold of 0s only excludes AMIs which are already deprecated. If not set,
+	// deprecated AMIs are still selected when no other AMI matches, and are reported by the AMIsDeprecated condition.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	// +kubebuilder:validation:Type="string"
+	// +optional
+	AMIDeprecationThreshold *metav1.Duration `json:"amiDeprecationThreshold,omitempty" hash:"ignore"`
 	// Context is a Reserved field in EC2 APIs
 	// https://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateFleet.html
 	// +optional
@@ -145,6 +347,7 @@ type EC2NodeClassSpec struct {
 
 // SubnetSelectorTerm defines selection logic for a subnet used by Karpenter to launch nodes.
 // If multiple fields are used for selection, the requirements are ANDed.
+// +kubebuilder:validation:XValidation:message="cidrMatch may only be set along with cidr",rule="!has(self.cidrMatch) || has(self.cidr)"
 type SubnetSelectorTerm struct {
 	// Tags is a map of key/value tags used to select subnets
 	// Specifying '*' for a value selects all values for a given tag key.
@@ -156,8 +359,134 @@ type SubnetSelectorTerm struct {
 	// +kubebuilder:validation:Pattern="subnet-[0-9a-z]+"
 	// +optional
 	ID string `json:"id,omitempty"`
+	// CIDR is an IPv4 or IPv6 CIDR block used to select subnets, e.g. "10.42.0.0/16".
+	// +kubebuilder:validation:Pattern=`^(([0-9]{1,3}\.){3}[0-9]{1,3}/([0-9]|[12][0-9]|3[0-2])|[0-9a-fA-F:]+/([0-9]|[1-9][0-9]|1[01][0-9]|12[0-8]))$`
+	// +kubebuilder:validation:MaxLength=43
+	// +optional
+	CIDR string `json:"cidr,omitempty"`
+	// CIDRMatch determines how the cidr selects subnets. Exact, the default, selects the subnets whose CIDR block is
+	// the cidr. Contains selects the subnets whose CIDR blocks are contained in the cidr, such as the subnets of a VPC's
+	// secondary CIDR block.
+	// +kubebuilder:validation:Enum:={Exact,Contains}
+	// +optional
+	CIDRMatch *CIDRMatch `json:"cidrMatch,omitempty"`
+}
+
+// SubnetExclusionTerm defines subnets that are excluded from the subnets selected by subnetSelectorTerms.
+// If multiple fields are used for exclusion, the requirements are ANDed.
+type SubnetExclusionTerm struct {
+	// Tags is a map of key/value tags which the subnet must have.
+	// Specifying '*' for a value matches all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// ID is the subnet id in EC2
+	// +kubebuilder:validation:Pattern="subnet-[0-9a-z]+"
+	// +optional
+	ID string `json:"id,omitempty"`
+	// CIDR is an IPv4 or IPv6 CIDR block which contains one of the subnet's CIDR blocks, e.g. "100.64.0.0/16".
+	// +kubebuilder:validation:Pattern=`^(([0-9]{1,3}\.){3}[0-9]{1,3}/([0-9]|[12][0-9]|3[0-2])|[0-9a-fA-F:]+/([0-9]|[1-9][0-9]|1[01][0-9]|12[0-8]))$`
+	// +kubebuilder:validation:MaxLength=43
+	// +optional
+	CIDR string `json:"cidr,omitempty"`
+}
+
+// NetworkInterface defines additional network interfaces which are attached to nodes
+type NetworkInterface struct {
+	// DeviceIndex is the device index of the network interface, or of the first network interface when count is more
+	// than one. The primary network interface has device index 0.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=31
+	// +required
+	DeviceIndex int32 `json:"deviceIndex"`
+	// Count is the number of network interfaces, which are attached at consecutive device indexes. Defaults to 1.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=31
+	// +optional
+	Count *int32 `json:"count,omitempty"`
+	// SubnetSelectorTerms is a list of subnet selector terms which select the subnets of the network interfaces. The
+	// terms are ORed. The network interfaces are created in a selected subnet in the zone the node is launched into.
+	// +kubebuilder:validation:XValidation:message="subnetSelectorTerms cannot be empty",rule="self.size() != 0"
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'cidr']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.cidr))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in a subnet selector term",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.cidr)))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +required
+	SubnetSelectorTerms []SubnetSelectorTerm `json:"subnetSelectorTerms"`
+	// SecurityGroupSelectorTerms is a list of security group selector terms which select the security groups of the
+	// network interfaces. The terms are ORed. The node's security groups are used when they're omitted.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in a security group selector term",rule="!self.all(x, has(x.id) && (has(x.tags) || has(x.name) || has(x.vpcID)))"
+	// +kubebuilder:validation:XValidation:message="'name' is mutually exclusive, cannot be set with a combination of other fields in a security group selector term",rule="!self.all(x, has(x.name) && (has(x.tags) || has(x.id)))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	SecurityGroupSelectorTerms []SecurityGroupSelectorTerm `json:"securityGroupSelectorTerms,omitempty"`
+}
+
+// IPv6 configures the IPv6 addresses and prefixes of the primary network interface of nodes
+type IPv6 struct {
+	// AddressCount is the number of IPv6 addresses assigned to the primary network interface. Defaults to one address in
+	// IPv6 clusters, and to none otherwise.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=50
+	// +optional
+	AddressCount *int32 `json:"addressCount,omitempty"`
+	// PrefixCount is the number of IPv6 /80 prefixes assigned to the primary network interface.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=50
+	// +optional
+	PrefixCount *int32 `json:"prefixCount,omitempty"`
+}
+
+// EFA configures the Elastic Fabric Adapter (EFA) interfaces of nodes
+type EFA struct {
+	// Count is the number of EFA interfaces attached to nodes, which are attached to consecutive network cards. Instance
+	// types that support fewer EFA interfaces are limited to their maximum. Defaults to the instance type's maximum.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=32
+	// +optional
+	Count *int32 `json:"count,omitempty"`
+	// EFAOnly attaches EFA-only interfaces to the secondary network cards, which don't consume IP addresses from the
+	// subnet. The primary network card always has an EFA interface with IP addresses.
+	// +optional
+	EFAOnly *bool `json:"efaOnly,omitempty"`
+}
+
+// ElasticIP configures the Elastic IP addresses that are associated with nodes
+type ElasticIP struct {
+	// SelectorTerms is a list of Elastic IP address selector terms. The terms are ORed. Nodes are associated with an
+	// unassociated address selected by the terms, and wait for one to become available when there are none. When
+	// omitted, an address is allocated for each node, tagged with its NodeClaim, and released once it's terminated.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in an elastic ip selector term",rule="!self.all(x, has(x.id) && has(x.tags))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	SelectorTerms []ElasticIPSelectorTerm `json:"selectorTerms,omitempty"`
+}
+
+// ElasticIPSelectorTerm defines selection logic for the Elastic IP addresses which are associated with nodes.
+// If multiple fields are used for selection, the requirements are ANDed.
+type ElasticIPSelectorTerm struct {
+	// Tags is a map of key/value tags used to select addresses.
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// ID is the allocation id of the address in EC2
+	// +kubebuilder:validation:Pattern:="^eipalloc-[0-9a-z]+$"
+	// +optional
+	ID string `json:"id,omitempty"`
 }
 
+// CIDRMatch enumerates the ways that a cidr subnetSelectorTerm selects subnets
+type CIDRMatch string
+
+const (
+	CIDRMatchExact    CIDRMatch = "Exact"
+	CIDRMatchContains CIDRMatch = "Contains"
+)
+
 // SecurityGroupSelectorTerm defines selection logic for a security group used by Karpenter to launch nodes.
 // If multiple fields are used for selection, the requirements are ANDed.
 type SecurityGroupSelectorTerm struct {
@@ -171,9 +500,15 @@ type SecurityGroupSelectorTerm struct {
 	// +kubebuilder:validation:Pattern:="sg-[0-9a-z]+"
 	// +optional
 	ID string `json:"id,omitempty"`
-	// Name is the security group name in EC2.
+	// Name is the security group name in EC2, which may contain the wildcards '*' and '?'.
 	// This value is the name field, which is different from the name tag.
 	Name string `json:"name,omitempty"`
+	// VPCID is the id of the VPC in EC2 that the security groups selected by the term's name or tags must be in.
+	// Security group names are only unique within a VPC, so this selects security groups deterministically when
+	// several VPCs, such as shared VPCs, have security groups of the same name.
+	// +kubebuilder:validation:Pattern:="vpc-[0-9a-z]+"
+	// +optional
+	VPCID string `json:"vpcID,omitempty"`
 }
 
 type CapacityReservationSelectorTerm struct {
@@ -195,15 +530,16 @@ type CapacityReservationSelectorTerm struct {
 
 // AMISelectorTerm defines selection logic for an ami used by Karpenter to launch nodes.
 // If multiple fields are used for selection, the requirements are ANDed.
+// +kubebuilder:validation:XValidation:message="minAge must be less than maxAge",rule="!has(self.minAge) || !has(self.maxAge) || duration(self.minAge) < duration(self.maxAge)"
 type AMISelectorTerm struct {
 	// Alias specifies which EKS optimized AMI to select.
 	// Each alias consists of a family and an AMI version, specified as "family@version".
-	// Valid families include: al2, al2023, bottlerocket, windows2019, and windows2022.
-	// The version can either be pinned to a specific AMI release, with that AMIs version format (ex: "al2023@v20240625" or "bottlerocket@v1.10.0").
+	// Valid families include: al2, al2023, bottlerocket, flatcar, talos, ubuntu, windows2019, and windows2022.
+	// The version can either be pinned to a specific AMI release, with that AMIs version format (ex: "al2023@v20240625", "bottlerocket@v1.10.0", "flatcar@4081.2.0", "talos@v1.7.6", or "ubuntu@20240625").
 	// The version can also be set to "latest" for any family. Setting the version to latest will result in drift when a new AMI is released. This is **not** recommended for production environments.
 	// Note: The Windows families do **not** support version pinning, and only latest may be used.
 	// +kubebuilder:validation:XValidation:message="'alias' is improperly formatted, must match the format 'family@version'",rule="self.matches('^[a-zA-Z0-9]+@.+$')"
-	// +kubebuilder:validation:XValidation:message="family is not supported, must be one of the following: 'al2', 'al2023', 'bottlerocket', 'windows2019', 'windows2022'",rule="self.split('@')[0] in ['al2','al2023','bottlerocket','windows2019','windows2022']"
+	// +kubebuilder:validation:XValidation:message="family is not supported, must be one of the following: 'al2', 'al2023', 'bottlerocket', 'flatcar', 'talos', 'ubuntu', 'windows2019', 'windows2022'",rule="self.split('@')[0] in ['al2','al2023','bottlerocket','flatcar','talos','ubuntu','windows2019','windows2022']"
 	// +kubebuilder:validation:XValidation:message="windows families may only specify version 'latest'",rule="self.split('@')[0] in ['windows2019','windows2022'] ? self.split('@')[1] == 'latest' : true"
 	// +kubebuilder:validation:MaxLength=30
 	// +optional
@@ -220,12 +556,83 @@ type AMISelectorTerm struct {
 	ID string `json:"id,omitempty"`
 	// Name is the ami name in EC2.
 	// This value is the name field, which is different from the name tag.
+	// It may contain the wildcards '*' and '?', e.g. "amazon-eks-node-1.31-*".
 	// +optional
 	Name string `json:"name,omitempty"`
+	// NamePattern is a regular expression, in RE2 syntax, which the ami name must match, e.g.
+	// "^amazon-eks-node-1\.31-v2024(09|10)[0-9]{2}$". It's matched after the AMIs are described, so it's best combined
+	// with a name, tags or owner which narrow the AMIs that are described.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=1024
+	// +optional
+	NamePattern string `json:"namePattern,omitempty"`
 	// Owner is the owner for the ami.
 	// You can specify a combination of AWS account IDs, "self", "amazon", and "aws-marketplace"
 	// +optional
 	Owner string `json:"owner,omitempty"`
+	// NewestCount is the number of AMIs selected by the term for each set of requirements, such as each architecture.
+	// Nodes are launched with the newest selected AMI, but nodes running any of the selected AMIs aren't drifted, which
+	// keeps previous AMIs eligible during a rollout. Defaults to 1.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=10
+	// +optional
+	NewestCount *int32 `json:"newestCount,omitempty"`
+	// MinAge is the time that must have passed since an AMI was created before it's selected by the term, e.g. "168h".
+	// This can be used to only roll out AMIs after they've been published for some time.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	// +kubebuilder:validation:Type="string"
+	// +optional
+	MinAge *metav1.Duration `json:"minAge,omitempty"`
+	// MaxAge is the time since an AMI was created after which it's no longer selected by the term, e.g. "2160h".
+	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	// +kubebuilder:validation:Type="string"
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+	// ImageBuilderARN is the ARN of an EC2 Image Builder image pipeline or image recipe. The term selects the AMIs
+	// output by the most recently created image of the pipeline or recipe that's available, so that new images are
+	// picked up as the pipeline builds them.
+	// +kubebuilder:validation:Pattern:=`^arn:aws[a-z-]*:imagebuilder:[a-z0-9-]+:[0-9]{12}:(image-pipeline/[a-z0-9-_]+|image-recipe/[a-z0-9-_]+/[0-9]+\.[0-9]+\.[0-9]+)$`
+	// +kubebuilder:validation:MaxLength=1024
+	// +optional
+	ImageBuilderARN string `json:"imageBuilderARN,omitempty"`
+	// SSMParameter is the name or ARN of an SSM parameter whose value is the ID of the AMI to select. Parameters shared
+	// from other accounts are referenced by their ARN, and are read with the role configured by --ami-parameter-role-arn
+	// if it's set.
+	// +kubebuilder:validation:Pattern:=`^(arn:aws[a-z-]*:ssm:[a-z0-9-]+:[0-9]{12}:parameter)?/[a-zA-Z0-9_.\-/]+$`
+	// +kubebuilder:validation:MaxLength=2048
+	// +optional
+	SSMParameter string `json:"ssmParameter,omitempty"`
+	// SSMParameterPath is an SSM parameter path, such as "/golden/eks", under which the IDs of AMI variants are stored,
+	// e.g. "/golden/eks/1.31/x86_64" and "/golden/eks/1.31/arm64". Every parameter under the path whose value is an AMI
+	// ID is selected, except those whose path contains a Kubernetes version, like "1.31" or "k8s-1.31", other than the
+	// cluster's. The AMIs' architectures determine which instance types they're used for.
+	// +kubebuilder:validation:Pattern:=`^/[a-zA-Z0-9_.\-/]+$`
+	// +kubebuilder:validation:MaxLength=2048
+	// +optional
+	SSMParameterPath string `json:"ssmParameterPath,omitempty"`
+}
+
+// AMIExclusionTerm defines AMIs that are excluded from the AMIs selected by amiSelectorTerms.
+// If multiple fields are used for exclusion, the requirements are ANDed.
+type AMIExclusionTerm struct {
+	// ID is the ami id in EC2
+	// +kubebuilder:validation:Pattern:="ami-[0-9a-z]+"
+	// +optional
+	ID string `json:"id,omitempty"`
+	// Name is the ami name in EC2, which may contain the wildcards '*' and '?'.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// NamePattern is a regular expression, in RE2 syntax, which the ami name must match.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=1024
+	// +optional
+	NamePattern string `json:"namePattern,omitempty"`
+	// Tags is a map of key/value tags which the ami must have.
+	// Specifying '*' for a value matches all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 // KubeletConfiguration defines args to be used when configuring kubelet on provisioned nodes.
@@ -347,6 +754,157 @@ type MetadataOptions struct {
 	HTTPTokens *string `json:"httpTokens,omitempty"`
 }
 
+// PrivateDNSNameOptions contains parameters for specifying the hostnames of provisioned EC2 nodes.
+// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/ec2-instance-naming.html
+type PrivateDNSNameOptions struct {
+	// HostnameType is the type of hostname of provisioned nodes. An "ip-name" hostname is derived from the node's
+	// private IPv4 address, e.g. ip-10-0-0-1.ec2.internal, while a "resource-name" hostname is derived from its
+	// instance ID, e.g. i-0123456789abcdef0.ec2.internal. If not set, the subnet's hostname type is used.
+	// +kubebuilder:validation:Enum:={ip-name,resource-name}
+	// +optional
+	HostnameType *string `json:"hostnameType,omitempty"`
+	// EnableResourceNameDNSARecord creates a DNS A record for the node's resource-name hostname, so that it resolves to
+	// the node's private IPv4 address.
+	// +optional
+	EnableResourceNameDNSARecord *bool `json:"enableResourceNameDnsARecord,omitempty"`
+	// EnableResourceNameDNSAAAARecord creates a DNS AAAA record for the node's resource-name hostname, so that it resolves
+	// to the node's IPv6 address. Defaults to true for nodes in IPv6-only subnets.
+	// +optional
+	EnableResourceNameDNSAAAARecord *bool `json:"enableResourceNameDnsAAAARecord,omitempty"`
+}
+
+// CPUOptions contains parameters for the processors of provisioned EC2 nodes.
+// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/instance-optimize-cpu.html
+type CPUOptions struct {
+	// ThreadsPerCore is the number of threads that each of the instance's cores runs. Setting it to 1 disables
+	// simultaneous multithreading, and the instances' CPU capacity is advertised in physical cores rather than vCPUs.
+	// Instance types which don't support the number of threads per core aren't launched. If not set, the instance
+	// type's default is used.
+	// +kubebuilder:validation:Enum:={1,2}
+	// +optional
+	ThreadsPerCore *int32 `json:"threadsPerCore,omitempty"`
+}
+
+// BootOptions contains parameters for the boot integrity of provisioned EC2 nodes. EC2 determines the boot mode and
+// NitroTPM support of an instance from its AMI, so these restrict the AMIs which are selected and the instance types
+// which are launched with them.
+// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/uefi-secure-boot.html
+// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/nitrotpm.html
+// +kubebuilder:validation:XValidation:message="nitroTPM requires the uefi bootMode",rule="has(self.nitroTPM) && self.nitroTPM ? has(self.bootMode) && self.bootMode == 'uefi' : true"
+// +kubebuilder:validation:XValidation:message="measuredBootPCRs requires nitroTPM",rule="has(self.measuredBootPCRs) ? has(self.nitroTPM) && self.nitroTPM : true"
+type BootOptions struct {
+	// BootMode requires instances to boot with UEFI, which UEFI Secure Boot depends on. Only AMIs with the uefi or
+	// uefi-preferred boot mode are selected, and only instance types which support UEFI are launched.
+	// +kubebuilder:validation:Enum:={uefi}
+	// +optional
+	BootMode *string `json:"bootMode,omitempty"`
+	// NitroTPM requires instances to have a NitroTPM device. Only AMIs which support NitroTPM 2.0 are selected, and only
+	// instance types which support NitroTPM are launched.
+	// +optional
+	NitroTPM *bool `json:"nitroTPM,omitempty"`
+	// MeasuredBootPCRs are the values which the NitroTPM's platform configuration registers are expected to hold once
+	// instances have booted, keyed by register index, as hex encoded SHA-256 or SHA-384 digests. They're published on
+	// each NodeClaim as the karpenter.k8s.aws/measured-boot-pcrs annotation, so that attestation of the node can be
+	// verified against the values it was launched with.
+	// +kubebuilder:validation:XValidation:message="measuredBootPCRs keys must be register indexes from 0 to 23",rule="self.all(k, k.matches('^([0-9]|1[0-9]|2[0-3])$'))"
+	// +kubebuilder:validation:MaxProperties:=24
+	// +optional
+	MeasuredBootPCRs map[string]PCRDigest `json:"measuredBootPCRs,omitempty" hash:"ignore"`
+}
+
+// PCRDigest is the hex encoded SHA-256 or SHA-384 digest held by a platform configuration register
+// +kubebuilder:validation:Pattern:=`^([0-9a-f]{64}|[0-9a-f]{96})$`
+type PCRDigest string
+
+// BootModeUEFI is the boot mode that BootOptions can require
+const BootModeUEFI = "uefi"
+
+// FleetOptions contains parameters for the CreateFleet requests which launch instances.
+// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/ec2-fleet-allocation-strategy.html
+type FleetOptions struct {
+	// SpotAllocationStrategy is the strategy used to choose between spot offerings. If not set, the
+	// price-capacity-optimized strategy is used.
+	// +kubebuilder:validation:Enum:={price-capacity-optimized,capacity-optimized,capacity-optimized-prioritized,lowest-price,diversified}
+	// +optional
+	SpotAllocationStrategy *string `json:"spotAllocationStrategy,omitempty"`
+	// InstanceTypePriority lists instance types from the most to the least preferred. Instance types which aren't
+	// listed are preferred least. On-demand instances are launched with the prioritized allocation strategy, trying
+	// each instance type in this order and the cheapest offerings of each instance type first. Spot instances follow
+	// this order on a best-effort basis when the capacity-optimized-prioritized strategy is used.
+	// +kubebuilder:validation:MaxItems:=100
+	// +listType=set
+	// +optional
+	InstanceTypePriority []string `json:"instanceTypePriority,omitempty"`
+	// ArchitecturePriority lists CPU architectures from the most to the least preferred. Offerings of a preferred
+	// architecture are tried before cheaper offerings of the next architecture, as long as they cost no more than
+	// architecturePriceTolerancePercent more. Like instanceTypePriority, on-demand instances are launched with the
+	// prioritized allocation strategy, and spot instances follow this order on a best-effort basis when the
+	// capacity-optimized-prioritized strategy is used. instanceTypePriority takes precedence over this order.
+	// +kubebuilder:validation:XValidation:message="architecturePriority must not contain duplicates",rule="self.all(x, self.exists_one(y, x == y))"
+	// +kubebuilder:validation:items:Enum:={amd64,arm64}
+	// +kubebuilder:validation:MaxItems:=2
+	// +optional
+	ArchitecturePriority []string `json:"architecturePriority,omitempty"`
+	// ArchitecturePriceTolerancePercent is how much more an offering of a preferred architecture may cost, as a
+	// percentage of the price of an offering of the next architecture in architecturePriority, and still be tried
+	// first. Defaults to 10.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=100
+	// +optional
+	ArchitecturePriceTolerancePercent *int32 `json:"architecturePriceTolerancePercent,omitempty"`
+}
+
+// DefaultArchitecturePriceTolerancePercent is used when an EC2NodeClass sets an architecturePriority without a
+// tolerance
+const DefaultArchitecturePriceTolerancePercent = 10
+
+// AMIRollout contains parameters for gradually moving launches onto newly resolved AMIs.
+type AMIRollout struct {
+	// Duration is the time over which launches are ramped onto newly resolved AMIs. Once it elapses, all new nodes are
+	// launched with the new AMIs and nodes running the previous AMIs are drifted.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	// +kubebuilder:validation:Type="string"
+	// +required
+	Duration metav1.Duration `json:"duration"`
+	// InitialPercentage is the percentage of new nodes launched with the new AMIs when a rollout starts. The percentage
+	// increases linearly to 100 over the rollout's duration.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=100
+	// +kubebuilder:default:=10
+	// +optional
+	InitialPercentage *int32 `json:"initialPercentage,omitempty"`
+	// MaxNodes is the maximum number of nodes launched with the new AMIs before the rollout completes. Once reached,
+	// new nodes are launched with the previous AMIs for the remainder of the rollout.
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	MaxNodes *int32 `json:"maxNodes,omitempty"`
+}
+
+// MaintenanceWindow is a recurring period of time during which disruptive changes may be made to nodes.
+type MaintenanceWindow struct {
+	// Schedule specifies when the window opens, following the upstream cronjob syntax. Timezones aren't supported,
+	// the schedule is evaluated in UTC.
+	// +kubebuilder:validation:Pattern:=`^(@(annually|yearly|monthly|weekly|daily|midnight|hourly))|((.+)\s(.+)\s(.+)\s(.+)\s(.+))$`
+	// +required
+	Schedule string `json:"schedule"`
+	// Duration is how long the window stays open after each time the schedule is hit. Only minutes and hours are
+	// accepted, as cron does not work in seconds.
+	// +kubebuilder:validation:Pattern=`^((([0-9]+(h|m))|([0-9]+h[0-9]+m))(0s)?)$`
+	// +kubebuilder:validation:Type="string"
+	// +required
+	Duration metav1.Duration `json:"duration"`
+}
+
+// IsOpen returns whether the window is open at the given time
+func (in *MaintenanceWindow) IsOpen(now time.Time) (bool, error) {
+	schedule, err := cron.ParseStandard(fmt.Sprintf("TZ=UTC %s", in.Schedule))
+	if err != nil {
+		return false, fmt.Errorf("parsing schedule %q, %w", in.Schedule, err)
+	}
+	// The window is open if the schedule was hit within the window's duration
+	return !schedule.Next(now.UTC().Add(-in.Duration.Duration)).After(now.UTC()), nil
+}
+
 type BlockDeviceMapping struct {
 	// The device name (for example, /dev/sdh or xvdh).
 	// +optional
@@ -427,6 +985,272 @@ type BlockDevice struct {
 	VolumeType *string `json:"volumeType,omitempty"`
 }
 
+// BottlerocketSettings holds arbitrary Bottlerocket settings, structured the same as the settings table of Bottlerocket's
+// TOML UserData
+// +kubebuilder:validation:Type=object
+// +kubebuilder:pruning:PreserveUnknownFields
+type BottlerocketSettings struct {
+	apiextensionsv1.JSON `json:",inline"`
+}
+
+func (in *BottlerocketSettings) String() string {
+	if in == nil {
+		return ""
+	}
+	return string(in.Raw)
+}
+
+// NodeConfig holds the parts of nodeadm's NodeConfig (https://awslabs.github.io/amazon-eks-ami/nodeadm/) which can be
+// configured on an EC2NodeClass
+type NodeConfig struct {
+	// Kubelet configures the kubelet's configuration file and command line flags
+	// +optional
+	Kubelet *NodeConfigKubelet `json:"kubelet,omitempty"`
+	// Containerd configures the containerd runtime
+	// +optional
+	Containerd *NodeConfigContainerd `json:"containerd,omitempty"`
+	// Instance configures the instance's local storage
+	// +optional
+	Instance *NodeConfigInstance `json:"instance,omitempty"`
+}
+
+type NodeConfigKubelet struct {
+	// Config is merged into the kubelet's KubeletConfiguration (https://kubernetes.io/docs/reference/config-api/kubelet-config.v1beta1/).
+	// Fields which can be set with the kubelet field, and registerWithTaints, are generated by Karpenter and can't be set.
+	// +kubebuilder:validation:MaxProperties:=100
+	// +optional
+	Config map[string]apiextensionsv1.JSON `json:"config,omitempty"`
+	// Flags are appended to the kubelet's command line. Node labels and taints are generated by Karpenter from the
+	// NodePool, and can't be set with flags.
+	// +kubebuilder:validation:XValidation:message="kubelet flags must start with '--'",rule="self.all(x, x.startsWith('--'))"
+	// +kubebuilder:validation:XValidation:message="--node-labels and --register-with-taints are managed by Karpenter",rule="self.all(x, !x.startsWith('--node-labels') && !x.startsWith('--register-with-taints'))"
+	// +kubebuilder:validation:MaxItems:=50
+	// +kubebuilder:validation:items:MaxLength:=1024
+	// +optional
+	Flags []string `json:"flags,omitempty"`
+}
+
+type NodeConfigContainerd struct {
+	// Config is a TOML containerd configuration which nodeadm merges into the default configuration, e.g. to configure
+	// registry hosts or runtime options.
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:MaxLength:=16384
+	// +optional
+	Config string `json:"config,omitempty"`
+}
+
+type NodeConfigInstance struct {
+	// LocalStorage configures how nodeadm uses the instance's NVMe instance store volumes
+	// +optional
+	LocalStorage *NodeConfigLocalStorage `json:"localStorage,omitempty"`
+}
+
+type NodeConfigLocalStorage struct {
+	// Strategy is how instance store volumes are set up. RAID0 creates a RAID-0 array of all of the volumes, and Mount
+	// mounts each volume separately. In both cases the kubelet and containerd state directories use the instance store.
+	// +kubebuilder:validation:Enum:={RAID0,Mount}
+	// +required
+	Strategy string `json:"strategy"`
+}
+
+// KubeletConfigDropIn is a partial kubelet configuration
+type KubeletConfigDropIn struct {
+	// Name identifies the drop-in, and is used to name its file on the node
+	// +kubebuilder:validation:Pattern:="^[a-z0-9]([a-z0-9-]*[a-z0-9])?$"
+	// +kubebuilder:validation:MaxLength:=63
+	// +required
+	Name string `json:"name"`
+	// Config holds fields of the kubelet's KubeletConfiguration (https://kubernetes.io/docs/reference/config-api/kubelet-config.v1beta1/).
+	// Fields which can be set with the kubelet field, and registerWithTaints, are generated by Karpenter and can't be set.
+	// +kubebuilder:validation:MinProperties:=1
+	// +kubebuilder:validation:MaxProperties:=100
+	// +required
+	Config map[string]apiextensionsv1.JSON `json:"config"`
+}
+
+// RegistryMirror configures the mirrors of a container registry
+type RegistryMirror struct {
+	// Registry is the host of the mirrored registry, with an optional port, e.g. docker.io or public.ecr.aws
+	// +kubebuilder:validation:Pattern:=`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?(:[0-9]+)?$`
+	// +kubebuilder:validation:MaxLength:=253
+	// +required
+	Registry string `json:"registry"`
+	// Endpoints are the URLs of the mirrors, which are tried in order before the registry itself
+	// +kubebuilder:validation:XValidation:message="endpoints must be http or https URLs",rule="self.all(x, x.startsWith('https://') || x.startsWith('http://'))"
+	// +kubebuilder:validation:MinItems:=1
+	// +kubebuilder:validation:MaxItems:=5
+	// +kubebuilder:validation:items:MaxLength:=2048
+	// +required
+	Endpoints []string `json:"endpoints"`
+	// CredentialsSecretName is the name of a Secret in Karpenter's namespace, with username and password keys, that
+	// holds the credentials used to authenticate with the mirrors. The credentials are written to the node's UserData.
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:MaxLength:=253
+	// +optional
+	CredentialsSecretName *string `json:"credentialsSecretName,omitempty"`
+}
+
+// Proxy configures the HTTP proxy of a node's container runtime and kubelet
+// +kubebuilder:validation:XValidation:message="must specify httpProxy or httpsProxy",rule="has(self.httpProxy) || has(self.httpsProxy)"
+type Proxy struct {
+	// HTTPProxy is the URL of the proxy used for HTTP requests, e.g. http://proxy.example.com:3128
+	// +kubebuilder:validation:Pattern:=`^https?://[^\s'"]+$`
+	// +kubebuilder:validation:MaxLength:=2048
+	// +optional
+	HTTPProxy *string `json:"httpProxy,omitempty"`
+	// HTTPSProxy is the URL of the proxy used for HTTPS requests, e.g. http://proxy.example.com:3128
+	// +kubebuilder:validation:Pattern:=`^https?://[^\s'"]+$`
+	// +kubebuilder:validation:MaxLength:=2048
+	// +optional
+	HTTPSProxy *string `json:"httpsProxy,omitempty"`
+	// NoProxy are the hosts, domains (e.g. .example.com), IP addresses, and CIDRs which are reached without the proxy.
+	// The cluster's API server endpoint, service CIDR, the instance metadata service and localhost are always reached
+	// without the proxy, and needn't be listed.
+	// +kubebuilder:validation:items:Pattern:=`^[^\s,'"]+$`
+	// +kubebuilder:validation:items:MaxLength:=253
+	// +kubebuilder:validation:MaxItems:=50
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+// TrustedCABundle is a bundle of PEM encoded certificate authorities, set inline or read from a ConfigMap
+// +kubebuilder:validation:XValidation:message="must specify exactly one of pem or configMapName",rule="has(self.pem) != has(self.configMapName)"
+type TrustedCABundle struct {
+	// PEM is the bundle of PEM encoded certificates
+	// +kubebuilder:validation:XValidation:message="pem must contain a PEM encoded certificate",rule="self.contains('-----BEGIN CERTIFICATE-----')"
+	// +kubebuilder:validation:MaxLength:=65536
+	// +optional
+	PEM *string `json:"pem,omitempty"`
+	// ConfigMapName is the name of a ConfigMap in Karpenter's namespace whose ca-bundle.crt key holds the bundle of PEM
+	// encoded certificates
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:MaxLength:=253
+	// +optional
+	ConfigMapName *string `json:"configMapName,omitempty"`
+}
+
+// Swap configures the swap space of the node and how the kubelet lets workloads use it
+// +kubebuilder:validation:XValidation:message="size must be specified when source is Swapfile, and only then",rule="self.source == 'Swapfile' ? has(self.size) : !has(self.size)"
+type Swap struct {
+	// Source is where the swap space is provisioned. Swapfile creates a file of the given size on the root volume, and
+	// InstanceStore uses each of the instance's NVMe instance store volumes as a swap device.
+	// +kubebuilder:validation:Enum:={Swapfile,InstanceStore}
+	// +required
+	Source SwapSource `json:"source"`
+	// Size of the swapfile in `Mi` or `Gi`
+	// +kubebuilder:validation:Pattern:="^[1-9][0-9]{0,6}(Mi|Gi)$"
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type:=string
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty" hash:"string"`
+	// SwapBehavior is how the kubelet lets workloads use swap. LimitedSwap lets the containers of Burstable pods use swap
+	// in proportion to their memory requests, and NoSwap leaves swap to processes outside of Kubernetes. Defaults to
+	// LimitedSwap.
+	// +kubebuilder:validation:Enum:={NoSwap,LimitedSwap}
+	// +optional
+	SwapBehavior *SwapBehavior `json:"swapBehavior,omitempty"`
+}
+
+// SwapSource enumerates where swap space is provisioned
+type SwapSource string
+
+const (
+	SwapSourceSwapfile      SwapSource = "Swapfile"
+	SwapSourceInstanceStore SwapSource = "InstanceStore"
+)
+
+// SwapBehavior enumerates the kubelet's memorySwap.swapBehavior
+type SwapBehavior string
+
+const (
+	SwapBehaviorNoSwap      SwapBehavior = "NoSwap"
+	SwapBehaviorLimitedSwap SwapBehavior = "LimitedSwap"
+)
+
+// ExtendedResource adds resources to the capacity of the instance types it selects
+// +kubebuilder:validation:XValidation:message="must specify instanceFamilies or instanceTypes",rule="has(self.instanceFamilies) || has(self.instanceTypes)"
+type ExtendedResource struct {
+	// InstanceFamilies select the instance types of the listed families, e.g. trn1 or m7i
+	// +kubebuilder:validation:items:Pattern:=`^[a-z0-9-]+$`
+	// +kubebuilder:validation:MaxItems:=50
+	// +optional
+	InstanceFamilies []string `json:"instanceFamilies,omitempty"`
+	// InstanceTypes select the listed instance types, e.g. trn1.32xlarge
+	// +kubebuilder:validation:items:Pattern:=`^[a-z0-9-]+\.[a-z0-9-]+$`
+	// +kubebuilder:validation:MaxItems:=100
+	// +optional
+	InstanceTypes []string `json:"instanceTypes,omitempty"`
+	// Resources are the quantities of the extended resources that each of the selected instance types has, e.g.
+	// example.com/asic: 4
+	// +kubebuilder:validation:XValidation:message="resources must be extended resources, with a domain other than kubernetes.io",rule="self.all(x, x.contains('/') && !x.startsWith('kubernetes.io/') && !x.split('/')[0].endsWith('.kubernetes.io'))"
+	// +kubebuilder:validation:MinProperties:=1
+	// +kubebuilder:validation:MaxProperties:=10
+	// +required
+	Resources corev1.ResourceList `json:"resources"`
+}
+
+// AMIVariant enumerates the variants of the EKS optimized AMIs.
+// +kubebuilder:validation:Enum={standard,nvidia,nvidia-open,neuron}
+type AMIVariant string
+
+const (
+	AMIVariantStandard   AMIVariant = "standard"
+	AMIVariantNvidia     AMIVariant = "nvidia"
+	AMIVariantNvidiaOpen AMIVariant = "nvidia-open"
+	AMIVariantNeuron     AMIVariant = "neuron"
+)
+
+// UserDataDriftPolicy enumerates the policies for drifting nodes when userData changes.
+type UserDataDriftPolicy string
+
+const (
+	UserDataDriftPolicySemantic UserDataDriftPolicy = "Semantic"
+	UserDataDriftPolicyStrict   UserDataDriftPolicy = "Strict"
+)
+
+// DeviceValidation configures the devices which are checked before the node bootstraps
+type DeviceValidation struct {
+	// Devices are the kinds of devices which are checked. GPU checks that every NVIDIA GPU attached to the instance is
+	// visible to nvidia-smi, EFA that every EFA device is bound to the EFA driver, and NVMe that every NVMe instance
+	// store volume has a block device.
+	// +kubebuilder:validation:MinItems:=1
+	// +kubebuilder:validation:MaxItems:=3
+	// +kubebuilder:validation:XValidation:message="devices must not contain duplicates",rule="self.all(x, self.exists_one(y, x == y))"
+	// +required
+	Devices []DeviceValidationDevice `json:"devices"`
+	// Timeout is how long the devices are waited for before the instance is stopped. Defaults to 5m.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	// +kubebuilder:validation:Type="string"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// DeviceValidationDevice enumerates the kinds of devices that can be checked before the node bootstraps.
+// +kubebuilder:validation:Enum:={GPU,EFA,NVMe}
+type DeviceValidationDevice string
+
+const (
+	DeviceValidationDeviceGPU  DeviceValidationDevice = "GPU"
+	DeviceValidationDeviceEFA  DeviceValidationDevice = "EFA"
+	DeviceValidationDeviceNVMe DeviceValidationDevice = "NVMe"
+)
+
+// UserDataMergeOrder enumerates the positions that userData can be merged in relative to the generated bootstrap script.
+type UserDataMergeOrder string
+
+const (
+	UserDataMergeOrderBeforeBootstrap UserDataMergeOrder = "BeforeBootstrap"
+	UserDataMergeOrderAfterBootstrap  UserDataMergeOrder = "AfterBootstrap"
+)
+
+// BootstrapMode enumerates the ways that the Custom AMI family can bootstrap the node.
+type BootstrapMode string
+
+const (
+	BootstrapModeUserData BootstrapMode = "UserData"
+	BootstrapModeNodeadm  BootstrapMode = "Nodeadm"
+)
+
 // InstanceStorePolicy enumerates options for configuring instance store disks.
 // +kubebuilder:validation:Enum={RAID0}
 type InstanceStorePolicy string
@@ -439,6 +1263,19 @@ const (
 	InstanceStorePolicyRAID0 InstanceStorePolicy = "RAID0"
 )
 
+// SecurityGroupDriftPolicy enumerates how nodes whose security groups have diverged from the EC2NodeClass are handled.
+// +kubebuilder:validation:Enum={Replace,Reconcile}
+type SecurityGroupDriftPolicy string
+
+const (
+	// SecurityGroupDriftPolicyReplace marks the nodes as drifted, so that they're replaced with nodes that are launched
+	// with the resolved security groups.
+	SecurityGroupDriftPolicyReplace SecurityGroupDriftPolicy = "Replace"
+	// SecurityGroupDriftPolicyReconcile replaces the security groups of the nodes' primary network interfaces with the
+	// resolved security groups, without replacing the nodes.
+	SecurityGroupDriftPolicyReconcile SecurityGroupDriftPolicy = "Reconcile"
+)
+
 // EC2NodeClass is the Schema for the EC2NodeClass API
 // +kubebuilder:object:root=true
 // +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description=""
@@ -456,9 +1293,28 @@ type EC2NodeClass struct {
 	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'AL2' or 'Custom' when using an AL2 alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'al2') ? (self.amiFamily == 'Custom' || self.amiFamily == 'AL2') : true)"
 	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'AL2023' or 'Custom' when using an AL2023 alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'al2023') ? (self.amiFamily == 'Custom' || self.amiFamily == 'AL2023') : true)"
 	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'Bottlerocket' or 'Custom' when using a Bottlerocket alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'bottlerocket') ? (self.amiFamily == 'Custom' || self.amiFamily == 'Bottlerocket') : true)"
+	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'Flatcar' or 'Custom' when using a Flatcar alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'flatcar') ? (self.amiFamily == 'Custom' || self.amiFamily == 'Flatcar') : true)"
+	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'Talos' or 'Custom' when using a Talos alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'talos') ? (self.amiFamily == 'Custom' || self.amiFamily == 'Talos') : true)"
+	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'Ubuntu' or 'Custom' when using an Ubuntu alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'ubuntu') ? (self.amiFamily == 'Custom' || self.amiFamily == 'Ubuntu') : true)"
 	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'Windows2019' or 'Custom' when using a Windows2019 alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'windows2019') ? (self.amiFamily == 'Custom' || self.amiFamily == 'Windows2019') : true)"
 	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'Windows2022' or 'Custom' when using a Windows2022 alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'windows2022') ? (self.amiFamily == 'Custom' || self.amiFamily == 'Windows2022') : true)"
 	// +kubebuilder:validation:XValidation:message="must specify amiFamily if amiSelectorTerms does not contain an alias",rule="self.amiSelectorTerms.exists(x, has(x.alias)) ? true : has(self.amiFamily)"
+	// +kubebuilder:validation:XValidation:message="bottlerocketSettings may only be set when using the Bottlerocket AMI family",rule="!has(self.bottlerocketSettings) || (has(self.amiFamily) ? self.amiFamily == 'Bottlerocket' : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'bottlerocket'))"
+	// +kubebuilder:validation:XValidation:message="nodeConfig may only be set when using the AL2023 AMI family, or the Custom AMI family with the Nodeadm bootstrapMode",rule="!has(self.nodeConfig) || (has(self.amiFamily) ? self.amiFamily == 'AL2023' || (self.amiFamily == 'Custom' && has(self.bootstrapMode) && self.bootstrapMode == 'Nodeadm') : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'al2023'))"
+	// +kubebuilder:validation:XValidation:message="kubeletConfigDropIns may only be set when using the AL2023 or Bottlerocket AMI families, or the Custom AMI family with the Nodeadm bootstrapMode",rule="!has(self.kubeletConfigDropIns) || (has(self.amiFamily) ? self.amiFamily in ['AL2023', 'Bottlerocket'] || (self.amiFamily == 'Custom' && has(self.bootstrapMode) && self.bootstrapMode == 'Nodeadm') : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') in ['al2023', 'bottlerocket']))"
+	// +kubebuilder:validation:XValidation:message="nodeConfig.instance.localStorage can't be set along with instanceStorePolicy",rule="!has(self.instanceStorePolicy) || !has(self.nodeConfig) || !has(self.nodeConfig.instance) || !has(self.nodeConfig.instance.localStorage)"
+	// +kubebuilder:validation:XValidation:message="registryMirrors are only supported by the AL2, AL2023, Bottlerocket, Windows2019 and Windows2022 AMI families",rule="!has(self.registryMirrors) || (has(self.amiFamily) ? self.amiFamily in ['AL2','AL2023','Bottlerocket','Windows2019','Windows2022'] : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') in ['al2','al2023','bottlerocket','windows2019','windows2022']))"
+	// +kubebuilder:validation:XValidation:message="proxy is only supported by the AL2, AL2023, Bottlerocket, Windows2019 and Windows2022 AMI families",rule="!has(self.proxy) || (has(self.amiFamily) ? self.amiFamily in ['AL2','AL2023','Bottlerocket','Windows2019','Windows2022'] : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') in ['al2','al2023','bottlerocket','windows2019','windows2022']))"
+	// +kubebuilder:validation:XValidation:message="proxy.httpProxy and proxy.httpsProxy must be the same when using the Bottlerocket AMI family",rule="!has(self.proxy) || !has(self.proxy.httpProxy) || !has(self.proxy.httpsProxy) || self.proxy.httpProxy == self.proxy.httpsProxy || !(has(self.amiFamily) ? self.amiFamily == 'Bottlerocket' : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'bottlerocket'))"
+	// +kubebuilder:validation:XValidation:message="trustedCABundle is only supported by the AL2, AL2023, Bottlerocket, Windows2019 and Windows2022 AMI families",rule="!has(self.trustedCABundle) || (has(self.amiFamily) ? self.amiFamily in ['AL2','AL2023','Bottlerocket','Windows2019','Windows2022'] : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') in ['al2','al2023','bottlerocket','windows2019','windows2022']))"
+	// +kubebuilder:validation:XValidation:message="swap may only be set when using the AL2023 AMI family, or the Custom AMI family with the Nodeadm bootstrapMode",rule="!has(self.swap) || (has(self.amiFamily) ? self.amiFamily == 'AL2023' || (self.amiFamily == 'Custom' && has(self.bootstrapMode) && self.bootstrapMode == 'Nodeadm') : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'al2023'))"
+	// +kubebuilder:validation:XValidation:message="swap.source can't be InstanceStore along with instanceStorePolicy or nodeConfig.instance.localStorage",rule="!has(self.swap) || self.swap.source != 'InstanceStore' || (!has(self.instanceStorePolicy) && (!has(self.nodeConfig) || !has(self.nodeConfig.instance) || !has(self.nodeConfig.instance.localStorage)))"
+	// +kubebuilder:validation:XValidation:message="bootstrapMode may only be set when using the Custom AMI family",rule="!has(self.bootstrapMode) || (has(self.amiFamily) && self.amiFamily == 'Custom')"
+	// +kubebuilder:validation:XValidation:message="amiExclusionTerms may not be set when using an alias amiSelectorTerm",rule="!has(self.amiExclusionTerms) || !self.amiSelectorTerms.exists(x, has(x.alias))"
+	// +kubebuilder:validation:XValidation:message="amiVariants may only be set when using an alias amiSelectorTerm",rule="!has(self.amiVariants) || self.amiSelectorTerms.exists(x, has(x.alias))"
+	// +kubebuilder:validation:XValidation:message="fips may only be enabled when using an AL2023 or Bottlerocket alias amiSelectorTerm",rule="!has(self.fips) || !self.fips || self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') in ['al2023', 'bottlerocket'])"
+	// +kubebuilder:validation:XValidation:message="nameTagTemplate can't be set along with a Name tag",rule="!has(self.nameTagTemplate) || !has(self.tags) || !('Name' in self.tags)"
+	// +kubebuilder:validation:XValidation:message="deviceValidation is only supported by the AL2, AL2023 and Ubuntu AMI families",rule="!has(self.deviceValidation) || (has(self.amiFamily) ? self.amiFamily in ['AL2','AL2023','Ubuntu'] : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') in ['al2','al2023','ubuntu']))"
 	Spec   EC2NodeClassSpec   `json:"spec,omitempty"`
 	Status EC2NodeClassStatus `json:"status,omitempty"`
 }
@@ -467,11 +1323,17 @@ type EC2NodeClass struct {
 // 1. A field changes its default value for an existing field that is already hashed
 // 2. A field is added to the hash calculation with an already-set value
 // 3. A field is removed from the hash calculations
-const EC2NodeClassHashVersion = "v4"
+const EC2NodeClassHashVersion = "v5"
 
 func (in *EC2NodeClass) Hash() string {
+	spec := in.Spec
+	// UserData is hashed with the changes that don't affect the node normalized away, unless the strict policy is used.
+	// Normalized UserData which is unchanged keeps the same hash.
+	if lo.FromPtr(spec.UserDataDriftPolicy) != UserDataDriftPolicyStrict {
+		spec.UserData = normalizeUserData(spec.UserData, in.AMIFamily())
+	}
 	return fmt.Sprint(lo.Must(hashstructure.Hash([]interface{}{
-		in.Spec,
+		spec,
 		// AMIFamily should be hashed using the dynamically resolved value rather than the literal value of the field.
 		// This ensures that scenarios such as changing the field from nil to AL2023 with the alias "al2023@latest"
 		// doesn't trigger drift.
@@ -515,6 +1377,56 @@ func (in *EC2NodeClass) AMIFamily() string {
 	return AMIFamilyCustom
 }
 
+// ThreadsPerCore returns the number of threads per core that the EC2NodeClass's instances are launched with, if set
+func (in *EC2NodeClass) ThreadsPerCore() *int32 {
+	if in.Spec.CPUOptions == nil {
+		return nil
+	}
+	return in.Spec.CPUOptions.ThreadsPerCore
+}
+
+// IPv6Native returns whether all of the EC2NodeClass's subnets are IPv6-only, so that its instances are launched
+// without IPv4 addresses
+func (in *EC2NodeClass) IPv6Native() bool {
+	return len(in.Status.Subnets) != 0 && lo.EveryBy(in.Status.Subnets, func(s Subnet) bool { return s.IPv6Native })
+}
+
+// ExtendedResourcesFor returns the extended resources that the EC2NodeClass adds to the capacity of an instance type
+func (in *EC2NodeClass) ExtendedResourcesFor(instanceType string) corev1.ResourceList {
+	family, _, _ := strings.Cut(instanceType, ".")
+	resources := corev1.ResourceList{}
+	for _, e := range in.Spec.ExtendedResources {
+		if lo.Contains(e.InstanceTypes, instanceType) || lo.Contains(e.InstanceFamilies, family) {
+			for name, quantity := range e.Resources {
+				resources[name] = quantity
+			}
+		}
+	}
+	return resources
+}
+
+// RequiresUEFI returns whether the EC2NodeClass's instances must boot with UEFI
+func (in *EC2NodeClass) RequiresUEFI() bool {
+	return in.Spec.BootOptions != nil && lo.FromPtr(in.Spec.BootOptions.BootMode) == BootModeUEFI
+}
+
+// RequiresNitroTPM returns whether the EC2NodeClass's instances must have a NitroTPM device
+func (in *EC2NodeClass) RequiresNitroTPM() bool {
+	return in.Spec.BootOptions != nil && lo.FromPtr(in.Spec.BootOptions.NitroTPM)
+}
+
+// InAMIMaintenanceWindow returns whether newly released AMIs may be adopted at the given time. This is always the case
+// when no maintenance windows are configured. Windows that fail to parse are treated as closed.
+func (in *EC2NodeClass) InAMIMaintenanceWindow(now time.Time) bool {
+	if len(in.Spec.AMIMaintenanceWindows) == 0 {
+		return true
+	}
+	return lo.ContainsBy(in.Spec.AMIMaintenanceWindows, func(w MaintenanceWindow) bool {
+		open, err := w.IsOpen(now)
+		return err == nil && open
+	})
+}
+
 type Alias struct {
 	Family  string
 	Version string
@@ -550,6 +1462,9 @@ func amiFamilyFromAlias(alias string) string {
 		AMIFamilyAL2,
 		AMIFamilyAL2023,
 		AMIFamilyBottlerocket,
+		AMIFamilyFlatcar,
+		AMIFamilyTalos,
+		AMIFamilyUbuntu,
 		AMIFamilyWindows2019,
 		AMIFamilyWindows2022,
 	}, func(family string) bool {
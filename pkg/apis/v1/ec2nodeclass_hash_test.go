@@ -15,8 +15,12 @@ limitations under the License.
 package v1_test
 
 import (
+	"strings"
+
 	"github.com/imdario/mergo"
 	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/karpenter/pkg/test"
@@ -106,6 +110,7 @@ var _ = Describe("Hash", func() {
 		Entry("Modified AMISelector", staticHash, v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{AMISelectorTerms: []v1.AMISelectorTerm{{Tags: map[string]string{"": "ami-test-value"}}}}}),
 		Entry("Modified SubnetSelector", staticHash, v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{SubnetSelectorTerms: []v1.SubnetSelectorTerm{{Tags: map[string]string{"subnet-test-key": "subnet-test-value"}}}}}),
 		Entry("Modified SecurityGroupSelector", staticHash, v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{SecurityGroupSelectorTerms: []v1.SecurityGroupSelectorTerm{{Tags: map[string]string{"security-group-test-key": "security-group-test-value"}}}}}),
+		Entry("Modified FleetOptions", staticHash, v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{FleetOptions: &v1.FleetOptions{SpotAllocationStrategy: lo.ToPtr("lowest-price"), InstanceTypePriority: []string{"m5.large"}}}}),
 	)
 	// We create a separate test for updating blockDeviceMapping volumeSize, since resource.Quantity is a struct, and mergo.WithSliceDeepCopy
 	// doesn't work well with unexported fields, like the ones that are present in resource.Quantity
@@ -133,11 +138,23 @@ var _ = Describe("Hash", func() {
 		Expect(hash).ToNot(Equal(updatedHash))
 	},
 		Entry("UserData", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{UserData: aws.String("userdata-test-2")}}),
+		Entry("TemplatedUserData", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{TemplatedUserData: lo.ToPtr(true)}}),
+		Entry("UserDataMergeOrder", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{UserDataMergeOrder: lo.ToPtr(v1.UserDataMergeOrderAfterBootstrap)}}),
+		Entry("DeviceValidation", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{DeviceValidation: &v1.DeviceValidation{Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceGPU}}}}),
+		Entry("BottlerocketSettings", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{BottlerocketSettings: &v1.BottlerocketSettings{JSON: apiextensionsv1.JSON{Raw: []byte(`{"motd":"hello"}`)}}}}),
+		Entry("NodeConfig", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{NodeConfig: &v1.NodeConfig{Kubelet: &v1.NodeConfigKubelet{Flags: []string{"--v=4"}}}}}),
 		Entry("Tags", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Tags: map[string]string{"keyTag-test-3": "valueTag-test-3"}}}),
+		Entry("Labels", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Labels: map[string]string{"network-tier": "public"}}}),
 		Entry("Context", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Context: aws.String("context-2")}}),
+		Entry("PrivateDNSNameOptions", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{PrivateDNSNameOptions: &v1.PrivateDNSNameOptions{HostnameType: aws.String("resource-name")}}}),
 		Entry("DetailedMonitoring", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{DetailedMonitoring: aws.Bool(true)}}),
+		Entry("CPUOptions", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{CPUOptions: &v1.CPUOptions{ThreadsPerCore: lo.ToPtr[int32](1)}}}),
+		Entry("BootOptions BootMode", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{BootOptions: &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI)}}}),
+		Entry("BootOptions NitroTPM", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{BootOptions: &v1.BootOptions{NitroTPM: lo.ToPtr(true)}}}),
 		Entry("InstanceStorePolicy", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{InstanceStorePolicy: lo.ToPtr(v1.InstanceStorePolicyRAID0)}}),
 		Entry("AssociatePublicIPAddress", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{AssociatePublicIPAddress: lo.ToPtr(true)}}),
+		Entry("IPv6", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{IPv6: &v1.IPv6{PrefixCount: lo.ToPtr[int32](1)}}}),
+		Entry("SourceDestCheck", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{SourceDestCheck: lo.ToPtr(false)}}),
 		Entry("MetadataOptions HTTPEndpoint", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{MetadataOptions: &v1.MetadataOptions{HTTPEndpoint: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPProtocolIPv6", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{MetadataOptions: &v1.MetadataOptions{HTTPProtocolIPv6: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPPutResponseHopLimit", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{MetadataOptions: &v1.MetadataOptions{HTTPPutResponseHopLimit: lo.ToPtr(int64(10))}}}),
@@ -160,6 +177,26 @@ var _ = Describe("Hash", func() {
 		updatedHash := nodeClass.Hash()
 		Expect(hash).ToNot(Equal(updatedHash))
 	})
+	// mergo.WithSliceDeepCopy only merges the elements that are already present in the destination slice, so adding
+	// registry mirrors is tested separately
+	It("should change hash when registryMirrors are updated", func() {
+		hash := nodeClass.Hash()
+		nodeClass.Spec.RegistryMirrors = []v1.RegistryMirror{{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com"}}}
+		updatedHash := nodeClass.Hash()
+		Expect(hash).ToNot(Equal(updatedHash))
+	})
+	It("should change hash when the trusted CA bundle is updated", func() {
+		hash := nodeClass.Hash()
+		nodeClass.Spec.TrustedCABundle = &v1.TrustedCABundle{ConfigMapName: lo.ToPtr("trusted-ca-bundle")}
+		updatedHash := nodeClass.Hash()
+		Expect(hash).ToNot(Equal(updatedHash))
+	})
+	It("should change hash when the proxy is updated", func() {
+		hash := nodeClass.Hash()
+		nodeClass.Spec.Proxy = &v1.Proxy{HTTPSProxy: lo.ToPtr("http://proxy.example.com:3128")}
+		updatedHash := nodeClass.Hash()
+		Expect(hash).ToNot(Equal(updatedHash))
+	})
 	It("should change hash when instanceProfile is updated", func() {
 		nodeClass.Spec.Role = ""
 		nodeClass.Spec.InstanceProfile = lo.ToPtr("test-instance-profile")
@@ -168,6 +205,27 @@ var _ = Describe("Hash", func() {
 		updatedHash := nodeClass.Hash()
 		Expect(hash).ToNot(Equal(updatedHash))
 	})
+	It("should change hash when kubeletConfigDropIns are updated", func() {
+		hash := nodeClass.Hash()
+		nodeClass.Spec.KubeletConfigDropIns = []v1.KubeletConfigDropIn{{Name: "swap", Config: map[string]apiextensionsv1.JSON{"failSwapOn": {Raw: []byte(`false`)}}}}
+		updatedHash := nodeClass.Hash()
+		Expect(hash).ToNot(Equal(updatedHash))
+	})
+	It("should not change hash when measuredBootPCRs are updated", func() {
+		nodeClass.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI), NitroTPM: lo.ToPtr(true)}
+		hash := nodeClass.Hash()
+		nodeClass.Spec.BootOptions.MeasuredBootPCRs = map[string]v1.PCRDigest{"0": v1.PCRDigest(strings.Repeat("a", 64))}
+		updatedHash := nodeClass.Hash()
+		Expect(hash).To(Equal(updatedHash))
+	})
+	It("should not change hash when extendedResources are updated", func() {
+		hash := nodeClass.Hash()
+		nodeClass.Spec.ExtendedResources = []v1.ExtendedResource{
+			{InstanceFamilies: []string{"trn1"}, Resources: corev1.ResourceList{"example.com/asic": resource.MustParse("4")}},
+		}
+		updatedHash := nodeClass.Hash()
+		Expect(hash).To(Equal(updatedHash))
+	})
 	It("should not change hash when tags are re-ordered", func() {
 		hash := nodeClass.Hash()
 		nodeClass.Spec.Tags = map[string]string{"keyTag-2": "valueTag-2", "keyTag-1": "valueTag-1"}
@@ -199,6 +257,85 @@ var _ = Describe("Hash", func() {
 		updatedHash := nodeClass.Hash()
 		Expect(hash).To(Equal(updatedHash))
 	})
+	Context("UserData", func() {
+		It("should not change hash when comments, blank lines, or trailing whitespace change", func() {
+			nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho hello\n")
+			hash := nodeClass.Hash()
+			nodeClass.Spec.UserData = aws.String("#!/bin/bash\n\n# say hello\necho hello   \n\n")
+			Expect(nodeClass.Hash()).To(Equal(hash))
+		})
+		It("should change hash when the first line changes", func() {
+			nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho hello")
+			hash := nodeClass.Hash()
+			nodeClass.Spec.UserData = aws.String("#!/bin/sh\necho hello")
+			Expect(nodeClass.Hash()).ToNot(Equal(hash))
+		})
+		It("should not change hash when the keys of Bottlerocket settings are re-ordered", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			nodeClass.Spec.UserData = aws.String("[settings.kubernetes]\nmax-pods = 110\nimage-gc-low-threshold-percent = 50\n")
+			hash := nodeClass.Hash()
+			nodeClass.Spec.UserData = aws.String("# kubelet settings\n[settings.kubernetes]\nimage-gc-low-threshold-percent = 50\nmax-pods = 110\n")
+			Expect(nodeClass.Hash()).To(Equal(hash))
+		})
+		It("should not change hash when the boundary or NodeConfig keys of a MIME document change", func() {
+			nodeClass.Spec.UserData = aws.String(`MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: application/node.eks.aws
+
+apiVersion: node.eks.aws/v1alpha1
+kind: NodeConfig
+spec:
+  kubelet:
+    config:
+      maxPods: 42
+
+--BOUNDARY
+Content-Type: text/x-shellscript; charset="us-ascii"
+
+#!/bin/bash
+echo "Hello, AL2023!"
+--BOUNDARY--
+`)
+			hash := nodeClass.Hash()
+			nodeClass.Spec.UserData = aws.String(`Content-Type: multipart/mixed; boundary="//"
+MIME-Version: 1.0
+
+--//
+Content-Type: application/node.eks.aws
+
+# Set the max pods
+kind: NodeConfig
+apiVersion: node.eks.aws/v1alpha1
+spec:
+  kubelet:
+    config:
+      maxPods: 42
+--//
+Content-Type: text/x-shellscript
+
+#!/bin/bash
+# Say hello
+echo "Hello, AL2023!"
+--//--
+`)
+			Expect(nodeClass.Hash()).To(Equal(hash))
+		})
+		It("should change hash when the order of MIME parts changes", func() {
+			nodeClass.Spec.UserData = aws.String("MIME-Version: 1.0\nContent-Type: multipart/mixed; boundary=\"//\"\n\n--//\nContent-Type: text/x-shellscript\n\n#!/bin/bash\necho first\n--//\nContent-Type: text/x-shellscript\n\n#!/bin/bash\necho second\n--//--\n")
+			hash := nodeClass.Hash()
+			nodeClass.Spec.UserData = aws.String("MIME-Version: 1.0\nContent-Type: multipart/mixed; boundary=\"//\"\n\n--//\nContent-Type: text/x-shellscript\n\n#!/bin/bash\necho second\n--//\nContent-Type: text/x-shellscript\n\n#!/bin/bash\necho first\n--//--\n")
+			Expect(nodeClass.Hash()).ToNot(Equal(hash))
+		})
+		It("should change hash when comments change with the Strict policy", func() {
+			nodeClass.Spec.UserDataDriftPolicy = lo.ToPtr(v1.UserDataDriftPolicyStrict)
+			nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho hello\n")
+			hash := nodeClass.Hash()
+			nodeClass.Spec.UserData = aws.String("#!/bin/bash\n# say hello\necho hello\n")
+			Expect(nodeClass.Hash()).ToNot(Equal(hash))
+		})
+	})
 	It("should expect two EC2NodeClasses with the same spec to have the same hash", func() {
 		otherNodeClass := &v1.EC2NodeClass{
 			Spec: nodeClass.Spec,
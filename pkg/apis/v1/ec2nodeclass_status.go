@@ -31,8 +31,39 @@ const (
 	ConditionTypeInstanceProfileReady      = "InstanceProfileReady"
 	ConditionTypeCapacityReservationsReady = "CapacityReservationsReady"
 	ConditionTypeValidationSucceeded       = "ValidationSucceeded"
+	// ConditionTypeNetworkInterfacesReady is only part of readiness when the EC2NodeClass has additional network interfaces
+	ConditionTypeNetworkInterfacesReady = "NetworkInterfacesReady"
+	// ConditionTypeDegraded is true while a background subsystem, such as pricing or instance type discovery, is failing
+	// to refresh. It doesn't affect readiness.
+	ConditionTypeDegraded = "Degraded"
+	// ConditionTypeAMIsDeprecated is true while the amiSelectorTerms match AMIs which are deprecated, or are within the
+	// EC2NodeClass's deprecation threshold. It doesn't affect readiness.
+	ConditionTypeAMIsDeprecated = "AMIsDeprecated"
+	// ConditionTypeInstanceTypesIncompatible is true while instance types are excluded from the EC2NodeClass because
+	// the AMI they'd be launched with doesn't support them, such as an AMI without ENA support. It doesn't affect
+	// readiness.
+	ConditionTypeInstanceTypesIncompatible = "InstanceTypesIncompatible"
+	// ConditionTypeSecurityGroupRulesMissing is true while the security groups don't have the rules nodes need to join
+	// the cluster, such as HTTPS to the cluster endpoint or traffic between nodes. It doesn't affect readiness.
+	ConditionTypeSecurityGroupRulesMissing = "SecurityGroupRulesMissing"
 )
 
+// NetworkInterfaceStatus contains the resolved subnets and security groups of additional network interfaces
+type NetworkInterfaceStatus struct {
+	// DeviceIndex of the network interface, or of the first network interface when there's more than one
+	// +required
+	DeviceIndex int32 `json:"deviceIndex"`
+	// Count is the number of network interfaces
+	// +required
+	Count int32 `json:"count"`
+	// Subnets of the network interfaces, which are available to the zones the node may launch into
+	// +optional
+	Subnets []Subnet `json:"subnets,omitempty"`
+	// SecurityGroups of the network interfaces, if they're not the node's security groups
+	// +optional
+	SecurityGroups []SecurityGroup `json:"securityGroups,omitempty"`
+}
+
 // Subnet contains resolved Subnet selector values utilized for node launch
 type Subnet struct {
 	// ID of the subnet
@@ -44,6 +75,13 @@ type Subnet struct {
 	// The associated availability zone ID
 	// +optional
 	ZoneID string `json:"zoneID,omitempty"`
+	// The number of IP addresses that are available in the subnet, as of when the subnets were last discovered
+	// +optional
+	AvailableIPAddressCount *int32 `json:"availableIPAddressCount,omitempty" hash:"ignore"`
+	// IPv6Native is whether the subnet is IPv6-only. Instances launched into it don't have IPv4 addresses, and its
+	// IPv4 addresses aren't counted when choosing a subnet to launch into.
+	// +optional
+	IPv6Native bool `json:"ipv6Native,omitempty"`
 }
 
 // SecurityGroup contains resolved SecurityGroup selector values utilized for node launch
@@ -67,6 +105,17 @@ type AMI struct {
 	// Name of the AMI
 	// +optional
 	Name string `json:"name,omitempty"`
+	// PlatformDetails of the AMI, which determine the operating system license it's billed for, e.g. "Linux/UNIX",
+	// "Windows" or "Red Hat Enterprise Linux"
+	// +optional
+	PlatformDetails string `json:"platformDetails,omitempty"`
+	// BootMode of the AMI, e.g. "legacy-bios", "uefi" or "uefi-preferred"
+	// +optional
+	BootMode string `json:"bootMode,omitempty"`
+	// ENASupport is whether the AMI supports the Elastic Network Adapter, which instance types that require ENA can't be
+	// launched without
+	// +optional
+	ENASupport *bool `json:"enaSupport,omitempty"`
 	// Requirements of the AMI to be utilized on an instance type
 	// +required
 	Requirements []corev1.NodeSelectorRequirement `json:"requirements"`
@@ -97,6 +146,47 @@ type CapacityReservation struct {
 	OwnerID string `json:"ownerID"`
 }
 
+// AMIRolloutStatus contains the progress of a rollout onto newly resolved AMIs
+type AMIRolloutStatus struct {
+	// PreviousAMIs are the AMIs resolved before the rollout started. New nodes which aren't selected for the current
+	// AMIs are launched with them.
+	// +required
+	PreviousAMIs []AMI `json:"previousAMIs"`
+	// StartTime is the time at which the current AMIs were resolved
+	// +required
+	StartTime metav1.Time `json:"startTime"`
+	// Percentage is the current percentage of new nodes launched with the current AMIs
+	// +optional
+	Percentage int32 `json:"percentage,omitempty"`
+	// LaunchedNodes is the number of nodes launched with the current AMIs since the rollout started
+	// +optional
+	LaunchedNodes int32 `json:"launchedNodes,omitempty"`
+}
+
+// AccountDefaults contains the EC2 account-level defaults for the region which apply to instances where the EC2NodeClass
+// leaves the corresponding setting unset. Each field is only set when Karpenter is authorized to read the setting and
+// the account sets a default.
+type AccountDefaults struct {
+	// EBSEncryptionByDefault is whether new EBS volumes are encrypted by default
+	// +optional
+	EBSEncryptionByDefault *bool `json:"ebsEncryptionByDefault,omitempty"`
+	// EBSDefaultKMSKeyID is the KMS key which encrypts new EBS volumes by default
+	// +optional
+	EBSDefaultKMSKeyID *string `json:"ebsDefaultKMSKeyID,omitempty"`
+	// HTTPEndpoint is the default state of the instance metadata service endpoint
+	// +optional
+	HTTPEndpoint *string `json:"httpEndpoint,omitempty"`
+	// HTTPPutResponseHopLimit is the default hop limit of instance metadata PUT responses
+	// +optional
+	HTTPPutResponseHopLimit *int64 `json:"httpPutResponseHopLimit,omitempty"`
+	// HTTPTokens is the default state of token usage for instance metadata requests
+	// +optional
+	HTTPTokens *string `json:"httpTokens,omitempty"`
+	// SerialConsoleAccessEnabled is whether access to the EC2 serial console is enabled for the account
+	// +optional
+	SerialConsoleAccessEnabled *bool `json:"serialConsoleAccessEnabled,omitempty"`
+}
+
 // EC2NodeClassStatus contains the resolved state of the EC2NodeClass
 type EC2NodeClassStatus struct {
 	// Subnets contains the current subnet values that are available to the
@@ -107,6 +197,9 @@ type EC2NodeClassStatus struct {
 	// cluster under the SecurityGroups selectors.
 	// +optional
 	SecurityGroups []SecurityGroup `json:"securityGroups,omitempty"`
+	// NetworkInterfaces contains the subnets and security groups of the additional network interfaces
+	// +optional
+	NetworkInterfaces []NetworkInterfaceStatus `json:"networkInterfaces,omitempty"`
 	// CapacityReservations contains the current capacity reservation values that are available to this NodeClass under the
 	// CapacityReservation selectors.
 	// +optional
@@ -115,9 +208,15 @@ type EC2NodeClassStatus struct {
 	// cluster under the AMI selectors.
 	// +optional
 	AMIs []AMI `json:"amis,omitempty"`
+	// AMIRollout contains the progress of the rollout onto the current AMIs, if one is in progress
+	// +optional
+	AMIRollout *AMIRolloutStatus `json:"amiRollout,omitempty"`
 	// InstanceProfile contains the resolved instance profile for the role
 	// +optional
 	InstanceProfile string `json:"instanceProfile,omitempty"`
+	// AccountDefaults contains the EC2 account-level defaults which apply where the EC2NodeClass leaves a setting unset
+	// +optional
+	AccountDefaults *AccountDefaults `json:"accountDefaults,omitempty"`
 	// Conditions contains signals for health and readiness
 	// +optional
 	Conditions []status.Condition `json:"conditions,omitempty"`
@@ -134,6 +233,9 @@ func (in *EC2NodeClass) StatusConditions() status.ConditionSet {
 	if CapacityReservationsEnabled {
 		conds = append(conds, ConditionTypeCapacityReservationsReady)
 	}
+	if len(in.Spec.NetworkInterfaces) != 0 {
+		conds = append(conds, ConditionTypeNetworkInterfacesReady)
+	}
 	return status.NewReadyConditions(conds...).For(in)
 }
 
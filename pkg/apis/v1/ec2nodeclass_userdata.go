@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/samber/lo"
+	"sigs.k8s.io/yaml"
+)
+
+// normalizeUserData returns the userData with the changes that don't change what the node runs normalized away. The
+// parts of MIME multi-part documents are normalized individually, keeping only their order and content types. TOML
+// documents and NodeConfig parts are normalized to JSON, which ignores comments and the order of keys, and any other
+// documents are normalized as scripts.
+func normalizeUserData(userData *string, amiFamily string) *string {
+	if userData == nil {
+		return nil
+	}
+	if parts, err := userDataParts(*userData); err == nil {
+		return lo.ToPtr(strings.Join(lo.Map(parts, func(p userDataPart, _ int) string {
+			return fmt.Sprintf("Content-Type: %s\n\n%s", p.contentType, p.normalize())
+		}), "\n--\n"))
+	}
+	if amiFamily == AMIFamilyBottlerocket {
+		return lo.ToPtr(normalizeTOML(*userData))
+	}
+	return lo.ToPtr(normalizeScript(*userData))
+}
+
+type userDataPart struct {
+	contentType string
+	content     string
+}
+
+func (p userDataPart) normalize() string {
+	if p.contentType == "application/node.eks.aws" {
+		return normalizeYAML(p.content)
+	}
+	return normalizeScript(p.content)
+}
+
+// userDataParts returns the parts of a MIME multi-part document, with their media types stripped of parameters
+func userDataParts(userData string) ([]userDataPart, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(userData))
+	if err != nil {
+		return nil, err
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("media type %q is not multi-part", mediaType)
+	}
+	var parts []userDataPart
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			contentType = part.Header.Get("Content-Type")
+		}
+		parts = append(parts, userDataPart{contentType: contentType, content: string(content)})
+	}
+}
+
+// normalizeScript removes blank lines, trailing whitespace, and comment lines. The first line is always kept, since
+// it's often a directive such as a shebang or "#cloud-config".
+func normalizeScript(script string) string {
+	var lines []string
+	for i, line := range strings.Split(strings.ReplaceAll(script, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" || (i > 0 && strings.HasPrefix(strings.TrimSpace(line), "#")) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// normalizeTOML returns the document as JSON, falling back to normalizing it as a script if it can't be parsed
+func normalizeTOML(document string) string {
+	var values map[string]any
+	if err := toml.Unmarshal([]byte(document), &values); err != nil {
+		return normalizeScript(document)
+	}
+	normalized, err := json.Marshal(values)
+	if err != nil {
+		return normalizeScript(document)
+	}
+	return string(normalized)
+}
+
+// normalizeYAML returns the document as JSON, falling back to normalizing it as a script if it can't be parsed or
+// holds multiple YAML documents
+func normalizeYAML(document string) string {
+	if strings.Contains(strings.TrimPrefix(strings.TrimSpace(document), "---"), "\n---") {
+		return normalizeScript(document)
+	}
+	normalized, err := yaml.YAMLToJSON([]byte(document))
+	if err != nil {
+		return normalizeScript(document)
+	}
+	return string(normalized)
+}
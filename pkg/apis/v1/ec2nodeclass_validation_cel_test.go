@@ -22,8 +22,10 @@ import (
 	"github.com/imdario/mergo"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/test"
 
@@ -87,7 +89,7 @@ var _ = Describe("CEL/Validation", func() {
 		})
 	})
 	Context("AMIFamily", func() {
-		amiFamilies := []string{v1.AMIFamilyAL2, v1.AMIFamilyAL2023, v1.AMIFamilyBottlerocket, v1.AMIFamilyWindows2019, v1.AMIFamilyWindows2022, v1.AMIFamilyCustom}
+		amiFamilies := []string{v1.AMIFamilyAL2, v1.AMIFamilyAL2023, v1.AMIFamilyBottlerocket, v1.AMIFamilyFlatcar, v1.AMIFamilyTalos, v1.AMIFamilyUbuntu, v1.AMIFamilyWindows2019, v1.AMIFamilyWindows2022, v1.AMIFamilyCustom}
 		DescribeTable("should succeed with valid families", func() []interface{} {
 			f := func(amiFamily string) {
 				// Set a custom AMI family so it's compatible with all ami family types
@@ -100,10 +102,10 @@ var _ = Describe("CEL/Validation", func() {
 			})
 			return append([]interface{}{f}, entries...)
 		}()...)
-		It("should fail with the ubuntu family", func() {
+		It("should fail with an unsupported family", func() {
 			// Set a custom AMI family so it's compatible with all ami family types
 			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-0123456789abcdef"}}
-			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyUbuntu)
+			nc.Spec.AMIFamily = lo.ToPtr("Debian")
 			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
 		})
 		DescribeTable("should succeed when the amiFamily matches amiSelectorTerms[].alias", func() []interface{} {
@@ -209,6 +211,735 @@ var _ = Describe("CEL/Validation", func() {
 				"karpenter.sh/nodeclaim": "test",
 			}
 			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+			nc.Spec.Tags = map[string]string{
+				v1.NodeAccessTagKey: "test",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("BottlerocketSettings", func() {
+		BeforeEach(func() {
+			nc.Spec.BottlerocketSettings = &v1.BottlerocketSettings{JSON: apiextensionsv1.JSON{Raw: []byte(`{"kernel":{"sysctl":{"net.core.somaxconn":"1024"}}}`)}}
+		})
+		It("should succeed with a Bottlerocket alias", func() {
+			nc.Spec.AMIFamily = nil
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+			Expect(env.Client.Get(ctx, client.ObjectKeyFromObject(nc), nc)).To(Succeed())
+			Expect(string(nc.Spec.BottlerocketSettings.Raw)).To(Equal(`{"kernel":{"sysctl":{"net.core.somaxconn":"1024"}}}`))
+		})
+		It("should succeed with the Bottlerocket AMI family", func() {
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyBottlerocket)
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with other AMI families", func() {
+			nc.Spec.AMIFamily = nil
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with the Custom AMI family", func() {
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("NodeConfig", func() {
+		BeforeEach(func() {
+			nc.Spec.AMIFamily = nil
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			nc.Spec.NodeConfig = &v1.NodeConfig{
+				Kubelet: &v1.NodeConfigKubelet{
+					Config: map[string]apiextensionsv1.JSON{"shutdownGracePeriod": {Raw: []byte(`"30s"`)}},
+					Flags:  []string{"--v=4"},
+				},
+				Containerd: &v1.NodeConfigContainerd{Config: "version = 2\n"},
+			}
+		})
+		It("should succeed with an AL2023 alias", func() {
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with the AL2023 AMI family", func() {
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyAL2023)
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with other AMI families", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when a kubelet flag doesn't start with '--'", func() {
+			nc.Spec.NodeConfig.Kubelet.Flags = []string{"v=4"}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when setting node labels with kubelet flags", func() {
+			nc.Spec.NodeConfig.Kubelet.Flags = []string{"--node-labels=foo=bar"}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an unknown local storage strategy", func() {
+			nc.Spec.NodeConfig.Instance = &v1.NodeConfigInstance{LocalStorage: &v1.NodeConfigLocalStorage{Strategy: "RAID10"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when local storage is set along with instanceStorePolicy", func() {
+			nc.Spec.NodeConfig.Instance = &v1.NodeConfigInstance{LocalStorage: &v1.NodeConfigLocalStorage{Strategy: "Mount"}}
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyRAID0)
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("KubeletConfigDropIns", func() {
+		BeforeEach(func() {
+			nc.Spec.AMIFamily = nil
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			nc.Spec.KubeletConfigDropIns = []v1.KubeletConfigDropIn{
+				{Name: "logs", Config: map[string]apiextensionsv1.JSON{"containerLogMaxSize": {Raw: []byte(`"50Mi"`)}}},
+			}
+		})
+		It("should succeed with an AL2023 alias", func() {
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a Bottlerocket alias", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with other AMI families", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2@latest"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with duplicate names", func() {
+			nc.Spec.KubeletConfigDropIns = append(nc.Spec.KubeletConfigDropIns, nc.Spec.KubeletConfigDropIns[0])
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with a name that can't be used in a file name", func() {
+			nc.Spec.KubeletConfigDropIns[0].Name = "../logs"
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("NameTagTemplate", func() {
+		It("should succeed with a name tag template", func() {
+			nc.Spec.NameTagTemplate = lo.ToPtr("{{ .NodePool }}-{{ .Zone }}")
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail if the name tag template is set along with a Name tag", func() {
+			nc.Spec.NameTagTemplate = lo.ToPtr("{{ .NodePool }}-{{ .Zone }}")
+			nc.Spec.Tags = map[string]string{v1.NameTagKey: "node"}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("IPv6", func() {
+		It("should succeed when assigning IPv6 addresses and prefixes", func() {
+			nc.Spec.IPv6 = &v1.IPv6{AddressCount: lo.ToPtr[int32](2), PrefixCount: lo.ToPtr[int32](1)}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when assigning no IPv6 addresses", func() {
+			nc.Spec.IPv6 = &v1.IPv6{AddressCount: lo.ToPtr[int32](0)}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail when assigning too many IPv6 prefixes", func() {
+			nc.Spec.IPv6 = &v1.IPv6{PrefixCount: lo.ToPtr[int32](51)}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("PrivateDNSNameOptions", func() {
+		It("should succeed with a valid hostname type", func() {
+			nc.Spec.PrivateDNSNameOptions = &v1.PrivateDNSNameOptions{HostnameType: lo.ToPtr("resource-name")}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed when enabling resource-name DNS records", func() {
+			nc.Spec.PrivateDNSNameOptions = &v1.PrivateDNSNameOptions{
+				HostnameType:                    lo.ToPtr("resource-name"),
+				EnableResourceNameDNSARecord:    lo.ToPtr(true),
+				EnableResourceNameDNSAAAARecord: lo.ToPtr(true),
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an invalid hostname type", func() {
+			nc.Spec.PrivateDNSNameOptions = &v1.PrivateDNSNameOptions{HostnameType: lo.ToPtr("instance-id")}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("CPUOptions", func() {
+		It("should succeed when disabling multithreading", func() {
+			nc.Spec.CPUOptions = &v1.CPUOptions{ThreadsPerCore: lo.ToPtr[int32](1)}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an unsupported number of threads per core", func() {
+			nc.Spec.CPUOptions = &v1.CPUOptions{ThreadsPerCore: lo.ToPtr[int32](4)}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("BootOptions", func() {
+		It("should succeed when requiring UEFI and NitroTPM with measured boot PCRs", func() {
+			nc.Spec.BootOptions = &v1.BootOptions{
+				BootMode:         lo.ToPtr(v1.BootModeUEFI),
+				NitroTPM:         lo.ToPtr(true),
+				MeasuredBootPCRs: map[string]v1.PCRDigest{"0": v1.PCRDigest(strings.Repeat("a", 64)), "23": v1.PCRDigest(strings.Repeat("b", 96))},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an unsupported boot mode", func() {
+			nc.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr("legacy-bios")}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail when requiring NitroTPM without the uefi boot mode", func() {
+			nc.Spec.BootOptions = &v1.BootOptions{NitroTPM: lo.ToPtr(true)}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail when setting measured boot PCRs without NitroTPM", func() {
+			nc.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI), MeasuredBootPCRs: map[string]v1.PCRDigest{"0": v1.PCRDigest(strings.Repeat("a", 64))}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with a register index out of range", func() {
+			nc.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI), NitroTPM: lo.ToPtr(true), MeasuredBootPCRs: map[string]v1.PCRDigest{"24": v1.PCRDigest(strings.Repeat("a", 64))}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with a value which isn't a SHA-256 or SHA-384 digest", func() {
+			nc.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI), NitroTPM: lo.ToPtr(true), MeasuredBootPCRs: map[string]v1.PCRDigest{"0": v1.PCRDigest(strings.Repeat("a", 40))}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("RegistryMirrors", func() {
+		BeforeEach(func() {
+			nc.Spec.RegistryMirrors = []v1.RegistryMirror{{
+				Registry:              "docker.io",
+				Endpoints:             []string{"https://mirror.example.com", "http://mirror.internal:5000"},
+				CredentialsSecretName: lo.ToPtr("mirror-credentials"),
+			}}
+		})
+		It("should succeed with valid registry mirrors", func() {
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a registry that has a port", func() {
+			nc.Spec.RegistryMirrors[0].Registry = "registry.internal:5000"
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with AMI families that don't support registry mirrors", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with duplicate registries", func() {
+			nc.Spec.RegistryMirrors = append(nc.Spec.RegistryMirrors, v1.RegistryMirror{Registry: "docker.io", Endpoints: []string{"https://other.example.com"}})
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with an endpoint that isn't a URL", func() {
+			nc.Spec.RegistryMirrors[0].Endpoints = []string{"mirror.example.com"}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail without endpoints", func() {
+			nc.Spec.RegistryMirrors[0].Endpoints = nil
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with an invalid registry", func() {
+			nc.Spec.RegistryMirrors[0].Registry = "https://docker.io"
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("Proxy", func() {
+		BeforeEach(func() {
+			nc.Spec.Proxy = &v1.Proxy{
+				HTTPProxy:  lo.ToPtr("http://proxy.example.com:3128"),
+				HTTPSProxy: lo.ToPtr("http://proxy.example.com:3128"),
+				NoProxy:    []string{".example.com", "10.0.0.0/8"},
+			}
+		})
+		It("should succeed with a valid proxy", func() {
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail without httpProxy or httpsProxy", func() {
+			nc.Spec.Proxy = &v1.Proxy{NoProxy: []string{".example.com"}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with a proxy that isn't a URL", func() {
+			nc.Spec.Proxy.HTTPSProxy = lo.ToPtr("proxy.example.com:3128")
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with a noProxy entry that contains a comma", func() {
+			nc.Spec.Proxy.NoProxy = []string{".example.com,.example.org"}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with AMI families that don't support a proxy", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should succeed with different httpProxy and httpsProxy for AL2023", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			nc.Spec.Proxy.HTTPSProxy = lo.ToPtr("http://other-proxy.example.com:3128")
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with different httpProxy and httpsProxy for Bottlerocket", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			nc.Spec.Proxy.HTTPSProxy = lo.ToPtr("http://other-proxy.example.com:3128")
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("TrustedCABundle", func() {
+		It("should succeed with an inline bundle", func() {
+			nc.Spec.TrustedCABundle = &v1.TrustedCABundle{PEM: lo.ToPtr("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n")}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a ConfigMap", func() {
+			nc.Spec.TrustedCABundle = &v1.TrustedCABundle{ConfigMapName: lo.ToPtr("trusted-ca-bundle")}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with both an inline bundle and a ConfigMap", func() {
+			nc.Spec.TrustedCABundle = &v1.TrustedCABundle{
+				PEM:           lo.ToPtr("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"),
+				ConfigMapName: lo.ToPtr("trusted-ca-bundle"),
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail without an inline bundle or a ConfigMap", func() {
+			nc.Spec.TrustedCABundle = &v1.TrustedCABundle{}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with an inline bundle that doesn't contain a certificate", func() {
+			nc.Spec.TrustedCABundle = &v1.TrustedCABundle{PEM: lo.ToPtr("not a certificate")}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with AMI families that don't support a trusted CA bundle", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+			nc.Spec.TrustedCABundle = &v1.TrustedCABundle{ConfigMapName: lo.ToPtr("trusted-ca-bundle")}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("BootstrapMode", func() {
+		BeforeEach(func() {
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+		})
+		It("should succeed with the Nodeadm bootstrap mode with the Custom AMI family", func() {
+			nc.Spec.BootstrapMode = lo.ToPtr(v1.BootstrapModeNodeadm)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with nodeadm settings with the Nodeadm bootstrap mode", func() {
+			nc.Spec.BootstrapMode = lo.ToPtr(v1.BootstrapModeNodeadm)
+			nc.Spec.NodeConfig = &v1.NodeConfig{Kubelet: &v1.NodeConfigKubelet{Flags: []string{"--v=4"}}}
+			nc.Spec.KubeletConfigDropIns = []v1.KubeletConfigDropIn{
+				{Name: "logging", Config: map[string]apiextensionsv1.JSON{"logging": {Raw: []byte(`{"verbosity":4}`)}}},
+			}
+			nc.Spec.Swap = &v1.Swap{Source: v1.SwapSourceInstanceStore}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with nodeadm settings with the UserData bootstrap mode", func() {
+			nc.Spec.BootstrapMode = lo.ToPtr(v1.BootstrapModeUserData)
+			nc.Spec.NodeConfig = &v1.NodeConfig{Kubelet: &v1.NodeConfigKubelet{Flags: []string{"--v=4"}}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with an unknown bootstrap mode", func() {
+			nc.Spec.BootstrapMode = lo.ToPtr(v1.BootstrapMode("Script"))
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with AMI families other than Custom", func() {
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyAL2023)
+			nc.Spec.BootstrapMode = lo.ToPtr(v1.BootstrapModeNodeadm)
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("Swap", func() {
+		It("should succeed with a swapfile", func() {
+			nc.Spec.Swap = &v1.Swap{Source: v1.SwapSourceSwapfile, Size: lo.ToPtr(resource.MustParse("4Gi")), SwapBehavior: lo.ToPtr(v1.SwapBehaviorLimitedSwap)}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with instance store swap", func() {
+			nc.Spec.Swap = &v1.Swap{Source: v1.SwapSourceInstanceStore, SwapBehavior: lo.ToPtr(v1.SwapBehaviorNoSwap)}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with a swapfile without a size", func() {
+			nc.Spec.Swap = &v1.Swap{Source: v1.SwapSourceSwapfile}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with instance store swap with a size", func() {
+			nc.Spec.Swap = &v1.Swap{Source: v1.SwapSourceInstanceStore, Size: lo.ToPtr(resource.MustParse("4Gi"))}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with an unknown swap behavior", func() {
+			nc.Spec.Swap = &v1.Swap{Source: v1.SwapSourceInstanceStore, SwapBehavior: lo.ToPtr(v1.SwapBehavior("UnlimitedSwap"))}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with instance store swap along with an instanceStorePolicy", func() {
+			nc.Spec.Swap = &v1.Swap{Source: v1.SwapSourceInstanceStore}
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyRAID0)
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with AMI families other than AL2023", func() {
+			nc.Spec.AMIFamily = &v1.AMIFamilyBottlerocket
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			nc.Spec.Swap = &v1.Swap{Source: v1.SwapSourceInstanceStore}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("DeviceValidation", func() {
+		It("should succeed with each kind of device", func() {
+			nc.Spec.DeviceValidation = &v1.DeviceValidation{
+				Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceGPU, v1.DeviceValidationDeviceEFA, v1.DeviceValidationDeviceNVMe},
+				Timeout: &metav1.Duration{Duration: 10 * time.Minute},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail without devices", func() {
+			nc.Spec.DeviceValidation = &v1.DeviceValidation{}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with duplicate devices", func() {
+			nc.Spec.DeviceValidation = &v1.DeviceValidation{
+				Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceNVMe, v1.DeviceValidationDeviceGPU, v1.DeviceValidationDeviceNVMe},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		DescribeTable("should fail with devices that aren't a kind of device", func(device string) {
+			nc.Spec.DeviceValidation = &v1.DeviceValidation{Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDevice(device)}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		},
+			Entry("a device path", "/dev/nvme1n1"),
+			Entry("a device path prefix", "/dev/"),
+			Entry("a device name", "nvidia0"),
+			Entry("a lowercase kind", "gpu"),
+		)
+		It("should fail with a fractional timeout", func() {
+			nc.Spec.DeviceValidation = &v1.DeviceValidation{
+				Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceGPU},
+				Timeout: &metav1.Duration{Duration: 1500 * time.Millisecond},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		DescribeTable("should succeed with supported AMI families", func(alias string) {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: alias}}
+			nc.Spec.AMIFamily = nil
+			nc.Spec.DeviceValidation = &v1.DeviceValidation{Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceGPU}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		},
+			Entry("AL2", "al2@latest"),
+			Entry("AL2023", "al2023@latest"),
+			Entry("Ubuntu", "ubuntu@latest"),
+		)
+		DescribeTable("should fail with AMI families which don't generate the device validation", func(alias string, amiFamily *string) {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: alias}}
+			nc.Spec.AMIFamily = amiFamily
+			nc.Spec.DeviceValidation = &v1.DeviceValidation{Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceGPU}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		},
+			Entry("Bottlerocket", "bottlerocket@latest", nil),
+			Entry("Windows2022", "windows2022@latest", nil),
+			Entry("Custom", "al2023@latest", &v1.AMIFamilyCustom),
+		)
+	})
+	Context("ExtendedResources", func() {
+		It("should succeed with extended resources selected by instance family or type", func() {
+			nc.Spec.ExtendedResources = []v1.ExtendedResource{
+				{InstanceFamilies: []string{"trn1"}, Resources: corev1.ResourceList{"example.com/asic": resource.MustParse("4")}},
+				{InstanceTypes: []string{"trn1.32xlarge"}, Resources: corev1.ResourceList{"example.com/asic": resource.MustParse("16")}},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail without an instance family or type", func() {
+			nc.Spec.ExtendedResources = []v1.ExtendedResource{
+				{Resources: corev1.ResourceList{"example.com/asic": resource.MustParse("4")}},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail without resources", func() {
+			nc.Spec.ExtendedResources = []v1.ExtendedResource{
+				{InstanceFamilies: []string{"trn1"}, Resources: corev1.ResourceList{}},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with an instance type that isn't qualified by its family", func() {
+			nc.Spec.ExtendedResources = []v1.ExtendedResource{
+				{InstanceTypes: []string{"32xlarge"}, Resources: corev1.ResourceList{"example.com/asic": resource.MustParse("4")}},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		DescribeTable("should fail with resources that aren't extended resources", func(name string) {
+			nc.Spec.ExtendedResources = []v1.ExtendedResource{
+				{InstanceFamilies: []string{"trn1"}, Resources: corev1.ResourceList{corev1.ResourceName(name): resource.MustParse("4")}},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		},
+			Entry("cpu", "cpu"),
+			Entry("kubernetes.io", "kubernetes.io/asic"),
+			Entry("kubernetes.io subdomain", "node.kubernetes.io/asic"),
+		)
+	})
+	Context("AMIVariants", func() {
+		BeforeEach(func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+		})
+		It("should succeed with valid variants", func() {
+			nc.Spec.AMIVariants = []v1.AMIVariant{v1.AMIVariantStandard, v1.AMIVariantNvidiaOpen, v1.AMIVariantNeuron}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an unknown variant", func() {
+			nc.Spec.AMIVariants = []v1.AMIVariant{"cuda"}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with both nvidia variants", func() {
+			nc.Spec.AMIVariants = []v1.AMIVariant{v1.AMIVariantNvidia, v1.AMIVariantNvidiaOpen}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail without an alias", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyAL2023)
+			nc.Spec.AMIVariants = []v1.AMIVariant{v1.AMIVariantStandard}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("FIPS", func() {
+		It("should succeed with an AL2023 alias", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			nc.Spec.FIPS = lo.ToPtr(true)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a Bottlerocket alias", func() {
+			nc.Spec.AMIFamily = nil
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			nc.Spec.FIPS = lo.ToPtr(true)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an alias whose family doesn't publish FIPS AMIs", func() {
+			nc.Spec.AMIFamily = nil
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2@latest"}}
+			nc.Spec.FIPS = lo.ToPtr(true)
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail without an alias", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyAL2023)
+			nc.Spec.FIPS = lo.ToPtr(true)
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should succeed when disabled without an alias", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-01234567890abcdef"}}
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyAL2023)
+			nc.Spec.FIPS = lo.ToPtr(false)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+	})
+	Context("FleetOptions", func() {
+		It("should succeed with a valid spot allocation strategy and instance type priority", func() {
+			nc.Spec.FleetOptions = &v1.FleetOptions{
+				SpotAllocationStrategy: lo.ToPtr("capacity-optimized-prioritized"),
+				InstanceTypePriority:   []string{"m5.large", "m5.xlarge"},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an invalid spot allocation strategy", func() {
+			nc.Spec.FleetOptions = &v1.FleetOptions{SpotAllocationStrategy: lo.ToPtr("cheapest")}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with duplicate instance types in the priority", func() {
+			nc.Spec.FleetOptions = &v1.FleetOptions{InstanceTypePriority: []string{"m5.large", "m5.large"}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should succeed with an architecture priority", func() {
+			nc.Spec.FleetOptions = &v1.FleetOptions{
+				ArchitecturePriority:              []string{karpv1.ArchitectureArm64, karpv1.ArchitectureAmd64},
+				ArchitecturePriceTolerancePercent: lo.ToPtr[int32](15),
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an unknown architecture in the priority", func() {
+			nc.Spec.FleetOptions = &v1.FleetOptions{ArchitecturePriority: []string{"s390x"}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with duplicate architectures in the priority", func() {
+			nc.Spec.FleetOptions = &v1.FleetOptions{ArchitecturePriority: []string{karpv1.ArchitectureArm64, karpv1.ArchitectureArm64}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with a price tolerance over 100 percent", func() {
+			nc.Spec.FleetOptions = &v1.FleetOptions{ArchitecturePriority: []string{karpv1.ArchitectureArm64}, ArchitecturePriceTolerancePercent: lo.ToPtr[int32](101)}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("AMIRollout", func() {
+		It("should succeed with a valid rollout", func() {
+			nc.Spec.AMIRollout = &v1.AMIRollout{
+				Duration:          metav1.Duration{Duration: 6 * time.Hour},
+				InitialPercentage: lo.ToPtr[int32](25),
+				MaxNodes:          lo.ToPtr[int32](10),
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+			Expect(lo.FromPtr(nc.Spec.AMIRollout.InitialPercentage)).To(BeNumerically("==", 25))
+		})
+		It("should default the initial percentage", func() {
+			nc.Spec.AMIRollout = &v1.AMIRollout{Duration: metav1.Duration{Duration: time.Hour}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+			Expect(lo.FromPtr(nc.Spec.AMIRollout.InitialPercentage)).To(BeNumerically("==", 10))
+		})
+		It("should fail with an initial percentage above 100", func() {
+			nc.Spec.AMIRollout = &v1.AMIRollout{Duration: metav1.Duration{Duration: time.Hour}, InitialPercentage: lo.ToPtr[int32](101)}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with a max nodes below 1", func() {
+			nc.Spec.AMIRollout = &v1.AMIRollout{Duration: metav1.Duration{Duration: time.Hour}, MaxNodes: lo.ToPtr[int32](0)}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("AMIMaintenanceWindows", func() {
+		It("should succeed with valid maintenance windows", func() {
+			nc.Spec.AMIMaintenanceWindows = []v1.MaintenanceWindow{
+				{Schedule: "0 2 * * sun", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+				{Schedule: "@daily", Duration: metav1.Duration{Duration: 30 * time.Minute}},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an invalid schedule", func() {
+			nc.Spec.AMIMaintenanceWindows = []v1.MaintenanceWindow{{Schedule: "@every-other-day", Duration: metav1.Duration{Duration: time.Hour}}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+		It("should fail with a duration in seconds", func() {
+			nc.Spec.AMIMaintenanceWindows = []v1.MaintenanceWindow{{Schedule: "@daily", Duration: metav1.Duration{Duration: 30 * time.Second}}}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		})
+	})
+	Context("AMIDeprecationThreshold", func() {
+		It("should succeed with a valid threshold", func() {
+			nc.Spec.AMIDeprecationThreshold = &metav1.Duration{Duration: 168 * time.Hour}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a threshold of zero", func() {
+			nc.Spec.AMIDeprecationThreshold = &metav1.Duration{}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+	})
+	Context("Labels", func() {
+		It("should succeed if labels aren't in restricted label domains", func() {
+			nc.Spec.Labels = map[string]string{
+				"network-tier":                         "public",
+				"example.com/team":                     "platform",
+				"node.kubernetes.io/exclude-from-lb":   "true",
+				"node-restriction.kubernetes.io/stage": "prod",
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		DescribeTable("should fail if labels contain a restricted label domain", func(key string) {
+			nc.Spec.Labels = map[string]string{key: "value"}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		},
+			Entry("kubernetes.io", "kubernetes.io/hostname"),
+			Entry("k8s.io", "k8s.io/custom"),
+			Entry("karpenter.sh", karpv1.NodePoolLabelKey),
+			Entry("karpenter.k8s.aws", v1.LabelInstanceFamily),
+		)
+	})
+	Context("MinSubnetAvailableIPAddresses", func() {
+		It("should succeed with a minimum number of available IP addresses", func() {
+			nc.Spec.MinSubnetAvailableIPAddresses = lo.ToPtr[int32](32)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with a negative minimum number of available IP addresses", func() {
+			nc.Spec.MinSubnetAvailableIPAddresses = lo.ToPtr[int32](-1)
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("EFA", func() {
+		It("should succeed with an EFA count and EFA-only interfaces", func() {
+			nc.Spec.EFA = &v1.EFA{Count: lo.ToPtr[int32](4), EFAOnly: lo.ToPtr(true)}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when the EFA count is zero", func() {
+			nc.Spec.EFA = &v1.EFA{Count: lo.ToPtr[int32](0)}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when the EFA count is greater than the most network cards an instance can have", func() {
+			nc.Spec.EFA = &v1.EFA{Count: lo.ToPtr[int32](33)}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("ElasticIP", func() {
+		It("should succeed without selector terms", func() {
+			nc.Spec.ElasticIP = &v1.ElasticIP{}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with valid selector terms", func() {
+			nc.Spec.ElasticIP = &v1.ElasticIP{SelectorTerms: []v1.ElasticIPSelectorTerm{
+				{Tags: map[string]string{"pool": "egress"}},
+				{ID: "eipalloc-12345749"},
+			}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with an empty selector term", func() {
+			nc.Spec.ElasticIP = &v1.ElasticIP{SelectorTerms: []v1.ElasticIPSelectorTerm{{}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when a selector term has both an id and tags", func() {
+			nc.Spec.ElasticIP = &v1.ElasticIP{SelectorTerms: []v1.ElasticIPSelectorTerm{
+				{ID: "eipalloc-12345749", Tags: map[string]string{"pool": "egress"}},
+			}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an invalid allocation id", func() {
+			nc.Spec.ElasticIP = &v1.ElasticIP{SelectorTerms: []v1.ElasticIPSelectorTerm{{ID: "12345749"}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("NetworkInterfaces", func() {
+		It("should succeed with valid network interfaces", func() {
+			nc.Spec.NetworkInterfaces = []v1.NetworkInterface{
+				{
+					DeviceIndex:         1,
+					Count:               lo.ToPtr[int32](2),
+					SubnetSelectorTerms: []v1.SubnetSelectorTerm{{Tags: map[string]string{"role": "data-plane"}}},
+				},
+				{
+					DeviceIndex:                3,
+					SubnetSelectorTerms:        []v1.SubnetSelectorTerm{{ID: "subnet-12345749"}},
+					SecurityGroupSelectorTerms: []v1.SecurityGroupSelectorTerm{{Name: "data-plane", VPCID: "vpc-12345749"}},
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when the device index is the primary network interface's", func() {
+			nc.Spec.NetworkInterfaces = []v1.NetworkInterface{
+				{DeviceIndex: 0, SubnetSelectorTerms: []v1.SubnetSelectorTerm{{ID: "subnet-12345749"}}},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail without subnet selector terms", func() {
+			nc.Spec.NetworkInterfaces = []v1.NetworkInterface{{DeviceIndex: 1}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when network interfaces have the same device index", func() {
+			nc.Spec.NetworkInterfaces = []v1.NetworkInterface{
+				{DeviceIndex: 1, SubnetSelectorTerms: []v1.SubnetSelectorTerm{{ID: "subnet-12345749"}}},
+				{DeviceIndex: 1, SubnetSelectorTerms: []v1.SubnetSelectorTerm{{ID: "subnet-12345749"}}},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when network interfaces overlap in their device indexes", func() {
+			nc.Spec.NetworkInterfaces = []v1.NetworkInterface{
+				{DeviceIndex: 1, Count: lo.ToPtr[int32](2), SubnetSelectorTerms: []v1.SubnetSelectorTerm{{ID: "subnet-12345749"}}},
+				{DeviceIndex: 2, SubnetSelectorTerms: []v1.SubnetSelectorTerm{{Tags: map[string]string{"role": "data-plane"}}}},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("SubnetExclusionTerms", func() {
+		It("should succeed with valid subnet exclusion terms", func() {
+			nc.Spec.SubnetExclusionTerms = []v1.SubnetExclusionTerm{
+				{ID: "subnet-12345749"},
+				{Tags: map[string]string{"role": "firewall"}},
+				{CIDR: "100.64.0.0/16", Tags: map[string]string{"role": "*"}},
+				{CIDR: "2600:1f14:abc::/48"},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when a subnet exclusion term has no fields", func() {
+			nc.Spec.SubnetExclusionTerms = []v1.SubnetExclusionTerm{{}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an invalid id", func() {
+			nc.Spec.SubnetExclusionTerms = []v1.SubnetExclusionTerm{{ID: "sg-12345749"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an invalid cidr", func() {
+			nc.Spec.SubnetExclusionTerms = []v1.SubnetExclusionTerm{{CIDR: "100.64.0.0"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with empty tag keys or values", func() {
+			nc.Spec.SubnetExclusionTerms = []v1.SubnetExclusionTerm{{Tags: map[string]string{"role": ""}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
 		})
 	})
 	Context("SubnetSelectorTerms", func() {
@@ -230,6 +961,32 @@ var _ = Describe("CEL/Validation", func() {
 			}
 			Expect(env.Client.Create(ctx, nc)).To(Succeed())
 		})
+		It("should succeed with a valid subnet selector on cidr", func() {
+			nc.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{
+				{CIDR: "10.42.1.0/24"},
+				{CIDR: "10.42.0.0/16", CIDRMatch: lo.ToPtr(v1.CIDRMatchContains), Tags: map[string]string{"test": "testvalue"}},
+				{CIDR: "2600:1f14:abc::/56", CIDRMatch: lo.ToPtr(v1.CIDRMatchExact)},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		DescribeTable("should fail with an invalid cidr",
+			func(cidr string) {
+				nc.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{CIDR: cidr}}
+				Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+			},
+			Entry("without a prefix length", "10.42.0.0"),
+			Entry("with an invalid prefix length", "10.42.0.0/33"),
+			Entry("with an invalid ipv6 prefix length", "2600:1f14:abc::/129"),
+			Entry("with a hostname", "subnet.example.com/16"),
+		)
+		It("should fail when specifying cidrMatch without cidr", func() {
+			nc.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{Tags: map[string]string{"test": "testvalue"}, CIDRMatch: lo.ToPtr(v1.CIDRMatchContains)}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when specifying id with cidr", func() {
+			nc.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{ID: "subnet-12345749", CIDR: "10.42.1.0/24"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
 		It("should fail when subnet selector terms is set to nil", func() {
 			nc.Spec.SubnetSelectorTerms = nil
 			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
@@ -336,6 +1093,50 @@ var _ = Describe("CEL/Validation", func() {
 			}
 			Expect(env.Client.Create(ctx, nc)).To(Succeed())
 		})
+		It("should succeed with a valid security group selector on name wildcards and vpc id", func() {
+			nc.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{
+				{
+					Name:  "eks-cluster-sg-*",
+					VPCID: "vpc-12345749",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a valid security group selector on tags and vpc id", func() {
+			nc.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{
+				{
+					Tags:  map[string]string{"test": "testvalue"},
+					VPCID: "vpc-12345749",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when a security group selector term only sets a vpc id", func() {
+			nc.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{
+				{
+					VPCID: "vpc-12345749",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when a security group selector term sets an id and a vpc id", func() {
+			nc.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{
+				{
+					ID:    "sg-12345749",
+					VPCID: "vpc-12345749",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an invalid vpc id", func() {
+			nc.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{
+				{
+					Name:  "testname",
+					VPCID: "subnet-12345749",
+				},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
 		It("should fail when security group selector terms is set to nil", func() {
 			nc.Spec.SecurityGroupSelectorTerms = nil
 			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
@@ -678,6 +1479,9 @@ var _ = Describe("CEL/Validation", func() {
 			}),
 			Entry("name", v1.AMISelectorTerm{Name: "my-custom-ami"}),
 			Entry("owner", v1.AMISelectorTerm{Owner: "123456789"}),
+			Entry("newestCount", v1.AMISelectorTerm{NewestCount: lo.ToPtr[int32](2)}),
+			Entry("minAge", v1.AMISelectorTerm{MinAge: &metav1.Duration{Duration: time.Hour}}),
+			Entry("maxAge", v1.AMISelectorTerm{MaxAge: &metav1.Duration{Duration: time.Hour}}),
 		)
 		DescribeTable(
 			"should fail when specifying alias with other fields",
@@ -693,7 +1497,92 @@ var _ = Describe("CEL/Validation", func() {
 			}),
 			Entry("name", v1.AMISelectorTerm{Name: "my-custom-ami"}),
 			Entry("owner", v1.AMISelectorTerm{Owner: "123456789"}),
+			Entry("newestCount", v1.AMISelectorTerm{NewestCount: lo.ToPtr[int32](2)}),
+			Entry("minAge", v1.AMISelectorTerm{MinAge: &metav1.Duration{Duration: time.Hour}}),
+			Entry("maxAge", v1.AMISelectorTerm{MaxAge: &metav1.Duration{Duration: time.Hour}}),
 		)
+		It("should succeed when windowing a term", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{
+				Name:        "my-custom-ami",
+				NewestCount: lo.ToPtr[int32](2),
+				MinAge:      &metav1.Duration{Duration: 7 * 24 * time.Hour},
+				MaxAge:      &metav1.Duration{Duration: 90 * 24 * time.Hour},
+			}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when minAge isn't less than maxAge", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{
+				Name:   "my-custom-ami",
+				MinAge: &metav1.Duration{Duration: 7 * 24 * time.Hour},
+				MaxAge: &metav1.Duration{Duration: 24 * time.Hour},
+			}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when newestCount is zero", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Name: "my-custom-ami", NewestCount: lo.ToPtr[int32](0)}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		DescribeTable(
+			"should succeed with a valid ami selector on imageBuilderARN",
+			func(arn string) {
+				nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ImageBuilderARN: arn}, {ID: "ami-12345749"}}
+				Expect(env.Client.Create(ctx, nc)).To(Succeed())
+			},
+			Entry("pipeline", "arn:aws:imagebuilder:us-west-2:123456789012:image-pipeline/golden-al2023"),
+			Entry("recipe", "arn:aws:imagebuilder:us-west-2:123456789012:image-recipe/golden-al2023/1.0.2"),
+			Entry("partition", "arn:aws-cn:imagebuilder:cn-north-1:123456789012:image-pipeline/golden"),
+		)
+		DescribeTable(
+			"should fail with an invalid imageBuilderARN",
+			func(arn string) {
+				nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ImageBuilderARN: arn}}
+				Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+			},
+			Entry("image", "arn:aws:imagebuilder:us-west-2:123456789012:image/golden/1.0.0/1"),
+			Entry("recipe without version", "arn:aws:imagebuilder:us-west-2:123456789012:image-recipe/golden"),
+			Entry("other service", "arn:aws:ec2:us-west-2:123456789012:image-pipeline/golden"),
+		)
+		DescribeTable(
+			"should succeed with a valid ami selector on ssmParameter",
+			func(parameter string) {
+				nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{SSMParameter: parameter}}
+				Expect(env.Client.Create(ctx, nc)).To(Succeed())
+			},
+			Entry("name", "/golden/al2023/image_id"),
+			Entry("arn", "arn:aws:ssm:us-west-2:111122223333:parameter/golden/al2023/image_id"),
+		)
+		DescribeTable(
+			"should fail with an invalid ssmParameter",
+			func(parameter string) {
+				nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{SSMParameter: parameter}}
+				Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+			},
+			Entry("relative name", "golden/al2023"),
+			Entry("other service", "arn:aws:secretsmanager:us-west-2:111122223333:parameter/golden"),
+		)
+		It("should fail when specifying ssmParameter with other fields in a term", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{SSMParameter: "/golden/al2023", Owner: "111122223333"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should succeed with a valid ami selector on ssmParameterPath", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{SSMParameterPath: "/golden/eks"}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail with a relative ssmParameterPath", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{SSMParameterPath: "golden/eks"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when specifying ssmParameterPath with other fields in a term", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{SSMParameterPath: "/golden/eks", SSMParameter: "/golden/al2023"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when specifying imageBuilderARN with other fields in a term", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{
+				ImageBuilderARN: "arn:aws:imagebuilder:us-west-2:123456789012:image-pipeline/golden",
+				Tags:            map[string]string{"test": "testvalue"},
+			}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
 		It("should fail when specifying alias with other terms", func() {
 			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{
 				{Alias: "al2023@latest"},
@@ -714,6 +1603,10 @@ var _ = Describe("CEL/Validation", func() {
 			Entry("al2023 (pinned)", "al2023@v20240625", v1.AMIFamilyAL2023),
 			Entry("bottlerocket (latest)", "bottlerocket@latest", v1.AMIFamilyBottlerocket),
 			Entry("bottlerocket (pinned)", "bottlerocket@1.10.0", v1.AMIFamilyBottlerocket),
+			Entry("talos (latest)", "talos@latest", v1.AMIFamilyTalos),
+			Entry("talos (pinned)", "talos@v1.7.6", v1.AMIFamilyTalos),
+			Entry("ubuntu (latest)", "ubuntu@latest", v1.AMIFamilyUbuntu),
+			Entry("ubuntu (pinned)", "ubuntu@20240625", v1.AMIFamilyUbuntu),
 			Entry("windows2019 (latest)", "windows2019@latest", v1.AMIFamilyWindows2019),
 			Entry("windows2022 (latest)", "windows2022@latest", v1.AMIFamilyWindows2022),
 		)
@@ -730,7 +1623,7 @@ var _ = Describe("CEL/Validation", func() {
 			Entry("invalid separator", "al2023-latest"),
 		)
 		It("should fail for an alias with an invalid family", func() {
-			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "ubuntu@latest"}}
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "debian@latest"}}
 			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
 		})
 		DescribeTable(
@@ -742,6 +1635,45 @@ var _ = Describe("CEL/Validation", func() {
 			Entry("Windows2019", "windows2019@v1.0.0"),
 			Entry("Windows2022", "windows2022@v1.0.0"),
 		)
+		It("should succeed with a valid ami selector on name pattern", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Name: "amazon-eks-node-1.31-*", NamePattern: `^amazon-eks-node-1\.31-v2024(09|10)[0-9]{2}$`}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when specifying id with name pattern", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-12345749", NamePattern: "^my-ami"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
+	Context("AMIExclusionTerms", func() {
+		BeforeEach(func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Name: "amazon-eks-node-1.31-*"}}
+		})
+		It("should succeed with valid ami exclusion terms", func() {
+			nc.Spec.AMIExclusionTerms = []v1.AMIExclusionTerm{
+				{ID: "ami-12345749"},
+				{Name: "amazon-eks-node-1.31-v20241001"},
+				{NamePattern: "-v202410(0[1-9])$"},
+				{Tags: map[string]string{"quarantined": "*"}},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when an ami exclusion term has no values", func() {
+			nc.Spec.AMIExclusionTerms = []v1.AMIExclusionTerm{{}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when an ami exclusion term has a tag value that is empty", func() {
+			nc.Spec.AMIExclusionTerms = []v1.AMIExclusionTerm{{Tags: map[string]string{"quarantined": ""}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when an ami exclusion term has an invalid id", func() {
+			nc.Spec.AMIExclusionTerms = []v1.AMIExclusionTerm{{ID: "my-ami"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when using an alias ami selector term", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			nc.Spec.AMIExclusionTerms = []v1.AMIExclusionTerm{{ID: "ami-12345749"}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
 	})
 	Context("Kubelet", func() {
 		It("should fail on kubeReserved with invalid keys", func() {
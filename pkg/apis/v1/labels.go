@@ -31,6 +31,9 @@ func init() {
 	karpv1.WellKnownLabels = karpv1.WellKnownLabels.Insert(
 		LabelCapacityReservationID,
 		LabelInstanceHypervisor,
+		LabelInstanceBootMode,
+		LabelInstanceTPMSupported,
+		LabelInstanceENASupport,
 		LabelInstanceEncryptionInTransitSupported,
 		LabelInstanceCategory,
 		LabelInstanceFamily,
@@ -57,6 +60,8 @@ func init() {
 
 var (
 	TerminationFinalizer   = apis.Group + "/termination"
+	DNSRecordFinalizer     = apis.Group + "/dns-record"
+	ElasticIPFinalizer     = apis.Group + "/elastic-ip"
 	AWSToKubeArchitectures = map[string]string{
 		"x86_64":                 karpv1.ArchitectureAmd64,
 		karpv1.ArchitectureArm64: karpv1.ArchitectureArm64,
@@ -76,10 +81,20 @@ var (
 		regexp.MustCompile(fmt.Sprintf("^%s$", regexp.QuoteMeta(EKSClusterNameTagKey))),
 		regexp.MustCompile(fmt.Sprintf("^%s$", regexp.QuoteMeta(NodeClassTagKey))),
 		regexp.MustCompile(fmt.Sprintf("^%s$", regexp.QuoteMeta(NodeClaimTagKey))),
+		regexp.MustCompile(fmt.Sprintf("^%s", regexp.QuoteMeta(NodeAccessTagKey))),
+	}
+	// OwnershipTagKeys are the tag keys which record the Kubernetes resources that an AWS resource belongs to. The domain
+	// of these keys may be replaced with the configured tag key prefix.
+	OwnershipTagKeys = []string{
+		NodePoolTagKey,
+		NodeClassTagKey,
+		NodeClaimTagKey,
 	}
 	AMIFamilyBottlerocket                          = "Bottlerocket"
 	AMIFamilyAL2                                   = "AL2"
 	AMIFamilyAL2023                                = "AL2023"
+	AMIFamilyFlatcar                               = "Flatcar"
+	AMIFamilyTalos                                 = "Talos"
 	AMIFamilyUbuntu                                = "Ubuntu"
 	AMIFamilyWindows2019                           = "Windows2019"
 	AMIFamilyWindows2022                           = "Windows2022"
@@ -100,6 +115,9 @@ var (
 
 	LabelCapacityReservationID                = apis.Group + "/capacity-reservation-id"
 	LabelInstanceHypervisor                   = apis.Group + "/instance-hypervisor"
+	LabelInstanceBootMode                     = apis.Group + "/instance-boot-mode"
+	LabelInstanceTPMSupported                 = apis.Group + "/instance-tpm-supported"
+	LabelInstanceENASupport                   = apis.Group + "/instance-ena-support"
 	LabelInstanceEncryptionInTransitSupported = apis.Group + "/instance-encryption-in-transit-supported"
 	LabelInstanceCategory                     = apis.Group + "/instance-category"
 	LabelInstanceFamily                       = apis.Group + "/instance-family"
@@ -120,13 +138,61 @@ var (
 	LabelInstanceAcceleratorManufacturer      = apis.Group + "/instance-accelerator-manufacturer"
 	LabelInstanceAcceleratorCount             = apis.Group + "/instance-accelerator-count"
 	LabelNodeClass                            = apis.Group + "/ec2nodeclass"
+	// LabelInstanceID and LabelImageID identify a node's instance and the AMI it was launched from. They're unique to
+	// each instance, so they aren't supported as scheduling requirements.
+	LabelInstanceID = apis.Group + "/instance-id"
+	LabelImageID    = apis.Group + "/image-id"
 
 	LabelTopologyZoneID = "topology.k8s.aws/zone-id"
 
 	AnnotationEC2NodeClassHash               = apis.Group + "/ec2nodeclass-hash"
 	AnnotationClusterNameTaggedCompatability = apis.CompatibilityGroup + "/cluster-name-tagged"
+	AnnotationDNSRecordHostedZoneID          = apis.Group + "/dns-record-hosted-zone-id"
+	AnnotationDNSRecordName                  = apis.Group + "/dns-record-name"
+	AnnotationDNSRecordAddresses             = apis.Group + "/dns-record-addresses"
+	AnnotationElasticIPAllocationID          = apis.Group + "/elastic-ip-allocation-id"
+	AnnotationElasticIPPublicIP              = apis.Group + "/elastic-ip-public-ip"
+	AnnotationSpotInstanceRequestID          = apis.Group + "/spot-instance-request-id"
+	AnnotationSpotInstanceRequestState       = apis.Group + "/spot-instance-request-state"
+	AnnotationSpotInstanceRequestStatus      = apis.Group + "/spot-instance-request-status"
 	AnnotationEC2NodeClassHashVersion        = apis.Group + "/ec2nodeclass-hash-version"
 	AnnotationInstanceTagged                 = apis.Group + "/tagged"
+	AnnotationPricingSnapshotTimestamp       = apis.Group + "/pricing-snapshot-timestamp"
+	AnnotationSerialConsole                  = apis.Group + "/serial-console"
+	AnnotationTagKeyPrefix                   = apis.Group + "/tag-key-prefix"
+	AnnotationMeasuredBootPCRs               = apis.Group + "/measured-boot-pcrs"
+	// AnnotationSurgeCapacityUntil is set on a NodePool to relax its instance type and capacity type requirements until
+	// the given RFC3339 time. AnnotationSurgeCapacityRequirements holds the NodePool's original requirements while the
+	// surge is in progress, and is managed by Karpenter.
+	AnnotationSurgeCapacityUntil        = apis.Group + "/surge-capacity-until"
+	AnnotationSurgeCapacityRequirements = apis.Group + "/surge-capacity-requirements"
+	// AnnotationAccruedCost is the estimated cost of a NodeClaim's instance up to AnnotationCostAccruedAt, in the
+	// currency of the region's prices, which is accrued periodically at the prices of the time. AnnotationLifetimeCost
+	// is the estimated cost of the instance's whole lifetime, which is set once the NodeClaim is deleted.
+	AnnotationAccruedCost   = apis.Group + "/accrued-cost"
+	AnnotationCostAccruedAt = apis.Group + "/cost-accrued-at"
+	AnnotationLifetimeCost  = apis.Group + "/lifetime-cost"
+	// AnnotationFallbackNodeClasses is set on a NodePool to a comma-separated list of EC2NodeClasses, which are tried in
+	// order when a NodeClaim can't be launched with the NodePool's nodeClassRef because it isn't ready or has no
+	// available capacity. AnnotationFallbackNodeClass is set on a NodeClaim which was launched with one of them.
+	AnnotationFallbackNodeClasses = apis.Group + "/fallback-ec2nodeclasses"
+	AnnotationFallbackNodeClass   = apis.Group + "/fallback-ec2nodeclass"
+	// AnnotationSpotInterruptedAt is set on a NodeClaim to the RFC3339 time a spot interruption warning was received
+	// for it, when it's drained only once AnnotationInterruptionReplacement, the NodeClaim launched to replace it, has
+	// initialized.
+	AnnotationSpotInterruptedAt       = apis.Group + "/spot-interrupted-at"
+	AnnotationInterruptionReplacement = apis.Group + "/interruption-replacement"
+	// AnnotationSourceDestCheckDisabled is set on a NodeClaim once source/destination checking has been disabled on the
+	// network interfaces of its instance.
+	AnnotationSourceDestCheckDisabled = apis.Group + "/source-dest-check-disabled"
+	// AnnotationRegistrationFailure is set on a NodeClaim which never registered to the likely cause of the failure,
+	// once it has been diagnosed from the console output of its instance.
+	AnnotationRegistrationFailure = apis.Group + "/registration-failure"
+	// AnnotationLaunchTemplateName, AnnotationLaunchTemplateID and AnnotationLaunchTemplateVersion are set on a NodeClaim
+	// to the launch template that its instance was launched from.
+	AnnotationLaunchTemplateName    = apis.Group + "/launch-template-name"
+	AnnotationLaunchTemplateID      = apis.Group + "/launch-template-id"
+	AnnotationLaunchTemplateVersion = apis.Group + "/launch-template-version"
 
 	NodeClaimTagKey          = coreapis.Group + "/nodeclaim"
 	NameTagKey               = "Name"
@@ -134,4 +200,8 @@ var (
 	NodeClassTagKey          = LabelNodeClass
 	LaunchTemplateNamePrefix = apis.Group
 	EKSClusterNameTagKey     = "eks:eks-cluster-name"
+	// NodeAccessTagKey is set on an instance to the principal of the NodeAccessRequest that currently grants access to
+	// it, and NodeAccessRequestTagKey to the request's name. Both are managed by Karpenter.
+	NodeAccessTagKey        = apis.Group + "/node-access"
+	NodeAccessRequestTagKey = apis.Group + "/node-access-request"
 )
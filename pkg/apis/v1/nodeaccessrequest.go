@@ -0,0 +1,121 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"github.com/awslabs/operatorpkg/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionTypeAccessGranted is true while the node's instance is tagged to allow the request's principal to connect
+	ConditionTypeAccessGranted = "AccessGranted"
+
+	NodeAccessReasonAwaitingApproval = "AwaitingApproval"
+	NodeAccessReasonNodeNotFound     = "NodeNotFound"
+	NodeAccessReasonNodeNotManaged   = "NodeNotManaged"
+	NodeAccessReasonConflict         = "Conflict"
+	NodeAccessReasonGranted          = "Granted"
+	NodeAccessReasonExpired          = "Expired"
+)
+
+// NodeAccessRequestSpec describes the node that access is requested for, who it's for, and for how long
+// +kubebuilder:validation:XValidation:message="spec is immutable",rule="self == oldSelf"
+type NodeAccessRequestSpec struct {
+	// NodeName is the name of the Karpenter managed node to grant access to
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	NodeName string `json:"nodeName"`
+	// Principal identifies who may connect to the node. It's written as the value of the karpenter.k8s.aws/node-access
+	// tag on the node's instance, so that IAM policies for EC2 Instance Connect can match it with the
+	// aws:ResourceTag/karpenter.k8s.aws/node-access condition key.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9 _.:/=+\-@]+$`
+	// +kubebuilder:validation:MaxLength=256
+	// +required
+	Principal string `json:"principal"`
+	// Duration is how long access is granted for once the request is approved, up to 12h
+	// +kubebuilder:validation:Pattern=`^([0-9]+(s|m|h))+$`
+	// +kubebuilder:validation:Type="string"
+	// +kubebuilder:validation:XValidation:message="duration may not be longer than 12h",rule="duration(self) <= duration('12h')"
+	// +required
+	Duration metav1.Duration `json:"duration"`
+	// Reason records why access to the node is needed
+	// +kubebuilder:validation:MaxLength=1024
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// NodeAccessRequestStatus records the approval of a NodeAccessRequest and the access window it was granted
+// +kubebuilder:validation:XValidation:message="approvedBy can't be changed once set",rule="!has(oldSelf.approvedBy) || (has(self.approvedBy) && self.approvedBy == oldSelf.approvedBy)"
+type NodeAccessRequestStatus struct {
+	// ApprovedBy records who approved the request. It's set through the status subresource, so that permission to
+	// approve requests can be granted separately from permission to create them. Access isn't granted until it's set.
+	// +optional
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	// InstanceID is the ID of the instance that access was granted to
+	// +optional
+	InstanceID string `json:"instanceID,omitempty"`
+	// GrantedAt is when access was granted
+	// +optional
+	GrantedAt *metav1.Time `json:"grantedAt,omitempty"`
+	// ExpiresAt is when access is revoked
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// Conditions contains signals for the state of the request
+	// +optional
+	Conditions []status.Condition `json:"conditions,omitempty"`
+}
+
+// NodeAccessRequest is a request for time-boxed access to a node through EC2 Instance Connect. Once approved, Karpenter
+// tags the node's instance with the request's principal, and removes the tag when the access window ends or the
+// request is deleted.
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Node",type="string",JSONPath=".spec.nodeName",description=""
+// +kubebuilder:printcolumn:name="Principal",type="string",JSONPath=".spec.principal",description=""
+// +kubebuilder:printcolumn:name="ApprovedBy",type="string",JSONPath=".status.approvedBy",description=""
+// +kubebuilder:printcolumn:name="Granted",type="string",JSONPath=".status.conditions[?(@.type==\"AccessGranted\")].status",description=""
+// +kubebuilder:printcolumn:name="Expires",type="string",JSONPath=".status.expiresAt",description=""
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+// +kubebuilder:resource:path=nodeaccessrequests,scope=Cluster,categories=karpenter,shortName={nar,nars}
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+type NodeAccessRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +required
+	Spec   NodeAccessRequestSpec   `json:"spec"`
+	Status NodeAccessRequestStatus `json:"status,omitempty"`
+}
+
+func (in *NodeAccessRequest) StatusConditions() status.ConditionSet {
+	return status.NewReadyConditions(ConditionTypeAccessGranted).For(in)
+}
+
+func (in *NodeAccessRequest) GetConditions() []status.Condition {
+	return in.Status.Conditions
+}
+
+func (in *NodeAccessRequest) SetConditions(conditions []status.Condition) {
+	in.Status.Conditions = conditions
+}
+
+// NodeAccessRequestList contains a list of NodeAccessRequest
+// +kubebuilder:object:root=true
+type NodeAccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeAccessRequest `json:"items"`
+}
@@ -21,6 +21,7 @@ package v1
 import (
 	"github.com/awslabs/operatorpkg/status"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -28,6 +29,11 @@ import (
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AMI) DeepCopyInto(out *AMI) {
 	*out = *in
+	if in.ENASupport != nil {
+		in, out := &in.ENASupport, &out.ENASupport
+		*out = new(bool)
+		**out = **in
+	}
 	if in.Requirements != nil {
 		in, out := &in.Requirements, &out.Requirements
 		*out = make([]corev1.NodeSelectorRequirement, len(*in))
@@ -47,6 +53,77 @@ func (in *AMI) DeepCopy() *AMI {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AMIExclusionTerm) DeepCopyInto(out *AMIExclusionTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AMIExclusionTerm.
+func (in *AMIExclusionTerm) DeepCopy() *AMIExclusionTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(AMIExclusionTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AMIRollout) DeepCopyInto(out *AMIRollout) {
+	*out = *in
+	out.Duration = in.Duration
+	if in.InitialPercentage != nil {
+		in, out := &in.InitialPercentage, &out.InitialPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxNodes != nil {
+		in, out := &in.MaxNodes, &out.MaxNodes
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AMIRollout.
+func (in *AMIRollout) DeepCopy() *AMIRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(AMIRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AMIRolloutStatus) DeepCopyInto(out *AMIRolloutStatus) {
+	*out = *in
+	if in.PreviousAMIs != nil {
+		in, out := &in.PreviousAMIs, &out.PreviousAMIs
+		*out = make([]AMI, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AMIRolloutStatus.
+func (in *AMIRolloutStatus) DeepCopy() *AMIRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AMIRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AMISelectorTerm) DeepCopyInto(out *AMISelectorTerm) {
 	*out = *in
@@ -57,6 +134,21 @@ func (in *AMISelectorTerm) DeepCopyInto(out *AMISelectorTerm) {
 			(*out)[key] = val
 		}
 	}
+	if in.NewestCount != nil {
+		in, out := &in.NewestCount, &out.NewestCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinAge != nil {
+		in, out := &in.MinAge, &out.MinAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AMISelectorTerm.
@@ -69,6 +161,51 @@ func (in *AMISelectorTerm) DeepCopy() *AMISelectorTerm {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccountDefaults) DeepCopyInto(out *AccountDefaults) {
+	*out = *in
+	if in.EBSEncryptionByDefault != nil {
+		in, out := &in.EBSEncryptionByDefault, &out.EBSEncryptionByDefault
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EBSDefaultKMSKeyID != nil {
+		in, out := &in.EBSDefaultKMSKeyID, &out.EBSDefaultKMSKeyID
+		*out = new(string)
+		**out = **in
+	}
+	if in.HTTPEndpoint != nil {
+		in, out := &in.HTTPEndpoint, &out.HTTPEndpoint
+		*out = new(string)
+		**out = **in
+	}
+	if in.HTTPPutResponseHopLimit != nil {
+		in, out := &in.HTTPPutResponseHopLimit, &out.HTTPPutResponseHopLimit
+		*out = new(int64)
+		**out = **in
+	}
+	if in.HTTPTokens != nil {
+		in, out := &in.HTTPTokens, &out.HTTPTokens
+		*out = new(string)
+		**out = **in
+	}
+	if in.SerialConsoleAccessEnabled != nil {
+		in, out := &in.SerialConsoleAccessEnabled, &out.SerialConsoleAccessEnabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccountDefaults.
+func (in *AccountDefaults) DeepCopy() *AccountDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(AccountDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Alias) DeepCopyInto(out *Alias) {
 	*out = *in
@@ -164,6 +301,74 @@ func (in *BlockDeviceMapping) DeepCopy() *BlockDeviceMapping {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootOptions) DeepCopyInto(out *BootOptions) {
+	*out = *in
+	if in.BootMode != nil {
+		in, out := &in.BootMode, &out.BootMode
+		*out = new(string)
+		**out = **in
+	}
+	if in.NitroTPM != nil {
+		in, out := &in.NitroTPM, &out.NitroTPM
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MeasuredBootPCRs != nil {
+		in, out := &in.MeasuredBootPCRs, &out.MeasuredBootPCRs
+		*out = make(map[string]PCRDigest, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootOptions.
+func (in *BootOptions) DeepCopy() *BootOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(BootOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BottlerocketSettings) DeepCopyInto(out *BottlerocketSettings) {
+	*out = *in
+	in.JSON.DeepCopyInto(&out.JSON)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BottlerocketSettings.
+func (in *BottlerocketSettings) DeepCopy() *BottlerocketSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(BottlerocketSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CPUOptions) DeepCopyInto(out *CPUOptions) {
+	*out = *in
+	if in.ThreadsPerCore != nil {
+		in, out := &in.ThreadsPerCore, &out.ThreadsPerCore
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CPUOptions.
+func (in *CPUOptions) DeepCopy() *CPUOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(CPUOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CapacityReservation) DeepCopyInto(out *CapacityReservation) {
 	*out = *in
@@ -205,6 +410,31 @@ func (in *CapacityReservationSelectorTerm) DeepCopy() *CapacityReservationSelect
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceValidation) DeepCopyInto(out *DeviceValidation) {
+	*out = *in
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]DeviceValidationDevice, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceValidation.
+func (in *DeviceValidation) DeepCopy() *DeviceValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EC2NodeClass) DeepCopyInto(out *EC2NodeClass) {
 	*out = *in
@@ -274,6 +504,18 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SubnetExclusionTerms != nil {
+		in, out := &in.SubnetExclusionTerms, &out.SubnetExclusionTerms
+		*out = make([]SubnetExclusionTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MinSubnetAvailableIPAddresses != nil {
+		in, out := &in.MinSubnetAvailableIPAddresses, &out.MinSubnetAvailableIPAddresses
+		*out = new(int32)
+		**out = **in
+	}
 	if in.SecurityGroupSelectorTerms != nil {
 		in, out := &in.SecurityGroupSelectorTerms, &out.SecurityGroupSelectorTerms
 		*out = make([]SecurityGroupSelectorTerm, len(*in))
@@ -281,6 +523,11 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SecurityGroupDriftPolicy != nil {
+		in, out := &in.SecurityGroupDriftPolicy, &out.SecurityGroupDriftPolicy
+		*out = new(SecurityGroupDriftPolicy)
+		**out = **in
+	}
 	if in.CapacityReservationSelectorTerms != nil {
 		in, out := &in.CapacityReservationSelectorTerms, &out.CapacityReservationSelectorTerms
 		*out = make([]CapacityReservationSelectorTerm, len(*in))
@@ -293,6 +540,33 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.IPv6 != nil {
+		in, out := &in.IPv6, &out.IPv6
+		*out = new(IPv6)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SourceDestCheck != nil {
+		in, out := &in.SourceDestCheck, &out.SourceDestCheck
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NetworkInterfaces != nil {
+		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
+		*out = make([]NetworkInterface, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EFA != nil {
+		in, out := &in.EFA, &out.EFA
+		*out = new(EFA)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ElasticIP != nil {
+		in, out := &in.ElasticIP, &out.ElasticIP
+		*out = new(ElasticIP)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AMISelectorTerms != nil {
 		in, out := &in.AMISelectorTerms, &out.AMISelectorTerms
 		*out = make([]AMISelectorTerm, len(*in))
@@ -300,16 +574,97 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AMIExclusionTerms != nil {
+		in, out := &in.AMIExclusionTerms, &out.AMIExclusionTerms
+		*out = make([]AMIExclusionTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.AMIFamily != nil {
 		in, out := &in.AMIFamily, &out.AMIFamily
 		*out = new(string)
 		**out = **in
 	}
+	if in.AMIVariants != nil {
+		in, out := &in.AMIVariants, &out.AMIVariants
+		*out = make([]AMIVariant, len(*in))
+		copy(*out, *in)
+	}
+	if in.FIPS != nil {
+		in, out := &in.FIPS, &out.FIPS
+		*out = new(bool)
+		**out = **in
+	}
 	if in.UserData != nil {
 		in, out := &in.UserData, &out.UserData
 		*out = new(string)
 		**out = **in
 	}
+	if in.TemplatedUserData != nil {
+		in, out := &in.TemplatedUserData, &out.TemplatedUserData
+		*out = new(bool)
+		**out = **in
+	}
+	if in.UserDataDriftPolicy != nil {
+		in, out := &in.UserDataDriftPolicy, &out.UserDataDriftPolicy
+		*out = new(UserDataDriftPolicy)
+		**out = **in
+	}
+	if in.UserDataMergeOrder != nil {
+		in, out := &in.UserDataMergeOrder, &out.UserDataMergeOrder
+		*out = new(UserDataMergeOrder)
+		**out = **in
+	}
+	if in.BootstrapMode != nil {
+		in, out := &in.BootstrapMode, &out.BootstrapMode
+		*out = new(BootstrapMode)
+		**out = **in
+	}
+	if in.DeviceValidation != nil {
+		in, out := &in.DeviceValidation, &out.DeviceValidation
+		*out = new(DeviceValidation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BottlerocketSettings != nil {
+		in, out := &in.BottlerocketSettings, &out.BottlerocketSettings
+		*out = new(BottlerocketSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeConfig != nil {
+		in, out := &in.NodeConfig, &out.NodeConfig
+		*out = new(NodeConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KubeletConfigDropIns != nil {
+		in, out := &in.KubeletConfigDropIns, &out.KubeletConfigDropIns
+		*out = make([]KubeletConfigDropIn, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RegistryMirrors != nil {
+		in, out := &in.RegistryMirrors, &out.RegistryMirrors
+		*out = make([]RegistryMirror, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TrustedCABundle != nil {
+		in, out := &in.TrustedCABundle, &out.TrustedCABundle
+		*out = new(TrustedCABundle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Swap != nil {
+		in, out := &in.Swap, &out.Swap
+		*out = new(Swap)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.InstanceProfile != nil {
 		in, out := &in.InstanceProfile, &out.InstanceProfile
 		*out = new(string)
@@ -322,6 +677,18 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.NameTagTemplate != nil {
+		in, out := &in.NameTagTemplate, &out.NameTagTemplate
+		*out = new(string)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Kubelet != nil {
 		in, out := &in.Kubelet, &out.Kubelet
 		*out = new(KubeletConfiguration)
@@ -348,11 +715,58 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.SerialConsole != nil {
+		in, out := &in.SerialConsole, &out.SerialConsole
+		*out = new(bool)
+		**out = **in
+	}
 	if in.MetadataOptions != nil {
 		in, out := &in.MetadataOptions, &out.MetadataOptions
 		*out = new(MetadataOptions)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PrivateDNSNameOptions != nil {
+		in, out := &in.PrivateDNSNameOptions, &out.PrivateDNSNameOptions
+		*out = new(PrivateDNSNameOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CPUOptions != nil {
+		in, out := &in.CPUOptions, &out.CPUOptions
+		*out = new(CPUOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExtendedResources != nil {
+		in, out := &in.ExtendedResources, &out.ExtendedResources
+		*out = make([]ExtendedResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BootOptions != nil {
+		in, out := &in.BootOptions, &out.BootOptions
+		*out = new(BootOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FleetOptions != nil {
+		in, out := &in.FleetOptions, &out.FleetOptions
+		*out = new(FleetOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AMIRollout != nil {
+		in, out := &in.AMIRollout, &out.AMIRollout
+		*out = new(AMIRollout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AMIMaintenanceWindows != nil {
+		in, out := &in.AMIMaintenanceWindows, &out.AMIMaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.AMIDeprecationThreshold != nil {
+		in, out := &in.AMIDeprecationThreshold, &out.AMIDeprecationThreshold
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.Context != nil {
 		in, out := &in.Context, &out.Context
 		*out = new(string)
@@ -376,13 +790,22 @@ func (in *EC2NodeClassStatus) DeepCopyInto(out *EC2NodeClassStatus) {
 	if in.Subnets != nil {
 		in, out := &in.Subnets, &out.Subnets
 		*out = make([]Subnet, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.SecurityGroups != nil {
 		in, out := &in.SecurityGroups, &out.SecurityGroups
 		*out = make([]SecurityGroup, len(*in))
 		copy(*out, *in)
 	}
+	if in.NetworkInterfaces != nil {
+		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
+		*out = make([]NetworkInterfaceStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.CapacityReservations != nil {
 		in, out := &in.CapacityReservations, &out.CapacityReservations
 		*out = make([]CapacityReservation, len(*in))
@@ -397,8 +820,18 @@ func (in *EC2NodeClassStatus) DeepCopyInto(out *EC2NodeClassStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
+	if in.AMIRollout != nil {
+		in, out := &in.AMIRollout, &out.AMIRollout
+		*out = new(AMIRolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AccountDefaults != nil {
+		in, out := &in.AccountDefaults, &out.AccountDefaults
+		*out = new(AccountDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
 		*out = make([]status.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
@@ -416,6 +849,189 @@ func (in *EC2NodeClassStatus) DeepCopy() *EC2NodeClassStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EFA) DeepCopyInto(out *EFA) {
+	*out = *in
+	if in.Count != nil {
+		in, out := &in.Count, &out.Count
+		*out = new(int32)
+		**out = **in
+	}
+	if in.EFAOnly != nil {
+		in, out := &in.EFAOnly, &out.EFAOnly
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EFA.
+func (in *EFA) DeepCopy() *EFA {
+	if in == nil {
+		return nil
+	}
+	out := new(EFA)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticIP) DeepCopyInto(out *ElasticIP) {
+	*out = *in
+	if in.SelectorTerms != nil {
+		in, out := &in.SelectorTerms, &out.SelectorTerms
+		*out = make([]ElasticIPSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticIP.
+func (in *ElasticIP) DeepCopy() *ElasticIP {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticIP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticIPSelectorTerm) DeepCopyInto(out *ElasticIPSelectorTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticIPSelectorTerm.
+func (in *ElasticIPSelectorTerm) DeepCopy() *ElasticIPSelectorTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticIPSelectorTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtendedResource) DeepCopyInto(out *ExtendedResource) {
+	*out = *in
+	if in.InstanceFamilies != nil {
+		in, out := &in.InstanceFamilies, &out.InstanceFamilies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InstanceTypes != nil {
+		in, out := &in.InstanceTypes, &out.InstanceTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtendedResource.
+func (in *ExtendedResource) DeepCopy() *ExtendedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtendedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FleetOptions) DeepCopyInto(out *FleetOptions) {
+	*out = *in
+	if in.SpotAllocationStrategy != nil {
+		in, out := &in.SpotAllocationStrategy, &out.SpotAllocationStrategy
+		*out = new(string)
+		**out = **in
+	}
+	if in.InstanceTypePriority != nil {
+		in, out := &in.InstanceTypePriority, &out.InstanceTypePriority
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ArchitecturePriority != nil {
+		in, out := &in.ArchitecturePriority, &out.ArchitecturePriority
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ArchitecturePriceTolerancePercent != nil {
+		in, out := &in.ArchitecturePriceTolerancePercent, &out.ArchitecturePriceTolerancePercent
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FleetOptions.
+func (in *FleetOptions) DeepCopy() *FleetOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(FleetOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPv6) DeepCopyInto(out *IPv6) {
+	*out = *in
+	if in.AddressCount != nil {
+		in, out := &in.AddressCount, &out.AddressCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PrefixCount != nil {
+		in, out := &in.PrefixCount, &out.PrefixCount
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPv6.
+func (in *IPv6) DeepCopy() *IPv6 {
+	if in == nil {
+		return nil
+	}
+	out := new(IPv6)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletConfigDropIn) DeepCopyInto(out *KubeletConfigDropIn) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]apiextensionsv1.JSON, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletConfigDropIn.
+func (in *KubeletConfigDropIn) DeepCopy() *KubeletConfigDropIn {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletConfigDropIn)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubeletConfiguration) DeepCopyInto(out *KubeletConfiguration) {
 	*out = *in
@@ -501,6 +1117,22 @@ func (in *KubeletConfiguration) DeepCopy() *KubeletConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetadataOptions) DeepCopyInto(out *MetadataOptions) {
 	*out = *in
@@ -536,6 +1168,364 @@ func (in *MetadataOptions) DeepCopy() *MetadataOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkInterface) DeepCopyInto(out *NetworkInterface) {
+	*out = *in
+	if in.Count != nil {
+		in, out := &in.Count, &out.Count
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SubnetSelectorTerms != nil {
+		in, out := &in.SubnetSelectorTerms, &out.SubnetSelectorTerms
+		*out = make([]SubnetSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecurityGroupSelectorTerms != nil {
+		in, out := &in.SecurityGroupSelectorTerms, &out.SecurityGroupSelectorTerms
+		*out = make([]SecurityGroupSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterface.
+func (in *NetworkInterface) DeepCopy() *NetworkInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkInterfaceStatus) DeepCopyInto(out *NetworkInterfaceStatus) {
+	*out = *in
+	if in.Subnets != nil {
+		in, out := &in.Subnets, &out.Subnets
+		*out = make([]Subnet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecurityGroups != nil {
+		in, out := &in.SecurityGroups, &out.SecurityGroups
+		*out = make([]SecurityGroup, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterfaceStatus.
+func (in *NetworkInterfaceStatus) DeepCopy() *NetworkInterfaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkInterfaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeAccessRequest) DeepCopyInto(out *NodeAccessRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeAccessRequest.
+func (in *NodeAccessRequest) DeepCopy() *NodeAccessRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeAccessRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeAccessRequest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeAccessRequestList) DeepCopyInto(out *NodeAccessRequestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NodeAccessRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeAccessRequestList.
+func (in *NodeAccessRequestList) DeepCopy() *NodeAccessRequestList {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeAccessRequestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeAccessRequestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeAccessRequestSpec) DeepCopyInto(out *NodeAccessRequestSpec) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeAccessRequestSpec.
+func (in *NodeAccessRequestSpec) DeepCopy() *NodeAccessRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeAccessRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeAccessRequestStatus) DeepCopyInto(out *NodeAccessRequestStatus) {
+	*out = *in
+	if in.GrantedAt != nil {
+		in, out := &in.GrantedAt, &out.GrantedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]status.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeAccessRequestStatus.
+func (in *NodeAccessRequestStatus) DeepCopy() *NodeAccessRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeAccessRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeConfig) DeepCopyInto(out *NodeConfig) {
+	*out = *in
+	if in.Kubelet != nil {
+		in, out := &in.Kubelet, &out.Kubelet
+		*out = new(NodeConfigKubelet)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Containerd != nil {
+		in, out := &in.Containerd, &out.Containerd
+		*out = new(NodeConfigContainerd)
+		**out = **in
+	}
+	if in.Instance != nil {
+		in, out := &in.Instance, &out.Instance
+		*out = new(NodeConfigInstance)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfig.
+func (in *NodeConfig) DeepCopy() *NodeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeConfigContainerd) DeepCopyInto(out *NodeConfigContainerd) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfigContainerd.
+func (in *NodeConfigContainerd) DeepCopy() *NodeConfigContainerd {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeConfigContainerd)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeConfigInstance) DeepCopyInto(out *NodeConfigInstance) {
+	*out = *in
+	if in.LocalStorage != nil {
+		in, out := &in.LocalStorage, &out.LocalStorage
+		*out = new(NodeConfigLocalStorage)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfigInstance.
+func (in *NodeConfigInstance) DeepCopy() *NodeConfigInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeConfigInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeConfigKubelet) DeepCopyInto(out *NodeConfigKubelet) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]apiextensionsv1.JSON, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Flags != nil {
+		in, out := &in.Flags, &out.Flags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfigKubelet.
+func (in *NodeConfigKubelet) DeepCopy() *NodeConfigKubelet {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeConfigKubelet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeConfigLocalStorage) DeepCopyInto(out *NodeConfigLocalStorage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfigLocalStorage.
+func (in *NodeConfigLocalStorage) DeepCopy() *NodeConfigLocalStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeConfigLocalStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrivateDNSNameOptions) DeepCopyInto(out *PrivateDNSNameOptions) {
+	*out = *in
+	if in.HostnameType != nil {
+		in, out := &in.HostnameType, &out.HostnameType
+		*out = new(string)
+		**out = **in
+	}
+	if in.EnableResourceNameDNSARecord != nil {
+		in, out := &in.EnableResourceNameDNSARecord, &out.EnableResourceNameDNSARecord
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EnableResourceNameDNSAAAARecord != nil {
+		in, out := &in.EnableResourceNameDNSAAAARecord, &out.EnableResourceNameDNSAAAARecord
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrivateDNSNameOptions.
+func (in *PrivateDNSNameOptions) DeepCopy() *PrivateDNSNameOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(PrivateDNSNameOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Proxy) DeepCopyInto(out *Proxy) {
+	*out = *in
+	if in.HTTPProxy != nil {
+		in, out := &in.HTTPProxy, &out.HTTPProxy
+		*out = new(string)
+		**out = **in
+	}
+	if in.HTTPSProxy != nil {
+		in, out := &in.HTTPSProxy, &out.HTTPSProxy
+		*out = new(string)
+		**out = **in
+	}
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proxy.
+func (in *Proxy) DeepCopy() *Proxy {
+	if in == nil {
+		return nil
+	}
+	out := new(Proxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryMirror) DeepCopyInto(out *RegistryMirror) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CredentialsSecretName != nil {
+		in, out := &in.CredentialsSecretName, &out.CredentialsSecretName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryMirror.
+func (in *RegistryMirror) DeepCopy() *RegistryMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityGroup) DeepCopyInto(out *SecurityGroup) {
 	*out = *in
@@ -576,6 +1566,11 @@ func (in *SecurityGroupSelectorTerm) DeepCopy() *SecurityGroupSelectorTerm {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Subnet) DeepCopyInto(out *Subnet) {
 	*out = *in
+	if in.AvailableIPAddressCount != nil {
+		in, out := &in.AvailableIPAddressCount, &out.AvailableIPAddressCount
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Subnet.
@@ -588,6 +1583,28 @@ func (in *Subnet) DeepCopy() *Subnet {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubnetExclusionTerm) DeepCopyInto(out *SubnetExclusionTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetExclusionTerm.
+func (in *SubnetExclusionTerm) DeepCopy() *SubnetExclusionTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(SubnetExclusionTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SubnetSelectorTerm) DeepCopyInto(out *SubnetSelectorTerm) {
 	*out = *in
@@ -598,6 +1615,11 @@ func (in *SubnetSelectorTerm) DeepCopyInto(out *SubnetSelectorTerm) {
 			(*out)[key] = val
 		}
 	}
+	if in.CIDRMatch != nil {
+		in, out := &in.CIDRMatch, &out.CIDRMatch
+		*out = new(CIDRMatch)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetSelectorTerm.
@@ -609,3 +1631,53 @@ func (in *SubnetSelectorTerm) DeepCopy() *SubnetSelectorTerm {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Swap) DeepCopyInto(out *Swap) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.SwapBehavior != nil {
+		in, out := &in.SwapBehavior, &out.SwapBehavior
+		*out = new(SwapBehavior)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Swap.
+func (in *Swap) DeepCopy() *Swap {
+	if in == nil {
+		return nil
+	}
+	out := new(Swap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedCABundle) DeepCopyInto(out *TrustedCABundle) {
+	*out = *in
+	if in.PEM != nil {
+		in, out := &in.PEM, &out.PEM
+		*out = new(string)
+		**out = **in
+	}
+	if in.ConfigMapName != nil {
+		in, out := &in.ConfigMapName, &out.ConfigMapName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedCABundle.
+func (in *TrustedCABundle) DeepCopy() *TrustedCABundle {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedCABundle)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 
@@ -32,14 +33,14 @@ type CreateFleetBatcher struct {
 	batcher *Batcher[ec2.CreateFleetInput, ec2.CreateFleetOutput]
 }
 
-func NewCreateFleetBatcher(ctx context.Context, ec2api sdk.EC2API) *CreateFleetBatcher {
+func NewCreateFleetBatcher(ctx context.Context, ec2api sdk.EC2API, launchLimiter *LaunchLimiter) *CreateFleetBatcher {
 	options := Options[ec2.CreateFleetInput, ec2.CreateFleetOutput]{
 		Name:          "create_fleet",
 		IdleTimeout:   35 * time.Millisecond,
 		MaxTimeout:    1 * time.Second,
 		MaxItems:      1_000,
 		RequestHasher: DefaultHasher[ec2.CreateFleetInput],
-		BatchExecutor: execCreateFleetBatch(ec2api),
+		BatchExecutor: execCreateFleetBatch(ec2api, launchLimiter),
 	}
 	return &CreateFleetBatcher{batcher: NewBatcher(ctx, options)}
 }
@@ -51,62 +52,81 @@ func (b *CreateFleetBatcher) CreateFleet(ctx context.Context, createFleetInput *
 	return result.Output, result.Err
 }
 
-func execCreateFleetBatch(ec2api sdk.EC2API) BatchExecutor[ec2.CreateFleetInput, ec2.CreateFleetOutput] {
+func execCreateFleetBatch(ec2api sdk.EC2API, launchLimiter *LaunchLimiter) BatchExecutor[ec2.CreateFleetInput, ec2.CreateFleetOutput] {
 	return func(ctx context.Context, inputs []*ec2.CreateFleetInput) []Result[ec2.CreateFleetOutput] {
-		results := make([]Result[ec2.CreateFleetOutput], 0, len(inputs))
-		firstInput := inputs[0]
-		//nolint:gosec
-		firstInput.TargetCapacitySpecification.TotalTargetCapacity = aws.Int32(int32(len(inputs)))
-		output, err := ec2api.CreateFleet(ctx, firstInput)
-		if err != nil {
-			for range inputs {
-				results = append(results, Result[ec2.CreateFleetOutput]{Err: err})
-			}
-			return results
+		// Batches which launch more instances than the launch limiter admits at once are split into several calls
+		if size := launchLimiter.MaxBatchSize(); size > 0 && len(inputs) > size {
+			return lo.Flatten(lo.Map(lo.Chunk(inputs, size), func(chunk []*ec2.CreateFleetInput, _ int) []Result[ec2.CreateFleetOutput] {
+				return createFleet(ctx, ec2api, launchLimiter, chunk)
+			}))
+		}
+		return createFleet(ctx, ec2api, launchLimiter, inputs)
+	}
+}
+
+// createFleet launches a batch of NodeClaims with a single CreateFleet call once the launch limiter admits it
+func createFleet(ctx context.Context, ec2api sdk.EC2API, launchLimiter *LaunchLimiter, inputs []*ec2.CreateFleetInput) []Result[ec2.CreateFleetOutput] {
+	results := make([]Result[ec2.CreateFleetOutput], 0, len(inputs))
+	firstInput := inputs[0]
+	//nolint:gosec
+	firstInput.TargetCapacitySpecification.TotalTargetCapacity = aws.Int32(int32(len(inputs)))
+	release, err := launchLimiter.Acquire(ctx, len(inputs))
+	if err != nil {
+		for range inputs {
+			results = append(results, Result[ec2.CreateFleetOutput]{Err: err})
 		}
-		// we can get partial fulfillment of a CreateFleet request, so we:
-		// 1) split out the single instance IDs and deliver to each requestor
-		// 2) deliver errors to any remaining requestors for which we don't have an instance
-		requestIdx := -1
-		for _, reservation := range output.Instances {
-			for _, instanceID := range reservation.InstanceIds {
-				requestIdx++
-				if requestIdx >= len(inputs) {
-					log.FromContext(ctx).Error(fmt.Errorf("received more instances than requested, ignoring instance %s", instanceID), "received error while batching")
-					continue
-				}
-				results = append(results, Result[ec2.CreateFleetOutput]{
-					Output: &ec2.CreateFleetOutput{
-						FleetId: output.FleetId,
-						Errors:  output.Errors,
-						Instances: []ec2types.CreateFleetInstance{
-							{
-								InstanceIds:                []string{instanceID},
-								InstanceType:               reservation.InstanceType,
-								LaunchTemplateAndOverrides: reservation.LaunchTemplateAndOverrides,
-								Lifecycle:                  reservation.Lifecycle,
-								Platform:                   reservation.Platform,
-							},
+		return results
+	}
+	output, err := ec2api.CreateFleet(ctx, firstInput)
+	release()
+	if err != nil {
+		for range inputs {
+			results = append(results, Result[ec2.CreateFleetOutput]{Err: err})
+		}
+		return results
+	}
+	// we can get partial fulfillment of a CreateFleet request, so we:
+	// 1) split out the single instance IDs and deliver to each requestor
+	// 2) deliver errors to any remaining requestors for which we don't have an instance
+	requestIdx := -1
+	for _, reservation := range output.Instances {
+		for _, instanceID := range reservation.InstanceIds {
+			requestIdx++
+			if requestIdx >= len(inputs) {
+				log.FromContext(ctx).Error(fmt.Errorf("received more instances than requested, ignoring instance %s", instanceID), "received error while batching")
+				continue
+			}
+			results = append(results, Result[ec2.CreateFleetOutput]{
+				Output: &ec2.CreateFleetOutput{
+					FleetId: output.FleetId,
+					Errors:  output.Errors,
+					Instances: []ec2types.CreateFleetInstance{
+						{
+							InstanceIds:                []string{instanceID},
+							InstanceType:               reservation.InstanceType,
+							LaunchTemplateAndOverrides: reservation.LaunchTemplateAndOverrides,
+							Lifecycle:                  reservation.Lifecycle,
+							Platform:                   reservation.Platform,
 						},
 					},
-				})
-			}
+				},
+			})
 		}
-		if requestIdx != len(inputs) {
-			// we should receive some sort of error, but just in case
-			if len(output.Errors) == 0 {
-				output.Errors = append(output.Errors, ec2types.CreateFleetError{
-					ErrorCode:    aws.String("too few instances returned"),
-					ErrorMessage: aws.String("too few instances returned"),
-				})
-			}
-			for i := requestIdx + 1; i < len(inputs); i++ {
-				results = append(results, Result[ec2.CreateFleetOutput]{
-					Output: &ec2.CreateFleetOutput{
-						Errors: output.Errors,
-					}})
-			}
+	}
+	if requestIdx != len(inputs) {
+		// we should receive some sort of error, but just in case
+		if len(output.Errors) == 0 {
+			output.Errors = append(output.Errors, ec2types.CreateFleetError{
+				ErrorCode:    aws.String("too few instances returned"),
+				ErrorMessage: aws.String("too few instances returned"),
+			})
+		}
+		for i := requestIdx + 1; i < len(inputs); i++ {
+			results = append(results, Result[ec2.CreateFleetOutput]{
+				Output: &ec2.CreateFleetOutput{
+					Errors: output.Errors,
+				}})
 		}
-		return results
 	}
+	return results
 }
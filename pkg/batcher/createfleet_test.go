@@ -15,6 +15,7 @@ limitations under the License.
 package batcher_test
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -35,7 +36,7 @@ var _ = Describe("CreateFleet Batching", func() {
 
 	BeforeEach(func() {
 		fakeEC2API.Reset()
-		cfb = batcher.NewCreateFleetBatcher(ctx, fakeEC2API)
+		cfb = batcher.NewCreateFleetBatcher(ctx, fakeEC2API, batcher.NewLaunchLimiter(0, 0))
 	})
 
 	It("should batch the same inputs into a single call", func() {
@@ -79,6 +80,65 @@ var _ = Describe("CreateFleet Batching", func() {
 		call := fakeEC2API.CreateFleetBehavior.CalledWithInput.Pop()
 		Expect(*call.TargetCapacitySpecification.TotalTargetCapacity).To(BeNumerically("==", 5))
 	})
+	It("should throttle a batch which launches more instances than the launch rate", func() {
+		// The limiter admits 2 instances a minute, so only the first 2 of a batch of 5 NodeClaims launch in time
+		cfb = batcher.NewCreateFleetBatcher(ctx, fakeEC2API, batcher.NewLaunchLimiter(0, 2))
+		timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		var wg sync.WaitGroup
+		var launched, throttled int64
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := cfb.CreateFleet(timeoutCtx, &ec2.CreateFleetInput{
+					LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
+						{
+							LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
+								LaunchTemplateName: aws.String("my-template"),
+							},
+							Overrides: []ec2types.FleetLaunchTemplateOverridesRequest{
+								{
+									AvailabilityZone: aws.String("us-east-1"),
+								},
+							},
+						},
+					},
+					TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+						TotalTargetCapacity: aws.Int32(1),
+					},
+				})
+				if err != nil {
+					atomic.AddInt64(&throttled, 1)
+				} else {
+					atomic.AddInt64(&launched, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(launched).To(BeNumerically("==", 2))
+		Expect(throttled).To(BeNumerically("==", 3))
+		Expect(fakeEC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+		call := fakeEC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		Expect(*call.TargetCapacitySpecification.TotalTargetCapacity).To(BeNumerically("==", 2))
+	})
+	It("should fail the batched NodeClaims when the launch limiter doesn't admit the call", func() {
+		limiter := batcher.NewLaunchLimiter(1, 0)
+		release, err := limiter.Acquire(ctx, 1)
+		Expect(err).ToNot(HaveOccurred())
+		defer release()
+		cfb = batcher.NewCreateFleetBatcher(ctx, fakeEC2API, limiter)
+		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err = cfb.CreateFleet(timeoutCtx, &ec2.CreateFleetInput{
+			TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+				TotalTargetCapacity: aws.Int32(1),
+			},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(fakeEC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+	})
 	It("should batch different inputs into multiple calls", func() {
 		east1input := &ec2.CreateFleetInput{
 			LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
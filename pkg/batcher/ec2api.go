@@ -26,9 +26,9 @@ type EC2API struct {
 	*TerminateInstancesBatcher
 }
 
-func EC2(ctx context.Context, ec2api sdk.EC2API) *EC2API {
+func EC2(ctx context.Context, ec2api sdk.EC2API, launchLimiter *LaunchLimiter) *EC2API {
 	return &EC2API{
-		CreateFleetBatcher:        NewCreateFleetBatcher(ctx, ec2api),
+		CreateFleetBatcher:        NewCreateFleetBatcher(ctx, ec2api, launchLimiter),
 		DescribeInstancesBatcher:  NewDescribeInstancesBatcher(ctx, ec2api),
 		TerminateInstancesBatcher: NewTerminateInstancesBatcher(ctx, ec2api),
 	}
@@ -0,0 +1,94 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+)
+
+// LaunchLimiter queues CreateFleet calls so that at most a maximum number of instances are being launched at once, and
+// instances are launched at no more than a maximum rate, keeping large scale-outs within the account's EC2 API
+// throttling budget. Calls are weighted by the number of instances they launch, so a batched call takes a token for
+// each of its NodeClaims. Calls are admitted in the order they're queued.
+type LaunchLimiter struct {
+	inFlight     *semaphore.Weighted
+	rate         *rate.Limiter
+	maxBatchSize int
+
+	mu     sync.Mutex
+	queued int
+}
+
+// NewLaunchLimiter constructs a limiter which allows maxConcurrent instances to be launched at once and maxPerMinute
+// instances to be launched per minute. A limit of 0 disables it.
+func NewLaunchLimiter(maxConcurrent int, maxPerMinute int) *LaunchLimiter {
+	l := &LaunchLimiter{}
+	if maxConcurrent > 0 {
+		l.inFlight = semaphore.NewWeighted(int64(maxConcurrent))
+		l.maxBatchSize = maxConcurrent
+	}
+	if maxPerMinute > 0 {
+		// The rate is enforced with a token bucket, which like EC2's own throttling allows a burst of up to a minute's
+		// launches once the bucket has refilled
+		l.rate = rate.NewLimiter(rate.Every(time.Minute/time.Duration(maxPerMinute)), maxPerMinute)
+		if l.maxBatchSize == 0 || maxPerMinute < l.maxBatchSize {
+			l.maxBatchSize = maxPerMinute
+		}
+	}
+	return l
+}
+
+// MaxBatchSize returns the most instances that a single CreateFleet call may launch, or 0 if it's unlimited. A call
+// launching more instances than either limit would never be admitted, so larger batches must be split.
+func (l *LaunchLimiter) MaxBatchSize() int {
+	return l.maxBatchSize
+}
+
+// Acquire waits until a CreateFleet call launching n instances is admitted, returning a function which must be called
+// once the call has completed. An error is returned if the context is done before the call is admitted.
+func (l *LaunchLimiter) Acquire(ctx context.Context, n int) (func(), error) {
+	l.updateQueued(n)
+	defer l.updateQueued(-n)
+	if l.inFlight != nil {
+		if err := l.inFlight.Acquire(ctx, int64(n)); err != nil {
+			return nil, fmt.Errorf("waiting for in-flight launches to complete, %w", err)
+		}
+	}
+	release := func() {
+		if l.inFlight != nil {
+			l.inFlight.Release(int64(n))
+		}
+	}
+	if l.rate != nil {
+		if err := l.rate.WaitN(ctx, n); err != nil {
+			release()
+			return nil, fmt.Errorf("waiting for the launch rate limit, %w", err)
+		}
+	}
+	return release, nil
+}
+
+func (l *LaunchLimiter) updateQueued(delta int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queued += delta
+	LaunchQueueDepth.Set(float64(l.queued), map[string]string{})
+}
@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package batcher_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/karpenter-provider-aws/pkg/batcher"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("LaunchLimiter", func() {
+	It("should admit launches immediately when it's unlimited", func() {
+		limiter := batcher.NewLaunchLimiter(0, 0)
+		for range 100 {
+			release, err := limiter.Acquire(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+			release()
+		}
+	})
+	It("should queue launches beyond the maximum in-flight launches", func() {
+		limiter := batcher.NewLaunchLimiter(1, 0)
+		release, err := limiter.Acquire(ctx, 1)
+		Expect(err).ToNot(HaveOccurred())
+
+		admitted := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			queuedRelease, err := limiter.Acquire(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+			queuedRelease()
+			close(admitted)
+		}()
+		Eventually(func(g Gomega) {
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_launch_queue_depth", map[string]string{})
+			g.Expect(ok).To(BeTrue())
+			g.Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 1))
+		}).Should(Succeed())
+		Consistently(admitted).WithTimeout(100 * time.Millisecond).ShouldNot(BeClosed())
+
+		release()
+		Eventually(admitted).Should(BeClosed())
+		metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_launch_queue_depth", map[string]string{})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 0))
+	})
+	It("should fail queued launches when the context is done", func() {
+		limiter := batcher.NewLaunchLimiter(1, 0)
+		release, err := limiter.Acquire(ctx, 1)
+		Expect(err).ToNot(HaveOccurred())
+		defer release()
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = limiter.Acquire(timeoutCtx, 1)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should queue launches beyond the maximum launch rate", func() {
+		limiter := batcher.NewLaunchLimiter(0, 2)
+		for range 2 {
+			release, err := limiter.Acquire(ctx, 1)
+			Expect(err).ToNot(HaveOccurred())
+			release()
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, err := limiter.Acquire(timeoutCtx, 1)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should take a token for each instance a call launches", func() {
+		limiter := batcher.NewLaunchLimiter(0, 5)
+		release, err := limiter.Acquire(ctx, 3)
+		Expect(err).ToNot(HaveOccurred())
+		release()
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, err = limiter.Acquire(timeoutCtx, 3)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should count each instance a call launches against the maximum in-flight launches", func() {
+		limiter := batcher.NewLaunchLimiter(4, 0)
+		release, err := limiter.Acquire(ctx, 3)
+		Expect(err).ToNot(HaveOccurred())
+		defer release()
+		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err = limiter.Acquire(timeoutCtx, 2)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should limit batches to the smaller of its limits", func() {
+		Expect(batcher.NewLaunchLimiter(0, 0).MaxBatchSize()).To(Equal(0))
+		Expect(batcher.NewLaunchLimiter(10, 0).MaxBatchSize()).To(Equal(10))
+		Expect(batcher.NewLaunchLimiter(0, 20).MaxBatchSize()).To(Equal(20))
+		Expect(batcher.NewLaunchLimiter(10, 5).MaxBatchSize()).To(Equal(5))
+	})
+})
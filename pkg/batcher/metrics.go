@@ -23,8 +23,9 @@ import (
 )
 
 const (
-	batcherSubsystem = "cloudprovider_batcher"
-	batcherNameLabel = "batcher"
+	batcherSubsystem       = "cloudprovider_batcher"
+	batcherNameLabel       = "batcher"
+	cloudProviderSubsystem = "cloudprovider"
 )
 
 // SizeBuckets returns a []float64 of default threshold values for size histograms.
@@ -49,4 +50,10 @@ var (
 		Help:      "Size of the request batch per batcher",
 		Buckets:   SizeBuckets(),
 	}, []string{batcherNameLabel})
+	LaunchQueueDepth = opmetrics.NewPrometheusGauge(crmetrics.Registry, prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: cloudProviderSubsystem,
+		Name:      "launch_queue_depth",
+		Help:      "Number of instances whose CreateFleet calls are waiting for the launch limiter to admit them.",
+	}, []string{})
 )
@@ -27,6 +27,9 @@ const (
 	// UnavailableOfferingsTTL is the time before offerings that were marked as unavailable
 	// are removed from the cache and are available for launch again
 	UnavailableOfferingsTTL = 3 * time.Minute
+	// ExhaustedSubnetsTTL is the time before subnets that ran out of free IP addresses during a launch are launched
+	// into again
+	ExhaustedSubnetsTTL = 3 * time.Minute
 	// CapacityReservationAvailabilityTTL is the time we will persist cached capacity availability. Nominally, this is
 	// updated every minute, but we want to persist the data longer in the event of an EC2 API outage. 24 hours was the
 	// compormise made for API outage reseliency and gargage collecting entries for orphaned reservations.
@@ -42,11 +45,22 @@ const (
 	// SSMGetParametersByPathTTL is the time to drop SSM Parameters by path data. This only queries EKS Optimized AMI
 	// releases, so we should expect this to be updated relatively infrequently.
 	SSMCacheTTL = 24 * time.Hour
+	// AMIParameterTTL is the time before we refresh the SSM parameters referenced by amiSelectorTerms. Unlike the EKS
+	// optimized AMI parameters, these are usually updated in place by pipelines publishing new AMIs.
+	AMIParameterTTL = 5 * time.Minute
 	// DiscoveredCapacityCacheTTL is the time to drop discovered resource capacity data per-instance type
 	// if it is not updated by a node creation event or refreshed during controller reconciliation
 	DiscoveredCapacityCacheTTL = 60 * 24 * time.Hour
 	// ValidationTTL is time to check authorization errors with validation controller
 	ValidationTTL = 10 * time.Minute
+	// AccountSettingsTTL is the time before we refresh the EC2 account-level defaults, such as EBS encryption by default
+	// and instance metadata defaults. These are changed rarely, so there's no need to check them frequently.
+	AccountSettingsTTL = 15 * time.Minute
+)
+
+const (
+	// InstanceTypesCacheName labels the metrics of the size-bounded cache of resolved instance types
+	InstanceTypesCacheName = "instance_types"
 )
 
 const (
@@ -0,0 +1,66 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+)
+
+// Invalidation identifies cached data which has become stale
+type Invalidation struct {
+	// NodeClass is the name of an EC2NodeClass whose resolved instance types are stale, e.g. because its status changed
+	// or it was deleted
+	NodeClass string
+	// InstanceTypes are the instance types whose offerings are stale, e.g. because their availability or prices changed
+	InstanceTypes []string
+	// Rediscover is set when the instance types themselves, rather than only their offerings, may be out of date, e.g.
+	// because EC2 rejected a launch with an instance type's current data
+	Rediscover bool
+}
+
+// InvalidationBus lets the controllers and caches which observe changes invalidate only the cache entries affected by
+// them, rather than every consumer checking for changes on each lookup. Subscribers are called synchronously by the
+// publisher, so they must not block.
+type InvalidationBus struct {
+	mu          sync.RWMutex
+	subscribers []func(Invalidation)
+}
+
+func NewInvalidationBus() *InvalidationBus {
+	return &InvalidationBus{}
+}
+
+// Subscribe registers a function which is called with each published invalidation
+func (b *InvalidationBus) Subscribe(subscriber func(Invalidation)) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+// Publish notifies the subscribers of an invalidation. Publishing to a nil bus is a no-op, so that components
+// constructed without a bus, such as in tools which don't launch instances, don't need to special case it.
+func (b *InvalidationBus) Publish(invalidation Invalidation) {
+	if b == nil || (invalidation.NodeClass == "" && len(invalidation.InstanceTypes) == 0) {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, subscriber := range b.subscribers {
+		subscriber(invalidation)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	evictionReasonCapacity = "capacity"
+	evictionReasonExpired  = "expired"
+)
+
+// LRU is a TTL cache with an upper bound on the number of entries and on their estimated total size. When adding an
+// entry would exceed either bound, the least recently used entries are evicted until the cache fits again. A bound
+// of zero disables that limit. Unlike go-cache, LRU doesn't run a janitor; expired entries are dropped when they're
+// looked up or when a new entry is added.
+type LRU struct {
+	name       string
+	ttl        time.Duration
+	maxEntries int
+	maxSize    int64
+	sizeFunc   func(interface{}) int64
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	size  int64
+}
+
+type lruEntry struct {
+	key        string
+	value      interface{}
+	size       int64
+	expiration time.Time
+}
+
+// NewLRU constructs an LRU cache. The name is used to label the cache's metrics and sizeFunc, if provided, is used to
+// estimate the size in bytes of each entry for the maxSize bound.
+func NewLRU(name string, ttl time.Duration, maxEntries int, maxSize int64, sizeFunc func(interface{}) int64) *LRU {
+	return &LRU{
+		name:       name,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxSize:    maxSize,
+		sizeFunc:   sizeFunc,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+	}
+}
+
+// Get returns the value stored for the key and marks it as the most recently used entry
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expiration) {
+		c.remove(elem, evictionReasonExpired)
+		c.updateMetrics()
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// SetDefault adds the value to the cache with the cache's default TTL, replacing any existing value for the key
+func (c *LRU) SetDefault(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var size int64
+	if c.sizeFunc != nil {
+		size = c.sizeFunc(value)
+	}
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem, "")
+	}
+	c.items[key] = c.ll.PushFront(&lruEntry{
+		key:        key,
+		value:      value,
+		size:       size,
+		expiration: time.Now().Add(c.ttl),
+	})
+	c.size += size
+	c.deleteExpired()
+	// Always retain the entry which was just added, even if it alone exceeds the size bound. Dropping it would only
+	// guarantee a miss on the next call.
+	for c.ll.Len() > 1 && ((c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxSize > 0 && c.size > c.maxSize)) {
+		c.remove(c.ll.Back(), evictionReasonCapacity)
+	}
+	c.updateMetrics()
+}
+
+func (c *LRU) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem, "")
+		c.updateMetrics()
+	}
+}
+
+func (c *LRU) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+	c.size = 0
+	c.updateMetrics()
+}
+
+// Contains returns whether an unexpired entry is stored for the key, without marking it as recently used
+func (c *LRU) Contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	return ok && !time.Now().After(elem.Value.(*lruEntry).expiration)
+}
+
+func (c *LRU) ItemCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Size returns the estimated size of all entries in the cache
+func (c *LRU) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *LRU) deleteExpired() {
+	now := time.Now()
+	for elem := c.ll.Back(); elem != nil; {
+		prev := elem.Prev()
+		if now.After(elem.Value.(*lruEntry).expiration) {
+			c.remove(elem, evictionReasonExpired)
+		}
+		elem = prev
+	}
+}
+
+// remove drops the element from the cache, recording an eviction if a reason is provided
+func (c *LRU) remove(elem *list.Element, reason string) {
+	entry := c.ll.Remove(elem).(*lruEntry)
+	delete(c.items, entry.key)
+	c.size -= entry.size
+	if reason != "" {
+		CacheEvictionsTotal.Inc(map[string]string{
+			cacheNameLabel: c.name,
+			reasonLabel:    reason,
+		})
+	}
+}
+
+func (c *LRU) updateMetrics() {
+	CacheEntries.Set(float64(c.ll.Len()), map[string]string{cacheNameLabel: c.name})
+	CacheSizeBytes.Set(float64(c.size), map[string]string{cacheNameLabel: c.name})
+}
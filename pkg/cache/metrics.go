@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cacheSubsystem = "cloudprovider_cache"
+	cacheNameLabel = "cache"
+	reasonLabel    = "reason"
+)
+
+var (
+	CacheEvictionsTotal = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cacheSubsystem,
+			Name:      "evictions_total",
+			Help:      "Number of entries evicted from a size-bounded cache, based on cache and eviction reason.",
+		},
+		[]string{
+			cacheNameLabel,
+			reasonLabel,
+		},
+	)
+	CacheEntries = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cacheSubsystem,
+			Name:      "entries",
+			Help:      "Number of entries currently stored in a size-bounded cache.",
+		},
+		[]string{
+			cacheNameLabel,
+		},
+	)
+	CacheSizeBytes = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cacheSubsystem,
+			Name:      "size_bytes",
+			Help:      "Estimated size, in bytes, of the entries currently stored in a size-bounded cache.",
+		},
+		[]string{
+			cacheNameLabel,
+		},
+	)
+)
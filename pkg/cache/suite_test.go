@@ -0,0 +1,191 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+)
+
+func fleetErr(code string, instanceType ec2types.InstanceType) ec2types.CreateFleetError {
+	return ec2types.CreateFleetError{
+		ErrorCode: aws.String(code),
+		LaunchTemplateAndOverrides: &ec2types.LaunchTemplateAndOverridesResponse{
+			Overrides: &ec2types.FleetLaunchTemplateOverrides{
+				InstanceType:     instanceType,
+				AvailabilityZone: aws.String("test-zone-1a"),
+			},
+		},
+	}
+}
+
+func TestCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cache")
+}
+
+var _ = Describe("LRU", func() {
+	It("should evict the least recently used entry when the max entries are exceeded", func() {
+		lru := awscache.NewLRU("test", time.Hour, 2, 0, nil)
+		lru.SetDefault("a", 1)
+		lru.SetDefault("b", 2)
+		// Access "a" so that "b" becomes the least recently used entry
+		_, ok := lru.Get("a")
+		Expect(ok).To(BeTrue())
+		lru.SetDefault("c", 3)
+
+		Expect(lru.ItemCount()).To(Equal(2))
+		_, ok = lru.Get("b")
+		Expect(ok).To(BeFalse())
+		v, ok := lru.Get("a")
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal(1))
+		v, ok = lru.Get("c")
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal(3))
+	})
+	It("should evict entries until the estimated size fits within the max size", func() {
+		lru := awscache.NewLRU("test", time.Hour, 0, 10, func(v interface{}) int64 { return int64(v.(int)) })
+		lru.SetDefault("a", 4)
+		lru.SetDefault("b", 4)
+		Expect(lru.Size()).To(BeNumerically("==", 8))
+		lru.SetDefault("c", 4)
+
+		Expect(lru.ItemCount()).To(Equal(2))
+		Expect(lru.Size()).To(BeNumerically("==", 8))
+		_, ok := lru.Get("a")
+		Expect(ok).To(BeFalse())
+	})
+	It("should retain a single entry that exceeds the max size", func() {
+		lru := awscache.NewLRU("test", time.Hour, 0, 10, func(v interface{}) int64 { return int64(v.(int)) })
+		lru.SetDefault("a", 4)
+		lru.SetDefault("b", 20)
+
+		Expect(lru.ItemCount()).To(Equal(1))
+		_, ok := lru.Get("b")
+		Expect(ok).To(BeTrue())
+	})
+	It("should replace the value and size of an existing key", func() {
+		lru := awscache.NewLRU("test", time.Hour, 0, 0, func(v interface{}) int64 { return int64(v.(int)) })
+		lru.SetDefault("a", 4)
+		lru.SetDefault("a", 6)
+
+		Expect(lru.ItemCount()).To(Equal(1))
+		Expect(lru.Size()).To(BeNumerically("==", 6))
+		v, ok := lru.Get("a")
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal(6))
+	})
+	It("should not return expired entries", func() {
+		lru := awscache.NewLRU("test", time.Nanosecond, 0, 0, nil)
+		lru.SetDefault("a", 1)
+		time.Sleep(time.Millisecond)
+
+		_, ok := lru.Get("a")
+		Expect(ok).To(BeFalse())
+		Expect(lru.ItemCount()).To(Equal(0))
+	})
+	It("should remove all entries on flush", func() {
+		lru := awscache.NewLRU("test", time.Hour, 0, 0, func(v interface{}) int64 { return int64(v.(int)) })
+		lru.SetDefault("a", 1)
+		lru.SetDefault("b", 2)
+		lru.Flush()
+
+		Expect(lru.ItemCount()).To(Equal(0))
+		Expect(lru.Size()).To(BeNumerically("==", 0))
+	})
+	It("should check for an entry without marking it as recently used", func() {
+		lru := awscache.NewLRU("test", time.Hour, 2, 0, nil)
+		lru.SetDefault("a", 1)
+		lru.SetDefault("b", 2)
+		Expect(lru.Contains("a")).To(BeTrue())
+		lru.SetDefault("c", 3)
+
+		Expect(lru.Contains("a")).To(BeFalse())
+		Expect(lru.Contains("b")).To(BeTrue())
+		Expect(lru.Contains("c")).To(BeTrue())
+	})
+})
+
+var _ = Describe("InvalidationBus", func() {
+	var bus *awscache.InvalidationBus
+	var published []awscache.Invalidation
+	BeforeEach(func() {
+		bus = awscache.NewInvalidationBus()
+		published = nil
+		bus.Subscribe(func(invalidation awscache.Invalidation) { published = append(published, invalidation) })
+	})
+	It("should notify each subscriber of published invalidations", func() {
+		var other []awscache.Invalidation
+		bus.Subscribe(func(invalidation awscache.Invalidation) { other = append(other, invalidation) })
+		bus.Publish(awscache.Invalidation{NodeClass: "default"})
+
+		Expect(published).To(ConsistOf(awscache.Invalidation{NodeClass: "default"}))
+		Expect(other).To(ConsistOf(awscache.Invalidation{NodeClass: "default"}))
+	})
+	It("should not publish empty invalidations", func() {
+		bus.Publish(awscache.Invalidation{})
+		Expect(published).To(BeEmpty())
+	})
+	It("should ignore a nil bus", func() {
+		var nilBus *awscache.InvalidationBus
+		nilBus.Subscribe(func(awscache.Invalidation) { Fail("subscriber shouldn't be called") })
+		nilBus.Publish(awscache.Invalidation{NodeClass: "default"})
+	})
+	Context("UnavailableOfferings", func() {
+		var unavailableOfferings *awscache.UnavailableOfferings
+		BeforeEach(func() {
+			unavailableOfferings = awscache.NewUnavailableOfferings(bus)
+		})
+		It("should invalidate the instance type when an offering is marked unavailable", func() {
+			unavailableOfferings.MarkUnavailable(context.Background(), "InsufficientInstanceCapacity", ec2types.InstanceTypeM5Large, "test-zone-1a", "spot")
+			Expect(published).To(ConsistOf(awscache.Invalidation{InstanceTypes: []string{"m5.large"}}))
+		})
+		It("should invalidate the instance type when an offering is deleted", func() {
+			unavailableOfferings.MarkUnavailable(context.Background(), "InsufficientInstanceCapacity", ec2types.InstanceTypeM5Large, "test-zone-1a", "spot")
+			published = nil
+			unavailableOfferings.Delete(ec2types.InstanceTypeM5Large, "test-zone-1a", "spot")
+			Expect(published).To(ConsistOf(awscache.Invalidation{InstanceTypes: []string{"m5.large"}}))
+		})
+		It("should invalidate each instance type with an unavailable offering when flushed", func() {
+			unavailableOfferings.MarkUnavailable(context.Background(), "InsufficientInstanceCapacity", ec2types.InstanceTypeM5Large, "test-zone-1a", "spot")
+			unavailableOfferings.MarkUnavailable(context.Background(), "InsufficientInstanceCapacity", ec2types.InstanceTypeM5Large, "test-zone-1b", "spot")
+			unavailableOfferings.MarkUnavailable(context.Background(), "InsufficientInstanceCapacity", ec2types.InstanceTypeC5Large, "test-zone-1a", "on-demand")
+			published = nil
+			unavailableOfferings.Flush()
+			Expect(published).To(ConsistOf(awscache.Invalidation{InstanceTypes: []string{"c5.large", "m5.large"}}))
+		})
+		It("should request rediscovery of an instance type when a launch is unsupported", func() {
+			unavailableOfferings.MarkUnavailableForFleetErr(context.Background(), fleetErr("Unsupported", ec2types.InstanceTypeM5Large), "spot")
+			Expect(published).To(ConsistOf(
+				awscache.Invalidation{InstanceTypes: []string{"m5.large"}},
+				awscache.Invalidation{InstanceTypes: []string{"m5.large"}, Rediscover: true},
+			))
+		})
+		It("should not request rediscovery of an instance type when capacity is unavailable", func() {
+			unavailableOfferings.MarkUnavailableForFleetErr(context.Background(), fleetErr("InsufficientInstanceCapacity", ec2types.InstanceTypeM5Large), "spot")
+			Expect(published).To(ConsistOf(awscache.Invalidation{InstanceTypes: []string{"m5.large"}}))
+		})
+	})
+})
@@ -17,32 +17,36 @@ package cache
 import (
 	"context"
 	"fmt"
-	"sync/atomic"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/samber/lo"
 
 	"github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 )
 
 // UnavailableOfferings stores any offerings that return ICE (insufficient capacity errors) when
 // attempting to launch the capacity. These offerings are ignored as long as they are in the cache on
-// GetInstanceTypes responses
+// GetInstanceTypes responses. Each time an offering is marked unavailable or becomes available again, the offerings of
+// its instance type are invalidated.
 type UnavailableOfferings struct {
 	// key: <capacityType>:<instanceType>:<zone>, value: struct{}{}
-	cache  *cache.Cache
-	SeqNum uint64
+	cache         *cache.Cache
+	invalidations *InvalidationBus
 }
 
-func NewUnavailableOfferings() *UnavailableOfferings {
+func NewUnavailableOfferings(invalidations *InvalidationBus) *UnavailableOfferings {
 	uo := &UnavailableOfferings{
-		cache:  cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
-		SeqNum: 0,
+		cache:         cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
+		invalidations: invalidations,
 	}
-	uo.cache.OnEvicted(func(_ string, _ interface{}) {
-		atomic.AddUint64(&uo.SeqNum, 1)
+	uo.cache.OnEvicted(func(key string, _ interface{}) {
+		uo.invalidate(key)
 	})
 	return uo
 }
@@ -63,24 +67,47 @@ func (u *UnavailableOfferings) MarkUnavailable(ctx context.Context, unavailableR
 		"capacity-type", capacityType,
 		"ttl", UnavailableOfferingsTTL).V(1).Info("removing offering from offerings")
 	u.cache.SetDefault(u.key(instanceType, zone, capacityType), struct{}{})
-	atomic.AddUint64(&u.SeqNum, 1)
+	u.invalidate(u.key(instanceType, zone, capacityType))
 }
 
 func (u *UnavailableOfferings) MarkUnavailableForFleetErr(ctx context.Context, fleetErr ec2types.CreateFleetError, capacityType string) {
 	instanceType := fleetErr.LaunchTemplateAndOverrides.Overrides.InstanceType
 	zone := aws.ToString(fleetErr.LaunchTemplateAndOverrides.Overrides.AvailabilityZone)
 	u.MarkUnavailable(ctx, lo.FromPtr(fleetErr.ErrorCode), instanceType, zone, capacityType)
+	if fleetErr.ErrorCode != nil && awserrors.IsUnsupported(fleetErr) {
+		u.invalidations.Publish(Invalidation{InstanceTypes: []string{string(instanceType)}, Rediscover: true})
+	}
 }
 
 func (u *UnavailableOfferings) Delete(instanceType ec2types.InstanceType, zone string, capacityType string) {
+	// Deleting an entry calls the eviction callback, which invalidates the instance type
 	u.cache.Delete(u.key(instanceType, zone, capacityType))
 }
 
 func (u *UnavailableOfferings) Flush() {
+	// Flushing doesn't call the eviction callback, so the instance types are invalidated explicitly
+	instanceTypes := sets.New[string]()
+	for key := range u.cache.Items() {
+		instanceTypes.Insert(instanceTypeFromKey(key))
+	}
 	u.cache.Flush()
+	u.invalidations.Publish(Invalidation{InstanceTypes: sets.List(instanceTypes)})
+}
+
+func (u *UnavailableOfferings) invalidate(key string) {
+	if instanceType := instanceTypeFromKey(key); instanceType != "" {
+		u.invalidations.Publish(Invalidation{InstanceTypes: []string{instanceType}})
+	}
 }
 
 // key returns the cache key for all offerings in the cache
 func (u *UnavailableOfferings) key(instanceType ec2types.InstanceType, zone string, capacityType string) string {
 	return fmt.Sprintf("%s:%s:%s", capacityType, instanceType, zone)
 }
+
+func instanceTypeFromKey(key string) string {
+	if parts := strings.Split(key, ":"); len(parts) == 3 {
+		return parts[1]
+	}
+	return ""
+}
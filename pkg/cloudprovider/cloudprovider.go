@@ -16,25 +16,33 @@ package cloudprovider
 
 import (
 	"context"
+	"encoding/json"
 	stderrors "errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/awslabs/operatorpkg/status"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	coreapis "sigs.k8s.io/karpenter/pkg/apis"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/metrics"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
@@ -42,6 +50,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
@@ -56,6 +65,10 @@ import (
 
 var _ cloudprovider.CloudProvider = (*CloudProvider)(nil)
 
+// consoleOutputExcerptLength bounds the console output published in NodeClaim events, leaving room within the event
+// message limit for the rest of the message
+const consoleOutputExcerptLength = 768
+
 type CloudProvider struct {
 	kubeClient client.Client
 	recorder   events.Recorder
@@ -65,6 +78,7 @@ type CloudProvider struct {
 	amiProvider                 amifamily.Provider
 	securityGroupProvider       securitygroup.Provider
 	capacityReservationProvider capacityreservation.Provider
+	clk                         clock.Clock
 }
 
 func New(
@@ -75,6 +89,7 @@ func New(
 	amiProvider amifamily.Provider,
 	securityGroupProvider securitygroup.Provider,
 	capacityReservationProvider capacityreservation.Provider,
+	clk clock.Clock,
 ) *CloudProvider {
 	return &CloudProvider{
 		instanceTypeProvider:        instanceTypeProvider,
@@ -84,12 +99,52 @@ func New(
 		securityGroupProvider:       securityGroupProvider,
 		capacityReservationProvider: capacityReservationProvider,
 		recorder:                    recorder,
+		clk:                         clk,
 	}
 }
 
-// Create a NodeClaim given the constraints.
+// Create a NodeClaim given the constraints. If the NodeClaim can't be launched with its nodeClassRef because the
+// EC2NodeClass isn't ready or has no available capacity, its NodePool's fallback EC2NodeClasses are tried in order.
 func (c *CloudProvider) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim) (*karpv1.NodeClaim, error) {
-	nodeClass, err := c.resolveNodeClassFromNodeClaim(ctx, nodeClaim)
+	nc, err := c.create(ctx, nodeClaim, nodeClaim.Spec.NodeClassRef.Name)
+	if err == nil || !shouldFallBack(err) {
+		return nc, err
+	}
+	for _, name := range c.fallbackNodeClassNames(ctx, nodeClaim) {
+		nc, fallbackErr := c.create(ctx, nodeClaim, name)
+		if fallbackErr == nil {
+			log.FromContext(ctx).WithValues("EC2NodeClass", klog.KRef("", name), "reason", err.Error()).Info("launched with fallback ec2nodeclass")
+			nc.Annotations = lo.Assign(nc.Annotations, map[string]string{v1.AnnotationFallbackNodeClass: name})
+			return nc, nil
+		}
+		if !shouldFallBack(fallbackErr) {
+			return nil, fallbackErr
+		}
+	}
+	return nil, err
+}
+
+// shouldFallBack returns whether a NodeClaim which failed to launch with an EC2NodeClass may be launched with the next
+// fallback EC2NodeClass
+func shouldFallBack(err error) bool {
+	return cloudprovider.IsInsufficientCapacityError(err) || cloudprovider.IsNodeClassNotReadyError(err)
+}
+
+// fallbackNodeClassNames returns the fallback EC2NodeClasses of the NodeClaim's NodePool
+func (c *CloudProvider) fallbackNodeClassNames(ctx context.Context, nodeClaim *karpv1.NodeClaim) []string {
+	nodePoolName, ok := nodeClaim.Labels[karpv1.NodePoolLabelKey]
+	if !ok {
+		return nil
+	}
+	nodePool := &karpv1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
+		return nil
+	}
+	return utils.FallbackNodeClassNames(nodePool)
+}
+
+func (c *CloudProvider) create(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodeClassName string) (*karpv1.NodeClaim, error) {
+	nodeClass, err := c.resolveNodeClass(ctx, nodeClassName)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// We treat a failure to resolve the NodeClass as an ICE since this means there is no capacity possibilities for this NodeClaim
@@ -107,6 +162,9 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	if nodeClassReady.IsUnknown() {
 		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving NodeClass readiness, NodeClass is in Ready=Unknown, %s", nodeClassReady.Message), "NodeClassReadinessUnknown", "NodeClass is in Ready=Unknown")
 	}
+	if err := validateNodeClassLabels(nodeClaim, nodeClass); err != nil {
+		return nil, cloudprovider.NewCreateError(err, "NodeClassLabelConflict", "NodeClass labels conflict with the NodeClaim's labels or requirements")
+	}
 	instanceTypes, err := c.resolveInstanceTypes(ctx, nodeClaim, nodeClass)
 	if err != nil {
 		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving instance types, %w", err), "InstanceTypeResolutionFailed", "Error resolving instance types")
@@ -114,7 +172,7 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	if len(instanceTypes) == 0 {
 		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all requested instance types were unavailable during launch"))
 	}
-	tags, err := utils.GetTags(nodeClass, nodeClaim, options.FromContext(ctx).ClusterName)
+	tags, err := utils.GetTags(nodeClass, nodeClaim, options.FromContext(ctx).ClusterName, options.FromContext(ctx).TagKeyPrefix)
 	if err != nil {
 		return nil, cloudprovider.NewNodeClassNotReadyError(err)
 	}
@@ -128,11 +186,14 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
 		return i.Name == string(instance.Type)
 	})
-	nc := c.instanceToNodeClaim(instance, instanceType, nodeClass)
+	nc := c.instanceToNodeClaim(ctx, instance, instanceType, nodeClass)
 	nc.Annotations = lo.Assign(nc.Annotations, map[string]string{
 		v1.AnnotationEC2NodeClassHash:        nodeClass.Hash(),
 		v1.AnnotationEC2NodeClassHashVersion: v1.EC2NodeClassHashVersion,
 	})
+	if nodeClass.Spec.BootOptions != nil && len(nodeClass.Spec.BootOptions.MeasuredBootPCRs) != 0 {
+		nc.Annotations[v1.AnnotationMeasuredBootPCRs] = string(lo.Must(json.Marshal(nodeClass.Spec.BootOptions.MeasuredBootPCRs)))
+	}
 	return nc, nil
 }
 
@@ -151,7 +212,7 @@ func (c *CloudProvider) List(ctx context.Context) ([]*karpv1.NodeClaim, error) {
 		if client.IgnoreNotFound(err) != nil {
 			return nil, fmt.Errorf("resolving nodeclass, %w", err)
 		}
-		nodeClaims = append(nodeClaims, c.instanceToNodeClaim(instance, instanceType, nc))
+		nodeClaims = append(nodeClaims, c.instanceToNodeClaim(ctx, instance, instanceType, nc))
 	}
 	return nodeClaims, nil
 }
@@ -174,7 +235,7 @@ func (c *CloudProvider) Get(ctx context.Context, providerID string) (*karpv1.Nod
 	if client.IgnoreNotFound(err) != nil {
 		return nil, fmt.Errorf("resolving nodeclass, %w", err)
 	}
-	return c.instanceToNodeClaim(instance, instanceType, nc), nil
+	return c.instanceToNodeClaim(ctx, instance, instanceType, nc), nil
 }
 
 // GetInstanceTypes returns all available InstanceTypes
@@ -202,6 +263,11 @@ func (c *CloudProvider) Delete(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 		return fmt.Errorf("getting instance ID, %w", err)
 	}
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("id", id))
+	// Delete is retried until the instance is terminating, but the failure is only diagnosed once
+	if _, diagnosed := nodeClaim.Annotations[v1.AnnotationRegistrationFailure]; !diagnosed &&
+		nodeClaim.StatusConditions().Get(karpv1.ConditionTypeLaunched).IsTrue() && !nodeClaim.StatusConditions().Get(karpv1.ConditionTypeRegistered).IsTrue() {
+		c.diagnoseRegistrationFailure(ctx, nodeClaim, id)
+	}
 	err = c.instanceProvider.Delete(ctx, id)
 	if id := nodeClaim.Labels[cloudprovider.ReservationIDLabel]; id != "" && cloudprovider.IsNodeClaimNotFoundError(err) {
 		c.capacityReservationProvider.MarkTerminated(id)
@@ -209,6 +275,75 @@ func (c *CloudProvider) Delete(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	return err
 }
 
+// diagnoseRegistrationFailure publishes the tail of the instance's console output and the likely cause of the failure
+// as events on the NodeClaim so that bootstrap failures can be debugged after the instance has been terminated. The
+// NodeClaim is annotated with the cause before the events are published, so that they're only published once. Failures
+// are logged rather than returned since they shouldn't block termination.
+func (c *CloudProvider) diagnoseRegistrationFailure(ctx context.Context, nodeClaim *karpv1.NodeClaim, id string) {
+	instance, err := c.instanceProvider.Get(ctx, id)
+	if err != nil {
+		if !cloudprovider.IsNodeClaimNotFoundError(err) {
+			log.FromContext(ctx).Error(err, "failed getting instance for console output")
+		}
+		return
+	}
+	// The instance is already terminating if a previous attempt failed to annotate the NodeClaim
+	if instance.State == ec2types.InstanceStateNameShuttingDown {
+		return
+	}
+	output, err := c.instanceProvider.GetConsoleOutput(ctx, id)
+	if err != nil {
+		if awserrors.IsUnauthorizedOperationError(err) {
+			log.FromContext(ctx).V(1).Info("not authorized to get console output, skipping")
+		} else if !cloudprovider.IsNodeClaimNotFoundError(err) {
+			log.FromContext(ctx).Error(err, "failed getting console output")
+		}
+		// Continue without the console output since the instance's state can still be used to classify the failure
+		output = ""
+	}
+	cause := classifyRegistrationFailure(instance, output)
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationRegistrationFailure: string(cause)})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		if !errors.IsNotFound(err) {
+			log.FromContext(ctx).Error(err, "failed annotating nodeclaim with registration failure")
+		}
+		return
+	}
+	log.FromContext(ctx).WithValues("cause", cause).Info("nodeclaim failed to register")
+	if excerpt := consoleOutputExcerpt(output); excerpt != "" {
+		c.recorder.Publish(cloudproviderevents.NodeClaimConsoleOutput(nodeClaim, excerpt))
+	}
+	c.recorder.Publish(cloudproviderevents.NodeClaimRegistrationFailed(nodeClaim, string(cause)))
+	RegistrationFailuresTotal.Inc(map[string]string{
+		causeLabel:            string(cause),
+		metrics.NodePoolLabel: nodeClaim.Labels[karpv1.NodePoolLabelKey],
+	})
+}
+
+// consoleOutputExcerpt returns the last lines of the console output that fit within an event message, with any
+// non-printable characters (e.g. terminal escape sequences) removed
+func consoleOutputExcerpt(output string) string {
+	output = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r == '\n' || unicode.IsPrint(r) {
+			return r
+		}
+		return -1
+	}, output))
+	if len(output) <= consoleOutputExcerptLength {
+		return output
+	}
+	output = output[len(output)-consoleOutputExcerptLength:]
+	// Drop the partial rune and line at the start of the excerpt
+	for len(output) > 0 && !utf8.RuneStart(output[0]) {
+		output = output[1:]
+	}
+	if i := strings.Index(output, "\n"); i != -1 {
+		output = output[i+1:]
+	}
+	return output
+}
+
 func (c *CloudProvider) DisruptionReasons() []karpv1.DisruptionReason {
 	return nil
 }
@@ -226,7 +361,16 @@ func (c *CloudProvider) IsDrifted(ctx context.Context, nodeClaim *karpv1.NodeCla
 	if nodePool.Spec.Template.Spec.NodeClassRef == nil {
 		return "", nil
 	}
-	nodeClass, err := c.resolveNodeClassFromNodePool(ctx, nodePool)
+	// NodeClaims launched with a fallback EC2NodeClass are drifted once the NodePool no longer falls back to it, and are
+	// otherwise compared against the EC2NodeClass they were launched with
+	nodeClassName := nodePool.Spec.Template.Spec.NodeClassRef.Name
+	if fallback, ok := nodeClaim.Annotations[v1.AnnotationFallbackNodeClass]; ok {
+		if !lo.Contains(utils.FallbackNodeClassNames(nodePool), fallback) {
+			return NodeClassDrift, nil
+		}
+		nodeClassName = fallback
+	}
+	nodeClass, err := c.resolveNodeClass(ctx, nodeClassName)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// We can't determine the drift status for the NodeClaim if we can no longer resolve the NodeClass
@@ -235,7 +379,7 @@ func (c *CloudProvider) IsDrifted(ctx context.Context, nodeClaim *karpv1.NodeCla
 		}
 		return "", fmt.Errorf("resolving node class, %w", err)
 	}
-	driftReason, err := c.isNodeClassDrifted(ctx, nodeClaim, nodePool, nodeClass)
+	driftReason, err := c.isNodeClassDrifted(ctx, nodeClaim, nodeClass)
 	if err != nil {
 		return "", err
 	}
@@ -294,9 +438,9 @@ func (c *CloudProvider) RepairPolicies() []cloudprovider.RepairPolicy {
 	}
 }
 
-func (c *CloudProvider) resolveNodeClassFromNodeClaim(ctx context.Context, nodeClaim *karpv1.NodeClaim) (*v1.EC2NodeClass, error) {
+func (c *CloudProvider) resolveNodeClass(ctx context.Context, name string) (*v1.EC2NodeClass, error) {
 	nodeClass := &v1.EC2NodeClass{}
-	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: name}, nodeClass); err != nil {
 		return nil, err
 	}
 	// For the purposes of NodeClass CloudProvider resolution, we treat deleting NodeClasses as NotFound
@@ -309,16 +453,7 @@ func (c *CloudProvider) resolveNodeClassFromNodeClaim(ctx context.Context, nodeC
 }
 
 func (c *CloudProvider) resolveNodeClassFromNodePool(ctx context.Context, nodePool *karpv1.NodePool) (*v1.EC2NodeClass, error) {
-	nodeClass := &v1.EC2NodeClass{}
-	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass); err != nil {
-		return nil, err
-	}
-	if !nodeClass.DeletionTimestamp.IsZero() {
-		// For the purposes of NodeClass CloudProvider resolution, we treat deleting NodeClasses as NotFound,
-		// but we return a different error message to be clearer to users
-		return nil, newTerminatingNodeClassError(nodeClass.Name)
-	}
-	return nodeClass, nil
+	return c.resolveNodeClass(ctx, nodePool.Spec.Template.Spec.NodeClassRef.Name)
 }
 
 func (c *CloudProvider) resolveInstanceTypes(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass) ([]*cloudprovider.InstanceType, error) {
@@ -334,6 +469,27 @@ func (c *CloudProvider) resolveInstanceTypes(ctx context.Context, nodeClaim *kar
 	}), nil
 }
 
+// validateNodeClassLabels returns an error if any of the NodeClass's labels conflict with a label or requirement of the
+// NodeClaim, which are inherited from its NodePool. NodeClaim labels take precedence over the labels returned by the
+// cloud provider, so a conflicting NodeClass label would otherwise be silently dropped.
+func validateNodeClassLabels(nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass) error {
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	var conflicts []string
+	for _, key := range lo.Keys(nodeClass.Spec.Labels) {
+		value := nodeClass.Spec.Labels[key]
+		if v, ok := nodeClaim.Labels[key]; ok && v != value {
+			conflicts = append(conflicts, fmt.Sprintf("label %q has value %q, but the NodeClass sets %q", key, v, value))
+		} else if reqs.Has(key) && !reqs.Get(key).Has(value) {
+			conflicts = append(conflicts, fmt.Sprintf("requirement %q doesn't allow the NodeClass value %q", key, value))
+		}
+	}
+	if len(conflicts) != 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("nodeclass labels conflict with nodeclaim, %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
 func (c *CloudProvider) resolveInstanceTypeFromInstance(ctx context.Context, instance *instance.Instance) (*cloudprovider.InstanceType, error) {
 	nodePool, err := c.resolveNodePoolFromInstance(ctx, instance)
 	if err != nil {
@@ -352,7 +508,7 @@ func (c *CloudProvider) resolveInstanceTypeFromInstance(ctx context.Context, ins
 }
 
 func (c *CloudProvider) resolveNodeClassFromInstance(ctx context.Context, instance *instance.Instance) (*v1.EC2NodeClass, error) {
-	name, ok := instance.Tags[v1.NodeClassTagKey]
+	name, ok := options.FromContext(ctx).TagValue(instance.Tags, v1.NodeClassTagKey)
 	if !ok {
 		return nil, errors.NewNotFound(schema.GroupResource{Group: apis.Group, Resource: "ec2nodeclasses"}, "")
 	}
@@ -369,7 +525,7 @@ func (c *CloudProvider) resolveNodeClassFromInstance(ctx context.Context, instan
 }
 
 func (c *CloudProvider) resolveNodePoolFromInstance(ctx context.Context, instance *instance.Instance) (*karpv1.NodePool, error) {
-	if nodePoolName, ok := instance.Tags[karpv1.NodePoolLabelKey]; ok {
+	if nodePoolName, ok := options.FromContext(ctx).TagValue(instance.Tags, v1.NodePoolTagKey); ok {
 		nodePool := &karpv1.NodePool{}
 		if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
 			return nil, err
@@ -380,11 +536,16 @@ func (c *CloudProvider) resolveNodePoolFromInstance(ctx context.Context, instanc
 }
 
 //nolint:gocyclo
-func (c *CloudProvider) instanceToNodeClaim(i *instance.Instance, instanceType *cloudprovider.InstanceType, nodeClass *v1.EC2NodeClass) *karpv1.NodeClaim {
+func (c *CloudProvider) instanceToNodeClaim(ctx context.Context, i *instance.Instance, instanceType *cloudprovider.InstanceType, nodeClass *v1.EC2NodeClass) *karpv1.NodeClaim {
 	nodeClaim := &karpv1.NodeClaim{}
 	labels := map[string]string{}
 	annotations := map[string]string{}
 
+	// NodeClass labels are added first so that they never override the labels resolved from the instance
+	if nodeClass != nil {
+		labels = lo.Assign(nodeClass.Spec.Labels)
+	}
+
 	if instanceType != nil {
 		for key, req := range instanceType.Requirements {
 			// We only want to add a label based on the instance type requirements if there is a single value for that
@@ -426,9 +587,24 @@ func (c *CloudProvider) instanceToNodeClaim(i *instance.Instance, instanceType *
 	if i.CapacityType == karpv1.CapacityTypeReserved {
 		labels[cloudprovider.ReservationIDLabel] = i.CapacityReservationID
 	}
-	if v, ok := i.Tags[karpv1.NodePoolLabelKey]; ok {
+	if v, ok := options.FromContext(ctx).TagValue(i.Tags, v1.NodePoolTagKey); ok {
 		labels[karpv1.NodePoolLabelKey] = v
 	}
+	// Identifiers resolved at launch are propagated to the node when it registers, so that they can be used without
+	// querying EC2. Launch template names contain a "/", which isn't valid in a label value.
+	labels[v1.LabelInstanceID] = i.ID
+	if i.ImageID != "" {
+		labels[v1.LabelImageID] = i.ImageID
+	}
+	for key, value := range map[string]string{
+		v1.AnnotationLaunchTemplateName:    i.LaunchTemplateName,
+		v1.AnnotationLaunchTemplateID:      i.LaunchTemplateID,
+		v1.AnnotationLaunchTemplateVersion: i.LaunchTemplateVersion,
+	} {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
 	nodeClaim.Labels = labels
 	nodeClaim.Annotations = annotations
 	nodeClaim.CreationTimestamp = metav1.Time{Time: i.LaunchTime}
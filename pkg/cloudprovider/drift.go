@@ -39,7 +39,7 @@ const (
 	NodeClassDrift           cloudprovider.DriftReason = "NodeClassDrift"
 )
 
-func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool, nodeClass *v1.EC2NodeClass) (cloudprovider.DriftReason, error) {
+func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass) (cloudprovider.DriftReason, error) {
 	// First check if the node class is statically drifted to save on API calls.
 	if drifted := c.areStaticFieldsDrifted(nodeClaim, nodeClass); drifted != "" {
 		return drifted, nil
@@ -48,7 +48,7 @@ func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv
 	if err != nil {
 		return "", err
 	}
-	amiDrifted, err := c.isAMIDrifted(ctx, nodeClaim, nodePool, instance, nodeClass)
+	amiDrifted, err := c.isAMIDrifted(ctx, nodeClaim, instance, nodeClass)
 	if err != nil {
 		return "", fmt.Errorf("calculating ami drift, %w", err)
 	}
@@ -72,9 +72,9 @@ func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv
 	return drifted, nil
 }
 
-func (c *CloudProvider) isAMIDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool,
-	instance *instance.Instance, nodeClass *v1.EC2NodeClass) (cloudprovider.DriftReason, error) {
-	instanceTypes, err := c.GetInstanceTypes(ctx, nodePool)
+func (c *CloudProvider) isAMIDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, instance *instance.Instance,
+	nodeClass *v1.EC2NodeClass) (cloudprovider.DriftReason, error) {
+	instanceTypes, err := c.instanceTypeProvider.List(ctx, nodeClass)
 	if err != nil {
 		return "", fmt.Errorf("getting instanceTypes, %w", err)
 	}
@@ -87,8 +87,11 @@ func (c *CloudProvider) isAMIDrifted(ctx context.Context, nodeClaim *karpv1.Node
 	if len(nodeClass.Status.AMIs) == 0 {
 		return "", fmt.Errorf("no amis exist given constraints")
 	}
-	mappedAMIs := amifamily.MapToInstanceTypes([]*cloudprovider.InstanceType{nodeInstanceType}, nodeClass.Status.AMIs)
-	if !lo.Contains(lo.Keys(mappedAMIs), instance.ImageID) {
+	// AMI changes only drift nodes during a maintenance window; outside of one, existing nodes keep their AMI
+	if !nodeClass.InAMIMaintenanceWindow(c.clk.Now()) {
+		return "", nil
+	}
+	if !lo.Contains(amifamily.RolloutEligibleAMIs(nodeInstanceType, nodeClass), instance.ImageID) {
 		return AMIDrift, nil
 	}
 	return "", nil
@@ -113,8 +116,12 @@ func (c *CloudProvider) isSubnetDrifted(instance *instance.Instance, nodeClass *
 }
 
 // Checks if the security groups are drifted, by comparing the security groups returned from the SecurityGroupProvider
-// to the ec2 instance security groups
+// to the security groups attached to the ec2 instance's primary network interface. When the EC2NodeClass reconciles
+// security groups in place, they're attached by the nodeclaim.securitygroup controller and never drift the node.
 func (c *CloudProvider) areSecurityGroupsDrifted(ec2Instance *instance.Instance, nodeClass *v1.EC2NodeClass) (cloudprovider.DriftReason, error) {
+	if lo.FromPtr(nodeClass.Spec.SecurityGroupDriftPolicy) == v1.SecurityGroupDriftPolicyReconcile {
+		return "", nil
+	}
 	securityGroupIds := sets.New(lo.Map(nodeClass.Status.SecurityGroups, func(sg v1.SecurityGroup, _ int) string { return sg.ID })...)
 	if len(securityGroupIds) == 0 {
 		return "", fmt.Errorf("no security groups are present in the status")
@@ -15,6 +15,8 @@ limitations under the License.
 package events
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 
 	v1 "sigs.k8s.io/karpenter/pkg/apis/v1"
@@ -38,3 +40,23 @@ func NodeClaimFailedToResolveNodeClass(nodeClaim *v1.NodeClaim) events.Event {
 		DedupeValues:   []string{string(nodeClaim.UID)},
 	}
 }
+
+func NodeClaimConsoleOutput(nodeClaim *v1.NodeClaim, output string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "ConsoleOutput",
+		Message:        fmt.Sprintf("NodeClaim terminated before registering, console output:\n%s", output),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodeClaimRegistrationFailed(nodeClaim *v1.NodeClaim, cause string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "RegistrationFailed",
+		Message:        fmt.Sprintf("NodeClaim terminated before registering, likely cause: %s", cause),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"strings"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily/bootstrap"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+)
+
+// RegistrationFailureCause is the likely reason that an instance failed to register with the cluster
+type RegistrationFailureCause string
+
+const (
+	RegistrationFailureCauseUserData RegistrationFailureCause = "UserData"
+	RegistrationFailureCauseNetwork  RegistrationFailureCause = "Network"
+	RegistrationFailureCauseIAM      RegistrationFailureCause = "IAM"
+	RegistrationFailureCauseAMI      RegistrationFailureCause = "AMI"
+	RegistrationFailureCauseDevices  RegistrationFailureCause = "Devices"
+	RegistrationFailureCauseUnknown  RegistrationFailureCause = "Unknown"
+)
+
+// registrationFailureSignatures are matched, in order, against the lowercased console output. Device validation failures
+// are checked first since they're logged by Karpenter's own userData, then boot failures since nothing else runs on an
+// instance which can't boot the AMI. Authentication failures are checked before network failures since the clients
+// which log them usually retry through transient network errors first.
+var registrationFailureSignatures = []struct {
+	cause    RegistrationFailureCause
+	patterns []string
+}{
+	{
+		cause:    RegistrationFailureCauseDevices,
+		patterns: []string{bootstrap.DeviceValidationFailedMessage},
+	},
+	{
+		cause: RegistrationFailureCauseAMI,
+		patterns: []string{
+			"kernel panic",
+			"not syncing",
+			"no bootable device",
+			"unable to mount root fs",
+			"exec format error",
+			"emergency mode",
+		},
+	},
+	{
+		cause: RegistrationFailureCauseIAM,
+		patterns: []string{
+			"unauthorized",
+			"accessdenied",
+			"access denied",
+			"you must be logged in to the server",
+			"invalidclienttokenid",
+			"expiredtoken",
+			"unable to locate credentials",
+		},
+	},
+	{
+		cause: RegistrationFailureCauseNetwork,
+		patterns: []string{
+			"i/o timeout",
+			"connection timed out",
+			"connection refused",
+			"no route to host",
+			"network is unreachable",
+			"temporary failure in name resolution",
+			"tls handshake timeout",
+		},
+	},
+	{
+		cause: RegistrationFailureCauseUserData,
+		patterns: []string{
+			"failed to run module scripts-user",
+			"failed to run module scripts_user",
+			"failed to parse user data",
+			"syntax error",
+			"command not found",
+			"nodeadm: error",
+			"could not decode nodeconfig",
+		},
+	},
+}
+
+// classifyRegistrationFailure returns the likely cause of a registration failure from the instance's console output and
+// its state as reported by EC2. The console output may be empty if it couldn't be retrieved or hasn't been published
+// yet, in which case only the instance state is considered.
+func classifyRegistrationFailure(instance *instance.Instance, output string) RegistrationFailureCause {
+	output = strings.ToLower(output)
+	for _, signature := range registrationFailureSignatures {
+		for _, pattern := range signature.patterns {
+			if strings.Contains(output, pattern) {
+				return signature.cause
+			}
+		}
+	}
+	// An instance which stopped itself without logging a known failure most often failed to boot the AMI, e.g. because
+	// it's incompatible with the instance type's architecture, boot mode, or drivers
+	if instance != nil && (instance.State == ec2types.InstanceStateNameStopping || instance.State == ec2types.InstanceStateNameStopped) {
+		return RegistrationFailureCauseAMI
+	}
+	return RegistrationFailureCauseUnknown
+}
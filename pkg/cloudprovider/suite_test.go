@@ -16,11 +16,14 @@ package cloudprovider_test
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
 
@@ -35,6 +38,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 
 	opstatus "github.com/awslabs/operatorpkg/status"
 	"github.com/imdario/mergo"
@@ -47,6 +51,7 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -91,7 +96,7 @@ var _ = BeforeSuite(func() {
 	fakeClock = clock.NewFakeClock(time.Now())
 	recorder = events.NewRecorder(&record.FakeRecorder{})
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, recorder,
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, recorder, cloudProvider, cluster, fakeClock)
 })
@@ -247,6 +252,62 @@ var _ = Describe("CloudProvider", func() {
 		Expect(cloudProviderNodeClaim).ToNot(BeNil())
 		Expect(cloudProviderNodeClaim.Status.ImageID).ToNot(BeEmpty())
 	})
+	It("should label the nodeClaim with the instance and image IDs, and annotate its launch template", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).ToNot(HaveOccurred())
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		instanceID := lo.Must(utils.ParseInstanceID(cloudProviderNodeClaim.Status.ProviderID))
+		Expect(cloudProviderNodeClaim.Labels).To(HaveKeyWithValue(v1.LabelInstanceID, instanceID))
+		Expect(cloudProviderNodeClaim.Labels).To(HaveKeyWithValue(v1.LabelImageID, cloudProviderNodeClaim.Status.ImageID))
+
+		launchTemplateName := lo.FromPtr(createFleetInput.LaunchTemplateConfigs[0].LaunchTemplateSpecification.LaunchTemplateName)
+		var launchTemplateID string
+		awsEnv.EC2API.LaunchTemplates.Range(func(_, value any) bool {
+			if lt := value.(ec2types.LaunchTemplate); lo.FromPtr(lt.LaunchTemplateName) == launchTemplateName {
+				launchTemplateID = lo.FromPtr(lt.LaunchTemplateId)
+			}
+			return true
+		})
+		Expect(launchTemplateID).ToNot(BeEmpty())
+		Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationLaunchTemplateName, launchTemplateName))
+		Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationLaunchTemplateID, launchTemplateID))
+		Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationLaunchTemplateVersion, "1"))
+	})
+	It("should return the EC2NodeClass labels as labels on the nodeClaim", func() {
+		nodeClass.Spec.Labels = map[string]string{"network-tier": "public"}
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudProviderNodeClaim.Labels).To(HaveKeyWithValue("network-tier", "public"))
+	})
+	It("should fail to launch when the EC2NodeClass labels conflict with the nodeClaim's labels", func() {
+		nodeClass.Spec.Labels = map[string]string{"network-tier": "public"}
+		nodeClaim.Labels = lo.Assign(nodeClaim.Labels, map[string]string{"network-tier": "private"})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		var createErr *corecloudprovider.CreateError
+		Expect(errors.As(err, &createErr)).To(BeTrue())
+		Expect(createErr.ConditionReason).To(Equal("NodeClassLabelConflict"))
+		Expect(cloudProviderNodeClaim).To(BeNil())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+	})
+	It("should fail to launch when the EC2NodeClass labels conflict with the nodeClaim's requirements", func() {
+		nodeClass.Spec.Labels = map[string]string{"network-tier": "public"}
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+				Key:      "network-tier",
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{"private"},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		var createErr *corecloudprovider.CreateError
+		Expect(errors.As(err, &createErr)).To(BeTrue())
+		Expect(createErr.ConditionReason).To(Equal("NodeClassLabelConflict"))
+		Expect(cloudProviderNodeClaim).To(BeNil())
+	})
 	It("should return availability zone ID as a label on the nodeClaim", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
 		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
@@ -267,8 +328,33 @@ var _ = Describe("CloudProvider", func() {
 		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
 		Expect(err).To(BeNil())
 		Expect(cloudProviderNodeClaim).ToNot(BeNil())
-		Expect(len(lo.Keys(cloudProviderNodeClaim.Annotations))).To(BeNumerically("==", 2))
-		Expect(lo.Keys(cloudProviderNodeClaim.Annotations)).To(ContainElements(v1.AnnotationEC2NodeClassHash, v1.AnnotationEC2NodeClassHashVersion))
+		Expect(len(lo.Keys(cloudProviderNodeClaim.Annotations))).To(BeNumerically("==", 5))
+		Expect(lo.Keys(cloudProviderNodeClaim.Annotations)).To(ContainElements(
+			v1.AnnotationEC2NodeClassHash,
+			v1.AnnotationEC2NodeClassHashVersion,
+			v1.AnnotationLaunchTemplateName,
+			v1.AnnotationLaunchTemplateID,
+			v1.AnnotationLaunchTemplateVersion,
+		))
+	})
+	It("should return the measured boot PCRs as an annotation on the nodeClaim", func() {
+		instances := lo.Map(fake.MakeInstances(), func(info ec2types.InstanceTypeInfo, _ int) ec2types.InstanceTypeInfo {
+			info.SupportedBootModes = []ec2types.BootModeType{ec2types.BootModeTypeUefi}
+			info.NitroTpmSupport = ec2types.NitroTpmSupportSupported
+			return info
+		})
+		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: instances})
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		nodeClass.Spec.BootOptions = &v1.BootOptions{
+			BootMode:         lo.ToPtr(v1.BootModeUEFI),
+			NitroTPM:         lo.ToPtr(true),
+			MeasuredBootPCRs: map[string]v1.PCRDigest{"7": v1.PCRDigest(strings.Repeat("a", 64))},
+		}
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).To(BeNil())
+		Expect(cloudProviderNodeClaim).ToNot(BeNil())
+		Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationMeasuredBootPCRs, fmt.Sprintf(`{"7":"%s"}`, strings.Repeat("a", 64))))
 	})
 	It("should return NodeClass Hash on the nodeClaim", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
@@ -287,6 +373,59 @@ var _ = Describe("CloudProvider", func() {
 		Expect(ok).To(BeTrue())
 		Expect(v).To(Equal(v1.EC2NodeClassHashVersion))
 	})
+	Context("Fallback EC2NodeClasses", func() {
+		var fallbackNodeClass *v1.EC2NodeClass
+		BeforeEach(func() {
+			fallbackNodeClass = test.EC2NodeClass(v1.EC2NodeClass{Status: *nodeClass.Status.DeepCopy()})
+			fallbackNodeClass.StatusConditions().SetTrue(opstatus.ConditionReady)
+			nodePool.Annotations = lo.Assign(nodePool.Annotations, map[string]string{
+				v1.AnnotationFallbackNodeClasses: fmt.Sprintf("missing, %s", fallbackNodeClass.Name),
+			})
+		})
+		It("should launch with the nodeClassRef when it's ready", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, fallbackNodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationFallbackNodeClass))
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationEC2NodeClassHash, nodeClass.Hash()))
+		})
+		It("should launch with a fallback EC2NodeClass when the nodeClassRef isn't ready", func() {
+			nodeClass.StatusConditions().SetFalse(opstatus.ConditionReady, "NodeClassNotReady", "NodeClass not ready")
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, fallbackNodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationFallbackNodeClass, fallbackNodeClass.Name))
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationEC2NodeClassHash, fallbackNodeClass.Hash()))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			tags := createFleetInput.TagSpecifications[0].Tags
+			Expect(tags).To(ContainElement(ec2types.Tag{Key: lo.ToPtr(v1.NodeClassTagKey), Value: lo.ToPtr(fallbackNodeClass.Name)}))
+		})
+		It("should launch with a fallback EC2NodeClass when the nodeClassRef has no available capacity", func() {
+			nodeClass.Status.Subnets = []v1.Subnet{{ID: "subnet-test1", Zone: "test-zone-1a", ZoneID: "tstz1-1a"}}
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"test-zone-1b"}},
+			})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, fallbackNodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationFallbackNodeClass, fallbackNodeClass.Name))
+		})
+		It("should return the nodeClassRef's error when no fallback EC2NodeClass can be launched with", func() {
+			nodeClass.StatusConditions().SetFalse(opstatus.ConditionReady, "NodeClassNotReady", "NodeClass not ready")
+			fallbackNodeClass.StatusConditions().SetFalse(opstatus.ConditionReady, "NodeClassNotReady", "NodeClass not ready")
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, fallbackNodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsNodeClassNotReadyError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should not fall back when the NodePool has no fallback EC2NodeClasses", func() {
+			delete(nodePool.Annotations, v1.AnnotationFallbackNodeClasses)
+			nodeClass.StatusConditions().SetFalse(opstatus.ConditionReady, "NodeClassNotReady", "NodeClass not ready")
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, fallbackNodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsNodeClassNotReadyError(err)).To(BeTrue())
+		})
+	})
 	Context("EC2 Context", func() {
 		contextID := "context-1234"
 		It("should set context on the CreateFleet request if specified on the NodePool", func() {
@@ -781,6 +920,30 @@ var _ = Describe("CloudProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(drifted).To(BeEmpty())
 		})
+		It("should compare a NodeClaim launched with a fallback EC2NodeClass against that EC2NodeClass", func() {
+			fallbackNodeClass := test.EC2NodeClass()
+			fallbackNodeClass.Status = *nodeClass.Status.DeepCopy()
+			fallbackNodeClass.Annotations = lo.Assign(fallbackNodeClass.Annotations, map[string]string{
+				v1.AnnotationEC2NodeClassHash:        fallbackNodeClass.Hash(),
+				v1.AnnotationEC2NodeClassHashVersion: v1.EC2NodeClassHashVersion,
+			})
+			nodePool.Annotations = lo.Assign(nodePool.Annotations, map[string]string{v1.AnnotationFallbackNodeClasses: fallbackNodeClass.Name})
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+				v1.AnnotationFallbackNodeClass: fallbackNodeClass.Name,
+				v1.AnnotationEC2NodeClassHash:  fallbackNodeClass.Hash(),
+			})
+			nodeClass.Status.AMIs = nil
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, fallbackNodeClass)
+			drifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drifted).To(BeEmpty())
+		})
+		It("should return drifted if the NodePool no longer falls back to the EC2NodeClass the NodeClaim was launched with", func() {
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationFallbackNodeClass: "removed"})
+			drifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drifted).To(Equal(cloudprovider.NodeClassDrift))
+		})
 		It("should return drifted if the AMI is not valid", func() {
 			// Instance is a reference to what we return in the GetInstances call
 			instance.ImageId = aws.String(fake.ImageID())
@@ -873,6 +1036,57 @@ var _ = Describe("CloudProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(isDrifted).To(Equal(cloudprovider.SecurityGroupDrift))
 		})
+		Context("Primary Network Interface", func() {
+			var primaryNetworkInterfaceID string
+			BeforeEach(func() {
+				primaryNetworkInterfaceID = fake.NetworkInterfaceID()
+				// The instance's security groups include those of its additional network interfaces
+				instance.SecurityGroups = []ec2types.GroupIdentifier{{GroupId: aws.String(validSecurityGroup)}, {GroupId: aws.String(fake.SecurityGroupID())}}
+				instance.NetworkInterfaces = []ec2types.InstanceNetworkInterface{
+					{
+						NetworkInterfaceId: aws.String(primaryNetworkInterfaceID),
+						Attachment:         &ec2types.InstanceNetworkInterfaceAttachment{DeviceIndex: lo.ToPtr[int32](0), NetworkCardIndex: lo.ToPtr[int32](0)},
+						Groups:             []ec2types.GroupIdentifier{{GroupId: aws.String(validSecurityGroup)}},
+					},
+					{
+						NetworkInterfaceId: aws.String(fake.NetworkInterfaceID()),
+						Attachment:         &ec2types.InstanceNetworkInterfaceAttachment{DeviceIndex: lo.ToPtr[int32](1), NetworkCardIndex: lo.ToPtr[int32](0)},
+						Groups:             []ec2types.GroupIdentifier{{GroupId: instance.SecurityGroups[1].GroupId}},
+					},
+				}
+				awsEnv.EC2API.DescribeInstancesBehavior.Output.Set(&ec2.DescribeInstancesOutput{
+					Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{instance}}},
+				})
+			})
+			It("should only compare the security groups of the primary network interface", func() {
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(BeEmpty())
+			})
+			It("should return drifted if the primary network interface's security groups don't match the discovered values", func() {
+				instance.NetworkInterfaces[0].Groups = []ec2types.GroupIdentifier{{GroupId: aws.String(fake.SecurityGroupID())}}
+				awsEnv.EC2API.DescribeInstancesBehavior.Output.Set(&ec2.DescribeInstancesOutput{
+					Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{instance}}},
+				})
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(Equal(cloudprovider.SecurityGroupDrift))
+				Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+			})
+			It("should not return drifted when the drift policy is Reconcile", func() {
+				nodeClass.Spec.SecurityGroupDriftPolicy = lo.ToPtr(v1.SecurityGroupDriftPolicyReconcile)
+				ExpectApplied(ctx, env.Client, nodeClass)
+				instance.NetworkInterfaces[0].Groups = []ec2types.GroupIdentifier{{GroupId: aws.String(fake.SecurityGroupID())}}
+				awsEnv.EC2API.DescribeInstancesBehavior.Output.Set(&ec2.DescribeInstancesOutput{
+					Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{instance}}},
+				})
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(BeEmpty())
+				// The security groups are reconciled by the nodeclaim.securitygroup controller rather than while checking drift
+				Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+			})
+		})
 		It("should dynamically drift nodeclaims for capacity reservations", func() {
 			nodeClass.Status.CapacityReservations = []v1.CapacityReservation{
 				{
@@ -941,6 +1155,87 @@ var _ = Describe("CloudProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
 		})
+		It("should not return drifted if the instance runs an older AMI which is still selected", func() {
+			amd64Requirements := []corev1.NodeSelectorRequirement{
+				{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}},
+			}
+			nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyAL2023)
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}, NewestCount: lo.ToPtr[int32](2)}}
+			nodeClass.Status.AMIs = []v1.AMI{
+				{ID: fake.ImageID(), Requirements: amd64Requirements},
+				{ID: amdAMIID, Requirements: amd64Requirements},
+			}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(BeEmpty())
+		})
+		It("should return drifted if the instance runs an AMI selected for different requirements", func() {
+			nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyAL2023)
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}, NewestCount: lo.ToPtr[int32](2)}}
+			nodeClass.Status.AMIs = []v1.AMI{
+				{
+					ID: fake.ImageID(),
+					Requirements: []corev1.NodeSelectorRequirement{
+						{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}},
+					},
+				},
+				{
+					ID: amdAMIID,
+					Requirements: []corev1.NodeSelectorRequirement{
+						{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}},
+						{Key: v1.LabelInstanceGPUCount, Operator: corev1.NodeSelectorOpExists},
+					},
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
+		})
+		It("should not return drifted if the instance runs an AMI from before an in-progress rollout", func() {
+			amd64Requirements := []corev1.NodeSelectorRequirement{
+				{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}},
+			}
+			nodeClass.Spec.AMIRollout = &v1.AMIRollout{Duration: metav1.Duration{Duration: time.Hour}}
+			nodeClass.Status.AMIs = []v1.AMI{{ID: fake.ImageID(), Requirements: amd64Requirements}}
+			nodeClass.Status.AMIRollout = &v1.AMIRolloutStatus{
+				PreviousAMIs: []v1.AMI{{ID: amdAMIID, Requirements: amd64Requirements}},
+				StartTime:    metav1.Now(),
+			}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(BeEmpty())
+
+			nodeClass.Status.AMIRollout = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			isDrifted, err = cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
+		})
+		It("should only return AMI drift during a maintenance window", func() {
+			awsEnv.Clock.SetTime(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC))
+			nodeClass.Spec.AMIMaintenanceWindows = []v1.MaintenanceWindow{{
+				Schedule: "0 2 * * *",
+				Duration: metav1.Duration{Duration: time.Hour},
+			}}
+			nodeClass.Status.AMIs = []v1.AMI{{
+				ID: fake.ImageID(),
+				Requirements: []corev1.NodeSelectorRequirement{
+					{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}},
+				},
+			}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(BeEmpty())
+
+			awsEnv.Clock.SetTime(time.Date(2024, time.January, 2, 2, 30, 0, 0, time.UTC))
+			isDrifted, err = cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
+		})
 		Context("Static Drift Detection", func() {
 			BeforeEach(func() {
 				armRequirements := []corev1.NodeSelectorRequirement{
@@ -1186,7 +1481,7 @@ var _ = Describe("CloudProvider", func() {
 				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(100),
 					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
 			}})
-			controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver)
+			controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.VPCEndpointProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.AccountSettingsProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver, awsEnv.HealthTracker, awsEnv.InstanceTypesProvider)
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
 			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{corev1.LabelTopologyZone: "test-zone-1a"}})
@@ -1203,7 +1498,7 @@ var _ = Describe("CloudProvider", func() {
 				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(11),
 					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
 			}})
-			controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver)
+			controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.VPCEndpointProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.AccountSettingsProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver, awsEnv.HealthTracker, awsEnv.InstanceTypesProvider)
 			nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{
 				MaxPods: aws.Int32(1),
 			}
@@ -1224,6 +1519,59 @@ var _ = Describe("CloudProvider", func() {
 			createFleetInput = awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
 			Expect(fake.SubnetsFromFleetRequest(createFleetInput)).To(ConsistOf("test-subnet-1"))
 		})
+		It("should not launch instances into subnets with fewer available IP addresses than the minimum", func() {
+			awsEnv.SubnetCache.Flush()
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{
+				{SubnetId: aws.String("test-subnet-1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(10),
+					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-1")}}},
+				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1b"), AvailabilityZoneId: aws.String("tstz1-1b"), AvailableIpAddressCount: aws.Int32(100),
+					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
+			}})
+			controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.VPCEndpointProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.AccountSettingsProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver, awsEnv.HealthTracker, awsEnv.InstanceTypesProvider)
+			nodeClass.Spec.MinSubnetAvailableIPAddresses = aws.Int32(50)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeRequirements: []corev1.NodeSelectorRequirement{{
+				Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"test-zone-1a", "test-zone-1b"},
+			}}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(fake.SubnetsFromFleetRequest(createFleetInput)).To(ConsistOf("test-subnet-2"))
+		})
+		It("should fail to launch instances when every subnet has fewer available IP addresses than the minimum", func() {
+			awsEnv.SubnetCache.Flush()
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{
+				{SubnetId: aws.String("test-subnet-1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(10),
+					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-1")}}},
+			}})
+			controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.VPCEndpointProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.AccountSettingsProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver, awsEnv.HealthTracker, awsEnv.InstanceTypesProvider)
+			nodeClass.Spec.MinSubnetAvailableIPAddresses = aws.Int32(50)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{corev1.LabelTopologyZone: "test-zone-1a"}})
+			bindings := ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			Expect(bindings).To(HaveLen(0))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(0))
+		})
+		It("should prefer IPv6-only subnets, without counting their IPv4 addresses", func() {
+			awsEnv.SubnetCache.Flush()
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{
+				{SubnetId: aws.String("test-subnet-1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(100),
+					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-1")}}},
+				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(0), Ipv6Native: aws.Bool(true),
+					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
+			}})
+			controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.VPCEndpointProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.AccountSettingsProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver, awsEnv.HealthTracker, awsEnv.InstanceTypesProvider)
+			nodeClass.Spec.MinSubnetAvailableIPAddresses = aws.Int32(50)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{corev1.LabelTopologyZone: "test-zone-1a"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(fake.SubnetsFromFleetRequest(createFleetInput)).To(ConsistOf("test-subnet-2"))
+		})
 		It("should update in-flight IPs when a CreateFleet error occurs", func() {
 			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{
 				{SubnetId: aws.String("test-subnet-1"), AvailabilityZone: aws.String("test-zone-1a"), AvailableIpAddressCount: aws.Int32(10),
@@ -1244,7 +1592,7 @@ var _ = Describe("CloudProvider", func() {
 			}})
 			nodeClass.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{Tags: map[string]string{"Name": "test-subnet-1"}}}
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
-			controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver)
+			controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.VPCEndpointProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.AccountSettingsProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver, awsEnv.HealthTracker, awsEnv.InstanceTypesProvider)
 			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
 			podSubnet1 := coretest.UnschedulablePod()
 			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, podSubnet1)
@@ -1371,6 +1719,158 @@ var _ = Describe("CloudProvider", func() {
 			Expect(lo.Keys(cloudProviderNodeClaim.Status.Allocatable)).ToNot(ContainElement(v1.ResourceEFA))
 		})
 	})
+	Context("Console Output", func() {
+		var nodeClaim *karpv1.NodeClaim
+		BeforeEach(func() {
+			pod := coretest.UnschedulablePod()
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, pod)
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ncs := ExpectNodeClaims(ctx, env.Client)
+			Expect(ncs).To(HaveLen(1))
+			nodeClaim = ncs[0]
+			nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeLaunched)
+			nodeClaim.StatusConditions().SetUnknown(karpv1.ConditionTypeRegistered)
+		})
+		It("should capture the console output for NodeClaims which never registered", func() {
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.GetConsoleOutputBehavior.CalledWithInput.Len()).To(Equal(1))
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationRegistrationFailure, string(cloudprovider.RegistrationFailureCauseUnknown)))
+		})
+		It("should not capture the console output once the registration failure has been diagnosed", func() {
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationRegistrationFailure: string(cloudprovider.RegistrationFailureCauseNetwork)})
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.GetConsoleOutputBehavior.CalledWithInput.Len()).To(Equal(0))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+		})
+		It("should cut the console output excerpt on a rune boundary", func() {
+			fakeRecorder := record.NewFakeRecorder(10)
+			cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(fakeRecorder),
+				env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+			// A single line of multi-byte runes with an odd length, so that the excerpt can't be cut on a line boundary
+			// and its last bytes start in the middle of a rune
+			awsEnv.EC2API.GetConsoleOutputBehavior.Output.Set(&ec2.GetConsoleOutputOutput{
+				Output: lo.ToPtr(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("é", 1000) + "."))),
+			})
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			var consoleOutput string
+			for len(fakeRecorder.Events) > 0 {
+				if event := <-fakeRecorder.Events; strings.Contains(event, "ConsoleOutput") {
+					consoleOutput = event
+				}
+			}
+			Expect(consoleOutput).To(HaveSuffix("é."))
+			Expect(utf8.ValidString(consoleOutput)).To(BeTrue())
+		})
+		It("should not capture the console output for registered NodeClaims", func() {
+			nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.GetConsoleOutputBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should not capture the console output once the instance is terminating", func() {
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(cloudProvider.Delete(ctx, nodeClaim))).To(BeTrue())
+			Expect(awsEnv.EC2API.GetConsoleOutputBehavior.CalledWithInput.Len()).To(Equal(1))
+		})
+		It("should terminate the instance when the console output can't be retrieved", func() {
+			awsEnv.EC2API.GetConsoleOutputBehavior.Error.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+		})
+		expectRegistrationFailure := func(output string, cause cloudprovider.RegistrationFailureCause) {
+			awsEnv.EC2API.GetConsoleOutputBehavior.Output.Set(&ec2.GetConsoleOutputOutput{
+				Output: lo.ToPtr(base64.StdEncoding.EncodeToString([]byte(output))),
+			})
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			ExpectMetricCounterValue(cloudprovider.RegistrationFailuresTotal, 1, map[string]string{
+				"cause":    string(cause),
+				"nodepool": nodePool.Name,
+			})
+			Expect(ExpectExists(ctx, env.Client, nodeClaim).Annotations).To(HaveKeyWithValue(v1.AnnotationRegistrationFailure, string(cause)))
+		}
+		DescribeTable("should classify bootstrap and kubelet failures as user data failures",
+			func(output string) { expectRegistrationFailure(output, cloudprovider.RegistrationFailureCauseUserData) },
+			Entry("cloud-init scripts", "cloud-init[1234]: Failed to run module scripts-user (scripts in /var/lib/cloud/instance/scripts)"),
+			Entry("cloud-init scripts with an underscore", "cloud-init[1234]: failed to run module scripts_user"),
+			Entry("unparsable user data", "cloud-init[1234]: Failed to parse user data: mapping values are not allowed here"),
+			Entry("shell syntax errors", "/var/lib/cloud/instance/scripts/part-001: line 4: syntax error near unexpected token `fi'"),
+			Entry("missing commands", "/var/lib/cloud/instance/scripts/part-001: line 2: /etc/eks/bootstrap.sh: command not found"),
+			Entry("nodeadm errors", `nodeadm[1234]: {"level":"fatal","msg":"Command failed","error":"nodeadm: error: missing cluster name"}`),
+			Entry("invalid NodeConfigs", "nodeadm[1234]: could not decode NodeConfig: unknown field \"spec.kubelet.flag\""),
+		)
+		DescribeTable("should classify network failures",
+			func(output string) { expectRegistrationFailure(output, cloudprovider.RegistrationFailureCauseNetwork) },
+			Entry("dial timeouts", "kubelet[2345]: dial tcp 10.0.0.1:443: i/o timeout"),
+			Entry("connection timeouts", "curl: (28) Failed to connect to 10.0.0.1 port 443: Connection timed out"),
+			Entry("refused connections", "kubelet[2345]: dial tcp 10.0.0.1:443: connect: connection refused"),
+			Entry("unroutable hosts", "kubelet[2345]: dial tcp 10.0.0.1:443: connect: no route to host"),
+			Entry("unreachable networks", "kubelet[2345]: dial tcp 10.0.0.1:443: connect: network is unreachable"),
+			Entry("DNS failures", "curl: (6) Could not resolve host: eks.us-west-2.amazonaws.com: Temporary failure in name resolution"),
+			Entry("TLS handshake timeouts", "kubelet[2345]: net/http: TLS handshake timeout"),
+		)
+		DescribeTable("should classify IAM failures",
+			func(output string) { expectRegistrationFailure(output, cloudprovider.RegistrationFailureCauseIAM) },
+			Entry("unauthorized API server requests", "kubelet[2345]: error: You must be logged in to the server (Unauthorized)"),
+			Entry("denied API calls", "nodeadm[1234]: operation error EKS: DescribeCluster, api error AccessDeniedException: User is not authorized"),
+			Entry("denied access", "aws: An error occurred (AccessDenied) when calling the AssumeRole operation: Access denied"),
+			Entry("invalid tokens", "aws: An error occurred (InvalidClientTokenId) when calling the GetCallerIdentity operation"),
+			Entry("expired tokens", "aws: An error occurred (ExpiredToken) when calling the GetCallerIdentity operation"),
+			Entry("missing credentials", "aws: Unable to locate credentials. You can configure credentials by running \"aws configure\""),
+			Entry("authentication failures behind network retries", "kubelet[2345]: dial tcp 10.0.0.1:443: i/o timeout\nkubelet[2345]: error: You must be logged in to the server (Unauthorized)"),
+		)
+		DescribeTable("should classify AMI failures",
+			func(output string) { expectRegistrationFailure(output, cloudprovider.RegistrationFailureCauseAMI) },
+			Entry("kernel panics", "Kernel panic - not syncing: VFS: Unable to mount root fs on unknown-block(0,0)"),
+			Entry("missing boot devices", "No bootable device."),
+			Entry("binaries for another architecture", "/usr/bin/nodeadm: cannot execute binary file: Exec format error"),
+			Entry("emergency mode", "You are in emergency mode. After logging in, type \"journalctl -xb\" to view system logs"),
+			Entry("boot failures ahead of user data failures", "Kernel panic - not syncing: Attempted to kill init!\ncloud-init[1234]: Failed to run module scripts-user"),
+		)
+		DescribeTable("should classify device validation failures",
+			func(output string) { expectRegistrationFailure(output, cloudprovider.RegistrationFailureCauseDevices) },
+			Entry("missing GPUs", "karpenter device validation failed: GPU devices aren't available"),
+			Entry("device validation ahead of user data failures", "karpenter device validation failed: /dev/nvme1n1 isn't available\ncloud-init[1234]: Failed to run module scripts-user"),
+		)
+		DescribeTable("should classify unrecognized failures as unknown",
+			func(output string) { expectRegistrationFailure(output, cloudprovider.RegistrationFailureCauseUnknown) },
+			Entry("successful boots", "Cloud-init v. 22.2.2 finished at Thu, 01 Jan 2026 00:00:00 +0000. Up 32.45 seconds"),
+			Entry("empty output", ""),
+			Entry("whitespace", "\n\n  \n"),
+			Entry("output truncated in the middle of a signature", "kubelet[2345]: dial tcp 10.0.0.1:443: i/o tim"),
+			Entry("output truncated to a partial line", "[   12.345678] systemd[1]: Started kub"),
+		)
+		It("should classify output truncated to its most recent lines", func() {
+			// The console output only holds the most recent output, so a failure may be all that's left of it
+			expectRegistrationFailure(strings.Repeat("[   1.000000] random: crng init done\n", 2000)+"kubelet[2345]: dial tcp 10.0.0.1:443: i/o timeout", cloudprovider.RegistrationFailureCauseNetwork)
+		})
+		It("should classify an instance which stopped itself without a known failure as an AMI failure", func() {
+			id := lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+			raw, ok := awsEnv.EC2API.Instances.Load(id)
+			Expect(ok).To(BeTrue())
+			ec2Instance := raw.(ec2types.Instance)
+			ec2Instance.State = &ec2types.InstanceState{Name: ec2types.InstanceStateNameStopped}
+			awsEnv.EC2API.Instances.Store(id, ec2Instance)
+			expectRegistrationFailure("", cloudprovider.RegistrationFailureCauseAMI)
+		})
+		It("should prefer a known failure over the instance state", func() {
+			id := lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+			raw, ok := awsEnv.EC2API.Instances.Load(id)
+			Expect(ok).To(BeTrue())
+			ec2Instance := raw.(ec2types.Instance)
+			ec2Instance.State = &ec2types.InstanceState{Name: ec2types.InstanceStateNameStopping}
+			awsEnv.EC2API.Instances.Store(id, ec2Instance)
+			expectRegistrationFailure("kubelet[2345]: dial tcp 10.0.0.1:443: i/o timeout", cloudprovider.RegistrationFailureCauseNetwork)
+		})
+		It("should classify the registration failure without the console output", func() {
+			awsEnv.EC2API.GetConsoleOutputBehavior.Error.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			ExpectMetricCounterValue(cloudprovider.RegistrationFailuresTotal, 1, map[string]string{
+				"cause":    string(cloudprovider.RegistrationFailureCauseUnknown),
+				"nodepool": nodePool.Name,
+			})
+		})
+	})
 	Context("Capacity Reservations", func() {
 		var reservationID string
 		BeforeEach(func() {
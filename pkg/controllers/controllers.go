@@ -16,10 +16,12 @@ package controllers
 
 import (
 	"context"
+	"os"
 
 	"github.com/awslabs/operatorpkg/controller"
 	"github.com/awslabs/operatorpkg/status"
 	"github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 
@@ -27,18 +29,27 @@ import (
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeaccessrequest"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass"
+	nodeclassamihash "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/amihash"
 	nodeclasshash "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/hash"
 	controllersinstancetype "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype"
 	controllersinstancetypecapacity "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype/capacity"
 	controllerspricing "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing"
+	controllerspricingbulk "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing/bulk"
+	controllerspricingoverrides "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing/overrides"
+	controllerspricingsnapshot "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing/snapshot"
+	controllersreservedinstance "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/reservedinstance"
 	ssminvalidation "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/ssm/invalidation"
 	controllersversion "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/version"
+	controllerszone "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/zone"
 	capacityreservationprovider "github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 
+	"github.com/aws/aws-sdk-go-v2/service/route53"
 	servicesqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/samber/lo"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -47,18 +58,35 @@ import (
 
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption"
+	metricscapacitymix "github.com/aws/karpenter-provider-aws/pkg/controllers/metrics/capacitymix"
+	metricscost "github.com/aws/karpenter-provider-aws/pkg/controllers/metrics/cost"
+	metricsinfo "github.com/aws/karpenter-provider-aws/pkg/controllers/metrics/info"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/capacityreservation"
+	nodeclaimcost "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/cost"
+	nodeclaimdns "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/dns"
+	nodeclaimelasticip "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/elasticip"
 	nodeclaimgarbagecollection "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/garbagecollection"
+	nodeclaimsecuritygroup "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/securitygroup"
+	nodeclaimserialconsole "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/serialconsole"
+	nodeclaimsourcedestcheck "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/sourcedestcheck"
+	nodeclaimspotrequest "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/spotrequest"
 	nodeclaimtagging "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/tagging"
+	nodepoolsurge "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/surge"
+	nodepoolzone "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/zone"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accountsettings"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/reservedinstance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
 )
 
 func NewControllers(
@@ -74,6 +102,7 @@ func NewControllers(
 	validationCache *cache.Cache,
 	cloudProvider cloudprovider.CloudProvider,
 	subnetProvider subnet.Provider,
+	vpcEndpointProvider vpcendpoint.Provider,
 	securityGroupProvider securitygroup.Provider,
 	instanceProfileProvider instanceprofile.Provider,
 	instanceProvider instance.Provider,
@@ -83,25 +112,59 @@ func NewControllers(
 	versionProvider *version.DefaultProvider,
 	instanceTypeProvider *instancetype.DefaultProvider,
 	capacityReservationProvider capacityreservationprovider.Provider,
+	reservedInstanceProvider reservedinstance.Provider,
+	zoneProvider zone.Provider,
+	accountSettingsProvider accountsettings.Provider,
 	amiResolver amifamily.Resolver,
+	amiHashStore *amifamily.HashStore,
+	invalidationBus *awscache.InvalidationBus,
+	healthTracker *health.Tracker,
 ) []controller.Controller {
 	controllers := []controller.Controller{
 		nodeclasshash.NewController(kubeClient),
-		nodeclass.NewController(clk, kubeClient, recorder, subnetProvider, securityGroupProvider, amiProvider, instanceProfileProvider, launchTemplateProvider, capacityReservationProvider, ec2api, validationCache, amiResolver),
-		nodeclaimgarbagecollection.NewController(kubeClient, cloudProvider),
+		nodeclassamihash.NewController(kubeClient, amiHashStore, invalidationBus),
+		nodeclass.NewController(clk, kubeClient, recorder, subnetProvider, vpcEndpointProvider, securityGroupProvider, amiProvider, instanceProfileProvider, launchTemplateProvider, capacityReservationProvider, accountSettingsProvider, ec2api, validationCache, amiResolver, healthTracker, instanceTypeProvider),
+		nodeclaimgarbagecollection.NewController(kubeClient, cloudProvider, ec2api),
 		nodeclaimtagging.NewController(kubeClient, cloudProvider, instanceProvider),
-		controllerspricing.NewController(pricingProvider),
-		controllersinstancetype.NewController(instanceTypeProvider),
+		metricscost.NewController(kubeClient, cloudProvider, pricingProvider),
+		metricscapacitymix.NewController(instanceProvider),
+		metricsinfo.NewController(kubeClient, mgr.GetAPIReader(), sts.NewFromConfig(cfg), versionProvider, cfg.Region, types.NamespacedName{Namespace: os.Getenv("SYSTEM_NAMESPACE"), Name: options.FromContext(ctx).ProviderInfoConfigMap}),
+		nodeclaimdns.NewController(kubeClient, cloudProvider, route53.NewFromConfig(cfg)),
+		nodeclaimserialconsole.NewController(kubeClient, cloudProvider, accountSettingsProvider, cfg.Region),
+		nodeclaimelasticip.NewController(kubeClient, cloudProvider, ec2api),
+		nodeclaimsecuritygroup.NewController(kubeClient, cloudProvider, instanceProvider),
+		nodeclaimsourcedestcheck.NewController(kubeClient, cloudProvider, instanceProvider),
+		nodeclaimspotrequest.NewController(kubeClient, cloudProvider, ec2api, recorder),
+		nodeclaimcost.NewController(clk, kubeClient, cloudProvider, pricingProvider, recorder),
+		controllerspricing.NewController(pricingProvider, invalidationBus, healthTracker),
+		controllersreservedinstance.NewController(reservedInstanceProvider),
+		controllerszone.NewController(zoneProvider),
+		nodepoolzone.NewController(recorder, cloudProvider, zoneProvider),
+		nodepoolsurge.NewController(clk, kubeClient, recorder, cloudProvider),
+		nodeaccessrequest.NewController(clk, kubeClient, recorder, instanceProvider),
+		controllersinstancetype.NewController(clk, instanceTypeProvider, healthTracker, invalidationBus),
 		controllersinstancetypecapacity.NewController(kubeClient, cloudProvider, instanceTypeProvider),
-		ssminvalidation.NewController(ssmCache, amiProvider),
+		ssminvalidation.NewController(ssmCache, amiProvider, healthTracker),
 		status.NewController[*v1.EC2NodeClass](kubeClient, mgr.GetEventRecorderFor("karpenter"), status.EmitDeprecatedMetrics),
 		controllersversion.NewController(versionProvider, versionProvider.UpdateVersionWithValidation),
 		capacityreservation.NewController(kubeClient, cloudProvider),
 	}
+	if name := options.FromContext(ctx).PricingOverridesConfigMap; name != "" {
+		controllers = append(controllers, controllerspricingoverrides.NewController(mgr.GetAPIReader(), pricingProvider, types.NamespacedName{Namespace: os.Getenv("SYSTEM_NAMESPACE"), Name: name}))
+	}
+	if name := options.FromContext(ctx).PricingSnapshotConfigMap; name != "" {
+		controllers = append(controllers, controllerspricingsnapshot.NewController(kubeClient, mgr.GetAPIReader(), clk, pricingProvider, types.NamespacedName{Namespace: os.Getenv("SYSTEM_NAMESPACE"), Name: name}))
+	}
+	if pricing.UseBulkPricing(ctx, cfg.Region) {
+		controllers = append(controllers, controllerspricingbulk.NewController(pricingProvider))
+	}
 	if options.FromContext(ctx).InterruptionQueue != "" {
 		sqsapi := servicesqs.NewFromConfig(cfg)
 		out := lo.Must(sqsapi.GetQueueUrl(ctx, &servicesqs.GetQueueUrlInput{QueueName: lo.ToPtr(options.FromContext(ctx).InterruptionQueue)}))
-		controllers = append(controllers, interruption.NewController(kubeClient, cloudProvider, clk, recorder, lo.Must(sqs.NewDefaultProvider(sqsapi, lo.FromPtr(out.QueueUrl))), unavailableOfferings))
+		controllers = append(controllers, interruption.NewController(kubeClient, cloudProvider, clk, recorder, lo.Must(sqs.NewDefaultProvider(sqsapi, lo.FromPtr(out.QueueUrl))), unavailableOfferings, instanceProvider))
+		if options.FromContext(ctx).SpotInterruptionDrainDelay > 0 {
+			controllers = append(controllers, interruption.NewReplacementController(clk, kubeClient, cloudProvider, recorder))
+		}
 	}
 	return controllers
 }
@@ -16,7 +16,10 @@ package interruption
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sync"
 	"time"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -25,8 +28,12 @@ import (
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
@@ -39,13 +46,18 @@ import (
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+	"sigs.k8s.io/karpenter/pkg/utils/resources"
 
 	"sigs.k8s.io/karpenter/pkg/events"
 
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	"github.com/aws/karpenter-provider-aws/pkg/cache"
 	interruptionevents "github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/events"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
 type Action string
@@ -55,6 +67,16 @@ const (
 	NoAction       Action = "NoAction"
 )
 
+// reservationTTL is how long a reservation is kept for a replacement which can't be found, which is long enough for the
+// cache to observe a replacement that was just created
+const reservationTTL = time.Minute
+
+// reservation holds a NodePool's limit headroom for a replacement until it's counted in the NodePool's resources
+type reservation struct {
+	resources corev1.ResourceList
+	createdAt time.Time
+}
+
 // Controller is an AWS interruption controller.
 // It continually polls an SQS queue for events from aws.ec2 and aws.health that
 // trigger node health events or node spot interruption/rebalance events.
@@ -65,8 +87,13 @@ type Controller struct {
 	recorder                  events.Recorder
 	sqsProvider               sqs.Provider
 	unavailableOfferingsCache *cache.UnavailableOfferings
+	instanceProvider          instance.Provider
 	parser                    *EventParser
 	cm                        *pretty.ChangeMonitor
+
+	mu sync.Mutex
+	// reservations are keyed by NodePool and replacement name
+	reservations map[string]map[string]reservation
 }
 
 func NewController(
@@ -76,6 +103,7 @@ func NewController(
 	recorder events.Recorder,
 	sqsProvider sqs.Provider,
 	unavailableOfferingsCache *cache.UnavailableOfferings,
+	instanceProvider instance.Provider,
 ) *Controller {
 	return &Controller{
 		kubeClient:                kubeClient,
@@ -84,8 +112,10 @@ func NewController(
 		recorder:                  recorder,
 		sqsProvider:               sqsProvider,
 		unavailableOfferingsCache: unavailableOfferingsCache,
+		instanceProvider:          instanceProvider,
 		parser:                    NewEventParser(DefaultParsers...),
 		cm:                        pretty.NewChangeMonitor(),
+		reservations:              map[string]map[string]reservation{},
 	}
 }
 
@@ -212,23 +242,186 @@ func (c *Controller) handleNodeClaim(ctx context.Context, msg messages.Message,
 		}
 	}
 	if action != NoAction {
-		return c.deleteNodeClaim(ctx, msg, nodeClaim, node)
+		if msg.Kind() == messages.SpotInterruptionKind && options.FromContext(ctx).SpotInterruptionDrainDelay > 0 {
+			return c.replaceNodeClaim(ctx, msg, nodeClaim, node)
+		}
+		return deleteNodeClaim(ctx, c.kubeClient, c.recorder, msg.Kind(), nodeClaim, node)
+	}
+	return nil
+}
+
+// replaceNodeClaim launches a replacement for a NodeClaim which received a spot interruption warning, and marks the
+// NodeClaim so that it's drained by the Replacement controller once the replacement has initialized. NodeClaims whose
+// NodePool no longer exists are deleted immediately.
+func (c *Controller) replaceNodeClaim(ctx context.Context, msg messages.Message, nodeClaim *karpv1.NodeClaim, node *corev1.Node) error {
+	if !nodeClaim.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	// The warning may be delivered more than once
+	if _, ok := nodeClaim.Annotations[v1.AnnotationSpotInterruptedAt]; ok {
+		return nil
+	}
+	nodePool := &karpv1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Labels[karpv1.NodePoolLabelKey]}, nodePool); err != nil {
+		if errors.IsNotFound(err) {
+			return deleteNodeClaim(ctx, c.kubeClient, c.recorder, msg.Kind(), nodeClaim, node)
+		}
+		return fmt.Errorf("getting nodepool, %w", err)
+	}
+	replacement := replacementNodeClaim(nodePool, nodeClaim)
+	if err := c.restrictToVolumeZone(ctx, nodeClaim, replacement); err != nil {
+		return err
+	}
+	// The replacement is launched outside of provisioning, so it has to respect the NodePool's limits itself
+	reserved, err := c.reserve(ctx, nodePool, replacement.Name, nodeClaim.Status.Capacity)
+	if err != nil {
+		return fmt.Errorf("reserving nodepool limits, %w", err)
+	}
+	if !reserved {
+		return deleteNodeClaim(ctx, c.kubeClient, c.recorder, msg.Kind(), nodeClaim, node)
+	}
+	// The replacement's name is deterministic, so it already exists if a previous attempt failed to annotate the NodeClaim
+	if err := c.kubeClient.Create(ctx, replacement); err != nil && !errors.IsAlreadyExists(err) {
+		c.release(nodePool.Name, replacement.Name)
+		return fmt.Errorf("creating replacement nodeclaim, %w", err)
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1.AnnotationSpotInterruptedAt:       msg.StartTime().UTC().Format(time.RFC3339),
+		v1.AnnotationInterruptionReplacement: replacement.Name,
+	})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("annotating the node on interruption message, %w", err))
+	}
+	log.FromContext(ctx).WithValues("replacement", klog.KObj(replacement)).Info("launching replacement from interruption message")
+	c.recorder.Publish(interruptionevents.ReplacingOnInterruption(node, nodeClaim, replacement.Name)...)
+	return nil
+}
+
+// reserve reserves the NodePool's limit headroom for a replacement with the interrupted NodeClaim's capacity, and returns
+// false if the replacement would exceed the NodePool's limits. A replacement isn't counted in the NodePool's resources
+// until it has launched, so the headroom of each replacement which hasn't launched yet is reserved. Otherwise, warnings
+// which are handled at the same time would each fit within the limits on their own, and exceed them together.
+func (c *Controller) reserve(ctx context.Context, nodePool *karpv1.NodePool, name string, capacity corev1.ResourceList) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reservations := c.reservations[nodePool.Name]
+	for replacementName, r := range reservations {
+		replacement := &karpv1.NodeClaim{}
+		if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: replacementName}, replacement); err != nil {
+			if !errors.IsNotFound(err) {
+				return false, fmt.Errorf("getting replacement nodeclaim, %w", err)
+			}
+			if c.clk.Since(r.createdAt) > reservationTTL {
+				delete(reservations, replacementName)
+			}
+			continue
+		}
+		// The replacement's capacity is counted in the NodePool's resources once it has launched
+		if len(replacement.Status.Capacity) != 0 || !replacement.DeletionTimestamp.IsZero() {
+			delete(reservations, replacementName)
+		}
+	}
+	if _, ok := reservations[name]; ok {
+		return true, nil
+	}
+	// The replacement already exists if a previous attempt failed to annotate the NodeClaim
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: name}, &karpv1.NodeClaim{}); err == nil {
+		return true, nil
+	} else if !errors.IsNotFound(err) {
+		return false, fmt.Errorf("getting replacement nodeclaim, %w", err)
+	}
+	usage := resources.Merge(append([]corev1.ResourceList{nodePool.Status.Resources, capacity}, lo.MapToSlice(reservations, func(_ string, r reservation) corev1.ResourceList {
+		return r.resources
+	})...)...)
+	if err := nodePool.Spec.Limits.ExceededBy(usage); err != nil {
+		log.FromContext(ctx).WithValues("NodePool", klog.KObj(nodePool)).V(1).Info(fmt.Sprintf("not launching replacement from interruption message, %s", err))
+		return false, nil
+	}
+	c.reservations[nodePool.Name] = lo.Assign(reservations, map[string]reservation{name: {resources: capacity, createdAt: c.clk.Now()}})
+	return true, nil
+}
+
+// release releases the NodePool's limit headroom for a replacement which couldn't be created
+func (c *Controller) release(nodePoolName, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.reservations[nodePoolName], name)
+}
+
+// restrictToVolumeZone restricts the replacement to the interrupted NodeClaim's zone when the instance has EBS volumes
+// provisioned by the EBS CSI driver. EBS volumes are zonal, so the pods using them can only move to a node in the same
+// zone, and a replacement launched elsewhere would leave them pending until another node is launched. Replacements for
+// consolidation and drift aren't affected, since they're scheduled from the pods, whose persistent volumes already
+// constrain their zone.
+func (c *Controller) restrictToVolumeZone(ctx context.Context, nodeClaim, replacement *karpv1.NodeClaim) error {
+	zone, ok := nodeClaim.Labels[corev1.LabelTopologyZone]
+	if !ok {
+		return nil
 	}
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		return nil
+	}
+	volumeIDs, err := c.instanceProvider.AttachedVolumes(ctx, id)
+	if err != nil {
+		return fmt.Errorf("getting attached volumes, %w", err)
+	}
+	if len(volumeIDs) == 0 {
+		return nil
+	}
+	replacement.Spec.Requirements = append(lo.Reject(replacement.Spec.Requirements, func(r karpv1.NodeSelectorRequirementWithMinValues, _ int) bool {
+		return r.Key == corev1.LabelTopologyZone
+	}), karpv1.NodeSelectorRequirementWithMinValues{
+		NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+			Key:      corev1.LabelTopologyZone,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{zone},
+		},
+	})
+	log.FromContext(ctx).WithValues("zone", zone, "volumes", volumeIDs).V(1).Info("restricting replacement to the zone of attached volumes")
 	return nil
 }
 
+// replacementNodeClaim returns a NodeClaim which replaces the interrupted NodeClaim. It's templated from the NodePool
+// like any other NodeClaim, but keeps the interrupted NodeClaim's spec so that it fits the same pods. The interrupted
+// offering has already been marked unavailable, so the replacement is launched with a different one. The replacement's
+// name is derived from the interrupted NodeClaim's UID so that a retried warning never launches a second replacement.
+func replacementNodeClaim(nodePool *karpv1.NodePool, nodeClaim *karpv1.NodeClaim) *karpv1.NodeClaim {
+	return &karpv1.NodeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("%s-%s", nodePool.Name, replacementSuffix(nodeClaim)),
+			Labels: lo.Assign(nodePool.Spec.Template.Labels, map[string]string{
+				karpv1.NodePoolLabelKey: nodePool.Name,
+			}),
+			Annotations: lo.Assign(nodePool.Spec.Template.Annotations, map[string]string{
+				karpv1.NodePoolHashAnnotationKey:        nodePool.Hash(),
+				karpv1.NodePoolHashVersionAnnotationKey: karpv1.NodePoolHashVersion,
+			}),
+			OwnerReferences: nodeClaim.OwnerReferences,
+		},
+		Spec: *nodeClaim.Spec.DeepCopy(),
+	}
+}
+
+func replacementSuffix(nodeClaim *karpv1.NodeClaim) string {
+	sum := sha256.Sum256([]byte(nodeClaim.UID))
+	return hex.EncodeToString(sum[:])[:10]
+}
+
 // deleteNodeClaim removes the NodeClaim from the api-server
-func (c *Controller) deleteNodeClaim(ctx context.Context, msg messages.Message, nodeClaim *karpv1.NodeClaim, node *corev1.Node) error {
+func deleteNodeClaim(ctx context.Context, kubeClient client.Client, recorder events.Recorder, kind messages.Kind, nodeClaim *karpv1.NodeClaim, node *corev1.Node) error {
 	if !nodeClaim.DeletionTimestamp.IsZero() {
 		return nil
 	}
-	if err := c.kubeClient.Delete(ctx, nodeClaim); err != nil {
+	if err := kubeClient.Delete(ctx, nodeClaim); err != nil {
 		return client.IgnoreNotFound(fmt.Errorf("deleting the node on interruption message, %w", err))
 	}
 	log.FromContext(ctx).Info("initiating delete from interruption message")
-	c.recorder.Publish(interruptionevents.TerminatingOnInterruption(node, nodeClaim)...)
+	recorder.Publish(interruptionevents.TerminatingOnInterruption(node, nodeClaim)...)
 	metrics.NodeClaimsDisruptedTotal.Inc(map[string]string{
-		metrics.ReasonLabel:       string(msg.Kind()),
+		metrics.ReasonLabel:       string(kind),
 		metrics.NodePoolLabel:     nodeClaim.Labels[karpv1.NodePoolLabelKey],
 		metrics.CapacityTypeLabel: nodeClaim.Labels[karpv1.CapacityTypeLabelKey],
 	})
@@ -15,6 +15,8 @@ limitations under the License.
 package events
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
@@ -140,3 +142,23 @@ func TerminatingOnInterruption(node *corev1.Node, nodeClaim *karpv1.NodeClaim) (
 	}
 	return evts
 }
+
+func ReplacingOnInterruption(node *corev1.Node, nodeClaim *karpv1.NodeClaim, replacement string) (evts []events.Event) {
+	evts = append(evts, events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           corev1.EventTypeNormal,
+		Reason:         "ReplacingOnInterruption",
+		Message:        fmt.Sprintf("Interruption triggered launching replacement NodeClaim %s before termination", replacement),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	})
+	if node != nil {
+		evts = append(evts, events.Event{
+			InvolvedObject: node,
+			Type:           corev1.EventTypeNormal,
+			Reason:         "ReplacingOnInterruption",
+			Message:        fmt.Sprintf("Interruption triggered launching replacement NodeClaim %s before termination", replacement),
+			DedupeValues:   []string{string(node.UID)},
+		})
+	}
+	return evts
+}
@@ -0,0 +1,127 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interruption
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// replacementPollInterval is how often a replacement NodeClaim is checked for initialization
+const replacementPollInterval = 5 * time.Second
+
+// ReplacementController drains NodeClaims which received a spot interruption warning once the NodeClaim launched to
+// replace them has initialized, or once the spot interruption drain delay has passed since the warning, whichever is
+// first. The interrupted NodeClaim keeps running its pods while the replacement boots, so that they're evicted onto
+// capacity which is ready to run them.
+type ReplacementController struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+	recorder      events.Recorder
+}
+
+func NewReplacementController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, recorder events.Recorder) *ReplacementController {
+	return &ReplacementController{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+		recorder:      recorder,
+	}
+}
+
+func (c *ReplacementController) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "interruption.replacement")
+
+	if !isAwaitingReplacement(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	replacementName := nodeClaim.Annotations[v1.AnnotationInterruptionReplacement]
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("replacement", klog.KRef("", replacementName)))
+	interruptedAt, err := time.Parse(time.RFC3339, nodeClaim.Annotations[v1.AnnotationSpotInterruptedAt])
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed parsing spot interruption time")
+		return reconcile.Result{}, c.drain(ctx, nodeClaim)
+	}
+	if remaining := interruptedAt.Add(options.FromContext(ctx).SpotInterruptionDrainDelay).Sub(c.clk.Now()); remaining > 0 {
+		replacement := &karpv1.NodeClaim{}
+		if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: replacementName}, replacement); client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, fmt.Errorf("getting replacement nodeclaim, %w", err)
+		} else if err == nil && replacement.DeletionTimestamp.IsZero() && !replacement.StatusConditions().IsTrue(karpv1.ConditionTypeInitialized) {
+			return reconcile.Result{RequeueAfter: min(remaining, replacementPollInterval)}, nil
+		}
+		// The replacement has initialized, or failed to launch, so there's no reason to wait any longer
+	}
+	return reconcile.Result{}, c.drain(ctx, nodeClaim)
+}
+
+// drain deletes the interrupted NodeClaim, which drains its node
+func (c *ReplacementController) drain(ctx context.Context, nodeClaim *karpv1.NodeClaim) error {
+	var node *corev1.Node
+	if nodeClaim.Status.NodeName != "" {
+		node = &corev1.Node{}
+		if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("getting node, %w", err)
+			}
+			node = nil
+		}
+	}
+	return deleteNodeClaim(ctx, c.kubeClient, c.recorder, messages.SpotInterruptionKind, nodeClaim, node)
+}
+
+func (c *ReplacementController) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("interruption.replacement").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isAwaitingReplacement(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isAwaitingReplacement(nc *karpv1.NodeClaim) bool {
+	_, ok := nc.Annotations[v1.AnnotationSpotInterruptedAt]
+	return ok && nc.DeletionTimestamp.IsZero()
+}
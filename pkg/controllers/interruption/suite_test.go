@@ -24,11 +24,14 @@ import (
 	"sigs.k8s.io/karpenter/pkg/metrics"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	servicesqs "github.com/aws/aws-sdk-go-v2/service/sqs"
 	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/aws/smithy-go"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/tools/record"
@@ -41,6 +44,7 @@ import (
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption"
@@ -74,7 +78,9 @@ var sqsapi *fake.SQSAPI
 var sqsProvider *sqs.DefaultProvider
 var unavailableOfferingsCache *awscache.UnavailableOfferings
 var fakeClock *clock.FakeClock
+var cloudProvider *cloudprovider.CloudProvider
 var controller *interruption.Controller
+var replacementController *interruption.ReplacementController
 
 func TestAPIs(t *testing.T) {
 	ctx = TestContextWithLogger(t)
@@ -87,12 +93,12 @@ var _ = BeforeSuite(func() {
 	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...), coretest.WithFieldIndexers(test.NodeInstanceIDFieldIndexer(ctx), test.NodeClaimInstanceIDFieldIndexer(ctx)))
 	awsEnv = test.NewEnvironment(ctx, env)
 	fakeClock = &clock.FakeClock{}
-	unavailableOfferingsCache = awscache.NewUnavailableOfferings()
+	unavailableOfferingsCache = awscache.NewUnavailableOfferings(nil)
 	sqsapi = &fake.SQSAPI{}
 	sqsProvider = lo.Must(sqs.NewDefaultProvider(sqsapi, fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/test-cluster", fake.DefaultRegion, fake.DefaultAccount)))
-	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider)
-	controller = interruption.NewController(env.Client, cloudProvider, fakeClock, events.NewRecorder(&record.FakeRecorder{}), sqsProvider, unavailableOfferingsCache)
+	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+	replacementController = interruption.NewReplacementController(fakeClock, env.Client, cloudProvider, events.NewRecorder(&record.FakeRecorder{}))
 })
 
 var _ = AfterSuite(func() {
@@ -103,6 +109,8 @@ var _ = BeforeEach(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options(coretest.OptionsFields{FeatureGates: coretest.FeatureGates{ReservedCapacity: lo.ToPtr(true)}}))
 	unavailableOfferingsCache.Flush()
 	sqsapi.Reset()
+	// The controller reserves NodePool limits for the replacements it launches, so it isn't shared between tests
+	controller = interruption.NewController(env.Client, cloudProvider, fakeClock, events.NewRecorder(&record.FakeRecorder{}), sqsProvider, unavailableOfferingsCache, awsEnv.InstanceProvider)
 })
 
 var _ = AfterEach(func() {
@@ -257,6 +265,182 @@ var _ = Describe("InterruptionHandling", func() {
 			Expect(unavailableOfferingsCache.IsUnavailable("t3.large", "coretest-zone-1a", karpv1.CapacityTypeSpot)).To(BeTrue())
 		})
 	})
+	Context("Spot Interruption Replacement", func() {
+		var nodePool *karpv1.NodePool
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotInterruptionDrainDelay: lo.ToPtr(90 * time.Second)}))
+			nodePool = coretest.NodePool(karpv1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+			nodeClaim.Labels = lo.Assign(nodeClaim.Labels, map[string]string{karpv1.CapacityTypeLabelKey: karpv1.CapacityTypeSpot})
+			fakeClock.SetTime(time.Now())
+			awsEnv.EC2API.Reset()
+		})
+		AfterEach(func() {
+			ctx = options.ToContext(ctx, test.Options())
+		})
+		// interrupt sends a spot interruption warning for the NodeClaim and returns the replacement NodeClaim
+		interrupt := func() *karpv1.NodeClaim {
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+			ExpectSingletonReconciled(ctx, controller)
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Annotations).To(HaveKey(v1.AnnotationSpotInterruptedAt))
+			Expect(nodeClaim.Annotations).To(HaveKey(v1.AnnotationInterruptionReplacement))
+			return ExpectExists(ctx, env.Client, &karpv1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Name: nodeClaim.Annotations[v1.AnnotationInterruptionReplacement]}})
+		}
+		It("should launch a replacement rather than deleting the NodeClaim", func() {
+			replacement := interrupt()
+			Expect(replacement.Name).ToNot(Equal(nodeClaim.Name))
+			Expect(replacement.Labels).To(HaveKeyWithValue(karpv1.NodePoolLabelKey, "default"))
+			Expect(replacement.Annotations).To(HaveKeyWithValue(karpv1.NodePoolHashAnnotationKey, nodePool.Hash()))
+			Expect(replacement.Spec.NodeClassRef).To(Equal(nodeClaim.Spec.NodeClassRef))
+			Expect(replacement.Spec.Requirements).To(Equal(nodeClaim.Spec.Requirements))
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			_, found := FindMetricWithLabelValues("karpenter_nodeclaims_disrupted_total", map[string]string{
+				metrics.ReasonLabel: "spot_interrupted",
+				"nodepool":          "default",
+			})
+			Expect(found).To(BeFalse())
+		})
+		It("should restrict the replacement to the zone of attached EBS volumes", func() {
+			nodeClaim.Labels[corev1.LabelTopologyZone] = "test-zone-1a"
+			awsEnv.EC2API.DescribeVolumesBehavior.Output.Set(&ec2.DescribeVolumesOutput{
+				Volumes: []ec2types.Volume{{VolumeId: aws.String("vol-0123456789abcdef0")}},
+			})
+			replacement := interrupt()
+			Expect(awsEnv.EC2API.DescribeVolumesBehavior.CalledWithInput.Len()).To(Equal(1))
+			input := awsEnv.EC2API.DescribeVolumesBehavior.CalledWithInput.Pop()
+			Expect(input.Filters).To(ContainElement(ec2types.Filter{
+				Name:   aws.String("attachment.instance-id"),
+				Values: []string{lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))},
+			}))
+			Expect(input.Filters).To(ContainElement(ec2types.Filter{
+				Name:   aws.String("tag-key"),
+				Values: []string{"ebs.csi.aws.com/cluster", "CSIVolumeName"},
+			}))
+			Expect(replacement.Spec.Requirements).To(ContainElement(karpv1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelTopologyZone,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"test-zone-1a"},
+				},
+			}))
+		})
+		It("should not restrict the replacement's zone when no EBS volumes are attached", func() {
+			nodeClaim.Labels[corev1.LabelTopologyZone] = "test-zone-1a"
+			replacement := interrupt()
+			Expect(replacement.Spec.Requirements).ToNot(ContainElement(HaveField("Key", corev1.LabelTopologyZone)))
+		})
+		It("should only launch one replacement when the warning is delivered twice", func() {
+			interrupt()
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectSingletonReconciled(ctx, controller)
+			nodeClaims := &karpv1.NodeClaimList{}
+			Expect(env.Client.List(ctx, nodeClaims)).To(Succeed())
+			Expect(nodeClaims.Items).To(HaveLen(2))
+		})
+		It("should not launch a second replacement when annotating the NodeClaim failed", func() {
+			replacement := interrupt()
+			stored := nodeClaim.DeepCopy()
+			delete(nodeClaim.Annotations, v1.AnnotationSpotInterruptedAt)
+			delete(nodeClaim.Annotations, v1.AnnotationInterruptionReplacement)
+			Expect(env.Client.Patch(ctx, nodeClaim, client.MergeFrom(stored))).To(Succeed())
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectSingletonReconciled(ctx, controller)
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationInterruptionReplacement, replacement.Name))
+			nodeClaims := &karpv1.NodeClaimList{}
+			Expect(env.Client.List(ctx, nodeClaims)).To(Succeed())
+			Expect(nodeClaims.Items).To(HaveLen(2))
+		})
+		It("should delete the NodeClaim rather than exceed the NodePool's limits", func() {
+			nodePool.Spec.Limits = karpv1.Limits{corev1.ResourceCPU: resource.MustParse("4")}
+			nodePool.Status.Resources = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")}
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+			ExpectSingletonReconciled(ctx, controller)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			nodeClaims := &karpv1.NodeClaimList{}
+			Expect(env.Client.List(ctx, nodeClaims)).To(Succeed())
+			Expect(nodeClaims.Items).To(BeEmpty())
+		})
+		It("should not exceed the NodePool's limits when several warnings arrive together", func() {
+			nodePool.Spec.Limits = karpv1.Limits{corev1.ResourceCPU: resource.MustParse("10")}
+			nodePool.Status.Resources = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("6")}
+			ExpectApplied(ctx, env.Client, nodePool)
+			var interrupted []*karpv1.NodeClaim
+			var msgs []interface{}
+			for range 3 {
+				nc, n := coretest.NodeClaimAndNode(karpv1.NodeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							karpv1.NodePoolLabelKey:     "default",
+							karpv1.CapacityTypeLabelKey: karpv1.CapacityTypeSpot,
+						},
+					},
+					Status: karpv1.NodeClaimStatus{
+						ProviderID: fake.RandomProviderID(),
+						Capacity:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+					},
+				})
+				ExpectApplied(ctx, env.Client, nc, n)
+				interrupted = append(interrupted, nc)
+				msgs = append(msgs, spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nc.Status.ProviderID))))
+			}
+			ExpectMessagesCreated(msgs...)
+			ExpectSingletonReconciled(ctx, controller)
+
+			// Only two replacements fit within the limits, so the last NodeClaim is deleted rather than replaced
+			replaced := lo.Filter(interrupted, func(nc *karpv1.NodeClaim, _ int) bool {
+				stored := &karpv1.NodeClaim{}
+				if err := env.Client.Get(ctx, client.ObjectKeyFromObject(nc), stored); err != nil {
+					return false
+				}
+				return stored.DeletionTimestamp.IsZero() && stored.Annotations[v1.AnnotationInterruptionReplacement] != ""
+			})
+			Expect(replaced).To(HaveLen(2))
+			nodeClaims := &karpv1.NodeClaimList{}
+			Expect(env.Client.List(ctx, nodeClaims)).To(Succeed())
+			Expect(nodeClaims.Items).To(HaveLen(4))
+		})
+		It("should delete the NodeClaim when its NodePool no longer exists", func() {
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+			ExpectSingletonReconciled(ctx, controller)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should wait for the replacement to initialize before deleting the NodeClaim", func() {
+			interrupt()
+			result := ExpectObjectReconciled(ctx, env.Client, replacementController, nodeClaim)
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", 5*time.Second))
+			ExpectExists(ctx, env.Client, nodeClaim)
+		})
+		It("should delete the NodeClaim once the replacement has initialized", func() {
+			replacement := interrupt()
+			replacement.StatusConditions().SetTrue(karpv1.ConditionTypeInitialized)
+			ExpectApplied(ctx, env.Client, replacement)
+			ExpectObjectReconciled(ctx, env.Client, replacementController, nodeClaim)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			ExpectMetricCounterValue(metrics.NodeClaimsDisruptedTotal, 1, map[string]string{
+				metrics.ReasonLabel: "spot_interrupted",
+				"nodepool":          "default",
+			})
+		})
+		It("should delete the NodeClaim once the replacement has been deleted", func() {
+			replacement := interrupt()
+			ExpectDeleted(ctx, env.Client, replacement)
+			ExpectObjectReconciled(ctx, env.Client, replacementController, nodeClaim)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should delete the NodeClaim once the drain delay has passed", func() {
+			interrupt()
+			ExpectObjectReconciled(ctx, env.Client, replacementController, nodeClaim)
+			ExpectExists(ctx, env.Client, nodeClaim)
+			fakeClock.Step(90 * time.Second)
+			ExpectObjectReconciled(ctx, env.Client, replacementController, nodeClaim)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+	})
 })
 
 var _ = Describe("Error Handling", func() {
@@ -0,0 +1,132 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacitymix
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/singleton"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+)
+
+var capacityTypes = []string{karpv1.CapacityTypeSpot, karpv1.CapacityTypeOnDemand, karpv1.CapacityTypeReserved}
+
+type poolKey struct {
+	nodePool string
+	zone     string
+}
+
+func (k poolKey) labels(capacityType string) map[string]string {
+	return map[string]string{
+		nodePoolLabel:     k.nodePool,
+		zoneLabel:         k.zone,
+		capacityTypeLabel: capacityType,
+	}
+}
+
+type mix struct {
+	instances map[string]int
+	vcpus     map[string]int32
+}
+
+// Controller reports the mix of spot, on-demand, and reserved capacity of each NodePool in each zone from the
+// instances described at EC2, rather than from NodeClaims, so that it reflects instances which haven't registered or
+// whose NodeClaims were already removed.
+type Controller struct {
+	instanceProvider instance.Provider
+	// keys are the NodePools and zones emitted by the previous reconcile, which are deleted once they have no instances
+	keys map[poolKey]struct{}
+}
+
+func NewController(instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		instanceProvider: instanceProvider,
+		keys:             map[poolKey]struct{}{},
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "metrics.capacitymix")
+
+	instances, err := c.instanceProvider.List(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing instances, %w", err)
+	}
+	mixes := map[poolKey]*mix{}
+	for _, i := range instances {
+		if i.State != ec2types.InstanceStateNamePending && i.State != ec2types.InstanceStateNameRunning {
+			continue
+		}
+		nodePool, ok := options.FromContext(ctx).TagValue(i.Tags, v1.NodePoolTagKey)
+		if !ok {
+			continue
+		}
+		key := poolKey{nodePool: nodePool, zone: i.Zone}
+		if _, ok := mixes[key]; !ok {
+			mixes[key] = &mix{instances: map[string]int{}, vcpus: map[string]int32{}}
+		}
+		mixes[key].instances[i.CapacityType]++
+		mixes[key].vcpus[i.CapacityType] += i.VCPUs
+	}
+	for key, m := range mixes {
+		var total int32
+		for _, vcpus := range m.vcpus {
+			total += vcpus
+		}
+		// Every capacity type is reported for each NodePool and zone, so that a capacity type dropping to zero is visible
+		// rather than the series disappearing
+		for _, capacityType := range capacityTypes {
+			Instances.Set(float64(m.instances[capacityType]), key.labels(capacityType))
+			if total > 0 {
+				VCPUShare.Set(float64(m.vcpus[capacityType])/float64(total), key.labels(capacityType))
+			} else {
+				VCPUShare.Delete(key.labels(capacityType))
+			}
+		}
+	}
+	for key := range c.keys {
+		if _, ok := mixes[key]; ok {
+			continue
+		}
+		for _, capacityType := range capacityTypes {
+			Instances.Delete(key.labels(capacityType))
+			VCPUShare.Delete(key.labels(capacityType))
+		}
+	}
+	c.keys = map[poolKey]struct{}{}
+	for key := range mixes {
+		c.keys[key] = struct{}{}
+	}
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("metrics.capacitymix").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,61 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacitymix
+
+import (
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	nodePoolLabel          = "nodepool"
+	zoneLabel              = "zone"
+	capacityTypeLabel      = "capacity_type"
+)
+
+var (
+	Instances = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "nodepool_instances",
+			Help:      "Number of pending and running instances launched for a NodePool. Broken down by NodePool, zone, and capacity type.",
+		},
+		[]string{
+			nodePoolLabel,
+			zoneLabel,
+			capacityTypeLabel,
+		},
+	)
+	VCPUShare = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "nodepool_vcpu_share",
+			Help:      "Fraction, between 0 and 1, of the vCPUs of a NodePool's pending and running instances in a zone that are of the capacity type. Broken down by NodePool, zone, and capacity type.",
+		},
+		[]string{
+			nodePoolLabel,
+			zoneLabel,
+			capacityTypeLabel,
+		},
+	)
+)
@@ -0,0 +1,151 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacitymix_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/metrics/capacitymix"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *capacitymix.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CapacityMixMetrics")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options(coretest.OptionsFields{FeatureGates: coretest.FeatureGates{ReservedCapacity: lo.ToPtr(true)}}))
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = capacitymix.NewController(awsEnv.InstanceProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = Describe("CapacityMixMetrics", func() {
+	storeInstance := func(nodePool, capacityType, zone string, vcpus int32, state ec2types.InstanceStateName) string {
+		id := fake.InstanceID()
+		instance := ec2types.Instance{
+			InstanceId:   aws.String(id),
+			InstanceType: "m5.large",
+			State:        &ec2types.InstanceState{Name: state},
+			Placement:    &ec2types.Placement{AvailabilityZone: aws.String(zone)},
+			CpuOptions:   &ec2types.CpuOptions{CoreCount: aws.Int32(vcpus / 2), ThreadsPerCore: aws.Int32(2)},
+			Tags: []ec2types.Tag{
+				{Key: aws.String(karpv1.NodePoolLabelKey), Value: aws.String(nodePool)},
+				{Key: aws.String(v1.LabelNodeClass), Value: aws.String("default")},
+				{Key: aws.String(v1.EKSClusterNameTagKey), Value: aws.String(options.FromContext(ctx).ClusterName)},
+			},
+		}
+		switch capacityType {
+		case karpv1.CapacityTypeSpot:
+			instance.SpotInstanceRequestId = aws.String("sir-1234")
+		case karpv1.CapacityTypeReserved:
+			instance.CapacityReservationId = aws.String("cr-1234")
+		}
+		awsEnv.EC2API.Instances.Store(id, instance)
+		return id
+	}
+	expectGauge := func(name, nodePool, zone, capacityType string, value float64) {
+		GinkgoHelper()
+		metric, ok := FindMetricWithLabelValues(name, map[string]string{
+			"nodepool":      nodePool,
+			"zone":          zone,
+			"capacity_type": capacityType,
+		})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("~", value))
+	}
+
+	It("should count the instances of each capacity type", func() {
+		storeInstance("default", karpv1.CapacityTypeSpot, "test-zone-1a", 2, ec2types.InstanceStateNameRunning)
+		storeInstance("default", karpv1.CapacityTypeSpot, "test-zone-1a", 2, ec2types.InstanceStateNamePending)
+		storeInstance("default", karpv1.CapacityTypeOnDemand, "test-zone-1a", 2, ec2types.InstanceStateNameRunning)
+		ExpectSingletonReconciled(ctx, controller)
+		expectGauge("karpenter_cloudprovider_nodepool_instances", "default", "test-zone-1a", karpv1.CapacityTypeSpot, 2)
+		expectGauge("karpenter_cloudprovider_nodepool_instances", "default", "test-zone-1a", karpv1.CapacityTypeOnDemand, 1)
+		expectGauge("karpenter_cloudprovider_nodepool_instances", "default", "test-zone-1a", karpv1.CapacityTypeReserved, 0)
+	})
+	It("should compute the vcpu share of each capacity type", func() {
+		storeInstance("default", karpv1.CapacityTypeSpot, "test-zone-1a", 8, ec2types.InstanceStateNameRunning)
+		storeInstance("default", karpv1.CapacityTypeOnDemand, "test-zone-1a", 4, ec2types.InstanceStateNameRunning)
+		storeInstance("default", karpv1.CapacityTypeReserved, "test-zone-1a", 4, ec2types.InstanceStateNameRunning)
+		ExpectSingletonReconciled(ctx, controller)
+		expectGauge("karpenter_cloudprovider_nodepool_vcpu_share", "default", "test-zone-1a", karpv1.CapacityTypeSpot, 0.5)
+		expectGauge("karpenter_cloudprovider_nodepool_vcpu_share", "default", "test-zone-1a", karpv1.CapacityTypeOnDemand, 0.25)
+		expectGauge("karpenter_cloudprovider_nodepool_vcpu_share", "default", "test-zone-1a", karpv1.CapacityTypeReserved, 0.25)
+	})
+	It("should break down the mix by nodepool and zone", func() {
+		storeInstance("default", karpv1.CapacityTypeSpot, "test-zone-1a", 2, ec2types.InstanceStateNameRunning)
+		storeInstance("default", karpv1.CapacityTypeOnDemand, "test-zone-1b", 2, ec2types.InstanceStateNameRunning)
+		storeInstance("batch", karpv1.CapacityTypeOnDemand, "test-zone-1a", 2, ec2types.InstanceStateNameRunning)
+		ExpectSingletonReconciled(ctx, controller)
+		expectGauge("karpenter_cloudprovider_nodepool_vcpu_share", "default", "test-zone-1a", karpv1.CapacityTypeSpot, 1)
+		expectGauge("karpenter_cloudprovider_nodepool_vcpu_share", "default", "test-zone-1b", karpv1.CapacityTypeOnDemand, 1)
+		expectGauge("karpenter_cloudprovider_nodepool_vcpu_share", "batch", "test-zone-1a", karpv1.CapacityTypeOnDemand, 1)
+		expectGauge("karpenter_cloudprovider_nodepool_vcpu_share", "batch", "test-zone-1a", karpv1.CapacityTypeSpot, 0)
+	})
+	It("should not count stopped instances", func() {
+		storeInstance("default", karpv1.CapacityTypeOnDemand, "test-zone-1a", 2, ec2types.InstanceStateNameRunning)
+		storeInstance("default", karpv1.CapacityTypeOnDemand, "test-zone-1a", 2, ec2types.InstanceStateNameStopped)
+		ExpectSingletonReconciled(ctx, controller)
+		expectGauge("karpenter_cloudprovider_nodepool_instances", "default", "test-zone-1a", karpv1.CapacityTypeOnDemand, 1)
+	})
+	It("should remove the metrics once a nodepool has no instances in a zone", func() {
+		id := storeInstance("default", karpv1.CapacityTypeOnDemand, "test-zone-1c", 2, ec2types.InstanceStateNameRunning)
+		ExpectSingletonReconciled(ctx, controller)
+		expectGauge("karpenter_cloudprovider_nodepool_instances", "default", "test-zone-1c", karpv1.CapacityTypeOnDemand, 1)
+		awsEnv.EC2API.Instances.Delete(id)
+		ExpectSingletonReconciled(ctx, controller)
+		_, ok := FindMetricWithLabelValues("karpenter_cloudprovider_nodepool_instances", map[string]string{
+			"nodepool":      "default",
+			"zone":          "test-zone-1c",
+			"capacity_type": karpv1.CapacityTypeOnDemand,
+		})
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,143 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cost
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/singleton"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+)
+
+type costKey struct {
+	nodePool     string
+	nodeClass    string
+	capacityType string
+	zone         string
+}
+
+func (k costKey) labels() map[string]string {
+	return map[string]string{
+		nodePoolLabel:     k.nodePool,
+		nodeClassLabel:    k.nodeClass,
+		capacityTypeLabel: k.capacityType,
+		zoneLabel:         k.zone,
+	}
+}
+
+// Controller estimates the hourly price of the instances launched for NodeClaims from the current prices, so that
+// spend can be tracked as capacity changes rather than when it's billed. It also estimates how much each NodePool's
+// spot instances save over launching the same instances as on-demand.
+type Controller struct {
+	kubeClient      client.Client
+	cloudProvider   cloudprovider.CloudProvider
+	pricingProvider pricing.Provider
+	// keys are the label sets emitted by the previous reconcile, which are deleted once they no longer have capacity
+	keys map[costKey]struct{}
+	// savingsKeys are the NodePools with spot savings emitted by the previous reconcile
+	savingsKeys sets.Set[string]
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, pricingProvider pricing.Provider) *Controller {
+	return &Controller{
+		kubeClient:      kubeClient,
+		cloudProvider:   cloudProvider,
+		pricingProvider: pricingProvider,
+		keys:            map[costKey]struct{}{},
+		savingsKeys:     sets.New[string](),
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "metrics.cost")
+
+	nodeClaims, err := nodeclaimutils.ListManaged(ctx, c.kubeClient, c.cloudProvider)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	prices := map[costKey]float64{}
+	savings := map[string]float64{}
+	for _, nc := range nodeClaims {
+		// The instance hasn't been launched yet
+		if nc.Status.ProviderID == "" {
+			continue
+		}
+		key := costKey{
+			nodePool:     nc.Labels[karpv1.NodePoolLabelKey],
+			capacityType: nc.Labels[karpv1.CapacityTypeLabelKey],
+			zone:         nc.Labels[corev1.LabelTopologyZone],
+		}
+		if nc.Spec.NodeClassRef != nil {
+			key.nodeClass = nc.Spec.NodeClassRef.Name
+		}
+		instanceType := ec2types.InstanceType(nc.Labels[corev1.LabelInstanceTypeStable])
+		price, ok := pricing.InstancePrice(c.pricingProvider, instanceType, key.capacityType, key.zone)
+		if !ok {
+			continue
+		}
+		prices[key] += price
+		if key.capacityType != karpv1.CapacityTypeSpot {
+			continue
+		}
+		if odPrice, ok := c.pricingProvider.ZonalOnDemandPrice(instanceType, key.zone); ok {
+			// Spot prices can exceed the on-demand price, in which case the instance costs more than it would have
+			savings[key.nodePool] += odPrice - price
+		}
+	}
+	for key, price := range prices {
+		HourlyPriceEstimate.Set(price, key.labels())
+	}
+	for key := range c.keys {
+		if _, ok := prices[key]; !ok {
+			HourlyPriceEstimate.Delete(key.labels())
+		}
+	}
+	c.keys = map[costKey]struct{}{}
+	for key := range prices {
+		c.keys[key] = struct{}{}
+	}
+	for nodePool, saving := range savings {
+		SpotSavingsEstimate.Set(saving, map[string]string{nodePoolLabel: nodePool})
+	}
+	for nodePool := range c.savingsKeys {
+		if _, ok := savings[nodePool]; !ok {
+			SpotSavingsEstimate.Delete(map[string]string{nodePoolLabel: nodePool})
+		}
+	}
+	c.savingsKeys = sets.KeySet(savings)
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("metrics.cost").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,61 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cost
+
+import (
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	nodePoolLabel          = "nodepool"
+	nodeClassLabel         = "nodeclass"
+	capacityTypeLabel      = "capacity_type"
+	zoneLabel              = "zone"
+)
+
+var (
+	HourlyPriceEstimate = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "nodeclaims_hourly_price_estimate",
+			Help:      "Estimated hourly price of the instances launched for NodeClaims, based on current on-demand and spot prices. Broken down by NodePool, NodeClass, capacity type, and zone.",
+		},
+		[]string{
+			nodePoolLabel,
+			nodeClassLabel,
+			capacityTypeLabel,
+			zoneLabel,
+		},
+	)
+	SpotSavingsEstimate = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "nodepool_spot_savings_hourly_estimate",
+			Help:      "Estimated hourly savings of the spot instances launched for NodeClaims, compared to the on-demand price of the same instance types in the same zones. Broken down by NodePool.",
+		},
+		[]string{
+			nodePoolLabel,
+		},
+	)
+)
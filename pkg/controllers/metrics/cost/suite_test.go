@@ -0,0 +1,203 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cost_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/metrics/cost"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *cost.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CostMetrics")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+	controller = cost.NewController(env.Client, cloudProvider, awsEnv.PricingProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("CostMetrics", func() {
+	var nodePool *karpv1.NodePool
+	var nodeClass *v1.EC2NodeClass
+
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass()
+		nodePool = coretest.NodePool()
+	})
+	nodeClaim := func(instanceType, capacityType, zone string) *karpv1.NodeClaim {
+		return coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					karpv1.NodePoolLabelKey:        nodePool.Name,
+					karpv1.CapacityTypeLabelKey:    capacityType,
+					corev1.LabelTopologyZone:       zone,
+					corev1.LabelInstanceTypeStable: instanceType,
+				},
+			},
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+	}
+	expectPrice := func(capacityType, zone string, price float64) {
+		GinkgoHelper()
+		metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_nodeclaims_hourly_price_estimate", map[string]string{
+			"nodepool":      nodePool.Name,
+			"nodeclass":     nodeClass.Name,
+			"capacity_type": capacityType,
+			"zone":          zone,
+		})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("~", price))
+	}
+
+	It("should sum the on-demand price of nodeclaims", func() {
+		odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass,
+			nodeClaim("m5.large", karpv1.CapacityTypeOnDemand, "test-zone-1a"),
+			nodeClaim("m5.large", karpv1.CapacityTypeOnDemand, "test-zone-1a"),
+		)
+		ExpectSingletonReconciled(ctx, controller)
+		expectPrice(karpv1.CapacityTypeOnDemand, "test-zone-1a", 2*odPrice)
+	})
+	It("should break down the price by capacity type and zone", func() {
+		odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+		spotPrice := lo.Must(awsEnv.PricingProvider.SpotPrice("m5.large", "test-zone-1b"))
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass,
+			nodeClaim("m5.large", karpv1.CapacityTypeOnDemand, "test-zone-1a"),
+			nodeClaim("m5.large", karpv1.CapacityTypeSpot, "test-zone-1b"),
+		)
+		ExpectSingletonReconciled(ctx, controller)
+		expectPrice(karpv1.CapacityTypeOnDemand, "test-zone-1a", odPrice)
+		expectPrice(karpv1.CapacityTypeSpot, "test-zone-1b", spotPrice)
+	})
+	It("should price reserved capacity at the on-demand price", func() {
+		odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim("m5.large", karpv1.CapacityTypeReserved, "test-zone-1a"))
+		ExpectSingletonReconciled(ctx, controller)
+		expectPrice(karpv1.CapacityTypeReserved, "test-zone-1a", odPrice)
+	})
+	Context("Spot Savings", func() {
+		expectSavings := func(savings float64) {
+			GinkgoHelper()
+			metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_nodepool_spot_savings_hourly_estimate", map[string]string{
+				"nodepool": nodePool.Name,
+			})
+			Expect(ok).To(BeTrue())
+			Expect(metric.GetGauge().GetValue()).To(BeNumerically("~", savings))
+		}
+
+		It("should estimate the savings of spot nodeclaims over on-demand", func() {
+			odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+			spotPriceA := lo.Must(awsEnv.PricingProvider.SpotPrice("m5.large", "test-zone-1a"))
+			spotPriceB := lo.Must(awsEnv.PricingProvider.SpotPrice("m5.large", "test-zone-1b"))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass,
+				nodeClaim("m5.large", karpv1.CapacityTypeSpot, "test-zone-1a"),
+				nodeClaim("m5.large", karpv1.CapacityTypeSpot, "test-zone-1b"),
+				nodeClaim("m5.large", karpv1.CapacityTypeOnDemand, "test-zone-1a"),
+			)
+			ExpectSingletonReconciled(ctx, controller)
+			expectSavings((odPrice - spotPriceA) + (odPrice - spotPriceB))
+		})
+		It("should not estimate savings for nodepools without spot nodeclaims", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim("m5.large", karpv1.CapacityTypeOnDemand, "test-zone-1a"))
+			ExpectSingletonReconciled(ctx, controller)
+			_, ok := FindMetricWithLabelValues("karpenter_cloudprovider_nodepool_spot_savings_hourly_estimate", map[string]string{
+				"nodepool": nodePool.Name,
+			})
+			Expect(ok).To(BeFalse())
+		})
+		It("should remove the savings once the spot nodeclaims are gone", func() {
+			nc := nodeClaim("m5.large", karpv1.CapacityTypeSpot, "test-zone-1a")
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nc)
+			ExpectSingletonReconciled(ctx, controller)
+			expectSavings(lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large")) - lo.Must(awsEnv.PricingProvider.SpotPrice("m5.large", "test-zone-1a")))
+			ExpectDeleted(ctx, env.Client, nc)
+			ExpectSingletonReconciled(ctx, controller)
+			_, ok := FindMetricWithLabelValues("karpenter_cloudprovider_nodepool_spot_savings_hourly_estimate", map[string]string{
+				"nodepool": nodePool.Name,
+			})
+			Expect(ok).To(BeFalse())
+		})
+	})
+	It("should remove the estimate once the nodeclaims are gone", func() {
+		nc := nodeClaim("m5.large", karpv1.CapacityTypeOnDemand, "test-zone-1c")
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nc)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectDeleted(ctx, env.Client, nc)
+		ExpectSingletonReconciled(ctx, controller)
+		_, ok := FindMetricWithLabelValues("karpenter_cloudprovider_nodeclaims_hourly_price_estimate", map[string]string{
+			"nodepool":      nodePool.Name,
+			"capacity_type": karpv1.CapacityTypeOnDemand,
+			"zone":          "test-zone-1c",
+		})
+		Expect(ok).To(BeFalse())
+	})
+})
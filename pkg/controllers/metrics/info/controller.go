@@ -0,0 +1,193 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package info
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
+)
+
+// Info describes a Karpenter deployment, so that fleet management tooling can audit deployments across clusters
+type Info struct {
+	Version           string
+	Partition         string
+	Region            string
+	AccountID         string
+	KubernetesVersion string
+	// FeatureGates are the names of the enabled feature gates
+	FeatureGates []string
+}
+
+func (i Info) labels() map[string]string {
+	return map[string]string{
+		versionLabel:           i.Version,
+		partitionLabel:         i.Partition,
+		regionLabel:            i.Region,
+		accountIDLabel:         i.AccountID,
+		kubernetesVersionLabel: i.KubernetesVersion,
+		featureGatesLabel:      strings.Join(i.FeatureGates, ","),
+	}
+}
+
+func (i Info) data() map[string]string {
+	return map[string]string{
+		"version":           i.Version,
+		"partition":         i.Partition,
+		"region":            i.Region,
+		"accountID":         i.AccountID,
+		"kubernetesVersion": i.KubernetesVersion,
+		"featureGates":      strings.Join(i.FeatureGates, ","),
+	}
+}
+
+// Controller publishes the provider version and the environment Karpenter is running in as the
+// karpenter_cloudprovider_info metric and, if configured, to a ConfigMap. The account and partition are discovered
+// once with sts:GetCallerIdentity, which doesn't require any permissions.
+type Controller struct {
+	kubeClient      client.Client
+	kubeReader      client.Reader
+	stsapi          sdk.STSAPI
+	versionProvider version.Provider
+	region          string
+	// configMap is the ConfigMap the info is written to. It isn't written if the name is empty.
+	configMap types.NamespacedName
+
+	identity  *arn.ARN
+	published *Info
+	written   *Info
+}
+
+func NewController(kubeClient client.Client, kubeReader client.Reader, stsapi sdk.STSAPI, versionProvider version.Provider, region string, configMap types.NamespacedName) *Controller {
+	return &Controller{
+		kubeClient:      kubeClient,
+		kubeReader:      kubeReader,
+		stsapi:          stsapi,
+		versionProvider: versionProvider,
+		region:          region,
+		configMap:       configMap,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "metrics.info")
+
+	// The info is published without the account and partition if the caller identity can't be retrieved, so that
+	// the rest of it is still available
+	identityErr := c.resolveIdentity(ctx)
+	info := c.info(ctx)
+	if c.published == nil || !maps.Equal(c.published.data(), info.data()) {
+		ProviderInfo.Reset()
+		ProviderInfo.Set(1, info.labels())
+		c.published = &info
+	}
+	if c.configMap.Name != "" && (c.written == nil || !maps.Equal(c.written.data(), info.data())) {
+		if err := c.write(ctx, info); err != nil {
+			return reconcile.Result{}, err
+		}
+		c.written = &info
+	}
+	if identityErr != nil {
+		return reconcile.Result{}, identityErr
+	}
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+func (c *Controller) resolveIdentity(ctx context.Context) error {
+	if c.identity != nil {
+		return nil
+	}
+	out, err := c.stsapi.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return fmt.Errorf("getting caller identity, %w", err)
+	}
+	identity, err := arn.Parse(lo.FromPtr(out.Arn))
+	if err != nil {
+		return fmt.Errorf("parsing caller identity, %w", err)
+	}
+	c.identity = &identity
+	return nil
+}
+
+func (c *Controller) info(ctx context.Context) Info {
+	gates := coreoptions.FromContext(ctx).FeatureGates
+	info := Info{
+		Version:           operator.Version,
+		Region:            c.region,
+		KubernetesVersion: c.versionProvider.Get(ctx),
+		FeatureGates: lo.Keys(lo.PickBy(map[string]bool{
+			"NodeRepair":              gates.NodeRepair,
+			"ReservedCapacity":        gates.ReservedCapacity,
+			"SpotToSpotConsolidation": gates.SpotToSpotConsolidation,
+		}, func(_ string, enabled bool) bool { return enabled })),
+	}
+	sort.Strings(info.FeatureGates)
+	if c.identity != nil {
+		info.Partition = c.identity.Partition
+		info.AccountID = c.identity.AccountID
+	}
+	return info
+}
+
+func (c *Controller) write(ctx context.Context, info Info) error {
+	cm := &corev1.ConfigMap{}
+	if err := c.kubeReader.Get(ctx, c.configMap, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("getting provider info configmap, %w", err)
+		}
+		if err := c.kubeClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: c.configMap.Namespace, Name: c.configMap.Name},
+			Data:       info.data(),
+		}); err != nil {
+			return fmt.Errorf("creating provider info configmap, %w", err)
+		}
+		return nil
+	}
+	stored := cm.DeepCopy()
+	cm.Data = lo.Assign(cm.Data, info.data())
+	if err := c.kubeClient.Patch(ctx, cm, client.MergeFrom(stored)); err != nil {
+		return fmt.Errorf("patching provider info configmap, %w", err)
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("metrics.info").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,53 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package info
+
+import (
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	versionLabel           = "version"
+	partitionLabel         = "partition"
+	regionLabel            = "region"
+	accountIDLabel         = "account_id"
+	kubernetesVersionLabel = "kubernetes_version"
+	featureGatesLabel      = "feature_gates"
+)
+
+var (
+	ProviderInfo = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "info",
+			Help:      "A metric with a constant '1' value labeled by the AWS provider version, the partition, region, and account it's running in, the cluster's Kubernetes version, and the enabled feature gates.",
+		},
+		[]string{
+			versionLabel,
+			partitionLabel,
+			regionLabel,
+			accountIDLabel,
+			kubernetesVersionLabel,
+			featureGatesLabel,
+		},
+	)
+)
@@ -0,0 +1,159 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package info_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/karpenter/pkg/operator"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/metrics/info"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *info.Controller
+
+var configMapKey = types.NamespacedName{Namespace: "default", Name: "karpenter-provider-info"}
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "InfoMetrics")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options(coretest.OptionsFields{FeatureGates: coretest.FeatureGates{
+		ReservedCapacity: lo.ToPtr(true),
+		NodeRepair:       lo.ToPtr(true),
+	}}))
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	// The caller identity is only retrieved once, so the controller is recreated for each test
+	controller = info.NewController(env.Client, env.Client, awsEnv.STSAPI, awsEnv.VersionProvider, "us-west-2", configMapKey)
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+	cm := &corev1.ConfigMap{}
+	if err := env.Client.Get(ctx, configMapKey, cm); err == nil {
+		ExpectDeleted(ctx, env.Client, cm)
+	}
+})
+
+var _ = Describe("InfoMetrics", func() {
+	expectInfo := func(partition, accountID string) {
+		GinkgoHelper()
+		metric, ok := FindMetricWithLabelValues("karpenter_cloudprovider_info", map[string]string{
+			"version":            operator.Version,
+			"partition":          partition,
+			"region":             "us-west-2",
+			"account_id":         accountID,
+			"kubernetes_version": awsEnv.VersionProvider.Get(ctx),
+			"feature_gates":      "NodeRepair,ReservedCapacity",
+		})
+		Expect(ok).To(BeTrue())
+		Expect(metric.GetGauge().GetValue()).To(BeNumerically("==", 1))
+	}
+
+	It("should publish the provider version and environment", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		expectInfo("aws", "123456789012")
+		Expect(awsEnv.STSAPI.GetCallerIdentityBehavior.Calls()).To(Equal(1))
+	})
+	It("should only retrieve the caller identity once", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.STSAPI.GetCallerIdentityBehavior.Calls()).To(Equal(1))
+	})
+	It("should discover the partition from the caller identity", func() {
+		awsEnv.STSAPI.GetCallerIdentityBehavior.Output.Set(&sts.GetCallerIdentityOutput{
+			Account: aws.String("210987654321"),
+			Arn:     aws.String("arn:aws-us-gov:sts::210987654321:assumed-role/KarpenterControllerRole/karpenter"),
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		expectInfo("aws-us-gov", "210987654321")
+	})
+	It("should publish without the account when the caller identity can't be retrieved", func() {
+		awsEnv.STSAPI.GetCallerIdentityBehavior.Error.Set(fmt.Errorf("network unreachable"))
+		_, err := controller.Reconcile(ctx)
+		Expect(err).To(HaveOccurred())
+		expectInfo("", "")
+
+		awsEnv.STSAPI.GetCallerIdentityBehavior.Error.Set(nil)
+		ExpectSingletonReconciled(ctx, controller)
+		expectInfo("aws", "123456789012")
+		_, ok := FindMetricWithLabelValues("karpenter_cloudprovider_info", map[string]string{"account_id": ""})
+		Expect(ok).To(BeFalse())
+	})
+	It("should create the ConfigMap", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		cm := ExpectExists(ctx, env.Client, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: configMapKey.Namespace, Name: configMapKey.Name}})
+		Expect(cm.Data).To(Equal(map[string]string{
+			"version":           operator.Version,
+			"partition":         "aws",
+			"region":            "us-west-2",
+			"accountID":         "123456789012",
+			"kubernetesVersion": awsEnv.VersionProvider.Get(ctx),
+			"featureGates":      "NodeRepair,ReservedCapacity",
+		}))
+	})
+	It("should update an existing ConfigMap, retaining other keys", func() {
+		ExpectApplied(ctx, env.Client, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: configMapKey.Namespace, Name: configMapKey.Name},
+			Data:       map[string]string{"cluster": "production", "region": "us-east-1"},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		cm := ExpectExists(ctx, env.Client, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: configMapKey.Namespace, Name: configMapKey.Name}})
+		Expect(cm.Data).To(HaveKeyWithValue("cluster", "production"))
+		Expect(cm.Data).To(HaveKeyWithValue("region", "us-west-2"))
+		Expect(cm.Data).To(HaveKeyWithValue("accountID", "123456789012"))
+	})
+	It("should not write a ConfigMap when one isn't configured", func() {
+		controller = info.NewController(env.Client, env.Client, awsEnv.STSAPI, awsEnv.VersionProvider, "us-west-2", types.NamespacedName{Namespace: "default"})
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectNotFound(ctx, env.Client, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: configMapKey.Namespace, Name: configMapKey.Name}})
+		expectInfo("aws", "123456789012")
+	})
+})
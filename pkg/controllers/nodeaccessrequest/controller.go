@@ -0,0 +1,217 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeaccessrequest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// conflictRetryInterval is how often a request that's blocked by another request for the same node is retried
+const conflictRetryInterval = time.Minute
+
+// Controller grants time-boxed access to nodes for approved NodeAccessRequests. Access is granted by tagging the node's
+// instance with the request's principal, which IAM policies for EC2 Instance Connect can match on, and is revoked by
+// removing the tags once the access window ends or the request is deleted.
+type Controller struct {
+	clk              clock.Clock
+	kubeClient       client.Client
+	recorder         events.Recorder
+	instanceProvider instance.Provider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, recorder events.Recorder, instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		clk:              clk,
+		kubeClient:       kubeClient,
+		recorder:         recorder,
+		instanceProvider: instanceProvider,
+	}
+}
+
+func (c *Controller) Name() string {
+	return "nodeaccessrequest"
+}
+
+func (c *Controller) Reconcile(ctx context.Context, request *v1.NodeAccessRequest) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, c.Name())
+
+	if !request.GetDeletionTimestamp().IsZero() {
+		return reconcile.Result{}, c.finalize(ctx, request)
+	}
+	if !controllerutil.ContainsFinalizer(request, v1.TerminationFinalizer) {
+		stored := request.DeepCopy()
+		controllerutil.AddFinalizer(request, v1.TerminationFinalizer)
+		if err := c.kubeClient.Patch(ctx, request, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+	}
+
+	stored := request.DeepCopy()
+	result, err := c.reconcile(ctx, request)
+	if !equality.Semantic.DeepEqual(stored, request) {
+		if patchErr := c.kubeClient.Status().Patch(ctx, request, client.MergeFrom(stored)); patchErr != nil {
+			if errors.IsNotFound(patchErr) {
+				return reconcile.Result{}, nil
+			}
+			return reconcile.Result{}, fmt.Errorf("patching status, %w", patchErr)
+		}
+	}
+	return result, err
+}
+
+//nolint:gocyclo
+func (c *Controller) reconcile(ctx context.Context, request *v1.NodeAccessRequest) (reconcile.Result, error) {
+	// A request is only ever granted once, so that an expired request can't be reused to regain access
+	if request.Status.ExpiresAt != nil {
+		if remaining := request.Status.ExpiresAt.Sub(c.clk.Now()); remaining > 0 {
+			return reconcile.Result{RequeueAfter: remaining}, nil
+		}
+		if err := c.revoke(ctx, request); err != nil {
+			return reconcile.Result{}, err
+		}
+		request.StatusConditions().SetFalse(v1.ConditionTypeAccessGranted, v1.NodeAccessReasonExpired, "Access window ended")
+		return reconcile.Result{}, nil
+	}
+	if request.Status.ApprovedBy == "" {
+		request.StatusConditions().SetFalse(v1.ConditionTypeAccessGranted, v1.NodeAccessReasonAwaitingApproval, "Request hasn't been approved")
+		return reconcile.Result{}, nil
+	}
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: request.Spec.NodeName}, node); err != nil {
+		if errors.IsNotFound(err) {
+			request.StatusConditions().SetFalse(v1.ConditionTypeAccessGranted, v1.NodeAccessReasonNodeNotFound, fmt.Sprintf("Node %q doesn't exist", request.Spec.NodeName))
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("getting node, %w", err)
+	}
+	if _, ok := node.Labels[karpv1.NodePoolLabelKey]; !ok || node.Spec.ProviderID == "" {
+		request.StatusConditions().SetFalse(v1.ConditionTypeAccessGranted, v1.NodeAccessReasonNodeNotManaged, fmt.Sprintf("Node %q isn't managed by Karpenter", node.Name))
+		return reconcile.Result{}, nil
+	}
+	id, err := utils.ParseInstanceID(node.Spec.ProviderID)
+	if err != nil {
+		request.StatusConditions().SetFalse(v1.ConditionTypeAccessGranted, v1.NodeAccessReasonNodeNotManaged, err.Error())
+		return reconcile.Result{}, nil
+	}
+	holder, err := c.holder(ctx, request, id)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if holder != nil {
+		request.StatusConditions().SetFalse(v1.ConditionTypeAccessGranted, v1.NodeAccessReasonConflict, fmt.Sprintf("NodeAccessRequest %q already grants access to the node", holder.Name))
+		return reconcile.Result{RequeueAfter: conflictRetryInterval}, nil
+	}
+	if err := c.instanceProvider.CreateTags(ctx, id, map[string]string{
+		v1.NodeAccessTagKey:        request.Spec.Principal,
+		v1.NodeAccessRequestTagKey: request.Name,
+	}); err != nil {
+		if cloudprovider.IsNodeClaimNotFoundError(err) {
+			request.StatusConditions().SetFalse(v1.ConditionTypeAccessGranted, v1.NodeAccessReasonNodeNotFound, fmt.Sprintf("Instance %q doesn't exist", id))
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("granting access, %w", err)
+	}
+	now := c.clk.Now()
+	request.Status.InstanceID = id
+	request.Status.GrantedAt = lo.ToPtr(metav1.NewTime(now))
+	request.Status.ExpiresAt = lo.ToPtr(metav1.NewTime(now.Add(request.Spec.Duration.Duration)))
+	request.StatusConditions().SetTrueWithReason(v1.ConditionTypeAccessGranted, v1.NodeAccessReasonGranted, fmt.Sprintf("Access granted to %q", request.Spec.Principal))
+	log.FromContext(ctx).WithValues("instance-id", id, "principal", request.Spec.Principal, "approved-by", request.Status.ApprovedBy, "expires-at", request.Status.ExpiresAt.Format(time.RFC3339)).Info("granted node access")
+	c.recorder.Publish(AccessGrantedEvent(request))
+	return reconcile.Result{RequeueAfter: request.Spec.Duration.Duration}, nil
+}
+
+// holder returns another NodeAccessRequest which currently grants access to the instance, if there is one. Only one
+// request may hold an instance's access tags at a time, since the tags can only carry a single principal.
+func (c *Controller) holder(ctx context.Context, request *v1.NodeAccessRequest, id string) (*v1.NodeAccessRequest, error) {
+	requests := &v1.NodeAccessRequestList{}
+	if err := c.kubeClient.List(ctx, requests); err != nil {
+		return nil, fmt.Errorf("listing node access requests, %w", err)
+	}
+	holder, ok := lo.Find(requests.Items, func(r v1.NodeAccessRequest) bool {
+		return r.Name != request.Name && r.Status.InstanceID == id && r.StatusConditions().IsTrue(v1.ConditionTypeAccessGranted)
+	})
+	return lo.Ternary(ok, &holder, nil), nil
+}
+
+// revoke removes the access tags from the request's instance if they were granted and haven't already been removed
+func (c *Controller) revoke(ctx context.Context, request *v1.NodeAccessRequest) error {
+	if !request.StatusConditions().IsTrue(v1.ConditionTypeAccessGranted) {
+		return nil
+	}
+	if err := c.instanceProvider.DeleteTags(ctx, request.Status.InstanceID, v1.NodeAccessTagKey, v1.NodeAccessRequestTagKey); err != nil {
+		// The instance has been terminated, so there's no access left to revoke
+		if !cloudprovider.IsNodeClaimNotFoundError(err) {
+			return fmt.Errorf("revoking access, %w", err)
+		}
+	}
+	log.FromContext(ctx).WithValues("instance-id", request.Status.InstanceID, "principal", request.Spec.Principal).Info("revoked node access")
+	c.recorder.Publish(AccessRevokedEvent(request))
+	return nil
+}
+
+func (c *Controller) finalize(ctx context.Context, request *v1.NodeAccessRequest) error {
+	if !controllerutil.ContainsFinalizer(request, v1.TerminationFinalizer) {
+		return nil
+	}
+	if err := c.revoke(ctx, request); err != nil {
+		return err
+	}
+	stored := request.DeepCopy()
+	controllerutil.RemoveFinalizer(request, v1.TerminationFinalizer)
+	if err := c.kubeClient.Patch(ctx, request, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named(c.Name()).
+		For(&v1.NodeAccessRequest{}).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+			// Requests are reconciled one at a time so that two requests for the same node can't both be granted
+			MaxConcurrentReconciles: 1,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
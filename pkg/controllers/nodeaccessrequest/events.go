@@ -0,0 +1,46 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeaccessrequest
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+func AccessGrantedEvent(request *v1.NodeAccessRequest) events.Event {
+	return events.Event{
+		InvolvedObject: request,
+		Type:           corev1.EventTypeNormal,
+		Reason:         "NodeAccessGranted",
+		Message:        fmt.Sprintf("Granted %q access to node %q until %s, approved by %q", request.Spec.Principal, request.Spec.NodeName, request.Status.ExpiresAt.Format(time.RFC3339), request.Status.ApprovedBy),
+		DedupeValues:   []string{string(request.UID)},
+	}
+}
+
+func AccessRevokedEvent(request *v1.NodeAccessRequest) events.Event {
+	return events.Event{
+		InvolvedObject: request,
+		Type:           corev1.EventTypeNormal,
+		Reason:         "NodeAccessRevoked",
+		Message:        fmt.Sprintf("Revoked %q access to node %q", request.Spec.Principal, request.Spec.NodeName),
+		DedupeValues:   []string{string(request.UID)},
+	}
+}
@@ -0,0 +1,251 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeaccessrequest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeaccessrequest"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var recorder *coretest.EventRecorder
+var controller *nodeaccessrequest.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodeAccessRequest")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	recorder = coretest.NewEventRecorder()
+	controller = nodeaccessrequest.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.InstanceProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	awsEnv.Clock.SetTime(time.Now())
+	recorder.Reset()
+})
+
+var _ = AfterEach(func() {
+	requests := &v1.NodeAccessRequestList{}
+	Expect(env.Client.List(ctx, requests)).To(Succeed())
+	for i := range requests.Items {
+		stored := requests.Items[i].DeepCopy()
+		requests.Items[i].Finalizers = nil
+		Expect(client.IgnoreNotFound(env.Client.Patch(ctx, &requests.Items[i], client.MergeFrom(stored)))).To(Succeed())
+		ExpectDeleted(ctx, env.Client, &requests.Items[i])
+	}
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("NodeAccessRequest", func() {
+	var instanceID string
+	var node *corev1.Node
+	var request *v1.NodeAccessRequest
+
+	instanceTags := func() map[string]string {
+		raw, ok := awsEnv.EC2API.Instances.Load(instanceID)
+		Expect(ok).To(BeTrue())
+		return lo.SliceToMap(raw.(ec2types.Instance).Tags, func(t ec2types.Tag) (string, string) {
+			return aws.ToString(t.Key), aws.ToString(t.Value)
+		})
+	}
+	approve := func(approver string) {
+		stored := request.DeepCopy()
+		request.Status.ApprovedBy = approver
+		Expect(env.Client.Status().Patch(ctx, request, client.MergeFrom(stored))).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		instanceID = fake.InstanceID()
+		awsEnv.EC2API.Instances.Store(instanceID, ec2types.Instance{
+			InstanceId: aws.String(instanceID),
+			State:      &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			Tags:       []ec2types.Tag{{Key: aws.String(karpv1.NodePoolLabelKey), Value: aws.String("default")}},
+		})
+		node = coretest.Node(coretest.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{karpv1.NodePoolLabelKey: "default"}},
+			ProviderID: fake.ProviderID(instanceID),
+		})
+		request = &v1.NodeAccessRequest{
+			ObjectMeta: coretest.ObjectMeta(),
+			Spec: v1.NodeAccessRequestSpec{
+				NodeName:  node.Name,
+				Principal: "arn:aws:iam::123456789012:role/oncall",
+				Duration:  metav1.Duration{Duration: time.Hour},
+				Reason:    "investigating a kernel panic",
+			},
+		}
+	})
+	It("should wait for approval before granting access", func() {
+		ExpectApplied(ctx, env.Client, node, request)
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+
+		request = ExpectExists(ctx, env.Client, request)
+		Expect(request.Finalizers).To(ContainElement(v1.TerminationFinalizer))
+		condition := request.StatusConditions().Get(v1.ConditionTypeAccessGranted)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal(v1.NodeAccessReasonAwaitingApproval))
+		Expect(instanceTags()).ToNot(HaveKey(v1.NodeAccessTagKey))
+	})
+	It("should tag the instance once approved", func() {
+		ExpectApplied(ctx, env.Client, node, request)
+		approve("jane")
+		result := ExpectObjectReconciled(ctx, env.Client, controller, request)
+		Expect(result.RequeueAfter).To(Equal(time.Hour))
+
+		request = ExpectExists(ctx, env.Client, request)
+		Expect(request.StatusConditions().IsTrue(v1.ConditionTypeAccessGranted)).To(BeTrue())
+		Expect(request.Status.InstanceID).To(Equal(instanceID))
+		Expect(request.Status.GrantedAt.Time).To(BeTemporally("~", awsEnv.Clock.Now(), time.Second))
+		Expect(request.Status.ExpiresAt.Time).To(BeTemporally("~", awsEnv.Clock.Now().Add(time.Hour), time.Second))
+		Expect(instanceTags()).To(HaveKeyWithValue(v1.NodeAccessTagKey, "arn:aws:iam::123456789012:role/oncall"))
+		Expect(instanceTags()).To(HaveKeyWithValue(v1.NodeAccessRequestTagKey, request.Name))
+		Expect(recorder.Calls("NodeAccessGranted")).To(Equal(1))
+	})
+	It("should revoke access once the window ends", func() {
+		ExpectApplied(ctx, env.Client, node, request)
+		approve("jane")
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+
+		awsEnv.Clock.Step(time.Hour)
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+		request = ExpectExists(ctx, env.Client, request)
+		condition := request.StatusConditions().Get(v1.ConditionTypeAccessGranted)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal(v1.NodeAccessReasonExpired))
+		Expect(instanceTags()).ToNot(HaveKey(v1.NodeAccessTagKey))
+		Expect(instanceTags()).ToNot(HaveKey(v1.NodeAccessRequestTagKey))
+		Expect(instanceTags()).To(HaveKey(karpv1.NodePoolLabelKey))
+		Expect(recorder.Calls("NodeAccessRevoked")).To(Equal(1))
+
+		// Expired requests can't be used to regain access
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+		Expect(instanceTags()).ToNot(HaveKey(v1.NodeAccessTagKey))
+	})
+	It("should revoke access when the request is deleted", func() {
+		ExpectApplied(ctx, env.Client, node, request)
+		approve("jane")
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+		Expect(instanceTags()).To(HaveKey(v1.NodeAccessTagKey))
+
+		Expect(env.Client.Delete(ctx, request)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+		ExpectNotFound(ctx, env.Client, request)
+		Expect(instanceTags()).ToNot(HaveKey(v1.NodeAccessTagKey))
+		Expect(recorder.Calls("NodeAccessRevoked")).To(Equal(1))
+	})
+	It("should remove the finalizer when the instance has already been terminated", func() {
+		ExpectApplied(ctx, env.Client, node, request)
+		approve("jane")
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+
+		awsEnv.EC2API.DeleteTagsBehavior.Error.Set(&smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound"})
+		Expect(env.Client.Delete(ctx, request)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+		ExpectNotFound(ctx, env.Client, request)
+	})
+	It("should not grant access to a node which doesn't exist", func() {
+		ExpectApplied(ctx, env.Client, request)
+		approve("jane")
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+
+		request = ExpectExists(ctx, env.Client, request)
+		Expect(request.StatusConditions().Get(v1.ConditionTypeAccessGranted).Reason).To(Equal(v1.NodeAccessReasonNodeNotFound))
+		Expect(instanceTags()).ToNot(HaveKey(v1.NodeAccessTagKey))
+	})
+	It("should not grant access to a node which isn't managed by Karpenter", func() {
+		delete(node.Labels, karpv1.NodePoolLabelKey)
+		ExpectApplied(ctx, env.Client, node, request)
+		approve("jane")
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+
+		request = ExpectExists(ctx, env.Client, request)
+		Expect(request.StatusConditions().Get(v1.ConditionTypeAccessGranted).Reason).To(Equal(v1.NodeAccessReasonNodeNotManaged))
+		Expect(instanceTags()).ToNot(HaveKey(v1.NodeAccessTagKey))
+	})
+	It("should not grant access while another request holds the node", func() {
+		other := request.DeepCopy()
+		other.ObjectMeta = coretest.ObjectMeta()
+		other.Spec.Principal = "arn:aws:iam::123456789012:role/admin"
+		ExpectApplied(ctx, env.Client, node, request, other)
+		approve("jane")
+		ExpectObjectReconciled(ctx, env.Client, controller, request)
+
+		stored := other.DeepCopy()
+		other.Status.ApprovedBy = "jane"
+		Expect(env.Client.Status().Patch(ctx, other, client.MergeFrom(stored))).To(Succeed())
+		result := ExpectObjectReconciled(ctx, env.Client, controller, other)
+		Expect(result.RequeueAfter).To(Equal(time.Minute))
+		other = ExpectExists(ctx, env.Client, other)
+		Expect(other.StatusConditions().Get(v1.ConditionTypeAccessGranted).Reason).To(Equal(v1.NodeAccessReasonConflict))
+		Expect(instanceTags()).To(HaveKeyWithValue(v1.NodeAccessRequestTagKey, request.Name))
+	})
+	Context("Validation", func() {
+		It("should fail when the duration is longer than 12h", func() {
+			request.Spec.Duration = metav1.Duration{Duration: 13 * time.Hour}
+			Expect(env.Client.Create(ctx, request)).ToNot(Succeed())
+		})
+		It("should fail when the spec is changed", func() {
+			ExpectApplied(ctx, env.Client, request)
+			request.Spec.NodeName = "other"
+			Expect(env.Client.Update(ctx, request)).ToNot(Succeed())
+		})
+		It("should fail when the approver is changed", func() {
+			ExpectApplied(ctx, env.Client, request)
+			approve("jane")
+			stored := request.DeepCopy()
+			request.Status.ApprovedBy = "john"
+			Expect(env.Client.Status().Patch(ctx, request, client.MergeFrom(stored))).ToNot(Succeed())
+		})
+	})
+})
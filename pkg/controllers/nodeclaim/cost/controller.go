@@ -0,0 +1,175 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cost
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+)
+
+// AccrualInterval is how often the cost of a running instance is accrued. Each interval is priced at the price when
+// it's accrued, so the lifetime cost follows price changes at this granularity.
+const AccrualInterval = time.Hour
+
+// Controller estimates the cost of each NodeClaim's instance over its lifetime. The cost is accrued on the NodeClaim
+// while the instance runs, and once the NodeClaim is deleted its lifetime cost is annotated and published as an event.
+type Controller struct {
+	clock           clock.Clock
+	kubeClient      client.Client
+	cloudProvider   cloudprovider.CloudProvider
+	pricingProvider pricing.Provider
+	recorder        events.Recorder
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, pricingProvider pricing.Provider, recorder events.Recorder) *Controller {
+	return &Controller{
+		clock:           clk,
+		kubeClient:      kubeClient,
+		cloudProvider:   cloudProvider,
+		pricingProvider: pricingProvider,
+		recorder:        recorder,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.cost")
+
+	if !isAccruable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	launched := nodeClaim.StatusConditions().Get(karpv1.ConditionTypeLaunched)
+	if launched == nil || !launched.IsTrue() {
+		return reconcile.Result{}, nil
+	}
+	accrued, accruedAt := accrual(ctx, nodeClaim, launched.LastTransitionTime.Time)
+	price, hasPrice := pricing.InstancePrice(
+		c.pricingProvider,
+		ec2types.InstanceType(nodeClaim.Labels[corev1.LabelInstanceTypeStable]),
+		nodeClaim.Labels[karpv1.CapacityTypeLabelKey],
+		nodeClaim.Labels[corev1.LabelTopologyZone],
+	)
+	if !nodeClaim.DeletionTimestamp.IsZero() {
+		if !hasPrice {
+			log.FromContext(ctx).V(1).Info("skipping lifetime cost, instance type has no known price")
+			return reconcile.Result{}, nil
+		}
+		end := lo.Ternary(nodeClaim.DeletionTimestamp.Time.After(accruedAt), nodeClaim.DeletionTimestamp.Time, accruedAt)
+		lifetimeCost := accrued + accrue(price, end.Sub(accruedAt))
+		stored := nodeClaim.DeepCopy()
+		nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationLifetimeCost: formatCost(lifetimeCost)})
+		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+		c.recorder.Publish(LifetimeCostEvent(nodeClaim, end.Sub(launched.LastTransitionTime.Time), lifetimeCost))
+		return reconcile.Result{}, nil
+	}
+	now := c.clock.Now()
+	if elapsed := now.Sub(accruedAt); elapsed < AccrualInterval {
+		return reconcile.Result{RequeueAfter: AccrualInterval - elapsed}, nil
+	}
+	// The interval is priced once a price is known, rather than being left out of the lifetime cost
+	if !hasPrice {
+		return reconcile.Result{RequeueAfter: AccrualInterval}, nil
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1.AnnotationAccruedCost:   formatCost(accrued + accrue(price, now.Sub(accruedAt))),
+		v1.AnnotationCostAccruedAt: now.UTC().Format(time.RFC3339),
+	})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{RequeueAfter: AccrualInterval}, nil
+}
+
+// accrual returns the cost accrued on the NodeClaim and when it was accrued until. Nothing has been accrued when the
+// instance was launched, or when the annotations can't be parsed.
+func accrual(ctx context.Context, nodeClaim *karpv1.NodeClaim, launchedAt time.Time) (float64, time.Time) {
+	rawCost, ok := nodeClaim.Annotations[v1.AnnotationAccruedCost]
+	rawAccruedAt, ok2 := nodeClaim.Annotations[v1.AnnotationCostAccruedAt]
+	if !ok || !ok2 {
+		return 0, launchedAt
+	}
+	accrued, err := strconv.ParseFloat(rawCost, 64)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed parsing accrued cost")
+		return 0, launchedAt
+	}
+	accruedAt, err := time.Parse(time.RFC3339, rawAccruedAt)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed parsing cost accrual time")
+		return 0, launchedAt
+	}
+	return accrued, accruedAt
+}
+
+// accrue returns the cost of running an instance for the duration at the hourly price
+func accrue(hourlyPrice float64, d time.Duration) float64 {
+	return hourlyPrice * d.Hours()
+}
+
+func formatCost(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', 6, 64)
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.cost").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isAccruable(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isAccruable(nc *karpv1.NodeClaim) bool {
+	// Instance has not yet been launched
+	if nc.Status.ProviderID == "" {
+		return false
+	}
+	// Lifetime cost has already been published
+	if _, ok := nc.Annotations[v1.AnnotationLifetimeCost]; ok {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cost_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/cost"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var recorder *coretest.EventRecorder
+var controller *cost.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodeClaimCost")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	recorder = coretest.NewEventRecorder()
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+	controller = cost.NewController(awsEnv.Clock, env.Client, cloudProvider, awsEnv.PricingProvider, recorder)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	recorder.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("NodeClaimCost", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var odPrice float64
+
+	BeforeEach(func() {
+		odPrice = lo.Must(awsEnv.PricingProvider.ZonalOnDemandPrice("m5.large", "test-zone-1a"))
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					karpv1.CapacityTypeLabelKey:    karpv1.CapacityTypeOnDemand,
+					corev1.LabelTopologyZone:       "test-zone-1a",
+					corev1.LabelInstanceTypeStable: "m5.large",
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeLaunched)
+	})
+	// launchedAt returns when the NodeClaim was launched, as stored by the API server
+	launchedAt := func() time.Time {
+		GinkgoHelper()
+		return ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(karpv1.ConditionTypeLaunched).LastTransitionTime.Time
+	}
+	expectCost := func(key string, expected float64) {
+		GinkgoHelper()
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKey(key))
+		Expect(strconv.ParseFloat(nodeClaim.Annotations[key], 64)).To(BeNumerically("~", expected, 0.001))
+	}
+
+	It("should accrue the cost since launch once the accrual interval has elapsed", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		now := launchedAt().Add(2 * time.Hour)
+		awsEnv.Clock.SetTime(now)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(Equal(cost.AccrualInterval))
+		expectCost(v1.AnnotationAccruedCost, 2*odPrice)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationCostAccruedAt, now.UTC().Format(time.RFC3339)))
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationLifetimeCost))
+	})
+	It("should not accrue the cost before the accrual interval has elapsed", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		awsEnv.Clock.SetTime(launchedAt().Add(20 * time.Minute))
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(Equal(40 * time.Minute))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationAccruedCost))
+	})
+	It("should add to the previously accrued cost", func() {
+		accruedAt := time.Now().Truncate(time.Second)
+		nodeClaim.Annotations = map[string]string{
+			v1.AnnotationAccruedCost:   "1.000000",
+			v1.AnnotationCostAccruedAt: accruedAt.UTC().Format(time.RFC3339),
+		}
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		awsEnv.Clock.SetTime(accruedAt.Add(time.Hour))
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		expectCost(v1.AnnotationAccruedCost, 1+odPrice)
+	})
+	It("should not accrue the cost of nodeclaims which haven't launched", func() {
+		nodeClaim.Status.ProviderID = ""
+		nodeClaim.StatusConditions().SetUnknown(karpv1.ConditionTypeLaunched)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		awsEnv.Clock.SetTime(time.Now().Add(2 * time.Hour))
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationAccruedCost))
+	})
+	Context("Deletion", func() {
+		BeforeEach(func() {
+			nodeClaim.Finalizers = []string{karpv1.TerminationFinalizer}
+			nodeClaim.Annotations = map[string]string{
+				v1.AnnotationAccruedCost:   "1.000000",
+				v1.AnnotationCostAccruedAt: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			}
+		})
+		It("should annotate the lifetime cost and publish an event when the nodeclaim is deleted", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+			expectCost(v1.AnnotationLifetimeCost, 1+odPrice)
+			Expect(recorder.Calls("LifetimeCost")).To(Equal(1))
+		})
+		It("should only publish the lifetime cost once", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+			lifetimeCost := ExpectExists(ctx, env.Client, nodeClaim).Annotations[v1.AnnotationLifetimeCost]
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+			Expect(ExpectExists(ctx, env.Client, nodeClaim).Annotations).To(HaveKeyWithValue(v1.AnnotationLifetimeCost, lifetimeCost))
+			Expect(recorder.Calls("LifetimeCost")).To(Equal(1))
+		})
+		It("should not annotate the lifetime cost when the instance type has no known price", func() {
+			nodeClaim.Labels[corev1.LabelInstanceTypeStable] = "unknown.large"
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+			Expect(ExpectExists(ctx, env.Client, nodeClaim).Annotations).ToNot(HaveKey(v1.AnnotationLifetimeCost))
+			Expect(recorder.Calls("LifetimeCost")).To(Equal(0))
+		})
+	})
+})
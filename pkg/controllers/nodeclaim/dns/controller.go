@@ -0,0 +1,253 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// RecordTTL is the TTL of node DNS records. Records are deleted when their node is terminated, so a short TTL limits
+// how long a terminated node's address is cached.
+const RecordTTL = 60
+
+// Controller creates A and AAAA records for each node's internal addresses in a Route 53 hosted zone once the node
+// registers, and deletes them when its NodeClaim is deleted. The records which were created are recorded on the
+// NodeClaim, since Route 53 requires the exact records in order to delete them, and the node may already be gone.
+type Controller struct {
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+	route53api    sdk.Route53API
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, route53api sdk.Route53API) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+		route53api:    route53api,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.dns")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() {
+		return c.finalize(ctx, nodeClaim)
+	}
+	if !isRecordable(ctx, nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Node", klog.KRef("", nodeClaim.Status.NodeName)))
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKey{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	addresses := internalAddresses(node)
+	if len(addresses) == 0 {
+		// The node's addresses are reported by the kubelet, and may not be known when it registers
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+
+	// The finalizer is added before the records are created so that they're deleted even if the NodeClaim is deleted
+	// before they're recorded
+	if !controllerutil.ContainsFinalizer(nodeClaim, v1.DNSRecordFinalizer) {
+		stored := nodeClaim.DeepCopy()
+		controllerutil.AddFinalizer(nodeClaim, v1.DNSRecordFinalizer)
+		// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+		// can cause races due to the fact that it fully replaces the list on a change
+		// Here, we are updating the finalizer list
+		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			if apierrors.IsConflict(err) {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+	}
+	hostedZoneID := options.FromContext(ctx).NodeDNSHostedZoneID
+	name := RecordName(node.Name, options.FromContext(ctx).NodeDNSDomain)
+	if err := c.changeRecords(ctx, route53types.ChangeActionUpsert, hostedZoneID, name, addresses); err != nil {
+		return reconcile.Result{}, fmt.Errorf("creating dns records, %w", err)
+	}
+	log.FromContext(ctx).WithValues("name", name, "addresses", addresses).V(1).Info("created dns records")
+
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1.AnnotationDNSRecordHostedZoneID: hostedZoneID,
+		v1.AnnotationDNSRecordName:         name,
+		v1.AnnotationDNSRecordAddresses:    strings.Join(addresses, ","),
+	})
+	if !equality.Semantic.DeepEqual(nodeClaim, stored) {
+		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) finalize(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	if !controllerutil.ContainsFinalizer(nodeClaim, v1.DNSRecordFinalizer) {
+		return reconcile.Result{}, nil
+	}
+	hostedZoneID := nodeClaim.Annotations[v1.AnnotationDNSRecordHostedZoneID]
+	name := nodeClaim.Annotations[v1.AnnotationDNSRecordName]
+	addresses := lo.Compact(strings.Split(nodeClaim.Annotations[v1.AnnotationDNSRecordAddresses], ","))
+	if hostedZoneID != "" && name != "" && len(addresses) > 0 {
+		if err := c.changeRecords(ctx, route53types.ChangeActionDelete, hostedZoneID, name, addresses); err != nil {
+			// The records have already been deleted or replaced, so there's nothing left to clean up
+			if !isInvalidChangeBatch(err) {
+				return reconcile.Result{}, fmt.Errorf("deleting dns records, %w", err)
+			}
+			log.FromContext(ctx).WithValues("name", name).V(1).Info(fmt.Sprintf("skipping dns record deletion, %s", err))
+		} else {
+			log.FromContext(ctx).WithValues("name", name, "addresses", addresses).V(1).Info("deleted dns records")
+		}
+	}
+	stored := nodeClaim.DeepCopy()
+	controllerutil.RemoveFinalizer(nodeClaim, v1.DNSRecordFinalizer)
+	// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+	// can cause races due to the fact that it fully replaces the list on a change
+	// Here, we are updating the finalizer list
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		if apierrors.IsConflict(err) {
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{}, nil
+}
+
+// changeRecords applies the action to an A record for the IPv4 addresses and an AAAA record for the IPv6 addresses in
+// a single batch, so that neither record is changed if the other can't be
+func (c *Controller) changeRecords(ctx context.Context, action route53types.ChangeAction, hostedZoneID, name string, addresses []string) error {
+	records := map[route53types.RRType][]route53types.ResourceRecord{}
+	for _, a := range addresses {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			return fmt.Errorf("parsing address %q, %w", a, err)
+		}
+		recordType := lo.Ternary(addr.Is4(), route53types.RRTypeA, route53types.RRTypeAaaa)
+		records[recordType] = append(records[recordType], route53types.ResourceRecord{Value: aws.String(addr.String())})
+	}
+	var changes []route53types.Change
+	for _, recordType := range []route53types.RRType{route53types.RRTypeA, route53types.RRTypeAaaa} {
+		if len(records[recordType]) == 0 {
+			continue
+		}
+		changes = append(changes, route53types.Change{
+			Action: action,
+			ResourceRecordSet: &route53types.ResourceRecordSet{
+				Name:            aws.String(name),
+				Type:            recordType,
+				TTL:             aws.Int64(RecordTTL),
+				ResourceRecords: records[recordType],
+			},
+		})
+	}
+	_, err := c.route53api.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(hostedZoneID),
+		ChangeBatch:  &route53types.ChangeBatch{Changes: changes},
+	})
+	return err
+}
+
+func (c *Controller) Register(ctx context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.dns").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			nc := o.(*karpv1.NodeClaim)
+			return isRecordable(ctx, nc) || (!nc.DeletionTimestamp.IsZero() && controllerutil.ContainsFinalizer(nc, v1.DNSRecordFinalizer))
+		})).
+		// Ok with using the default MaxConcurrentReconciles of 1 since Route 53 limits the request rate per account
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+// RecordName returns the name of the DNS record for a node. The record is named after the first label of the node's
+// name, so that both IP and resource based hostnames are named consistently within the domain.
+func RecordName(nodeName, domain string) string {
+	return fmt.Sprintf("%s.%s", strings.SplitN(nodeName, ".", 2)[0], strings.Trim(domain, "."))
+}
+
+// internalAddresses returns the node's internal IPv4 and IPv6 addresses
+func internalAddresses(node *corev1.Node) []string {
+	return lo.Uniq(lo.FilterMap(node.Status.Addresses, func(a corev1.NodeAddress, _ int) (string, bool) {
+		if a.Type != corev1.NodeInternalIP {
+			return "", false
+		}
+		addr, err := netip.ParseAddr(a.Address)
+		if err != nil {
+			return "", false
+		}
+		return addr.String(), true
+	}))
+}
+
+func isInvalidChangeBatch(err error) bool {
+	apiErr := smithy.APIError(nil)
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidChangeBatch"
+}
+
+func isRecordable(ctx context.Context, nc *karpv1.NodeClaim) bool {
+	// Node DNS records are disabled
+	if options.FromContext(ctx).NodeDNSHostedZoneID == "" {
+		return false
+	}
+	// Records have already been created
+	if nc.Annotations[v1.AnnotationDNSRecordName] != "" {
+		return false
+	}
+	// Node name is not yet known
+	if nc.Status.NodeName == "" {
+		return false
+	}
+	// NodeClaim is currently terminating
+	return nc.DeletionTimestamp.IsZero()
+}
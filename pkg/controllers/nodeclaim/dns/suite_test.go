@@ -0,0 +1,194 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns_test
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/dns"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var dnsController *dns.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DNSController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+	dnsController = dns.NewController(env.Client, cloudProvider, awsEnv.Route53API)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+		NodeDNSHostedZoneID: lo.ToPtr("Z0123456789"),
+		NodeDNSDomain:       lo.ToPtr("nodes.example.com"),
+	}))
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("DNSController", func() {
+	var node *corev1.Node
+	var nodeClaim *karpv1.NodeClaim
+
+	BeforeEach(func() {
+		node = coretest.Node(coretest.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-0-1.ec2.internal"},
+		})
+		node.Status.Addresses = []corev1.NodeAddress{
+			{Type: corev1.NodeHostName, Address: "ip-10-0-0-1.ec2.internal"},
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+			{Type: corev1.NodeInternalIP, Address: "2600:1f14::1"},
+			{Type: corev1.NodeExternalIP, Address: "203.0.113.1"},
+		}
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+				NodeName:   node.Name,
+			},
+		})
+	})
+	It("should create A and AAAA records for the node's internal addresses", func() {
+		ExpectApplied(ctx, env.Client, node, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, dnsController, nodeClaim)
+
+		Expect(awsEnv.Route53API.ChangeResourceRecordSetsBehavior.CalledWithInput.Len()).To(Equal(1))
+		input := awsEnv.Route53API.ChangeResourceRecordSetsBehavior.CalledWithInput.Pop()
+		Expect(aws.ToString(input.HostedZoneId)).To(Equal("Z0123456789"))
+		Expect(input.ChangeBatch.Changes).To(ConsistOf(
+			route53types.Change{Action: route53types.ChangeActionUpsert, ResourceRecordSet: &route53types.ResourceRecordSet{
+				Name: aws.String("ip-10-0-0-1.nodes.example.com"), Type: route53types.RRTypeA, TTL: aws.Int64(dns.RecordTTL), ResourceRecords: []route53types.ResourceRecord{{Value: aws.String("10.0.0.1")}},
+			}},
+			route53types.Change{Action: route53types.ChangeActionUpsert, ResourceRecordSet: &route53types.ResourceRecordSet{
+				Name: aws.String("ip-10-0-0-1.nodes.example.com"), Type: route53types.RRTypeAaaa, TTL: aws.Int64(dns.RecordTTL), ResourceRecords: []route53types.ResourceRecord{{Value: aws.String("2600:1f14::1")}},
+			}},
+		))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Finalizers).To(ContainElement(v1.DNSRecordFinalizer))
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationDNSRecordHostedZoneID, "Z0123456789"))
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationDNSRecordName, "ip-10-0-0-1.nodes.example.com"))
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationDNSRecordAddresses, "10.0.0.1,2600:1f14::1"))
+	})
+	It("should only create the records once", func() {
+		ExpectApplied(ctx, env.Client, node, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, dnsController, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, dnsController, nodeClaim)
+		Expect(awsEnv.Route53API.ChangeResourceRecordSetsBehavior.Calls()).To(Equal(1))
+	})
+	It("should wait for the node's addresses to be reported", func() {
+		node.Status.Addresses = nil
+		ExpectApplied(ctx, env.Client, node, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, dnsController, nodeClaim)
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(awsEnv.Route53API.ChangeResourceRecordSetsBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Finalizers).ToNot(ContainElement(v1.DNSRecordFinalizer))
+	})
+	It("shouldn't create records when node DNS records are disabled", func() {
+		ctx = options.ToContext(ctx, test.Options())
+		ExpectApplied(ctx, env.Client, node, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, dnsController, nodeClaim)
+		Expect(awsEnv.Route53API.ChangeResourceRecordSetsBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Finalizers).ToNot(ContainElement(v1.DNSRecordFinalizer))
+	})
+	Context("Deletion", func() {
+		BeforeEach(func() {
+			nodeClaim.Finalizers = []string{v1.DNSRecordFinalizer}
+			nodeClaim.Annotations = map[string]string{
+				v1.AnnotationDNSRecordHostedZoneID: "Z9876543210",
+				v1.AnnotationDNSRecordName:         "ip-10-0-0-1.nodes.example.com",
+				v1.AnnotationDNSRecordAddresses:    "10.0.0.1",
+			}
+		})
+		It("should delete the recorded records when the nodeclaim is deleted", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, dnsController, nodeClaim)
+
+			input := awsEnv.Route53API.ChangeResourceRecordSetsBehavior.CalledWithInput.Pop()
+			Expect(aws.ToString(input.HostedZoneId)).To(Equal("Z9876543210"))
+			Expect(input.ChangeBatch.Changes).To(ConsistOf(
+				route53types.Change{Action: route53types.ChangeActionDelete, ResourceRecordSet: &route53types.ResourceRecordSet{
+					Name: aws.String("ip-10-0-0-1.nodes.example.com"), Type: route53types.RRTypeA, TTL: aws.Int64(dns.RecordTTL), ResourceRecords: []route53types.ResourceRecord{{Value: aws.String("10.0.0.1")}},
+				}},
+			))
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should remove the finalizer when the records have already been deleted", func() {
+			awsEnv.Route53API.ChangeResourceRecordSetsBehavior.Error.Set(&smithy.GenericAPIError{Code: "InvalidChangeBatch", Message: "not found"})
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, dnsController, nodeClaim)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should retain the finalizer when the records can't be deleted", func() {
+			awsEnv.Route53API.ChangeResourceRecordSetsBehavior.Error.Set(&smithy.GenericAPIError{Code: "Throttling", Message: "rate exceeded"})
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+			_ = ExpectObjectReconcileFailed(ctx, env.Client, dnsController, nodeClaim)
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Finalizers).To(ContainElement(v1.DNSRecordFinalizer))
+
+			awsEnv.Route53API.ChangeResourceRecordSetsBehavior.Error.Reset()
+			ExpectObjectReconciled(ctx, env.Client, dnsController, nodeClaim)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+	})
+})
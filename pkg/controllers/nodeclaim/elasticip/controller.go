@@ -0,0 +1,339 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// Controller associates an Elastic IP address with the primary network interface of each node whose EC2NodeClass
+// configures one, once its instance is launched. Addresses are either selected from the unassociated addresses of the
+// EC2NodeClass's pool, or allocated for the node. Allocated addresses are tagged with their NodeClaim, so that they're
+// found again if the controller restarts before the association is recorded, and are released once the NodeClaim's
+// instance is terminated.
+type Controller struct {
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+	ec2api        sdk.EC2API
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, ec2api sdk.EC2API) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+		ec2api:        ec2api,
+	}
+}
+
+//nolint:gocyclo
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.elasticip")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() {
+		return c.finalize(ctx, nodeClaim)
+	}
+	if !isAssociable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKey{Name: utils.NodeClassName(nodeClaim)}, nodeClass); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if nodeClass.Spec.ElasticIP == nil {
+		return reconcile.Result{}, nil
+	}
+	instanceID, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instance-id", instanceID))
+	networkInterfaceID, err := c.primaryNetworkInterfaceID(ctx, instanceID)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if networkInterfaceID == "" {
+		// The instance's network interfaces are attached while it's pending, and may not be known when it's launched
+		return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+
+	var address *ec2types.Address
+	if len(nodeClass.Spec.ElasticIP.SelectorTerms) == 0 {
+		// The finalizer is added before the address is allocated so that it's released even if the NodeClaim is
+		// deleted before the association is recorded
+		if !controllerutil.ContainsFinalizer(nodeClaim, v1.ElasticIPFinalizer) {
+			stored := nodeClaim.DeepCopy()
+			controllerutil.AddFinalizer(nodeClaim, v1.ElasticIPFinalizer)
+			// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+			// can cause races due to the fact that it fully replaces the list on a change
+			// Here, we are updating the finalizer list
+			if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+				if apierrors.IsConflict(err) {
+					return reconcile.Result{Requeue: true}, nil
+				}
+				return reconcile.Result{}, client.IgnoreNotFound(err)
+			}
+		}
+		if address, err = c.allocate(ctx, nodeClass, nodeClaim); err != nil {
+			return reconcile.Result{}, fmt.Errorf("allocating elastic ip, %w", err)
+		}
+	} else {
+		if address, err = c.selectAddress(ctx, nodeClass, networkInterfaceID); err != nil {
+			return reconcile.Result{}, fmt.Errorf("selecting elastic ip, %w", err)
+		}
+		if address == nil {
+			log.FromContext(ctx).Info("waiting for an unassociated elastic ip to become available")
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("allocation-id", aws.ToString(address.AllocationId), "public-ip", aws.ToString(address.PublicIp)))
+	if aws.ToString(address.NetworkInterfaceId) != networkInterfaceID {
+		if _, err := c.ec2api.AssociateAddress(ctx, &ec2.AssociateAddressInput{
+			AllocationId:       address.AllocationId,
+			NetworkInterfaceId: aws.String(networkInterfaceID),
+			AllowReassociation: aws.Bool(false),
+		}); err != nil {
+			// Another node may have been associated with the address since it was selected
+			if isAlreadyAssociated(err) {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, fmt.Errorf("associating elastic ip, %w", err)
+		}
+		log.FromContext(ctx).V(1).Info("associated elastic ip")
+	}
+
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1.AnnotationElasticIPAllocationID: aws.ToString(address.AllocationId),
+		v1.AnnotationElasticIPPublicIP:     aws.ToString(address.PublicIp),
+	})
+	if !equality.Semantic.DeepEqual(nodeClaim, stored) {
+		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) finalize(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	if !controllerutil.ContainsFinalizer(nodeClaim, v1.ElasticIPFinalizer) {
+		return reconcile.Result{}, nil
+	}
+	// An address can't be released while it's associated, and it's disassociated once the instance is terminated, which
+	// has happened by the time the NodeClaim's termination finalizer is removed
+	if controllerutil.ContainsFinalizer(nodeClaim, karpv1.TerminationFinalizer) {
+		return reconcile.Result{}, nil
+	}
+	addresses, err := c.allocatedAddresses(ctx, nodeClaim.Name)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("describing elastic ips, %w", err)
+	}
+	for _, address := range addresses {
+		if _, err := c.ec2api.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{AllocationId: address.AllocationId}); awserrors.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, fmt.Errorf("releasing elastic ip, %w", err)
+		}
+		log.FromContext(ctx).WithValues("allocation-id", aws.ToString(address.AllocationId)).V(1).Info("released elastic ip")
+	}
+	stored := nodeClaim.DeepCopy()
+	controllerutil.RemoveFinalizer(nodeClaim, v1.ElasticIPFinalizer)
+	// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+	// can cause races due to the fact that it fully replaces the list on a change
+	// Here, we are updating the finalizer list
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		if apierrors.IsConflict(err) {
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{}, nil
+}
+
+// primaryNetworkInterfaceID returns the id of the network interface at the first device index of the instance's first
+// network card. The address is associated with it rather than the instance, since an instance with several network
+// interfaces can't be associated with an address directly.
+func (c *Controller) primaryNetworkInterfaceID(ctx context.Context, instanceID string) (string, error) {
+	out, err := c.ec2api.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+	if err != nil {
+		if awserrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("describing instance, %w", err)
+	}
+	for _, reservation := range out.Reservations {
+		for _, instance := range reservation.Instances {
+			for _, ni := range instance.NetworkInterfaces {
+				if ni.Attachment != nil && lo.FromPtr(ni.Attachment.DeviceIndex) == 0 && lo.FromPtr(ni.Attachment.NetworkCardIndex) == 0 {
+					return aws.ToString(ni.NetworkInterfaceId), nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+// allocate returns the address which was allocated for the NodeClaim, allocating one if there isn't one yet
+func (c *Controller) allocate(ctx context.Context, nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.NodeClaim) (*ec2types.Address, error) {
+	addresses, err := c.allocatedAddresses(ctx, nodeClaim.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) > 0 {
+		return &addresses[0], nil
+	}
+	tags, err := utils.GetTags(nodeClass, nodeClaim, options.FromContext(ctx).ClusterName, options.FromContext(ctx).TagKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.ec2api.AllocateAddress(ctx, &ec2.AllocateAddressInput{
+		Domain: ec2types.DomainTypeVpc,
+		TagSpecifications: []ec2types.TagSpecification{{
+			ResourceType: ec2types.ResourceTypeElasticIp,
+			Tags:         utils.MergeTags(tags, map[string]string{options.FromContext(ctx).TagKey(v1.NodeClaimTagKey): nodeClaim.Name}),
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.FromContext(ctx).WithValues("allocation-id", aws.ToString(out.AllocationId), "public-ip", aws.ToString(out.PublicIp)).V(1).Info("allocated elastic ip")
+	return &ec2types.Address{AllocationId: out.AllocationId, PublicIp: out.PublicIp}, nil
+}
+
+// selectAddress returns the address of the EC2NodeClass's pool which is associated with the network interface, or the
+// first unassociated address if there isn't one. No address is returned if every address of the pool is associated.
+func (c *Controller) selectAddress(ctx context.Context, nodeClass *v1.EC2NodeClass, networkInterfaceID string) (*ec2types.Address, error) {
+	addresses := map[string]ec2types.Address{}
+	for _, term := range nodeClass.Spec.ElasticIP.SelectorTerms {
+		out, err := c.ec2api.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{Filters: filters(term)})
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range out.Addresses {
+			addresses[aws.ToString(address.AllocationId)] = address
+		}
+	}
+	if address, ok := lo.Find(lo.Values(addresses), func(a ec2types.Address) bool {
+		return aws.ToString(a.NetworkInterfaceId) == networkInterfaceID
+	}); ok {
+		return &address, nil
+	}
+	unassociated := lo.Filter(lo.Values(addresses), func(a ec2types.Address, _ int) bool {
+		return a.AssociationId == nil
+	})
+	if len(unassociated) == 0 {
+		return nil, nil
+	}
+	sort.Slice(unassociated, func(i, j int) bool {
+		return aws.ToString(unassociated[i].AllocationId) < aws.ToString(unassociated[j].AllocationId)
+	})
+	return &unassociated[0], nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.elasticip").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			nc := o.(*karpv1.NodeClaim)
+			return isAssociable(nc) || (!nc.DeletionTimestamp.IsZero() && controllerutil.ContainsFinalizer(nc, v1.ElasticIPFinalizer))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+// allocatedAddresses returns the addresses which were allocated for the NodeClaim in the cluster
+func (c *Controller) allocatedAddresses(ctx context.Context, nodeClaimName string) ([]ec2types.Address, error) {
+	out, err := c.ec2api.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", v1.EKSClusterNameTagKey)),
+				Values: []string{options.FromContext(ctx).ClusterName},
+			},
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", options.FromContext(ctx).TagKey(v1.NodeClaimTagKey))),
+				Values: []string{nodeClaimName},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Addresses, nil
+}
+
+func filters(term v1.ElasticIPSelectorTerm) []ec2types.Filter {
+	if term.ID != "" {
+		return []ec2types.Filter{{Name: aws.String("allocation-id"), Values: []string{term.ID}}}
+	}
+	return lo.MapToSlice(term.Tags, func(k, v string) ec2types.Filter {
+		if v == "*" {
+			return ec2types.Filter{Name: aws.String("tag-key"), Values: []string{k}}
+		}
+		return ec2types.Filter{Name: aws.String(fmt.Sprintf("tag:%s", k)), Values: []string{v}}
+	})
+}
+
+func isAlreadyAssociated(err error) bool {
+	apiErr := smithy.APIError(nil)
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "Resource.AlreadyAssociated"
+}
+
+func isAssociable(nc *karpv1.NodeClaim) bool {
+	// An address has already been associated
+	if nc.Annotations[v1.AnnotationElasticIPAllocationID] != "" {
+		return false
+	}
+	// Instance has not yet been launched
+	if nc.Status.ProviderID == "" {
+		return false
+	}
+	// NodeClaim is currently terminating
+	return nc.DeletionTimestamp.IsZero()
+}
@@ -0,0 +1,301 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticip_test
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/elasticip"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var elasticIPController *elasticip.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ElasticIPController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+	elasticIPController = elasticip.NewController(env.Client, cloudProvider, awsEnv.EC2API)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("ElasticIPController", func() {
+	var nodeClass *v1.EC2NodeClass
+	var nodeClaim *karpv1.NodeClaim
+	var instanceID string
+
+	BeforeEach(func() {
+		instanceID = fake.InstanceID()
+		awsEnv.EC2API.Instances.Store(instanceID, ec2types.Instance{
+			InstanceId: aws.String(instanceID),
+			State:      &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			NetworkInterfaces: []ec2types.InstanceNetworkInterface{
+				{
+					NetworkInterfaceId: aws.String("eni-secondary"),
+					Attachment:         &ec2types.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int32(1), NetworkCardIndex: aws.Int32(0)},
+				},
+				{
+					NetworkInterfaceId: aws.String("eni-primary"),
+					Attachment:         &ec2types.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int32(0), NetworkCardIndex: aws.Int32(0)},
+				},
+			},
+		})
+		nodeClass = test.EC2NodeClass()
+		nodeClass.Spec.ElasticIP = &v1.ElasticIP{}
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(instanceID),
+			},
+		})
+	})
+	It("should allocate and associate an address with the node's primary network interface", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(awsEnv.EC2API.AllocateAddressBehavior.Calls()).To(Equal(1))
+		allocateInput := awsEnv.EC2API.AllocateAddressBehavior.CalledWithInput.Pop()
+		Expect(allocateInput.Domain).To(Equal(ec2types.DomainTypeVpc))
+		Expect(allocateInput.TagSpecifications).To(HaveLen(1))
+		Expect(allocateInput.TagSpecifications[0].ResourceType).To(Equal(ec2types.ResourceTypeElasticIp))
+		Expect(allocateInput.TagSpecifications[0].Tags).To(ContainElements(
+			ec2types.Tag{Key: aws.String(v1.NodeClaimTagKey), Value: aws.String(nodeClaim.Name)},
+			ec2types.Tag{Key: aws.String(v1.EKSClusterNameTagKey), Value: aws.String(options.FromContext(ctx).ClusterName)},
+		))
+		associateInput := awsEnv.EC2API.AssociateAddressBehavior.CalledWithInput.Pop()
+		Expect(aws.ToString(associateInput.NetworkInterfaceId)).To(Equal("eni-primary"))
+		Expect(aws.ToBool(associateInput.AllowReassociation)).To(BeFalse())
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Finalizers).To(ContainElement(v1.ElasticIPFinalizer))
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationElasticIPAllocationID, aws.ToString(associateInput.AllocationId)))
+		Expect(nodeClaim.Annotations).To(HaveKey(v1.AnnotationElasticIPPublicIP))
+	})
+	It("should reuse an address which was already allocated for the nodeclaim", func() {
+		allocationID := fake.ElasticIPAllocationID()
+		awsEnv.EC2API.Addresses.Store(allocationID, ec2types.Address{
+			AllocationId: aws.String(allocationID),
+			PublicIp:     aws.String("203.0.113.10"),
+			Tags: []ec2types.Tag{
+				{Key: aws.String(v1.EKSClusterNameTagKey), Value: aws.String(options.FromContext(ctx).ClusterName)},
+				{Key: aws.String(v1.NodeClaimTagKey), Value: aws.String(nodeClaim.Name)},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(awsEnv.EC2API.AllocateAddressBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationElasticIPAllocationID, allocationID))
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationElasticIPPublicIP, "203.0.113.10"))
+	})
+	It("should only associate an address once", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+		Expect(awsEnv.EC2API.AllocateAddressBehavior.Calls()).To(Equal(1))
+		Expect(awsEnv.EC2API.AssociateAddressBehavior.Calls()).To(Equal(1))
+	})
+	It("shouldn't associate an address when the nodeclass doesn't configure one", func() {
+		nodeClass.Spec.ElasticIP = nil
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+		Expect(awsEnv.EC2API.AllocateAddressBehavior.Calls()).To(Equal(0))
+		Expect(awsEnv.EC2API.AssociateAddressBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Finalizers).ToNot(ContainElement(v1.ElasticIPFinalizer))
+	})
+	It("should wait for the instance's network interfaces to be attached", func() {
+		awsEnv.EC2API.Instances.Store(instanceID, ec2types.Instance{
+			InstanceId: aws.String(instanceID),
+			State:      &ec2types.InstanceState{Name: ec2types.InstanceStateNamePending},
+		})
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(awsEnv.EC2API.AllocateAddressBehavior.Calls()).To(Equal(0))
+	})
+	Context("Selector Terms", func() {
+		var pool []string
+
+		BeforeEach(func() {
+			pool = []string{"eipalloc-00000000000000001", "eipalloc-00000000000000002"}
+			for _, id := range pool {
+				awsEnv.EC2API.Addresses.Store(id, ec2types.Address{
+					AllocationId: aws.String(id),
+					PublicIp:     aws.String("203.0.113.10"),
+					Tags:         []ec2types.Tag{{Key: aws.String("pool"), Value: aws.String("egress")}},
+				})
+			}
+			nodeClass.Spec.ElasticIP = &v1.ElasticIP{SelectorTerms: []v1.ElasticIPSelectorTerm{{Tags: map[string]string{"pool": "egress"}}}}
+		})
+		It("should associate an unassociated address from the pool", func() {
+			raw, _ := awsEnv.EC2API.Addresses.Load(pool[0])
+			associated := raw.(ec2types.Address)
+			associated.AssociationId = aws.String("eipassoc-12345749")
+			associated.NetworkInterfaceId = aws.String("eni-other")
+			awsEnv.EC2API.Addresses.Store(pool[0], associated)
+
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+			Expect(awsEnv.EC2API.AllocateAddressBehavior.Calls()).To(Equal(0))
+			associateInput := awsEnv.EC2API.AssociateAddressBehavior.CalledWithInput.Pop()
+			Expect(aws.ToString(associateInput.AllocationId)).To(Equal(pool[1]))
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Finalizers).ToNot(ContainElement(v1.ElasticIPFinalizer))
+			Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationElasticIPAllocationID, pool[1]))
+		})
+		It("should select addresses by id", func() {
+			nodeClass.Spec.ElasticIP = &v1.ElasticIP{SelectorTerms: []v1.ElasticIPSelectorTerm{{ID: pool[1]}}}
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationElasticIPAllocationID, pool[1]))
+		})
+		It("should wait when every address in the pool is associated", func() {
+			for _, id := range pool {
+				raw, _ := awsEnv.EC2API.Addresses.Load(id)
+				associated := raw.(ec2types.Address)
+				associated.AssociationId = aws.String("eipassoc-12345749")
+				associated.NetworkInterfaceId = aws.String("eni-other")
+				awsEnv.EC2API.Addresses.Store(id, associated)
+			}
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			result := ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+			Expect(result.RequeueAfter).ToNot(BeZero())
+			Expect(awsEnv.EC2API.AssociateAddressBehavior.Calls()).To(Equal(0))
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationElasticIPAllocationID))
+		})
+		It("should retry when the address is associated with another node before it's associated", func() {
+			awsEnv.EC2API.AssociateAddressBehavior.Error.Set(&smithy.GenericAPIError{Code: "Resource.AlreadyAssociated", Message: "already associated"}, fake.MaxCalls(1))
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			result := ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+			Expect(result.Requeue).To(BeTrue())
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationElasticIPAllocationID))
+		})
+	})
+	Context("Deletion", func() {
+		var allocationID string
+
+		BeforeEach(func() {
+			allocationID = fake.ElasticIPAllocationID()
+			awsEnv.EC2API.Addresses.Store(allocationID, ec2types.Address{
+				AllocationId: aws.String(allocationID),
+				Tags: []ec2types.Tag{
+					{Key: aws.String(v1.EKSClusterNameTagKey), Value: aws.String(options.FromContext(ctx).ClusterName)},
+					{Key: aws.String(v1.NodeClaimTagKey), Value: aws.String(nodeClaim.Name)},
+				},
+			})
+			nodeClaim.Finalizers = []string{v1.ElasticIPFinalizer}
+			nodeClaim.Annotations = map[string]string{v1.AnnotationElasticIPAllocationID: allocationID}
+		})
+		It("should release the allocated address when the nodeclaim is deleted", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+			Expect(aws.ToString(awsEnv.EC2API.ReleaseAddressBehavior.CalledWithInput.Pop().AllocationId)).To(Equal(allocationID))
+			_, ok := awsEnv.EC2API.Addresses.Load(allocationID)
+			Expect(ok).To(BeFalse())
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should wait for the instance to be terminated before releasing the address", func() {
+			nodeClaim.Finalizers = append(nodeClaim.Finalizers, karpv1.TerminationFinalizer)
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+			Expect(awsEnv.EC2API.ReleaseAddressBehavior.Calls()).To(Equal(0))
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Finalizers).To(ContainElement(v1.ElasticIPFinalizer))
+
+			stored := nodeClaim.DeepCopy()
+			nodeClaim.Finalizers = lo.Without(nodeClaim.Finalizers, karpv1.TerminationFinalizer)
+			Expect(env.Client.Patch(ctx, nodeClaim, client.MergeFrom(stored))).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+			Expect(awsEnv.EC2API.ReleaseAddressBehavior.Calls()).To(Equal(1))
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should retain the finalizer when the address can't be released", func() {
+			awsEnv.EC2API.ReleaseAddressBehavior.Error.Set(&smithy.GenericAPIError{Code: "InvalidIPAddress.InUse", Message: "in use"})
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+			_ = ExpectObjectReconcileFailed(ctx, env.Client, elasticIPController, nodeClaim)
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Finalizers).To(ContainElement(v1.ElasticIPFinalizer))
+
+			awsEnv.EC2API.ReleaseAddressBehavior.Error.Reset()
+			ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+	})
+})
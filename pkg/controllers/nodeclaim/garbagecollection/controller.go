@@ -19,6 +19,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/awslabs/operatorpkg/singleton"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
@@ -36,18 +39,25 @@ import (
 	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 )
 
 type Controller struct {
 	kubeClient      client.Client
 	cloudProvider   cloudprovider.CloudProvider
+	ec2api          sdk.EC2API
 	successfulCount uint64 // keeps track of successful reconciles for more aggressive requeueing near the start of the controller
 }
 
-func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, ec2api sdk.EC2API) *Controller {
 	return &Controller{
 		kubeClient:      kubeClient,
 		cloudProvider:   cloudProvider,
+		ec2api:          ec2api,
 		successfulCount: 0,
 	}
 }
@@ -55,6 +65,12 @@ func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudPr
 func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	ctx = injection.WithControllerName(ctx, "instance.garbagecollection")
 
+	// Elastic IP addresses are described BEFORE NodeClaims are listed for the same reason as instances, since an address
+	// is only allocated for a NodeClaim once it exists
+	addresses, err := c.unassociatedElasticIPs(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("describing elastic ips, %w", err)
+	}
 	// We LIST NodeClaims on the CloudProvider BEFORE we grab NodeClaims/Nodes on the cluster so that we make sure that, if
 	// LISTing cloudNodeClaims takes a long time, our information is more updated by the time we get to Node and NodeClaim LIST
 	// This works since our CloudProvider cloudNodeClaims are deleted based on whether the Machine exists or not, not vise-versa
@@ -70,6 +86,9 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	clusterNodeClaimNames := sets.New(lo.Map(clusterNodeClaims, func(nc *karpv1.NodeClaim, _ int) string {
+		return nc.Name
+	})...)
 	clusterProviderIDs := sets.New(lo.FilterMap(clusterNodeClaims, func(nc *karpv1.NodeClaim, _ int) (string, bool) {
 		return nc.Status.ProviderID, nc.Status.ProviderID != ""
 	})...)
@@ -86,6 +105,14 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	if err = multierr.Combine(errs...); err != nil {
 		return reconcile.Result{}, err
 	}
+	for _, address := range addresses {
+		tags := lo.SliceToMap(address.Tags, func(t ec2types.Tag) (string, string) { return lo.FromPtr(t.Key), lo.FromPtr(t.Value) })
+		if name, _ := options.FromContext(ctx).TagValue(tags, v1.NodeClaimTagKey); !clusterNodeClaimNames.Has(name) {
+			if err = c.releaseElasticIP(ctx, address); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	}
 	c.successfulCount++
 	return reconcile.Result{RequeueAfter: lo.Ternary(c.successfulCount <= 20, time.Second*10, time.Minute*2)}, nil
 }
@@ -109,6 +136,42 @@ func (c *Controller) garbageCollect(ctx context.Context, nodeClaim *karpv1.NodeC
 	return nil
 }
 
+// unassociatedElasticIPs returns the unassociated Elastic IP addresses which were allocated for the cluster's NodeClaims
+func (c *Controller) unassociatedElasticIPs(ctx context.Context) ([]ec2types.Address, error) {
+	out, err := c.ec2api.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", v1.EKSClusterNameTagKey)),
+				Values: []string{options.FromContext(ctx).ClusterName},
+			},
+			{
+				Name:   aws.String("tag-key"),
+				Values: options.FromContext(ctx).TagKeys(v1.NodeClaimTagKey),
+			},
+		},
+	})
+	if err != nil {
+		// Elastic IP addresses are only allocated for EC2NodeClasses which configure them, so the permission is optional
+		if awserrors.IsUnauthorizedOperationError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return lo.Filter(out.Addresses, func(a ec2types.Address, _ int) bool {
+		return a.AssociationId == nil
+	}), nil
+}
+
+// releaseElasticIP releases an address whose NodeClaim no longer exists, such as when the NodeClaim's finalizer was
+// removed before the address was released
+func (c *Controller) releaseElasticIP(ctx context.Context, address ec2types.Address) error {
+	if _, err := c.ec2api.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{AllocationId: address.AllocationId}); awserrors.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("releasing elastic ip, %w", err)
+	}
+	log.FromContext(ctx).WithValues("allocation-id", aws.ToString(address.AllocationId)).V(1).Info("garbage collected elastic ip")
+	return nil
+}
+
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("instance.garbagecollection").
@@ -68,8 +68,8 @@ var _ = BeforeSuite(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options(coretest.OptionsFields{FeatureGates: coretest.FeatureGates{ReservedCapacity: lo.ToPtr(true)}}))
 	awsEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider)
-	garbageCollectionController = garbagecollection.NewController(env.Client, cloudProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+	garbageCollectionController = garbagecollection.NewController(env.Client, cloudProvider, awsEnv.EC2API)
 })
 
 var _ = AfterSuite(func() {
@@ -404,4 +404,56 @@ var _ = Describe("GarbageCollection", func() {
 		}
 		wg.Wait()
 	})
+	Context("Elastic IPs", func() {
+		var address ec2types.Address
+
+		BeforeEach(func() {
+			address = ec2types.Address{
+				AllocationId: aws.String(fake.ElasticIPAllocationID()),
+				PublicIp:     aws.String("203.0.113.10"),
+				Tags: []ec2types.Tag{
+					{Key: aws.String(v1.EKSClusterNameTagKey), Value: aws.String(options.FromContext(ctx).ClusterName)},
+					{Key: aws.String(v1.NodeClaimTagKey), Value: aws.String("default-abcde")},
+				},
+			}
+		})
+		It("should release an unassociated address if there is no NodeClaim owner", func() {
+			awsEnv.EC2API.Addresses.Store(aws.ToString(address.AllocationId), address)
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, ok := awsEnv.EC2API.Addresses.Load(aws.ToString(address.AllocationId))
+			Expect(ok).To(BeFalse())
+		})
+		It("should not release an address if it has a NodeClaim owner", func() {
+			nodeClaim := coretest.NodeClaim(karpv1.NodeClaim{
+				Spec: karpv1.NodeClaimSpec{
+					NodeClassRef: &karpv1.NodeClassReference{
+						Group: object.GVK(nodeClass).Group,
+						Kind:  object.GVK(nodeClass).Kind,
+						Name:  nodeClass.Name,
+					},
+				},
+			})
+			address.Tags[1].Value = aws.String(nodeClaim.Name)
+			awsEnv.EC2API.Addresses.Store(aws.ToString(address.AllocationId), address)
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, ok := awsEnv.EC2API.Addresses.Load(aws.ToString(address.AllocationId))
+			Expect(ok).To(BeTrue())
+		})
+		It("should not release an address if it's associated", func() {
+			address.AssociationId = aws.String("eipassoc-12345749")
+			address.InstanceId = aws.String(fake.InstanceID())
+			awsEnv.EC2API.Addresses.Store(aws.ToString(address.AllocationId), address)
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, ok := awsEnv.EC2API.Addresses.Load(aws.ToString(address.AllocationId))
+			Expect(ok).To(BeTrue())
+		})
+		It("should not release an address if it wasn't allocated for a NodeClaim", func() {
+			address.Tags = address.Tags[:1]
+			awsEnv.EC2API.Addresses.Store(aws.ToString(address.AllocationId), address)
+			ExpectSingletonReconciled(ctx, garbageCollectionController)
+			_, ok := awsEnv.EC2API.Addresses.Load(aws.ToString(address.AllocationId))
+			Expect(ok).To(BeTrue())
+		})
+	})
 })
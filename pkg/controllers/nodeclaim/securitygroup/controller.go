@@ -0,0 +1,127 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitygroup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// reconcileInterval is how often the security groups of a node are compared with the resolved security groups of its
+// EC2NodeClass, so that security groups which are changed outside of Karpenter are reconciled as well
+const reconcileInterval = 5 * time.Minute
+
+// Controller attaches the resolved security groups of an EC2NodeClass to the primary network interface of each of its
+// nodes when its SecurityGroupDriftPolicy is Reconcile. Nodes whose EC2NodeClass uses the Replace policy are drifted by
+// the CloudProvider instead.
+type Controller struct {
+	kubeClient       client.Client
+	cloudProvider    cloudprovider.CloudProvider
+	instanceProvider instance.Provider
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		kubeClient:       kubeClient,
+		cloudProvider:    cloudProvider,
+		instanceProvider: instanceProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.securitygroup")
+
+	if !isReconcilable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKey{Name: utils.NodeClassName(nodeClaim)}, nodeClass); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	// The policy is checked on every interval so that nodes are reconciled once it's changed to Reconcile
+	if lo.FromPtr(nodeClass.Spec.SecurityGroupDriftPolicy) != v1.SecurityGroupDriftPolicyReconcile {
+		return reconcile.Result{RequeueAfter: reconcileInterval}, nil
+	}
+	securityGroupIDs := sets.New(lo.Map(nodeClass.Status.SecurityGroups, func(sg v1.SecurityGroup, _ int) string { return sg.ID })...)
+	if len(securityGroupIDs) == 0 {
+		return reconcile.Result{RequeueAfter: reconcileInterval}, nil
+	}
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	ec2Instance, err := c.instanceProvider.Get(ctx, id)
+	if err != nil {
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(fmt.Errorf("getting instance, %w", err))
+	}
+	if ec2Instance.PrimaryNetworkInterfaceID == "" || securityGroupIDs.Equal(sets.New(ec2Instance.SecurityGroupIDs...)) {
+		return reconcile.Result{RequeueAfter: reconcileInterval}, nil
+	}
+	if err := c.instanceProvider.ModifySecurityGroups(ctx, ec2Instance.PrimaryNetworkInterfaceID, sets.List(securityGroupIDs)); err != nil {
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(fmt.Errorf("reconciling security groups, %w", err))
+	}
+	log.FromContext(ctx).WithValues(
+		"instance-id", id,
+		"network-interface-id", ec2Instance.PrimaryNetworkInterfaceID,
+		"security-group-ids", sets.List(securityGroupIDs),
+	).Info("reconciled security groups")
+	return reconcile.Result{RequeueAfter: reconcileInterval}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.securitygroup").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isReconcilable(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isReconcilable(nc *karpv1.NodeClaim) bool {
+	// Instance has not yet been launched
+	if nc.Status.ProviderID == "" {
+		return false
+	}
+	// NodeClaim is currently terminating
+	return nc.DeletionTimestamp.IsZero()
+}
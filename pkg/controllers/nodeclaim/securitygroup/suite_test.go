@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitygroup_test
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var securityGroupController *securitygroup.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SecurityGroupController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+	securityGroupController = securitygroup.NewController(env.Client, cloudProvider, awsEnv.InstanceProvider)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("SecurityGroupController", func() {
+	var nodeClass *v1.EC2NodeClass
+	var nodeClaim *karpv1.NodeClaim
+	var instanceID string
+
+	BeforeEach(func() {
+		instanceID = fake.InstanceID()
+		awsEnv.EC2API.Instances.Store(instanceID, ec2types.Instance{
+			InstanceId:     aws.String(instanceID),
+			State:          &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			Placement:      &ec2types.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+			SecurityGroups: []ec2types.GroupIdentifier{{GroupId: aws.String("sg-test1")}, {GroupId: aws.String("sg-unmanaged")}},
+			NetworkInterfaces: []ec2types.InstanceNetworkInterface{
+				{
+					NetworkInterfaceId: aws.String("eni-secondary"),
+					Attachment:         &ec2types.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int32(1), NetworkCardIndex: aws.Int32(0)},
+					Groups:             []ec2types.GroupIdentifier{{GroupId: aws.String("sg-unmanaged")}},
+				},
+				{
+					NetworkInterfaceId: aws.String("eni-primary"),
+					Attachment:         &ec2types.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int32(0), NetworkCardIndex: aws.Int32(0)},
+					Groups:             []ec2types.GroupIdentifier{{GroupId: aws.String("sg-test1")}},
+				},
+			},
+		})
+		nodeClass = test.EC2NodeClass(v1.EC2NodeClass{
+			Spec: v1.EC2NodeClassSpec{
+				SecurityGroupDriftPolicy: lo.ToPtr(v1.SecurityGroupDriftPolicyReconcile),
+			},
+		})
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(instanceID),
+			},
+		})
+	})
+	It("should attach the resolved security groups to the node's primary network interface", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, securityGroupController, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.CalledWithInput.Len()).To(Equal(1))
+		input := awsEnv.EC2API.ModifyNetworkInterfaceBehavior.CalledWithInput.Pop()
+		Expect(aws.ToString(input.NetworkInterfaceId)).To(Equal("eni-primary"))
+		Expect(input.Groups).To(ConsistOf("sg-test1", "sg-test2", "sg-test3"))
+	})
+	It("should not modify the network interface when its security groups match", func() {
+		nodeClass.Status.SecurityGroups = []v1.SecurityGroup{{ID: "sg-test1"}}
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, securityGroupController, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+	})
+	It("should not modify the network interface when the drift policy is Replace", func() {
+		nodeClass.Spec.SecurityGroupDriftPolicy = lo.ToPtr(v1.SecurityGroupDriftPolicyReplace)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, securityGroupController, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+	})
+	It("should not modify the network interface of an instance which hasn't launched", func() {
+		nodeClaim.Status.ProviderID = ""
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, securityGroupController, nodeClaim)
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+	})
+	It("should not modify the network interface when the primary network interface isn't known", func() {
+		awsEnv.EC2API.Instances.Store(instanceID, ec2types.Instance{
+			InstanceId:     aws.String(instanceID),
+			State:          &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			Placement:      &ec2types.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+			SecurityGroups: []ec2types.GroupIdentifier{{GroupId: aws.String("sg-test1")}},
+		})
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, securityGroupController, nodeClaim)
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+	})
+	It("should ignore NodeClaims whose instance has been terminated", func() {
+		awsEnv.EC2API.Instances.Delete(instanceID)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, securityGroupController, nodeClaim)
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+	})
+})
@@ -0,0 +1,131 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serialconsole
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accountsettings"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// Controller publishes the EC2 serial console connection information for NodeClaims whose EC2NodeClass opts into it.
+// The annotation is published as soon as the instance is launched so that it's available for instances which never
+// register with the cluster.
+type Controller struct {
+	kubeClient              client.Client
+	cloudProvider           cloudprovider.CloudProvider
+	accountSettingsProvider accountsettings.Provider
+	region                  string
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, accountSettingsProvider accountsettings.Provider, region string) *Controller {
+	return &Controller{
+		kubeClient:              kubeClient,
+		cloudProvider:           cloudProvider,
+		accountSettingsProvider: accountSettingsProvider,
+		region:                  region,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.serialconsole")
+
+	if !isPublishable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if !lo.FromPtr(nodeClass.Spec.SerialConsole) {
+		return reconcile.Result{}, nil
+	}
+	// The serial console is only available on Nitro and bare metal instances
+	if nodeClaim.Labels[v1.LabelInstanceHypervisor] == "xen" {
+		return reconcile.Result{}, nil
+	}
+	settings, err := c.accountSettingsProvider.Get(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting account settings, %w", err)
+	}
+	// When the account setting can't be read we publish the connection information regardless, since access may still
+	// be enabled. The EC2NodeClass reports when access is known to be disabled.
+	if settings.SerialConsoleAccessEnabled != nil && !*settings.SerialConsoleAccessEnabled {
+		return reconcile.Result{}, nil
+	}
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1.AnnotationSerialConsole: fmt.Sprintf("%s.port0@serial-console.ec2-instance-connect.%s.aws", id, c.region),
+	})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.serialconsole").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isPublishable(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isPublishable(nc *karpv1.NodeClaim) bool {
+	// Connection information has already been published
+	if _, ok := nc.Annotations[v1.AnnotationSerialConsole]; ok {
+		return false
+	}
+	// Instance has not yet been launched
+	if nc.Status.ProviderID == "" {
+		return false
+	}
+	// NodeClaim is currently terminating
+	if !nc.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return true
+}
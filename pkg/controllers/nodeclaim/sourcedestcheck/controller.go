@@ -0,0 +1,138 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sourcedestcheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// pendingInterval is how often the instance is described until its network interfaces are known, since EC2 is
+// eventually consistent and a newly launched instance may not be found or may not have its network interfaces yet
+const pendingInterval = 5 * time.Second
+
+// Controller disables source/destination checking on each network interface of a NodeClaim's instance once it's
+// launched, when its EC2NodeClass disables it. It can't be configured in a launch template.
+type Controller struct {
+	kubeClient       client.Client
+	cloudProvider    cloudprovider.CloudProvider
+	instanceProvider instance.Provider
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		kubeClient:       kubeClient,
+		cloudProvider:    cloudProvider,
+		instanceProvider: instanceProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.sourcedestcheck")
+
+	if !isReconcilable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKey{Name: utils.NodeClassName(nodeClaim)}, nodeClass); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if lo.FromPtrOr(nodeClass.Spec.SourceDestCheck, true) {
+		return reconcile.Result{}, nil
+	}
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	ec2Instance, err := c.instanceProvider.Get(ctx, id)
+	if cloudprovider.IsNodeClaimNotFoundError(err) {
+		return reconcile.Result{RequeueAfter: pendingInterval}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting instance, %w", err)
+	}
+	if ec2Instance.PrimaryNetworkInterfaceID == "" {
+		return reconcile.Result{RequeueAfter: pendingInterval}, nil
+	}
+	for _, networkInterfaceID := range ec2Instance.SourceDestCheckNetworkInterfaceIDs {
+		if err := c.instanceProvider.DisableSourceDestCheck(ctx, networkInterfaceID); err != nil {
+			if cloudprovider.IsNodeClaimNotFoundError(err) {
+				return reconcile.Result{RequeueAfter: pendingInterval}, nil
+			}
+			return reconcile.Result{}, fmt.Errorf("disabling source/destination check, %w", err)
+		}
+	}
+	if len(ec2Instance.SourceDestCheckNetworkInterfaceIDs) != 0 {
+		log.FromContext(ctx).WithValues(
+			"instance-id", id,
+			"network-interface-ids", ec2Instance.SourceDestCheckNetworkInterfaceIDs,
+		).Info("disabled source/destination check")
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationSourceDestCheckDisabled: "true"})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.sourcedestcheck").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isReconcilable(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isReconcilable(nc *karpv1.NodeClaim) bool {
+	// Source/destination check has already been disabled
+	if nc.Annotations[v1.AnnotationSourceDestCheckDisabled] == "true" {
+		return false
+	}
+	// Instance has not yet been launched
+	if nc.Status.ProviderID == "" {
+		return false
+	}
+	// NodeClaim is currently terminating
+	return nc.DeletionTimestamp.IsZero()
+}
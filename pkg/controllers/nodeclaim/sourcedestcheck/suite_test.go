@@ -0,0 +1,184 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sourcedestcheck_test
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/sourcedestcheck"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var sourceDestCheckController *sourcedestcheck.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SourceDestCheckController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+	sourceDestCheckController = sourcedestcheck.NewController(env.Client, cloudProvider, awsEnv.InstanceProvider)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("SourceDestCheckController", func() {
+	var nodeClass *v1.EC2NodeClass
+	var nodeClaim *karpv1.NodeClaim
+	var instanceID string
+	var ec2Instance ec2types.Instance
+
+	BeforeEach(func() {
+		instanceID = fake.InstanceID()
+		ec2Instance = ec2types.Instance{
+			InstanceId: aws.String(instanceID),
+			State:      &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			Placement:  &ec2types.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+			NetworkInterfaces: []ec2types.InstanceNetworkInterface{
+				{
+					NetworkInterfaceId: aws.String("eni-primary"),
+					Attachment:         &ec2types.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int32(0), NetworkCardIndex: aws.Int32(0)},
+					SourceDestCheck:    aws.Bool(true),
+				},
+				{
+					NetworkInterfaceId: aws.String("eni-secondary"),
+					Attachment:         &ec2types.InstanceNetworkInterfaceAttachment{DeviceIndex: aws.Int32(1), NetworkCardIndex: aws.Int32(0)},
+					SourceDestCheck:    aws.Bool(true),
+				},
+			},
+		}
+		nodeClass = test.EC2NodeClass(v1.EC2NodeClass{
+			Spec: v1.EC2NodeClassSpec{
+				SourceDestCheck: lo.ToPtr(false),
+			},
+		})
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(instanceID),
+			},
+		})
+	})
+	It("should disable source/destination check on each network interface of the instance", func() {
+		awsEnv.EC2API.Instances.Store(instanceID, ec2Instance)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, sourceDestCheckController, nodeClaim)
+		Expect(result.RequeueAfter).To(BeZero())
+
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.CalledWithInput.Len()).To(Equal(2))
+		var networkInterfaceIDs []string
+		awsEnv.EC2API.ModifyNetworkInterfaceBehavior.CalledWithInput.ForEach(func(input *ec2.ModifyNetworkInterfaceAttributeInput) {
+			Expect(aws.ToBool(input.SourceDestCheck.Value)).To(BeFalse())
+			networkInterfaceIDs = append(networkInterfaceIDs, aws.ToString(input.NetworkInterfaceId))
+		})
+		Expect(networkInterfaceIDs).To(ConsistOf("eni-primary", "eni-secondary"))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationSourceDestCheckDisabled, "true"))
+	})
+	It("should only modify the network interfaces which have source/destination check enabled", func() {
+		ec2Instance.NetworkInterfaces[1].SourceDestCheck = aws.Bool(false)
+		awsEnv.EC2API.Instances.Store(instanceID, ec2Instance)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, sourceDestCheckController, nodeClaim)
+
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.CalledWithInput.Len()).To(Equal(1))
+		input := awsEnv.EC2API.ModifyNetworkInterfaceBehavior.CalledWithInput.Pop()
+		Expect(aws.ToString(input.NetworkInterfaceId)).To(Equal("eni-primary"))
+	})
+	It("should not modify the network interfaces when source/destination check isn't disabled", func() {
+		nodeClass.Spec.SourceDestCheck = nil
+		awsEnv.EC2API.Instances.Store(instanceID, ec2Instance)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, sourceDestCheckController, nodeClaim)
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationSourceDestCheckDisabled))
+	})
+	It("should requeue when the instance can't be found yet", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, sourceDestCheckController, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationSourceDestCheckDisabled))
+	})
+	It("should requeue when the instance doesn't have network interfaces yet", func() {
+		ec2Instance.NetworkInterfaces = nil
+		awsEnv.EC2API.Instances.Store(instanceID, ec2Instance)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, sourceDestCheckController, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationSourceDestCheckDisabled))
+	})
+	It("should not modify the network interfaces once source/destination check has been disabled", func() {
+		awsEnv.EC2API.Instances.Store(instanceID, ec2Instance)
+		nodeClaim.Annotations = map[string]string{v1.AnnotationSourceDestCheckDisabled: "true"}
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, sourceDestCheckController, nodeClaim)
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+	})
+})
@@ -0,0 +1,172 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spotrequest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// maxFilterValues is the maximum number of values accepted by a filter in a DescribeSpotInstanceRequests call
+const maxFilterValues = 200
+
+// Controller records the spot instance request backing each spot NodeClaim, along with the request's current state
+// and status, so that the NodeClaim can be correlated with the request in the EC2 console. An event is published on
+// the NodeClaim each time the status of its request changes.
+type Controller struct {
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+	ec2api        sdk.EC2API
+	recorder      events.Recorder
+	cm            *pretty.ChangeMonitor
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, ec2api sdk.EC2API, recorder events.Recorder) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+		ec2api:        ec2api,
+		recorder:      recorder,
+		cm:            pretty.NewChangeMonitor(),
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.spotrequest")
+
+	nodeClaims, err := nodeclaimutils.ListManaged(ctx, c.kubeClient, c.cloudProvider)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	// Deleting NodeClaims are included, since the status of their requests explains why they were interrupted
+	instanceIDsToNodeClaims := map[string]*karpv1.NodeClaim{}
+	for _, nc := range nodeClaims {
+		if nc.Labels[karpv1.CapacityTypeLabelKey] != karpv1.CapacityTypeSpot || nc.Status.ProviderID == "" {
+			continue
+		}
+		id, err := utils.ParseInstanceID(nc.Status.ProviderID)
+		if err != nil {
+			continue
+		}
+		instanceIDsToNodeClaims[id] = nc
+	}
+	if len(instanceIDsToNodeClaims) == 0 {
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	requests, err := c.describeSpotInstanceRequests(ctx, lo.Keys(instanceIDsToNodeClaims))
+	if err != nil {
+		if awserrors.IsUnauthorizedOperationError(err) {
+			if c.cm.HasChanged("unauthorized", true) {
+				log.FromContext(ctx).Info("unable to describe spot instance requests, spot instance request annotations will not be updated")
+			}
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("describing spot instance requests, %w", err)
+	}
+	var errs []error
+	for _, request := range requests {
+		nc, ok := instanceIDsToNodeClaims[lo.FromPtr(request.InstanceId)]
+		if !ok {
+			continue
+		}
+		if err := c.sync(ctx, nc, request); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		if lo.EveryBy(errs, func(err error) bool { return errors.IsConflict(err) }) {
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, multierr.Combine(errs...)
+	}
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+func (c *Controller) describeSpotInstanceRequests(ctx context.Context, instanceIDs []string) ([]ec2types.SpotInstanceRequest, error) {
+	var requests []ec2types.SpotInstanceRequest
+	for _, chunk := range lo.Chunk(instanceIDs, maxFilterValues) {
+		paginator := ec2.NewDescribeSpotInstanceRequestsPaginator(c.ec2api, &ec2.DescribeSpotInstanceRequestsInput{
+			Filters: []ec2types.Filter{{Name: aws.String("instance-id"), Values: chunk}},
+		})
+		for paginator.HasMorePages() {
+			out, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			requests = append(requests, out.SpotInstanceRequests...)
+		}
+	}
+	return requests, nil
+}
+
+// sync annotates the NodeClaim with the request's ID, state and status code, publishing an event if the status code
+// has changed since it was last annotated
+func (c *Controller) sync(ctx context.Context, nc *karpv1.NodeClaim, request ec2types.SpotInstanceRequest) error {
+	stored := nc.DeepCopy()
+	requestID := lo.FromPtr(request.SpotInstanceRequestId)
+	code, message := "", ""
+	if request.Status != nil {
+		code, message = lo.FromPtr(request.Status.Code), lo.FromPtr(request.Status.Message)
+	}
+	nc.Annotations = lo.Assign(nc.Annotations, map[string]string{
+		v1.AnnotationSpotInstanceRequestID:     requestID,
+		v1.AnnotationSpotInstanceRequestState:  string(request.State),
+		v1.AnnotationSpotInstanceRequestStatus: code,
+	})
+	if equality.Semantic.DeepEqual(nc, stored) {
+		return nil
+	}
+	if err := c.kubeClient.Patch(ctx, nc, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if code != "" && code != stored.Annotations[v1.AnnotationSpotInstanceRequestStatus] {
+		c.recorder.Publish(StatusChangedEvent(nc, requestID, code, message))
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.spotrequest").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
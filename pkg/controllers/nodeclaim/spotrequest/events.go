@@ -0,0 +1,43 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spotrequest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func StatusChangedEvent(nodeClaim *karpv1.NodeClaim, requestID, code, message string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           lo.Ternary(isDisruptive(code), corev1.EventTypeWarning, corev1.EventTypeNormal),
+		Reason:         "SpotInstanceRequestStatusChanged",
+		Message:        fmt.Sprintf("Spot instance request %s status changed to %s, %s", requestID, code, message),
+		DedupeValues:   []string{string(nodeClaim.UID), code},
+	}
+}
+
+// isDisruptive returns whether the status code indicates that EC2 is interrupting, or has interrupted, the instance
+func isDisruptive(code string) bool {
+	return strings.HasPrefix(code, "marked-for-") ||
+		strings.HasPrefix(code, "instance-terminated-") ||
+		strings.HasPrefix(code, "instance-stopped-")
+}
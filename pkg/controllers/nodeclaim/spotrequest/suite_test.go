@@ -0,0 +1,171 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spotrequest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/spotrequest"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var recorder *coretest.EventRecorder
+var controller *spotrequest.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SpotRequest")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	recorder = coretest.NewEventRecorder()
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
+	controller = spotrequest.NewController(env.Client, cloudProvider, awsEnv.EC2API, recorder)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	recorder.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("SpotRequest", func() {
+	var instanceID string
+	var nodeClaim *karpv1.NodeClaim
+
+	BeforeEach(func() {
+		instanceID = fake.InstanceID()
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					karpv1.CapacityTypeLabelKey: karpv1.CapacityTypeSpot,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(instanceID),
+			},
+		})
+	})
+	spotRequest := func(state ec2types.SpotInstanceState, code, message string) *ec2.DescribeSpotInstanceRequestsOutput {
+		return &ec2.DescribeSpotInstanceRequestsOutput{
+			SpotInstanceRequests: []ec2types.SpotInstanceRequest{{
+				SpotInstanceRequestId: lo.ToPtr("sir-test"),
+				InstanceId:            lo.ToPtr(instanceID),
+				State:                 state,
+				Status: &ec2types.SpotInstanceStatus{
+					Code:    lo.ToPtr(code),
+					Message: lo.ToPtr(message),
+				},
+			}},
+		}
+	}
+
+	It("should annotate the nodeclaim with its spot instance request", func() {
+		awsEnv.EC2API.DescribeSpotInstanceRequestsBehavior.Output.Set(spotRequest(ec2types.SpotInstanceStateActive, "fulfilled", "Your spot request is fulfilled."))
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectSingletonReconciled(ctx, controller)
+
+		input := awsEnv.EC2API.DescribeSpotInstanceRequestsBehavior.CalledWithInput.Pop()
+		Expect(input.Filters).To(ConsistOf(ec2types.Filter{Name: lo.ToPtr("instance-id"), Values: []string{instanceID}}))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationSpotInstanceRequestID, "sir-test"))
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationSpotInstanceRequestState, "active"))
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationSpotInstanceRequestStatus, "fulfilled"))
+		Expect(recorder.Calls("SpotInstanceRequestStatusChanged")).To(Equal(1))
+	})
+	It("should publish an event when the status of the request changes", func() {
+		awsEnv.EC2API.DescribeSpotInstanceRequestsBehavior.Output.Set(spotRequest(ec2types.SpotInstanceStateActive, "fulfilled", "Your spot request is fulfilled."))
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectSingletonReconciled(ctx, controller)
+		recorder.Reset()
+
+		awsEnv.EC2API.DescribeSpotInstanceRequestsBehavior.Output.Set(spotRequest(ec2types.SpotInstanceStateActive, "marked-for-termination", "Spot instance is marked for termination."))
+		ExpectSingletonReconciled(ctx, controller)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationSpotInstanceRequestStatus, "marked-for-termination"))
+		Expect(recorder.Calls("SpotInstanceRequestStatusChanged")).To(Equal(1))
+		Expect(recorder.DetectedEvent("Spot instance request sir-test status changed to marked-for-termination, Spot instance is marked for termination.")).To(BeTrue())
+	})
+	It("should not publish an event when the status of the request is unchanged", func() {
+		awsEnv.EC2API.DescribeSpotInstanceRequestsBehavior.Output.Set(spotRequest(ec2types.SpotInstanceStateActive, "fulfilled", "Your spot request is fulfilled."))
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectSingletonReconciled(ctx, controller)
+		recorder.Reset()
+
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(recorder.Events()).To(BeEmpty())
+	})
+	It("should ignore on-demand nodeclaims", func() {
+		nodeClaim.Labels[karpv1.CapacityTypeLabelKey] = karpv1.CapacityTypeOnDemand
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.EC2API.DescribeSpotInstanceRequestsBehavior.Calls()).To(Equal(0))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationSpotInstanceRequestID))
+	})
+	It("should ignore nodeclaims which haven't launched", func() {
+		nodeClaim.Status.ProviderID = ""
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.EC2API.DescribeSpotInstanceRequestsBehavior.Calls()).To(Equal(0))
+	})
+	It("should tolerate being unauthorized to describe spot instance requests", func() {
+		awsEnv.EC2API.DescribeSpotInstanceRequestsBehavior.Error.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		result := ExpectSingletonReconciled(ctx, controller)
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).ToNot(HaveKey(v1.AnnotationSpotInstanceRequestID))
+	})
+})
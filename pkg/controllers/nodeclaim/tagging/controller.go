@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog/v2"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -63,7 +64,7 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	ctx = injection.WithControllerName(ctx, "nodeclaim.tagging")
 
 	stored := nodeClaim.DeepCopy()
-	if !isTaggable(nodeClaim) {
+	if !isTaggable(ctx, nodeClaim) {
 		return reconcile.Result{}, nil
 	}
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Node", klog.KRef("", nodeClaim.Status.NodeName), "provider-id", nodeClaim.Status.ProviderID))
@@ -80,6 +81,12 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 		v1.AnnotationInstanceTagged:                 "true",
 		v1.AnnotationClusterNameTaggedCompatability: "true",
 	})
+	// Record the prefix that the instance was tagged with so that it's retagged if the prefix changes
+	if prefix := options.FromContext(ctx).TagKeyPrefix; prefix != "" {
+		nodeClaim.Annotations[v1.AnnotationTagKeyPrefix] = prefix
+	} else {
+		delete(nodeClaim.Annotations, v1.AnnotationTagKeyPrefix)
+	}
 	if !equality.Semantic.DeepEqual(nodeClaim, stored) {
 		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
 			return reconcile.Result{}, client.IgnoreNotFound(err)
@@ -88,12 +95,12 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	return reconcile.Result{}, nil
 }
 
-func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+func (c *Controller) Register(ctx context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("nodeclaim.tagging").
 		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
 		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
-			return isTaggable(o.(*karpv1.NodeClaim))
+			return isTaggable(ctx, o.(*karpv1.NodeClaim))
 		})).
 		// Ok with using the default MaxConcurrentReconciles of 1 to avoid throttling from CreateTag write API
 		WithOptions(controller.Options{
@@ -104,9 +111,18 @@ func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 
 func (c *Controller) tagInstance(ctx context.Context, nc *karpv1.NodeClaim, id string) error {
 	tags := map[string]string{
-		v1.NameTagKey:           nc.Status.NodeName,
-		v1.NodeClaimTagKey:      nc.Name,
+		v1.NameTagKey:           c.nameTag(ctx, nc),
 		v1.EKSClusterNameTagKey: options.FromContext(ctx).ClusterName,
+		options.FromContext(ctx).TagKey(v1.NodeClaimTagKey): nc.Name,
+	}
+	// The NodePool and EC2NodeClass tags are set at launch, but are included here so that instances launched before the
+	// tag key prefix was changed are retagged with the new keys. The previous keys are retained so that the instances are
+	// still discovered if the change is rolled back.
+	if nodePool, ok := nc.Labels[karpv1.NodePoolLabelKey]; ok && nodePool != "" {
+		tags[options.FromContext(ctx).TagKey(v1.NodePoolTagKey)] = nodePool
+	}
+	if nc.Spec.NodeClassRef != nil && nc.Spec.NodeClassRef.Name != "" {
+		tags[options.FromContext(ctx).TagKey(v1.NodeClassTagKey)] = nc.Spec.NodeClassRef.Name
 	}
 
 	// Remove tags which have been already populated
@@ -128,11 +144,36 @@ func (c *Controller) tagInstance(ctx context.Context, nc *karpv1.NodeClaim, id s
 	return nil
 }
 
-func isTaggable(nc *karpv1.NodeClaim) bool {
-	// Instance has already been tagged
+// nameTag returns the Name tag of the NodeClaim's instance, rendered from its EC2NodeClass's Name tag template if it
+// has one. The instance is named after its node if the template can't be rendered, so that it's still tagged.
+func (c *Controller) nameTag(ctx context.Context, nc *karpv1.NodeClaim) string {
+	if nc.Spec.NodeClassRef == nil {
+		return nc.Status.NodeName
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKey{Name: nc.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		if !errors.IsNotFound(err) {
+			log.FromContext(ctx).Error(err, "failed getting nodeclass for name tag")
+		}
+		return nc.Status.NodeName
+	}
+	if nodeClass.Spec.NameTagTemplate == nil {
+		return nc.Status.NodeName
+	}
+	name, err := utils.RenderNameTag(*nodeClass.Spec.NameTagTemplate, utils.NewNameTagTemplateData(nc))
+	if err != nil {
+		log.FromContext(ctx).WithValues("EC2NodeClass", klog.KObj(nodeClass)).Error(err, "failed rendering name tag, falling back to node name")
+		return nc.Status.NodeName
+	}
+	return name
+}
+
+func isTaggable(ctx context.Context, nc *karpv1.NodeClaim) bool {
+	// Instance has already been tagged with the current tag key prefix
 	instanceTagged := nc.Annotations[v1.AnnotationInstanceTagged]
 	clusterNameTagged := nc.Annotations[v1.AnnotationClusterNameTaggedCompatability]
-	if instanceTagged == "true" && clusterNameTagged == "true" {
+	tagKeyPrefix := nc.Annotations[v1.AnnotationTagKeyPrefix]
+	if instanceTagged == "true" && clusterNameTagged == "true" && tagKeyPrefix == options.FromContext(ctx).TagKeyPrefix {
 		return false
 	}
 	// Node name is not yet known
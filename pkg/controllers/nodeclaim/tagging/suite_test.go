@@ -24,6 +24,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/object"
 	"github.com/samber/lo"
 	corev1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
@@ -64,7 +65,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	awsEnv = test.NewEnvironment(ctx, env)
 	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
 	taggingController = tagging.NewController(env.Client, cloudProvider, awsEnv.InstanceProvider)
 })
 var _ = AfterSuite(func() {
@@ -72,6 +73,7 @@ var _ = AfterSuite(func() {
 })
 
 var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
 	awsEnv.Reset()
 })
 
@@ -190,6 +192,83 @@ var _ = Describe("TaggingController", func() {
 		})).To(BeFalse())
 	})
 
+	It("should retag previously tagged instances when the tag key prefix changes", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			TagKeyPrefix: lo.ToPtr("example.com/karpenter"),
+		}))
+		nodeClaim := coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: corev1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: "default"},
+				Annotations: map[string]string{
+					v1.AnnotationInstanceTagged:                 "true",
+					v1.AnnotationClusterNameTaggedCompatability: "true",
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+				NodeName:   "default",
+			},
+		})
+
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, taggingController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationTagKeyPrefix, "example.com/karpenter"))
+
+		ec2Instance := lo.Must(awsEnv.EC2API.Instances.Load(*ec2Instance.InstanceId)).(ec2types.Instance)
+		instanceTags := instance.NewInstance(ctx, ec2Instance).Tags
+		Expect(instanceTags).To(HaveKeyWithValue("example.com/karpenter/nodepool", "default"))
+		Expect(instanceTags).To(HaveKeyWithValue("example.com/karpenter/nodeclaim", nodeClaim.Name))
+		Expect(instanceTags).To(HaveKeyWithValue("example.com/karpenter/ec2nodeclass", nodeClaim.Spec.NodeClassRef.Name))
+		// The unprefixed tags are retained so that the change can be rolled back
+		Expect(instanceTags).To(HaveKeyWithValue(karpv1.NodePoolLabelKey, "default"))
+	})
+
+	Context("Name Tag Template", func() {
+		var nodeClass *v1.EC2NodeClass
+		var nodeClaim *karpv1.NodeClaim
+		BeforeEach(func() {
+			nodeClass = test.EC2NodeClass()
+			nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+				ObjectMeta: corev1.ObjectMeta{
+					Labels: map[string]string{
+						karpv1.NodePoolLabelKey:       "default",
+						"topology.kubernetes.io/zone": "test-zone-1a",
+					},
+				},
+				Spec: karpv1.NodeClaimSpec{
+					NodeClassRef: &karpv1.NodeClassReference{
+						Group: object.GVK(nodeClass).Group,
+						Kind:  object.GVK(nodeClass).Kind,
+						Name:  nodeClass.Name,
+					},
+				},
+				Status: karpv1.NodeClaimStatus{
+					ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+					NodeName:   "default",
+				},
+			})
+		})
+		nameTag := func() string {
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			ExpectObjectReconciled(ctx, env.Client, taggingController, nodeClaim)
+			ec2Instance := lo.Must(awsEnv.EC2API.Instances.Load(*ec2Instance.InstanceId)).(ec2types.Instance)
+			return instance.NewInstance(ctx, ec2Instance).Tags[v1.NameTagKey]
+		}
+		It("should render the Name tag from the EC2NodeClass's template", func() {
+			nodeClass.Spec.NameTagTemplate = lo.ToPtr("{{ .NodePool }}-{{ .Zone }}-{{ .InstanceID }}")
+			Expect(nameTag()).To(Equal(fmt.Sprintf("default-test-zone-1a-%s", *ec2Instance.InstanceId)))
+		})
+		It("should fall back to the node name when the template can't be rendered", func() {
+			nodeClass.Spec.NameTagTemplate = lo.ToPtr("{{ .Unknown }}")
+			Expect(nameTag()).To(Equal("default"))
+		})
+		It("should fall back to the node name when the EC2NodeClass doesn't exist", func() {
+			nodeClaim.Spec.NodeClassRef.Name = "unknown"
+			Expect(nameTag()).To(Equal("default"))
+		})
+	})
+
 	DescribeTable(
 		"should tag taggable instances",
 		func(customTags ...string) {
@@ -0,0 +1,99 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass
+
+import (
+	"context"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accountsettings"
+)
+
+// AccountSettings resolves the EC2 account-level defaults into the NodeClass's status, where they're applied to the
+// settings which the NodeClass leaves unset, and surfaces the places where they change how the NodeClass's instances are
+// launched. None of these conflicts prevent instances from launching, so they're published as events rather than
+// reflected in the NodeClass's readiness.
+type AccountSettings struct {
+	recorder                events.Recorder
+	accountSettingsProvider accountsettings.Provider
+}
+
+func NewAccountSettingsReconciler(recorder events.Recorder, accountSettingsProvider accountsettings.Provider) *AccountSettings {
+	return &AccountSettings{
+		recorder:                recorder,
+		accountSettingsProvider: accountSettingsProvider,
+	}
+}
+
+func (a *AccountSettings) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	settings, err := a.accountSettingsProvider.Get(ctx)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed getting account settings")
+		return reconcile.Result{}, nil
+	}
+	nodeClass.Status.AccountDefaults = accountDefaults(settings)
+	if lo.FromPtr(settings.EBSEncryptionByDefault) {
+		if deviceNames := unencryptedDeviceNames(nodeClass); len(deviceNames) != 0 {
+			a.recorder.Publish(EncryptionByDefaultOverridesVolumesEvent(nodeClass, deviceNames))
+		}
+	}
+	if settings.MetadataDefaults != nil && settings.MetadataDefaults.HttpTokens == ec2types.HttpTokensStateRequired &&
+		nodeClass.Spec.MetadataOptions != nil && lo.FromPtr(nodeClass.Spec.MetadataOptions.HTTPTokens) == string(ec2types.HttpTokensStateOptional) {
+		a.recorder.Publish(MetadataOptionsOverrideAccountDefaultsEvent(nodeClass, settings.MetadataDefaultsManagedByPolicy()))
+	}
+	if lo.FromPtr(nodeClass.Spec.SerialConsole) && settings.SerialConsoleAccessEnabled != nil && !*settings.SerialConsoleAccessEnabled {
+		a.recorder.Publish(SerialConsoleAccessDisabledEvent(nodeClass))
+	}
+	return reconcile.Result{}, nil
+}
+
+// accountDefaults returns the account settings which apply where the NodeClass leaves them unset, or nil if the account
+// doesn't set any of them
+func accountDefaults(settings *accountsettings.Settings) *v1.AccountDefaults {
+	defaults := &v1.AccountDefaults{
+		EBSEncryptionByDefault:     settings.EBSEncryptionByDefault,
+		EBSDefaultKMSKeyID:         settings.EBSDefaultKMSKeyID,
+		SerialConsoleAccessEnabled: settings.SerialConsoleAccessEnabled,
+	}
+	if metadataDefaults := settings.MetadataDefaults; metadataDefaults != nil {
+		if metadataDefaults.HttpEndpoint != "" {
+			defaults.HTTPEndpoint = lo.ToPtr(string(metadataDefaults.HttpEndpoint))
+		}
+		if metadataDefaults.HttpPutResponseHopLimit != nil {
+			defaults.HTTPPutResponseHopLimit = lo.ToPtr(int64(*metadataDefaults.HttpPutResponseHopLimit))
+		}
+		if metadataDefaults.HttpTokens != "" {
+			defaults.HTTPTokens = lo.ToPtr(string(metadataDefaults.HttpTokens))
+		}
+	}
+	if lo.IsEmpty(*defaults) {
+		return nil
+	}
+	return defaults
+}
+
+// unencryptedDeviceNames returns the devices whose volumes are explicitly requested to be unencrypted
+func unencryptedDeviceNames(nodeClass *v1.EC2NodeClass) []string {
+	return lo.FilterMap(nodeClass.Spec.BlockDeviceMappings, func(bdm *v1.BlockDeviceMapping, _ int) (string, bool) {
+		return lo.FromPtr(bdm.DeviceName), bdm.EBS != nil && bdm.EBS.Encrypted != nil && !*bdm.EBS.Encrypted
+	})
+}
@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass_test
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NodeClass Account Settings Reconciler", func() {
+	var recorder *coretest.EventRecorder
+	var reconciler *nodeclass.AccountSettings
+	BeforeEach(func() {
+		recorder = coretest.NewEventRecorder()
+		reconciler = nodeclass.NewAccountSettingsReconciler(recorder, awsEnv.AccountSettingsProvider)
+	})
+	It("should publish an event when account encryption overrides unencrypted volumes", func() {
+		awsEnv.EC2API.GetEbsEncryptionByDefaultBehavior.Output.Set(&ec2.GetEbsEncryptionByDefaultOutput{EbsEncryptionByDefault: lo.ToPtr(true)})
+		nodeClass.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{
+			{DeviceName: lo.ToPtr("/dev/xvda"), EBS: &v1.BlockDevice{Encrypted: lo.ToPtr(false)}},
+			{DeviceName: lo.ToPtr("/dev/xvdb"), EBS: &v1.BlockDevice{Encrypted: lo.ToPtr(true)}},
+		}
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("EncryptionByDefaultOverridesVolumes")).To(Equal(1))
+		Expect(recorder.DetectedEvent("EBS encryption by default is enabled for the account, volumes for /dev/xvda will be encrypted")).To(BeTrue())
+	})
+	It("should not publish an event when account encryption is disabled", func() {
+		nodeClass.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{
+			{DeviceName: lo.ToPtr("/dev/xvda"), EBS: &v1.BlockDevice{Encrypted: lo.ToPtr(false)}},
+		}
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("EncryptionByDefaultOverridesVolumes")).To(Equal(0))
+	})
+	It("should publish an event when the metadata options allow IMDSv1 but the account requires IMDSv2", func() {
+		awsEnv.EC2API.GetInstanceMetadataDefaultsBehavior.Output.Set(&ec2.GetInstanceMetadataDefaultsOutput{
+			AccountLevel: &ec2types.InstanceMetadataDefaultsResponse{
+				HttpTokens: ec2types.HttpTokensStateRequired,
+				ManagedBy:  ec2types.ManagedByDeclarativePolicy,
+			},
+		})
+		nodeClass.Spec.MetadataOptions = &v1.MetadataOptions{HTTPTokens: lo.ToPtr("optional")}
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("MetadataOptionsOverrideAccountDefaults")).To(Equal(1))
+		Expect(recorder.DetectedEvent("Instance metadata defaults set by a declarative policy require IMDSv2, but metadataOptions.httpTokens is optional")).To(BeTrue())
+	})
+	It("should not publish an event when the metadata options require IMDSv2", func() {
+		awsEnv.EC2API.GetInstanceMetadataDefaultsBehavior.Output.Set(&ec2.GetInstanceMetadataDefaultsOutput{
+			AccountLevel: &ec2types.InstanceMetadataDefaultsResponse{HttpTokens: ec2types.HttpTokensStateRequired},
+		})
+		nodeClass.Spec.MetadataOptions = &v1.MetadataOptions{HTTPTokens: lo.ToPtr("required")}
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("MetadataOptionsOverrideAccountDefaults")).To(Equal(0))
+	})
+	It("should publish an event when the serial console is requested but account access is disabled", func() {
+		nodeClass.Spec.SerialConsole = lo.ToPtr(true)
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("SerialConsoleAccessDisabled")).To(Equal(1))
+	})
+	It("should not publish an event when the serial console is requested and account access is enabled", func() {
+		awsEnv.EC2API.GetSerialConsoleAccessStatusBehavior.Output.Set(&ec2.GetSerialConsoleAccessStatusOutput{SerialConsoleAccessEnabled: lo.ToPtr(true)})
+		nodeClass.Spec.SerialConsole = lo.ToPtr(true)
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("SerialConsoleAccessDisabled")).To(Equal(0))
+	})
+	It("should resolve the account defaults into the status", func() {
+		awsEnv.EC2API.GetEbsEncryptionByDefaultBehavior.Output.Set(&ec2.GetEbsEncryptionByDefaultOutput{EbsEncryptionByDefault: lo.ToPtr(true)})
+		awsEnv.EC2API.GetInstanceMetadataDefaultsBehavior.Output.Set(&ec2.GetInstanceMetadataDefaultsOutput{
+			AccountLevel: &ec2types.InstanceMetadataDefaultsResponse{
+				HttpEndpoint:            ec2types.InstanceMetadataEndpointStateEnabled,
+				HttpPutResponseHopLimit: lo.ToPtr[int32](2),
+				HttpTokens:              ec2types.HttpTokensStateRequired,
+			},
+		})
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.Status.AccountDefaults).ToNot(BeNil())
+		Expect(nodeClass.Status.AccountDefaults.EBSEncryptionByDefault).To(Equal(lo.ToPtr(true)))
+		Expect(nodeClass.Status.AccountDefaults.HTTPEndpoint).To(Equal(lo.ToPtr("enabled")))
+		Expect(nodeClass.Status.AccountDefaults.HTTPPutResponseHopLimit).To(Equal(lo.ToPtr[int64](2)))
+		Expect(nodeClass.Status.AccountDefaults.HTTPTokens).To(Equal(lo.ToPtr("required")))
+	})
+})
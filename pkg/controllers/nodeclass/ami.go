@@ -20,26 +20,34 @@ import (
 	"sort"
 	"time"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
 type AMI struct {
 	amiProvider amifamily.Provider
+	clk         clock.Clock
+	recorder    events.Recorder
 	cm          *pretty.ChangeMonitor
 }
 
-func NewAMIReconciler(provider amifamily.Provider) *AMI {
+func NewAMIReconciler(clk clock.Clock, recorder events.Recorder, provider amifamily.Provider) *AMI {
 	return &AMI{
 		amiProvider: provider,
+		clk:         clk,
+		recorder:    recorder,
 		cm:          pretty.NewChangeMonitor(),
 	}
 }
@@ -49,9 +57,14 @@ func (a *AMI) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconc
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("getting amis, %w", err)
 	}
+	amis = a.excludeDeprecated(nodeClass, amis)
+	amis = excludeUnsupportedBootOptions(nodeClass, amis)
 	if len(amis) == 0 {
 		nodeClass.Status.AMIs = nil
-		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeAMIsReady, "AMINotFound", "AMISelector did not match any AMIs")
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeAMIsReady, "AMINotFound", "AMISelector did not match any AMIs"+
+			lo.Ternary(nodeClass.Spec.AMIDeprecationThreshold != nil, " outside of the deprecation threshold", "")+
+			lo.Ternary(nodeClass.RequiresUEFI() || nodeClass.RequiresNitroTPM(), " which support the boot options", ""),
+		)
 		// If users have omitted the necessary tags from their AMIs and later add them, we need to reprocess the information.
 		// Returning 'ok' in this case means that the nodeclass will remain in an unready state until the component is restarted.
 		return reconcile.Result{RequeueAfter: time.Minute}, nil
@@ -62,7 +75,7 @@ func (a *AMI) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconc
 		log.FromContext(ctx).WithValues("ids", uniqueAMIs).V(1).Info("discovered amis")
 	}
 
-	nodeClass.Status.AMIs = lo.Map(amis, func(ami amifamily.AMI, _ int) v1.AMI {
+	resolved := lo.Map(amis, func(ami amifamily.AMI, _ int) v1.AMI {
 		reqs := lo.Map(ami.Requirements.NodeSelectorRequirements(), func(item karpv1.NodeSelectorRequirementWithMinValues, _ int) corev1.NodeSelectorRequirement {
 			return item.NodeSelectorRequirement
 		})
@@ -74,13 +87,74 @@ func (a *AMI) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconc
 			return reqs[i].Key < reqs[j].Key
 		})
 		return v1.AMI{
-			Name:         ami.Name,
-			ID:           ami.AmiID,
-			Deprecated:   ami.Deprecated,
-			Requirements: reqs,
+			Name:            ami.Name,
+			ID:              ami.AmiID,
+			Deprecated:      ami.Deprecated,
+			Requirements:    reqs,
+			PlatformDetails: ami.PlatformDetails,
+			BootMode:        ami.BootMode,
+			ENASupport:      ami.ENASupport,
 		}
 	})
+	if a.deferAdoption(nodeClass, resolved) {
+		if a.cm.HasChanged(fmt.Sprintf("deferred-amis/%s", nodeClass.Name), amiIDs(resolved)) {
+			log.FromContext(ctx).WithValues("ids", amiIDs(resolved)).Info("deferring adoption of amis until the next maintenance window")
+		}
+		nodeClass.StatusConditions().SetTrue(v1.ConditionTypeAMIsReady)
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	previous := nodeClass.Status.AMIs
+	nodeClass.Status.AMIs = resolved
+	startAMIRollout(ctx, a.clk, nodeClass, previous)
 
 	nodeClass.StatusConditions().SetTrue(v1.ConditionTypeAMIsReady)
 	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
 }
+
+// deferAdoption returns whether the resolved AMIs should be adopted in a later maintenance window, rather than
+// replacing the AMIs which nodes are currently launched with. AMIs are adopted immediately when there are none yet, or
+// when the EC2NodeClass's spec has changed since they were last resolved, since that change was made intentionally.
+func (a *AMI) deferAdoption(nodeClass *v1.EC2NodeClass, resolved []v1.AMI) bool {
+	if len(nodeClass.Status.AMIs) == 0 || sameAMIs(nodeClass.Status.AMIs, resolved) {
+		return false
+	}
+	if cond := nodeClass.StatusConditions().Get(v1.ConditionTypeAMIsReady); cond == nil || cond.ObservedGeneration != nodeClass.Generation {
+		return false
+	}
+	return !nodeClass.InAMIMaintenanceWindow(a.clk.Now())
+}
+
+// excludeUnsupportedBootOptions excludes the AMIs which can't boot with the EC2NodeClass's boot options. AMIs which
+// prefer UEFI boot with it on the instance types which support it, which are the only ones launched when it's required.
+func excludeUnsupportedBootOptions(nodeClass *v1.EC2NodeClass, amis amifamily.AMIs) amifamily.AMIs {
+	return lo.Filter(amis, func(ami amifamily.AMI, _ int) bool {
+		if nodeClass.RequiresUEFI() && ami.BootMode != string(ec2types.BootModeValuesUefi) && ami.BootMode != string(ec2types.BootModeValuesUefiPreferred) {
+			return false
+		}
+		return !nodeClass.RequiresNitroTPM() || ami.TPMSupport == string(ec2types.TpmSupportValuesV20)
+	})
+}
+
+// excludeDeprecated reports the AMIs which are deprecated, or are within the EC2NodeClass's deprecation threshold,
+// through the AMIsDeprecated condition and an event. If a threshold is set, those AMIs are excluded from the result.
+func (a *AMI) excludeDeprecated(nodeClass *v1.EC2NodeClass, amis amifamily.AMIs) amifamily.AMIs {
+	deprecated := lo.Filter(amis, func(ami amifamily.AMI, _ int) bool { return ami.Deprecated })
+	if threshold := nodeClass.Spec.AMIDeprecationThreshold; threshold != nil {
+		amis, deprecated = lo.FilterReject(amis, func(ami amifamily.AMI, _ int) bool {
+			return !ami.DeprecatedWithin(threshold.Duration, a.clk.Now())
+		})
+	}
+	if len(deprecated) == 0 {
+		_ = nodeClass.StatusConditions().Clear(v1.ConditionTypeAMIsDeprecated)
+		return amis
+	}
+	ids := lo.Uniq(lo.Map(deprecated, func(ami amifamily.AMI, _ int) string { return ami.AmiID }))
+	sort.Strings(ids)
+	excluded := nodeClass.Spec.AMIDeprecationThreshold != nil
+	nodeClass.StatusConditions().SetTrueWithReason(v1.ConditionTypeAMIsDeprecated,
+		lo.Ternary(excluded, "AMIsExcluded", "AMIsDeprecated"),
+		fmt.Sprintf("AMISelector matched %s %s", lo.Ternary(excluded, "AMIs within the deprecation threshold", "deprecated AMIs"), utils.PrettySlice(ids, 5)),
+	)
+	a.recorder.Publish(AMIsDeprecatedEvent(nodeClass, ids, excluded))
+	return amis
+}
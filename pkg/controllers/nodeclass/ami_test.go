@@ -23,6 +23,7 @@ import (
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
@@ -626,6 +627,7 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				},
 			))
 			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsReady)).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeAMIsDeprecated)).To(BeNil())
 
 			// Increment clock to simulate status updates on deprecated AMIs
 			awsEnv.Clock.Step(40 * time.Minute)
@@ -663,6 +665,7 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				},
 			))
 			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsReady)).To(BeTrue())
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsDeprecated)).To(BeTrue())
 		})
 		It("should remove AMIDeprecated status condition when non deprecated AMIs are discovered", func() {
 			// Increment clock to simulate status updates on deprecated AMIs
@@ -700,6 +703,7 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 			))
 			// Checks if both AMIsReady and AMIsDeprecated status conditions are set
 			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsReady)).To(BeTrue())
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsDeprecated)).To(BeTrue())
 
 			// rediscover AMIs again and reconcile
 			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
@@ -763,6 +767,163 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 			))
 			// Since all AMIs discovered are non deprecated, the status conditions should remove AMIsDeprecated and only set AMIsReady
 			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsReady)).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeAMIsDeprecated)).To(BeNil())
+		})
+		Context("Deprecation Threshold", func() {
+			It("should exclude AMIs which will be deprecated within the threshold", func() {
+				nodeClass.Spec.AMIDeprecationThreshold = &metav1.Duration{Duration: time.Hour}
+				ExpectApplied(ctx, env.Client, nodeClass)
+				ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+				nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+				Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-id-456"))
+				Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsReady)).To(BeTrue())
+				cond := nodeClass.StatusConditions().Get(v1.ConditionTypeAMIsDeprecated)
+				Expect(cond.IsTrue()).To(BeTrue())
+				Expect(cond.Reason).To(Equal("AMIsExcluded"))
+				Expect(cond.Message).To(ContainSubstring("ami-id-789"))
+			})
+			It("should exclude AMIs once they're deprecated with a threshold of zero", func() {
+				nodeClass.Spec.AMIDeprecationThreshold = &metav1.Duration{}
+				ExpectApplied(ctx, env.Client, nodeClass)
+				ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+				nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+				Expect(nodeClass.Status.AMIs).To(HaveLen(2))
+				Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeAMIsDeprecated)).To(BeNil())
+
+				awsEnv.Clock.Step(40 * time.Minute)
+				awsEnv.EC2Cache.Flush()
+				ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+				nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+				Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-id-456"))
+				Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsDeprecated)).To(BeTrue())
+			})
+			It("should not be ready when every matched AMI is excluded", func() {
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"Name": "test-ami-3"}}}
+				nodeClass.Spec.AMIDeprecationThreshold = &metav1.Duration{Duration: time.Hour}
+				ExpectApplied(ctx, env.Client, nodeClass)
+				ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+				nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+				Expect(nodeClass.Status.AMIs).To(BeEmpty())
+				Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsReady)).To(BeFalse())
+				Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsDeprecated)).To(BeTrue())
+			})
+		})
+	})
+	Context("Boot Options", func() {
+		BeforeEach(func() {
+			image := func(id string, bootMode ec2types.BootModeValues, tpmSupport ec2types.TpmSupportValues) ec2types.Image {
+				return ec2types.Image{
+					Name:         aws.String(id),
+					ImageId:      aws.String(id),
+					CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+					Architecture: "x86_64",
+					BootMode:     bootMode,
+					TpmSupport:   tpmSupport,
+					Tags:         []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String(id)}},
+					State:        ec2types.ImageStateAvailable,
+				}
+			}
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+				Images: []ec2types.Image{
+					image("ami-legacy-bios", ec2types.BootModeValuesLegacyBios, ""),
+					image("ami-uefi-preferred", ec2types.BootModeValuesUefiPreferred, ""),
+					image("ami-uefi-tpm", ec2types.BootModeValuesUefi, ec2types.TpmSupportValuesV20),
+				},
+			})
+		})
+		It("should select AMIs regardless of their boot mode when the boot options aren't set", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-legacy-bios", "ami-uefi-preferred", "ami-uefi-tpm"))
+		})
+		It("should only select AMIs which boot with UEFI when it's required", func() {
+			nodeClass.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI)}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-uefi-preferred", "ami-uefi-tpm"))
+		})
+		It("should only select AMIs which support NitroTPM when it's required", func() {
+			nodeClass.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI), NitroTPM: lo.ToPtr(true)}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-uefi-tpm"))
+		})
+		It("should not be ready when no AMI supports the boot options", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"Name": "ami-legacy-bios"}}}
+			nodeClass.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI)}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(BeEmpty())
+			cond := nodeClass.StatusConditions().Get(v1.ConditionTypeAMIsReady)
+			Expect(cond.IsFalse()).To(BeTrue())
+			Expect(cond.Message).To(Equal("AMISelector did not match any AMIs which support the boot options"))
+		})
+	})
+	Context("Maintenance Windows", func() {
+		var image ec2types.Image
+		BeforeEach(func() {
+			// The window is open between 02:00 and 03:00 UTC, the clock starts outside of it
+			awsEnv.Clock.SetTime(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC))
+			nodeClass.Spec.AMIMaintenanceWindows = []v1.MaintenanceWindow{{
+				Schedule: "0 2 * * *",
+				Duration: metav1.Duration{Duration: time.Hour},
+			}}
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"Name": "amd64-standard"}}}
+			image = ec2types.Image{
+				Name:         aws.String("amd64-standard"),
+				ImageId:      aws.String("ami-amd64-standard"),
+				CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+				Architecture: "x86_64",
+				Tags:         []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("amd64-standard")}},
+				State:        ec2types.ImageStateAvailable,
+			}
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []ec2types.Image{image}})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-amd64-standard"))
+
+			// Release a newer AMI matching the same selector
+			newImage := image
+			newImage.ImageId = aws.String("ami-amd64-standard-new")
+			newImage.CreationDate = aws.String(time.Now().Add(time.Minute).Format(time.RFC3339))
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []ec2types.Image{image, newImage}})
+			awsEnv.EC2Cache.Flush()
+		})
+		It("should keep the current AMIs outside of a maintenance window", func() {
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-amd64-standard"))
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsReady)).To(BeTrue())
+		})
+		It("should adopt new AMIs once a maintenance window opens", func() {
+			awsEnv.Clock.SetTime(time.Date(2024, time.January, 2, 2, 30, 0, 0, time.UTC))
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-amd64-standard-new"))
+		})
+		It("should adopt new AMIs immediately when the spec changes", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"Name": "amd64-standard"}}, {ID: "ami-amd64-standard-new"}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-amd64-standard-new"))
+		})
+		It("should adopt new AMIs when no maintenance windows are configured", func() {
+			nodeClass.Spec.AMIMaintenanceWindows = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.AMIs).To(HaveLen(1))
+			Expect(nodeClass.Status.AMIs[0].ID).To(Equal("ami-amd64-standard-new"))
 		})
 	})
 })
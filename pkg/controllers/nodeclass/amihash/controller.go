@@ -0,0 +1,77 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amihash
+
+import (
+	"context"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+)
+
+// Controller keeps the hash of each EC2NodeClass's resolved AMIs in the shared store up to date, so that it's computed
+// once when the EC2NodeClass's status changes rather than each time its instance types are listed. The instance types
+// resolved for an EC2NodeClass are invalidated when its AMIs change or it's deleted.
+type Controller struct {
+	kubeClient    client.Client
+	store         *amifamily.HashStore
+	invalidations *awscache.InvalidationBus
+}
+
+func NewController(kubeClient client.Client, store *amifamily.HashStore, invalidations *awscache.InvalidationBus) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		store:         store,
+		invalidations: invalidations,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclass.amihash")
+
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, req.NamespacedName, nodeClass); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.store.Delete(req.Name)
+			c.invalidations.Publish(awscache.Invalidation{NodeClass: req.Name})
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if c.store.Update(nodeClass) {
+		c.invalidations.Publish(awscache.Invalidation{NodeClass: nodeClass.Name})
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclass.amihash").
+		For(&v1.EC2NodeClass{}).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(c)
+}
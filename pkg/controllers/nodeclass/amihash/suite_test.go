@@ -0,0 +1,172 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amihash_test
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/amihash"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *amihash.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AMIHash")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+
+	controller = amihash.NewController(env.Client, awsEnv.AMIHashStore, awsEnv.InvalidationBus)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("AMI Hash Controller", func() {
+	var nodeClass *v1.EC2NodeClass
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass()
+		nodeClass.Status.AMIs = []v1.AMI{
+			{
+				ID: "ami-id-123",
+				Requirements: []corev1.NodeSelectorRequirement{
+					{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64"}},
+				},
+			},
+		}
+	})
+	It("should store the hash of the EC2NodeClass's AMIs", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+
+		// The stored hash is returned, even though the AMIs on this copy of the EC2NodeClass have since changed
+		stale := nodeClass.DeepCopy()
+		stale.Status.AMIs = nil
+		Expect(awsEnv.AMIHashStore.Hash(stale)).To(Equal(amifamily.HashAMIs(nodeClass.Status.AMIs)))
+		Expect(awsEnv.AMIHashStore.Hash(stale)).ToNot(Equal(amifamily.HashAMIs(nil)))
+	})
+	It("should compute the hash when the EC2NodeClass has changed since it was stored", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+
+		nodeClass.Status.AMIs = append(nodeClass.Status.AMIs, v1.AMI{
+			ID: "ami-id-456",
+			Requirements: []corev1.NodeSelectorRequirement{
+				{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"arm64"}},
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(awsEnv.AMIHashStore.Hash(nodeClass)).To(Equal(amifamily.HashAMIs(nodeClass.Status.AMIs)))
+	})
+	It("should remove the stored hash when the EC2NodeClass is deleted", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		stale := nodeClass.DeepCopy()
+		stale.Status.AMIs = nil
+
+		ExpectDeleted(ctx, env.Client, nodeClass)
+		ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+		Expect(awsEnv.AMIHashStore.Hash(stale)).To(Equal(amifamily.HashAMIs(nil)))
+	})
+	Context("Invalidation", func() {
+		var invalidated []string
+		BeforeEach(func() {
+			invalidated = nil
+			bus := awscache.NewInvalidationBus()
+			bus.Subscribe(func(invalidation awscache.Invalidation) { invalidated = append(invalidated, invalidation.NodeClass) })
+			controller = amihash.NewController(env.Client, awsEnv.AMIHashStore, bus)
+		})
+		AfterEach(func() {
+			controller = amihash.NewController(env.Client, awsEnv.AMIHashStore, awsEnv.InvalidationBus)
+		})
+		It("should invalidate the EC2NodeClass when its AMIs change", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+			Expect(invalidated).To(BeEmpty())
+
+			nodeClass.Status.AMIs = append(nodeClass.Status.AMIs, v1.AMI{
+				ID: "ami-id-456",
+				Requirements: []corev1.NodeSelectorRequirement{
+					{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"arm64"}},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+			Expect(invalidated).To(ConsistOf(nodeClass.Name))
+		})
+		It("should not invalidate the EC2NodeClass when other fields change", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+
+			nodeClass.Spec.Tags = map[string]string{"team": "a"}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+			Expect(invalidated).To(BeEmpty())
+		})
+		It("should invalidate the EC2NodeClass when it's deleted", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+
+			ExpectDeleted(ctx, env.Client, nodeClass)
+			ExpectReconcileSucceeded(ctx, controller, client.ObjectKeyFromObject(nodeClass))
+			Expect(invalidated).To(ConsistOf(nodeClass.Name))
+		})
+	})
+	It("should not store a hash for an EC2NodeClass without a resource version", func() {
+		nodeClass.ResourceVersion = ""
+		awsEnv.AMIHashStore.Update(nodeClass)
+		Expect(awsEnv.AMIHashStore.Hash(&v1.EC2NodeClass{ObjectMeta: nodeClass.ObjectMeta})).To(Equal(amifamily.HashAMIs(nil)))
+	})
+})
@@ -0,0 +1,119 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+const (
+	amiRolloutPollPeriod               = time.Minute
+	defaultAMIRolloutInitialPercentage = 10
+)
+
+// AMIRollout updates the progress of an EC2NodeClass's AMI rollout, completing it once its duration elapses. Rollouts
+// are started by the AMI reconciler, which observes the previously resolved AMIs.
+type AMIRollout struct {
+	clk        clock.Clock
+	kubeClient client.Client
+}
+
+func NewAMIRolloutReconciler(clk clock.Clock, kubeClient client.Client) *AMIRollout {
+	return &AMIRollout{
+		clk:        clk,
+		kubeClient: kubeClient,
+	}
+}
+
+func (r *AMIRollout) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	rollout := nodeClass.Status.AMIRollout
+	if rollout == nil {
+		return reconcile.Result{}, nil
+	}
+	if nodeClass.Spec.AMIRollout == nil {
+		nodeClass.Status.AMIRollout = nil
+		return reconcile.Result{}, nil
+	}
+	duration := nodeClass.Spec.AMIRollout.Duration.Duration
+	elapsed := r.clk.Since(rollout.StartTime.Time)
+	if elapsed >= duration {
+		log.FromContext(ctx).WithValues("ids", amiIDs(nodeClass.Status.AMIs)).Info("completed ami rollout")
+		nodeClass.Status.AMIRollout = nil
+		return reconcile.Result{}, nil
+	}
+	initial := lo.FromPtrOr(nodeClass.Spec.AMIRollout.InitialPercentage, defaultAMIRolloutInitialPercentage)
+	rollout.Percentage = initial + int32(float64(100-initial)*float64(elapsed)/float64(duration))
+
+	nodeClaims := &karpv1.NodeClaimList{}
+	if err := r.kubeClient.List(ctx, nodeClaims, nodeclaimutils.ForNodeClass(nodeClass)); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	ids := amiIDs(nodeClass.Status.AMIs)
+	rollout.LaunchedNodes = int32(lo.CountBy(nodeClaims.Items, func(nc karpv1.NodeClaim) bool {
+		return !nc.CreationTimestamp.Before(&rollout.StartTime) && lo.Contains(ids, nc.Status.ImageID)
+	}))
+	return reconcile.Result{RequeueAfter: min(amiRolloutPollPeriod, duration-elapsed)}, nil
+}
+
+// startAMIRollout starts a rollout when the resolved AMIs change from the previously resolved AMIs. If they change
+// again during a rollout, the rollout restarts from the AMIs which were resolved before it began, since those are the
+// AMIs which most of the EC2NodeClass's nodes are still running.
+func startAMIRollout(ctx context.Context, clk clock.Clock, nodeClass *v1.EC2NodeClass, previous []v1.AMI) {
+	if nodeClass.Spec.AMIRollout == nil {
+		nodeClass.Status.AMIRollout = nil
+		return
+	}
+	if rollout := nodeClass.Status.AMIRollout; rollout != nil {
+		if sameAMIs(rollout.PreviousAMIs, nodeClass.Status.AMIs) {
+			nodeClass.Status.AMIRollout = nil
+			return
+		}
+		if sameAMIs(previous, nodeClass.Status.AMIs) {
+			return
+		}
+		previous = rollout.PreviousAMIs
+	} else if len(previous) == 0 || sameAMIs(previous, nodeClass.Status.AMIs) {
+		return
+	}
+	log.FromContext(ctx).WithValues("ids", amiIDs(nodeClass.Status.AMIs), "previous-ids", amiIDs(previous)).Info("started ami rollout")
+	nodeClass.Status.AMIRollout = &v1.AMIRolloutStatus{
+		PreviousAMIs: previous,
+		StartTime:    metav1.NewTime(clk.Now()),
+		Percentage:   lo.FromPtrOr(nodeClass.Spec.AMIRollout.InitialPercentage, defaultAMIRolloutInitialPercentage),
+	}
+}
+
+func sameAMIs(a, b []v1.AMI) bool {
+	return sets.New(amiIDs(a)...).Equal(sets.New(amiIDs(b)...))
+}
+
+func amiIDs(amis []v1.AMI) []string {
+	return lo.Uniq(lo.Map(amis, func(a v1.AMI, _ int) string { return a.ID }))
+}
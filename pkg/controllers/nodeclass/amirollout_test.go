@@ -0,0 +1,142 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass_test
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass AMI Rollout Status Controller", func() {
+	BeforeEach(func() {
+		awsEnv.Clock.SetTime(time.Now())
+		awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+			Images: lo.Map([]string{"ami-previous", "ami-current", "ami-next"}, func(id string, _ int) ec2types.Image {
+				return ec2types.Image{
+					Name:         aws.String(id),
+					ImageId:      aws.String(id),
+					CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+					Architecture: "x86_64",
+					State:        ec2types.ImageStateAvailable,
+				}
+			}),
+		})
+		nodeClass = test.EC2NodeClass(v1.EC2NodeClass{
+			Spec: v1.EC2NodeClassSpec{
+				AMIFamily:        lo.ToPtr(v1.AMIFamilyAL2023),
+				AMISelectorTerms: []v1.AMISelectorTerm{{ID: "ami-previous"}},
+				AMIRollout: &v1.AMIRollout{
+					Duration:          metav1.Duration{Duration: time.Hour},
+					InitialPercentage: lo.ToPtr[int32](20),
+				},
+			},
+		})
+		// Rollouts start from the AMIs resolved by the controller, rather than the AMIs the test EC2NodeClass is created with
+		nodeClass.Status = v1.EC2NodeClassStatus{}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AMIRollout).To(BeNil())
+	})
+	changeAMI := func(id string) {
+		nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: id}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+	}
+	It("should start a rollout when the resolved AMIs change", func() {
+		changeAMI("ami-current")
+		Expect(nodeClass.Status.AMIRollout).ToNot(BeNil())
+		Expect(nodeClass.Status.AMIRollout.PreviousAMIs).To(HaveLen(1))
+		Expect(nodeClass.Status.AMIRollout.PreviousAMIs[0].ID).To(Equal("ami-previous"))
+		Expect(nodeClass.Status.AMIRollout.Percentage).To(BeNumerically("==", 20))
+		Expect(nodeClass.Status.AMIRollout.LaunchedNodes).To(BeNumerically("==", 0))
+	})
+	It("should not start a rollout when amiRollout isn't set", func() {
+		nodeClass.Spec.AMIRollout = nil
+		changeAMI("ami-current")
+		Expect(nodeClass.Status.AMIRollout).To(BeNil())
+	})
+	It("should ramp the percentage over the rollout's duration", func() {
+		changeAMI("ami-current")
+		awsEnv.Clock.Step(30 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AMIRollout.Percentage).To(BeNumerically("==", 60))
+	})
+	It("should complete the rollout once its duration elapses", func() {
+		changeAMI("ami-current")
+		awsEnv.Clock.Step(time.Hour)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AMIRollout).To(BeNil())
+	})
+	It("should count the nodes launched with the current AMIs", func() {
+		changeAMI("ami-current")
+		for _, id := range []string{"ami-current", "ami-current", "ami-previous"} {
+			nodeClaim := coretest.NodeClaim(karpv1.NodeClaim{
+				Spec: karpv1.NodeClaimSpec{
+					NodeClassRef: &karpv1.NodeClassReference{
+						Group: object.GVK(nodeClass).Group,
+						Kind:  object.GVK(nodeClass).Kind,
+						Name:  nodeClass.Name,
+					},
+				},
+			})
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			nodeClaim.Status.ImageID = id
+			ExpectApplied(ctx, env.Client, nodeClaim)
+		}
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AMIRollout.LaunchedNodes).To(BeNumerically("==", 2))
+	})
+	It("should keep the previous AMIs when the AMIs change again during a rollout", func() {
+		changeAMI("ami-current")
+		changeAMI("ami-next")
+		Expect(nodeClass.Status.AMIRollout).ToNot(BeNil())
+		Expect(nodeClass.Status.AMIRollout.PreviousAMIs[0].ID).To(Equal("ami-previous"))
+	})
+	It("should end the rollout when the AMIs revert to the previous AMIs", func() {
+		changeAMI("ami-current")
+		changeAMI("ami-previous")
+		Expect(nodeClass.Status.AMIRollout).To(BeNil())
+	})
+	It("should end the rollout when amiRollout is removed", func() {
+		changeAMI("ami-current")
+		nodeClass.Spec.AMIRollout = nil
+		// Rollouts start from the AMIs resolved by the controller, rather than the AMIs the test EC2NodeClass is created with
+		nodeClass.Status = v1.EC2NodeClassStatus{}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AMIRollout).To(BeNil())
+	})
+})
@@ -48,12 +48,16 @@ import (
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accountsettings"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
 )
 
 type Controller struct {
@@ -70,14 +74,18 @@ func NewController(
 	kubeClient client.Client,
 	recorder events.Recorder,
 	subnetProvider subnet.Provider,
+	vpcEndpointProvider vpcendpoint.Provider,
 	securityGroupProvider securitygroup.Provider,
 	amiProvider amifamily.Provider,
 	instanceProfileProvider instanceprofile.Provider,
 	launchTemplateProvider launchtemplate.Provider,
 	capacityReservationProvider capacityreservation.Provider,
+	accountSettingsProvider accountsettings.Provider,
 	ec2api sdk.EC2API,
 	validationCache *cache.Cache,
 	amiResolver amifamily.Resolver,
+	healthTracker *health.Tracker,
+	instanceTypeProvider instancetype.Provider,
 ) *Controller {
 	validation := NewValidationReconciler(ec2api, amiResolver, launchTemplateProvider, validationCache)
 	return &Controller{
@@ -87,13 +95,19 @@ func NewController(
 		instanceProfileProvider: instanceProfileProvider,
 		validation:              validation,
 		reconcilers: []reconcile.TypedReconciler[*v1.EC2NodeClass]{
-			NewAMIReconciler(amiProvider),
+			NewAMIReconciler(clk, recorder, amiProvider),
+			NewAMIRolloutReconciler(clk, kubeClient),
 			NewCapacityReservationReconciler(clk, capacityReservationProvider),
-			NewSubnetReconciler(subnetProvider),
+			NewNetworkInterfaceReconciler(subnetProvider, securityGroupProvider),
+			NewSubnetReconciler(subnetProvider, vpcEndpointProvider),
 			NewSecurityGroupReconciler(securityGroupProvider),
+			NewSecurityGroupRulesReconciler(securityGroupProvider),
 			NewInstanceProfileReconciler(instanceProfileProvider),
+			NewInstanceTypeCompatibilityReconciler(instanceTypeProvider),
+			NewAccountSettingsReconciler(recorder, accountSettingsProvider),
 			validation,
 			NewReadinessReconciler(launchTemplateProvider),
+			NewHealthReconciler(healthTracker),
 		},
 	}
 }
@@ -17,6 +17,7 @@ package nodeclass
 import (
 	"fmt"
 
+	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -34,3 +35,47 @@ func WaitingOnNodeClaimTerminationEvent(nodeClass *v1.EC2NodeClass, names []stri
 		DedupeValues:   []string{string(nodeClass.UID)},
 	}
 }
+
+func EncryptionByDefaultOverridesVolumesEvent(nodeClass *v1.EC2NodeClass, deviceNames []string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "EncryptionByDefaultOverridesVolumes",
+		Message:        fmt.Sprintf("EBS encryption by default is enabled for the account, volumes for %s will be encrypted", utils.PrettySlice(deviceNames, 5)),
+		DedupeValues:   []string{string(nodeClass.UID)},
+	}
+}
+
+func MetadataOptionsOverrideAccountDefaultsEvent(nodeClass *v1.EC2NodeClass, managedByPolicy bool) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "MetadataOptionsOverrideAccountDefaults",
+		Message: fmt.Sprintf("Instance metadata defaults %s require IMDSv2, but metadataOptions.httpTokens is optional",
+			lo.Ternary(managedByPolicy, "set by a declarative policy", "for the account")),
+		DedupeValues: []string{string(nodeClass.UID)},
+	}
+}
+
+func SerialConsoleAccessDisabledEvent(nodeClass *v1.EC2NodeClass) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "SerialConsoleAccessDisabled",
+		Message:        "Serial console access is disabled for the account, connection information won't be published for instances",
+		DedupeValues:   []string{string(nodeClass.UID)},
+	}
+}
+
+func AMIsDeprecatedEvent(nodeClass *v1.EC2NodeClass, ids []string, excluded bool) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "AMIsDeprecated",
+		Message: lo.Ternary(excluded,
+			fmt.Sprintf("Excluded AMIs within the deprecation threshold %s, update the amiSelectorTerms to select newer AMIs", utils.PrettySlice(ids, 5)),
+			fmt.Sprintf("Launching nodes with deprecated AMIs %s, update the amiSelectorTerms to select newer AMIs", utils.PrettySlice(ids, 5)),
+		),
+		DedupeValues: append([]string{string(nodeClass.UID)}, ids...),
+	}
+}
@@ -32,6 +32,7 @@ import (
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
 type Controller struct {
@@ -88,10 +89,22 @@ func (c *Controller) updateNodeClaimHash(ctx context.Context, nodeClass *v1.EC2N
 	if err := c.kubeClient.List(ctx, nodeClaims, nodeclaimutils.ForNodeClass(nodeClass)); err != nil {
 		return err
 	}
+	// NodeClaims which were launched with a fallback EC2NodeClass are hashed against that EC2NodeClass, rather than the
+	// EC2NodeClass they reference
+	allNodeClaims := &karpv1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, allNodeClaims); err != nil {
+		return err
+	}
+	launched := lo.Filter(nodeClaims.Items, func(nc karpv1.NodeClaim, _ int) bool {
+		return utils.NodeClassName(&nc) == nodeClass.Name
+	})
+	launched = append(launched, lo.Filter(allNodeClaims.Items, func(nc karpv1.NodeClaim, _ int) bool {
+		return nc.Annotations[v1.AnnotationFallbackNodeClass] == nodeClass.Name
+	})...)
 
-	errs := make([]error, len(nodeClaims.Items))
-	for i := range nodeClaims.Items {
-		nc := &nodeClaims.Items[i]
+	errs := make([]error, len(launched))
+	for i := range launched {
+		nc := &launched[i]
 		stored := nc.DeepCopy()
 
 		if nc.Annotations[v1.AnnotationEC2NodeClassHashVersion] != v1.EC2NodeClassHashVersion {
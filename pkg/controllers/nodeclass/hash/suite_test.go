@@ -236,6 +236,59 @@ var _ = Describe("NodeClass Hash Controller", func() {
 		Expect(nodeClaimTwo.Annotations).To(HaveKeyWithValue(v1.AnnotationEC2NodeClassHash, expectedHash))
 		Expect(nodeClaimTwo.Annotations).To(HaveKeyWithValue(v1.AnnotationEC2NodeClassHashVersion, v1.EC2NodeClassHashVersion))
 	})
+	It("should update ec2nodeclass-hash on the NodeClaims launched with the NodeClass as a fallback, rather than on those which reference it", func() {
+		otherNodeClass := test.EC2NodeClass()
+		nodeClass.Annotations = map[string]string{
+			v1.AnnotationEC2NodeClassHash:        "abceduefed",
+			v1.AnnotationEC2NodeClassHashVersion: "test",
+		}
+		fallbackNodeClaim := coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: nodePool.Name},
+				Annotations: map[string]string{
+					v1.AnnotationEC2NodeClassHash:        "123456",
+					v1.AnnotationEC2NodeClassHashVersion: "test",
+					v1.AnnotationFallbackNodeClass:       nodeClass.Name,
+				},
+			},
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(otherNodeClass).Group,
+					Kind:  object.GVK(otherNodeClass).Kind,
+					Name:  otherNodeClass.Name,
+				},
+			},
+		})
+		referencingNodeClaim := coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: nodePool.Name},
+				Annotations: map[string]string{
+					v1.AnnotationEC2NodeClassHash:        "123456",
+					v1.AnnotationEC2NodeClassHashVersion: "test",
+					v1.AnnotationFallbackNodeClass:       otherNodeClass.Name,
+				},
+			},
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+		})
+
+		ExpectApplied(ctx, env.Client, nodeClass, fallbackNodeClaim, referencingNodeClaim, nodePool)
+
+		ExpectObjectReconciled(ctx, env.Client, hashController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		fallbackNodeClaim = ExpectExists(ctx, env.Client, fallbackNodeClaim)
+		referencingNodeClaim = ExpectExists(ctx, env.Client, referencingNodeClaim)
+
+		Expect(fallbackNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationEC2NodeClassHash, nodeClass.Hash()))
+		Expect(fallbackNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationEC2NodeClassHashVersion, v1.EC2NodeClassHashVersion))
+		Expect(referencingNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationEC2NodeClassHash, "123456"))
+		Expect(referencingNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationEC2NodeClassHashVersion, "test"))
+	})
 	It("should not update ec2nodeclass-hash on all NodeClaims when the ec2nodeclass-hash-version matches the controller hash version", func() {
 		nodeClass.Annotations = map[string]string{
 			v1.AnnotationEC2NodeClassHash:        "abceduefed",
@@ -0,0 +1,45 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
+)
+
+// Health reports the background subsystems which are failing to refresh on each EC2NodeClass. A degraded subsystem
+// doesn't make the EC2NodeClass unready, since nodes can still be launched with the data it last refreshed.
+type Health struct {
+	healthTracker *health.Tracker
+}
+
+func NewHealthReconciler(healthTracker *health.Tracker) *Health {
+	return &Health{
+		healthTracker: healthTracker,
+	}
+}
+
+func (h *Health) Reconcile(_ context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	if message := h.healthTracker.DegradedMessage(); message != "" {
+		nodeClass.StatusConditions().SetTrueWithReason(v1.ConditionTypeDegraded, "SubsystemRefreshFailed", message)
+		return reconcile.Result{}, nil
+	}
+	_ = nodeClass.StatusConditions().Clear(v1.ConditionTypeDegraded)
+	return reconcile.Result{}, nil
+}
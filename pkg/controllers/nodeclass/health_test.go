@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass_test
+
+import (
+	"fmt"
+
+	"github.com/awslabs/operatorpkg/status"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Health Reconciler", func() {
+	It("should name the degraded subsystems without affecting readiness", func() {
+		awsEnv.HealthTracker.Observe(health.SubsystemPricing, fmt.Errorf("pricing api unavailable"))
+		awsEnv.HealthTracker.Observe(health.SubsystemAMIs, fmt.Errorf("ssm throttled"))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+
+		condition := nodeClass.StatusConditions().Get(v1.ConditionTypeDegraded)
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Message).To(Equal("AMIs: ssm throttled; Pricing: pricing api unavailable"))
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+	})
+	It("should remove the condition once the subsystems recover", func() {
+		awsEnv.HealthTracker.Observe(health.SubsystemPricing, fmt.Errorf("pricing api unavailable"))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeDegraded)).ToNot(BeNil())
+
+		awsEnv.HealthTracker.Observe(health.SubsystemPricing, nil)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeDegraded)).To(BeNil())
+	})
+})
@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// InstanceTypeCompatibility reports the instance types which are excluded from the EC2NodeClass because the AMI they'd
+// be launched with doesn't support them, through the InstanceTypesIncompatible condition. The remaining instance types
+// can still be launched, so this doesn't affect the EC2NodeClass's readiness.
+type InstanceTypeCompatibility struct {
+	instanceTypeProvider instancetype.Provider
+}
+
+func NewInstanceTypeCompatibilityReconciler(instanceTypeProvider instancetype.Provider) *InstanceTypeCompatibility {
+	return &InstanceTypeCompatibility{
+		instanceTypeProvider: instanceTypeProvider,
+	}
+}
+
+func (i *InstanceTypeCompatibility) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	// The AMIs in the status may have been resolved earlier in this reconcile, so the resource version is cleared to
+	// ensure the instance types are keyed by the hash of those AMIs rather than the hash stored for the last version
+	current := nodeClass.DeepCopy()
+	current.ResourceVersion = ""
+	incompatible, err := i.instanceTypeProvider.IncompatibleInstanceTypes(ctx, current)
+	if err != nil {
+		// Instance types can't be resolved until the EC2NodeClass's subnets and the instance types are discovered, which
+		// are reported through the readiness and Degraded conditions respectively
+		return reconcile.Result{}, nil
+	}
+	if len(incompatible) == 0 {
+		_ = nodeClass.StatusConditions().Clear(v1.ConditionTypeInstanceTypesIncompatible)
+		return reconcile.Result{}, nil
+	}
+	nodeClass.StatusConditions().SetTrueWithReason(v1.ConditionTypeInstanceTypesIncompatible, "InstanceTypesExcluded",
+		fmt.Sprintf("Excluded instance types which can't be launched with their AMI, %s", incompatibilityMessage(incompatible)),
+	)
+	return reconcile.Result{}, nil
+}
+
+// incompatibilityMessage groups the excluded instance types by the reason they were excluded
+func incompatibilityMessage(incompatible map[string]string) string {
+	byReason := lo.GroupBy(lo.Keys(incompatible), func(name string) string { return incompatible[name] })
+	reasons := lo.Keys(byReason)
+	sort.Strings(reasons)
+	return strings.Join(lo.Map(reasons, func(reason string, _ int) string {
+		names := byReason[reason]
+		sort.Strings(names)
+		return fmt.Sprintf("%s (%s)", reason, utils.PrettySlice(names, 5))
+	}), "; ")
+}
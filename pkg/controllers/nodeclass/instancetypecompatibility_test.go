@@ -0,0 +1,89 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass_test
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NodeClass Instance Type Compatibility Reconciler", func() {
+	var reconciler *nodeclass.InstanceTypeCompatibility
+	BeforeEach(func() {
+		reconciler = nodeclass.NewInstanceTypeCompatibilityReconciler(awsEnv.InstanceTypesProvider)
+		instances := lo.Map(fake.MakeInstances(), func(info ec2types.InstanceTypeInfo, _ int) ec2types.InstanceTypeInfo {
+			if info.InstanceType == "m5.large" || info.InstanceType == "m5.xlarge" {
+				networkInfo := *info.NetworkInfo
+				networkInfo.EnaSupport = ec2types.EnaSupportRequired
+				info.NetworkInfo = &networkInfo
+			}
+			return info
+		})
+		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: instances})
+		awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+			InstanceTypeOfferings: fake.MakeInstanceOfferings(instances),
+		})
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+	})
+	It("should report the excluded instance types without affecting readiness", func() {
+		nodeClass.Status.AMIs[0].ENASupport = lo.ToPtr(false)
+		for _, cond := range []string{
+			v1.ConditionTypeAMIsReady,
+			v1.ConditionTypeSubnetsReady,
+			v1.ConditionTypeSecurityGroupsReady,
+			v1.ConditionTypeInstanceProfileReady,
+			v1.ConditionTypeValidationSucceeded,
+		} {
+			nodeClass.StatusConditions().SetTrue(cond)
+		}
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+
+		condition := nodeClass.StatusConditions().Get(v1.ConditionTypeInstanceTypesIncompatible)
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("InstanceTypesExcluded"))
+		Expect(condition.Message).To(Equal("Excluded instance types which can't be launched with their AMI, ami-test1 doesn't support ENA (m5.large, m5.xlarge)"))
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+	})
+	It("should remove the condition once no instance types are excluded", func() {
+		nodeClass.Status.AMIs[0].ENASupport = lo.ToPtr(false)
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeInstanceTypesIncompatible)).ToNot(BeNil())
+
+		nodeClass.Status.AMIs[0].ENASupport = lo.ToPtr(true)
+		_, err = reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeInstanceTypesIncompatible)).To(BeNil())
+	})
+	It("should not set the condition before the subnets are resolved", func() {
+		nodeClass.Status.Subnets = nil
+		nodeClass.Status.AMIs[0].ENASupport = lo.ToPtr(false)
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeInstanceTypesIncompatible)).To(BeNil())
+	})
+})
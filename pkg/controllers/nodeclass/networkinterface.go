@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+)
+
+// NetworkInterface resolves the subnets and security groups of the EC2NodeClass's additional network interfaces. It
+// runs before the Subnet reconciler, which only keeps the subnets in zones where every network interface has a subnet.
+type NetworkInterface struct {
+	subnetProvider        subnet.Provider
+	securityGroupProvider securitygroup.Provider
+}
+
+func NewNetworkInterfaceReconciler(subnetProvider subnet.Provider, securityGroupProvider securitygroup.Provider) *NetworkInterface {
+	return &NetworkInterface{
+		subnetProvider:        subnetProvider,
+		securityGroupProvider: securityGroupProvider,
+	}
+}
+
+func (n *NetworkInterface) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	if len(nodeClass.Spec.NetworkInterfaces) == 0 {
+		nodeClass.Status.NetworkInterfaces = nil
+		_ = nodeClass.StatusConditions().Clear(v1.ConditionTypeNetworkInterfacesReady)
+		return reconcile.Result{}, nil
+	}
+	var statuses []v1.NetworkInterfaceStatus
+	for _, networkInterface := range nodeClass.Spec.NetworkInterfaces {
+		subnets, err := n.subnetProvider.ListByTerms(ctx, networkInterface.SubnetSelectorTerms)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("getting network interface subnets, %w", err)
+		}
+		if len(subnets) == 0 {
+			nodeClass.Status.NetworkInterfaces = nil
+			nodeClass.StatusConditions().SetFalse(v1.ConditionTypeNetworkInterfacesReady, "SubnetsNotFound",
+				fmt.Sprintf("SubnetSelector of the network interface at device index %d did not match any Subnets", networkInterface.DeviceIndex))
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+		var securityGroups []ec2types.SecurityGroup
+		if len(networkInterface.SecurityGroupSelectorTerms) != 0 {
+			if securityGroups, err = n.securityGroupProvider.ListByTerms(ctx, networkInterface.SecurityGroupSelectorTerms); err != nil {
+				return reconcile.Result{}, fmt.Errorf("getting network interface security groups, %w", err)
+			}
+			if len(securityGroups) == 0 {
+				nodeClass.Status.NetworkInterfaces = nil
+				nodeClass.StatusConditions().SetFalse(v1.ConditionTypeNetworkInterfacesReady, "SecurityGroupsNotFound",
+					fmt.Sprintf("SecurityGroupSelector of the network interface at device index %d did not match any SecurityGroups", networkInterface.DeviceIndex))
+				return reconcile.Result{RequeueAfter: time.Minute}, nil
+			}
+		}
+		// Network interfaces are created in the subnet of their zone with the most available IP addresses
+		sort.Slice(subnets, func(i, j int) bool {
+			if lo.FromPtr(subnets[i].AvailableIpAddressCount) != lo.FromPtr(subnets[j].AvailableIpAddressCount) {
+				return lo.FromPtr(subnets[i].AvailableIpAddressCount) > lo.FromPtr(subnets[j].AvailableIpAddressCount)
+			}
+			return lo.FromPtr(subnets[i].SubnetId) < lo.FromPtr(subnets[j].SubnetId)
+		})
+		sort.Slice(securityGroups, func(i, j int) bool {
+			return lo.FromPtr(securityGroups[i].GroupId) < lo.FromPtr(securityGroups[j].GroupId)
+		})
+		statuses = append(statuses, v1.NetworkInterfaceStatus{
+			DeviceIndex: networkInterface.DeviceIndex,
+			Count:       lo.FromPtrOr(networkInterface.Count, 1),
+			Subnets: lo.Map(subnets, func(ec2subnet ec2types.Subnet, _ int) v1.Subnet {
+				return v1.Subnet{
+					ID:                      lo.FromPtr(ec2subnet.SubnetId),
+					Zone:                    lo.FromPtr(ec2subnet.AvailabilityZone),
+					ZoneID:                  lo.FromPtr(ec2subnet.AvailabilityZoneId),
+					AvailableIPAddressCount: ec2subnet.AvailableIpAddressCount,
+				}
+			}),
+			SecurityGroups: lo.Map(securityGroups, func(securityGroup ec2types.SecurityGroup, _ int) v1.SecurityGroup {
+				return v1.SecurityGroup{
+					ID:   lo.FromPtr(securityGroup.GroupId),
+					Name: lo.FromPtr(securityGroup.GroupName),
+				}
+			}),
+		})
+	}
+	nodeClass.Status.NetworkInterfaces = statuses
+	nodeClass.StatusConditions().SetTrue(v1.ConditionTypeNetworkInterfacesReady)
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
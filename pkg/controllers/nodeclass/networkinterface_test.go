@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass_test
+
+import (
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Network Interface Status Controller", func() {
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass(v1.EC2NodeClass{
+			Spec: v1.EC2NodeClassSpec{
+				SubnetSelectorTerms: []v1.SubnetSelectorTerm{
+					{
+						Tags: map[string]string{"*": "*"},
+					},
+				},
+				SecurityGroupSelectorTerms: []v1.SecurityGroupSelectorTerm{
+					{
+						Tags: map[string]string{"*": "*"},
+					},
+				},
+				AMIFamily: lo.ToPtr(v1.AMIFamilyCustom),
+				AMISelectorTerms: []v1.AMISelectorTerm{
+					{
+						Tags: map[string]string{"*": "*"},
+					},
+				},
+				NetworkInterfaces: []v1.NetworkInterface{
+					{
+						DeviceIndex: 1,
+						SubnetSelectorTerms: []v1.SubnetSelectorTerm{
+							{ID: "subnet-test1"},
+							{ID: "subnet-test2"},
+						},
+					},
+				},
+			},
+		})
+	})
+	It("should update EC2NodeClass status for the network interfaces", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.NetworkInterfaces).To(Equal([]v1.NetworkInterfaceStatus{
+			{
+				DeviceIndex: 1,
+				Count:       1,
+				Subnets: []v1.Subnet{
+					{
+						ID:                      "subnet-test1",
+						Zone:                    "test-zone-1a",
+						ZoneID:                  "tstz1-1a",
+						AvailableIPAddressCount: lo.ToPtr[int32](100),
+					},
+					{
+						ID:                      "subnet-test2",
+						Zone:                    "test-zone-1b",
+						ZoneID:                  "tstz1-1b",
+						AvailableIPAddressCount: lo.ToPtr[int32](100),
+					},
+				},
+			},
+		}))
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeNetworkInterfacesReady).IsTrue()).To(BeTrue())
+	})
+	It("should only keep the subnets in zones where every network interface has a subnet", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(lo.Map(nodeClass.Status.Subnets, func(s v1.Subnet, _ int) string { return s.ID })).To(ConsistOf("subnet-test1", "subnet-test2"))
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSubnetsReady).IsTrue()).To(BeTrue())
+	})
+	It("should resolve the security groups of the network interfaces", func() {
+		nodeClass.Spec.NetworkInterfaces[0].SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{{ID: "sg-test2"}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.NetworkInterfaces).To(HaveLen(1))
+		Expect(nodeClass.Status.NetworkInterfaces[0].SecurityGroups).To(Equal([]v1.SecurityGroup{{ID: "sg-test2", Name: "securityGroup-test2"}}))
+	})
+	It("should not be ready when a network interface's subnet selector doesn't match any subnets", func() {
+		nodeClass.Spec.NetworkInterfaces[0].SubnetSelectorTerms = []v1.SubnetSelectorTerm{{Tags: map[string]string{"foo": "invalid"}}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.NetworkInterfaces).To(BeNil())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeNetworkInterfacesReady).IsFalse()).To(BeTrue())
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsFalse()).To(BeTrue())
+	})
+	It("should not be ready when no subnet is in a zone of the network interfaces", func() {
+		nodeClass.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{ID: "subnet-test3"}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.Subnets).To(BeNil())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSubnetsReady).IsFalse()).To(BeTrue())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSubnetsReady).Reason).To(Equal("NetworkInterfaceSubnetsNotFound"))
+	})
+	It("should remove the network interfaces from the status once they're removed", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.NetworkInterfaces).To(HaveLen(1))
+
+		nodeClass.Spec.NetworkInterfaces = nil
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.NetworkInterfaces).To(BeNil())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeNetworkInterfacesReady)).To(BeNil())
+		Expect(lo.Map(nodeClass.Status.Subnets, func(s v1.Subnet, _ int) string { return s.ID })).To(ContainElement("subnet-test3"))
+	})
+})
@@ -38,10 +38,10 @@ func NewReadinessReconciler(launchTemplateProvider launchtemplate.Provider) *Rea
 }
 
 func (n Readiness) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
-	// A NodeClass that uses AL2023 requires the cluster CIDR for launching nodes.
+	// A NodeClass that uses AL2023, or that launches IPv6-only nodes, requires the cluster CIDR for launching nodes.
 	// To allow Karpenter to be used for Non-EKS clusters, resolving the Cluster CIDR
 	// will not be done at startup but instead in a reconcile loop.
-	if nodeClass.AMIFamily() == v1.AMIFamilyAL2023 {
+	if nodeClass.AMIFamily() == v1.AMIFamilyAL2023 || nodeClass.IPv6Native() {
 		if err := n.launchTemplateProvider.ResolveClusterCIDR(ctx); err != nil {
 			nodeClass.StatusConditions().SetFalse(status.ConditionReady, "NodeClassNotReady", "Failed to detect the cluster CIDR")
 			return reconcile.Result{}, fmt.Errorf("failed to detect the cluster CIDR, %w", err)
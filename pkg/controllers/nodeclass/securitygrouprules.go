@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+)
+
+// SecurityGroupRules reports the rules which nodes need to join the cluster, but which the EC2NodeClass's security groups
+// don't have, through the SecurityGroupRulesMissing condition. Without them, nodes launch but stay NotReady. Only the
+// security group rules are inspected, so traffic which is allowed in other ways, such as by a security group the EKS
+// control plane adds, can't be accounted for. This doesn't affect the EC2NodeClass's readiness for that reason.
+type SecurityGroupRules struct {
+	securityGroupProvider securitygroup.Provider
+}
+
+func NewSecurityGroupRulesReconciler(securityGroupProvider securitygroup.Provider) *SecurityGroupRules {
+	return &SecurityGroupRules{
+		securityGroupProvider: securityGroupProvider,
+	}
+}
+
+func (s *SecurityGroupRules) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	if len(nodeClass.Status.SecurityGroups) == 0 {
+		// Security groups which can't be resolved are reported through the SecurityGroupsReady condition
+		_ = nodeClass.StatusConditions().Clear(v1.ConditionTypeSecurityGroupRulesMissing)
+		return reconcile.Result{}, nil
+	}
+	securityGroups, err := s.securityGroupProvider.List(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting security groups, %w", err)
+	}
+	missing := missingSecurityGroupRules(securityGroups)
+	if len(missing) == 0 {
+		_ = nodeClass.StatusConditions().Clear(v1.ConditionTypeSecurityGroupRulesMissing)
+		return reconcile.Result{}, nil
+	}
+	ids := lo.Map(securityGroups, func(sg ec2types.SecurityGroup, _ int) string { return lo.FromPtr(sg.GroupId) })
+	sort.Strings(ids)
+	nodeClass.StatusConditions().SetTrueWithReason(v1.ConditionTypeSecurityGroupRulesMissing, "RulesMissing",
+		fmt.Sprintf("Security groups %v don't allow %s", ids, strings.Join(missing, ", ")),
+	)
+	return reconcile.Result{}, nil
+}
+
+// missingSecurityGroupRules returns the traffic which nodes need, but which no rule of the security groups allows. The
+// destination of the cluster endpoint and the source of the control plane aren't known, so any rule for their ports is
+// accepted. Pod traffic between nodes must be allowed by a rule referencing one of the security groups, or a CIDR.
+func missingSecurityGroupRules(securityGroups []ec2types.SecurityGroup) []string {
+	ids := sets.New(lo.Map(securityGroups, func(sg ec2types.SecurityGroup, _ int) string { return lo.FromPtr(sg.GroupId) })...)
+	ingress := lo.FlatMap(securityGroups, func(sg ec2types.SecurityGroup, _ int) []ec2types.IpPermission { return sg.IpPermissions })
+	egress := lo.FlatMap(securityGroups, func(sg ec2types.SecurityGroup, _ int) []ec2types.IpPermission { return sg.IpPermissionsEgress })
+	allowsPort := func(permissions []ec2types.IpPermission, port int32) bool {
+		return lo.ContainsBy(permissions, func(permission ec2types.IpPermission) bool {
+			switch lo.FromPtr(permission.IpProtocol) {
+			case "-1":
+				return true
+			case "tcp", "6":
+				return lo.FromPtr(permission.FromPort) <= port && port <= lo.FromPtr(permission.ToPort)
+			default:
+				return false
+			}
+		})
+	}
+	allowsNodes := func(permissions []ec2types.IpPermission) bool {
+		return lo.ContainsBy(permissions, func(permission ec2types.IpPermission) bool {
+			if lo.FromPtr(permission.IpProtocol) != "-1" {
+				return false
+			}
+			return len(permission.IpRanges) != 0 || len(permission.Ipv6Ranges) != 0 || len(permission.PrefixListIds) != 0 ||
+				lo.ContainsBy(permission.UserIdGroupPairs, func(pair ec2types.UserIdGroupPair) bool { return ids.Has(lo.FromPtr(pair.GroupId)) })
+		})
+	}
+	var missing []string
+	if !allowsPort(egress, 443) {
+		missing = append(missing, "outbound TCP 443 to the cluster endpoint")
+	}
+	if !allowsPort(ingress, 10250) {
+		missing = append(missing, "inbound TCP 10250 from the control plane to the kubelet")
+	}
+	if !allowsNodes(ingress) {
+		missing = append(missing, "all inbound traffic between nodes")
+	}
+	if !allowsNodes(egress) {
+		missing = append(missing, "all outbound traffic between nodes")
+	}
+	return missing
+}
@@ -0,0 +1,118 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeclass_test
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/status"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NodeClass Security Group Rules Reconciler", func() {
+	var reconciler *nodeclass.SecurityGroupRules
+	var securityGroup ec2types.SecurityGroup
+	BeforeEach(func() {
+		reconciler = nodeclass.NewSecurityGroupRulesReconciler(awsEnv.SecurityGroupProvider)
+		nodeClass.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{{ID: "sg-test1"}}
+		nodeClass.Status.SecurityGroups = []v1.SecurityGroup{{ID: "sg-test1"}}
+		securityGroup = ec2types.SecurityGroup{
+			GroupId:   aws.String("sg-test1"),
+			GroupName: aws.String("securityGroup-test1"),
+			IpPermissions: []ec2types.IpPermission{
+				{IpProtocol: aws.String("tcp"), FromPort: aws.Int32(10250), ToPort: aws.Int32(10250), IpRanges: []ec2types.IpRange{{CidrIp: aws.String("10.0.0.0/16")}}},
+				{IpProtocol: aws.String("-1"), UserIdGroupPairs: []ec2types.UserIdGroupPair{{GroupId: aws.String("sg-test1")}}},
+			},
+			IpPermissionsEgress: []ec2types.IpPermission{
+				{IpProtocol: aws.String("-1"), IpRanges: []ec2types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+			},
+		}
+	})
+	It("should not set the condition when the security groups allow the traffic nodes need", func() {
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{securityGroup}})
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSecurityGroupRulesMissing)).To(BeNil())
+	})
+	It("should report the missing rules without affecting readiness", func() {
+		securityGroup.IpPermissions = nil
+		securityGroup.IpPermissionsEgress = []ec2types.IpPermission{
+			{IpProtocol: aws.String("tcp"), FromPort: aws.Int32(443), ToPort: aws.Int32(443), IpRanges: []ec2types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+		}
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{securityGroup}})
+		for _, cond := range []string{
+			v1.ConditionTypeAMIsReady,
+			v1.ConditionTypeSubnetsReady,
+			v1.ConditionTypeSecurityGroupsReady,
+			v1.ConditionTypeInstanceProfileReady,
+			v1.ConditionTypeValidationSucceeded,
+		} {
+			nodeClass.StatusConditions().SetTrue(cond)
+		}
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+
+		condition := nodeClass.StatusConditions().Get(v1.ConditionTypeSecurityGroupRulesMissing)
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("RulesMissing"))
+		Expect(condition.Message).To(Equal("Security groups [sg-test1] don't allow inbound TCP 10250 from the control plane to the kubelet, all inbound traffic between nodes, all outbound traffic between nodes"))
+		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+	})
+	It("should report a missing rule for the cluster endpoint", func() {
+		securityGroup.IpPermissionsEgress = []ec2types.IpPermission{
+			{IpProtocol: aws.String("tcp"), FromPort: aws.Int32(0), ToPort: aws.Int32(80), IpRanges: []ec2types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}},
+		}
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{securityGroup}})
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSecurityGroupRulesMissing).Message).To(Equal("Security groups [sg-test1] don't allow outbound TCP 443 to the cluster endpoint, all outbound traffic between nodes"))
+	})
+	It("should not accept traffic between nodes from a security group that isn't selected", func() {
+		securityGroup.IpPermissions[1].UserIdGroupPairs = []ec2types.UserIdGroupPair{{GroupId: aws.String("sg-other")}}
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{securityGroup}})
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSecurityGroupRulesMissing).Message).To(Equal("Security groups [sg-test1] don't allow all inbound traffic between nodes"))
+	})
+	It("should remove the condition once the rules are added", func() {
+		missing := securityGroup
+		missing.IpPermissions = nil
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{missing}})
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSecurityGroupRulesMissing)).ToNot(BeNil())
+
+		awsEnv.SecurityGroupCache.Flush()
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{securityGroup}})
+		_, err = reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSecurityGroupRulesMissing)).To(BeNil())
+	})
+	It("should not set the condition before the security groups are resolved", func() {
+		nodeClass.Status.SecurityGroups = nil
+		securityGroup.IpPermissions = nil
+		awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{securityGroup}})
+		_, err := reconciler.Reconcile(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSecurityGroupRulesMissing)).To(BeNil())
+	})
+})
@@ -22,19 +22,28 @@ import (
 
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
 type Subnet struct {
-	subnetProvider subnet.Provider
+	subnetProvider      subnet.Provider
+	vpcEndpointProvider vpcendpoint.Provider
+	cm                  *pretty.ChangeMonitor
 }
 
-func NewSubnetReconciler(subnetProvider subnet.Provider) *Subnet {
+func NewSubnetReconciler(subnetProvider subnet.Provider, vpcEndpointProvider vpcendpoint.Provider) *Subnet {
 	return &Subnet{
-		subnetProvider: subnetProvider,
+		subnetProvider:      subnetProvider,
+		vpcEndpointProvider: vpcEndpointProvider,
+		cm:                  pretty.NewChangeMonitor(),
 	}
 }
 
@@ -50,6 +59,29 @@ func (s *Subnet) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (rec
 		// Returning 'ok' in this case means that the nodeclass will remain in an unready state until the component is restarted.
 		return reconcile.Result{RequeueAfter: time.Minute}, nil
 	}
+	if services := options.FromContext(ctx).RequiredVPCEndpointServices(); len(services) != 0 {
+		var missing map[string][]string
+		if subnets, missing, err = s.filterByVPCEndpoints(ctx, subnets, services); err != nil {
+			return reconcile.Result{}, fmt.Errorf("getting vpc endpoints, %w", err)
+		}
+		if s.cm.HasChanged(string(nodeClass.UID), missing) && len(missing) != 0 {
+			log.FromContext(ctx).WithValues("missing-vpc-endpoints", missing).Info("excluding subnets in zones without required vpc endpoints")
+		}
+		if len(subnets) == 0 {
+			nodeClass.Status.Subnets = nil
+			nodeClass.StatusConditions().SetFalse(v1.ConditionTypeSubnetsReady, "VPCEndpointsNotFound",
+				fmt.Sprintf("Required VPC endpoints aren't reachable from the zones of any selected subnet, %s", utils.PrettySlice(lo.Uniq(lo.Flatten(lo.Values(missing))), 5)))
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+	}
+	if len(nodeClass.Status.NetworkInterfaces) != 0 {
+		if subnets = filterByNetworkInterfaces(subnets, nodeClass.Status.NetworkInterfaces); len(subnets) == 0 {
+			nodeClass.Status.Subnets = nil
+			nodeClass.StatusConditions().SetFalse(v1.ConditionTypeSubnetsReady, "NetworkInterfaceSubnetsNotFound",
+				"No selected subnet is in a zone where every network interface has a subnet")
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+	}
 	sort.Slice(subnets, func(i, j int) bool {
 		if int(*subnets[i].AvailableIpAddressCount) != int(*subnets[j].AvailableIpAddressCount) {
 			return int(*subnets[i].AvailableIpAddressCount) > int(*subnets[j].AvailableIpAddressCount)
@@ -58,11 +90,41 @@ func (s *Subnet) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (rec
 	})
 	nodeClass.Status.Subnets = lo.Map(subnets, func(ec2subnet ec2types.Subnet, _ int) v1.Subnet {
 		return v1.Subnet{
-			ID:     *ec2subnet.SubnetId,
-			Zone:   *ec2subnet.AvailabilityZone,
-			ZoneID: *ec2subnet.AvailabilityZoneId,
+			ID:                      *ec2subnet.SubnetId,
+			Zone:                    *ec2subnet.AvailabilityZone,
+			ZoneID:                  *ec2subnet.AvailabilityZoneId,
+			AvailableIPAddressCount: lo.ToPtr(*ec2subnet.AvailableIpAddressCount),
+			IPv6Native:              lo.FromPtr(ec2subnet.Ipv6Native),
 		}
 	})
 	nodeClass.StatusConditions().SetTrue(v1.ConditionTypeSubnetsReady)
 	return reconcile.Result{RequeueAfter: time.Minute}, nil
 }
+
+// filterByVPCEndpoints returns the subnets in zones from which all of the services are reachable through a VPC endpoint,
+// along with the services which aren't reachable from each of the other zones
+func (s *Subnet) filterByVPCEndpoints(ctx context.Context, subnets []ec2types.Subnet, services []string) ([]ec2types.Subnet, map[string][]string, error) {
+	missing := map[string][]string{}
+	for vpcID, vpcSubnets := range lo.GroupBy(subnets, func(s ec2types.Subnet) string { return lo.FromPtr(s.VpcId) }) {
+		zones := lo.Uniq(lo.Map(vpcSubnets, func(s ec2types.Subnet, _ int) string { return lo.FromPtr(s.AvailabilityZone) }))
+		vpcMissing, err := s.vpcEndpointProvider.MissingServices(ctx, vpcID, services, zones)
+		if err != nil {
+			return nil, nil, err
+		}
+		missing = lo.Assign(missing, vpcMissing)
+	}
+	return lo.Reject(subnets, func(s ec2types.Subnet, _ int) bool {
+		_, ok := missing[lo.FromPtr(s.AvailabilityZone)]
+		return ok
+	}), missing, nil
+}
+
+// filterByNetworkInterfaces returns the subnets in zones where every additional network interface has a subnet, since
+// the network interfaces of an instance must be in the zone it's launched into
+func filterByNetworkInterfaces(subnets []ec2types.Subnet, networkInterfaces []v1.NetworkInterfaceStatus) []ec2types.Subnet {
+	return lo.Filter(subnets, func(s ec2types.Subnet, _ int) bool {
+		return lo.EveryBy(networkInterfaces, func(ni v1.NetworkInterfaceStatus) bool {
+			return lo.ContainsBy(ni.Subnets, func(niSubnet v1.Subnet) bool { return niSubnet.Zone == lo.FromPtr(s.AvailabilityZone) })
+		})
+	})
+}
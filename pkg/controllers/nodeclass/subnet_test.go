@@ -15,12 +15,16 @@ limitations under the License.
 package nodeclass_test
 
 import (
+	"fmt"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/samber/lo"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -57,28 +61,57 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
 	})
+	It("Should update EC2NodeClass status for IPv6-only Subnets", func() {
+		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{
+			{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(100)},
+			{SubnetId: aws.String("subnet-test2"), AvailabilityZone: aws.String("test-zone-1b"), AvailabilityZoneId: aws.String("tstz1-1b"), AvailableIpAddressCount: aws.Int32(0), Ipv6Native: aws.Bool(true)},
+		}})
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
+			{
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
+			},
+			{
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: lo.ToPtr[int32](0),
+				IPv6Native:              true,
+			},
+		}))
+		Expect(nodeClass.IPv6Native()).To(BeFalse())
+	})
 	It("Should have the correct ordering for the Subnets", func() {
 		awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{
 			{SubnetId: aws.String("subnet-test1"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(20)},
@@ -90,19 +123,22 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: lo.ToPtr[int32](50),
 			},
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](20),
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -121,14 +157,16 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -144,9 +182,10 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -157,24 +196,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 		}))
 
@@ -195,14 +238,16 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -213,24 +258,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 		}))
 
@@ -244,9 +293,10 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -269,24 +319,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: lo.ToPtr[int32](100),
 			},
 		}))
 
@@ -301,4 +355,52 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		Expect(nodeClass.Status.Subnets).To(BeNil())
 		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeSubnetsReady).IsFalse()).To(BeTrue())
 	})
+	Context("Required VPC Endpoints", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RequiredVPCEndpoints: lo.ToPtr("ecr.api,s3,sts")}))
+			awsEnv.EC2API.DescribeVpcEndpointsBehavior.Output.Set(&ec2.DescribeVpcEndpointsOutput{VpcEndpoints: []ec2types.VpcEndpoint{
+				{VpcEndpointId: aws.String("vpce-s3"), ServiceName: aws.String(fmt.Sprintf("com.amazonaws.%s.s3", fake.DefaultRegion)), VpcEndpointType: ec2types.VpcEndpointTypeGateway},
+				{VpcEndpointId: aws.String("vpce-ecr-api"), ServiceName: aws.String(fmt.Sprintf("com.amazonaws.%s.ecr.api", fake.DefaultRegion)), VpcEndpointType: ec2types.VpcEndpointTypeInterface, SubnetIds: []string{"subnet-test1", "subnet-test2"}},
+				{VpcEndpointId: aws.String("vpce-sts"), ServiceName: aws.String(fmt.Sprintf("com.amazonaws.%s.sts", fake.DefaultRegion)), VpcEndpointType: ec2types.VpcEndpointTypeInterface, SubnetIds: []string{"subnet-test1", "subnet-test2", "subnet-test3"}},
+			}})
+		})
+		It("should exclude subnets in zones without the required VPC endpoints", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
+				{
+					ID:                      "subnet-test1",
+					Zone:                    "test-zone-1a",
+					ZoneID:                  "tstz1-1a",
+					AvailableIPAddressCount: lo.ToPtr[int32](100),
+				},
+				{
+					ID:                      "subnet-test2",
+					Zone:                    "test-zone-1b",
+					ZoneID:                  "tstz1-1b",
+					AvailableIPAddressCount: lo.ToPtr[int32](100),
+				},
+			}))
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
+		})
+		It("should not be ready when no zone has the required VPC endpoints", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RequiredVPCEndpoints: lo.ToPtr("ecr.api,ecr.dkr")}))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.Subnets).To(BeNil())
+			condition := nodeClass.StatusConditions().Get(v1.ConditionTypeSubnetsReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("VPCEndpointsNotFound"))
+		})
+		It("should not restrict zones when no VPC endpoints are required", func() {
+			ctx = options.ToContext(ctx, test.Options())
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.Subnets).To(HaveLen(4))
+			Expect(awsEnv.EC2API.DescribeVpcEndpointsBehavior.Calls()).To(Equal(0))
+		})
+	})
 })
@@ -75,14 +75,18 @@ var _ = BeforeSuite(func() {
 		env.Client,
 		events.NewRecorder(&record.FakeRecorder{}),
 		awsEnv.SubnetProvider,
+		awsEnv.VPCEndpointProvider,
 		awsEnv.SecurityGroupProvider,
 		awsEnv.AMIProvider,
 		awsEnv.InstanceProfileProvider,
 		awsEnv.LaunchTemplateProvider,
 		awsEnv.CapacityReservationProvider,
+		awsEnv.AccountSettingsProvider,
 		awsEnv.EC2API,
 		awsEnv.ValidationCache,
 		awsEnv.AMIResolver,
+		awsEnv.HealthTracker,
+		awsEnv.InstanceTypesProvider,
 	)
 })
 
@@ -92,6 +96,7 @@ var _ = AfterSuite(func() {
 
 var _ = BeforeEach(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options(coretest.OptionsFields{FeatureGates: coretest.FeatureGates{ReservedCapacity: lo.ToPtr(true)}}))
+	ctx = options.ToContext(ctx, test.Options())
 	nodeClass = test.EC2NodeClass()
 	awsEnv.Reset()
 })
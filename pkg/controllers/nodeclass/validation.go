@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/mitchellh/hashstructure/v2"
@@ -31,6 +32,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
@@ -44,11 +47,15 @@ import (
 )
 
 const (
-	ConditionReasonCreateFleetAuthFailed          = "CreateFleetAuthCheckFailed"
-	ConditionReasonCreateLaunchTemplateAuthFailed = "CreateLaunchTemplateAuthCheckFailed"
-	ConditionReasonRunInstancesAuthFailed         = "RunInstancesAuthCheckFailed"
-	ConditionReasonDependenciesNotReady           = "DependenciesNotReady"
-	ConditionReasonTagValidationFailed            = "TagValidationFailed"
+	ConditionReasonCreateFleetAuthFailed            = "CreateFleetAuthCheckFailed"
+	ConditionReasonCreateLaunchTemplateAuthFailed   = "CreateLaunchTemplateAuthCheckFailed"
+	ConditionReasonRunInstancesAuthFailed           = "RunInstancesAuthCheckFailed"
+	ConditionReasonDependenciesNotReady             = "DependenciesNotReady"
+	ConditionReasonTagValidationFailed              = "TagValidationFailed"
+	ConditionReasonLabelValidationFailed            = "LabelValidationFailed"
+	ConditionReasonNameTagTemplateValidationFailed  = "NameTagTemplateValidationFailed"
+	ConditionReasonUserDataTemplateValidationFailed = "UserDataTemplateValidationFailed"
+	ConditionReasonKubeletConfigValidationFailed    = "KubeletConfigValidationFailed"
 )
 
 var ValidationConditionMessages = map[string]string{
@@ -106,11 +113,27 @@ func (v *Validation) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass)
 			},
 		},
 	}
-	tags, err := utils.GetTags(nodeClass, nodeClaim, options.FromContext(ctx).ClusterName)
+	tags, err := utils.GetTags(nodeClass, nodeClaim, options.FromContext(ctx).ClusterName, options.FromContext(ctx).TagKeyPrefix)
 	if err != nil {
 		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeValidationSucceeded, ConditionReasonTagValidationFailed, err.Error())
 		return reconcile.Result{}, reconcile.TerminalError(fmt.Errorf("validating tags, %w", err))
 	}
+	if err := validateLabels(nodeClass.Spec.Labels); err != nil {
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeValidationSucceeded, ConditionReasonLabelValidationFailed, err.Error())
+		return reconcile.Result{}, reconcile.TerminalError(fmt.Errorf("validating labels, %w", err))
+	}
+	if err := validateNameTagTemplate(nodeClass); err != nil {
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeValidationSucceeded, ConditionReasonNameTagTemplateValidationFailed, err.Error())
+		return reconcile.Result{}, reconcile.TerminalError(fmt.Errorf("validating name tag template, %w", err))
+	}
+	if err := validateUserDataTemplate(nodeClass); err != nil {
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeValidationSucceeded, ConditionReasonUserDataTemplateValidationFailed, err.Error())
+		return reconcile.Result{}, reconcile.TerminalError(fmt.Errorf("validating userData template, %w", err))
+	}
+	if err := validateKubeletConfig(nodeClass); err != nil {
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeValidationSucceeded, ConditionReasonKubeletConfigValidationFailed, err.Error())
+		return reconcile.Result{}, reconcile.TerminalError(fmt.Errorf("validating kubelet config, %w", err))
+	}
 
 	if val, ok := v.cache.Get(v.cacheKey(nodeClass, tags)); ok {
 		// We still update the status condition even if it's cached since we may have had a conflict error previously
@@ -277,6 +300,7 @@ func (*Validation) cacheKey(nodeClass *v1.EC2NodeClass, tags map[string]string)
 		nodeClass.Status.SecurityGroups,
 		nodeClass.Status.AMIs,
 		nodeClass.Status.InstanceProfile,
+		nodeClass.Status.AccountDefaults,
 		nodeClass.Spec.MetadataOptions,
 		nodeClass.Spec.BlockDeviceMappings,
 		tags,
@@ -340,3 +364,94 @@ func (v *Validation) mockOptions(ctx context.Context, nodeClaim *karpv1.NodeClai
 		},
 	}, karpv1.CapacityTypeOnDemand, amiOptions)
 }
+
+// validateLabels checks that the labels are valid Kubernetes labels. Restricted label domains are rejected by the
+// EC2NodeClass CRD's validation rules.
+func validateLabels(labels map[string]string) error {
+	var errs []string
+	for _, key := range lo.Keys(labels) {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Sprintf("label key %q is invalid, %s", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(labels[key]) {
+			errs = append(errs, fmt.Sprintf("label value %q is invalid, %s", labels[key], msg))
+		}
+	}
+	if len(errs) != 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validateNameTagTemplate checks that the Name tag template can be rendered for a NodeClaim launched with the
+// EC2NodeClass. Templates which reference unknown fields would otherwise only fail once a node has registered.
+func validateNameTagTemplate(nodeClass *v1.EC2NodeClass) error {
+	if nodeClass.Spec.NameTagTemplate == nil {
+		return nil
+	}
+	_, err := utils.RenderNameTag(*nodeClass.Spec.NameTagTemplate, utils.NameTagTemplateData{
+		NodeName:     "ip-10-0-0-1.ec2.internal",
+		NodeClaim:    "default-abcde",
+		NodePool:     "default",
+		NodeClass:    nodeClass.Name,
+		Zone:         "us-west-2a",
+		ZoneID:       "usw2-az1",
+		InstanceType: "m5.large",
+		CapacityType: karpv1.CapacityTypeOnDemand,
+		InstanceID:   "i-0123456789abcdef0",
+	})
+	return err
+}
+
+// validateUserDataTemplate checks that a templated userData can be rendered. Templates which reference unknown fields
+// would otherwise only fail when launch templates are generated for a NodeClaim.
+func validateUserDataTemplate(nodeClass *v1.EC2NodeClass) error {
+	if nodeClass.Spec.UserData == nil || !lo.FromPtr(nodeClass.Spec.TemplatedUserData) {
+		return nil
+	}
+	_, err := utils.RenderUserData(*nodeClass.Spec.UserData, utils.UserDataTemplateData{
+		ClusterName:     "cluster",
+		ClusterEndpoint: "https://test-cluster",
+		NodeClass:       nodeClass.Name,
+		NodePool:        "default",
+		CapacityType:    karpv1.CapacityTypeOnDemand,
+		Labels:          map[string]string{},
+		InstanceTypes:   []string{"m5.large"},
+	})
+	return err
+}
+
+// managedKubeletConfigFields are the KubeletConfiguration fields which Karpenter generates from spec.kubelet and the
+// NodePool's taints
+var managedKubeletConfigFields = sets.New(
+	"clusterDNS",
+	"maxPods",
+	"podsPerCore",
+	"systemReserved",
+	"kubeReserved",
+	"evictionHard",
+	"evictionSoft",
+	"evictionSoftGracePeriod",
+	"evictionMaxPodGracePeriod",
+	"imageGCHighThresholdPercent",
+	"imageGCLowThresholdPercent",
+	"cpuCFSQuota",
+	"registerWithTaints",
+)
+
+// validateKubeletConfig checks that the kubelet config of nodeConfig and of the kubelet config drop-ins doesn't set
+// fields which Karpenter manages. The configs can't be validated by the CRD, since their values have no schema.
+func validateKubeletConfig(nodeClass *v1.EC2NodeClass) error {
+	if nodeClass.Spec.NodeConfig != nil && nodeClass.Spec.NodeConfig.Kubelet != nil {
+		if fields := sets.List(managedKubeletConfigFields.Intersection(sets.KeySet(nodeClass.Spec.NodeConfig.Kubelet.Config))); len(fields) != 0 {
+			return fmt.Errorf("nodeConfig.kubelet.config fields %s are managed by Karpenter and must be set with spec.kubelet", strings.Join(fields, ", "))
+		}
+	}
+	for _, dropIn := range nodeClass.Spec.KubeletConfigDropIns {
+		if fields := sets.List(managedKubeletConfigFields.Union(sets.New("apiVersion", "kind")).Intersection(sets.KeySet(dropIn.Config))); len(fields) != 0 {
+			return fmt.Errorf("kubeletConfigDropIns %q fields %s are managed by Karpenter", dropIn.Name, strings.Join(fields, ", "))
+		}
+	}
+	return nil
+}
@@ -19,10 +19,12 @@ import (
 	"github.com/samber/lo"
 
 	"github.com/aws/smithy-go"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -117,6 +119,105 @@ var _ = Describe("NodeClass Validation Status Controller", func() {
 			Entry(v1.NodeClassTagKey, map[string]string{v1.NodeClassTagKey: "testnodeclass"}),
 			Entry(v1.NodeClaimTagKey, map[string]string{v1.NodeClaimTagKey: "testnodeclaim"}),
 		)
+		It("should update status condition on nodeClass as NotReady when tags use the prefixed ownership tag keys", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				TagKeyPrefix: lo.ToPtr("example.com/karpenter"),
+			}))
+			nodeClass.Spec.Tags = map[string]string{"example.com/karpenter/nodepool": "testnodepool"}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			err := ExpectObjectReconcileFailed(ctx, env.Client, controller, nodeClass)
+			Expect(err).To(HaveOccurred())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).IsFalse()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).Reason).To(Equal("TagValidationFailed"))
+		})
+		It("should update status condition on nodeClass as NotReady when label validation fails", func() {
+			nodeClass.Spec.Tags = nil
+			nodeClass.Spec.Labels = map[string]string{"network-tier": "not a valid value"}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			err := ExpectObjectReconcileFailed(ctx, env.Client, controller, nodeClass)
+			Expect(err).To(HaveOccurred())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).IsFalse()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).Reason).To(Equal("LabelValidationFailed"))
+		})
+		It("should update status condition on nodeClass as NotReady when the name tag template can't be rendered", func() {
+			nodeClass.Spec.Tags = nil
+			nodeClass.Spec.NameTagTemplate = lo.ToPtr("{{ .NodePool }}-{{ .Unknown }}")
+			ExpectApplied(ctx, env.Client, nodeClass)
+			err := ExpectObjectReconcileFailed(ctx, env.Client, controller, nodeClass)
+			Expect(err).To(HaveOccurred())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).IsFalse()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).Reason).To(Equal("NameTagTemplateValidationFailed"))
+		})
+		It("should update status condition on nodeClass as NotReady when the templated userData can't be rendered", func() {
+			nodeClass.Spec.Tags = nil
+			nodeClass.Spec.UserData = lo.ToPtr("#!/bin/bash\necho {{ .ClusterName }} {{ .Unknown }}")
+			nodeClass.Spec.TemplatedUserData = lo.ToPtr(true)
+			ExpectApplied(ctx, env.Client, nodeClass)
+			err := ExpectObjectReconcileFailed(ctx, env.Client, controller, nodeClass)
+			Expect(err).To(HaveOccurred())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).IsFalse()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).Reason).To(Equal("UserDataTemplateValidationFailed"))
+		})
+		DescribeTable("should update status condition on nodeClass as NotReady when nodeConfig sets kubelet config fields managed by Karpenter", func(field string) {
+			nodeClass.Spec.Tags = nil
+			nodeClass.Spec.BootstrapMode = lo.ToPtr(v1.BootstrapModeNodeadm)
+			nodeClass.Spec.NodeConfig = &v1.NodeConfig{
+				Kubelet: &v1.NodeConfigKubelet{Config: map[string]apiextensionsv1.JSON{field: {Raw: []byte(`1`)}}},
+			}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			err := ExpectObjectReconcileFailed(ctx, env.Client, controller, nodeClass)
+			Expect(err).To(HaveOccurred())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).IsFalse()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).Reason).To(Equal("KubeletConfigValidationFailed"))
+		},
+			Entry("maxPods", "maxPods"),
+			Entry("clusterDNS", "clusterDNS"),
+			Entry("registerWithTaints", "registerWithTaints"),
+		)
+		DescribeTable("should update status condition on nodeClass as NotReady when kubelet config drop-ins set fields managed by Karpenter", func(field string) {
+			nodeClass.Spec.Tags = nil
+			nodeClass.Spec.BootstrapMode = lo.ToPtr(v1.BootstrapModeNodeadm)
+			nodeClass.Spec.KubeletConfigDropIns = []v1.KubeletConfigDropIn{
+				{Name: "logs", Config: map[string]apiextensionsv1.JSON{field: {Raw: []byte(`1`)}}},
+			}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			err := ExpectObjectReconcileFailed(ctx, env.Client, controller, nodeClass)
+			Expect(err).To(HaveOccurred())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).IsFalse()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).Reason).To(Equal("KubeletConfigValidationFailed"))
+		},
+			Entry("maxPods", "maxPods"),
+			Entry("registerWithTaints", "registerWithTaints"),
+			Entry("kind", "kind"),
+		)
+		It("should not render userData that isn't templated", func() {
+			nodeClass.Spec.Tags = nil
+			nodeClass.Spec.UserData = lo.ToPtr("#!/bin/bash\necho {{ .Unknown }}")
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).IsTrue()).To(BeTrue())
+		})
+		DescribeTable("should update status condition as Ready when device validation is specified", func(amiFamily string) {
+			nodeClass.Spec.Tags = nil
+			nodeClass.Spec.AMIFamily = lo.ToPtr(amiFamily)
+			nodeClass.Spec.DeviceValidation = &v1.DeviceValidation{Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceGPU, v1.DeviceValidationDeviceNVMe}}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeValidationSucceeded).IsTrue()).To(BeTrue())
+			Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
+		},
+			Entry("AL2", v1.AMIFamilyAL2),
+			Entry("AL2023", v1.AMIFamilyAL2023),
+			Entry("Ubuntu", v1.AMIFamilyUbuntu),
+		)
 		It("should update status condition as Ready when tags are valid", func() {
 			nodeClass.Spec.Tags = map[string]string{}
 			ExpectApplied(ctx, env.Client, nodeClass)
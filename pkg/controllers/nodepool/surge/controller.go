@@ -0,0 +1,189 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package surge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodepoolutils "sigs.k8s.io/karpenter/pkg/utils/nodepool"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// MaxWindow bounds how long a NodePool's requirements may be relaxed for, so that a forgotten annotation can't leave
+// a NodePool surging indefinitely
+const MaxWindow = 24 * time.Hour
+
+// relaxedKeys are the requirements which are removed from a NodePool while it's surging
+var relaxedKeys = sets.New(
+	corev1.LabelInstanceTypeStable,
+	v1.LabelInstanceFamily,
+	v1.LabelInstanceCategory,
+	v1.LabelInstanceGeneration,
+	karpv1.CapacityTypeLabelKey,
+)
+
+// Controller temporarily relaxes the instance type and capacity type requirements of NodePools which are annotated with
+// a surge capacity window, for example to launch on-demand capacity from more instance families during an incident.
+// The NodePool's original requirements are restored once the window ends, or when the annotation is removed.
+type Controller struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodePool *karpv1.NodePool) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.surge")
+
+	until, err := surgeUntil(nodePool, c.clk.Now())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "ignoring surge capacity window")
+		c.recorder.Publish(InvalidSurgeWindowEvent(nodePool, err))
+	}
+	_, annotated := nodePool.Annotations[v1.AnnotationSurgeCapacityUntil]
+	_, surging := nodePool.Annotations[v1.AnnotationSurgeCapacityRequirements]
+	active := err == nil && c.clk.Now().Before(until)
+	switch {
+	case active && !surging:
+		if err := c.startSurge(ctx, nodePool, until); err != nil {
+			return reconcile.Result{}, err
+		}
+	case !active && surging:
+		return reconcile.Result{}, c.endSurge(ctx, nodePool)
+	case !active && annotated:
+		// Expired and invalid windows are removed so that they can't take effect later
+		return reconcile.Result{}, c.patch(ctx, nodePool, func(np *karpv1.NodePool) {
+			delete(np.Annotations, v1.AnnotationSurgeCapacityUntil)
+		})
+	}
+	if active {
+		return reconcile.Result{RequeueAfter: until.Sub(c.clk.Now())}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) startSurge(ctx context.Context, nodePool *karpv1.NodePool, until time.Time) error {
+	original, err := json.Marshal(nodePool.Spec.Template.Spec.Requirements)
+	if err != nil {
+		return fmt.Errorf("serializing requirements, %w", err)
+	}
+	if err := c.patch(ctx, nodePool, func(np *karpv1.NodePool) {
+		np.Annotations[v1.AnnotationSurgeCapacityRequirements] = string(original)
+		np.Spec.Template.Spec.Requirements = relax(np.Spec.Template.Spec.Requirements)
+	}); err != nil {
+		return err
+	}
+	log.FromContext(ctx).WithValues("until", until.Format(time.RFC3339)).Info("relaxed nodepool requirements for surge capacity")
+	c.recorder.Publish(SurgeStartedEvent(nodePool, until))
+	return nil
+}
+
+func (c *Controller) endSurge(ctx context.Context, nodePool *karpv1.NodePool) error {
+	var original []karpv1.NodeSelectorRequirementWithMinValues
+	if err := json.Unmarshal([]byte(nodePool.Annotations[v1.AnnotationSurgeCapacityRequirements]), &original); err != nil {
+		return fmt.Errorf("parsing original requirements, %w", err)
+	}
+	if err := c.patch(ctx, nodePool, func(np *karpv1.NodePool) {
+		np.Spec.Template.Spec.Requirements = original
+		delete(np.Annotations, v1.AnnotationSurgeCapacityRequirements)
+		delete(np.Annotations, v1.AnnotationSurgeCapacityUntil)
+	}); err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("restored nodepool requirements after surge capacity")
+	c.recorder.Publish(SurgeEndedEvent(nodePool))
+	return nil
+}
+
+func (c *Controller) patch(ctx context.Context, nodePool *karpv1.NodePool, mutate func(*karpv1.NodePool)) error {
+	stored := nodePool.DeepCopy()
+	nodePool.Annotations = lo.Assign(nodePool.Annotations)
+	mutate(nodePool)
+	// The optimistic lock ensures that changes made to the requirements since they were read aren't overwritten
+	if err := c.kubeClient.Patch(ctx, nodePool, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return nil
+}
+
+// surgeUntil returns when the NodePool's surge capacity window ends, or the zero time if the NodePool isn't annotated
+// with a window
+func surgeUntil(nodePool *karpv1.NodePool, now time.Time) (time.Time, error) {
+	value, ok := nodePool.Annotations[v1.AnnotationSurgeCapacityUntil]
+	if !ok {
+		return time.Time{}, nil
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing %s, %w", v1.AnnotationSurgeCapacityUntil, err)
+	}
+	if until.Sub(now) > MaxWindow {
+		return time.Time{}, fmt.Errorf("%s ends more than %s from now", v1.AnnotationSurgeCapacityUntil, MaxWindow)
+	}
+	return until, nil
+}
+
+// relax removes the instance type and capacity type requirements, and allows every capacity type
+func relax(requirements []karpv1.NodeSelectorRequirementWithMinValues) []karpv1.NodeSelectorRequirementWithMinValues {
+	return append(lo.Reject(requirements, func(r karpv1.NodeSelectorRequirementWithMinValues, _ int) bool {
+		return relaxedKeys.Has(r.Key)
+	}), karpv1.NodeSelectorRequirementWithMinValues{
+		NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+			Key:      karpv1.CapacityTypeLabelKey,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{karpv1.CapacityTypeOnDemand, karpv1.CapacityTypeSpot, karpv1.CapacityTypeReserved},
+		},
+	})
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.surge").
+		For(&karpv1.NodePool{}, builder.WithPredicates(nodepoolutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
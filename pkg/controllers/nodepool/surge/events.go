@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package surge
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func SurgeStartedEvent(nodePool *karpv1.NodePool, until time.Time) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           corev1.EventTypeNormal,
+		Reason:         "SurgeCapacityStarted",
+		Message:        fmt.Sprintf("Relaxed instance type and capacity type requirements until %s", until.Format(time.RFC3339)),
+		DedupeValues:   []string{string(nodePool.UID), until.String()},
+	}
+}
+
+func SurgeEndedEvent(nodePool *karpv1.NodePool) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           corev1.EventTypeNormal,
+		Reason:         "SurgeCapacityEnded",
+		Message:        "Restored instance type and capacity type requirements, nodes which don't satisfy them will drift",
+		DedupeValues:   []string{string(nodePool.UID)},
+	}
+}
+
+func InvalidSurgeWindowEvent(nodePool *karpv1.NodePool, err error) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "InvalidSurgeCapacityWindow",
+		Message:        fmt.Sprintf("Ignoring surge capacity window, %s", err),
+		DedupeValues:   []string{string(nodePool.UID), err.Error()},
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package surge_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/surge"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var recorder *coretest.EventRecorder
+var controller *surge.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodePoolSurge")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	fakeClock = clock.NewFakeClock(time.Now())
+	recorder = coretest.NewEventRecorder()
+	controller = surge.NewController(fakeClock, env.Client, recorder, nil)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	fakeClock.SetTime(time.Now())
+	recorder.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("NodePoolSurge", func() {
+	var nodePool *karpv1.NodePool
+	var requirements []karpv1.NodeSelectorRequirementWithMinValues
+	BeforeEach(func() {
+		requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+			{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: karpv1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.CapacityTypeSpot}}},
+			{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: v1.LabelInstanceFamily, Operator: corev1.NodeSelectorOpIn, Values: []string{"m5"}}},
+			{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}}},
+		}
+		nodePool = coretest.NodePool(karpv1.NodePool{
+			Spec: karpv1.NodePoolSpec{
+				Template: karpv1.NodeClaimTemplate{
+					Spec: karpv1.NodeClaimTemplateSpec{Requirements: requirements},
+				},
+			},
+		})
+	})
+	It("should relax the requirements during the window", func() {
+		nodePool.Annotations = map[string]string{v1.AnnotationSurgeCapacityUntil: fakeClock.Now().Add(time.Hour).Format(time.RFC3339)}
+		ExpectApplied(ctx, env.Client, nodePool)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Second))
+
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.Spec.Template.Spec.Requirements).To(ConsistOf(
+			karpv1.NodeSelectorRequirementWithMinValues{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}}},
+			karpv1.NodeSelectorRequirementWithMinValues{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: karpv1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.CapacityTypeOnDemand, karpv1.CapacityTypeSpot, karpv1.CapacityTypeReserved}}},
+		))
+		var original []karpv1.NodeSelectorRequirementWithMinValues
+		Expect(json.Unmarshal([]byte(nodePool.Annotations[v1.AnnotationSurgeCapacityRequirements]), &original)).To(Succeed())
+		Expect(original).To(Equal(requirements))
+		Expect(recorder.Calls("SurgeCapacityStarted")).To(Equal(1))
+	})
+	It("should restore the requirements once the window ends", func() {
+		nodePool.Annotations = map[string]string{v1.AnnotationSurgeCapacityUntil: fakeClock.Now().Add(time.Hour).Format(time.RFC3339)}
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+
+		fakeClock.Step(time.Hour)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.Spec.Template.Spec.Requirements).To(Equal(requirements))
+		Expect(nodePool.Annotations).ToNot(HaveKey(v1.AnnotationSurgeCapacityUntil))
+		Expect(nodePool.Annotations).ToNot(HaveKey(v1.AnnotationSurgeCapacityRequirements))
+		Expect(recorder.Calls("SurgeCapacityEnded")).To(Equal(1))
+	})
+	It("should restore the requirements when the window is removed", func() {
+		nodePool.Annotations = map[string]string{v1.AnnotationSurgeCapacityUntil: fakeClock.Now().Add(time.Hour).Format(time.RFC3339)}
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		delete(nodePool.Annotations, v1.AnnotationSurgeCapacityUntil)
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.Spec.Template.Spec.Requirements).To(Equal(requirements))
+		Expect(nodePool.Annotations).ToNot(HaveKey(v1.AnnotationSurgeCapacityRequirements))
+	})
+	DescribeTable("should remove invalid windows without relaxing the requirements", func(until func() string) {
+		nodePool.Annotations = map[string]string{v1.AnnotationSurgeCapacityUntil: until()}
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.Spec.Template.Spec.Requirements).To(Equal(requirements))
+		Expect(nodePool.Annotations).ToNot(HaveKey(v1.AnnotationSurgeCapacityUntil))
+		Expect(recorder.Calls("SurgeCapacityStarted")).To(Equal(0))
+	},
+		Entry("unparseable", func() string { return "tomorrow" }),
+		Entry("longer than the maximum window", func() string { return fakeClock.Now().Add(surge.MaxWindow + time.Hour).Format(time.RFC3339) }),
+		Entry("already ended", func() string { return fakeClock.Now().Add(-time.Hour).Format(time.RFC3339) }),
+	)
+	It("should not modify nodepools without a window", func() {
+		ExpectApplied(ctx, env.Client, nodePool)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(result.RequeueAfter).To(BeZero())
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.Spec.Template.Spec.Requirements).To(Equal(requirements))
+		Expect(lo.Keys(nodePool.Annotations)).ToNot(ContainElement(v1.AnnotationSurgeCapacityRequirements))
+	})
+})
@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zone
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	nodepoolutils "sigs.k8s.io/karpenter/pkg/utils/nodepool"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
+)
+
+// Controller validates the zone requirements of NodePools against the zones which are enabled for the account. Invalid
+// requirements are reported as events rather than rejected, since the zones which are enabled can change after the
+// NodePool is created.
+type Controller struct {
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+	zoneProvider  zone.Provider
+}
+
+func NewController(recorder events.Recorder, cloudProvider cloudprovider.CloudProvider, zoneProvider zone.Provider) *Controller {
+	return &Controller{
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+		zoneProvider:  zoneProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodePool *karpv1.NodePool) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.zone")
+
+	zones := c.zoneProvider.List()
+	// The zones haven't been discovered yet, or can't be discovered
+	if len(zones) == 0 {
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+	unknown, disabled := invalidZones(requirements, zones)
+	if len(unknown) != 0 {
+		log.FromContext(ctx).WithValues("zones", unknown).Info("nodepool requires zones which don't exist in the region")
+		c.recorder.Publish(UnknownZonesEvent(nodePool, unknown))
+	}
+	if len(disabled) != 0 {
+		log.FromContext(ctx).WithValues("zones", disabled).Info("nodepool requires zones which aren't enabled for the account")
+		c.recorder.Publish(ZonesNotEnabledEvent(nodePool, disabled))
+	}
+	if !lo.ContainsBy(zones, func(z zone.Zone) bool {
+		return z.Enabled && requirements.Get(corev1.LabelTopologyZone).Has(z.Name) && requirements.Get(v1.LabelTopologyZoneID).Has(z.ID)
+	}) {
+		log.FromContext(ctx).Info("nodepool zone requirements can't be satisfied by any enabled zone")
+		c.recorder.Publish(NoEnabledZonesEvent(nodePool))
+	}
+	// Revalidate periodically since zones can be enabled or disabled for the account
+	return reconcile.Result{RequeueAfter: time.Hour}, nil
+}
+
+// invalidZones returns the zone names and IDs required by the requirements which either don't exist in the region or
+// aren't enabled for the account
+func invalidZones(requirements scheduling.Requirements, zones []zone.Zone) (unknown []string, disabled []string) {
+	for key, toValue := range map[string]func(zone.Zone) string{
+		corev1.LabelTopologyZone: func(z zone.Zone) string { return z.Name },
+		v1.LabelTopologyZoneID:   func(z zone.Zone) string { return z.ID },
+	} {
+		requirement := requirements.Get(key)
+		if requirement.Operator() != corev1.NodeSelectorOpIn {
+			continue
+		}
+		for _, value := range requirement.Values() {
+			z, ok := lo.Find(zones, func(z zone.Zone) bool { return toValue(z) == value })
+			switch {
+			case !ok:
+				unknown = append(unknown, value)
+			case !z.Enabled:
+				disabled = append(disabled, value)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	sort.Strings(disabled)
+	return unknown, disabled
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.zone").
+		For(&karpv1.NodePool{}, builder.WithPredicates(nodepoolutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
@@ -0,0 +1,57 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zone
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+func UnknownZonesEvent(nodePool *karpv1.NodePool, zones []string) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "UnknownZones",
+		Message:        fmt.Sprintf("Zone requirements reference zones which don't exist in the region, %s", utils.PrettySlice(zones, 5)),
+		DedupeValues:   []string{string(nodePool.UID), strings.Join(zones, ",")},
+	}
+}
+
+func ZonesNotEnabledEvent(nodePool *karpv1.NodePool, zones []string) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "ZonesNotEnabled",
+		Message:        fmt.Sprintf("Zone requirements reference opt-in zones which aren't enabled for the account, %s", utils.PrettySlice(zones, 5)),
+		DedupeValues:   []string{string(nodePool.UID), strings.Join(zones, ",")},
+	}
+}
+
+func NoEnabledZonesEvent(nodePool *karpv1.NodePool) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "NoEnabledZones",
+		Message:        "Zone requirements can't be satisfied by any zone enabled for the account, no nodes will be launched",
+		DedupeValues:   []string{string(nodePool.UID)},
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zone_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/zone"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	zoneprovider "github.com/aws/karpenter-provider-aws/pkg/providers/zone"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var ec2api *fake.EC2API
+var zoneProvider *zoneprovider.DefaultProvider
+var recorder *coretest.EventRecorder
+var controller *zone.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodePoolZone")
+}
+
+var _ = BeforeEach(func() {
+	ec2api = fake.NewEC2API()
+	ec2api.DescribeAvailabilityZonesOutput.Set(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []ec2types.AvailabilityZone{
+		{ZoneName: lo.ToPtr("test-zone-1a"), ZoneId: lo.ToPtr("tstz1-1a"), ZoneType: lo.ToPtr("availability-zone"), OptInStatus: ec2types.AvailabilityZoneOptInStatusOptInNotRequired},
+		{ZoneName: lo.ToPtr("test-zone-1b"), ZoneId: lo.ToPtr("tstz1-1b"), ZoneType: lo.ToPtr("availability-zone"), OptInStatus: ec2types.AvailabilityZoneOptInStatusOptInNotRequired},
+		{ZoneName: lo.ToPtr("test-zone-1a-local"), ZoneId: lo.ToPtr("tstz1-1alocal"), ZoneType: lo.ToPtr("local-zone"), OptInStatus: ec2types.AvailabilityZoneOptInStatusNotOptedIn},
+	}})
+	zoneProvider = zoneprovider.NewDefaultProvider(ec2api)
+	Expect(zoneProvider.UpdateZones(ctx)).To(Succeed())
+	recorder = coretest.NewEventRecorder()
+	controller = zone.NewController(recorder, nil, zoneProvider)
+})
+
+func nodePoolWithRequirements(requirements ...corev1.NodeSelectorRequirement) *karpv1.NodePool {
+	return coretest.NodePool(karpv1.NodePool{
+		Spec: karpv1.NodePoolSpec{
+			Template: karpv1.NodeClaimTemplate{
+				Spec: karpv1.NodeClaimTemplateSpec{
+					Requirements: lo.Map(requirements, func(r corev1.NodeSelectorRequirement, _ int) karpv1.NodeSelectorRequirementWithMinValues {
+						return karpv1.NodeSelectorRequirementWithMinValues{NodeSelectorRequirement: r}
+					}),
+				},
+			},
+		},
+	})
+}
+
+var _ = Describe("NodePoolZone", func() {
+	It("should not publish events for valid zone requirements", func() {
+		nodePool := nodePoolWithRequirements(
+			corev1.NodeSelectorRequirement{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"test-zone-1a"}},
+			corev1.NodeSelectorRequirement{Key: v1.LabelTopologyZoneID, Operator: corev1.NodeSelectorOpNotIn, Values: []string{"tstz1-1b"}},
+		)
+		_, err := controller.Reconcile(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Events()).To(BeEmpty())
+	})
+	It("should publish an event when a required zone doesn't exist", func() {
+		nodePool := nodePoolWithRequirements(
+			corev1.NodeSelectorRequirement{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"test-zone-1a", "test-zone-1d"}},
+		)
+		_, err := controller.Reconcile(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("UnknownZones")).To(Equal(1))
+		Expect(recorder.DetectedEvent("Zone requirements reference zones which don't exist in the region, test-zone-1d")).To(BeTrue())
+		Expect(recorder.Calls("NoEnabledZones")).To(Equal(0))
+	})
+	It("should publish an event when a required zone isn't enabled", func() {
+		nodePool := nodePoolWithRequirements(
+			corev1.NodeSelectorRequirement{Key: v1.LabelTopologyZoneID, Operator: corev1.NodeSelectorOpIn, Values: []string{"tstz1-1a", "tstz1-1alocal"}},
+		)
+		_, err := controller.Reconcile(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("ZonesNotEnabled")).To(Equal(1))
+		Expect(recorder.DetectedEvent("Zone requirements reference opt-in zones which aren't enabled for the account, tstz1-1alocal")).To(BeTrue())
+		Expect(recorder.Calls("NoEnabledZones")).To(Equal(0))
+	})
+	It("should publish an event when no enabled zone satisfies the requirements", func() {
+		nodePool := nodePoolWithRequirements(
+			corev1.NodeSelectorRequirement{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"test-zone-1a"}},
+			corev1.NodeSelectorRequirement{Key: v1.LabelTopologyZoneID, Operator: corev1.NodeSelectorOpIn, Values: []string{"tstz1-1b"}},
+		)
+		_, err := controller.Reconcile(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.Calls("UnknownZones")).To(Equal(0))
+		Expect(recorder.Calls("NoEnabledZones")).To(Equal(1))
+	})
+	It("should requeue without validating when the zones haven't been discovered", func() {
+		zoneProvider.Reset()
+		nodePool := nodePoolWithRequirements(
+			corev1.NodeSelectorRequirement{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"test-zone-1d"}},
+		)
+		result, err := controller.Reconcile(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(recorder.Events()).To(BeEmpty())
+	})
+})
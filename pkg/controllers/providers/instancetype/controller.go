@@ -17,46 +17,133 @@ package instancetype
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
 	lop "github.com/samber/lo/parallel"
 	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 )
 
+const (
+	refreshInterval = 12 * time.Hour
+	// MinTriggeredRefreshInterval is the minimum time between a successful refresh and one triggered by a signal that
+	// new instance types are available, so that a burst of signals results in a single refresh
+	MinTriggeredRefreshInterval = 5 * time.Minute
+)
+
 type Controller struct {
+	clk                  clock.Clock
 	instanceTypeProvider *instancetype.DefaultProvider
+	healthTracker        *health.Tracker
+	triggers             chan event.GenericEvent
+
+	mu sync.Mutex
+	// known are the instance types discovered by the last successful refresh
+	known sets.Set[string]
+	// attempted are the unknown instance types which have already triggered a refresh. They aren't retried until the
+	// next scheduled refresh, so that an instance type which is priced but not yet offered doesn't trigger one each
+	// time the prices are updated.
+	attempted   sets.Set[string]
+	triggered   bool
+	lastRefresh time.Time
 }
 
-func NewController(instanceTypeProvider *instancetype.DefaultProvider) *Controller {
-	return &Controller{
+func NewController(clk clock.Clock, instanceTypeProvider *instancetype.DefaultProvider, healthTracker *health.Tracker, invalidations *awscache.InvalidationBus) *Controller {
+	c := &Controller{
+		clk:                  clk,
 		instanceTypeProvider: instanceTypeProvider,
+		healthTracker:        healthTracker,
+		triggers:             make(chan event.GenericEvent, 1),
+		known:                sets.New[string](),
+		attempted:            sets.New[string](),
 	}
+	invalidations.Subscribe(c.invalidate)
+	return c
 }
 
 func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	ctx = injection.WithControllerName(ctx, "providers.instancetype")
 
+	c.mu.Lock()
+	if c.triggered {
+		if wait := MinTriggeredRefreshInterval - c.clk.Since(c.lastRefresh); wait > 0 {
+			c.mu.Unlock()
+			return reconcile.Result{RequeueAfter: wait}, nil
+		}
+	}
+	c.mu.Unlock()
+
+	subsystems := []string{health.SubsystemInstanceTypes, health.SubsystemInstanceTypeOfferings}
 	work := []func(ctx context.Context) error{
 		c.instanceTypeProvider.UpdateInstanceTypes,
 		c.instanceTypeProvider.UpdateInstanceTypeOfferings,
 	}
 	errs := make([]error, len(work))
 	lop.ForEach(work, func(f func(ctx context.Context) error, i int) {
-		if err := f(ctx); err != nil {
-			errs[i] = err
-		}
+		errs[i] = health.Guard(f)(ctx)
+		c.healthTracker.Observe(subsystems[i], errs[i])
 	})
 	if err := multierr.Combine(errs...); err != nil {
 		return reconcile.Result{}, fmt.Errorf("updating instancetype, %w", err)
 	}
-	return reconcile.Result{RequeueAfter: 12 * time.Hour}, nil
+
+	known := c.instanceTypeProvider.InstanceTypeNames()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if discovered := known.Difference(c.known); c.known.Len() != 0 && discovered.Len() != 0 {
+		log.FromContext(ctx).WithValues("instance-types", sets.List(discovered)).Info("discovered new instance types")
+	}
+	c.known = known
+	c.attempted = c.attempted.Difference(known)
+	// Unknown instance types are retried by each scheduled refresh
+	if !c.triggered {
+		c.attempted.Clear()
+	}
+	c.triggered = false
+	c.lastRefresh = c.clk.Now()
+	return reconcile.Result{RequeueAfter: refreshInterval}, nil
+}
+
+// invalidate triggers a refresh ahead of the polling interval when an invalidation references an instance type which
+// wasn't discovered by the last refresh, such as a new instance family that has been added to the pricing data, or
+// when a launch was rejected in a way that suggests an instance type's data is out of date.
+func (c *Controller) invalidate(invalidation awscache.Invalidation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Until the first refresh completes every instance type is unknown, and a refresh is already pending
+	if c.known.Len() == 0 {
+		return
+	}
+	unknown := lo.Filter(invalidation.InstanceTypes, func(instanceType string, _ int) bool {
+		return (invalidation.Rediscover || !c.known.Has(instanceType)) && !c.attempted.Has(instanceType)
+	})
+	if len(unknown) == 0 {
+		return
+	}
+	c.attempted.Insert(unknown...)
+	c.triggered = true
+	// Subscribers must not block, and a pending trigger already covers this one
+	select {
+	case c.triggers <- event.GenericEvent{}:
+	default:
+	}
 }
 
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
@@ -64,5 +151,10 @@ func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("providers.instancetype").
 		WatchesRawSource(singleton.Source()).
+		WatchesRawSource(source.Channel(c.triggers, handler.Funcs{
+			GenericFunc: func(_ context.Context, _ event.GenericEvent, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				queue.Add(reconcile.Request{})
+			},
+		})).
 		Complete(singleton.AsReconciler(c))
 }
@@ -16,11 +16,14 @@ package instancetype_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
 
 	corev1 "k8s.io/api/core/v1"
+	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
@@ -30,6 +33,7 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	controllersinstancetype "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
@@ -59,7 +63,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	ctx, stop = context.WithCancel(ctx)
 	awsEnv = test.NewEnvironment(ctx, env)
-	controller = controllersinstancetype.NewController(awsEnv.InstanceTypesProvider)
+	controller = controllersinstancetype.NewController(awsEnv.Clock, awsEnv.InstanceTypesProvider, awsEnv.HealthTracker, awsEnv.InvalidationBus)
 })
 
 var _ = AfterSuite(func() {
@@ -79,6 +83,16 @@ var _ = AfterEach(func() {
 })
 
 var _ = Describe("InstanceType", func() {
+	It("should report the subsystem as degraded while refreshing fails", func() {
+		awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+		// Instance types and offerings are refreshed concurrently, so either may have failed
+		Expect(awsEnv.HealthTracker.Degraded()).To(HaveLen(1))
+		Expect(awsEnv.HealthTracker.DegradedMessage()).To(ContainSubstring("failed"))
+
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.HealthTracker.Degraded()).To(BeEmpty())
+	})
 	It("should update instance type date with response from the DescribeInstanceTypes API", func() {
 		ec2InstanceTypes := fake.MakeInstances()
 		ec2Offerings := fake.MakeInstanceOfferings(ec2InstanceTypes)
@@ -113,6 +127,33 @@ var _ = Describe("InstanceType", func() {
 			Expect(instanceTypes[i].Name).To(Equal(string(ec2InstanceTypes[i].InstanceType)))
 		}
 	})
+	It("should list instance types in the order EC2 returns them when they're described for each architecture", func() {
+		// Reverse the instance types, so that the order EC2 returns them in isn't their name order
+		ec2InstanceTypes := lo.Reverse(fake.MakeInstances())
+		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{
+			InstanceTypes: ec2InstanceTypes,
+		})
+		awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+			InstanceTypeOfferings: fake.MakeInstanceOfferings(ec2InstanceTypes),
+		})
+
+		ExpectSingletonReconciled(ctx, controller)
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, &v1.EC2NodeClass{
+			Status: v1.EC2NodeClassStatus{
+				Subnets: []v1.Subnet{
+					{
+						ID:   "subnet-test1",
+						Zone: "test-zone-1a",
+					},
+				},
+			},
+		})
+		Expect(err).To(BeNil())
+		// The fake returns every instance type for each architecture, but each is only listed once
+		Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(Equal(
+			lo.Map(ec2InstanceTypes, func(info ec2types.InstanceTypeInfo, _ int) string { return string(info.InstanceType) }),
+		))
+	})
 	It("should update instance type offering date with response from the DescribeInstanceTypesOfferings API", func() {
 		ec2InstanceTypes := fake.MakeInstances()
 		ec2Offerings := fake.MakeInstanceOfferings(ec2InstanceTypes)
@@ -169,4 +210,40 @@ var _ = Describe("InstanceType", func() {
 		_, err := awsEnv.InstanceTypesProvider.List(ctx, &v1.EC2NodeClass{})
 		Expect(err).ToNot(BeNil())
 	})
+	Context("Triggered Refresh", func() {
+		var triggeredController *controllersinstancetype.Controller
+		BeforeEach(func() {
+			awsEnv.Clock.SetTime(time.Now())
+			triggeredController = controllersinstancetype.NewController(awsEnv.Clock, awsEnv.InstanceTypesProvider, awsEnv.HealthTracker, awsEnv.InvalidationBus)
+			Expect(ExpectSingletonReconciled(ctx, triggeredController).RequeueAfter).To(Equal(12 * time.Hour))
+		})
+		It("should not trigger a refresh for instance types which are already known", func() {
+			awsEnv.InvalidationBus.Publish(awscache.Invalidation{InstanceTypes: []string{"c6g.large"}})
+			Expect(ExpectSingletonReconciled(ctx, triggeredController).RequeueAfter).To(Equal(12 * time.Hour))
+		})
+		It("should refresh after the minimum interval when an unknown instance type is referenced", func() {
+			awsEnv.InvalidationBus.Publish(awscache.Invalidation{InstanceTypes: []string{"m99.large"}})
+			Expect(ExpectSingletonReconciled(ctx, triggeredController).RequeueAfter).To(Equal(controllersinstancetype.MinTriggeredRefreshInterval))
+
+			awsEnv.Clock.Step(controllersinstancetype.MinTriggeredRefreshInterval)
+			Expect(ExpectSingletonReconciled(ctx, triggeredController).RequeueAfter).To(Equal(12 * time.Hour))
+		})
+		It("should trigger a refresh for a known instance type which needs to be rediscovered", func() {
+			awsEnv.InvalidationBus.Publish(awscache.Invalidation{InstanceTypes: []string{"c6g.large"}, Rediscover: true})
+			Expect(ExpectSingletonReconciled(ctx, triggeredController).RequeueAfter).To(Equal(controllersinstancetype.MinTriggeredRefreshInterval))
+		})
+		It("should not trigger another refresh for an instance type which is still unknown", func() {
+			awsEnv.InvalidationBus.Publish(awscache.Invalidation{InstanceTypes: []string{"m99.large"}})
+			awsEnv.Clock.Step(controllersinstancetype.MinTriggeredRefreshInterval)
+			ExpectSingletonReconciled(ctx, triggeredController)
+
+			awsEnv.InvalidationBus.Publish(awscache.Invalidation{InstanceTypes: []string{"m99.large"}})
+			Expect(ExpectSingletonReconciled(ctx, triggeredController).RequeueAfter).To(Equal(12 * time.Hour))
+		})
+		It("should not trigger a refresh before instance types have been discovered", func() {
+			undiscoveredController := controllersinstancetype.NewController(awsEnv.Clock, awsEnv.InstanceTypesProvider, awsEnv.HealthTracker, awsEnv.InvalidationBus)
+			awsEnv.InvalidationBus.Publish(awscache.Invalidation{InstanceTypes: []string{"m99.large"}})
+			Expect(ExpectSingletonReconciled(ctx, undiscoveredController).RequeueAfter).To(Equal(12 * time.Hour))
+		})
+	})
 })
@@ -0,0 +1,68 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bulk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+)
+
+// Controller refreshes on-demand prices from the Price List bulk offer file, for regions where the pricing API isn't
+// available or when an offer file is configured
+type Controller struct {
+	pricingProvider pricing.Provider
+	// failures is the number of consecutive refreshes which have failed
+	failures int
+}
+
+func NewController(pricingProvider pricing.Provider) *Controller {
+	return &Controller{
+		pricingProvider: pricingProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.pricing.bulk")
+
+	interval := options.FromContext(ctx).BulkPricingRefreshInterval
+	if err := c.pricingProvider.UpdateBulkOnDemandPricing(ctx); err != nil {
+		if options.FromContext(ctx).PricingRetryBackoff == 0 || interval == 0 {
+			return reconcile.Result{}, fmt.Errorf("updating on-demand pricing from offer file, %w", err)
+		}
+		c.failures++
+		retryAfter := pricing.NextRefresh(ctx, interval, c.failures)
+		log.FromContext(ctx).WithValues("retry-after", retryAfter).Error(err, "failed updating on-demand pricing from offer file")
+		return reconcile.Result{RequeueAfter: retryAfter}, nil
+	}
+	c.failures = 0
+	return reconcile.Result{RequeueAfter: pricing.NextRefresh(ctx, interval, 0)}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.pricing.bulk").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -17,26 +17,37 @@ package pricing
 import (
 	"context"
 	"fmt"
-	"time"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
 	lop "github.com/samber/lo/parallel"
 	"go.uber.org/multierr"
 	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 )
 
 type Controller struct {
 	pricingProvider pricing.Provider
+	invalidations   *awscache.InvalidationBus
+	healthTracker   *health.Tracker
+	// failures is the number of consecutive refreshes which have failed
+	failures int
 }
 
-func NewController(pricingProvider pricing.Provider) *Controller {
+func NewController(pricingProvider pricing.Provider, invalidations *awscache.InvalidationBus, healthTracker *health.Tracker) *Controller {
 	return &Controller{
 		pricingProvider: pricingProvider,
+		invalidations:   invalidations,
+		healthTracker:   healthTracker,
 	}
 }
 
@@ -46,17 +57,35 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	work := []func(ctx context.Context) error{
 		c.pricingProvider.UpdateSpotPricing,
 		c.pricingProvider.UpdateOnDemandPricing,
+		c.pricingProvider.UpdateSavingsPlanPricing,
+		c.pricingProvider.UpdateVolumePricing,
+		c.pricingProvider.UpdateLicensePricing,
 	}
+	before := c.pricingProvider.Snapshot()
 	errs := make([]error, len(work))
 	lop.ForEach(work, func(f func(ctx context.Context) error, i int) {
-		if err := f(ctx); err != nil {
-			errs[i] = err
-		}
+		errs[i] = health.Guard(f)(ctx)
 	})
-	if err := multierr.Combine(errs...); err != nil {
-		return reconcile.Result{}, fmt.Errorf("updating pricing, %w", err)
+	// Some prices may have been updated even if others failed, so the offerings of any instance type whose price
+	// changed are invalidated regardless of errors
+	if changed := before.ChangedInstanceTypes(c.pricingProvider.Snapshot()); len(changed) != 0 {
+		c.invalidations.Publish(awscache.Invalidation{InstanceTypes: lo.Map(changed, func(it ec2types.InstanceType, _ int) string { return string(it) })})
+	}
+	interval := options.FromContext(ctx).PricingRefreshInterval
+	err := multierr.Combine(errs...)
+	c.healthTracker.Observe(health.SubsystemPricing, err)
+	if err != nil {
+		// Without a retry backoff, the refresh is retried with the controller's rate limiter
+		if options.FromContext(ctx).PricingRetryBackoff == 0 {
+			return reconcile.Result{}, fmt.Errorf("updating pricing, %w", err)
+		}
+		c.failures++
+		retryAfter := pricing.NextRefresh(ctx, interval, c.failures)
+		log.FromContext(ctx).WithValues("retry-after", retryAfter).Error(err, "failed updating pricing")
+		return reconcile.Result{RequeueAfter: retryAfter}, nil
 	}
-	return reconcile.Result{RequeueAfter: 12 * time.Hour}, nil
+	c.failures = 0
+	return reconcile.Result{RequeueAfter: pricing.NextRefresh(ctx, interval, 0)}, nil
 }
 
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
@@ -0,0 +1,77 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package overrides
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+)
+
+// Controller loads price overrides from a ConfigMap into the pricing provider. The ConfigMap is polled through an
+// uncached reader, rather than watched, so that Karpenter doesn't need permission to watch all ConfigMaps.
+type Controller struct {
+	kubeReader      client.Reader
+	pricingProvider pricing.Provider
+	configMap       types.NamespacedName
+}
+
+func NewController(kubeReader client.Reader, pricingProvider pricing.Provider, configMap types.NamespacedName) *Controller {
+	return &Controller{
+		kubeReader:      kubeReader,
+		pricingProvider: pricingProvider,
+		configMap:       configMap,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.pricing.overrides")
+
+	cm := &corev1.ConfigMap{}
+	if err := c.kubeReader.Get(ctx, c.configMap, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("getting price overrides configmap, %w", err)
+		}
+		// Deleting the ConfigMap removes the overrides
+		c.pricingProvider.SetPriceOverrides(ctx, nil)
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	// If the overrides are invalid, the previous overrides are retained until they're fixed
+	overrides, err := pricing.ParsePriceOverrides(cm.Data[pricing.PriceOverridesKey])
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("configmap %s, %w", c.configMap, err)
+	}
+	c.pricingProvider.SetPriceOverrides(ctx, overrides)
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.pricing.overrides").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
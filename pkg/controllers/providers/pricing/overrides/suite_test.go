@@ -0,0 +1,143 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package overrides_test
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing/overrides"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *overrides.Controller
+var configMap *corev1.ConfigMap
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PricingOverrides")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = overrides.NewController(env.Client, awsEnv.PricingProvider, types.NamespacedName{Namespace: "default", Name: "karpenter-price-overrides"})
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	configMap = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "karpenter-price-overrides"},
+		Data: map[string]string{
+			pricing.PriceOverridesKey: `
+- instanceType: m5.large
+  capacityType: on-demand
+  price: 0.01
+- instanceType: m5.large
+  capacityType: on-demand
+  zone: test-zone-1b
+  price: 0.02
+- instanceType: m5.large
+  capacityType: spot
+  zone: test-zone-1a
+  price: 0.005
+- instanceType: x99.large
+  capacityType: on-demand
+  price: 1.5
+`,
+		},
+	}
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+	ExpectDeleted(ctx, env.Client, configMap)
+})
+
+var _ = Describe("PricingOverrides", func() {
+	It("should override on-demand and spot prices", func() {
+		ExpectApplied(ctx, env.Client, configMap)
+		ExpectSingletonReconciled(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.OnDemandPrice("m5.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.01))
+		price, ok = awsEnv.PricingProvider.ZonalOnDemandPrice("m5.large", "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.01))
+		price, ok = awsEnv.PricingProvider.ZonalOnDemandPrice("m5.large", "test-zone-1b")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.02))
+		price, ok = awsEnv.PricingProvider.SpotPrice("m5.large", "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.005))
+	})
+	It("should price instance types without a known price", func() {
+		_, ok := awsEnv.PricingProvider.OnDemandPrice("x99.large")
+		Expect(ok).To(BeFalse())
+		ExpectApplied(ctx, env.Client, configMap)
+		ExpectSingletonReconciled(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.OnDemandPrice("x99.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.5))
+		Expect(awsEnv.PricingProvider.InstanceTypes()).To(ContainElement(BeEquivalentTo("x99.large")))
+	})
+	It("should remove overrides when the configmap is deleted", func() {
+		ExpectApplied(ctx, env.Client, configMap)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectDeleted(ctx, env.Client, configMap)
+		ExpectSingletonReconciled(ctx, controller)
+
+		_, ok := awsEnv.PricingProvider.OnDemandPrice("x99.large")
+		Expect(ok).To(BeFalse())
+	})
+	It("should retain the previous overrides when the configmap is invalid", func() {
+		ExpectApplied(ctx, env.Client, configMap)
+		ExpectSingletonReconciled(ctx, controller)
+		configMap.Data[pricing.PriceOverridesKey] = `[{"instanceType": "m5.large", "capacityType": "reserved", "price": 0.01}]`
+		ExpectApplied(ctx, env.Client, configMap)
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.OnDemandPrice("x99.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.5))
+	})
+})
@@ -0,0 +1,158 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+)
+
+// Controller persists the prices retrieved by the pricing provider to a ConfigMap, and loads them from the ConfigMap
+// on startup. The ConfigMap is read through an uncached reader, rather than watched, so that Karpenter doesn't need
+// permission to watch all ConfigMaps.
+type Controller struct {
+	kubeClient      client.Client
+	kubeReader      client.Reader
+	clk             clock.Clock
+	pricingProvider pricing.Provider
+	configMap       types.NamespacedName
+
+	loaded bool
+	// written is the hash of the last snapshot that was written, so that the ConfigMap is only updated when prices change
+	written uint64
+}
+
+func NewController(kubeClient client.Client, kubeReader client.Reader, clk clock.Clock, pricingProvider pricing.Provider, configMap types.NamespacedName) *Controller {
+	return &Controller{
+		kubeClient:      kubeClient,
+		kubeReader:      kubeReader,
+		clk:             clk,
+		pricingProvider: pricingProvider,
+		configMap:       configMap,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.pricing.snapshot")
+
+	if !c.loaded {
+		cm, err := c.get(ctx)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		c.load(ctx, cm)
+		c.loaded = true
+	}
+	snapshot := c.pricingProvider.Snapshot()
+	hash := lo.Must(hashstructure.Hash(snapshot, hashstructure.FormatV2, nil))
+	if snapshot.IsEmpty() || hash == c.written {
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	cm, err := c.get(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := c.write(ctx, cm, snapshot); err != nil {
+		return reconcile.Result{}, err
+	}
+	c.written = hash
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+// get returns the snapshot ConfigMap, or nil if it doesn't exist
+func (c *Controller) get(ctx context.Context) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.kubeReader.Get(ctx, c.configMap, cm); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting pricing snapshot configmap, %w", err)
+	}
+	return cm, nil
+}
+
+// load loads the snapshot in the ConfigMap into the pricing provider. A snapshot which can't be parsed is ignored,
+// and replaced once prices are retrieved.
+func (c *Controller) load(ctx context.Context, cm *corev1.ConfigMap) {
+	if cm == nil || cm.Data[pricing.SnapshotKey] == "" {
+		return
+	}
+	snapshot := pricing.Snapshot{}
+	if err := json.Unmarshal([]byte(cm.Data[pricing.SnapshotKey]), &snapshot); err != nil {
+		log.FromContext(ctx).Error(err, "failed parsing pricing snapshot", "configmap", c.configMap)
+		return
+	}
+	if ts, err := time.Parse(time.RFC3339, cm.Annotations[v1.AnnotationPricingSnapshotTimestamp]); err == nil {
+		log.FromContext(ctx).WithValues("configmap", c.configMap, "age", c.clk.Since(ts).Truncate(time.Second)).Info("loading pricing snapshot")
+	}
+	c.pricingProvider.LoadSnapshot(ctx, snapshot)
+	// Prices loaded from the snapshot don't need to be written back until they're updated
+	c.written = lo.Must(hashstructure.Hash(c.pricingProvider.Snapshot(), hashstructure.FormatV2, nil))
+}
+
+func (c *Controller) write(ctx context.Context, cm *corev1.ConfigMap, snapshot pricing.Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshaling pricing snapshot, %w", err)
+	}
+	annotations := map[string]string{v1.AnnotationPricingSnapshotTimestamp: c.clk.Now().UTC().Format(time.RFC3339)}
+	if cm == nil {
+		if err := c.kubeClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   c.configMap.Namespace,
+				Name:        c.configMap.Name,
+				Annotations: annotations,
+			},
+			Data: map[string]string{pricing.SnapshotKey: string(data)},
+		}); err != nil {
+			return fmt.Errorf("creating pricing snapshot configmap, %w", err)
+		}
+		return nil
+	}
+	stored := cm.DeepCopy()
+	cm.Annotations = lo.Assign(cm.Annotations, annotations)
+	cm.Data = lo.Assign(cm.Data, map[string]string{pricing.SnapshotKey: string(data)})
+	if err := c.kubeClient.Patch(ctx, cm, client.MergeFrom(stored)); err != nil {
+		return fmt.Errorf("patching pricing snapshot configmap, %w", err)
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.pricing.snapshot").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,186 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clock "k8s.io/utils/clock/testing"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing/snapshot"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var fakeClock *clock.FakeClock
+var controller *snapshot.Controller
+
+var configMapKey = types.NamespacedName{Namespace: "default", Name: "karpenter-pricing-snapshot"}
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PricingSnapshot")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	// The controller only loads the snapshot on its first reconcile, so it's recreated to simulate a restart
+	controller = snapshot.NewController(env.Client, env.Client, fakeClock, awsEnv.PricingProvider, configMapKey)
+	awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
+		SpotPriceHistory: []ec2types.SpotPrice{{
+			AvailabilityZone: lo.ToPtr("test-zone-1a"),
+			InstanceType:     "m5.large",
+			SpotPrice:        lo.ToPtr("0.042"),
+			Timestamp:        lo.ToPtr(time.Now()),
+		}},
+	})
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+	cm := &corev1.ConfigMap{}
+	if err := env.Client.Get(ctx, configMapKey, cm); err == nil {
+		ExpectDeleted(ctx, env.Client, cm)
+	}
+})
+
+func snapshotConfigMap(s pricing.Snapshot) *corev1.ConfigMap {
+	data, err := json.Marshal(s)
+	Expect(err).ToNot(HaveOccurred())
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   configMapKey.Namespace,
+			Name:        configMapKey.Name,
+			Annotations: map[string]string{v1.AnnotationPricingSnapshotTimestamp: fakeClock.Now().Add(-time.Hour).UTC().Format(time.RFC3339)},
+		},
+		Data: map[string]string{pricing.SnapshotKey: string(data)},
+	}
+}
+
+var _ = Describe("PricingSnapshot", func() {
+	It("should not write a snapshot until prices are retrieved", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		err := env.Client.Get(ctx, configMapKey, &corev1.ConfigMap{})
+		Expect(err).To(HaveOccurred())
+	})
+	It("should write a snapshot once prices are retrieved", func() {
+		Expect(awsEnv.PricingProvider.UpdateSpotPricing(ctx)).To(Succeed())
+		ExpectSingletonReconciled(ctx, controller)
+
+		cm := &corev1.ConfigMap{}
+		Expect(env.Client.Get(ctx, configMapKey, cm)).To(Succeed())
+		Expect(cm.Annotations).To(HaveKeyWithValue(v1.AnnotationPricingSnapshotTimestamp, fakeClock.Now().UTC().Format(time.RFC3339)))
+		s := pricing.Snapshot{}
+		Expect(json.Unmarshal([]byte(cm.Data[pricing.SnapshotKey]), &s)).To(Succeed())
+		Expect(s.Region).To(Equal(fake.DefaultRegion))
+		Expect(s.OnDemand).To(BeEmpty())
+		Expect(s.Spot).To(HaveKeyWithValue(ec2types.InstanceTypeM5Large, HaveKeyWithValue("test-zone-1a", 0.042)))
+	})
+	It("should load the snapshot on startup", func() {
+		ExpectApplied(ctx, env.Client, snapshotConfigMap(pricing.Snapshot{
+			Region:   fake.DefaultRegion,
+			OnDemand: map[ec2types.InstanceType]float64{ec2types.InstanceTypeM5Large: 1.23},
+			Spot:     map[ec2types.InstanceType]map[string]float64{ec2types.InstanceTypeM5Large: {"test-zone-1b": 0.5}},
+		}))
+		ExpectSingletonReconciled(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.OnDemandPrice(ec2types.InstanceTypeM5Large)
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 1.23))
+		price, ok = awsEnv.PricingProvider.SpotPrice(ec2types.InstanceTypeM5Large, "test-zone-1b")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.5))
+
+		// The loaded prices aren't written back until they're updated
+		cm := &corev1.ConfigMap{}
+		Expect(env.Client.Get(ctx, configMapKey, cm)).To(Succeed())
+		Expect(cm.Annotations).To(HaveKeyWithValue(v1.AnnotationPricingSnapshotTimestamp, fakeClock.Now().Add(-time.Hour).UTC().Format(time.RFC3339)))
+	})
+	It("should not replace prices retrieved since startup", func() {
+		Expect(awsEnv.PricingProvider.UpdateSpotPricing(ctx)).To(Succeed())
+		ExpectApplied(ctx, env.Client, snapshotConfigMap(pricing.Snapshot{
+			Region: fake.DefaultRegion,
+			Spot:   map[ec2types.InstanceType]map[string]float64{ec2types.InstanceTypeM5Large: {"test-zone-1a": 0.5}},
+		}))
+		ExpectSingletonReconciled(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.SpotPrice(ec2types.InstanceTypeM5Large, "test-zone-1a")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", 0.042))
+	})
+	It("should ignore a snapshot from another region", func() {
+		ExpectApplied(ctx, env.Client, snapshotConfigMap(pricing.Snapshot{
+			Region:   "eu-west-1",
+			OnDemand: map[ec2types.InstanceType]float64{ec2types.InstanceTypeM5Large: 1.23},
+		}))
+		ExpectSingletonReconciled(ctx, controller)
+
+		price, ok := awsEnv.PricingProvider.OnDemandPrice(ec2types.InstanceTypeM5Large)
+		Expect(ok).To(BeTrue())
+		Expect(price).ToNot(BeNumerically("==", 1.23))
+	})
+	It("should update the snapshot when prices change", func() {
+		ExpectApplied(ctx, env.Client, snapshotConfigMap(pricing.Snapshot{
+			Region: fake.DefaultRegion,
+			Spot:   map[ec2types.InstanceType]map[string]float64{ec2types.InstanceTypeM5Large: {"test-zone-1a": 0.5}},
+		}))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.PricingProvider.UpdateSpotPricing(ctx)).To(Succeed())
+		ExpectSingletonReconciled(ctx, controller)
+
+		cm := &corev1.ConfigMap{}
+		Expect(env.Client.Get(ctx, configMapKey, cm)).To(Succeed())
+		Expect(cm.Annotations).To(HaveKeyWithValue(v1.AnnotationPricingSnapshotTimestamp, fakeClock.Now().UTC().Format(time.RFC3339)))
+		Expect(cm.Data[pricing.SnapshotKey]).To(ContainSubstring(fmt.Sprint(0.042)))
+	})
+})
@@ -17,6 +17,8 @@ package pricing_test
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -26,12 +28,16 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	awspricing "github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/savingsplans"
+	savingsplanstypes "github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
 	"github.com/samber/lo"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	controllerspricing "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing"
+	controllerspricingbulk "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing/bulk"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
@@ -61,7 +67,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	ctx, stop = context.WithCancel(ctx)
 	awsEnv = test.NewEnvironment(ctx, env)
-	controller = controllerspricing.NewController(awsEnv.PricingProvider)
+	controller = controllerspricing.NewController(awsEnv.PricingProvider, awsEnv.InvalidationBus, awsEnv.HealthTracker)
 })
 
 var _ = AfterSuite(func() {
@@ -100,7 +106,7 @@ var _ = Describe("Pricing", func() {
 			"should return correct static data for all partitions",
 			func(staticPricing map[string]map[ec2types.InstanceType]float64) {
 				for region, prices := range staticPricing {
-					provider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, region)
+					provider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, awsEnv.SavingsPlansAPI, awsEnv.ZoneProvider, region)
 					for instance, price := range prices {
 						val, ok := provider.OnDemandPrice(instance)
 						Expect(ok).To(BeTrue())
@@ -166,8 +172,8 @@ var _ = Describe("Pricing", func() {
 				To(ContainElements("Linux/UNIX", "Linux/UNIX (Amazon VPC)"))
 		})
 		It("should update on-demand pricing with response from the pricing API when in the CN partition", func() {
-			tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, "cn-anywhere-1")
-			tmpController := controllerspricing.NewController(tmpPricingProvider)
+			tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, awsEnv.SavingsPlansAPI, awsEnv.ZoneProvider, "cn-anywhere-1")
+			tmpController := controllerspricing.NewController(tmpPricingProvider, nil, nil)
 
 			now := time.Now()
 			awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
@@ -254,6 +260,135 @@ var _ = Describe("Pricing", func() {
 			}
 		})
 	})
+	Context("Local Zones", func() {
+		BeforeEach(func() {
+			now := time.Now()
+			awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []ec2types.SpotPrice{
+					{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "c99.large",
+						SpotPrice:        aws.String("1.23"),
+						Timestamp:        &now,
+					},
+				},
+			})
+			awsEnv.EC2API.DescribeAvailabilityZonesOutput.Set(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []ec2types.AvailabilityZone{
+				{ZoneName: aws.String("test-zone-1a"), GroupName: aws.String("test-zone-1"), ZoneType: aws.String("availability-zone")},
+				{ZoneName: aws.String("test-zone-1-lcl-1a"), GroupName: aws.String("test-zone-1-lcl-1"), ZoneType: aws.String("local-zone"), OptInStatus: ec2types.AvailabilityZoneOptInStatusOptedIn},
+				{ZoneName: aws.String("test-zone-1-lcl-2a"), GroupName: aws.String("test-zone-1-lcl-2"), ZoneType: aws.String("local-zone"), OptInStatus: ec2types.AvailabilityZoneOptInStatusNotOptedIn},
+			}})
+			awsEnv.PricingAPI.GetProductsBehavior.Output.Set(&awspricing.GetProductsOutput{
+				PriceList: []string{
+					fake.NewOnDemandPrice("c98.large", 1.20),
+					fake.NewOnDemandPrice("c99.large", 1.23),
+				},
+			})
+			awsEnv.PricingAPI.RegionCodeOutputs.Store("test-zone-1-lcl-1", &awspricing.GetProductsOutput{
+				PriceList: []string{
+					fake.NewOnDemandPrice("c99.large", 1.50),
+				},
+			})
+			Expect(awsEnv.ZoneProvider.UpdateZones(ctx)).To(Succeed())
+		})
+		It("should price instance types in a local zone at the local zone's price", func() {
+			ExpectSingletonReconciled(ctx, controller)
+
+			price, ok := awsEnv.PricingProvider.ZonalOnDemandPrice("c99.large", "test-zone-1-lcl-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.50))
+			price, ok = awsEnv.PricingProvider.ZonalOnDemandPrice("c99.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.23))
+		})
+		It("should fall back to the regional price for instance types without a local zone price", func() {
+			ExpectSingletonReconciled(ctx, controller)
+
+			price, ok := awsEnv.PricingProvider.ZonalOnDemandPrice("c98.large", "test-zone-1-lcl-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.20))
+		})
+		It("should only retrieve prices for local zones that are enabled", func() {
+			ExpectSingletonReconciled(ctx, controller)
+
+			// Shared tenancy and bare metal prices for the region, and shared tenancy prices for the enabled local zone
+			Expect(awsEnv.PricingAPI.GetProductsBehavior.CalledWithInput.Len()).To(Equal(3))
+			awsEnv.PricingAPI.GetProductsBehavior.CalledWithInput.ForEach(func(input *awspricing.GetProductsInput) {
+				Expect(input.Filters).ToNot(ContainElement(HaveField("Value", HaveValue(Equal("test-zone-1-lcl-2")))))
+			})
+			price, ok := awsEnv.PricingProvider.ZonalOnDemandPrice("c99.large", "test-zone-1-lcl-2a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.23))
+		})
+		It("should update the regional prices when a local zone's prices can't be retrieved", func() {
+			awsEnv.PricingAPI.RegionCodeOutputs.Store("test-zone-1-lcl-1", fmt.Errorf("failed"))
+			ExpectSingletonReconciled(ctx, controller)
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c99.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.23))
+			price, ok = awsEnv.PricingProvider.ZonalOnDemandPrice("c99.large", "test-zone-1-lcl-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.23))
+		})
+		It("should not describe availability zones to find local zones", func() {
+			awsEnv.EC2API.DescribeAvailabilityZonesOutput.Reset()
+			ExpectSingletonReconciled(ctx, controller)
+
+			price, ok := awsEnv.PricingProvider.ZonalOnDemandPrice("c99.large", "test-zone-1-lcl-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.50))
+		})
+	})
+	Context("Volumes", func() {
+		BeforeEach(func() {
+			awsEnv.PricingAPI.GetProductsBehavior.Output.Set(&awspricing.GetProductsOutput{
+				PriceList: []string{
+					fake.NewVolumePrice("Storage", "gp3", "", 0.0952),
+					fake.NewVolumePrice("System Operation", "gp3", "EBS IOPS", 0.006),
+					fake.NewVolumePrice("Provisioned Throughput", "gp3", "", 0.048),
+					fake.NewVolumePrice("System Operation", "io2", "EBS IOPS", 0.0745),
+					fake.NewVolumePrice("System Operation", "io2", "EBS IOPS", 0.052),
+					fake.NewVolumePrice("System Operation", "standard", "EBS I/O Requests", 0.06),
+				},
+			})
+		})
+		It("should use the static volume prices until prices are retrieved", func() {
+			price, ok := awsEnv.PricingProvider.VolumePrice(ec2types.VolumeTypeGp3)
+			Expect(ok).To(BeTrue())
+			Expect(price.GiBMonth).To(BeNumerically("==", 0.08))
+			Expect(price.BaselineIOPS).To(BeNumerically("==", 3000))
+		})
+		It("should not retrieve volume prices unless volume costs are included in offering prices", func() {
+			Expect(awsEnv.PricingProvider.UpdateVolumePricing(ctx)).To(Succeed())
+			Expect(awsEnv.PricingAPI.GetProductsBehavior.Calls()).To(Equal(0))
+		})
+		It("should update volume prices", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IncludeVolumeCost: lo.ToPtr(true)}))
+			Expect(awsEnv.PricingProvider.UpdateVolumePricing(ctx)).To(Succeed())
+
+			price, ok := awsEnv.PricingProvider.VolumePrice(ec2types.VolumeTypeGp3)
+			Expect(ok).To(BeTrue())
+			Expect(price.GiBMonth).To(BeNumerically("==", 0.0952))
+			Expect(price.IOPSMonth).To(BeNumerically("==", 0.006))
+			Expect(price.ThroughputMonth).To(BeNumerically("==", 0.048))
+			Expect(price.BaselineThroughput).To(BeNumerically("==", 125))
+			// io2 IOPS are priced at the first, most expensive, tier
+			price, ok = awsEnv.PricingProvider.VolumePrice(ec2types.VolumeTypeIo2)
+			Expect(ok).To(BeTrue())
+			Expect(price.IOPSMonth).To(BeNumerically("==", 0.0745))
+			// I/O requests to magnetic volumes aren't provisioned
+			price, ok = awsEnv.PricingProvider.VolumePrice(ec2types.VolumeTypeStandard)
+			Expect(ok).To(BeTrue())
+			Expect(price.IOPSMonth).To(BeZero())
+		})
+		It("should calculate the hourly cost of a volume", func() {
+			price, ok := awsEnv.PricingProvider.VolumePrice(ec2types.VolumeTypeGp3)
+			Expect(ok).To(BeTrue())
+			Expect(price.HourlyCost(100, 3000, 125)).To(BeNumerically("~", 100*0.08/pricing.HoursPerMonth))
+			Expect(price.HourlyCost(100, 6000, 250)).To(BeNumerically("~", (100*0.08+3000*0.005+125*0.04)/pricing.HoursPerMonth))
+		})
+	})
 	Context("Spot", func() {
 		BeforeEach(func() {
 			// Preventing errors from UpdateOnDemandPricing
@@ -526,4 +661,553 @@ var _ = Describe("Pricing", func() {
 			}
 		})
 	})
+	Context("Savings Plans", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SavingsPlansPricing: lo.ToPtr(true)}))
+			now := time.Now()
+			awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []ec2types.SpotPrice{
+					{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "c99.large",
+						SpotPrice:        aws.String("1.23"),
+						Timestamp:        &now,
+					},
+				},
+			})
+			awsEnv.PricingAPI.GetProductsBehavior.Output.Set(&awspricing.GetProductsOutput{
+				PriceList: []string{
+					fake.NewOnDemandPrice("c98.large", 1.20),
+					fake.NewOnDemandPrice("c99.large", 1.23),
+				},
+			})
+			awsEnv.SavingsPlansAPI.DescribeSavingsPlansBehavior.Output.Set(&savingsplans.DescribeSavingsPlansOutput{
+				SavingsPlans: []savingsplanstypes.SavingsPlan{
+					{SavingsPlanId: aws.String("sp-compute"), SavingsPlanType: savingsplanstypes.SavingsPlanTypeCompute, State: savingsplanstypes.SavingsPlanStateActive},
+				},
+			})
+			awsEnv.SavingsPlansAPI.DescribeSavingsPlanRatesBehavior.Output.Set(&savingsplans.DescribeSavingsPlanRatesOutput{
+				SearchResults: []savingsplanstypes.SavingsPlanRate{
+					fake.NewSavingsPlanRate("c98.large", fake.DefaultRegion, 0.80),
+				},
+			})
+		})
+		It("should price covered instance types at the savings plan rate", func() {
+			ExpectSingletonReconciled(ctx, controller)
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.80))
+
+			price, ok = awsEnv.PricingProvider.OnDemandPrice("c99.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.23))
+
+			inp := awsEnv.SavingsPlansAPI.DescribeSavingsPlanRatesBehavior.CalledWithInput.Pop()
+			Expect(aws.ToString(inp.SavingsPlanId)).To(Equal("sp-compute"))
+			Expect(inp.Filters).To(ContainElement(savingsplanstypes.SavingsPlanRateFilter{Name: savingsplanstypes.SavingsPlanRateFilterNameRegion, Values: []string{fake.DefaultRegion}}))
+		})
+		It("should use the lowest rate when multiple savings plans cover an instance type", func() {
+			awsEnv.SavingsPlansAPI.DescribeSavingsPlansBehavior.Output.Set(&savingsplans.DescribeSavingsPlansOutput{
+				SavingsPlans: []savingsplanstypes.SavingsPlan{
+					{SavingsPlanId: aws.String("sp-compute"), SavingsPlanType: savingsplanstypes.SavingsPlanTypeCompute, State: savingsplanstypes.SavingsPlanStateActive},
+					{SavingsPlanId: aws.String("sp-instance"), SavingsPlanType: savingsplanstypes.SavingsPlanTypeEc2Instance, State: savingsplanstypes.SavingsPlanStateActive, Region: aws.String(fake.DefaultRegion)},
+				},
+			})
+			awsEnv.SavingsPlansAPI.DescribeSavingsPlanRatesBehavior.Output.Reset()
+			awsEnv.SavingsPlansAPI.DescribeSavingsPlanRatesBehavior.OutputPages.Add(&savingsplans.DescribeSavingsPlanRatesOutput{
+				SearchResults: []savingsplanstypes.SavingsPlanRate{fake.NewSavingsPlanRate("c98.large", fake.DefaultRegion, 0.80)},
+			})
+			awsEnv.SavingsPlansAPI.DescribeSavingsPlanRatesBehavior.OutputPages.Add(&savingsplans.DescribeSavingsPlanRatesOutput{
+				SearchResults: []savingsplanstypes.SavingsPlanRate{fake.NewSavingsPlanRate("c98.large", fake.DefaultRegion, 0.70)},
+			})
+			ExpectSingletonReconciled(ctx, controller)
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 0.70))
+		})
+		It("should ignore EC2 Instance Savings Plans in other regions", func() {
+			awsEnv.SavingsPlansAPI.DescribeSavingsPlansBehavior.Output.Set(&savingsplans.DescribeSavingsPlansOutput{
+				SavingsPlans: []savingsplanstypes.SavingsPlan{
+					{SavingsPlanId: aws.String("sp-instance"), SavingsPlanType: savingsplanstypes.SavingsPlanTypeEc2Instance, State: savingsplanstypes.SavingsPlanStateActive, Region: aws.String("eu-west-1")},
+				},
+			})
+			ExpectSingletonReconciled(ctx, controller)
+
+			Expect(awsEnv.SavingsPlansAPI.DescribeSavingsPlanRatesBehavior.CalledWithInput.Len()).To(Equal(0))
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.20))
+		})
+		It("should not query savings plans when savings plans pricing is disabled", func() {
+			ctx = options.ToContext(ctx, test.Options())
+			ExpectSingletonReconciled(ctx, controller)
+
+			Expect(awsEnv.SavingsPlansAPI.DescribeSavingsPlansBehavior.CalledWithInput.Len()).To(Equal(0))
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.20))
+		})
+	})
+	Context("Spot Price Percentile", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{SpotPricePercentile: lo.ToPtr(90)}))
+			awsEnv.PricingAPI.GetProductsBehavior.Output.Set(&awspricing.GetProductsOutput{
+				PriceList: []string{
+					fake.NewOnDemandPrice("c98.large", 1.20),
+					fake.NewOnDemandPrice("c99.large", 1.23),
+				},
+			})
+		})
+		It("should request the spot price history over the window", func() {
+			now := time.Now()
+			awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []ec2types.SpotPrice{
+					{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "c99.large",
+						SpotPrice:        aws.String("1.23"),
+						Timestamp:        &now,
+					},
+				},
+			})
+			ExpectSingletonReconciled(ctx, controller)
+			input := awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.CalledWithInput.Pop()
+			Expect(input.EndTime).ToNot(BeNil())
+			Expect(input.EndTime.Sub(lo.FromPtr(input.StartTime))).To(Equal(pricing.SpotPriceHistoryWindow))
+		})
+		It("should price spot offerings at the percentile of their price, weighted by how long each price was in effect", func() {
+			now := time.Now()
+			awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []ec2types.SpotPrice{
+					// c99.large spiked to 2.00 for a day, which is more than 10% of the window
+					{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "c99.large",
+						SpotPrice:        aws.String("1.00"),
+						Timestamp:        aws.Time(now.Add(-8 * 24 * time.Hour)),
+					},
+					{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "c99.large",
+						SpotPrice:        aws.String("2.00"),
+						Timestamp:        aws.Time(now.Add(-3 * 24 * time.Hour)),
+					},
+					{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "c99.large",
+						SpotPrice:        aws.String("1.00"),
+						Timestamp:        aws.Time(now.Add(-2 * 24 * time.Hour)),
+					},
+					// c98.large spiked to 2.00 for an hour, which is less than 10% of the window
+					{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "c98.large",
+						SpotPrice:        aws.String("1.10"),
+						Timestamp:        aws.Time(now.Add(-8 * 24 * time.Hour)),
+					},
+					{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "c98.large",
+						SpotPrice:        aws.String("2.00"),
+						Timestamp:        aws.Time(now.Add(-3 * time.Hour)),
+					},
+					{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "c98.large",
+						SpotPrice:        aws.String("1.10"),
+						Timestamp:        aws.Time(now.Add(-2 * time.Hour)),
+					},
+				},
+			})
+			ExpectSingletonReconciled(ctx, controller)
+
+			price, ok := awsEnv.PricingProvider.SpotPrice("c99.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 2.00))
+
+			price, ok = awsEnv.PricingProvider.SpotPrice("c98.large", "test-zone-1a")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.10))
+		})
+	})
+	Context("Bulk Price List", func() {
+		var server *httptest.Server
+		var bulkController *controllerspricingbulk.Controller
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{
+	"formatVersion": "v1.0",
+	"products": {
+		"SKU1": {"productFamily": "Compute Instance", "attributes": {"instanceType": "c98.large", "regionCode": %[1]q, "tenancy": "Shared", "operatingSystem": "Linux", "preInstalledSw": "NA", "capacitystatus": "Used", "marketoption": "OnDemand"}},
+		"SKU2": {"productFamily": "Compute Instance", "attributes": {"instanceType": "c98.large", "regionCode": %[1]q, "tenancy": "Shared", "operatingSystem": "Windows", "preInstalledSw": "NA", "capacitystatus": "Used", "marketoption": "OnDemand"}},
+		"SKU3": {"productFamily": "Compute Instance (bare metal)", "attributes": {"instanceType": "c98.metal", "regionCode": %[1]q, "tenancy": "Dedicated", "operatingSystem": "Linux", "preInstalledSw": "NA", "capacitystatus": "Used", "marketoption": "OnDemand"}}
+	},
+	"terms": {
+		"Reserved": {"SKU1": {"SKU1.RI": {"priceDimensions": {"SKU1.RI.1": {"pricePerUnit": {"USD": "0.5"}}}}}},
+		"OnDemand": {
+			"SKU1": {"SKU1.OD": {"priceDimensions": {"SKU1.OD.1": {"unit": "Hrs", "pricePerUnit": {"USD": "1.2300000000"}}}}},
+			"SKU2": {"SKU2.OD": {"priceDimensions": {"SKU2.OD.1": {"unit": "Hrs", "pricePerUnit": {"USD": "4.5600000000"}}}}},
+			"SKU3": {"SKU3.OD": {"priceDimensions": {"SKU3.OD.1": {"unit": "Hrs", "pricePerUnit": {"USD": "7.8900000000"}}}}}
+		}
+	}
+}`, fake.DefaultRegion)
+			}))
+			DeferCleanup(server.Close)
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{BulkPricingURL: lo.ToPtr(server.URL)}))
+			bulkController = controllerspricingbulk.NewController(awsEnv.PricingProvider)
+		})
+		It("should update on-demand pricing from the offer file", func() {
+			result := ExpectSingletonReconciled(ctx, bulkController)
+			Expect(result.RequeueAfter).To(Equal(24 * time.Hour))
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.23))
+
+			price, ok = awsEnv.PricingProvider.OnDemandPrice("c98.metal")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 7.89))
+		})
+		It("should not use the pricing API when an offer file is configured", func() {
+			now := time.Now()
+			awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []ec2types.SpotPrice{{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     "c98.large",
+					SpotPrice:        aws.String("0.50"),
+					Timestamp:        &now,
+				}},
+			})
+			awsEnv.PricingAPI.GetProductsBehavior.Output.Set(&awspricing.GetProductsOutput{
+				PriceList: []string{
+					fake.NewOnDemandPrice("c98.large", 1.20),
+				},
+			})
+			ExpectSingletonReconciled(ctx, bulkController)
+			ExpectSingletonReconciled(ctx, controller)
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.23))
+		})
+		It("should retain the previous prices when the offer file can't be retrieved", func() {
+			ExpectSingletonReconciled(ctx, bulkController)
+			server.Close()
+			_, err := bulkController.Reconcile(ctx)
+			Expect(err).To(HaveOccurred())
+
+			price, ok := awsEnv.PricingProvider.OnDemandPrice("c98.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", 1.23))
+		})
+	})
+	Context("Refresh", func() {
+		var refreshController *controllerspricing.Controller
+		BeforeEach(func() {
+			refreshController = controllerspricing.NewController(awsEnv.PricingProvider, awsEnv.InvalidationBus, awsEnv.HealthTracker)
+			now := time.Now()
+			awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []ec2types.SpotPrice{{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     "c99.large",
+					SpotPrice:        aws.String("1.23"),
+					Timestamp:        &now,
+				}},
+			})
+		})
+		succeed := func() {
+			awsEnv.PricingAPI.GetProductsBehavior.Output.Set(&awspricing.GetProductsOutput{
+				PriceList: []string{fake.NewOnDemandPrice("c99.large", 1.23)},
+			})
+		}
+
+		It("should refresh prices at the refresh interval", func() {
+			succeed()
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{PricingRefreshInterval: lo.ToPtr(6 * time.Hour)}))
+			result := ExpectSingletonReconciled(ctx, refreshController)
+			Expect(result.RequeueAfter).To(Equal(6 * time.Hour))
+		})
+		It("should delay refreshes by up to the jitter", func() {
+			succeed()
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{PricingRefreshJitter: lo.ToPtr(0.5)}))
+			result := ExpectSingletonReconciled(ctx, refreshController)
+			Expect(result.RequeueAfter).To(BeNumerically(">=", 12*time.Hour))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", 18*time.Hour))
+		})
+		It("should return the error without a retry backoff", func() {
+			_ = ExpectSingletonReconcileFailed(ctx, refreshController)
+		})
+		It("should retry failed refreshes with an exponential backoff", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				PricingRefreshInterval: lo.ToPtr(5 * time.Minute),
+				PricingRetryBackoff:    lo.ToPtr(time.Minute),
+			}))
+			for _, expected := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+				result := ExpectSingletonReconciled(ctx, refreshController)
+				Expect(result.RequeueAfter).To(Equal(expected))
+			}
+			// A successful refresh resets the backoff
+			succeed()
+			result := ExpectSingletonReconciled(ctx, refreshController)
+			Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+			awsEnv.PricingAPI.GetProductsBehavior.Reset()
+			result = ExpectSingletonReconciled(ctx, refreshController)
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+		})
+		It("should invalidate the offerings of instance types whose prices changed", func() {
+			var invalidated []string
+			bus := awscache.NewInvalidationBus()
+			bus.Subscribe(func(invalidation awscache.Invalidation) {
+				invalidated = append(invalidated, invalidation.InstanceTypes...)
+			})
+			refreshController = controllerspricing.NewController(awsEnv.PricingProvider, bus, nil)
+			succeed()
+			ExpectSingletonReconciled(ctx, refreshController)
+			Expect(invalidated).To(ContainElement("c99.large"))
+
+			invalidated = nil
+			ExpectSingletonReconciled(ctx, refreshController)
+			Expect(invalidated).To(BeEmpty())
+
+			awsEnv.PricingAPI.GetProductsBehavior.Output.Set(&awspricing.GetProductsOutput{
+				PriceList: []string{fake.NewOnDemandPrice("c99.large", 2.34)},
+			})
+			ExpectSingletonReconciled(ctx, refreshController)
+			Expect(invalidated).To(ConsistOf("c99.large"))
+		})
+	})
+	Context("Licenses", func() {
+		BeforeEach(func() {
+			now := time.Now()
+			awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
+				SpotPriceHistory: []ec2types.SpotPrice{{
+					AvailabilityZone: aws.String("test-zone-1a"),
+					InstanceType:     "c98.large",
+					SpotPrice:        aws.String("0.50"),
+					Timestamp:        &now,
+				}},
+			})
+			awsEnv.PricingAPI.GetProductsBehavior.Output.Set(&awspricing.GetProductsOutput{
+				PriceList: []string{
+					fake.NewOnDemandPrice("c98.large", 1.20),
+					fake.NewOnDemandPrice("c99.large", 1.23),
+				},
+			})
+			awsEnv.PricingAPI.OperatingSystemOutputs.Store("Windows", &awspricing.GetProductsOutput{
+				PriceList: []string{
+					fake.NewOnDemandPrice("c98.large", 1.50),
+					fake.NewOnDemandPrice("c99.large", 1.63),
+				},
+			})
+			awsEnv.PricingAPI.OperatingSystemOutputs.Store("RHEL", &awspricing.GetProductsOutput{
+				PriceList: []string{fake.NewOnDemandPrice("c98.large", 1.25)},
+			})
+			awsEnv.PricingAPI.OperatingSystemOutputs.Store("SUSE", &awspricing.GetProductsOutput{})
+		})
+		It("should not retrieve license prices unless license costs are included in offering prices", func() {
+			Expect(awsEnv.PricingProvider.UpdateLicensePricing(ctx)).To(Succeed())
+			Expect(awsEnv.PricingAPI.GetProductsBehavior.Calls()).To(Equal(0))
+		})
+		It("should price licenses at the difference from the Linux price", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IncludeLicenseCost: lo.ToPtr(true)}))
+			ExpectSingletonReconciled(ctx, controller)
+
+			price, ok := awsEnv.PricingProvider.LicensePrice("c98.large", "Windows")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("~", 0.30))
+			price, ok = awsEnv.PricingProvider.LicensePrice("c99.large", "Windows")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("~", 0.40))
+			price, ok = awsEnv.PricingProvider.LicensePrice("c98.large", "Red Hat Enterprise Linux")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("~", 0.05))
+			_, ok = awsEnv.PricingProvider.LicensePrice("c99.large", "Red Hat Enterprise Linux")
+			Expect(ok).To(BeFalse())
+		})
+		It("should only retrieve license-included prices", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IncludeLicenseCost: lo.ToPtr(true)}))
+			Expect(awsEnv.PricingProvider.UpdateLicensePricing(ctx)).To(Succeed())
+			Expect(awsEnv.PricingAPI.GetProductsBehavior.CalledWithInput.Len()).To(Equal(4))
+			awsEnv.PricingAPI.GetProductsBehavior.CalledWithInput.ForEach(func(input *awspricing.GetProductsInput) {
+				Expect(input.Filters).To(ContainElement(HaveField("Value", HaveValue(Equal("No License required")))))
+			})
+		})
+		It("should not price platforms without a license", func() {
+			price, ok := awsEnv.PricingProvider.LicensePrice("c98.large", "Linux/UNIX")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeZero())
+			price, ok = awsEnv.PricingProvider.LicensePrice("c98.large", "Windows BYOL")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeZero())
+		})
+	})
+	Context("Backends", func() {
+		It("should serve the embedded prices without calling the pricing or EC2 APIs", func() {
+			provider, err := pricing.NewProvider(ctx, pricing.StaticProviderName, pricing.ProviderOptions{Region: "us-east-1"})
+			Expect(err).ToNot(HaveOccurred())
+			ExpectSingletonReconciled(ctx, controllerspricing.NewController(provider, nil, nil))
+			Expect(awsEnv.PricingAPI.GetProductsBehavior.Calls()).To(BeZero())
+			Expect(awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Calls()).To(BeZero())
+
+			price, ok := provider.OnDemandPrice("m5.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("==", pricing.InitialOnDemandPricesAWS["us-east-1"]["m5.large"]))
+		})
+		Context("HTTP", func() {
+			var server *httptest.Server
+			var rateCard string
+			var status int
+			var provider pricing.Provider
+			BeforeEach(func() {
+				status = http.StatusOK
+				rateCard = `{"region": "us-east-1", "onDemand": {"m5.large": 0.05, "c98.large": 1.23}}`
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(status)
+					fmt.Fprint(w, rateCard)
+				}))
+				DeferCleanup(server.Close)
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+					PricingProvider: lo.ToPtr(pricing.HTTPProviderName),
+					PricingEndpoint: lo.ToPtr(server.URL),
+				}))
+				var err error
+				provider, err = pricing.NewProvider(ctx, pricing.HTTPProviderName, pricing.ProviderOptions{
+					Region:          "us-east-1",
+					EC2API:          awsEnv.EC2API,
+					PricingAPI:      awsEnv.PricingAPI,
+					SavingsPlansAPI: awsEnv.SavingsPlansAPI,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				now := time.Now()
+				awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Output.Set(&ec2.DescribeSpotPriceHistoryOutput{
+					SpotPriceHistory: []ec2types.SpotPrice{{
+						AvailabilityZone: aws.String("test-zone-1a"),
+						InstanceType:     "m5.large",
+						SpotPrice:        aws.String("0.02"),
+						Timestamp:        &now,
+					}},
+				})
+			})
+			It("should fail to construct the provider without an endpoint", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{PricingProvider: lo.ToPtr(pricing.HTTPProviderName)}))
+				_, err := pricing.NewProvider(ctx, pricing.HTTPProviderName, pricing.ProviderOptions{Region: "us-east-1"})
+				Expect(err).To(HaveOccurred())
+			})
+			It("should retrieve on-demand prices from the rate card", func() {
+				Expect(provider.UpdateOnDemandPricing(ctx)).To(Succeed())
+				Expect(awsEnv.PricingAPI.GetProductsBehavior.Calls()).To(BeZero())
+				price, ok := provider.OnDemandPrice("c98.large")
+				Expect(ok).To(BeTrue())
+				Expect(price).To(BeNumerically("==", 1.23))
+				price, ok = provider.OnDemandPrice("m5.large")
+				Expect(ok).To(BeTrue())
+				Expect(price).To(BeNumerically("==", 0.05))
+				// Instance types missing from the rate card keep their embedded price
+				price, ok = provider.OnDemandPrice("m5.xlarge")
+				Expect(ok).To(BeTrue())
+				Expect(price).To(BeNumerically("==", pricing.InitialOnDemandPricesAWS["us-east-1"]["m5.xlarge"]))
+			})
+			It("should retrieve spot prices from EC2 when the rate card doesn't contain any", func() {
+				Expect(provider.UpdateOnDemandPricing(ctx)).To(Succeed())
+				Expect(provider.UpdateSpotPricing(ctx)).To(Succeed())
+				Expect(awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Calls()).To(Equal(1))
+				price, ok := provider.SpotPrice("m5.large", "test-zone-1a")
+				Expect(ok).To(BeTrue())
+				Expect(price).To(BeNumerically("==", 0.02))
+			})
+			It("should retrieve spot prices from the rate card", func() {
+				rateCard = `{"onDemand": {"m5.large": 0.05}, "spot": {"m5.large": {"test-zone-1a": 0.01}}}`
+				Expect(provider.UpdateOnDemandPricing(ctx)).To(Succeed())
+				Expect(provider.UpdateSpotPricing(ctx)).To(Succeed())
+				Expect(awsEnv.EC2API.DescribeSpotPriceHistoryBehavior.Calls()).To(BeZero())
+				price, ok := provider.SpotPrice("m5.large", "test-zone-1a")
+				Expect(ok).To(BeTrue())
+				Expect(price).To(BeNumerically("==", 0.01))
+			})
+			It("should retain the previous prices when the rate card can't be retrieved", func() {
+				Expect(provider.UpdateOnDemandPricing(ctx)).To(Succeed())
+				status = http.StatusServiceUnavailable
+				Expect(provider.UpdateOnDemandPricing(ctx)).ToNot(Succeed())
+				price, ok := provider.OnDemandPrice("c98.large")
+				Expect(ok).To(BeTrue())
+				Expect(price).To(BeNumerically("==", 1.23))
+			})
+			It("should reject a rate card for another region", func() {
+				rateCard = `{"region": "us-west-2", "onDemand": {"c98.large": 1.23}}`
+				Expect(provider.UpdateOnDemandPricing(ctx)).ToNot(Succeed())
+				_, ok := provider.OnDemandPrice("c98.large")
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+	Context("Registration", func() {
+		It("should construct the default provider", func() {
+			provider, err := pricing.NewProvider(ctx, pricing.DefaultProviderName, pricing.ProviderOptions{
+				Region:          "us-west-2",
+				EC2API:          awsEnv.EC2API,
+				PricingAPI:      awsEnv.PricingAPI,
+				SavingsPlansAPI: awsEnv.SavingsPlansAPI,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(provider).To(BeAssignableToTypeOf(&pricing.DefaultProvider{}))
+		})
+		It("should construct a registered provider", func() {
+			Expect(pricing.Registered()).To(ContainElements(pricing.DefaultProviderName, "negotiated-rates"))
+
+			provider, err := pricing.NewProvider(ctx, "negotiated-rates", pricing.ProviderOptions{
+				Region:          "us-west-2",
+				EC2API:          awsEnv.EC2API,
+				PricingAPI:      awsEnv.PricingAPI,
+				SavingsPlansAPI: awsEnv.SavingsPlansAPI,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			listPrice, ok := awsEnv.PricingProvider.OnDemandPrice("m5.large")
+			Expect(ok).To(BeTrue())
+			price, ok := provider.OnDemandPrice("m5.large")
+			Expect(ok).To(BeTrue())
+			Expect(price).To(BeNumerically("~", listPrice*0.5))
+		})
+		It("should fail to construct a provider which isn't registered", func() {
+			_, err := pricing.NewProvider(ctx, "unregistered", pricing.ProviderOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(pricing.DefaultProviderName))
+		})
+		It("should return the error from the provider factory", func() {
+			_, err := pricing.NewProvider(ctx, "unavailable-rates", pricing.ProviderOptions{})
+			Expect(err).To(MatchError(ContainSubstring("rate card unavailable")))
+		})
+		It("should panic when a name is registered twice", func() {
+			Expect(func() {
+				pricing.Register(pricing.DefaultProviderName, func(context.Context, pricing.ProviderOptions) (pricing.Provider, error) {
+					return nil, nil
+				})
+			}).To(Panic())
+		})
+	})
 })
+
+func init() {
+	pricing.Register("negotiated-rates", func(ctx context.Context, opts pricing.ProviderOptions) (pricing.Provider, error) {
+		return &negotiatedRatesProvider{
+			DefaultProvider: pricing.NewDefaultProvider(ctx, opts.PricingAPI, opts.EC2API, opts.SavingsPlansAPI, opts.ZoneProvider, opts.Region),
+			discount:        0.5,
+		}, nil
+	})
+	pricing.Register("unavailable-rates", func(context.Context, pricing.ProviderOptions) (pricing.Provider, error) {
+		return nil, fmt.Errorf("rate card unavailable")
+	})
+}
+
+// negotiatedRatesProvider applies a discount to the on-demand list price, standing in for a provider backed by a
+// negotiated rates service
+type negotiatedRatesProvider struct {
+	*pricing.DefaultProvider
+	discount float64
+}
+
+func (p *negotiatedRatesProvider) OnDemandPrice(instanceType ec2types.InstanceType) (float64, bool) {
+	price, ok := p.DefaultProvider.OnDemandPrice(instanceType)
+	return price * p.discount, ok
+}
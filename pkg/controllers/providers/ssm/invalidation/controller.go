@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/karpenter/pkg/operator/injection"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
 )
@@ -37,14 +38,16 @@ import (
 // release, however Karpenter should react faster when an AMI is deprecated. This controller will ensure Karpenter
 // reacts to AMI deprecations within it's polling period (30m).
 type Controller struct {
-	cache       *cache.Cache
-	amiProvider amifamily.Provider
+	cache         *cache.Cache
+	amiProvider   amifamily.Provider
+	healthTracker *health.Tracker
 }
 
-func NewController(ssmCache *cache.Cache, amiProvider amifamily.Provider) *Controller {
+func NewController(ssmCache *cache.Cache, amiProvider amifamily.Provider, healthTracker *health.Tracker) *Controller {
 	return &Controller{
-		cache:       ssmCache,
-		amiProvider: amiProvider,
+		cache:         ssmCache,
+		amiProvider:   amiProvider,
+		healthTracker: healthTracker,
 	}
 }
 
@@ -55,10 +58,19 @@ func (c *Controller) Name() string {
 func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	ctx = injection.WithControllerName(ctx, c.Name())
 
+	err := health.Guard(c.invalidate)(ctx)
+	c.healthTracker.Observe(health.SubsystemAMIs, err)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: 30 * time.Minute}, nil
+}
+
+func (c *Controller) invalidate(ctx context.Context) error {
 	amiIDsToParameters := map[string]ssm.Parameter{}
 	for _, item := range c.cache.Items() {
-		entry := item.Object.(ssm.CacheEntry)
-		if !entry.Parameter.IsMutable {
+		entry, ok := item.Object.(ssm.CacheEntry)
+		if !ok || !entry.Parameter.IsMutable {
 			continue
 		}
 		amiIDsToParameters[entry.Value] = entry.Parameter
@@ -73,7 +85,7 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	}) {
 		resolvedAMIs, err := c.amiProvider.List(ctx, nodeClass)
 		if err != nil {
-			return reconcile.Result{}, err
+			return err
 		}
 		amis = append(amis, resolvedAMIs...)
 	}
@@ -84,7 +96,7 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 		parameter := amiIDsToParameters[ami.AmiID]
 		c.cache.Delete(parameter.CacheKey())
 	}
-	return reconcile.Result{RequeueAfter: 30 * time.Minute}, nil
+	return nil
 }
 
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
@@ -58,7 +58,7 @@ var _ = BeforeSuite(func() {
 	ctx, stop = context.WithCancel(ctx)
 	awsEnv = test.NewEnvironment(ctx, env)
 
-	invalidationController = invalidation.NewController(awsEnv.SSMCache, awsEnv.AMIProvider)
+	invalidationController = invalidation.NewController(awsEnv.SSMCache, awsEnv.AMIProvider, awsEnv.HealthTracker)
 })
 
 var _ = AfterSuite(func() {
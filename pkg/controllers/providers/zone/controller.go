@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zone
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
+)
+
+type Controller struct {
+	zoneProvider zone.Provider
+}
+
+func NewController(zoneProvider zone.Provider) *Controller {
+	return &Controller{
+		zoneProvider: zoneProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.zone")
+
+	if err := c.zoneProvider.UpdateZones(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("updating zones, %w", err)
+	}
+	// The zones in a region only change when the account opts into or out of a zone group
+	return reconcile.Result{RequeueAfter: time.Hour}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.zone").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -36,6 +36,7 @@ var (
 	notFoundErrorCodes = sets.New[string](
 		"InvalidCapacityReservationId.NotFound",
 		"InvalidInstanceID.NotFound",
+		"InvalidAllocationID.NotFound",
 		launchTemplateNameNotFoundCode,
 		"InvalidLaunchTemplateId.NotFound",
 		"QueueDoesNotExist",
@@ -46,6 +47,8 @@ var (
 	)
 
 	reservationCapacityExceededErrorCode = "ReservationCapacityExceeded"
+	unsupportedErrorCode                 = "Unsupported"
+	insufficientFreeAddressesErrorCode   = "InsufficientFreeAddressesInSubnet"
 
 	// unfulfillableCapacityErrorCodes signify that capacity is temporarily unable to be launched
 	unfulfillableCapacityErrorCodes = sets.New[string](
@@ -53,8 +56,8 @@ var (
 		"MaxSpotInstanceCountExceeded",
 		"VcpuLimitExceeded",
 		"UnfulfillableCapacity",
-		"Unsupported",
-		"InsufficientFreeAddressesInSubnet",
+		unsupportedErrorCode,
+		insufficientFreeAddressesErrorCode,
 		reservationCapacityExceededErrorCode,
 	)
 )
@@ -159,6 +162,18 @@ func IsReservationCapacityExceeded(err ec2types.CreateFleetError) bool {
 	return *err.ErrorCode == reservationCapacityExceededErrorCode
 }
 
+// IsUnsupported returns true if the fleet error means the instance type isn't supported for the request. Brand-new
+// instance types can be rejected this way before the instance type data Karpenter discovered reflects them.
+func IsUnsupported(err ec2types.CreateFleetError) bool {
+	return *err.ErrorCode == unsupportedErrorCode
+}
+
+// IsInsufficientFreeAddresses returns true if the fleet error means the subnet of the override ran out of free IP
+// addresses, so other subnets may still be launched into.
+func IsInsufficientFreeAddresses(err ec2types.CreateFleetError) bool {
+	return *err.ErrorCode == insufficientFreeAddressesErrorCode
+}
+
 func IsLaunchTemplateNotFound(err error) bool {
 	if err == nil {
 		return false
@@ -47,25 +47,42 @@ type CapacityPool struct {
 // EC2Behavior must be reset between tests otherwise tests will
 // pollute each other.
 type EC2Behavior struct {
-	DescribeCapacityReservationsOutput  AtomicPtr[ec2.DescribeCapacityReservationsOutput]
-	DescribeImagesOutput                AtomicPtr[ec2.DescribeImagesOutput]
-	DescribeLaunchTemplatesOutput       AtomicPtr[ec2.DescribeLaunchTemplatesOutput]
-	DescribeSubnetsOutput               AtomicPtr[ec2.DescribeSubnetsOutput]
-	DescribeSecurityGroupsOutput        AtomicPtr[ec2.DescribeSecurityGroupsOutput]
-	DescribeInstanceTypesOutput         AtomicPtr[ec2.DescribeInstanceTypesOutput]
-	DescribeInstanceTypeOfferingsOutput AtomicPtr[ec2.DescribeInstanceTypeOfferingsOutput]
-	DescribeAvailabilityZonesOutput     AtomicPtr[ec2.DescribeAvailabilityZonesOutput]
-	DescribeSpotPriceHistoryBehavior    MockedFunction[ec2.DescribeSpotPriceHistoryInput, ec2.DescribeSpotPriceHistoryOutput]
-	CreateFleetBehavior                 MockedFunction[ec2.CreateFleetInput, ec2.CreateFleetOutput]
-	TerminateInstancesBehavior          MockedFunction[ec2.TerminateInstancesInput, ec2.TerminateInstancesOutput]
-	DescribeInstancesBehavior           MockedFunction[ec2.DescribeInstancesInput, ec2.DescribeInstancesOutput]
-	CreateTagsBehavior                  MockedFunction[ec2.CreateTagsInput, ec2.CreateTagsOutput]
-	RunInstancesBehavior                MockedFunction[ec2.RunInstancesInput, ec2.RunInstancesOutput]
-	CreateLaunchTemplateBehavior        MockedFunction[ec2.CreateLaunchTemplateInput, ec2.CreateLaunchTemplateOutput]
-	CalledWithDescribeImagesInput       AtomicPtrSlice[ec2.DescribeImagesInput]
-	Instances                           sync.Map
-	InsufficientCapacityPools           atomic.Slice[CapacityPool]
-	NextError                           AtomicError
+	DescribeCapacityReservationsOutput   AtomicPtr[ec2.DescribeCapacityReservationsOutput]
+	DescribeImagesOutput                 AtomicPtr[ec2.DescribeImagesOutput]
+	DescribeLaunchTemplatesOutput        AtomicPtr[ec2.DescribeLaunchTemplatesOutput]
+	DescribeSubnetsOutput                AtomicPtr[ec2.DescribeSubnetsOutput]
+	DescribeSecurityGroupsOutput         AtomicPtr[ec2.DescribeSecurityGroupsOutput]
+	DescribeInstanceTypesOutput          AtomicPtr[ec2.DescribeInstanceTypesOutput]
+	DescribeInstanceTypeOfferingsOutput  AtomicPtr[ec2.DescribeInstanceTypeOfferingsOutput]
+	DescribeAvailabilityZonesOutput      AtomicPtr[ec2.DescribeAvailabilityZonesOutput]
+	DescribeSpotPriceHistoryBehavior     MockedFunction[ec2.DescribeSpotPriceHistoryInput, ec2.DescribeSpotPriceHistoryOutput]
+	CreateFleetBehavior                  MockedFunction[ec2.CreateFleetInput, ec2.CreateFleetOutput]
+	TerminateInstancesBehavior           MockedFunction[ec2.TerminateInstancesInput, ec2.TerminateInstancesOutput]
+	DescribeInstancesBehavior            MockedFunction[ec2.DescribeInstancesInput, ec2.DescribeInstancesOutput]
+	CreateTagsBehavior                   MockedFunction[ec2.CreateTagsInput, ec2.CreateTagsOutput]
+	DeleteTagsBehavior                   MockedFunction[ec2.DeleteTagsInput, ec2.DeleteTagsOutput]
+	RunInstancesBehavior                 MockedFunction[ec2.RunInstancesInput, ec2.RunInstancesOutput]
+	CreateLaunchTemplateBehavior         MockedFunction[ec2.CreateLaunchTemplateInput, ec2.CreateLaunchTemplateOutput]
+	GetEbsEncryptionByDefaultBehavior    MockedFunction[ec2.GetEbsEncryptionByDefaultInput, ec2.GetEbsEncryptionByDefaultOutput]
+	GetEbsDefaultKmsKeyIdBehavior        MockedFunction[ec2.GetEbsDefaultKmsKeyIdInput, ec2.GetEbsDefaultKmsKeyIdOutput]
+	GetInstanceMetadataDefaultsBehavior  MockedFunction[ec2.GetInstanceMetadataDefaultsInput, ec2.GetInstanceMetadataDefaultsOutput]
+	GetSerialConsoleAccessStatusBehavior MockedFunction[ec2.GetSerialConsoleAccessStatusInput, ec2.GetSerialConsoleAccessStatusOutput]
+	GetConsoleOutputBehavior             MockedFunction[ec2.GetConsoleOutputInput, ec2.GetConsoleOutputOutput]
+	DescribeReservedInstancesBehavior    MockedFunction[ec2.DescribeReservedInstancesInput, ec2.DescribeReservedInstancesOutput]
+	DescribeVpcEndpointsBehavior         MockedFunction[ec2.DescribeVpcEndpointsInput, ec2.DescribeVpcEndpointsOutput]
+	DescribeSpotInstanceRequestsBehavior MockedFunction[ec2.DescribeSpotInstanceRequestsInput, ec2.DescribeSpotInstanceRequestsOutput]
+	DescribeVolumesBehavior              MockedFunction[ec2.DescribeVolumesInput, ec2.DescribeVolumesOutput]
+	DescribeAddressesBehavior            MockedFunction[ec2.DescribeAddressesInput, ec2.DescribeAddressesOutput]
+	AllocateAddressBehavior              MockedFunction[ec2.AllocateAddressInput, ec2.AllocateAddressOutput]
+	AssociateAddressBehavior             MockedFunction[ec2.AssociateAddressInput, ec2.AssociateAddressOutput]
+	ReleaseAddressBehavior               MockedFunction[ec2.ReleaseAddressInput, ec2.ReleaseAddressOutput]
+	ModifyNetworkInterfaceBehavior       MockedFunction[ec2.ModifyNetworkInterfaceAttributeInput, ec2.ModifyNetworkInterfaceAttributeOutput]
+	CalledWithDescribeImagesInput        AtomicPtrSlice[ec2.DescribeImagesInput]
+	Instances                            sync.Map
+	Addresses                            sync.Map
+	InsufficientCapacityPools            atomic.Slice[CapacityPool]
+	InsufficientFreeAddressesSubnets     atomic.Slice[string]
+	NextError                            AtomicError
 
 	LaunchTemplates                       sync.Map
 	launchTemplatesToCapacityReservations sync.Map // map[lt-name]cr-id
@@ -95,8 +112,23 @@ func (e *EC2API) Reset() {
 	e.DescribeAvailabilityZonesOutput.Reset()
 	e.CreateFleetBehavior.Reset()
 	e.TerminateInstancesBehavior.Reset()
+	e.DeleteTagsBehavior.Reset()
 	e.DescribeInstancesBehavior.Reset()
 	e.CreateLaunchTemplateBehavior.Reset()
+	e.GetEbsEncryptionByDefaultBehavior.Reset()
+	e.GetEbsDefaultKmsKeyIdBehavior.Reset()
+	e.GetInstanceMetadataDefaultsBehavior.Reset()
+	e.GetSerialConsoleAccessStatusBehavior.Reset()
+	e.GetConsoleOutputBehavior.Reset()
+	e.DescribeReservedInstancesBehavior.Reset()
+	e.DescribeVpcEndpointsBehavior.Reset()
+	e.DescribeSpotInstanceRequestsBehavior.Reset()
+	e.DescribeVolumesBehavior.Reset()
+	e.DescribeAddressesBehavior.Reset()
+	e.AllocateAddressBehavior.Reset()
+	e.AssociateAddressBehavior.Reset()
+	e.ReleaseAddressBehavior.Reset()
+	e.ModifyNetworkInterfaceBehavior.Reset()
 	e.CalledWithDescribeImagesInput.Reset()
 	e.DescribeSpotPriceHistoryBehavior.Reset()
 	e.Instances.Range(func(k, v any) bool {
@@ -107,7 +139,12 @@ func (e *EC2API) Reset() {
 		e.LaunchTemplates.Delete(k)
 		return true
 	})
+	e.Addresses.Range(func(k, v any) bool {
+		e.Addresses.Delete(k)
+		return true
+	})
 	e.InsufficientCapacityPools.Reset()
+	e.InsufficientFreeAddressesSubnets.Reset()
 	e.NextError.Reset()
 
 	e.launchTemplatesToCapacityReservations.Range(func(k, _ any) bool {
@@ -135,6 +172,7 @@ func (e *EC2API) CreateFleet(_ context.Context, input *ec2.CreateFleetInput, _ .
 		var instanceIds []string
 		var icedPools []CapacityPool
 		var reservationExceededPools []CapacityPool
+		var exhaustedOverrides []ec2types.FleetLaunchTemplateOverridesRequest
 		var spotInstanceRequestID *string
 
 		if string(input.TargetCapacitySpecification.DefaultTargetCapacityType) == karpv1.CapacityTypeSpot {
@@ -145,6 +183,17 @@ func (e *EC2API) CreateFleet(_ context.Context, input *ec2.CreateFleetInput, _ .
 		for _, ltc := range input.LaunchTemplateConfigs {
 			for _, override := range ltc.Overrides {
 				skipInstance := false
+				e.InsufficientFreeAddressesSubnets.Range(func(subnetID string) bool {
+					if subnetID == aws.ToString(override.SubnetId) {
+						exhaustedOverrides = append(exhaustedOverrides, override)
+						skipInstance = true
+						return false
+					}
+					return true
+				})
+				if skipInstance {
+					continue
+				}
 				e.InsufficientCapacityPools.Range(func(pool CapacityPool) bool {
 					if pool.InstanceType == string(override.InstanceType) &&
 						pool.Zone == aws.ToString(override.AvailabilityZone) &&
@@ -205,6 +254,10 @@ func (e *EC2API) CreateFleet(_ context.Context, input *ec2.CreateFleetInput, _ .
 				InstanceType: input.LaunchTemplateConfigs[0].Overrides[0].InstanceType,
 				Lifecycle:    ec2types.InstanceLifecycle(input.TargetCapacitySpecification.DefaultTargetCapacityType),
 				LaunchTemplateAndOverrides: &ec2types.LaunchTemplateAndOverridesResponse{
+					LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecification{
+						LaunchTemplateName: lo.FromPtr(input.LaunchTemplateConfigs[0].LaunchTemplateSpecification).LaunchTemplateName,
+						Version:            lo.FromPtr(input.LaunchTemplateConfigs[0].LaunchTemplateSpecification).Version,
+					},
 					Overrides: &ec2types.FleetLaunchTemplateOverrides{
 						SubnetId:         input.LaunchTemplateConfigs[0].Overrides[0].SubnetId,
 						ImageId:          input.LaunchTemplateConfigs[0].Overrides[0].ImageId,
@@ -225,6 +278,18 @@ func (e *EC2API) CreateFleet(_ context.Context, input *ec2.CreateFleetInput, _ .
 				},
 			})
 		}
+		for _, override := range exhaustedOverrides {
+			result.Errors = append(result.Errors, ec2types.CreateFleetError{
+				ErrorCode: aws.String("InsufficientFreeAddressesInSubnet"),
+				LaunchTemplateAndOverrides: &ec2types.LaunchTemplateAndOverridesResponse{
+					Overrides: &ec2types.FleetLaunchTemplateOverrides{
+						InstanceType:     override.InstanceType,
+						SubnetId:         override.SubnetId,
+						AvailabilityZone: override.AvailabilityZone,
+					},
+				},
+			})
+		}
 		for _, pool := range reservationExceededPools {
 			result.Errors = append(result.Errors, ec2types.CreateFleetError{
 				ErrorCode: lo.ToPtr("ReservationCapacityExceeded"),
@@ -245,6 +310,14 @@ func (e *EC2API) TerminateInstances(_ context.Context, input *ec2.TerminateInsta
 		var instanceStateChanges []ec2types.InstanceStateChange
 		for _, id := range input.InstanceIds {
 			if _, ok := e.Instances.LoadAndDelete(id); ok {
+				// Elastic IP addresses are disassociated from terminated instances
+				e.Addresses.Range(func(k, v any) bool {
+					if address := v.(ec2types.Address); aws.ToString(address.InstanceId) == id {
+						address.AssociationId, address.InstanceId, address.NetworkInterfaceId = nil, nil, nil
+						e.Addresses.Store(k, address)
+					}
+					return true
+				})
 				instanceStateChanges = append(instanceStateChanges, ec2types.InstanceStateChange{
 					PreviousState: &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning, Code: aws.Int32(16)},
 					CurrentState:  &ec2types.InstanceState{Name: ec2types.InstanceStateNameShuttingDown, Code: aws.Int32(32)},
@@ -273,7 +346,11 @@ func (e *EC2API) CreateLaunchTemplate(ctx context.Context, input *ec2.CreateLaun
 			defer e.NextError.Reset()
 			return nil, e.NextError.Get()
 		}
-		launchTemplate := ec2types.LaunchTemplate{LaunchTemplateName: input.LaunchTemplateName}
+		launchTemplate := ec2types.LaunchTemplate{
+			LaunchTemplateName:  input.LaunchTemplateName,
+			LaunchTemplateId:    aws.String(LaunchTemplateID()),
+			LatestVersionNumber: aws.Int64(1),
+		}
 		e.LaunchTemplates.Store(input.LaunchTemplateName, launchTemplate)
 		if crs := input.LaunchTemplateData.CapacityReservationSpecification; crs != nil && crs.CapacityReservationPreference == ec2types.CapacityReservationPreferenceCapacityReservationsOnly {
 			e.launchTemplatesToCapacityReservations.Store(*input.LaunchTemplateName, *crs.CapacityReservationTarget.CapacityReservationId)
@@ -306,6 +383,23 @@ func (e *EC2API) CreateTags(_ context.Context, input *ec2.CreateTagsInput, _ ...
 	})
 }
 
+func (e *EC2API) DeleteTags(_ context.Context, input *ec2.DeleteTagsInput, _ ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error) {
+	return e.DeleteTagsBehavior.Invoke(input, func(input *ec2.DeleteTagsInput) (*ec2.DeleteTagsOutput, error) {
+		for _, id := range input.Resources {
+			raw, ok := e.Instances.Load(id)
+			if !ok {
+				return nil, fmt.Errorf("instance with id '%s' does not exist", id)
+			}
+			instance := raw.(ec2types.Instance)
+			instance.Tags = lo.Reject(instance.Tags, func(tag ec2types.Tag, _ int) bool {
+				return lo.ContainsBy(input.Tags, func(t ec2types.Tag) bool { return lo.FromPtr(t.Key) == lo.FromPtr(tag.Key) })
+			})
+			e.Instances.Swap(lo.FromPtr(instance.InstanceId), instance)
+		}
+		return nil, nil
+	})
+}
+
 func (e *EC2API) DescribeInstances(_ context.Context, input *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
 	return e.DescribeInstancesBehavior.Invoke(input, func(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
 		var instances []ec2types.Instance
@@ -343,6 +437,11 @@ func filterInstances(instances []ec2types.Instance, filters []ec2types.Filter) [
 					passesFilter = false
 					break OUTER
 				}
+			case aws.ToString(filter.Name) == "instance-type":
+				if !sets.New(filter.Values...).Has(string(instance.InstanceType)) {
+					passesFilter = false
+					break OUTER
+				}
 			case aws.ToString(filter.Name) == "tag-key":
 				values := sets.New(filter.Values...)
 				if _, ok := lo.Find(instance.Tags, func(t ec2types.Tag) bool {
@@ -523,7 +622,7 @@ func (e *EC2API) DescribeSecurityGroups(_ context.Context, input *ec2.DescribeSe
 	if !e.DescribeSecurityGroupsOutput.IsNil() {
 		describeSecurityGroupsOutput := e.DescribeSecurityGroupsOutput.Clone()
 		describeSecurityGroupsOutput.SecurityGroups = FilterDescribeSecurtyGroups(describeSecurityGroupsOutput.SecurityGroups, input.Filters)
-		return e.DescribeSecurityGroupsOutput.Clone(), nil
+		return describeSecurityGroupsOutput, nil
 	}
 	sgs := []ec2types.SecurityGroup{
 		{
@@ -533,6 +632,9 @@ func (e *EC2API) DescribeSecurityGroups(_ context.Context, input *ec2.DescribeSe
 				{Key: aws.String("Name"), Value: aws.String("test-security-group-1")},
 				{Key: aws.String("foo"), Value: aws.String("bar")},
 			},
+			// Allows all traffic between members of the group, as required by EFA
+			IpPermissions:       []ec2types.IpPermission{{IpProtocol: aws.String("-1"), UserIdGroupPairs: []ec2types.UserIdGroupPair{{GroupId: aws.String("sg-test1")}}}},
+			IpPermissionsEgress: []ec2types.IpPermission{{IpProtocol: aws.String("-1"), UserIdGroupPairs: []ec2types.UserIdGroupPair{{GroupId: aws.String("sg-test1")}}}},
 		},
 		{
 			GroupId:   aws.String("sg-test2"),
@@ -752,3 +854,145 @@ func (e *EC2API) RunInstances(ctx context.Context, input *ec2.RunInstancesInput,
 		}, nil
 	})
 }
+
+func (e *EC2API) GetEbsEncryptionByDefault(_ context.Context, input *ec2.GetEbsEncryptionByDefaultInput, _ ...func(*ec2.Options)) (*ec2.GetEbsEncryptionByDefaultOutput, error) {
+	return e.GetEbsEncryptionByDefaultBehavior.Invoke(input, func(_ *ec2.GetEbsEncryptionByDefaultInput) (*ec2.GetEbsEncryptionByDefaultOutput, error) {
+		return &ec2.GetEbsEncryptionByDefaultOutput{EbsEncryptionByDefault: aws.Bool(false)}, nil
+	})
+}
+
+func (e *EC2API) GetEbsDefaultKmsKeyId(_ context.Context, input *ec2.GetEbsDefaultKmsKeyIdInput, _ ...func(*ec2.Options)) (*ec2.GetEbsDefaultKmsKeyIdOutput, error) {
+	return e.GetEbsDefaultKmsKeyIdBehavior.Invoke(input, func(_ *ec2.GetEbsDefaultKmsKeyIdInput) (*ec2.GetEbsDefaultKmsKeyIdOutput, error) {
+		return &ec2.GetEbsDefaultKmsKeyIdOutput{KmsKeyId: aws.String("alias/aws/ebs")}, nil
+	})
+}
+
+func (e *EC2API) GetInstanceMetadataDefaults(_ context.Context, input *ec2.GetInstanceMetadataDefaultsInput, _ ...func(*ec2.Options)) (*ec2.GetInstanceMetadataDefaultsOutput, error) {
+	return e.GetInstanceMetadataDefaultsBehavior.Invoke(input, func(_ *ec2.GetInstanceMetadataDefaultsInput) (*ec2.GetInstanceMetadataDefaultsOutput, error) {
+		return &ec2.GetInstanceMetadataDefaultsOutput{AccountLevel: &ec2types.InstanceMetadataDefaultsResponse{ManagedBy: ec2types.ManagedByAccount}}, nil
+	})
+}
+
+func (e *EC2API) GetSerialConsoleAccessStatus(_ context.Context, input *ec2.GetSerialConsoleAccessStatusInput, _ ...func(*ec2.Options)) (*ec2.GetSerialConsoleAccessStatusOutput, error) {
+	return e.GetSerialConsoleAccessStatusBehavior.Invoke(input, func(_ *ec2.GetSerialConsoleAccessStatusInput) (*ec2.GetSerialConsoleAccessStatusOutput, error) {
+		return &ec2.GetSerialConsoleAccessStatusOutput{SerialConsoleAccessEnabled: aws.Bool(false), ManagedBy: ec2types.ManagedByAccount}, nil
+	})
+}
+
+func (e *EC2API) GetConsoleOutput(_ context.Context, input *ec2.GetConsoleOutputInput, _ ...func(*ec2.Options)) (*ec2.GetConsoleOutputOutput, error) {
+	return e.GetConsoleOutputBehavior.Invoke(input, func(input *ec2.GetConsoleOutputInput) (*ec2.GetConsoleOutputOutput, error) {
+		return &ec2.GetConsoleOutputOutput{InstanceId: input.InstanceId}, nil
+	})
+}
+
+func (e *EC2API) DescribeReservedInstances(_ context.Context, input *ec2.DescribeReservedInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeReservedInstancesOutput, error) {
+	return e.DescribeReservedInstancesBehavior.Invoke(input, func(_ *ec2.DescribeReservedInstancesInput) (*ec2.DescribeReservedInstancesOutput, error) {
+		return &ec2.DescribeReservedInstancesOutput{}, nil
+	})
+}
+
+func (e *EC2API) DescribeVpcEndpoints(_ context.Context, input *ec2.DescribeVpcEndpointsInput, _ ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+	return e.DescribeVpcEndpointsBehavior.Invoke(input, func(_ *ec2.DescribeVpcEndpointsInput) (*ec2.DescribeVpcEndpointsOutput, error) {
+		return &ec2.DescribeVpcEndpointsOutput{}, nil
+	})
+}
+
+func (e *EC2API) DescribeSpotInstanceRequests(_ context.Context, input *ec2.DescribeSpotInstanceRequestsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSpotInstanceRequestsOutput, error) {
+	return e.DescribeSpotInstanceRequestsBehavior.Invoke(input, func(_ *ec2.DescribeSpotInstanceRequestsInput) (*ec2.DescribeSpotInstanceRequestsOutput, error) {
+		return &ec2.DescribeSpotInstanceRequestsOutput{}, nil
+	})
+}
+
+func (e *EC2API) DescribeVolumes(_ context.Context, input *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	return e.DescribeVolumesBehavior.Invoke(input, func(_ *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error) {
+		return &ec2.DescribeVolumesOutput{}, nil
+	})
+}
+
+func (e *EC2API) DescribeAddresses(_ context.Context, input *ec2.DescribeAddressesInput, _ ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	return e.DescribeAddressesBehavior.Invoke(input, func(input *ec2.DescribeAddressesInput) (*ec2.DescribeAddressesOutput, error) {
+		var addresses []ec2types.Address
+		e.Addresses.Range(func(_, v any) bool {
+			address := v.(ec2types.Address)
+			if len(input.AllocationIds) != 0 && !lo.Contains(input.AllocationIds, aws.ToString(address.AllocationId)) {
+				return true
+			}
+			if Filter(input.Filters, aws.ToString(address.AllocationId), "", "", "", address.Tags) {
+				addresses = append(addresses, address)
+			}
+			return true
+		})
+		return &ec2.DescribeAddressesOutput{Addresses: addresses}, nil
+	})
+}
+
+func (e *EC2API) AllocateAddress(_ context.Context, input *ec2.AllocateAddressInput, _ ...func(*ec2.Options)) (*ec2.AllocateAddressOutput, error) {
+	return e.AllocateAddressBehavior.Invoke(input, func(input *ec2.AllocateAddressInput) (*ec2.AllocateAddressOutput, error) {
+		address := ec2types.Address{
+			AllocationId: aws.String(ElasticIPAllocationID()),
+			PublicIp:     aws.String(fmt.Sprintf("203.0.113.%d", randomdata.Number(1, 255))),
+			Domain:       input.Domain,
+			Tags: lo.Flatten(lo.Map(input.TagSpecifications, func(ts ec2types.TagSpecification, _ int) []ec2types.Tag {
+				return ts.Tags
+			})),
+		}
+		e.Addresses.Store(aws.ToString(address.AllocationId), address)
+		return &ec2.AllocateAddressOutput{
+			AllocationId: address.AllocationId,
+			PublicIp:     address.PublicIp,
+			Domain:       address.Domain,
+		}, nil
+	})
+}
+
+func (e *EC2API) AssociateAddress(_ context.Context, input *ec2.AssociateAddressInput, _ ...func(*ec2.Options)) (*ec2.AssociateAddressOutput, error) {
+	return e.AssociateAddressBehavior.Invoke(input, func(input *ec2.AssociateAddressInput) (*ec2.AssociateAddressOutput, error) {
+		raw, ok := e.Addresses.Load(aws.ToString(input.AllocationId))
+		if !ok {
+			return nil, &smithy.GenericAPIError{Code: "InvalidAllocationID.NotFound", Message: fmt.Sprintf("allocation id '%s' does not exist", aws.ToString(input.AllocationId))}
+		}
+		address := raw.(ec2types.Address)
+		if address.AssociationId != nil && !aws.ToBool(input.AllowReassociation) {
+			return nil, &smithy.GenericAPIError{Code: "Resource.AlreadyAssociated", Message: fmt.Sprintf("resource %s is already associated", aws.ToString(input.AllocationId))}
+		}
+		address.AssociationId = aws.String(fmt.Sprintf("eipassoc-%s", strings.ToLower(randomdata.Alphanumeric(17))))
+		address.InstanceId = input.InstanceId
+		address.NetworkInterfaceId = input.NetworkInterfaceId
+		e.Addresses.Store(aws.ToString(address.AllocationId), address)
+		return &ec2.AssociateAddressOutput{AssociationId: address.AssociationId}, nil
+	})
+}
+
+func (e *EC2API) ReleaseAddress(_ context.Context, input *ec2.ReleaseAddressInput, _ ...func(*ec2.Options)) (*ec2.ReleaseAddressOutput, error) {
+	return e.ReleaseAddressBehavior.Invoke(input, func(input *ec2.ReleaseAddressInput) (*ec2.ReleaseAddressOutput, error) {
+		raw, ok := e.Addresses.Load(aws.ToString(input.AllocationId))
+		if !ok {
+			return nil, &smithy.GenericAPIError{Code: "InvalidAllocationID.NotFound", Message: fmt.Sprintf("allocation id '%s' does not exist", aws.ToString(input.AllocationId))}
+		}
+		if raw.(ec2types.Address).AssociationId != nil {
+			return nil, &smithy.GenericAPIError{Code: "InvalidIPAddress.InUse", Message: fmt.Sprintf("address %s is in use", aws.ToString(input.AllocationId))}
+		}
+		e.Addresses.Delete(aws.ToString(input.AllocationId))
+		return &ec2.ReleaseAddressOutput{}, nil
+	})
+}
+
+func (e *EC2API) ModifyNetworkInterfaceAttribute(_ context.Context, input *ec2.ModifyNetworkInterfaceAttributeInput, _ ...func(*ec2.Options)) (*ec2.ModifyNetworkInterfaceAttributeOutput, error) {
+	return e.ModifyNetworkInterfaceBehavior.Invoke(input, func(input *ec2.ModifyNetworkInterfaceAttributeInput) (*ec2.ModifyNetworkInterfaceAttributeOutput, error) {
+		// Security groups are replaced on the matching network interface of launched instances
+		e.Instances.Range(func(k, v any) bool {
+			instance := v.(ec2types.Instance)
+			for i, ni := range instance.NetworkInterfaces {
+				if aws.ToString(ni.NetworkInterfaceId) == aws.ToString(input.NetworkInterfaceId) && len(input.Groups) != 0 {
+					instance.NetworkInterfaces[i].Groups = lo.Map(input.Groups, func(id string, _ int) ec2types.GroupIdentifier {
+						return ec2types.GroupIdentifier{GroupId: aws.String(id)}
+					})
+					e.Instances.Store(k, instance)
+					return false
+				}
+			}
+			return true
+		})
+		return &ec2.ModifyNetworkInterfaceAttributeOutput{}, nil
+	})
+}
@@ -0,0 +1,46 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/imagebuilder"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+type ImageBuilderAPI struct {
+	sdk.ImageBuilderAPI
+	ListImagePipelineImagesBehavior MockedFunction[imagebuilder.ListImagePipelineImagesInput, imagebuilder.ListImagePipelineImagesOutput]
+	ListImageBuildVersionsBehavior  MockedFunction[imagebuilder.ListImageBuildVersionsInput, imagebuilder.ListImageBuildVersionsOutput]
+}
+
+func (i *ImageBuilderAPI) Reset() {
+	i.ListImagePipelineImagesBehavior.Reset()
+	i.ListImageBuildVersionsBehavior.Reset()
+}
+
+func (i *ImageBuilderAPI) ListImagePipelineImages(_ context.Context, input *imagebuilder.ListImagePipelineImagesInput, _ ...func(*imagebuilder.Options)) (*imagebuilder.ListImagePipelineImagesOutput, error) {
+	return i.ListImagePipelineImagesBehavior.Invoke(input, func(_ *imagebuilder.ListImagePipelineImagesInput) (*imagebuilder.ListImagePipelineImagesOutput, error) {
+		return &imagebuilder.ListImagePipelineImagesOutput{}, nil
+	})
+}
+
+func (i *ImageBuilderAPI) ListImageBuildVersions(_ context.Context, input *imagebuilder.ListImageBuildVersionsInput, _ ...func(*imagebuilder.Options)) (*imagebuilder.ListImageBuildVersionsOutput, error) {
+	return i.ListImageBuildVersionsBehavior.Invoke(input, func(_ *imagebuilder.ListImageBuildVersionsInput) (*imagebuilder.ListImageBuildVersionsOutput, error) {
+		return &imagebuilder.ListImageBuildVersionsOutput{}, nil
+	})
+}
@@ -19,8 +19,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/samber/lo"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 )
@@ -28,13 +30,34 @@ import (
 type PricingAPI struct {
 	sdk.PricingAPI
 	GetProductsBehavior MockedFunction[pricing.GetProductsInput, pricing.GetProductsOutput]
+	// RegionCodeOutputs holds the output, or error, to return for products filtered to a region code, such as a Local
+	// Zone group. Products for other region codes are returned by the GetProductsBehavior.
+	RegionCodeOutputs sync.Map
+	// OperatingSystemOutputs holds the output to return for products filtered to an operating system other than Linux.
+	// Products for Linux are returned by the GetProductsBehavior.
+	OperatingSystemOutputs sync.Map
 }
 
 func (p *PricingAPI) Reset() {
 	p.GetProductsBehavior.Reset()
+	p.RegionCodeOutputs.Clear()
+	p.OperatingSystemOutputs.Clear()
 }
 
 func (p *PricingAPI) GetProducts(_ context.Context, input *pricing.GetProductsInput, _ ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+	for _, f := range input.Filters {
+		outputs := map[string]*sync.Map{"regionCode": &p.RegionCodeOutputs, "operatingSystem": &p.OperatingSystemOutputs}[lo.FromPtr(f.Field)]
+		if outputs == nil {
+			continue
+		}
+		if out, ok := outputs.Load(lo.FromPtr(f.Value)); ok {
+			p.GetProductsBehavior.CalledWithInput.Add(input)
+			if err, ok := out.(error); ok {
+				return nil, err
+			}
+			return out.(*pricing.GetProductsOutput), nil
+		}
+	}
 	return p.GetProductsBehavior.Invoke(input, func(input *pricing.GetProductsInput) (*pricing.GetProductsOutput, error) {
 		// fail if the test doesn't provide specific data which causes our pricing provider to use its static price list
 		return &pricing.GetProductsOutput{}, errors.New("no pricing data provided")
@@ -70,3 +93,31 @@ func NewOnDemandPriceWithCurrency(instanceType string, price float64, currency s
 	ondemand, _ := json.Marshal(data)
 	return string(ondemand)
 }
+
+// NewVolumePrice returns an EBS product, where the product family is one of "Storage", "System Operation" or
+// "Provisioned Throughput"
+func NewVolumePrice(productFamily, volumeType, group string, price float64) string {
+	data := map[string]interface{}{
+		"product": map[string]interface{}{
+			"productFamily": productFamily,
+			"attributes": map[string]interface{}{
+				"volumeApiName": volumeType,
+				"group":         group,
+			},
+		},
+		"terms": map[string]interface{}{
+			"OnDemand": map[string]interface{}{
+				"JRTCKXETXF.foo": map[string]interface{}{
+					"offerTermCode": "JRTCKXETXF",
+					"priceDimensions": map[string]interface{}{
+						"JRTCKXETXF.foo.bar": map[string]interface{}{
+							"pricePerUnit": map[string]interface{}{"USD": fmt.Sprintf("%f", price)},
+						},
+					},
+				},
+			},
+		},
+	}
+	product, _ := json.Marshal(data)
+	return string(product)
+}
@@ -0,0 +1,40 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+type Route53API struct {
+	sdk.Route53API
+	ChangeResourceRecordSetsBehavior MockedFunction[route53.ChangeResourceRecordSetsInput, route53.ChangeResourceRecordSetsOutput]
+}
+
+func (r *Route53API) Reset() {
+	r.ChangeResourceRecordSetsBehavior.Reset()
+}
+
+func (r *Route53API) ChangeResourceRecordSets(_ context.Context, input *route53.ChangeResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	return r.ChangeResourceRecordSetsBehavior.Invoke(input, func(_ *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error) {
+		return &route53.ChangeResourceRecordSetsOutput{ChangeInfo: &route53types.ChangeInfo{Id: aws.String("/change/C0123456789"), Status: route53types.ChangeStatusPending}}, nil
+	})
+}
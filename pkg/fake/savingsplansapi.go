@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/savingsplans"
+	savingsplanstypes "github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+type SavingsPlansAPI struct {
+	sdk.SavingsPlansAPI
+	DescribeSavingsPlansBehavior     MockedFunction[savingsplans.DescribeSavingsPlansInput, savingsplans.DescribeSavingsPlansOutput]
+	DescribeSavingsPlanRatesBehavior MockedFunction[savingsplans.DescribeSavingsPlanRatesInput, savingsplans.DescribeSavingsPlanRatesOutput]
+}
+
+func (s *SavingsPlansAPI) Reset() {
+	s.DescribeSavingsPlansBehavior.Reset()
+	s.DescribeSavingsPlanRatesBehavior.Reset()
+}
+
+func (s *SavingsPlansAPI) DescribeSavingsPlans(_ context.Context, input *savingsplans.DescribeSavingsPlansInput, _ ...func(*savingsplans.Options)) (*savingsplans.DescribeSavingsPlansOutput, error) {
+	return s.DescribeSavingsPlansBehavior.Invoke(input, func(_ *savingsplans.DescribeSavingsPlansInput) (*savingsplans.DescribeSavingsPlansOutput, error) {
+		return &savingsplans.DescribeSavingsPlansOutput{}, nil
+	})
+}
+
+func (s *SavingsPlansAPI) DescribeSavingsPlanRates(_ context.Context, input *savingsplans.DescribeSavingsPlanRatesInput, _ ...func(*savingsplans.Options)) (*savingsplans.DescribeSavingsPlanRatesOutput, error) {
+	return s.DescribeSavingsPlanRatesBehavior.Invoke(input, func(input *savingsplans.DescribeSavingsPlanRatesInput) (*savingsplans.DescribeSavingsPlanRatesOutput, error) {
+		return &savingsplans.DescribeSavingsPlanRatesOutput{SavingsPlanId: input.SavingsPlanId}, nil
+	})
+}
+
+// NewSavingsPlanRate returns the rate for a Linux instance type in the given region, as returned by
+// DescribeSavingsPlanRates
+func NewSavingsPlanRate(instanceType string, region string, rate float64) savingsplanstypes.SavingsPlanRate {
+	return savingsplanstypes.SavingsPlanRate{
+		Rate:        aws.String(fmt.Sprintf("%f", rate)),
+		Currency:    savingsplanstypes.CurrencyCodeUsd,
+		Unit:        savingsplanstypes.SavingsPlanRateUnitHours,
+		ProductType: savingsplanstypes.SavingsPlanProductTypeEc2,
+		Operation:   aws.String("RunInstances"),
+		Properties: []savingsplanstypes.SavingsPlanRateProperty{
+			{Name: savingsplanstypes.SavingsPlanRatePropertyKeyInstanceType, Value: aws.String(instanceType)},
+			{Name: savingsplanstypes.SavingsPlanRatePropertyKeyRegion, Value: aws.String(region)},
+			{Name: savingsplanstypes.SavingsPlanRatePropertyKeyTenancy, Value: aws.String("shared")},
+			{Name: savingsplanstypes.SavingsPlanRatePropertyKeyProductDescription, Value: aws.String("Linux/UNIX")},
+		},
+	}
+}
@@ -17,6 +17,7 @@ package fake
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/Pallinder/go-randomdata"
 	"github.com/samber/lo"
@@ -77,6 +78,26 @@ func (a SSMAPI) GetParameter(_ context.Context, input *ssm.GetParameterInput, _
 	}, nil
 }
 
+// GetParametersByPath returns the parameters in Parameters under the path. Unlike GetParameter, no default parameters
+// are generated.
+func (a SSMAPI) GetParametersByPath(_ context.Context, input *ssm.GetParametersByPathInput, _ ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	if a.WantErr != nil {
+		return &ssm.GetParametersByPathOutput{}, a.WantErr
+	}
+	path := strings.TrimSuffix(lo.FromPtr(input.Path), "/") + "/"
+	var parameters []ssmtypes.Parameter
+	for name, value := range a.Parameters {
+		if !strings.HasPrefix(name, path) {
+			continue
+		}
+		if !lo.FromPtr(input.Recursive) && strings.Contains(strings.TrimPrefix(name, path), "/") {
+			continue
+		}
+		parameters = append(parameters, ssmtypes.Parameter{Name: lo.ToPtr(name), Value: lo.ToPtr(value)})
+	}
+	return &ssm.GetParametersByPathOutput{Parameters: parameters}, nil
+}
+
 func (a *SSMAPI) Reset() {
 	a.Parameters = nil
 	a.GetParameterOutput = nil
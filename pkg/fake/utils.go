@@ -17,6 +17,7 @@ package fake
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/Pallinder/go-randomdata"
@@ -53,6 +54,14 @@ func SubnetID() string {
 	return fmt.Sprintf("subnet-%s", randomdata.Alphanumeric(17))
 }
 
+func NetworkInterfaceID() string {
+	return fmt.Sprintf("eni-%s", strings.ToLower(randomdata.Alphanumeric(17)))
+}
+
+func ElasticIPAllocationID() string {
+	return fmt.Sprintf("eipalloc-%s", strings.ToLower(randomdata.Alphanumeric(17)))
+}
+
 func InstanceProfileID() string {
 	return fmt.Sprintf("instanceprofile-%s", randomdata.Alphanumeric(17))
 }
@@ -89,16 +98,29 @@ func SubnetsFromFleetRequest(createFleetInput *ec2.CreateFleetInput) []string {
 // FilterDescribeSecurtyGroups filters the passed in security groups based on the filters passed in.
 // Filters are chained with a logical "AND"
 func FilterDescribeSecurtyGroups(sgs []ec2types.SecurityGroup, filters []ec2types.Filter) []ec2types.SecurityGroup {
+	vpcFilters, filters := lo.FilterReject(filters, func(filter ec2types.Filter, _ int) bool {
+		return aws.ToString(filter.Name) == "vpc-id"
+	})
 	return lo.Filter(sgs, func(group ec2types.SecurityGroup, _ int) bool {
-		return Filter(filters, *group.GroupId, *group.GroupName, "", "", group.Tags)
+		return lo.EveryBy(vpcFilters, func(filter ec2types.Filter) bool {
+			return lo.Contains(filter.Values, aws.ToString(group.VpcId))
+		}) && Filter(filters, aws.ToString(group.GroupId), aws.ToString(group.GroupName), "", "", group.Tags)
 	})
 }
 
 // FilterDescribeSubnets filters the passed in subnets based on the filters passed in.
 // Filters are chained with a logical "AND"
 func FilterDescribeSubnets(subnets []ec2types.Subnet, filters []ec2types.Filter) []ec2types.Subnet {
+	cidrFilters, filters := lo.FilterReject(filters, func(filter ec2types.Filter, _ int) bool {
+		return aws.ToString(filter.Name) == "cidr-block" || aws.ToString(filter.Name) == "ipv6-cidr-block-association.ipv6-cidr-block"
+	})
 	return lo.Filter(subnets, func(subnet ec2types.Subnet, _ int) bool {
-		return Filter(filters, *subnet.SubnetId, "", "", "", subnet.Tags)
+		cidrs := append([]string{aws.ToString(subnet.CidrBlock)}, lo.Map(subnet.Ipv6CidrBlockAssociationSet, func(a ec2types.SubnetIpv6CidrBlockAssociation, _ int) string {
+			return aws.ToString(a.Ipv6CidrBlock)
+		})...)
+		return lo.EveryBy(cidrFilters, func(filter ec2types.Filter) bool {
+			return lo.Some(filter.Values, cidrs)
+		}) && Filter(filters, *subnet.SubnetId, "", "", "", subnet.Tags)
 	})
 }
 
@@ -113,8 +135,13 @@ func FilterDescribeCapacityReservations(crs []ec2types.CapacityReservation, ids
 }
 
 func FilterDescribeImages(images []ec2types.Image, filters []ec2types.Filter) []ec2types.Image {
+	architectureFilters, filters := lo.FilterReject(filters, func(filter ec2types.Filter, _ int) bool {
+		return aws.ToString(filter.Name) == "architecture"
+	})
 	return lo.Filter(images, func(image ec2types.Image, _ int) bool {
-		return Filter(filters, *image.ImageId, *image.Name, "", string(image.State), image.Tags)
+		return lo.EveryBy(architectureFilters, func(filter ec2types.Filter) bool {
+			return lo.Contains(filter.Values, string(image.Architecture))
+		}) && Filter(filters, *image.ImageId, *image.Name, "", string(image.State), image.Tags)
 	})
 }
 
@@ -128,7 +155,7 @@ func Filter(filters []ec2types.Filter, id, name, owner, state string, tags []ec2
 					return true
 				}
 			}
-		case filterName == "subnet-id" || filterName == "group-id" || filterName == "image-id":
+		case filterName == "subnet-id" || filterName == "group-id" || filterName == "image-id" || filterName == "allocation-id":
 			for _, val := range filter.Values {
 				if id == val {
 					return true
@@ -136,7 +163,7 @@ func Filter(filters []ec2types.Filter, id, name, owner, state string, tags []ec2
 			}
 		case filterName == "group-name" || filterName == "name":
 			for _, val := range filter.Values {
-				if name == val {
+				if matchWildcards(val, name) {
 					return true
 				}
 			}
@@ -157,6 +184,11 @@ func Filter(filters []ec2types.Filter, id, name, owner, state string, tags []ec2
 	})
 }
 
+// matchWildcards returns whether the value matches a filter value, which may contain the wildcards '*' and '?'
+func matchWildcards(pattern, value string) bool {
+	return regexp.MustCompile("^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern)) + "$").MatchString(value)
+}
+
 // matchTags is a predicate that matches a slice of tags with a tag:<key> or tag-keys filter
 // nolint: gocyclo
 func matchTags(tags []ec2types.Tag, filter ec2types.Filter) bool {
@@ -189,7 +221,7 @@ func MakeInstances() []ec2types.InstanceTypeInfo {
 	ctx := options.ToContext(context.Background(), &options.Options{IsolatedVPC: true})
 	// Use keys from the static pricing data so that we guarantee pricing for the data
 	// Create uniform instance data so all of them schedule for a given pod
-	for _, it := range pricing.NewDefaultProvider(ctx, nil, nil, "us-east-1").InstanceTypes() {
+	for _, it := range pricing.NewDefaultProvider(ctx, nil, nil, nil, nil, "us-east-1").InstanceTypes() {
 		instanceTypes = append(instanceTypes, ec2types.InstanceTypeInfo{
 			InstanceType: it,
 			ProcessorInfo: &ec2types.ProcessorInfo{
@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Subsystems which refresh the data used to provision nodes in the background. Provisioning continues with the last
+// data refreshed by a failing subsystem, which may be stale.
+const (
+	SubsystemInstanceTypes         = "InstanceTypes"
+	SubsystemInstanceTypeOfferings = "InstanceTypeOfferings"
+	SubsystemPricing               = "Pricing"
+	SubsystemAMIs                  = "AMIs"
+)
+
+// State is the health of a subsystem's most recent refreshes
+type State struct {
+	// Err is the error returned by the most recent refresh, if it failed
+	Err error
+	// ConsecutiveFailures is the number of refreshes which have failed since the last successful refresh
+	ConsecutiveFailures int
+	// LastSuccess is when the subsystem was last refreshed successfully. It's zero if no refresh has succeeded.
+	LastSuccess time.Time
+}
+
+// Tracker records the outcome of each subsystem's refreshes, so that a failing subsystem is reported rather than
+// provisioning silently continuing with stale data
+type Tracker struct {
+	clk    clock.Clock
+	mu     sync.RWMutex
+	states map[string]State
+}
+
+func NewTracker(clk clock.Clock) *Tracker {
+	return &Tracker{
+		clk:    clk,
+		states: map[string]State{},
+	}
+}
+
+// Observe records the outcome of a subsystem's refresh. Observing a nil tracker is a no-op.
+func (t *Tracker) Observe(subsystem string, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.states[subsystem]
+	if err != nil {
+		state.Err = err
+		state.ConsecutiveFailures++
+	} else {
+		state = State{LastSuccess: t.clk.Now()}
+	}
+	t.states[subsystem] = state
+	SubsystemDegraded.Set(float64(lo.Ternary(err != nil, 1, 0)), map[string]string{subsystemLabel: subsystem})
+}
+
+// Degraded returns the state of each subsystem whose most recent refresh failed
+func (t *Tracker) Degraded() map[string]State {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return lo.PickBy(t.states, func(_ string, state State) bool { return state.Err != nil })
+}
+
+// DegradedMessage describes the degraded subsystems and their errors, ordered by subsystem. It's empty if no
+// subsystem is degraded.
+func (t *Tracker) DegradedMessage() string {
+	degraded := t.Degraded()
+	subsystems := lo.Keys(degraded)
+	sort.Strings(subsystems)
+	return strings.Join(lo.Map(subsystems, func(subsystem string, _ int) string {
+		return fmt.Sprintf("%s: %s", subsystem, degraded[subsystem].Err)
+	}), "; ")
+}
+
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.states = map[string]State{}
+}
+
+// Guard wraps a refresh so that a panic is returned as an error rather than crashing the controller. Refreshes are
+// often run concurrently in their own goroutines, where a panic can't be recovered by the controller runtime.
+func Guard(f func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.FromContext(ctx).Error(fmt.Errorf("%v", r), "recovered from panic", "stack", string(debug.Stack()))
+				err = fmt.Errorf("panic, %v", r)
+			}
+		}()
+		return f(ctx)
+	}
+}
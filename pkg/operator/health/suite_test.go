@@ -0,0 +1,82 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	clock "k8s.io/utils/clock/testing"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestHealth(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Health")
+}
+
+var _ = Describe("Tracker", func() {
+	var clk *clock.FakeClock
+	var tracker *health.Tracker
+	BeforeEach(func() {
+		clk = clock.NewFakeClock(time.Now())
+		tracker = health.NewTracker(clk)
+	})
+	It("should report a subsystem as degraded until it's refreshed successfully", func() {
+		tracker.Observe(health.SubsystemPricing, nil)
+		lastSuccess := clk.Now()
+		clk.Step(time.Hour)
+		tracker.Observe(health.SubsystemPricing, fmt.Errorf("failed"))
+		tracker.Observe(health.SubsystemPricing, fmt.Errorf("failed again"))
+
+		degraded := tracker.Degraded()
+		Expect(degraded).To(HaveKey(health.SubsystemPricing))
+		Expect(degraded[health.SubsystemPricing].ConsecutiveFailures).To(Equal(2))
+		Expect(degraded[health.SubsystemPricing].Err).To(MatchError("failed again"))
+		Expect(degraded[health.SubsystemPricing].LastSuccess).To(Equal(lastSuccess))
+
+		tracker.Observe(health.SubsystemPricing, nil)
+		Expect(tracker.Degraded()).To(BeEmpty())
+	})
+	It("should describe the degraded subsystems in order", func() {
+		tracker.Observe(health.SubsystemPricing, fmt.Errorf("throttled"))
+		tracker.Observe(health.SubsystemInstanceTypes, nil)
+		tracker.Observe(health.SubsystemAMIs, fmt.Errorf("not found"))
+		Expect(tracker.DegradedMessage()).To(Equal("AMIs: not found; Pricing: throttled"))
+	})
+	It("should ignore a nil tracker", func() {
+		var nilTracker *health.Tracker
+		nilTracker.Observe(health.SubsystemPricing, fmt.Errorf("failed"))
+		Expect(nilTracker.Degraded()).To(BeEmpty())
+		Expect(nilTracker.DegradedMessage()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Guard", func() {
+	It("should return a panic as an error", func() {
+		err := health.Guard(func(context.Context) error { panic("nil map") })(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("nil map")))
+	})
+	It("should return the refresh's error", func() {
+		err := health.Guard(func(context.Context) error { return fmt.Errorf("failed") })(context.Background())
+		Expect(err).To(MatchError("failed"))
+	})
+})
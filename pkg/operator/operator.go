@@ -16,22 +16,30 @@ package operator
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	stdlog "log"
 	"net"
+	"net/http"
 	"os"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/imagebuilder"
+	awspricing "github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/savingsplans"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/aws/smithy-go"
@@ -46,6 +54,7 @@ import (
 	clinetconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/operator"
@@ -55,8 +64,11 @@ import (
 	"sigs.k8s.io/karpenter/pkg/apis"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/batcher"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accountsettings"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
@@ -64,10 +76,13 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/reservedinstance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	ssmp "github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
@@ -83,10 +98,14 @@ type Operator struct {
 	SSMCache                    *cache.Cache
 	ValidationCache             *cache.Cache
 	SubnetProvider              subnet.Provider
+	VPCEndpointProvider         vpcendpoint.Provider
 	SecurityGroupProvider       securitygroup.Provider
 	InstanceProfileProvider     instanceprofile.Provider
 	AMIProvider                 amifamily.Provider
 	AMIResolver                 amifamily.Resolver
+	AMIHashStore                *amifamily.HashStore
+	InvalidationBus             *awscache.InvalidationBus
+	HealthTracker               *health.Tracker
 	LaunchTemplateProvider      launchtemplate.Provider
 	PricingProvider             pricing.Provider
 	VersionProvider             *version.DefaultProvider
@@ -94,6 +113,9 @@ type Operator struct {
 	InstanceProvider            instance.Provider
 	SSMProvider                 ssmp.Provider
 	CapacityReservationProvider capacityreservation.Provider
+	ReservedInstanceProvider    reservedinstance.Provider
+	ZoneProvider                zone.Provider
+	AccountSettingsProvider     accountsettings.Provider
 	EC2API                      *ec2.Client
 }
 
@@ -142,27 +164,44 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 	} else {
 		log.FromContext(ctx).WithValues("kube-dns-ip", kubeDNSIP).V(1).Info("discovered kube dns")
 	}
-	unavailableOfferingsCache := awscache.NewUnavailableOfferings()
+	invalidationBus := awscache.NewInvalidationBus()
+	healthTracker := health.NewTracker(operator.Clock)
+	unavailableOfferingsCache := awscache.NewUnavailableOfferings(invalidationBus)
 	ssmCache := cache.New(awscache.SSMCacheTTL, awscache.DefaultCleanupInterval)
 	validationCache := cache.New(awscache.ValidationTTL, awscache.DefaultCleanupInterval)
 
-	subnetProvider := subnet.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval))
+	subnetProvider := subnet.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.ExhaustedSubnetsTTL, awscache.UnavailableOfferingsCleanupInterval))
+	vpcEndpointProvider := vpcendpoint.NewDefaultProvider(cfg.Region, ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
 	securityGroupProvider := securitygroup.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
 	instanceProfileProvider := instanceprofile.NewDefaultProvider(cfg.Region, iam.NewFromConfig(cfg), cache.New(awscache.InstanceProfileTTL, awscache.DefaultCleanupInterval))
-	pricingProvider := pricing.NewDefaultProvider(
-		ctx,
-		pricing.NewAPI(cfg),
-		ec2api,
-		cfg.Region,
-	)
+	pricingAPI := pricing.NewAPI(cfg, func(o *awspricing.Options) {
+		// Requests are still signed for the region the pricing API is called in, which isn't necessarily the cluster's
+		if endpoint := options.FromContext(ctx).PricingAPIEndpoint; endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+	zoneProvider := zone.NewDefaultProvider(ec2api)
+	pricingProvider, err := pricing.NewProvider(ctx, options.FromContext(ctx).PricingProvider, pricing.ProviderOptions{
+		Region:          cfg.Region,
+		EC2API:          ec2api,
+		PricingAPI:      pricingAPI,
+		SavingsPlansAPI: savingsplans.NewFromConfig(cfg),
+		ZoneProvider:    zoneProvider,
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed constructing pricing provider")
+		os.Exit(1)
+	}
 	versionProvider := version.NewDefaultProvider(operator.KubernetesInterface, eksapi)
 	// Ensure we're able to hydrate the version before starting any reliant controllers.
 	// Version updates are hydrated asynchronously after this, in the event of a failure
 	// the previously resolved value will be used.
 	lo.Must0(versionProvider.UpdateVersion(ctx))
 	ssmProvider := ssmp.NewDefaultProvider(ssm.NewFromConfig(cfg), ssmCache)
-	amiProvider := amifamily.NewDefaultProvider(operator.Clock, versionProvider, ssmProvider, ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+	amiParameterProvider := ssmp.NewDefaultProvider(ssm.NewFromConfig(WithAssumedRole(cfg, options.FromContext(ctx).AMIParameterRoleARN)), cache.New(awscache.AMIParameterTTL, awscache.DefaultCleanupInterval))
+	amiProvider := amifamily.NewDefaultProvider(operator.Clock, versionProvider, ssmProvider, amiParameterProvider, ec2api, imagebuilder.NewFromConfig(cfg), cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
 	amiResolver := amifamily.NewDefaultResolver()
+	amiHashStore := amifamily.NewHashStore()
 	launchTemplateProvider := launchtemplate.NewDefaultProvider(
 		ctx,
 		cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval),
@@ -175,6 +214,8 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		operator.Elected(),
 		kubeDNSIP,
 		clusterEndpoint,
+		operator.GetAPIReader(),
+		os.Getenv("SYSTEM_NAMESPACE"),
 	)
 	capacityReservationProvider := capacityreservation.NewProvider(
 		ec2api,
@@ -182,16 +223,27 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval),
 		cache.New(awscache.CapacityReservationAvailabilityTTL, awscache.DefaultCleanupInterval),
 	)
+	reservedInstanceProvider := reservedinstance.NewDefaultProvider(ec2api)
+	accountSettingsProvider := accountsettings.NewDefaultProvider(ec2api, cache.New(awscache.AccountSettingsTTL, awscache.DefaultCleanupInterval))
 	instanceTypeProvider := instancetype.NewDefaultProvider(
-		cache.New(awscache.InstanceTypesZonesAndOfferingsTTL, awscache.DefaultCleanupInterval),
+		awscache.NewLRU(
+			awscache.InstanceTypesCacheName,
+			awscache.InstanceTypesZonesAndOfferingsTTL,
+			options.FromContext(ctx).InstanceTypesCacheMaxEntries,
+			options.FromContext(ctx).InstanceTypesCacheMaxBytes,
+			instancetype.EstimateInstanceTypesSize,
+		),
 		cache.New(awscache.InstanceTypesZonesAndOfferingsTTL, awscache.DefaultCleanupInterval),
 		cache.New(awscache.DiscoveredCapacityCacheTTL, awscache.DefaultCleanupInterval),
 		ec2api,
 		subnetProvider,
 		pricingProvider,
 		capacityReservationProvider,
+		reservedInstanceProvider,
 		unavailableOfferingsCache,
-		instancetype.NewDefaultResolver(cfg.Region),
+		instancetype.NewRegisteredResolver(instancetype.NewDefaultResolver(cfg.Region)),
+		amiHashStore,
+		invalidationBus,
 	)
 	instanceProvider := instance.NewDefaultProvider(
 		ctx,
@@ -199,14 +251,25 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		ec2api,
 		unavailableOfferingsCache,
 		subnetProvider,
+		securityGroupProvider,
 		launchTemplateProvider,
 		capacityReservationProvider,
+		reservedInstanceProvider,
+		batcher.NewLaunchLimiter(options.FromContext(ctx).MaxConcurrentLaunches, options.FromContext(ctx).MaxLaunchesPerMinute),
 	)
 
 	// Setup field indexers on instanceID -- specifically for the interruption controller
 	if options.FromContext(ctx).InterruptionQueue != "" {
 		SetupIndexers(ctx, operator.Manager)
 	}
+	if options.FromContext(ctx).SecureMetricsBindAddress != "" {
+		server, err := NewSecureMetricsServer(ctx, operator.GetConfig(), operator.GetHTTPClient())
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed constructing secure metrics server")
+			os.Exit(1)
+		}
+		lo.Must0(operator.Add(server))
+	}
 	return ctx, &Operator{
 		Operator:                    operator,
 		Config:                      cfg,
@@ -214,10 +277,14 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		SSMCache:                    ssmCache,
 		ValidationCache:             validationCache,
 		SubnetProvider:              subnetProvider,
+		VPCEndpointProvider:         vpcEndpointProvider,
 		SecurityGroupProvider:       securityGroupProvider,
 		InstanceProfileProvider:     instanceProfileProvider,
 		AMIProvider:                 amiProvider,
 		AMIResolver:                 amiResolver,
+		AMIHashStore:                amiHashStore,
+		InvalidationBus:             invalidationBus,
+		HealthTracker:               healthTracker,
 		VersionProvider:             versionProvider,
 		LaunchTemplateProvider:      launchTemplateProvider,
 		PricingProvider:             pricingProvider,
@@ -225,6 +292,9 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		InstanceProvider:            instanceProvider,
 		SSMProvider:                 ssmProvider,
 		CapacityReservationProvider: capacityReservationProvider,
+		ReservedInstanceProvider:    reservedInstanceProvider,
+		ZoneProvider:                zoneProvider,
+		AccountSettingsProvider:     accountSettingsProvider,
 		EC2API:                      ec2api,
 	}
 }
@@ -238,6 +308,17 @@ func WithUserAgent(cfg aws.Config) aws.Config {
 	return cfg
 }
 
+// WithAssumedRole returns a copy of the config whose credentials are retrieved by assuming the role with the controller's
+// own credentials. The config is returned unchanged if no role is given.
+func WithAssumedRole(cfg aws.Config, roleARN string) aws.Config {
+	if roleARN == "" {
+		return cfg
+	}
+	assumed := cfg.Copy()
+	assumed.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN))
+	return assumed
+}
+
 // CheckEC2Connectivity makes a dry-run call to DescribeInstanceTypes.  If it fails, we provide an early indicator that we
 // are having issues connecting to the EC2 API.
 func CheckEC2Connectivity(ctx context.Context, api sdk.EC2API) error {
@@ -265,6 +346,34 @@ func ResolveClusterEndpoint(ctx context.Context, eksAPI sdk.EKSAPI) (string, err
 	return *out.Cluster.Endpoint, nil
 }
 
+// NewSecureMetricsServer returns a metrics server which serves the same registry as the plaintext metrics server over
+// TLS. If a client CA file is configured, clients must present a certificate signed by one of its CAs.
+func NewSecureMetricsServer(ctx context.Context, restConfig *rest.Config, httpClient *http.Client) (metricsserver.Server, error) {
+	opts := metricsserver.Options{
+		SecureServing: true,
+		BindAddress:   options.FromContext(ctx).SecureMetricsBindAddress,
+		CertDir:       options.FromContext(ctx).SecureMetricsCertDir,
+		TLSOpts: []func(*tls.Config){func(c *tls.Config) {
+			c.MinVersion = tls.VersionTLS12
+		}},
+	}
+	if caFile := options.FromContext(ctx).SecureMetricsClientCAFile; caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client ca file, %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("client ca file %s doesn't contain any PEM encoded certificates", caFile)
+		}
+		opts.TLSOpts = append(opts.TLSOpts, func(c *tls.Config) {
+			c.ClientCAs = pool
+			c.ClientAuth = tls.RequireAndVerifyClientCert
+		})
+	}
+	return metricsserver.NewServer(opts, restConfig, httpClient)
+}
+
 func GetCABundle(ctx context.Context, restConfig *rest.Config) (*string, error) {
 	// Discover CA Bundle from the REST client. We could alternatively
 	// have used the simpler client-go InClusterConfig() method.
@@ -20,7 +20,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/samber/lo"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	"sigs.k8s.io/karpenter/pkg/utils/env"
 
@@ -34,14 +37,48 @@ func init() {
 type optionsKey struct{}
 
 type Options struct {
-	ClusterCABundle         string
-	ClusterName             string
-	ClusterEndpoint         string
-	IsolatedVPC             bool
-	EKSControlPlane         bool
-	VMMemoryOverheadPercent float64
-	InterruptionQueue       string
-	ReservedENIs            int
+	ClusterCABundle                 string
+	ClusterName                     string
+	ClusterEndpoint                 string
+	IsolatedVPC                     bool
+	EKSControlPlane                 bool
+	VMMemoryOverheadPercent         float64
+	InterruptionQueue               string
+	ReservedENIs                    int
+	InstanceTypesCacheMaxEntries    int
+	InstanceTypesCacheMaxBytes      int64
+	SavingsPlansPricing             bool
+	ReservedInstanceCoverage        bool
+	PricingOverridesConfigMap       string
+	TagKeyPrefix                    string
+	SpotPricePercentile             int
+	BulkPricingURL                  string
+	BulkPricingRefreshInterval      time.Duration
+	RequiredVPCEndpoints            string
+	ReservedCapacityOnDemandPricing bool
+	PricingSnapshotConfigMap        string
+	NodeDNSHostedZoneID             string
+	NodeDNSDomain                   string
+	DeterministicOfferingSelection  bool
+	IncludeVolumeCost               bool
+	PricingProvider                 string
+	ProviderInfoConfigMap           string
+	PricingEndpoint                 string
+	IncludeLicenseCost              bool
+	PricingRefreshInterval          time.Duration
+	PricingRefreshJitter            float64
+	PricingRetryBackoff             time.Duration
+	PricingAPIEndpoint              string
+	MaxDescribedImages              int
+	SecureMetricsBindAddress        string
+	SecureMetricsCertDir            string
+	SecureMetricsClientCAFile       string
+	AMIParameterRoleARN             string
+	MaxConcurrentLaunches           int
+	MaxLaunchesPerMinute            int
+	SpotInterruptionDrainDelay      time.Duration
+	PodENI                          bool
+	PrefixDelegation                bool
 }
 
 func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
@@ -53,6 +90,40 @@ func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
 	fs.Float64Var(&o.VMMemoryOverheadPercent, "vm-memory-overhead-percent", utils.WithDefaultFloat64("VM_MEMORY_OVERHEAD_PERCENT", 0.075), "The VM memory overhead as a percent that will be subtracted from the total memory for all instance types when cached information is unavailable.")
 	fs.StringVar(&o.InterruptionQueue, "interruption-queue", env.WithDefaultString("INTERRUPTION_QUEUE", ""), "Interruption queue is the name of the SQS queue used for processing interruption events from EC2. Interruption handling is disabled if not specified. Enabling interruption handling may require additional permissions on the controller service account. Additional permissions are outlined in the docs.")
 	fs.IntVar(&o.ReservedENIs, "reserved-enis", env.WithDefaultInt("RESERVED_ENIS", 0), "Reserved ENIs are not included in the calculations for max-pods or kube-reserved. This is most often used in the VPC CNI custom networking setup https://docs.aws.amazon.com/eks/latest/userguide/cni-custom-network.html.")
+	fs.IntVar(&o.InstanceTypesCacheMaxEntries, "instance-types-cache-max-entries", env.WithDefaultInt("INSTANCE_TYPES_CACHE_MAX_ENTRIES", 256), "The maximum number of resolved instance type sets to cache. Each distinct EC2NodeClass configuration requires its own entry. When the limit is reached, the least recently used entry is evicted. Set to 0 to disable the limit.")
+	fs.Int64Var(&o.InstanceTypesCacheMaxBytes, "instance-types-cache-max-bytes", env.WithDefaultInt64("INSTANCE_TYPES_CACHE_MAX_BYTES", 0), "The maximum estimated size, in bytes, of the resolved instance types cache. When the limit is reached, the least recently used entry is evicted. Set to 0 to disable the limit.")
+	fs.BoolVarWithEnv(&o.SavingsPlansPricing, "savings-plans-pricing", "SAVINGS_PLANS_PRICING", false, "If true, on-demand capacity covered by the account's active EC2 Instance and Compute Savings Plans is priced at the Savings Plans rate. This requires the savingsplans:DescribeSavingsPlans and savingsplans:DescribeSavingsPlanRates permissions.")
+	fs.BoolVarWithEnv(&o.ReservedInstanceCoverage, "reserved-instance-coverage", "RESERVED_INSTANCE_COVERAGE", false, "If true, on-demand offerings which would be covered by the account's unused Linux Reserved Instances are preferred over other offerings when launching instances. This requires the ec2:DescribeReservedInstances permission.")
+	fs.StringVar(&o.PricingOverridesConfigMap, "pricing-overrides-configmap", env.WithDefaultString("PRICING_OVERRIDES_CONFIGMAP", ""), "The name of a ConfigMap in Karpenter's namespace containing price overrides for instance types, which take precedence over prices retrieved from the AWS pricing and EC2 APIs. If not set, prices aren't overridden.")
+	fs.StringVar(&o.TagKeyPrefix, "tag-key-prefix", env.WithDefaultString("TAG_KEY_PREFIX", ""), "[PREVIEW] If set, replaces the domain of the tag keys Karpenter uses to record the NodePool, EC2NodeClass, and NodeClaim of the resources it creates, e.g. karpenter.sh/nodepool becomes <prefix>/nodepool.")
+	fs.IntVar(&o.SpotPricePercentile, "spot-price-percentile", env.WithDefaultInt("SPOT_PRICE_PERCENTILE", 0), "If set, spot offerings are priced at this percentile of their spot price over the previous 7 days rather than their current spot price, so that instance types whose spot price is volatile are less likely to be launched. Must be between 0 and 100. If not set, the current spot price is used.")
+	fs.StringVar(&o.BulkPricingURL, "bulk-pricing-url", env.WithDefaultString("BULK_PRICING_URL", ""), "The URL of an AWS Price List bulk offer file for EC2 in the current region, which on-demand prices are retrieved from instead of the pricing API. If not set, the public offer file is used in regions where the pricing API isn't available.")
+	fs.DurationVar(&o.BulkPricingRefreshInterval, "bulk-pricing-refresh-interval", env.WithDefaultDuration("BULK_PRICING_REFRESH_INTERVAL", 24*time.Hour), "The interval at which on-demand prices are retrieved from the AWS Price List bulk offer file, when it's used. Set to 0 to disable retrieving prices from the offer file.")
+	fs.StringVar(&o.RequiredVPCEndpoints, "required-vpc-endpoints", env.WithDefaultString("REQUIRED_VPC_ENDPOINTS", ""), "A comma separated list of services, e.g. ecr.api,ecr.dkr,s3,sts,ec2, which must be reachable through a VPC endpoint from a zone for nodes to be launched in it. This is intended for private clusters, and requires the ec2:DescribeVpcEndpoints permission. If not set, zones aren't restricted.")
+	fs.BoolVarWithEnv(&o.ReservedCapacityOnDemandPricing, "reserved-capacity-on-demand-pricing", "RESERVED_CAPACITY_ON_DEMAND_PRICING", false, "If true, offerings backed by capacity reservations are priced at the on-demand price of their instance type. Otherwise, they're priced at close to zero since the reservation is already paid for, so that reserved capacity is used before other offerings, including cheaper spot offerings.")
+	fs.StringVar(&o.PricingSnapshotConfigMap, "pricing-snapshot-configmap", env.WithDefaultString("PRICING_SNAPSHOT_CONFIGMAP", ""), "The name of a ConfigMap in Karpenter's namespace which the last retrieved prices are persisted to, so that they're used after a restart until pricing is next retrieved. The ConfigMap is created if it doesn't exist. If not set, the initial prices embedded at build time are used after a restart.")
+	fs.StringVar(&o.NodeDNSHostedZoneID, "node-dns-hosted-zone-id", env.WithDefaultString("NODE_DNS_HOSTED_ZONE_ID", ""), "The ID of a Route 53 hosted zone in which A and AAAA records are created for each node. Requires node-dns-domain. If not set, DNS records aren't created for nodes.")
+	fs.StringVar(&o.NodeDNSDomain, "node-dns-domain", env.WithDefaultString("NODE_DNS_DOMAIN", ""), "The domain that node DNS records are created under, which must be within the node-dns-hosted-zone-id hosted zone.")
+	fs.BoolVarWithEnv(&o.DeterministicOfferingSelection, "deterministic-offering-selection", "DETERMINISTIC_OFFERING_SELECTION", false, "If true, offerings and CreateFleet overrides are ordered by instance type, zone and subnet rather than map iteration order, so that simulations and tests using a fake EC2 API make reproducible instance selections. EC2 Fleet's own choice between offerings isn't affected.")
+	fs.BoolVarWithEnv(&o.IncludeVolumeCost, "include-volume-cost", "INCLUDE_VOLUME_COST", false, "If true, the hourly cost of the EBS volumes in an EC2NodeClass's block device mappings is added to the price of each offering, so that the cost of a node's storage is accounted for when choosing and consolidating instances.")
+	fs.StringVar(&o.PricingProvider, "pricing-provider", env.WithDefaultString("PRICING_PROVIDER", "aws"), "The name of the pricing provider used to price offerings. Providers other than the built-in \"aws\" provider must be registered with the controller binary before it starts.")
+	fs.StringVar(&o.ProviderInfoConfigMap, "provider-info-configmap", env.WithDefaultString("PROVIDER_INFO_CONFIGMAP", ""), "The name of a ConfigMap in Karpenter's namespace to which the provider version, partition, region, account, Kubernetes version, and enabled feature gates are written. The ConfigMap isn't written if not specified.")
+	fs.StringVar(&o.PricingEndpoint, "pricing-endpoint", env.WithDefaultString("PRICING_ENDPOINT", ""), "The URL of a rate card which on-demand and spot prices are retrieved from when pricing-provider is \"http\".")
+	fs.BoolVarWithEnv(&o.IncludeLicenseCost, "include-license-cost", "INCLUDE_LICENSE_COST", false, "If true, the hourly cost of the operating system license of an EC2NodeClass's AMIs, such as Windows, RHEL or SUSE, is added to the price of each on-demand and spot offering, so that licensed platforms are priced as they're billed rather than as Linux.")
+	fs.DurationVar(&o.PricingRefreshInterval, "pricing-refresh-interval", env.WithDefaultDuration("PRICING_REFRESH_INTERVAL", 12*time.Hour), "The interval at which on-demand, spot, Savings Plans, volume and license prices are retrieved.")
+	fs.Float64Var(&o.PricingRefreshJitter, "pricing-refresh-jitter", utils.WithDefaultFloat64("PRICING_REFRESH_JITTER", 0), "The maximum fraction of the refresh interval by which each pricing refresh is randomly delayed, so that many clusters refreshing prices don't call the pricing APIs at the same time. Applies to pricing-refresh-interval and bulk-pricing-refresh-interval. Must be between 0 and 1.")
+	fs.DurationVar(&o.PricingRetryBackoff, "pricing-retry-backoff", env.WithDefaultDuration("PRICING_RETRY_BACKOFF", 0), "If set, a failed pricing refresh is retried after this delay, which doubles with each consecutive failure up to the refresh interval. If not set, failed refreshes are retried with the controller's default backoff.")
+	fs.StringVar(&o.PricingAPIEndpoint, "pricing-api-endpoint", env.WithDefaultString("PRICING_API_ENDPOINT", ""), "The URL of the AWS Price List API endpoint, such as an interface VPC endpoint or a proxy. If not set, the public endpoint in the region closest to the cluster's which serves the API is used.")
+	fs.IntVar(&o.MaxDescribedImages, "max-described-images", env.WithDefaultInt("MAX_DESCRIBED_IMAGES", 10000), "The maximum number of images which are described for each AMI selector term when resolving an EC2NodeClass's AMIs. When the limit is reached, the remaining images aren't considered and a warning is logged, so selectors matching very large numbers of images should be narrowed. Set to 0 to disable the limit.")
+	fs.StringVar(&o.SecureMetricsBindAddress, "secure-metrics-bind-address", env.WithDefaultString("SECURE_METRICS_BIND_ADDRESS", ""), "The address the TLS metrics server binds to, such as [::]:8443 to serve metrics over both IPv4 and IPv6. If not set, metrics are only served by the plaintext metrics server.")
+	fs.StringVar(&o.SecureMetricsCertDir, "secure-metrics-cert-dir", env.WithDefaultString("SECURE_METRICS_CERT_DIR", ""), "The directory containing the tls.crt and tls.key served by the TLS metrics server. The certificate is reloaded when it changes. If not set, a self-signed certificate is generated.")
+	fs.StringVar(&o.SecureMetricsClientCAFile, "secure-metrics-client-ca-file", env.WithDefaultString("SECURE_METRICS_CLIENT_CA_FILE", ""), "A CA bundle used to verify client certificates presented to the TLS metrics server. If set, clients must present a certificate signed by one of the CAs.")
+	fs.StringVar(&o.AMIParameterRoleARN, "ami-parameter-role-arn", env.WithDefaultString("AMI_PARAMETER_ROLE_ARN", ""), "The ARN of an IAM role which is assumed to read the SSM parameters referenced by amiSelectorTerms, such as parameters shared from other accounts. If unset, parameters are read with the controller's own credentials.")
+	fs.IntVar(&o.MaxConcurrentLaunches, "max-concurrent-launches", env.WithDefaultInt("MAX_CONCURRENT_LAUNCHES", 0), "The maximum number of instances which are being launched at once. Launches are batched, and a batched CreateFleet call counts each instance it launches, so batches larger than the limit are split into several calls. Launches beyond the limit are queued until in-flight launches complete. Set to 0 to disable the limit.")
+	fs.IntVar(&o.MaxLaunchesPerMinute, "max-launches-per-minute", env.WithDefaultInt("MAX_LAUNCHES_PER_MINUTE", 0), "The maximum number of instances launched per minute, so that large scale-outs stay within the account's EC2 API throttling budget. Launches are batched, and a batched CreateFleet call counts each instance it launches, so batches larger than the limit are split into several calls. Launches beyond the rate are queued, and up to a minute's launches may burst after a quiet period. Set to 0 to disable the limit.")
+	fs.DurationVar(&o.SpotInterruptionDrainDelay, "spot-interruption-drain-delay", env.WithDefaultDuration("SPOT_INTERRUPTION_DRAIN_DELAY", 0), "If set, a replacement is launched as soon as a spot interruption warning is received, and the interrupted node isn't drained until the replacement has initialized or this long after the warning, whichever is first, so that the replacement boots while the interrupted node is still running its pods. Must be less than the 2 minute warning. Requires interruption-queue. If not set, interrupted nodes are drained immediately.")
+	fs.BoolVarWithEnv(&o.PodENI, "pod-eni", "POD_ENI", false, "If true, the VPC CNI's Pod ENI feature, which is used by security groups for pods, is assumed to be enabled. Instance types which support trunking advertise their vpc.amazonaws.com/pod-eni capacity, and the trunk network interface that the VPC resource controller attaches to them is excluded from the calculations for max-pods and kube-reserved.")
+	fs.BoolVarWithEnv(&o.PrefixDelegation, "prefix-delegation", "PREFIX_DELEGATION", false, "If true, the VPC CNI's prefix delegation is assumed to be enabled, and the max-pods of Nitro and bare metal instance types is calculated from the number of IPv4 prefixes their network interfaces can be assigned, rather than individual addresses. This doesn't apply to EC2NodeClasses which set maxPods in their kubelet configuration.")
 }
 
 func (o *Options) Parse(fs *coreoptions.FlagSet, args ...string) error {
@@ -83,3 +154,31 @@ func FromContext(ctx context.Context) *Options {
 	}
 	return retval.(*Options)
 }
+
+// TagKey returns the key of an ownership tag, such as karpenter.sh/nodepool, with the configured tag key prefix applied
+func (o *Options) TagKey(key string) string {
+	return utils.PrefixedTagKey(o.TagKeyPrefix, key)
+}
+
+// TagValue returns the value of an ownership tag, falling back to the unprefixed key for resources which were tagged
+// before the tag key prefix was configured
+func (o *Options) TagValue(tags map[string]string, key string) (string, bool) {
+	if value, ok := tags[o.TagKey(key)]; ok {
+		return value, true
+	}
+	value, ok := tags[key]
+	return value, ok
+}
+
+// TagKeys returns both the prefixed and unprefixed keys of an ownership tag, which should be used when discovering
+// resources that may have been tagged before the tag key prefix was configured
+func (o *Options) TagKeys(key string) []string {
+	return lo.Uniq([]string{o.TagKey(key), key})
+}
+
+// RequiredVPCEndpointServices returns the services which must be reachable through a VPC endpoint from a zone
+func (o *Options) RequiredVPCEndpointServices() []string {
+	return lo.Compact(lo.Map(strings.Split(o.RequiredVPCEndpoints, ","), func(s string, _ int) string {
+		return strings.TrimSpace(s)
+	}))
+}
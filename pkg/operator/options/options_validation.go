@@ -16,7 +16,11 @@ package options
 
 import (
 	"fmt"
+	"net"
 	"net/url"
+	"regexp"
+	"strings"
+	"time"
 
 	"go.uber.org/multierr"
 )
@@ -26,6 +30,18 @@ func (o Options) Validate() error {
 		o.validateEndpoint(),
 		o.validateVMMemoryOverheadPercent(),
 		o.validateReservedENIs(),
+		o.validateInstanceTypesCacheLimits(),
+		o.validateTagKeyPrefix(),
+		o.validateSpotPricePercentile(),
+		o.validateMaxDescribedImages(),
+		o.validateLaunchLimits(),
+		o.validateSpotInterruptionDrainDelay(),
+		o.validateBulkPricing(),
+		o.validatePricingRefresh(),
+		o.validatePricingProvider(),
+		o.validateRequiredVPCEndpoints(),
+		o.validateNodeDNS(),
+		o.validateSecureMetrics(),
 		o.validateRequiredFields(),
 	)
 }
@@ -57,9 +73,155 @@ func (o Options) validateReservedENIs() error {
 	return nil
 }
 
+func (o Options) validateInstanceTypesCacheLimits() error {
+	if o.InstanceTypesCacheMaxEntries < 0 {
+		return fmt.Errorf("instance-types-cache-max-entries cannot be negative")
+	}
+	if o.InstanceTypesCacheMaxBytes < 0 {
+		return fmt.Errorf("instance-types-cache-max-bytes cannot be negative")
+	}
+	return nil
+}
+
+// vpcEndpointServicePattern matches the suffix of a VPC endpoint service name following the region, e.g. ecr.api
+var vpcEndpointServicePattern = regexp.MustCompile(`^[a-z0-9-]+(\.[a-z0-9-]+)*$`)
+
+// tagKeyPrefixPattern matches the characters permitted in EC2 tag keys
+var tagKeyPrefixPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]+$`)
+
+func (o Options) validateTagKeyPrefix() error {
+	if o.TagKeyPrefix == "" {
+		return nil
+	}
+	if !tagKeyPrefixPattern.MatchString(o.TagKeyPrefix) || strings.HasSuffix(o.TagKeyPrefix, "/") {
+		return fmt.Errorf("%q is not a valid tag-key-prefix", o.TagKeyPrefix)
+	}
+	if strings.HasPrefix(strings.ToLower(o.TagKeyPrefix), "aws:") {
+		return fmt.Errorf("tag-key-prefix cannot begin with the reserved \"aws:\" prefix")
+	}
+	// The longest key which the prefix is applied to must fit within the 128 character limit on tag keys
+	if len(o.TagKeyPrefix)+len("/ec2nodeclass") > 128 {
+		return fmt.Errorf("tag-key-prefix must be at most %d characters", 128-len("/ec2nodeclass"))
+	}
+	return nil
+}
+
+func (o Options) validateSpotPricePercentile() error {
+	if o.SpotPricePercentile < 0 || o.SpotPricePercentile > 100 {
+		return fmt.Errorf("spot-price-percentile must be between 0 and 100")
+	}
+	return nil
+}
+
+func (o Options) validateMaxDescribedImages() error {
+	if o.MaxDescribedImages < 0 {
+		return fmt.Errorf("max-described-images cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateLaunchLimits() error {
+	if o.MaxConcurrentLaunches < 0 {
+		return fmt.Errorf("max-concurrent-launches cannot be negative")
+	}
+	if o.MaxLaunchesPerMinute < 0 {
+		return fmt.Errorf("max-launches-per-minute cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateSpotInterruptionDrainDelay() error {
+	if o.SpotInterruptionDrainDelay < 0 || o.SpotInterruptionDrainDelay >= 2*time.Minute {
+		return fmt.Errorf("spot-interruption-drain-delay must be at least 0 and less than 2m")
+	}
+	return nil
+}
+
 func (o Options) validateRequiredFields() error {
 	if o.ClusterName == "" {
 		return fmt.Errorf("missing field, cluster-name")
 	}
 	return nil
 }
+
+func (o Options) validateBulkPricing() error {
+	if o.BulkPricingRefreshInterval < 0 {
+		return fmt.Errorf("bulk-pricing-refresh-interval cannot be negative")
+	}
+	if o.BulkPricingURL == "" {
+		return nil
+	}
+	u, err := url.Parse(o.BulkPricingURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%q is not a valid bulk-pricing-url URL", o.BulkPricingURL)
+	}
+	return nil
+}
+
+func (o Options) validatePricingRefresh() error {
+	if o.PricingRefreshInterval <= 0 {
+		return fmt.Errorf("pricing-refresh-interval must be positive")
+	}
+	if o.PricingRefreshJitter < 0 || o.PricingRefreshJitter > 1 {
+		return fmt.Errorf("pricing-refresh-jitter must be between 0 and 1")
+	}
+	if o.PricingRetryBackoff < 0 {
+		return fmt.Errorf("pricing-retry-backoff cannot be negative")
+	}
+	if o.PricingAPIEndpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(o.PricingAPIEndpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%q is not a valid pricing-api-endpoint URL", o.PricingAPIEndpoint)
+	}
+	return nil
+}
+
+// pricingProviderPattern matches the names that pricing providers can be registered with
+var pricingProviderPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+func (o Options) validatePricingProvider() error {
+	if !pricingProviderPattern.MatchString(o.PricingProvider) {
+		return fmt.Errorf("%q is not a valid pricing-provider name", o.PricingProvider)
+	}
+	if o.PricingEndpoint != "" {
+		u, err := url.Parse(o.PricingEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return fmt.Errorf("%q is not a valid pricing-endpoint URL", o.PricingEndpoint)
+		}
+	}
+	if o.PricingProvider == "http" && o.PricingEndpoint == "" {
+		return fmt.Errorf("pricing-endpoint is required when pricing-provider is \"http\"")
+	}
+	return nil
+}
+
+func (o Options) validateRequiredVPCEndpoints() error {
+	for _, service := range o.RequiredVPCEndpointServices() {
+		if !vpcEndpointServicePattern.MatchString(service) {
+			return fmt.Errorf("%q is not a valid required-vpc-endpoints service", service)
+		}
+	}
+	return nil
+}
+
+func (o Options) validateNodeDNS() error {
+	if (o.NodeDNSHostedZoneID == "") != (o.NodeDNSDomain == "") {
+		return fmt.Errorf("node-dns-hosted-zone-id and node-dns-domain must be set together")
+	}
+	return nil
+}
+
+func (o Options) validateSecureMetrics() error {
+	if o.SecureMetricsBindAddress == "" && (o.SecureMetricsCertDir != "" || o.SecureMetricsClientCAFile != "") {
+		return fmt.Errorf("secure-metrics-bind-address must be set to use secure-metrics-cert-dir or secure-metrics-client-ca-file")
+	}
+	if o.SecureMetricsBindAddress == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(o.SecureMetricsBindAddress); err != nil {
+		return fmt.Errorf("secure-metrics-bind-address must be a host and port, %w", err)
+	}
+	return nil
+}
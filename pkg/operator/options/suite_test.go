@@ -18,7 +18,9 @@ import (
 	"context"
 	"flag"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/samber/lo"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
@@ -62,16 +64,84 @@ var _ = Describe("Options", func() {
 			"--isolated-vpc",
 			"--vm-memory-overhead-percent", "0.1",
 			"--interruption-queue", "env-cluster",
-			"--reserved-enis", "10")
+			"--reserved-enis", "10",
+			"--instance-types-cache-max-entries", "100",
+			"--instance-types-cache-max-bytes", "1048576",
+			"--savings-plans-pricing",
+			"--reserved-instance-coverage",
+			"--pricing-overrides-configmap", "karpenter-price-overrides",
+			"--tag-key-prefix", "example.com/karpenter",
+			"--spot-price-percentile", "90",
+			"--bulk-pricing-url", "https://pricing.example.com/offers/v1.0/aws/AmazonEC2/current/us-west-2/index.json",
+			"--bulk-pricing-refresh-interval", "1h",
+			"--required-vpc-endpoints", "ecr.api,ecr.dkr,s3,sts,ec2",
+			"--reserved-capacity-on-demand-pricing",
+			"--pricing-snapshot-configmap", "karpenter-pricing-snapshot",
+			"--node-dns-hosted-zone-id", "Z0123456789",
+			"--node-dns-domain", "nodes.example.com",
+			"--deterministic-offering-selection",
+			"--include-volume-cost",
+			"--pricing-provider", "internal",
+			"--provider-info-configmap", "karpenter-provider-info",
+			"--pricing-endpoint", "https://rates.example.com/ec2/us-west-2.json",
+			"--include-license-cost",
+			"--pricing-refresh-interval", "6h",
+			"--pricing-refresh-jitter", "0.2",
+			"--pricing-retry-backoff", "1m",
+			"--pricing-api-endpoint", "https://vpce-0123456789-abcdefgh.api.pricing.us-east-1.vpce.amazonaws.com",
+			"--max-described-images", "5000",
+			"--secure-metrics-bind-address", "[::]:8443",
+			"--secure-metrics-cert-dir", "/etc/karpenter/metrics-certs",
+			"--secure-metrics-client-ca-file", "/etc/karpenter/metrics-ca/ca.crt",
+			"--ami-parameter-role-arn", "arn:aws:iam::111122223333:role/ami-parameters",
+			"--max-concurrent-launches", "20",
+			"--max-launches-per-minute", "600",
+			"--spot-interruption-drain-delay", "90s",
+			"--pod-eni",
+			"--prefix-delegation")
 		Expect(err).ToNot(HaveOccurred())
 		expectOptionsEqual(opts, test.Options(test.OptionsFields{
-			ClusterCABundle:         lo.ToPtr("env-bundle"),
-			ClusterName:             lo.ToPtr("env-cluster"),
-			ClusterEndpoint:         lo.ToPtr("https://env-cluster"),
-			IsolatedVPC:             lo.ToPtr(true),
-			VMMemoryOverheadPercent: lo.ToPtr[float64](0.1),
-			InterruptionQueue:       lo.ToPtr("env-cluster"),
-			ReservedENIs:            lo.ToPtr(10),
+			ClusterCABundle:                 lo.ToPtr("env-bundle"),
+			ClusterName:                     lo.ToPtr("env-cluster"),
+			ClusterEndpoint:                 lo.ToPtr("https://env-cluster"),
+			IsolatedVPC:                     lo.ToPtr(true),
+			VMMemoryOverheadPercent:         lo.ToPtr[float64](0.1),
+			InterruptionQueue:               lo.ToPtr("env-cluster"),
+			ReservedENIs:                    lo.ToPtr(10),
+			InstanceTypesCacheMaxEntries:    lo.ToPtr(100),
+			InstanceTypesCacheMaxBytes:      lo.ToPtr[int64](1048576),
+			SavingsPlansPricing:             lo.ToPtr(true),
+			ReservedInstanceCoverage:        lo.ToPtr(true),
+			PricingOverridesConfigMap:       lo.ToPtr("karpenter-price-overrides"),
+			TagKeyPrefix:                    lo.ToPtr("example.com/karpenter"),
+			SpotPricePercentile:             lo.ToPtr(90),
+			BulkPricingURL:                  lo.ToPtr("https://pricing.example.com/offers/v1.0/aws/AmazonEC2/current/us-west-2/index.json"),
+			BulkPricingRefreshInterval:      lo.ToPtr(time.Hour),
+			RequiredVPCEndpoints:            lo.ToPtr("ecr.api,ecr.dkr,s3,sts,ec2"),
+			ReservedCapacityOnDemandPricing: lo.ToPtr(true),
+			PricingSnapshotConfigMap:        lo.ToPtr("karpenter-pricing-snapshot"),
+			NodeDNSHostedZoneID:             lo.ToPtr("Z0123456789"),
+			NodeDNSDomain:                   lo.ToPtr("nodes.example.com"),
+			DeterministicOfferingSelection:  lo.ToPtr(true),
+			IncludeVolumeCost:               lo.ToPtr(true),
+			PricingProvider:                 lo.ToPtr("internal"),
+			ProviderInfoConfigMap:           lo.ToPtr("karpenter-provider-info"),
+			PricingEndpoint:                 lo.ToPtr("https://rates.example.com/ec2/us-west-2.json"),
+			IncludeLicenseCost:              lo.ToPtr(true),
+			PricingRefreshInterval:          lo.ToPtr(6 * time.Hour),
+			PricingRefreshJitter:            lo.ToPtr(0.2),
+			PricingRetryBackoff:             lo.ToPtr(time.Minute),
+			PricingAPIEndpoint:              lo.ToPtr("https://vpce-0123456789-abcdefgh.api.pricing.us-east-1.vpce.amazonaws.com"),
+			MaxDescribedImages:              lo.ToPtr(5000),
+			SecureMetricsBindAddress:        lo.ToPtr("[::]:8443"),
+			SecureMetricsCertDir:            lo.ToPtr("/etc/karpenter/metrics-certs"),
+			SecureMetricsClientCAFile:       lo.ToPtr("/etc/karpenter/metrics-ca/ca.crt"),
+			AMIParameterRoleARN:             lo.ToPtr("arn:aws:iam::111122223333:role/ami-parameters"),
+			MaxConcurrentLaunches:           lo.ToPtr(20),
+			MaxLaunchesPerMinute:            lo.ToPtr(600),
+			SpotInterruptionDrainDelay:      lo.ToPtr(90 * time.Second),
+			PodENI:                          lo.ToPtr(true),
+			PrefixDelegation:                lo.ToPtr(true),
 		}))
 	})
 	It("should correctly fallback to env vars when CLI flags aren't set", func() {
@@ -82,6 +152,40 @@ var _ = Describe("Options", func() {
 		os.Setenv("VM_MEMORY_OVERHEAD_PERCENT", "0.1")
 		os.Setenv("INTERRUPTION_QUEUE", "env-cluster")
 		os.Setenv("RESERVED_ENIS", "10")
+		os.Setenv("INSTANCE_TYPES_CACHE_MAX_ENTRIES", "100")
+		os.Setenv("INSTANCE_TYPES_CACHE_MAX_BYTES", "1048576")
+		os.Setenv("SAVINGS_PLANS_PRICING", "true")
+		os.Setenv("RESERVED_INSTANCE_COVERAGE", "true")
+		os.Setenv("PRICING_OVERRIDES_CONFIGMAP", "karpenter-price-overrides")
+		os.Setenv("TAG_KEY_PREFIX", "example.com/karpenter")
+		os.Setenv("SPOT_PRICE_PERCENTILE", "90")
+		os.Setenv("BULK_PRICING_URL", "https://pricing.example.com/offers/v1.0/aws/AmazonEC2/current/us-west-2/index.json")
+		os.Setenv("BULK_PRICING_REFRESH_INTERVAL", "1h")
+		os.Setenv("REQUIRED_VPC_ENDPOINTS", "ecr.api,ecr.dkr,s3,sts,ec2")
+		os.Setenv("RESERVED_CAPACITY_ON_DEMAND_PRICING", "true")
+		os.Setenv("PRICING_SNAPSHOT_CONFIGMAP", "karpenter-pricing-snapshot")
+		os.Setenv("NODE_DNS_HOSTED_ZONE_ID", "Z0123456789")
+		os.Setenv("NODE_DNS_DOMAIN", "nodes.example.com")
+		os.Setenv("DETERMINISTIC_OFFERING_SELECTION", "true")
+		os.Setenv("INCLUDE_VOLUME_COST", "true")
+		os.Setenv("PRICING_PROVIDER", "internal")
+		os.Setenv("PROVIDER_INFO_CONFIGMAP", "karpenter-provider-info")
+		os.Setenv("PRICING_ENDPOINT", "https://rates.example.com/ec2/us-west-2.json")
+		os.Setenv("INCLUDE_LICENSE_COST", "true")
+		os.Setenv("PRICING_REFRESH_INTERVAL", "6h")
+		os.Setenv("PRICING_REFRESH_JITTER", "0.2")
+		os.Setenv("PRICING_RETRY_BACKOFF", "1m")
+		os.Setenv("PRICING_API_ENDPOINT", "https://vpce-0123456789-abcdefgh.api.pricing.us-east-1.vpce.amazonaws.com")
+		os.Setenv("MAX_DESCRIBED_IMAGES", "5000")
+		os.Setenv("SECURE_METRICS_BIND_ADDRESS", "[::]:8443")
+		os.Setenv("SECURE_METRICS_CERT_DIR", "/etc/karpenter/metrics-certs")
+		os.Setenv("SECURE_METRICS_CLIENT_CA_FILE", "/etc/karpenter/metrics-ca/ca.crt")
+		os.Setenv("AMI_PARAMETER_ROLE_ARN", "arn:aws:iam::111122223333:role/ami-parameters")
+		os.Setenv("MAX_CONCURRENT_LAUNCHES", "20")
+		os.Setenv("MAX_LAUNCHES_PER_MINUTE", "600")
+		os.Setenv("SPOT_INTERRUPTION_DRAIN_DELAY", "90s")
+		os.Setenv("POD_ENI", "true")
+		os.Setenv("PREFIX_DELEGATION", "true")
 
 		// Add flags after we set the environment variables so that the parsing logic correctly refers
 		// to the new environment variable values
@@ -89,13 +193,47 @@ var _ = Describe("Options", func() {
 		err := opts.Parse(fs)
 		Expect(err).ToNot(HaveOccurred())
 		expectOptionsEqual(opts, test.Options(test.OptionsFields{
-			ClusterCABundle:         lo.ToPtr("env-bundle"),
-			ClusterName:             lo.ToPtr("env-cluster"),
-			ClusterEndpoint:         lo.ToPtr("https://env-cluster"),
-			IsolatedVPC:             lo.ToPtr(true),
-			VMMemoryOverheadPercent: lo.ToPtr[float64](0.1),
-			InterruptionQueue:       lo.ToPtr("env-cluster"),
-			ReservedENIs:            lo.ToPtr(10),
+			ClusterCABundle:                 lo.ToPtr("env-bundle"),
+			ClusterName:                     lo.ToPtr("env-cluster"),
+			ClusterEndpoint:                 lo.ToPtr("https://env-cluster"),
+			IsolatedVPC:                     lo.ToPtr(true),
+			VMMemoryOverheadPercent:         lo.ToPtr[float64](0.1),
+			InterruptionQueue:               lo.ToPtr("env-cluster"),
+			ReservedENIs:                    lo.ToPtr(10),
+			InstanceTypesCacheMaxEntries:    lo.ToPtr(100),
+			InstanceTypesCacheMaxBytes:      lo.ToPtr[int64](1048576),
+			SavingsPlansPricing:             lo.ToPtr(true),
+			ReservedInstanceCoverage:        lo.ToPtr(true),
+			PricingOverridesConfigMap:       lo.ToPtr("karpenter-price-overrides"),
+			TagKeyPrefix:                    lo.ToPtr("example.com/karpenter"),
+			SpotPricePercentile:             lo.ToPtr(90),
+			BulkPricingURL:                  lo.ToPtr("https://pricing.example.com/offers/v1.0/aws/AmazonEC2/current/us-west-2/index.json"),
+			BulkPricingRefreshInterval:      lo.ToPtr(time.Hour),
+			RequiredVPCEndpoints:            lo.ToPtr("ecr.api,ecr.dkr,s3,sts,ec2"),
+			ReservedCapacityOnDemandPricing: lo.ToPtr(true),
+			PricingSnapshotConfigMap:        lo.ToPtr("karpenter-pricing-snapshot"),
+			NodeDNSHostedZoneID:             lo.ToPtr("Z0123456789"),
+			NodeDNSDomain:                   lo.ToPtr("nodes.example.com"),
+			DeterministicOfferingSelection:  lo.ToPtr(true),
+			IncludeVolumeCost:               lo.ToPtr(true),
+			PricingProvider:                 lo.ToPtr("internal"),
+			ProviderInfoConfigMap:           lo.ToPtr("karpenter-provider-info"),
+			PricingEndpoint:                 lo.ToPtr("https://rates.example.com/ec2/us-west-2.json"),
+			IncludeLicenseCost:              lo.ToPtr(true),
+			PricingRefreshInterval:          lo.ToPtr(6 * time.Hour),
+			PricingRefreshJitter:            lo.ToPtr(0.2),
+			PricingRetryBackoff:             lo.ToPtr(time.Minute),
+			PricingAPIEndpoint:              lo.ToPtr("https://vpce-0123456789-abcdefgh.api.pricing.us-east-1.vpce.amazonaws.com"),
+			MaxDescribedImages:              lo.ToPtr(5000),
+			SecureMetricsBindAddress:        lo.ToPtr("[::]:8443"),
+			SecureMetricsCertDir:            lo.ToPtr("/etc/karpenter/metrics-certs"),
+			SecureMetricsClientCAFile:       lo.ToPtr("/etc/karpenter/metrics-ca/ca.crt"),
+			AMIParameterRoleARN:             lo.ToPtr("arn:aws:iam::111122223333:role/ami-parameters"),
+			MaxConcurrentLaunches:           lo.ToPtr(20),
+			MaxLaunchesPerMinute:            lo.ToPtr(600),
+			SpotInterruptionDrainDelay:      lo.ToPtr(90 * time.Second),
+			PodENI:                          lo.ToPtr(true),
+			PrefixDelegation:                lo.ToPtr(true),
 		}))
 	})
 
@@ -119,6 +257,100 @@ var _ = Describe("Options", func() {
 			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--reserved-enis", "-1")
 			Expect(err).To(HaveOccurred())
 		})
+		It("should fail when instanceTypesCacheMaxEntries is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-types-cache-max-entries", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when instanceTypesCacheMaxBytes is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-types-cache-max-bytes", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when maxDescribedImages is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--max-described-images", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when maxConcurrentLaunches is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--max-concurrent-launches", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when maxLaunchesPerMinute is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--max-launches-per-minute", "-1")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when spotInterruptionDrainDelay is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--spot-interruption-drain-delay", "-1s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when spotInterruptionDrainDelay isn't within the interruption warning", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--spot-interruption-drain-delay", "2m")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when secureMetricsClientCAFile is set without secureMetricsBindAddress", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--secure-metrics-client-ca-file", "/etc/karpenter/metrics-ca/ca.crt")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when secureMetricsBindAddress doesn't include a port", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--secure-metrics-bind-address", "::")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when spotPricePercentile is greater than 100", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--spot-price-percentile", "101")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when bulkPricingRefreshInterval is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--bulk-pricing-refresh-interval", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when bulkPricingURL is invalid", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--bulk-pricing-url", "pricing.example.com/index.json")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when pricingProvider is not a valid name", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--pricing-provider", "Internal Rates")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when pricingEndpoint is invalid", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--pricing-provider", "http", "--pricing-endpoint", "rates.example.com/ec2.json")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when pricingProvider is http and pricingEndpoint is not set", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--pricing-provider", "http")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when pricingRefreshInterval is not positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--pricing-refresh-interval", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when pricingRefreshJitter is greater than 1", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--pricing-refresh-jitter", "1.5")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when pricingRetryBackoff is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--pricing-retry-backoff", "-1m")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when pricingAPIEndpoint is invalid", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--pricing-api-endpoint", "api.pricing.us-east-1.amazonaws.com")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when requiredVPCEndpoints contains an invalid service", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--required-vpc-endpoints", "ecr.api,com.amazonaws.us-west-2.S3")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when nodeDNSHostedZoneID is set without nodeDNSDomain", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--node-dns-hosted-zone-id", "Z0123456789")
+			Expect(err).To(HaveOccurred())
+		})
+		DescribeTable("should fail when tagKeyPrefix is invalid",
+			func(prefix string) {
+				err := opts.Parse(fs, "--cluster-name", "test-cluster", "--tag-key-prefix", prefix)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("with a trailing slash", "example.com/karpenter/"),
+			Entry("with the reserved aws: prefix", "aws:karpenter"),
+			Entry("with invalid characters", "example.com/karpenter!"),
+			Entry("when too long", strings.Repeat("a", 116)),
+		)
 	})
 })
 
@@ -131,4 +363,38 @@ func expectOptionsEqual(optsA *options.Options, optsB *options.Options) {
 	Expect(optsA.VMMemoryOverheadPercent).To(Equal(optsB.VMMemoryOverheadPercent))
 	Expect(optsA.InterruptionQueue).To(Equal(optsB.InterruptionQueue))
 	Expect(optsA.ReservedENIs).To(Equal(optsB.ReservedENIs))
+	Expect(optsA.InstanceTypesCacheMaxEntries).To(Equal(optsB.InstanceTypesCacheMaxEntries))
+	Expect(optsA.InstanceTypesCacheMaxBytes).To(Equal(optsB.InstanceTypesCacheMaxBytes))
+	Expect(optsA.SavingsPlansPricing).To(Equal(optsB.SavingsPlansPricing))
+	Expect(optsA.ReservedInstanceCoverage).To(Equal(optsB.ReservedInstanceCoverage))
+	Expect(optsA.PricingOverridesConfigMap).To(Equal(optsB.PricingOverridesConfigMap))
+	Expect(optsA.TagKeyPrefix).To(Equal(optsB.TagKeyPrefix))
+	Expect(optsA.SpotPricePercentile).To(Equal(optsB.SpotPricePercentile))
+	Expect(optsA.BulkPricingURL).To(Equal(optsB.BulkPricingURL))
+	Expect(optsA.BulkPricingRefreshInterval).To(Equal(optsB.BulkPricingRefreshInterval))
+	Expect(optsA.RequiredVPCEndpoints).To(Equal(optsB.RequiredVPCEndpoints))
+	Expect(optsA.ReservedCapacityOnDemandPricing).To(Equal(optsB.ReservedCapacityOnDemandPricing))
+	Expect(optsA.PricingSnapshotConfigMap).To(Equal(optsB.PricingSnapshotConfigMap))
+	Expect(optsA.NodeDNSHostedZoneID).To(Equal(optsB.NodeDNSHostedZoneID))
+	Expect(optsA.NodeDNSDomain).To(Equal(optsB.NodeDNSDomain))
+	Expect(optsA.DeterministicOfferingSelection).To(Equal(optsB.DeterministicOfferingSelection))
+	Expect(optsA.IncludeVolumeCost).To(Equal(optsB.IncludeVolumeCost))
+	Expect(optsA.PricingProvider).To(Equal(optsB.PricingProvider))
+	Expect(optsA.ProviderInfoConfigMap).To(Equal(optsB.ProviderInfoConfigMap))
+	Expect(optsA.PricingEndpoint).To(Equal(optsB.PricingEndpoint))
+	Expect(optsA.IncludeLicenseCost).To(Equal(optsB.IncludeLicenseCost))
+	Expect(optsA.PricingRefreshInterval).To(Equal(optsB.PricingRefreshInterval))
+	Expect(optsA.PricingRefreshJitter).To(Equal(optsB.PricingRefreshJitter))
+	Expect(optsA.PricingRetryBackoff).To(Equal(optsB.PricingRetryBackoff))
+	Expect(optsA.PricingAPIEndpoint).To(Equal(optsB.PricingAPIEndpoint))
+	Expect(optsA.MaxDescribedImages).To(Equal(optsB.MaxDescribedImages))
+	Expect(optsA.SecureMetricsBindAddress).To(Equal(optsB.SecureMetricsBindAddress))
+	Expect(optsA.SecureMetricsCertDir).To(Equal(optsB.SecureMetricsCertDir))
+	Expect(optsA.SecureMetricsClientCAFile).To(Equal(optsB.SecureMetricsClientCAFile))
+	Expect(optsA.AMIParameterRoleARN).To(Equal(optsB.AMIParameterRoleARN))
+	Expect(optsA.MaxConcurrentLaunches).To(Equal(optsB.MaxConcurrentLaunches))
+	Expect(optsA.MaxLaunchesPerMinute).To(Equal(optsB.MaxLaunchesPerMinute))
+	Expect(optsA.SpotInterruptionDrainDelay).To(Equal(optsB.SpotInterruptionDrainDelay))
+	Expect(optsA.PodENI).To(Equal(optsB.PodENI))
+	Expect(optsA.PrefixDelegation).To(Equal(optsB.PrefixDelegation))
 }
@@ -17,10 +17,14 @@ package operator_test
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	"github.com/samber/lo"
@@ -104,4 +108,33 @@ var _ = Describe("Operator", func() {
 		_, err := awscontext.ResolveClusterEndpoint(ctx, fakeEKSAPI)
 		Expect(err).To(HaveOccurred())
 	})
+	It("should fail constructing the secure metrics server when the client ca file doesn't exist", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			SecureMetricsBindAddress:  lo.ToPtr("[::]:8443"),
+			SecureMetricsClientCAFile: lo.ToPtr(filepath.Join(GinkgoT().TempDir(), "ca.crt")),
+		}))
+		_, err := awscontext.NewSecureMetricsServer(ctx, env.Config, nil)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should fail constructing the secure metrics server when the client ca file doesn't contain certificates", func() {
+		caFile := filepath.Join(GinkgoT().TempDir(), "ca.crt")
+		Expect(os.WriteFile(caFile, []byte("not a certificate"), 0600)).To(Succeed())
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			SecureMetricsBindAddress:  lo.ToPtr("[::]:8443"),
+			SecureMetricsClientCAFile: lo.ToPtr(caFile),
+		}))
+		_, err := awscontext.NewSecureMetricsServer(ctx, env.Config, nil)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should not change the credentials of the config when no role is assumed", func() {
+		cfg := aws.Config{Region: "us-west-2", Credentials: credentials.NewStaticCredentialsProvider("key", "secret", "")}
+		Expect(awscontext.WithAssumedRole(cfg, "").Credentials).To(Equal(cfg.Credentials))
+	})
+	It("should retrieve the credentials of the config by assuming the role", func() {
+		cfg := aws.Config{Region: "us-west-2", Credentials: credentials.NewStaticCredentialsProvider("key", "secret", "")}
+		assumed := awscontext.WithAssumedRole(cfg, "arn:aws:iam::111122223333:role/ami-parameters")
+		Expect(assumed.Credentials).To(BeAssignableToTypeOf(&aws.CredentialsCache{}))
+		Expect(assumed.Region).To(Equal(cfg.Region))
+		Expect(cfg.Credentials).To(BeAssignableToTypeOf(credentials.StaticCredentialsProvider{}))
+	})
 })
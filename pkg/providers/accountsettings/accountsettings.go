@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accountsettings
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+)
+
+const cacheKey = "account-settings"
+
+// Settings are the EC2 account-level defaults for the current region which affect how instances are launched. Each
+// field is nil when the controller isn't authorized to read the corresponding setting, in which case nothing should be
+// assumed about it.
+type Settings struct {
+	EBSEncryptionByDefault     *bool
+	EBSDefaultKMSKeyID         *string
+	MetadataDefaults           *ec2types.InstanceMetadataDefaultsResponse
+	SerialConsoleAccessEnabled *bool
+}
+
+// MetadataDefaultsManagedByPolicy returns true if the account's instance metadata defaults are set by a declarative
+// policy rather than by the account itself
+func (s *Settings) MetadataDefaultsManagedByPolicy() bool {
+	return s.MetadataDefaults != nil && s.MetadataDefaults.ManagedBy == ec2types.ManagedByDeclarativePolicy
+}
+
+type Provider interface {
+	Get(context.Context) (*Settings, error)
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	ec2api sdk.EC2API
+	cache  *cache.Cache
+	cm     *pretty.ChangeMonitor
+}
+
+func NewDefaultProvider(ec2api sdk.EC2API, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api: ec2api,
+		cache:  cache,
+		cm:     pretty.NewChangeMonitor(),
+	}
+}
+
+func (p *DefaultProvider) Get(ctx context.Context) (*Settings, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if settings, ok := p.cache.Get(cacheKey); ok {
+		return settings.(*Settings), nil
+	}
+	settings := &Settings{}
+	ebsEncryption, err := p.ec2api.GetEbsEncryptionByDefault(ctx, &ec2.GetEbsEncryptionByDefaultInput{})
+	if err = p.ignoreUnauthorized(ctx, "ec2:GetEbsEncryptionByDefault", err); err != nil {
+		return nil, fmt.Errorf("getting ebs encryption by default, %w", err)
+	}
+	if ebsEncryption != nil {
+		settings.EBSEncryptionByDefault = ebsEncryption.EbsEncryptionByDefault
+	}
+	// The default KMS key is only relevant when volumes are encrypted by default
+	if lo.FromPtr(settings.EBSEncryptionByDefault) {
+		kmsKey, err := p.ec2api.GetEbsDefaultKmsKeyId(ctx, &ec2.GetEbsDefaultKmsKeyIdInput{})
+		if err = p.ignoreUnauthorized(ctx, "ec2:GetEbsDefaultKmsKeyId", err); err != nil {
+			return nil, fmt.Errorf("getting ebs default kms key id, %w", err)
+		}
+		if kmsKey != nil {
+			settings.EBSDefaultKMSKeyID = kmsKey.KmsKeyId
+		}
+	}
+	metadataDefaults, err := p.ec2api.GetInstanceMetadataDefaults(ctx, &ec2.GetInstanceMetadataDefaultsInput{})
+	if err = p.ignoreUnauthorized(ctx, "ec2:GetInstanceMetadataDefaults", err); err != nil {
+		return nil, fmt.Errorf("getting instance metadata defaults, %w", err)
+	}
+	if metadataDefaults != nil {
+		settings.MetadataDefaults = metadataDefaults.AccountLevel
+	}
+	serialConsole, err := p.ec2api.GetSerialConsoleAccessStatus(ctx, &ec2.GetSerialConsoleAccessStatusInput{})
+	if err = p.ignoreUnauthorized(ctx, "ec2:GetSerialConsoleAccessStatus", err); err != nil {
+		return nil, fmt.Errorf("getting serial console access status, %w", err)
+	}
+	if serialConsole != nil {
+		settings.SerialConsoleAccessEnabled = serialConsole.SerialConsoleAccessEnabled
+	}
+
+	if p.cm.HasChanged("account-settings", settings) {
+		log.FromContext(ctx).WithValues(
+			"ebs-encryption-by-default", lo.FromPtr(settings.EBSEncryptionByDefault),
+			"ebs-default-kms-key-id", lo.FromPtr(settings.EBSDefaultKMSKeyID),
+			"metadata-http-tokens", lo.FromPtr(settings.MetadataDefaults).HttpTokens,
+			"metadata-defaults-managed-by-policy", settings.MetadataDefaultsManagedByPolicy(),
+			"serial-console-access-enabled", lo.FromPtr(settings.SerialConsoleAccessEnabled),
+		).V(1).Info("discovered account settings")
+	}
+	p.cache.SetDefault(cacheKey, settings)
+	return settings, nil
+}
+
+// ignoreUnauthorized swallows authorization failures so that missing permissions for any single account attribute don't
+// prevent the remaining attributes from being discovered
+func (p *DefaultProvider) ignoreUnauthorized(ctx context.Context, action string, err error) error {
+	if err == nil || !awserrors.IsUnauthorizedOperationError(err) {
+		return err
+	}
+	if p.cm.HasChanged(fmt.Sprintf("unauthorized/%s", action), struct{}{}) {
+		log.FromContext(ctx).WithValues("action", action).V(1).Info("not authorized to read account setting, ignoring")
+	}
+	return nil
+}
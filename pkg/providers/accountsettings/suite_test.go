@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accountsettings_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accountsettings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var ec2api *fake.EC2API
+var accountSettingsProvider *accountsettings.DefaultProvider
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AccountSettingsProvider")
+}
+
+var _ = BeforeEach(func() {
+	ec2api = fake.NewEC2API()
+	accountSettingsProvider = accountsettings.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+})
+
+var _ = Describe("AccountSettingsProvider", func() {
+	It("should discover the account settings", func() {
+		ec2api.GetEbsEncryptionByDefaultBehavior.Output.Set(&ec2.GetEbsEncryptionByDefaultOutput{EbsEncryptionByDefault: lo.ToPtr(true)})
+		ec2api.GetEbsDefaultKmsKeyIdBehavior.Output.Set(&ec2.GetEbsDefaultKmsKeyIdOutput{KmsKeyId: lo.ToPtr("arn:aws:kms:us-west-2:111122223333:key/test-key")})
+		ec2api.GetInstanceMetadataDefaultsBehavior.Output.Set(&ec2.GetInstanceMetadataDefaultsOutput{
+			AccountLevel: &ec2types.InstanceMetadataDefaultsResponse{
+				HttpTokens: ec2types.HttpTokensStateRequired,
+				ManagedBy:  ec2types.ManagedByDeclarativePolicy,
+			},
+		})
+		ec2api.GetSerialConsoleAccessStatusBehavior.Output.Set(&ec2.GetSerialConsoleAccessStatusOutput{SerialConsoleAccessEnabled: lo.ToPtr(true)})
+
+		settings, err := accountSettingsProvider.Get(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lo.FromPtr(settings.EBSEncryptionByDefault)).To(BeTrue())
+		Expect(lo.FromPtr(settings.EBSDefaultKMSKeyID)).To(Equal("arn:aws:kms:us-west-2:111122223333:key/test-key"))
+		Expect(settings.MetadataDefaults.HttpTokens).To(Equal(ec2types.HttpTokensStateRequired))
+		Expect(settings.MetadataDefaultsManagedByPolicy()).To(BeTrue())
+		Expect(lo.FromPtr(settings.SerialConsoleAccessEnabled)).To(BeTrue())
+	})
+	It("should not look up the default KMS key when encryption by default is disabled", func() {
+		settings, err := accountSettingsProvider.Get(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lo.FromPtr(settings.EBSEncryptionByDefault)).To(BeFalse())
+		Expect(settings.EBSDefaultKMSKeyID).To(BeNil())
+		Expect(ec2api.GetEbsDefaultKmsKeyIdBehavior.CalledWithInput.Len()).To(Equal(0))
+	})
+	It("should leave settings unknown when the controller isn't authorized to read them", func() {
+		ec2api.GetEbsEncryptionByDefaultBehavior.Error.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+		ec2api.GetSerialConsoleAccessStatusBehavior.Error.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+
+		settings, err := accountSettingsProvider.Get(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(settings.EBSEncryptionByDefault).To(BeNil())
+		Expect(settings.SerialConsoleAccessEnabled).To(BeNil())
+		Expect(settings.MetadataDefaults).ToNot(BeNil())
+	})
+	It("should return other errors", func() {
+		ec2api.GetInstanceMetadataDefaultsBehavior.Error.Set(errors.New("internal error"))
+		_, err := accountSettingsProvider.Get(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should cache the account settings", func() {
+		_, err := accountSettingsProvider.Get(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = accountSettingsProvider.Get(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ec2api.GetEbsEncryptionByDefaultBehavior.CalledWithInput.Len()).To(Equal(1))
+		Expect(ec2api.GetInstanceMetadataDefaultsBehavior.CalledWithInput.Len()).To(Equal(1))
+	})
+})
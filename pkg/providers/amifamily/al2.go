@@ -58,6 +58,9 @@ func (a AL2) DescribeImageQuery(ctx context.Context, ssmProvider ssm.Provider, k
 			fmt.Sprintf("amazon-eks-gpu-node-%s-%s", k8sVersion, amiVersion),
 		)): {VariantNeuron, VariantNvidia},
 	} {
+		if variants = a.Options.selectedVariants(variants...); len(variants) == 0 {
+			continue
+		}
 		imageID, err := ssmProvider.Get(ctx, ssm.Parameter{
 			Name:      path,
 			IsMutable: amiVersion == v1.AliasVersionLatest,
@@ -92,12 +95,18 @@ func (a AL2) UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Ta
 		Options: bootstrap.Options{
 			ClusterName:         a.Options.ClusterName,
 			ClusterEndpoint:     a.Options.ClusterEndpoint,
+			ClusterCIDR:         a.Options.ClusterCIDR,
 			KubeletConfig:       kubeletConfig,
 			Taints:              taints,
 			Labels:              labels,
 			CABundle:            caBundle,
 			CustomUserData:      customUserData,
 			InstanceStorePolicy: instanceStorePolicy,
+			RegistryMirrors:     a.Options.RegistryMirrors,
+			Proxy:               a.Options.Proxy,
+			TrustedCABundle:     a.Options.TrustedCABundle,
+			UserDataMergeOrder:  a.Options.UserDataMergeOrder,
+			DeviceValidation:    bootstrap.NewDeviceValidation(a.Options.DeviceValidation),
 		},
 	}
 }
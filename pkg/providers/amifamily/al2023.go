@@ -38,11 +38,15 @@ type AL2023 struct {
 func (a AL2023) DescribeImageQuery(ctx context.Context, ssmProvider ssm.Provider, k8sVersion string, amiVersion string) (DescribeImageQuery, error) {
 	ids := map[string]Variant{}
 	for arch, variants := range map[string][]Variant{
-		"x86_64": {VariantStandard, VariantNvidia, VariantNeuron},
+		"x86_64": {VariantStandard, VariantNvidia, VariantNvidiaOpen, VariantNeuron},
 		"arm64":  {VariantStandard},
 	} {
-		for _, variant := range variants {
-			path := a.resolvePath(arch, string(variant), k8sVersion, amiVersion)
+		for _, variant := range a.Options.selectedVariants(variants...) {
+			// FIPS AMIs are only published for the standard variant
+			if a.Options.fips() && variant != VariantStandard {
+				continue
+			}
+			path := a.resolvePath(arch, string(variant)+lo.Ternary(a.Options.fips(), "-fips", ""), k8sVersion, amiVersion)
 			imageID, err := ssmProvider.Get(ctx, ssm.Parameter{
 				Name:      path,
 				IsMutable: amiVersion == v1.AliasVersionLatest,
@@ -55,7 +59,7 @@ func (a AL2023) DescribeImageQuery(ctx context.Context, ssmProvider ssm.Provider
 	}
 	// Failed to discover any AMIs, we should short circuit AMI discovery
 	if len(ids) == 0 {
-		return DescribeImageQuery{}, fmt.Errorf(`failed to discover AMIs for alias "al2023@%s"`, amiVersion)
+		return DescribeImageQuery{}, fmt.Errorf(`failed to discover %sAMIs for alias "al2023@%s"`, lo.Ternary(a.Options.fips(), "FIPS ", ""), amiVersion)
 	}
 
 	return DescribeImageQuery{
@@ -81,16 +85,23 @@ func (a AL2023) resolvePath(architecture, variant, k8sVersion, amiVersion string
 func (a AL2023) UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, caBundle *string, _ []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1.InstanceStorePolicy) bootstrap.Bootstrapper {
 	return bootstrap.Nodeadm{
 		Options: bootstrap.Options{
-			ClusterName:         a.Options.ClusterName,
-			ClusterEndpoint:     a.Options.ClusterEndpoint,
-			ClusterCIDR:         a.Options.ClusterCIDR,
-			KubeletConfig:       kubeletConfig,
-			Taints:              taints,
-			Labels:              labels,
-			CABundle:            caBundle,
-			CustomUserData:      customUserData,
-			InstanceStorePolicy: instanceStorePolicy,
+			ClusterName:          a.Options.ClusterName,
+			ClusterEndpoint:      a.Options.ClusterEndpoint,
+			ClusterCIDR:          a.Options.ClusterCIDR,
+			KubeletConfig:        kubeletConfig,
+			Taints:               taints,
+			Labels:               labels,
+			CABundle:             caBundle,
+			CustomUserData:       customUserData,
+			InstanceStorePolicy:  instanceStorePolicy,
+			RegistryMirrors:      a.Options.RegistryMirrors,
+			Proxy:                a.Options.Proxy,
+			TrustedCABundle:      a.Options.TrustedCABundle,
+			Swap:                 a.Options.Swap,
+			DeviceValidation:     bootstrap.NewDeviceValidation(a.Options.DeviceValidation),
+			KubeletConfigDropIns: a.Options.KubeletConfigDropIns,
 		},
+		NodeConfig: a.Options.NodeConfig,
 	}
 }
 
@@ -17,6 +17,9 @@ package amifamily
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -25,10 +28,16 @@ import (
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -37,6 +46,9 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
 )
 
+// maxConcurrentDescribeImagesRequests bounds the number of AMI queries that are described in parallel
+const maxConcurrentDescribeImagesRequests = 5
+
 type Provider interface {
 	List(ctx context.Context, nodeClass *v1.EC2NodeClass) (AMIs, error)
 }
@@ -47,17 +59,25 @@ type DefaultProvider struct {
 	clk             clock.Clock
 	cache           *cache.Cache
 	ec2api          sdk.EC2API
+	imageBuilderAPI sdk.ImageBuilderAPI
 	versionProvider version.Provider
 	ssmProvider     ssm.Provider
+	// amiParameterProvider reads the SSM parameters referenced by amiSelectorTerms. It's separate from the ssmProvider
+	// used for aliases since these parameters may belong to other accounts and are expected to change more frequently.
+	amiParameterProvider ssm.Provider
+	cm                   *pretty.ChangeMonitor
 }
 
-func NewDefaultProvider(clk clock.Clock, versionProvider version.Provider, ssmProvider ssm.Provider, ec2api sdk.EC2API, cache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(clk clock.Clock, versionProvider version.Provider, ssmProvider ssm.Provider, amiParameterProvider ssm.Provider, ec2api sdk.EC2API, imageBuilderAPI sdk.ImageBuilderAPI, cache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
-		clk:             clk,
-		cache:           cache,
-		ec2api:          ec2api,
-		versionProvider: versionProvider,
-		ssmProvider:     ssmProvider,
+		clk:                  clk,
+		cache:                cache,
+		ec2api:               ec2api,
+		imageBuilderAPI:      imageBuilderAPI,
+		versionProvider:      versionProvider,
+		ssmProvider:          ssmProvider,
+		amiParameterProvider: amiParameterProvider,
+		cm:                   pretty.NewChangeMonitor(),
 	}
 }
 
@@ -82,7 +102,10 @@ func (p *DefaultProvider) DescribeImageQueries(ctx context.Context, nodeClass *v
 	// This is enforced by a CEL validation, we will treat this as an invariant.
 	if alias := nodeClass.Alias(); alias != nil {
 		kubernetesVersion := p.versionProvider.Get(ctx)
-		query, err := GetAMIFamily(alias.Family, nil).DescribeImageQuery(ctx, p.ssmProvider, kubernetesVersion, alias.Version)
+		query, err := GetAMIFamily(alias.Family, &Options{
+			AMIVariants: lo.Map(nodeClass.Spec.AMIVariants, func(v v1.AMIVariant, _ int) Variant { return Variant(v) }),
+			FIPS:        lo.FromPtr(nodeClass.Spec.FIPS),
+		}).DescribeImageQuery(ctx, p.ssmProvider, kubernetesVersion, alias.Version)
 		if err != nil {
 			return []DescribeImageQuery{}, err
 		}
@@ -95,22 +118,40 @@ func (p *DefaultProvider) DescribeImageQueries(ctx context.Context, nodeClass *v
 		switch {
 		case term.ID != "":
 			idFilter.Values = append(idFilter.Values, term.ID)
+		case term.ImageBuilderARN != "":
+			ids, err := p.imageBuilderAMIIDs(ctx, term.ImageBuilderARN)
+			if err != nil {
+				return []DescribeImageQuery{}, err
+			}
+			idFilter.Values = append(idFilter.Values, ids...)
+		case term.SSMParameter != "":
+			id, err := p.amiParameterProvider.Get(ctx, ssm.Parameter{Name: term.SSMParameter, IsMutable: true})
+			if err != nil {
+				return []DescribeImageQuery{}, err
+			}
+			idFilter.Values = append(idFilter.Values, id)
+		case term.SSMParameterPath != "":
+			parameters, err := p.amiParameterProvider.GetByPath(ctx, term.SSMParameterPath)
+			if err != nil {
+				return []DescribeImageQuery{}, err
+			}
+			idFilter.Values = append(idFilter.Values, amiIDsForVersion(parameters, p.versionProvider.Get(ctx))...)
 		default:
 			query := DescribeImageQuery{
 				Owners: lo.Ternary(term.Owner != "", []string{term.Owner}, []string{}),
 			}
-			if term.Name != "" {
+			if term.Name != "" || term.NamePattern != "" {
 				// Default owners to self,amazon to ensure Karpenter only discovers cross-account AMIs if the user specifically allows it.
 				// Removing this default would cause Karpenter to discover publicly shared AMIs passing the name filter.
-				query = DescribeImageQuery{
-					Owners: lo.Ternary(term.Owner != "", []string{term.Owner}, []string{"self", "amazon"}),
-				}
+				query.Owners = lo.Ternary(term.Owner != "", []string{term.Owner}, []string{"self", "amazon"})
+			}
+			if term.Name != "" {
 				query.Filters = append(query.Filters, ec2types.Filter{
 					Name:   aws.String("name"),
 					Values: []string{term.Name},
 				})
-
 			}
+			query.NamePattern = term.NamePattern
 			for k, v := range term.Tags {
 				if v == "*" {
 					query.Filters = append(query.Filters, ec2types.Filter{
@@ -124,15 +165,47 @@ func (p *DefaultProvider) DescribeImageQueries(ctx context.Context, nodeClass *v
 					})
 				}
 			}
+			query.NewestCount = lo.FromPtr(term.NewestCount)
+			if term.MinAge != nil {
+				query.MinAge = term.MinAge.Duration
+			}
+			if term.MaxAge != nil {
+				query.MaxAge = term.MaxAge.Duration
+			}
 			queries = append(queries, query)
 		}
 	}
 	if len(idFilter.Values) > 0 {
 		queries = append(queries, DescribeImageQuery{Filters: []ec2types.Filter{idFilter}})
 	}
+	for i := range queries {
+		queries[i].Exclusions = nodeClass.Spec.AMIExclusionTerms
+	}
 	return queries, nil
 }
 
+// versionSegment matches a path segment naming a Kubernetes version, such as "1.31", "v1.31", or "k8s-1.31"
+var versionSegment = regexp.MustCompile(`^(?:.*[-_])?v?(1\.[0-9]+)$`)
+
+// amiIDsForVersion returns the AMI IDs stored in the parameters, skipping parameters for other Kubernetes versions
+func amiIDsForVersion(parameters map[string]string, kubernetesVersion string) []string {
+	var ids []string
+	for name, value := range parameters {
+		if !strings.HasPrefix(value, "ami-") {
+			continue
+		}
+		if lo.ContainsBy(strings.Split(name, "/"), func(segment string) bool {
+			match := versionSegment.FindStringSubmatch(segment)
+			return match != nil && match[1] != kubernetesVersion
+		}) {
+			continue
+		}
+		ids = append(ids, value)
+	}
+	sort.Strings(ids)
+	return lo.Uniq(ids)
+}
+
 //nolint:gocyclo
 func (p *DefaultProvider) amis(ctx context.Context, queries []DescribeImageQuery) (AMIs, error) {
 	hash, err := hashstructure.Hash(queries, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
@@ -144,47 +217,105 @@ func (p *DefaultProvider) amis(ctx context.Context, queries []DescribeImageQuery
 		// to the data don't affect the original
 		return append(AMIs{}, images.(AMIs)...), nil
 	}
-	images := map[uint64]AMI{}
-	for _, query := range queries {
-		paginator := ec2.NewDescribeImagesPaginator(p.ec2api, query.DescribeImagesInput())
-		for paginator.HasMorePages() {
-			page, err := paginator.NextPage(ctx)
-			if err != nil {
-				return nil, fmt.Errorf("describing images, %w", err)
+	// Candidates are grouped by their requirements, and the newest of each group are selected. Each group normally has a
+	// single AMI selected, unless a query which matched the group selects multiple with NewestCount. Since no group
+	// selects more than the largest NewestCount, each query only needs to keep that many candidates per group.
+	limit := int(lo.Max(append(lo.Map(queries, func(q DescribeImageQuery, _ int) int32 { return q.NewestCount }), 1)))
+	results := make([]map[uint64][]AMI, len(queries))
+	errs := make([]error, len(queries))
+	workqueue.ParallelizeUntil(ctx, maxConcurrentDescribeImagesRequests, len(queries), func(i int) {
+		results[i], errs[i] = p.describeImages(ctx, queries[i], limit)
+	})
+	if err := multierr.Combine(errs...); err != nil {
+		return nil, err
+	}
+	candidates := map[uint64][]AMI{}
+	newestCounts := map[uint64]int32{}
+	for i, result := range results {
+		for reqsHash, amis := range result {
+			candidates[reqsHash] = append(candidates[reqsHash], amis...)
+			newestCounts[reqsHash] = lo.Max([]int32{newestCounts[reqsHash], queries[i].NewestCount, 1})
+		}
+	}
+	images := AMIs{}
+	for reqsHash, amis := range candidates {
+		images = append(images, newestAMIs(amis, int(newestCounts[reqsHash]))...)
+	}
+	p.cache.SetDefault(fmt.Sprintf("%d", hash), images)
+	return append(AMIs{}, images...), nil
+}
+
+// describeImages returns the newest images matched by the query, grouped by the hash of their requirements, with at
+// most limit images in each group. Groups are trimmed after each page so that queries matching large numbers of images
+// aren't held in memory, and at most MaxDescribedImages images are described for the query.
+func (p *DefaultProvider) describeImages(ctx context.Context, query DescribeImageQuery, limit int) (map[uint64][]AMI, error) {
+	maxImages := options.FromContext(ctx).MaxDescribedImages
+	selectable, err := query.ImageFilter()
+	if err != nil {
+		return nil, err
+	}
+	candidates := map[uint64][]AMI{}
+	described := 0
+	paginator := ec2.NewDescribeImagesPaginator(p.ec2api, query.DescribeImagesInput())
+	for paginator.HasMorePages() {
+		if maxImages > 0 && described >= maxImages {
+			// The query is described again each time the AMI cache expires, so it's only logged once for each query
+			queryHash, _ := hashstructure.Hash([]interface{}{query.Owners, query.Filters}, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+			if p.cm.HasChanged(fmt.Sprintf("max-described-images/%016x", queryHash), maxImages) {
+				log.FromContext(ctx).WithValues("max-described-images", maxImages, "owners", query.Owners).Info("stopped describing images after reaching the maximum, newer images matched by the ami selector may not be considered")
 			}
-			for _, image := range page.Images {
-				arch, ok := v1.AWSToKubeArchitectures[string(image.Architecture)]
-				if !ok {
-					continue
-				}
-				// Each image may have multiple associated sets of requirements. For example, an image may be compatible with Neuron instances
-				// and GPU instances. In that case, we'll have a set of requirements for each, and will create one "image" for each.
-				for _, reqs := range query.RequirementsForImageWithArchitecture(lo.FromPtr(image.ImageId), arch) {
-					// Checks and store for AMIs
-					// Following checks are needed in order to always priortize non deprecated AMIs
-					// If we already have an image with the same set of requirements, but this image (candidate) is newer, replace the previous (existing) image.
-					// If we already have an image with the same set of requirements which is deprecated, but this image (candidate) is newer or non deprecated, replace the previous (existing) image
-					reqsHash := lo.Must(hashstructure.Hash(reqs.NodeSelectorRequirements(), hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true}))
-					candidateDeprecated := parseTimeWithDefault(lo.FromPtr(image.DeprecationTime), maxTime).Unix() <= p.clk.Now().Unix()
-					ami := AMI{
-						Name:         lo.FromPtr(image.Name),
-						AmiID:        lo.FromPtr(image.ImageId),
-						CreationDate: lo.FromPtr(image.CreationDate),
-						Deprecated:   candidateDeprecated,
-						Requirements: reqs,
-					}
-					if v, ok := images[reqsHash]; ok {
-						if cmpResult := compareAMI(v, ami); cmpResult <= 0 {
-							continue
-						}
-					}
-					images[reqsHash] = ami
-				}
+			break
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing images, %w", err)
+		}
+		images := page.Images
+		if maxImages > 0 {
+			images = images[:lo.Min([]int{len(images), maxImages - described})]
+		}
+		described += len(images)
+		for _, image := range images {
+			arch, ok := v1.AWSToKubeArchitectures[string(image.Architecture)]
+			if !ok {
+				continue
 			}
+			if !query.InAgeWindow(lo.FromPtr(image.CreationDate), p.clk.Now()) || !selectable(image) {
+				continue
+			}
+			// Each image may have multiple associated sets of requirements. For example, an image may be compatible with Neuron instances
+			// and GPU instances. In that case, we'll have a set of requirements for each, and will create one "image" for each.
+			for _, reqs := range query.RequirementsForImageWithArchitecture(lo.FromPtr(image.ImageId), arch) {
+				reqs.Add(capabilityRequirements(image)...)
+				reqsHash := lo.Must(hashstructure.Hash(reqs.NodeSelectorRequirements(), hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true}))
+				candidateDeprecated := parseTimeWithDefault(lo.FromPtr(image.DeprecationTime), maxTime).Unix() <= p.clk.Now().Unix()
+				candidates[reqsHash] = append(candidates[reqsHash], AMI{
+					Name:            lo.FromPtr(image.Name),
+					AmiID:           lo.FromPtr(image.ImageId),
+					CreationDate:    lo.FromPtr(image.CreationDate),
+					Deprecated:      candidateDeprecated,
+					DeprecationTime: lo.FromPtr(image.DeprecationTime),
+					Requirements:    reqs,
+					PlatformDetails: lo.FromPtr(image.PlatformDetails),
+					BootMode:        string(image.BootMode),
+					ENASupport:      image.EnaSupport,
+					TPMSupport:      string(image.TpmSupport),
+				})
+			}
+		}
+		for reqsHash, amis := range candidates {
+			candidates[reqsHash] = newestAMIs(amis, limit)
 		}
 	}
-	p.cache.SetDefault(fmt.Sprintf("%d", hash), AMIs(lo.Values(images)))
-	return lo.Values(images), nil
+	return candidates, nil
+}
+
+// newestAMIs returns the count newest of the AMIs, without duplicates
+func newestAMIs(amis []AMI, count int) []AMI {
+	// Following ordering is needed in order to always priortize non deprecated AMIs, and newer AMIs after that
+	sort.SliceStable(amis, func(i, j int) bool { return compareAMI(amis[i], amis[j]) < 0 })
+	amis = lo.UniqBy(amis, func(a AMI) string { return a.AmiID })
+	return amis[:lo.Min([]int{len(amis), count})]
 }
 
 // MapToInstanceTypes returns a map of AMIIDs that are the most recent on creationDate to compatible instancetypes
@@ -204,6 +335,65 @@ func MapToInstanceTypes(instanceTypes []*cloudprovider.InstanceType, amis []v1.A
 	return amiIDs
 }
 
+// EligibleAMIs returns the IDs of the AMIs that nodes of the instance type may run without being drifted. These are
+// the AMI that the instance type is mapped to and, if multiple AMIs were selected for its requirements, the older AMIs
+// which were selected along with it.
+func EligibleAMIs(instanceType *cloudprovider.InstanceType, amis []v1.AMI) []string {
+	newest, ok := MappedAMI(instanceType, amis)
+	if !ok {
+		return nil
+	}
+	reqsHash := lo.Must(hashstructure.Hash(newest.Requirements, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true}))
+	return lo.FilterMap(amis, func(ami v1.AMI, _ int) (string, bool) {
+		return ami.ID, lo.Must(hashstructure.Hash(ami.Requirements, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})) == reqsHash
+	})
+}
+
+// MappedAMI returns the AMI which nodes of the instance type are launched with, which is the first AMI whose
+// requirements are compatible with the instance type
+func MappedAMI(instanceType *cloudprovider.InstanceType, amis []v1.AMI) (v1.AMI, bool) {
+	return lo.Find(amis, func(ami v1.AMI) bool {
+		return instanceType.Requirements.Compatible(
+			scheduling.NewNodeSelectorRequirements(ami.Requirements...),
+			scheduling.AllowUndefinedWellKnownLabels,
+		) == nil
+	})
+}
+
+// capabilityRequirements returns the requirements that the capabilities the image declares place on the instance types
+// it's launched with, so that the image is never mapped to an instance type which can't run it. Elastic Fabric Adapters
+// are covered by the ENA requirement, since every instance type which supports EFA requires ENA.
+func capabilityRequirements(image ec2types.Image) []*scheduling.Requirement {
+	var requirements []*scheduling.Requirement
+	switch image.BootMode {
+	case ec2types.BootModeValuesLegacyBios, ec2types.BootModeValuesUefi:
+		requirements = append(requirements, scheduling.NewRequirement(v1.LabelInstanceBootMode, corev1.NodeSelectorOpIn, string(image.BootMode)))
+	}
+	if image.TpmSupport == ec2types.TpmSupportValuesV20 {
+		requirements = append(requirements, scheduling.NewRequirement(v1.LabelInstanceTPMSupported, corev1.NodeSelectorOpIn, "true"))
+	}
+	if image.EnaSupport != nil && !*image.EnaSupport {
+		requirements = append(requirements, scheduling.NewRequirement(v1.LabelInstanceENASupport, corev1.NodeSelectorOpNotIn, string(ec2types.EnaSupportRequired)))
+	}
+	return requirements
+}
+
+// IncompatibilityReason returns why instances of the instance type can't be launched with the AMI, or an empty string
+// if they can. Capabilities which either the AMI or the instance type doesn't declare are assumed to be compatible, so
+// that EC2 remains the final arbiter.
+func IncompatibilityReason(ami v1.AMI, info ec2types.InstanceTypeInfo) string {
+	if info.NetworkInfo != nil && info.NetworkInfo.EnaSupport == ec2types.EnaSupportRequired && ami.ENASupport != nil && !*ami.ENASupport {
+		return fmt.Sprintf("%s doesn't support ENA", ami.ID)
+	}
+	switch bootMode := ec2types.BootModeValues(ami.BootMode); bootMode {
+	case ec2types.BootModeValuesLegacyBios, ec2types.BootModeValuesUefi:
+		if len(info.SupportedBootModes) != 0 && !lo.Contains(info.SupportedBootModes, ec2types.BootModeType(bootMode)) {
+			return fmt.Sprintf("%s requires the %s boot mode", ami.ID, bootMode)
+		}
+	}
+	return ""
+}
+
 // Compare two AMI's based on their deprecation status, creation time or name
 // If both AMIs are deprecated, compare creation time and return the one with the newer creation time
 // If both AMIs are non-deprecated, compare creation time and return the one with the newer creation time
@@ -38,6 +38,19 @@ type Options struct {
 	ContainerRuntime    *string
 	CustomUserData      *string
 	InstanceStorePolicy *v1.InstanceStorePolicy
+	RegistryMirrors     []RegistryMirror
+	// Proxy is the HTTP proxy of the node's container runtime and kubelet
+	Proxy *v1.Proxy
+	// TrustedCABundle is the bundle of PEM encoded certificate authorities added to the node's trust stores
+	TrustedCABundle string
+	// Swap is the swap space that's provisioned on the node, and is only used by AL2023
+	Swap *v1.Swap
+	// UserDataMergeOrder is the position that the parts of CustomUserData are merged in relative to the bootstrap script
+	UserDataMergeOrder v1.UserDataMergeOrder
+	// DeviceValidation checks that the instance's devices came up before the node is bootstrapped
+	DeviceValidation *DeviceValidation
+	// KubeletConfigDropIns are merged over the kubelet's configuration, and are only used by AL2023 and Bottlerocket
+	KubeletConfigDropIns []v1.KubeletConfigDropIn
 }
 
 func (o Options) kubeletExtraArgs() (args []string) {
@@ -29,11 +29,17 @@ import (
 
 type Bottlerocket struct {
 	Options
+	// Settings are merged into the settings table of the custom UserData before Karpenter's settings are applied
+	Settings *v1.BottlerocketSettings `hash:"string"`
 }
 
 // nolint:gocyclo
 func (b Bottlerocket) Script() (string, error) {
-	s, err := NewBottlerocketConfig(b.CustomUserData)
+	userData, err := MergeBottlerocketSettings(b.CustomUserData, b.Settings)
+	if err != nil {
+		return "", err
+	}
+	s, err := NewBottlerocketConfig(userData)
 	if err != nil {
 		return "", fmt.Errorf("invalid UserData %w", err)
 	}
@@ -74,6 +80,10 @@ func (b Bottlerocket) Script() (string, error) {
 		}
 	}
 
+	if err := mergeBottlerocketKubeletConfigDropIns(&s.Settings.Kubernetes, b.KubeletConfigDropIns); err != nil {
+		return "", err
+	}
+
 	s.Settings.Kubernetes.NodeTaints = map[string][]string{}
 	for _, taint := range b.Taints {
 		s.Settings.Kubernetes.NodeTaints[taint.Key] = append(s.Settings.Kubernetes.NodeTaints[taint.Key], fmt.Sprintf("%s:%s", taint.Value, taint.Effect))
@@ -89,6 +99,21 @@ func (b Bottlerocket) Script() (string, error) {
 			Mode:      BootstrapCommandModeAlways,
 		}
 	}
+	if len(b.RegistryMirrors) != 0 {
+		if err := mergeBottlerocketRegistryMirrors(s, b.RegistryMirrors); err != nil {
+			return "", err
+		}
+	}
+	if b.TrustedCABundle != "" {
+		if err := mergeBottlerocketTrustedCABundle(s, b.TrustedCABundle); err != nil {
+			return "", err
+		}
+	}
+	if b.Proxy != nil {
+		if err := mergeBottlerocketProxy(s, b.Options); err != nil {
+			return "", err
+		}
+	}
 	script, err := s.MarshalTOML()
 	if err != nil {
 		return "", fmt.Errorf("constructing toml UserData %w", err)
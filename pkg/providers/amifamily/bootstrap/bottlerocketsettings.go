@@ -15,7 +15,36 @@ limitations under the License.
 package bootstrap
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
 	"github.com/pelletier/go-toml/v2"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// bottlerocketManagedSettings are the settings which Karpenter generates for every node, either unconditionally or from
+// the EC2NodeClass's kubelet configuration. They can't be set through an EC2NodeClass's bottlerocketSettings.
+var bottlerocketManagedSettings = sets.New(
+	"kubernetes.api-server",
+	"kubernetes.cluster-certificate",
+	"kubernetes.cluster-name",
+	"kubernetes.cluster-dns-ip",
+	"kubernetes.node-labels",
+	"kubernetes.node-taints",
+	"kubernetes.max-pods",
+	"kubernetes.system-reserved",
+	"kubernetes.kube-reserved",
+	"kubernetes.eviction-hard",
+	"kubernetes.image-gc-high-threshold-percent",
+	"kubernetes.image-gc-low-threshold-percent",
+	"kubernetes.cpu-cfs-quota-enforced",
 )
 
 func NewBottlerocketConfig(userdata *string) (*BottlerocketConfig, error) {
@@ -138,3 +167,83 @@ func (c *BottlerocketConfig) MarshalTOML() ([]byte, error) {
 	}
 	return toml.Marshal(c)
 }
+
+// MergeBottlerocketSettings merges structured settings into the settings table of the custom UserData. Settings which
+// Karpenter manages, or which the UserData sets to a different value, are reported as conflicts rather than silently
+// overwritten.
+func MergeBottlerocketSettings(userData *string, settings *v1.BottlerocketSettings) (*string, error) {
+	if settings == nil || len(settings.Raw) == 0 {
+		return userData, nil
+	}
+	var overrides map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(settings.Raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&overrides); err != nil {
+		return nil, fmt.Errorf("parsing bottlerocketSettings, %w", err)
+	}
+	doc := map[string]interface{}{}
+	if userData != nil {
+		if err := toml.Unmarshal([]byte(*userData), &doc); err != nil {
+			return nil, fmt.Errorf("invalid UserData %w", err)
+		}
+	}
+	base := map[string]interface{}{}
+	if raw, ok := doc["settings"]; ok {
+		if base, ok = raw.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("invalid UserData, settings must be a table")
+		}
+	}
+	var conflicts []string
+	doc["settings"] = mergeSettings(base, normalizeJSON(overrides).(map[string]interface{}), "", &conflicts)
+	if len(conflicts) != 0 {
+		sort.Strings(conflicts)
+		return nil, fmt.Errorf("bottlerocketSettings conflict with the node's settings, %s", strings.Join(conflicts, ", "))
+	}
+	merged, err := toml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("constructing toml UserData %w", err)
+	}
+	return lo.ToPtr(string(merged)), nil
+}
+
+func mergeSettings(base, overrides map[string]interface{}, prefix string, conflicts *[]string) map[string]interface{} {
+	for key, value := range overrides {
+		path := lo.Ternary(prefix == "", key, prefix+"."+key)
+		if bottlerocketManagedSettings.Has(path) {
+			*conflicts = append(*conflicts, fmt.Sprintf("%q is managed by Karpenter", path))
+			continue
+		}
+		existing, exists := base[key]
+		existingTable, existingIsTable := existing.(map[string]interface{})
+		valueTable, valueIsTable := value.(map[string]interface{})
+		switch {
+		// Tables are always merged key by key, so that managed settings nested within them are found
+		case valueIsTable && (!exists || existingIsTable):
+			base[key] = mergeSettings(lo.Ternary(exists, existingTable, map[string]interface{}{}), valueTable, path, conflicts)
+		case !exists:
+			base[key] = value
+		case !reflect.DeepEqual(existing, value):
+			*conflicts = append(*conflicts, fmt.Sprintf("%q is set to a different value in userData", path))
+		}
+	}
+	return base
+}
+
+// normalizeJSON converts JSON numbers to the types that TOML decodes them as, so that settings can be compared with
+// those decoded from the UserData
+func normalizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return lo.MapValues(v, func(e interface{}, _ string) interface{} { return normalizeJSON(e) })
+	case []interface{}:
+		return lo.Map(v, func(e interface{}, _ int) interface{} { return normalizeJSON(e) })
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+const (
+	// DeviceValidationFailedMessage is written to the console when the devices didn't come up, so that the registration
+	// failure can be classified from the instance's console output
+	DeviceValidationFailedMessage  = "karpenter device validation failed"
+	defaultDeviceValidationTimeout = 5 * time.Minute
+)
+
+// deviceValidationChecks are the shell conditions which hold once each kind of device is available. Devices are counted
+// from the PCI bus, which lists them even if their drivers failed to load.
+var deviceValidationChecks = map[v1.DeviceValidationDevice]string{
+	// NVIDIA 3D and VGA controllers
+	v1.DeviceValidationDeviceGPU: `[ "$(nvidia-smi -L 2>/dev/null | grep -c '^GPU')" -ge "$(pci_devices 0x10de '.*' '^0x030[02]')" ]`,
+	// Elastic Fabric Adapters
+	v1.DeviceValidationDeviceEFA: `[ "$(ls -1d /sys/class/infiniband/* 2>/dev/null | wc -l)" -ge "$(pci_devices 0x1d0f '^0xefa[0-9]$' '.*')" ]`,
+	// NVMe instance store controllers
+	v1.DeviceValidationDeviceNVMe: `[ "$(grep -l 'Instance Storage' /sys/class/nvme/nvme*/model 2>/dev/null | wc -l)" -ge "$(pci_devices 0x1d0f '^0xcd01$' '.*')" ]`,
+}
+
+// DeviceValidation checks that the instance's devices came up before the node bootstraps
+type DeviceValidation struct {
+	Devices []v1.DeviceValidationDevice
+	Timeout time.Duration
+}
+
+// NewDeviceValidation returns the DeviceValidation configured by an EC2NodeClass, if any
+func NewDeviceValidation(deviceValidation *v1.DeviceValidation) *DeviceValidation {
+	if deviceValidation == nil {
+		return nil
+	}
+	return &DeviceValidation{
+		Devices: deviceValidation.Devices,
+		Timeout: lo.Ternary(deviceValidation.Timeout != nil, lo.FromPtr(deviceValidation.Timeout).Duration, defaultDeviceValidationTimeout),
+	}
+}
+
+// shellScript returns a script which waits for the devices to be available, and stops the instance if any of them
+// aren't by the timeout. The instance is stopped rather than terminated so that its console output can be used to
+// classify the failure.
+func (d DeviceValidation) shellScript() string {
+	var b bytes.Buffer
+	b.WriteString("#!/bin/bash\n")
+	b.WriteString(`pci_devices() {
+  local count=0
+  for device in /sys/bus/pci/devices/*; do
+    [[ "$(cat "$device/vendor")" == "$1" && "$(cat "$device/device")" =~ $2 && "$(cat "$device/class")" =~ $3 ]] && count=$((count+1))
+  done
+  echo "$count"
+}
+`)
+	b.WriteString(fmt.Sprintf("deadline=$((SECONDS+%d))\n", int(d.Timeout.Seconds())))
+	for _, device := range d.Devices {
+		b.WriteString(fmt.Sprintf("until %s; do\n", deviceValidationChecks[device]))
+		b.WriteString("  if [ \"$SECONDS\" -ge \"$deadline\" ]; then\n")
+		b.WriteString(fmt.Sprintf("    echo \"%s: %s devices aren't available\" | tee /dev/console\n", DeviceValidationFailedMessage, device))
+		b.WriteString("    shutdown -h now\n")
+		b.WriteString("    exit 1\n")
+		b.WriteString("  fi\n")
+		b.WriteString("  sleep 5\n")
+		b.WriteString("done\n")
+	}
+	return b.String()
+}
@@ -41,10 +41,12 @@ const (
 	Boundary                      = "//"
 	MIMEVersionHeader             = "MIME-Version: 1.0"
 	MIMEContentTypeHeaderTemplate = "Content-Type: multipart/mixed; boundary=\"%s\""
+	// MergeOrderHeader overrides the EC2NodeClass's userDataMergeOrder for a part of a MIME multi-part custom UserData
+	MergeOrderHeader = "X-Karpenter-Merge-Order"
 )
 
 func (e EKS) Script() (string, error) {
-	userData, err := e.mergeCustomUserData(lo.Compact([]string{lo.FromPtr(e.CustomUserData), e.eksBootstrapScript()})...)
+	userData, err := e.mergeCustomUserData(lo.FromPtr(e.CustomUserData), e.eksBootstrapScript())
 	if err != nil {
 		return "", err
 	}
@@ -53,20 +55,38 @@ func (e EKS) Script() (string, error) {
 	return base64.StdEncoding.EncodeToString([]byte(strings.ReplaceAll(userData, "\r", ""))), nil
 }
 
-//nolint:gocyclo
 func (e EKS) eksBootstrapScript() string {
+	var userData bytes.Buffer
+	userData.WriteString("#!/bin/bash -xe\n")
+	userData.WriteString("exec > >(tee /var/log/user-data.log|logger -t user-data -s 2>/dev/console) 2>&1\n")
+	if e.TrustedCABundle != "" {
+		userData.WriteString(trustedCABundleShellScript(e.TrustedCABundle))
+	}
+	userData.WriteString(registryMirrorsShellScript(e.RegistryMirrors))
+	userData.WriteString(e.proxyShellScript())
+	userData.WriteString(e.bootstrapCommand("/etc/eks/bootstrap.sh"))
+	return userData.String()
+}
+
+// bootstrapCommand returns the invocation of the EKS bootstrap script at the given path. Besides the EKS optimized AMIs,
+// the script is shipped by other distributions which support EKS, such as Flatcar.
+//
+//nolint:gocyclo
+func (e EKS) bootstrapCommand(path string) string {
 	var caBundleArg string
 	if e.CABundle != nil {
 		caBundleArg = fmt.Sprintf("--b64-cluster-ca '%s'", *e.CABundle)
 	}
 	var userData bytes.Buffer
-	userData.WriteString("#!/bin/bash -xe\n")
-	userData.WriteString("exec > >(tee /var/log/user-data.log|logger -t user-data -s 2>/dev/console) 2>&1\n")
 	// Due to the way bootstrap.sh is written, parameters should not be passed to it with an equal sign
-	userData.WriteString(fmt.Sprintf("/etc/eks/bootstrap.sh '%s' --apiserver-endpoint '%s' %s", e.ClusterName, e.ClusterEndpoint, caBundleArg))
+	userData.WriteString(fmt.Sprintf("%s '%s' --apiserver-endpoint '%s' %s", path, e.ClusterName, e.ClusterEndpoint, caBundleArg))
 
 	if e.isIPv6() {
 		userData.WriteString(" \\\n--ip-family ipv6")
+		// The script can't discover the service CIDR itself when it's passed the cluster's endpoint and CA bundle
+		if cidr := lo.FromPtr(e.ClusterCIDR); cidr != "" {
+			userData.WriteString(fmt.Sprintf(" \\\n--service-ipv6-cidr '%s'", cidr))
+		}
 	}
 	if e.KubeletConfig != nil && len(e.KubeletConfig.ClusterDNS) > 0 {
 		userData.WriteString(fmt.Sprintf(" \\\n--dns-cluster-ip '%s'", e.KubeletConfig.ClusterDNS[0]))
@@ -83,7 +103,9 @@ func (e EKS) eksBootstrapScript() string {
 	return userData.String()
 }
 
-func (e EKS) mergeCustomUserData(userDatas ...string) (string, error) {
+// mergeCustomUserData merges the parts of the custom UserData with the bootstrap script. Parts are merged before the
+// bootstrap script unless the merge order, or the part's merge order header, places them after it.
+func (e EKS) mergeCustomUserData(customUserData string, bootstrapScript string) (string, error) {
 	var outputBuffer bytes.Buffer
 	writer := multipart.NewWriter(&outputBuffer)
 	if err := writer.SetBoundary(Boundary); err != nil {
@@ -91,12 +113,36 @@ func (e EKS) mergeCustomUserData(userDatas ...string) (string, error) {
 	}
 	outputBuffer.WriteString(MIMEVersionHeader + "\n")
 	outputBuffer.WriteString(fmt.Sprintf(MIMEContentTypeHeaderTemplate, Boundary) + "\n\n")
-	for _, userData := range userDatas {
-		mimedUserData, err := e.mimeify(userData)
+	customParts, err := e.customUserDataParts(customUserData)
+	if err != nil {
+		return "", err
+	}
+	mimedBootstrapScript, err := e.mimeify(bootstrapScript)
+	if err != nil {
+		return "", err
+	}
+	bootstrapParts, err := readUserDataParts(mimedBootstrapScript)
+	if err != nil {
+		return "", err
+	}
+	// Devices are validated after the custom parts that are merged before the bootstrap script, since those may install
+	// the devices' drivers
+	if e.DeviceValidation != nil {
+		mimedDeviceValidation, err := e.mimeify(e.DeviceValidation.shellScript())
+		if err != nil {
+			return "", err
+		}
+		deviceValidationParts, err := readUserDataParts(mimedDeviceValidation)
 		if err != nil {
 			return "", err
 		}
-		if err := copyCustomUserDataParts(writer, mimedUserData); err != nil {
+		bootstrapParts = append(deviceValidationParts, bootstrapParts...)
+	}
+	afterBootstrap, beforeBootstrap := lo.FilterReject(customParts, func(p userDataPart, _ int) bool {
+		return p.mergeOrder == v1.UserDataMergeOrderAfterBootstrap
+	})
+	for _, part := range lo.Flatten([][]userDataPart{beforeBootstrap, bootstrapParts, afterBootstrap}) {
+		if err := part.write(writer); err != nil {
 			return "", err
 		}
 	}
@@ -104,6 +150,29 @@ func (e EKS) mergeCustomUserData(userDatas ...string) (string, error) {
 	return outputBuffer.String(), nil
 }
 
+// customUserDataParts returns the parts of the custom UserData, with the merge order that each part is merged in
+func (e EKS) customUserDataParts(customUserData string) ([]userDataPart, error) {
+	if customUserData == "" {
+		return nil, nil
+	}
+	mimedUserData, err := e.mimeify(customUserData)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := readUserDataParts(mimedUserData)
+	if err != nil {
+		return nil, err
+	}
+	for i := range parts {
+		parts[i].mergeOrder = lo.CoalesceOrEmpty(v1.UserDataMergeOrder(parts[i].header.Get(MergeOrderHeader)), e.UserDataMergeOrder, v1.UserDataMergeOrderBeforeBootstrap)
+		if parts[i].mergeOrder != v1.UserDataMergeOrderBeforeBootstrap && parts[i].mergeOrder != v1.UserDataMergeOrderAfterBootstrap {
+			return nil, fmt.Errorf("parsing custom user data input, %s must be %s or %s, got %q", MergeOrderHeader, v1.UserDataMergeOrderBeforeBootstrap, v1.UserDataMergeOrderAfterBootstrap, parts[i].mergeOrder)
+		}
+		parts[i].header.Del(MergeOrderHeader)
+	}
+	return parts, nil
+}
+
 func (e EKS) isIPv6() bool {
 	if e.KubeletConfig == nil || len(e.KubeletConfig.ClusterDNS) == 0 {
 		return false
@@ -136,39 +205,46 @@ func (e EKS) mimeify(customUserData string) (string, error) {
 	return outputBuffer.String(), nil
 }
 
-// copyCustomUserDataParts reads the mime parts in the userData passed in and writes
-// to a new mime part in the passed in writer.
-func copyCustomUserDataParts(writer *multipart.Writer, customUserData string) error {
-	if customUserData == "" {
-		// No custom user data specified, so nothing to copy over.
-		return nil
-	}
-	reader, err := getMultiPartReader(customUserData)
+// userDataPart is a part of a MIME multi-part UserData
+type userDataPart struct {
+	header     textproto.MIMEHeader
+	content    []byte
+	mergeOrder v1.UserDataMergeOrder
+}
+
+// write copies the part to a new mime part in the passed in writer
+func (p userDataPart) write(writer *multipart.Writer) error {
+	partWriter, err := writer.CreatePart(p.header)
 	if err != nil {
 		return fmt.Errorf("parsing custom user data input %w", err)
 	}
+	if _, err = partWriter.Write(p.content); err != nil {
+		return fmt.Errorf("parsing custom user data input %w", err)
+	}
+	return nil
+}
+
+// readUserDataParts reads the mime parts in the userData passed in
+func readUserDataParts(userData string) ([]userDataPart, error) {
+	reader, err := getMultiPartReader(userData)
+	if err != nil {
+		return nil, fmt.Errorf("parsing custom user data input %w", err)
+	}
+	var parts []userDataPart
 	for {
 		p, err := reader.NextPart()
 		if errors.Is(err, io.EOF) {
-			break
+			return parts, nil
 		}
 		if err != nil {
-			return fmt.Errorf("parsing custom user data input %w", err)
+			return nil, fmt.Errorf("parsing custom user data input %w", err)
 		}
 		slurp, err := io.ReadAll(p)
 		if err != nil {
-			return fmt.Errorf("parsing custom user data input %w", err)
-		}
-		partWriter, err := writer.CreatePart(p.Header)
-		if err != nil {
-			return fmt.Errorf("parsing custom user data input %w", err)
-		}
-		_, err = partWriter.Write(slurp)
-		if err != nil {
-			return fmt.Errorf("parsing custom user data input %w", err)
+			return nil, fmt.Errorf("parsing custom user data input %w", err)
 		}
+		parts = append(parts, userDataPart{header: p.Header, content: slurp})
 	}
-	return nil
 }
 
 func getMultiPartReader(userData string) (*multipart.Reader, error) {
@@ -0,0 +1,159 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+const (
+	// IgnitionVersion is the Ignition config spec version generated for Flatcar nodes. Flatcar supports all 3.x specs,
+	// so custom user data may use a different 3.x version than the config it's merged into.
+	IgnitionVersion = "3.3.0"
+	// FlatcarBootstrapScriptPath is the path of the EKS bootstrap script shipped in Flatcar's AWS images
+	FlatcarBootstrapScriptPath = "/usr/share/amazon/eks/bootstrap.sh"
+	// FlatcarBootstrapUnit is the name of the systemd unit which joins a Flatcar node to the cluster
+	FlatcarBootstrapUnit = "karpenter-bootstrap.service"
+
+	flatcarBootstrapWrapperPath = "/opt/karpenter/bootstrap.sh"
+)
+
+// IgnitionConfig is the subset of the Ignition config spec that Karpenter generates for Flatcar nodes
+type IgnitionConfig struct {
+	Ignition IgnitionMetadata `json:"ignition"`
+	Storage  *IgnitionStorage `json:"storage,omitempty"`
+	Systemd  *IgnitionSystemd `json:"systemd,omitempty"`
+}
+
+type IgnitionMetadata struct {
+	Version string               `json:"version"`
+	Config  *IgnitionConfigMerge `json:"config,omitempty"`
+}
+
+type IgnitionConfigMerge struct {
+	Merge []IgnitionResource `json:"merge,omitempty"`
+}
+
+type IgnitionResource struct {
+	Source string `json:"source"`
+}
+
+type IgnitionStorage struct {
+	Files []IgnitionFile `json:"files,omitempty"`
+}
+
+type IgnitionFile struct {
+	Path      string           `json:"path"`
+	Mode      *int             `json:"mode,omitempty"`
+	Overwrite *bool            `json:"overwrite,omitempty"`
+	Contents  IgnitionResource `json:"contents"`
+}
+
+type IgnitionSystemd struct {
+	Units []IgnitionUnit `json:"units,omitempty"`
+}
+
+type IgnitionUnit struct {
+	Name     string `json:"name"`
+	Enabled  *bool  `json:"enabled,omitempty"`
+	Contents string `json:"contents,omitempty"`
+}
+
+// Flatcar generates an Ignition config which joins the node to the cluster with the EKS bootstrap script. Custom
+// user data must also be an Ignition config, and is merged into the generated config by Ignition on boot, so it can
+// add files and units or override the ones Karpenter generates.
+type Flatcar struct {
+	Options
+}
+
+func (f Flatcar) Script() (string, error) {
+	config := IgnitionConfig{
+		Ignition: IgnitionMetadata{Version: IgnitionVersion},
+		Storage: &IgnitionStorage{
+			Files: []IgnitionFile{{
+				Path:      flatcarBootstrapWrapperPath,
+				Mode:      lo.ToPtr(0o755),
+				Overwrite: lo.ToPtr(true),
+				Contents:  IgnitionResource{Source: dataURL(f.bootstrapScript())},
+			}},
+		},
+		Systemd: &IgnitionSystemd{
+			Units: []IgnitionUnit{{
+				Name:     FlatcarBootstrapUnit,
+				Enabled:  lo.ToPtr(true),
+				Contents: f.bootstrapUnit(),
+			}},
+		},
+	}
+	if f.CustomUserData != nil && strings.TrimSpace(*f.CustomUserData) != "" {
+		if err := validateIgnitionConfig(*f.CustomUserData); err != nil {
+			return "", err
+		}
+		config.Ignition.Config = &IgnitionConfigMerge{
+			Merge: []IgnitionResource{{Source: dataURL(*f.CustomUserData)}},
+		}
+	}
+	userData, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("marshaling ignition config, %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(userData), nil
+}
+
+// bootstrapScript wraps the EKS bootstrap script invocation in a script file rather than inlining it in the unit, since
+// the kubelet arguments may contain characters such as '%' which systemd would interpret as specifiers
+func (f Flatcar) bootstrapScript() string {
+	return fmt.Sprintf("#!/bin/bash -xe\n%s\n", EKS{Options: f.Options}.bootstrapCommand(FlatcarBootstrapScriptPath))
+}
+
+func (f Flatcar) bootstrapUnit() string {
+	return strings.Join([]string{
+		"[Unit]",
+		"Description=Join the node to the EKS cluster",
+		"Wants=network-online.target",
+		"After=network-online.target",
+		"",
+		"[Service]",
+		"Type=oneshot",
+		"RemainAfterExit=yes",
+		fmt.Sprintf("ExecStart=%s", flatcarBootstrapWrapperPath),
+		"",
+		"[Install]",
+		"WantedBy=multi-user.target",
+		"",
+	}, "\n")
+}
+
+// validateIgnitionConfig ensures that custom user data is an Ignition config of a spec version Flatcar supports.
+// Ignition fails the boot on an invalid config, so it's better to surface the error before launching an instance.
+func validateIgnitionConfig(userData string) error {
+	config := IgnitionConfig{}
+	if err := json.Unmarshal([]byte(userData), &config); err != nil {
+		return fmt.Errorf("parsing custom user data as an ignition config, %w", err)
+	}
+	if !strings.HasPrefix(config.Ignition.Version, "3.") {
+		return fmt.Errorf("custom user data must be an ignition config with a 3.x spec version, got %q", config.Ignition.Version)
+	}
+	return nil
+}
+
+func dataURL(contents string) string {
+	return fmt.Sprintf("data:;base64,%s", base64.StdEncoding.EncodeToString([]byte(contents)))
+}
@@ -0,0 +1,115 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+const (
+	// kubeletConfigDropInDir is the drop-in directory that nodeadm configures the kubelet with on AL2023. nodeadm writes
+	// its own drop-in as 00-nodeadm.conf, so the EC2NodeClass's drop-ins are numbered from 10 to be merged after it.
+	kubeletConfigDropInDir = "/etc/kubernetes/kubelet/config.json.d"
+	// kubeletConfigDropInDelimiter terminates the heredocs which write the drop-in files
+	kubeletConfigDropInDelimiter = "KARPENTER_KUBELET_CONFIG"
+)
+
+// bottlerocketKubeletSettings maps the kubelet configuration fields which Bottlerocket exposes as settings
+// (https://bottlerocket.dev/en/os/latest/#/api/settings/kubernetes/) to the names of their settings. Fields which
+// Karpenter manages are excluded.
+var bottlerocketKubeletSettings = map[string]string{
+	"allowedUnsafeSysctls":            "allowed-unsafe-sysctls",
+	"clusterDomain":                   "cluster-domain",
+	"containerLogMaxFiles":            "container-log-max-files",
+	"containerLogMaxSize":             "container-log-max-size",
+	"cpuManagerPolicy":                "cpu-manager-policy",
+	"cpuManagerReconcilePeriod":       "cpu-manager-reconcile-period",
+	"eventBurst":                      "event-burst",
+	"eventRecordQPS":                  "event-qps",
+	"kubeAPIBurst":                    "kube-api-burst",
+	"kubeAPIQPS":                      "kube-api-qps",
+	"podPidsLimit":                    "pod-pids-limit",
+	"registryBurst":                   "registry-burst",
+	"registryPullQPS":                 "registry-qps",
+	"seccompDefault":                  "seccomp-default",
+	"serverTLSBootstrap":              "server-tls-bootstrap",
+	"shutdownGracePeriod":             "shutdown-grace-period",
+	"shutdownGracePeriodCriticalPods": "shutdown-grace-period-for-critical-pods",
+	"topologyManagerPolicy":           "topology-manager-policy",
+	"topologyManagerScope":            "topology-manager-scope",
+}
+
+// kubeletConfigDropInsShellScript returns a script which writes the drop-ins to the kubelet's drop-in directory, named
+// so that the kubelet merges them in the order they're listed
+func kubeletConfigDropInsShellScript(dropIns []v1.KubeletConfigDropIn) (string, error) {
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("mkdir -p '%s'\n", kubeletConfigDropInDir))
+	for i, dropIn := range dropIns {
+		config := map[string]json.RawMessage{
+			"apiVersion": json.RawMessage(`"kubelet.config.k8s.io/v1beta1"`),
+			"kind":       json.RawMessage(`"KubeletConfiguration"`),
+		}
+		for k, v := range dropIn.Config {
+			config[k] = v.Raw
+		}
+		content, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("marshaling kubelet config drop-in %q, %w", dropIn.Name, err)
+		}
+		b.WriteString(fmt.Sprintf("cat > '%s/%02d-%s.conf' <<'%s'\n%s\n%s\n", kubeletConfigDropInDir, 10+i, dropIn.Name, kubeletConfigDropInDelimiter, content, kubeletConfigDropInDelimiter))
+	}
+	return b.String(), nil
+}
+
+// mergeBottlerocketKubeletConfigDropIns sets the Bottlerocket settings that are equivalent to the drop-ins' fields, in
+// the order the drop-ins are listed. Fields which Bottlerocket doesn't expose as settings can't be configured.
+func mergeBottlerocketKubeletConfigDropIns(settings *BottlerocketKubernetes, dropIns []v1.KubeletConfigDropIn) error {
+	for _, dropIn := range dropIns {
+		fields := lo.Keys(dropIn.Config)
+		sort.Strings(fields)
+		for _, field := range fields {
+			name, ok := bottlerocketKubeletSettings[field]
+			if !ok {
+				supported := lo.Keys(bottlerocketKubeletSettings)
+				sort.Strings(supported)
+				return fmt.Errorf("kubelet config drop-in %q, field %q isn't supported by Bottlerocket, supported fields are %s", dropIn.Name, field, strings.Join(supported, ", "))
+			}
+			if err := setBottlerocketKubernetesSetting(settings, name, dropIn.Config[field].Raw); err != nil {
+				return fmt.Errorf("kubelet config drop-in %q, field %q, %w", dropIn.Name, field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setBottlerocketKubernetesSetting decodes the JSON value into the field of the settings with the setting's name
+func setBottlerocketKubernetesSetting(settings *BottlerocketKubernetes, name string, raw []byte) error {
+	v := reflect.ValueOf(settings).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Split(v.Type().Field(i).Tag.Get("toml"), ",")[0] == name {
+			return json.Unmarshal(raw, v.Field(i).Addr().Interface())
+		}
+	}
+	return fmt.Errorf("setting %q isn't modeled", name)
+}
@@ -21,6 +21,7 @@ import (
 
 	admapi "github.com/awslabs/amazon-eks-ami/nodeadm/api"
 	admv1alpha1 "github.com/awslabs/amazon-eks-ami/nodeadm/api/v1alpha1"
+	"github.com/pelletier/go-toml/v2"
 	"github.com/samber/lo"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -34,6 +35,8 @@ import (
 
 type Nodeadm struct {
 	Options
+	// NodeConfig is merged into the generated NodeConfig. Karpenter's settings take precedence over it.
+	NodeConfig *v1.NodeConfig
 }
 
 func (n Nodeadm) Script() (string, error) {
@@ -45,6 +48,47 @@ func (n Nodeadm) Script() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("parsing custom UserData, %w", err)
 	}
+	// Shell scripts run before nodeadm starts the kubelet, so the node doesn't join the cluster if its devices didn't come up
+	if n.DeviceValidation != nil {
+		customEntries = append(customEntries, mime.Entry{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     n.DeviceValidation.shellScript(),
+		})
+	}
+	if len(n.KubeletConfigDropIns) != 0 {
+		script, err := kubeletConfigDropInsShellScript(n.KubeletConfigDropIns)
+		if err != nil {
+			return "", err
+		}
+		customEntries = append(customEntries, mime.Entry{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     "#!/bin/bash\n" + script,
+		})
+	}
+	if n.TrustedCABundle != "" {
+		customEntries = append(customEntries, mime.Entry{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     "#!/bin/bash\n" + trustedCABundleShellScript(n.TrustedCABundle),
+		})
+	}
+	if len(n.RegistryMirrors) != 0 {
+		customEntries = append(customEntries, mime.Entry{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     "#!/bin/bash\n" + registryMirrorsShellScript(n.RegistryMirrors),
+		})
+	}
+	if n.Proxy != nil {
+		customEntries = append(customEntries, mime.Entry{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     "#!/bin/bash\n" + n.proxyShellScript(),
+		})
+	}
+	if n.Swap != nil {
+		customEntries = append(customEntries, mime.Entry{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     "#!/bin/bash\n" + swapShellScript(n.Swap),
+		})
+	}
 	mimeArchive := mime.Archive(append(customEntries, mime.Entry{
 		ContentType: mime.ContentTypeNodeConfig,
 		Content:     nodeConfigYAML,
@@ -94,6 +138,9 @@ func (n Nodeadm) getNodeConfigYAML() (string, error) {
 	if arg := n.nodeLabelArg(); arg != "" {
 		config.Spec.Kubelet.Flags = []string{arg}
 	}
+	if err = n.mergeNodeConfig(config); err != nil {
+		return "", err
+	}
 
 	// Convert to YAML at the end for improved legibility.
 	configYAML, err := yaml.Marshal(config)
@@ -103,6 +150,33 @@ func (n Nodeadm) getNodeConfigYAML() (string, error) {
 	return fmt.Sprintf("# Karpenter Generated NodeConfig\n%s", string(configYAML)), nil
 }
 
+// mergeNodeConfig merges the EC2NodeClass's NodeConfig into the generated NodeConfig. Kubelet config fields that
+// Karpenter generates are rejected at admission, and are rejected here too rather than silently dropped.
+func (n Nodeadm) mergeNodeConfig(config *admv1alpha1.NodeConfig) error {
+	if n.NodeConfig == nil {
+		return nil
+	}
+	if kubelet := n.NodeConfig.Kubelet; kubelet != nil {
+		for k, v := range kubelet.Config {
+			if _, ok := config.Spec.Kubelet.Config[k]; ok {
+				return fmt.Errorf("kubelet config field %q is managed by Karpenter", k)
+			}
+			config.Spec.Kubelet.Config[k] = runtime.RawExtension{Raw: v.Raw}
+		}
+		config.Spec.Kubelet.Flags = append(config.Spec.Kubelet.Flags, kubelet.Flags...)
+	}
+	if containerd := n.NodeConfig.Containerd; containerd != nil && containerd.Config != "" {
+		if err := toml.Unmarshal([]byte(containerd.Config), lo.ToPtr(map[string]any{})); err != nil {
+			return fmt.Errorf("parsing containerd config, %w", err)
+		}
+		config.Spec.Containerd.Config = containerd.Config
+	}
+	if instance := n.NodeConfig.Instance; instance != nil && instance.LocalStorage != nil {
+		config.Spec.Instance.LocalStorage.Strategy = admv1alpha1.LocalStorageStrategy(instance.LocalStorage.Strategy)
+	}
+	return nil
+}
+
 // generateInlineKubeletConfiguration returns a serialized form of the KubeletConfiguration specified by the Nodeadm
 // options, for use with nodeadm's NodeConfig struct.
 func (n Nodeadm) generateInlineKubeletConfiguration() (map[string]runtime.RawExtension, error) {
@@ -118,6 +192,11 @@ func (n Nodeadm) generateInlineKubeletConfiguration() (map[string]runtime.RawExt
 	kubeConfigMap["registerWithTaints"] = runtime.RawExtension{
 		Raw: lo.Must(json.Marshal(n.Taints)),
 	}
+	if n.Swap != nil {
+		for k, v := range swapKubeletConfig(n.Swap) {
+			kubeConfigMap[k] = runtime.RawExtension{Raw: lo.Must(json.Marshal(v))}
+		}
+	}
 	return kubeConfigMap, nil
 }
 
@@ -0,0 +1,158 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+const (
+	// proxyDelimiter terminates the heredocs which write the proxy configuration
+	proxyDelimiter = "KARPENTER_PROXY"
+	// proxyDropIn is the name of the systemd drop-ins which set the proxy environment of the node's services
+	proxyDropIn = "http-proxy.conf"
+)
+
+// proxiedServices are the services which pull images and reach the API server, and so need the proxy environment
+var proxiedServices = []string{"containerd", "kubelet"}
+
+// noProxy returns the hosts which are reached without the proxy. Along with the EC2NodeClass's noProxy, these are the
+// hosts which the node reaches during bootstrap or which are never reachable through a proxy.
+func (o Options) noProxy() []string {
+	hosts := []string{"localhost", "127.0.0.1", "169.254.169.254", ".internal"}
+	if u, err := url.Parse(o.ClusterEndpoint); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, u.Hostname())
+	}
+	if cidr := lo.FromPtr(o.ClusterCIDR); cidr != "" {
+		hosts = append(hosts, cidr)
+	}
+	return lo.Uniq(append(hosts, o.Proxy.NoProxy...))
+}
+
+// proxyEnvironment returns the proxy environment variables, in upper and lower case since programs disagree on which
+// they read
+func (o Options) proxyEnvironment() []string {
+	var env []string
+	add := func(name, value string) {
+		env = append(env, fmt.Sprintf("%s=%s", name, value), fmt.Sprintf("%s=%s", strings.ToLower(name), value))
+	}
+	if o.Proxy.HTTPProxy != nil {
+		add("HTTP_PROXY", *o.Proxy.HTTPProxy)
+	}
+	if o.Proxy.HTTPSProxy != nil {
+		add("HTTPS_PROXY", *o.Proxy.HTTPSProxy)
+	}
+	add("NO_PROXY", strings.Join(o.noProxy(), ","))
+	return env
+}
+
+// proxyShellScript returns a script which sets the proxy environment of containerd and the kubelet through systemd
+// drop-ins, and of login shells through /etc/environment. Containerd is restarted if it's already running, so that it
+// picks up the proxy before pulling any images.
+func (o Options) proxyShellScript() string {
+	if o.Proxy == nil {
+		return ""
+	}
+	env := o.proxyEnvironment()
+	var b bytes.Buffer
+	for _, service := range proxiedServices {
+		dir := fmt.Sprintf("/etc/systemd/system/%s.service.d", service)
+		b.WriteString(fmt.Sprintf("mkdir -p '%s'\n", dir))
+		b.WriteString(fmt.Sprintf("cat > '%s/%s' <<'%s'\n[Service]\n", dir, proxyDropIn, proxyDelimiter))
+		for _, e := range env {
+			b.WriteString(fmt.Sprintf("Environment=\"%s\"\n", e))
+		}
+		b.WriteString(proxyDelimiter + "\n")
+	}
+	b.WriteString(fmt.Sprintf("cat >> /etc/environment <<'%s'\n%s\n%s\n", proxyDelimiter, strings.Join(env, "\n"), proxyDelimiter))
+	b.WriteString("systemctl daemon-reload\n")
+	b.WriteString("systemctl try-restart containerd\n")
+	return b.String()
+}
+
+// proxyPowerShell returns a PowerShell script which sets the proxy environment of the machine, the current process so
+// that it's used by the bootstrap script, and containerd. Services read the machine environment when Windows boots, so
+// containerd's environment is set on the service itself. The WinHTTP proxy is set for the system components which use it.
+func (o Options) proxyPowerShell() string {
+	if o.Proxy == nil {
+		return ""
+	}
+	var b bytes.Buffer
+	for _, e := range o.proxyEnvironment() {
+		// Windows environment variables are case-insensitive
+		name, value, _ := strings.Cut(e, "=")
+		if name != strings.ToUpper(name) {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("[Environment]::SetEnvironmentVariable('%s', '%s', 'Machine')\n", name, value))
+		b.WriteString(fmt.Sprintf("$env:%s = '%s'\n", name, value))
+	}
+	b.WriteString(o.proxyServicePowerShell("containerd"))
+	if server := lo.CoalesceOrEmpty(lo.FromPtr(o.Proxy.HTTPProxy), lo.FromPtr(o.Proxy.HTTPSProxy)); server != "" {
+		// The WinHTTP bypass list takes wildcards rather than domain suffixes, and doesn't support CIDRs
+		bypass := lo.FilterMap(o.noProxy(), func(host string, _ int) (string, bool) {
+			if strings.HasPrefix(host, ".") {
+				host = "*" + host
+			}
+			return host, !strings.Contains(host, "/")
+		})
+		_, host, _ := strings.Cut(server, "://")
+		b.WriteString(fmt.Sprintf("netsh winhttp set proxy proxy-server=\"%s\" bypass-list=\"%s\" | Out-Null\n", strings.TrimSuffix(host, "/"), strings.Join(bypass, ";")))
+	}
+	return b.String()
+}
+
+// proxyServicePowerShell returns a PowerShell script which sets the proxy environment of a service and restarts it
+func (o Options) proxyServicePowerShell(service string) string {
+	if o.Proxy == nil {
+		return ""
+	}
+	env := lo.Filter(o.proxyEnvironment(), func(e string, _ int) bool {
+		name, _, _ := strings.Cut(e, "=")
+		return name == strings.ToUpper(name)
+	})
+	values := lo.Map(env, func(e string, _ int) string { return fmt.Sprintf("'%s'", e) })
+	return fmt.Sprintf("Set-ItemProperty -Path 'HKLM:\\SYSTEM\\CurrentControlSet\\Services\\%s' -Name Environment -Type MultiString -Value @(%s)\nRestart-Service %s -ErrorAction SilentlyContinue\n",
+		service, strings.Join(values, ", "), service)
+}
+
+// mergeBottlerocketProxy sets the network settings (https://bottlerocket.dev/en/os/latest/#/api/settings/network/) of
+// the config. Bottlerocket's https-proxy is used for both HTTP and HTTPS requests, and it reaches the API server and
+// localhost without the proxy on its own.
+func mergeBottlerocketProxy(config *BottlerocketConfig, o Options) error {
+	if config.SettingsRaw == nil {
+		config.SettingsRaw = map[string]interface{}{}
+	}
+	networkSettings, ok := config.SettingsRaw["network"].(map[string]interface{})
+	if !ok {
+		networkSettings = map[string]interface{}{}
+	}
+	for _, key := range []string{"https-proxy", "no-proxy"} {
+		if _, ok := networkSettings[key]; ok {
+			return fmt.Errorf("network setting %q is configured in both proxy and userData", key)
+		}
+	}
+	networkSettings["https-proxy"] = lo.CoalesceOrEmpty(lo.FromPtr(o.Proxy.HTTPSProxy), lo.FromPtr(o.Proxy.HTTPProxy))
+	if len(o.Proxy.NoProxy) != 0 {
+		networkSettings["no-proxy"] = o.Proxy.NoProxy
+	}
+	config.SettingsRaw["network"] = networkSettings
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// containerdHostsDir is the directory that containerd reads registry host configuration from. The AL2 and AL2023
+	// EKS optimized AMIs configure containerd's CRI plugin with this config_path.
+	containerdHostsDir = "/etc/containerd/certs.d"
+	// hostsFileDelimiter terminates the heredocs which write the hosts files
+	hostsFileDelimiter = "KARPENTER_REGISTRY_HOSTS"
+)
+
+// RegistryMirror is an EC2NodeClass's registry mirror, with its credentials resolved
+type RegistryMirror struct {
+	Registry  string
+	Endpoints []string
+	Username  string
+	Password  string
+}
+
+func (m RegistryMirror) hasCredentials() bool {
+	return m.Username != "" || m.Password != ""
+}
+
+// hostsFile returns the containerd hosts.toml (https://github.com/containerd/containerd/blob/main/docs/hosts.md) which
+// directs pulls from the mirrored registry to its mirrors. The registry itself is used when none of the mirrors can
+// serve an image.
+func (m RegistryMirror) hostsFile() string {
+	var b bytes.Buffer
+	for _, endpoint := range m.Endpoints {
+		b.WriteString(fmt.Sprintf("[host.%q]\n", endpoint))
+		b.WriteString("  capabilities = [\"pull\", \"resolve\"]\n")
+		if m.hasCredentials() {
+			auth := base64.StdEncoding.EncodeToString([]byte(m.Username + ":" + m.Password))
+			b.WriteString(fmt.Sprintf("  [host.%q.header]\n", endpoint))
+			b.WriteString(fmt.Sprintf("    Authorization = [%q]\n", "Basic "+auth))
+		}
+	}
+	return b.String()
+}
+
+// registryMirrorsShellScript returns a script which writes the hosts files of the mirrors
+func registryMirrorsShellScript(mirrors []RegistryMirror) string {
+	var b bytes.Buffer
+	for _, m := range mirrors {
+		dir := fmt.Sprintf("%s/%s", containerdHostsDir, m.Registry)
+		b.WriteString(fmt.Sprintf("mkdir -p '%s'\n", dir))
+		b.WriteString(fmt.Sprintf("cat > '%s/hosts.toml' <<'%s'\n%s%s\n", dir, hostsFileDelimiter, m.hostsFile(), hostsFileDelimiter))
+	}
+	return b.String()
+}
+
+// registryMirrorsPowerShell returns a PowerShell script which writes the hosts files of the mirrors to the certs.d
+// directory of the containerd installation
+func registryMirrorsPowerShell(mirrors []RegistryMirror) (string, error) {
+	var b bytes.Buffer
+	for _, m := range mirrors {
+		// Hosts directories are named after the registry, and a port separator isn't valid in a Windows path
+		if strings.Contains(m.Registry, ":") {
+			return "", fmt.Errorf("mirroring registry %q, registries with a port aren't supported on Windows", m.Registry)
+		}
+		dir := fmt.Sprintf(`$env:ProgramFiles\containerd\certs.d\%s`, m.Registry)
+		b.WriteString(fmt.Sprintf("New-Item -ItemType Directory -Force -Path \"%s\" | Out-Null\n", dir))
+		b.WriteString(fmt.Sprintf("Set-Content -Path \"%s\\hosts.toml\" -Value @'\n%s'@\n", dir, m.hostsFile()))
+	}
+	return b.String(), nil
+}
+
+// mergeBottlerocketRegistryMirrors adds the mirrors to the container-registry settings (https://bottlerocket.dev/en/os/latest/#/api/settings/container-registry/)
+// of the config, along with credentials for each of the mirrors' hosts. Mirrors and credentials which the UserData
+// already configures for other registries are retained, and configuring the same registry in both is a conflict.
+func mergeBottlerocketRegistryMirrors(config *BottlerocketConfig, mirrors []RegistryMirror) error {
+	if config.SettingsRaw == nil {
+		config.SettingsRaw = map[string]interface{}{}
+	}
+	registrySettings, ok := config.SettingsRaw["container-registry"].(map[string]interface{})
+	if !ok {
+		registrySettings = map[string]interface{}{}
+	}
+	mirrorSettings, _ := registrySettings["mirrors"].([]interface{})
+	credentialSettings, _ := registrySettings["credentials"].([]interface{})
+	registries := func(settings []interface{}) map[string]bool {
+		result := map[string]bool{}
+		for _, setting := range settings {
+			if m, ok := setting.(map[string]interface{}); ok {
+				if registry, ok := m["registry"].(string); ok {
+					result[registry] = true
+				}
+			}
+		}
+		return result
+	}
+	mirrored, authenticated := registries(mirrorSettings), registries(credentialSettings)
+	for _, m := range mirrors {
+		if mirrored[m.Registry] {
+			return fmt.Errorf("registry mirrors for %q are configured in both registryMirrors and userData", m.Registry)
+		}
+		mirrorSettings = append(mirrorSettings, map[string]interface{}{"registry": m.Registry, "endpoint": m.Endpoints})
+		if !m.hasCredentials() {
+			continue
+		}
+		for _, endpoint := range m.Endpoints {
+			u, err := url.Parse(endpoint)
+			if err != nil {
+				return fmt.Errorf("parsing mirror endpoint %q, %w", endpoint, err)
+			}
+			if authenticated[u.Host] {
+				return fmt.Errorf("registry credentials for %q are configured in both registryMirrors and userData", u.Host)
+			}
+			authenticated[u.Host] = true
+			credentialSettings = append(credentialSettings, map[string]interface{}{"registry": u.Host, "username": m.Username, "password": m.Password})
+		}
+	}
+	registrySettings["mirrors"] = mirrorSettings
+	if len(credentialSettings) != 0 {
+		registrySettings["credentials"] = credentialSettings
+	}
+	config.SettingsRaw["container-registry"] = registrySettings
+	return nil
+}
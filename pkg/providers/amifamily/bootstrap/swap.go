@@ -0,0 +1,62 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/samber/lo"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// swapfile is where the swapfile is created on the root volume
+const swapfile = "/swapfile"
+
+// swapKubeletConfig returns the kubelet configuration fields which let the kubelet start with swap enabled, and
+// configure how workloads use it
+func swapKubeletConfig(swap *v1.Swap) map[string]any {
+	return map[string]any{
+		"failSwapOn": false,
+		"memorySwap": map[string]any{
+			"swapBehavior": lo.FromPtrOr(swap.SwapBehavior, v1.SwapBehaviorLimitedSwap),
+		},
+	}
+}
+
+// swapShellScript returns a script which provisions and enables the swap space. It runs before nodeadm starts the
+// kubelet, which only detects swap when it starts.
+func swapShellScript(swap *v1.Swap) string {
+	var b bytes.Buffer
+	switch swap.Source {
+	case v1.SwapSourceSwapfile:
+		b.WriteString(fmt.Sprintf("if [ ! -f '%s' ]; then\n", swapfile))
+		b.WriteString(fmt.Sprintf("  fallocate -l %d '%s'\n", swap.Size.Value(), swapfile))
+		b.WriteString(fmt.Sprintf("  chmod 600 '%s'\n", swapfile))
+		b.WriteString(fmt.Sprintf("  mkswap '%s'\n", swapfile))
+		b.WriteString("fi\n")
+		b.WriteString(fmt.Sprintf("swapon '%s' || true\n", swapfile))
+	case v1.SwapSourceInstanceStore:
+		// Each instance store volume is linked more than once, so the links are resolved to their devices
+		b.WriteString("for device in $(readlink -f /dev/disk/by-id/nvme-Amazon_EC2_NVMe_Instance_Storage_* | sort -u); do\n")
+		b.WriteString("  [ -b \"$device\" ] || continue\n")
+		b.WriteString("  swapon --show=NAME --noheadings | grep -qx \"$device\" && continue\n")
+		b.WriteString("  mkswap \"$device\"\n")
+		b.WriteString("  swapon \"$device\"\n")
+		b.WriteString("done\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// TalosMachineConfigVersion is the version of the Talos machine config document which Karpenter patches. Other documents
+// in a multi-document machine config, such as network or volume configs, are passed through unchanged.
+const TalosMachineConfigVersion = "v1alpha1"
+
+var talosDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// Talos generates a Talos machine config from the one in the custom user data. Talos nodes join the cluster with the
+// secrets in their machine config, which Karpenter can't generate, so the custom user data must contain the worker
+// machine config. Karpenter patches the node labels and kubelet configuration into it, overwriting any conflicting
+// settings.
+type Talos struct {
+	Options
+}
+
+func (t Talos) Script() (string, error) {
+	if t.CustomUserData == nil || strings.TrimSpace(*t.CustomUserData) == "" {
+		return "", fmt.Errorf("custom user data must contain a talos machine config")
+	}
+	if lo.FromPtr(t.InstanceStorePolicy) == v1.InstanceStorePolicyRAID0 {
+		return "", fmt.Errorf("instance store policy %q isn't supported by talos", v1.InstanceStorePolicyRAID0)
+	}
+	var documents []string
+	patched := false
+	for _, document := range talosDocumentSeparator.Split(*t.CustomUserData, -1) {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+		config := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(document), &config); err != nil {
+			return "", fmt.Errorf("parsing talos machine config, %w", err)
+		}
+		if config["version"] != TalosMachineConfigVersion {
+			documents = append(documents, strings.Trim(document, "\n"))
+			continue
+		}
+		if patched {
+			return "", fmt.Errorf("talos machine config contains multiple %s documents", TalosMachineConfigVersion)
+		}
+		if err := t.patch(config); err != nil {
+			return "", err
+		}
+		out, err := yaml.Marshal(config)
+		if err != nil {
+			return "", fmt.Errorf("marshaling talos machine config, %w", err)
+		}
+		documents = append(documents, strings.TrimSuffix(string(out), "\n"))
+		patched = true
+	}
+	if !patched {
+		return "", fmt.Errorf("custom user data must contain a talos machine config with version %s", TalosMachineConfigVersion)
+	}
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join(documents, "\n---\n") + "\n")), nil
+}
+
+func (t Talos) patch(config map[string]interface{}) error {
+	machine, err := talosSection(config, "machine")
+	if err != nil {
+		return err
+	}
+	if machineType, ok := machine["type"]; ok && machineType != "worker" {
+		return fmt.Errorf("talos machine config must be of type worker, got %v", machineType)
+	}
+	if len(t.Labels) > 0 {
+		nodeLabels, err := talosSection(machine, "nodeLabels")
+		if err != nil {
+			return err
+		}
+		for k, v := range t.Labels {
+			nodeLabels[k] = v
+		}
+	}
+	kubelet, err := talosSection(machine, "kubelet")
+	if err != nil {
+		return err
+	}
+	extraConfig, err := talosSection(kubelet, "extraConfig")
+	if err != nil {
+		return err
+	}
+	// Taints are registered by the kubelet rather than through machine.nodeTaints, since Talos continuously reconciles
+	// the latter and would restore startup taints after they've been removed
+	if len(t.Taints) > 0 {
+		extraConfig["registerWithTaints"] = lo.Map(t.Taints, func(taint corev1.Taint, _ int) map[string]interface{} {
+			return lo.OmitByValues(map[string]interface{}{"key": taint.Key, "value": taint.Value, "effect": string(taint.Effect)}, []interface{}{""})
+		})
+	}
+	if t.KubeletConfig == nil {
+		return nil
+	}
+	if len(t.KubeletConfig.ClusterDNS) > 0 {
+		kubelet["clusterDNS"] = t.KubeletConfig.ClusterDNS
+	}
+	for k, v := range t.kubeletExtraConfig() {
+		extraConfig[k] = v
+	}
+	return nil
+}
+
+// kubeletExtraConfig returns the KubeletConfiguration fields that Talos passes through to the kubelet unchanged
+func (t Talos) kubeletExtraConfig() map[string]interface{} {
+	k := t.KubeletConfig
+	config := map[string]interface{}{}
+	if k.MaxPods != nil {
+		config["maxPods"] = *k.MaxPods
+	}
+	if k.PodsPerCore != nil {
+		config["podsPerCore"] = *k.PodsPerCore
+	}
+	if len(k.SystemReserved) > 0 {
+		config["systemReserved"] = k.SystemReserved
+	}
+	if len(k.KubeReserved) > 0 {
+		config["kubeReserved"] = k.KubeReserved
+	}
+	if len(k.EvictionHard) > 0 {
+		config["evictionHard"] = k.EvictionHard
+	}
+	if len(k.EvictionSoft) > 0 {
+		config["evictionSoft"] = k.EvictionSoft
+	}
+	if len(k.EvictionSoftGracePeriod) > 0 {
+		config["evictionSoftGracePeriod"] = lo.MapValues(k.EvictionSoftGracePeriod, func(d metav1.Duration, _ string) string { return d.Duration.String() })
+	}
+	if k.EvictionMaxPodGracePeriod != nil {
+		config["evictionMaxPodGracePeriod"] = *k.EvictionMaxPodGracePeriod
+	}
+	if k.ImageGCHighThresholdPercent != nil {
+		config["imageGCHighThresholdPercent"] = *k.ImageGCHighThresholdPercent
+	}
+	if k.ImageGCLowThresholdPercent != nil {
+		config["imageGCLowThresholdPercent"] = *k.ImageGCLowThresholdPercent
+	}
+	if k.CPUCFSQuota != nil {
+		config["cpuCFSQuota"] = *k.CPUCFSQuota
+	}
+	return config
+}
+
+// talosSection returns the nested section of the machine config with the given key, creating it if it doesn't exist
+func talosSection(config map[string]interface{}, key string) (map[string]interface{}, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		section := map[string]interface{}{}
+		config[key] = section
+		return section, nil
+	}
+	section, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("talos machine config field %q must be an object", key)
+	}
+	return section, nil
+}
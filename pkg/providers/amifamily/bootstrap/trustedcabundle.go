@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+const (
+	// trustedCABundleAnchor is where the bundle is added to the Amazon Linux trust store, which containerd reads through
+	// the bundle that update-ca-trust extracts
+	trustedCABundleAnchor = "/etc/pki/ca-trust/source/anchors/karpenter-trusted-ca-bundle.crt"
+	// trustedCABundleDelimiter terminates the heredoc which writes the bundle
+	trustedCABundleDelimiter = "KARPENTER_TRUSTED_CA_BUNDLE"
+	// bottlerocketTrustedCABundle is the name of the bundle in the pki settings of Bottlerocket
+	bottlerocketTrustedCABundle = "karpenter-trusted-ca-bundle"
+)
+
+// ParseTrustedCABundle returns the certificates of a bundle of PEM encoded certificates, re-encoded so that anything
+// other than the certificates, such as comments or private keys, is left out of the UserData
+func ParseTrustedCABundle(bundle string) (string, error) {
+	var b bytes.Buffer
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return "", fmt.Errorf("parsing certificate, %w", err)
+		}
+		if err := pem.Encode(&b, &pem.Block{Type: block.Type, Bytes: block.Bytes}); err != nil {
+			return "", fmt.Errorf("encoding certificate, %w", err)
+		}
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("bundle doesn't contain a PEM encoded certificate")
+	}
+	return b.String(), nil
+}
+
+// trustedCABundleShellScript returns a script which adds the bundle to the Amazon Linux trust store. Containerd is
+// restarted if it's already running, so that it trusts the bundle before pulling any images.
+func trustedCABundleShellScript(bundle string) string {
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("cat > '%s' <<'%s'\n%s%s\n", trustedCABundleAnchor, trustedCABundleDelimiter, bundle, trustedCABundleDelimiter))
+	b.WriteString("update-ca-trust extract\n")
+	b.WriteString("systemctl try-restart containerd\n")
+	return b.String()
+}
+
+// trustedCABundlePowerShell returns a PowerShell script which imports each of the bundle's certificates into the
+// machine's trusted root store. Import-Certificate only reads the first certificate of a file.
+func trustedCABundlePowerShell(bundle string) string {
+	var b bytes.Buffer
+	rest := []byte(bundle)
+	for i := 0; ; i++ {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		b.WriteString(fmt.Sprintf("$CertificatePath = Join-Path $env:TEMP \"karpenter-trusted-ca-%d.crt\"\n", i))
+		b.WriteString(fmt.Sprintf("Set-Content -Path $CertificatePath -Value @'\n%s'@\n", pem.EncodeToMemory(block)))
+		b.WriteString("Import-Certificate -FilePath $CertificatePath -CertStoreLocation Cert:\\LocalMachine\\Root | Out-Null\n")
+		b.WriteString("Remove-Item -Path $CertificatePath\n")
+	}
+	return b.String()
+}
+
+// mergeBottlerocketTrustedCABundle adds the bundle to the pki settings (https://bottlerocket.dev/en/os/latest/#/api/settings/pki/)
+// of the config. Bundles which the UserData already configures are retained.
+func mergeBottlerocketTrustedCABundle(config *BottlerocketConfig, bundle string) error {
+	if config.SettingsRaw == nil {
+		config.SettingsRaw = map[string]interface{}{}
+	}
+	pkiSettings, ok := config.SettingsRaw["pki"].(map[string]interface{})
+	if !ok {
+		pkiSettings = map[string]interface{}{}
+	}
+	if _, ok := pkiSettings[bottlerocketTrustedCABundle]; ok {
+		return fmt.Errorf("pki setting %q is managed by Karpenter", bottlerocketTrustedCABundle)
+	}
+	pkiSettings[bottlerocketTrustedCABundle] = map[string]interface{}{
+		"data":    base64.StdEncoding.EncodeToString([]byte(strings.TrimSpace(bundle) + "\n")),
+		"trusted": true,
+	}
+	config.SettingsRaw["pki"] = pkiSettings
+	return nil
+}
@@ -21,8 +21,33 @@ import (
 	"strings"
 
 	"github.com/samber/lo"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 )
 
+// windowsInstanceStoreScript stripes the instance store NVMe disks into a single NTFS volume, and moves the containerd
+// and kubelet state directories onto it through directory junctions. The kubelet reports the volume's size as the
+// node's ephemeral storage, since it resolves the junction of its root directory. Containerd is stopped while its
+// state directory is moved, so the images cached in the AMI are moved along with it.
+const windowsInstanceStoreScript = `$InstanceStoreDisks = @(Get-PhysicalDisk -CanPool $true | Where-Object { $_.Model -like "*Instance Storage*" })
+if ($InstanceStoreDisks.Count -gt 0) {
+  New-StoragePool -FriendlyName "InstanceStore" -StorageSubSystemFriendlyName "Windows Storage*" -PhysicalDisks $InstanceStoreDisks | Out-Null
+  New-VirtualDisk -StoragePoolFriendlyName "InstanceStore" -FriendlyName "InstanceStore" -ResiliencySettingName Simple -NumberOfColumns $InstanceStoreDisks.Count -UseMaximumSize | Out-Null
+  $InstanceStoreVolume = Get-VirtualDisk -FriendlyName "InstanceStore" | Get-Disk | Initialize-Disk -PartitionStyle GPT -PassThru | New-Partition -AssignDriveLetter -UseMaximumSize | Format-Volume -FileSystem NTFS -NewFileSystemLabel "InstanceStore" -Confirm:$false
+  Stop-Service containerd -ErrorAction SilentlyContinue
+  foreach ($StateDir in @("$env:ProgramData\containerd", "C:\var\lib\kubelet")) {
+    $Target = Join-Path "$($InstanceStoreVolume.DriveLetter):\" (Split-Path $StateDir -NoQualifier)
+    New-Item -ItemType Directory -Path $Target -Force | Out-Null
+    if (Test-Path $StateDir) {
+      robocopy $StateDir $Target /E /MOVE /COPYALL /NFL /NDL /NJH /NJS | Out-Null
+      Remove-Item $StateDir -Recurse -Force -ErrorAction SilentlyContinue
+    }
+    New-Item -ItemType Junction -Path $StateDir -Target $Target | Out-Null
+  }
+  Start-Service containerd -ErrorAction SilentlyContinue
+}
+`
+
 type Windows struct {
 	Options
 }
@@ -36,6 +61,18 @@ func (w Windows) Script() (string, error) {
 	if customUserData != "" {
 		userData.WriteString(customUserData + "\n")
 	}
+	if lo.FromPtr(w.InstanceStorePolicy) == v1.InstanceStorePolicyRAID0 {
+		userData.WriteString(windowsInstanceStoreScript)
+	}
+	if w.TrustedCABundle != "" {
+		userData.WriteString(trustedCABundlePowerShell(w.TrustedCABundle))
+	}
+	mirrorsScript, err := registryMirrorsPowerShell(w.RegistryMirrors)
+	if err != nil {
+		return "", err
+	}
+	userData.WriteString(mirrorsScript)
+	userData.WriteString(w.proxyPowerShell())
 
 	userData.WriteString("[string]$EKSBootstrapScriptFile = \"$env:ProgramFiles\\Amazon\\EKS\\Start-EKSBootstrap.ps1\"\n")
 	userData.WriteString(fmt.Sprintf(`& $EKSBootstrapScriptFile -EKSClusterName '%s' -APIServerEndpoint '%s'`, w.ClusterName, w.ClusterEndpoint))
@@ -48,6 +85,9 @@ func (w Windows) Script() (string, error) {
 	if w.KubeletConfig != nil && len(w.KubeletConfig.ClusterDNS) > 0 {
 		userData.WriteString(fmt.Sprintf(` -DNSClusterIP '%s'`, w.KubeletConfig.ClusterDNS[0]))
 	}
-	userData.WriteString("\n</powershell>")
+	userData.WriteString("\n")
+	// The kubelet service is registered by the bootstrap script
+	userData.WriteString(w.proxyServicePowerShell("kubelet"))
+	userData.WriteString("</powershell>")
 	return base64.StdEncoding.EncodeToString(userData.Bytes()), nil
 }
@@ -43,12 +43,22 @@ func (b Bottlerocket) DescribeImageQuery(ctx context.Context, ssmProvider ssm.Pr
 	// Bottlerocket AMIs versions are prefixed with a v on GitHub, but not in the SSM path. We should accept both.
 	trimmedAMIVersion := strings.TrimLeft(amiVersion, "v")
 	ids := map[string][]Variant{}
-	for path, variants := range map[string][]Variant{
+	paths := map[string][]Variant{
 		fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/x86_64/%s/image_id", k8sVersion, trimmedAMIVersion):        {VariantStandard, VariantNeuron},
 		fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/arm64/%s/image_id", k8sVersion, trimmedAMIVersion):         {VariantStandard},
 		fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-nvidia/x86_64/%s/image_id", k8sVersion, trimmedAMIVersion): {VariantNvidia},
 		fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-nvidia/arm64/%s/image_id", k8sVersion, trimmedAMIVersion):  {VariantNvidia},
-	} {
+	}
+	if b.Options.fips() {
+		paths = map[string][]Variant{
+			fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-fips/x86_64/%s/image_id", k8sVersion, trimmedAMIVersion): {VariantStandard},
+			fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-fips/arm64/%s/image_id", k8sVersion, trimmedAMIVersion):  {VariantStandard},
+		}
+	}
+	for path, variants := range paths {
+		if variants = b.Options.selectedVariants(variants...); len(variants) == 0 {
+			continue
+		}
 		imageID, err := ssmProvider.Get(ctx, ssm.Parameter{
 			Name:      path,
 			IsMutable: amiVersion == v1.AliasVersionLatest,
@@ -60,7 +70,7 @@ func (b Bottlerocket) DescribeImageQuery(ctx context.Context, ssmProvider ssm.Pr
 	}
 	// Failed to discover any AMIs, we should short circuit AMI discovery
 	if len(ids) == 0 {
-		return DescribeImageQuery{}, fmt.Errorf(`failed to discover any AMIs for alias "bottlerocket@%s"`, amiVersion)
+		return DescribeImageQuery{}, fmt.Errorf(`failed to discover any %sAMIs for alias "bottlerocket@%s"`, lo.Ternary(b.Options.fips(), "FIPS ", ""), amiVersion)
 	}
 
 	return DescribeImageQuery{
@@ -78,15 +88,20 @@ func (b Bottlerocket) DescribeImageQuery(ctx context.Context, ssmProvider ssm.Pr
 func (b Bottlerocket) UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, caBundle *string, _ []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1.InstanceStorePolicy) bootstrap.Bootstrapper {
 	return bootstrap.Bottlerocket{
 		Options: bootstrap.Options{
-			ClusterName:         b.Options.ClusterName,
-			ClusterEndpoint:     b.Options.ClusterEndpoint,
-			KubeletConfig:       kubeletConfig,
-			Taints:              taints,
-			Labels:              labels,
-			CABundle:            caBundle,
-			CustomUserData:      customUserData,
-			InstanceStorePolicy: instanceStorePolicy,
+			ClusterName:          b.Options.ClusterName,
+			ClusterEndpoint:      b.Options.ClusterEndpoint,
+			KubeletConfig:        kubeletConfig,
+			Taints:               taints,
+			Labels:               labels,
+			CABundle:             caBundle,
+			CustomUserData:       customUserData,
+			InstanceStorePolicy:  instanceStorePolicy,
+			RegistryMirrors:      b.Options.RegistryMirrors,
+			Proxy:                b.Options.Proxy,
+			TrustedCABundle:      b.Options.TrustedCABundle,
+			KubeletConfigDropIns: b.Options.KubeletConfigDropIns,
 		},
+		Settings: b.Options.BottlerocketSettings,
 	}
 }
 
@@ -32,8 +32,27 @@ type Custom struct {
 	*Options
 }
 
-// UserData returns the default userdata script for the AMI Family
-func (c Custom) UserData(_ *v1.KubeletConfiguration, _ []corev1.Taint, _ map[string]string, _ *string, _ []*cloudprovider.InstanceType, customUserData *string, _ *v1.InstanceStorePolicy) bootstrap.Bootstrapper {
+// UserData returns the default userdata script for the AMI Family. With the Nodeadm bootstrap mode, a nodeadm NodeConfig
+// is generated as for AL2023, without the scripts which assume the layout of the AL2023 AMIs.
+func (c Custom) UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, caBundle *string, _ []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1.InstanceStorePolicy) bootstrap.Bootstrapper {
+	if c.Options != nil && c.Options.BootstrapMode == v1.BootstrapModeNodeadm {
+		return bootstrap.Nodeadm{
+			Options: bootstrap.Options{
+				ClusterName:          c.Options.ClusterName,
+				ClusterEndpoint:      c.Options.ClusterEndpoint,
+				ClusterCIDR:          c.Options.ClusterCIDR,
+				KubeletConfig:        kubeletConfig,
+				Taints:               taints,
+				Labels:               labels,
+				CABundle:             caBundle,
+				CustomUserData:       customUserData,
+				InstanceStorePolicy:  instanceStorePolicy,
+				Swap:                 c.Options.Swap,
+				KubeletConfigDropIns: c.Options.KubeletConfigDropIns,
+			},
+			NodeConfig: c.Options.NodeConfig,
+		}
+	}
 	return bootstrap.Custom{
 		Options: bootstrap.Options{
 			CustomUserData: customUserData,
@@ -0,0 +1,83 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amifamily
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily/bootstrap"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
+)
+
+// FlatcarOwner is the account which publishes the official Flatcar Container Linux AMIs in the commercial partition
+const FlatcarOwner = "075585003325"
+
+type Flatcar struct {
+	DefaultFamily
+	*Options
+}
+
+// DescribeImageQuery discovers AMIs from the Flatcar stable channel. Unlike the EKS optimized AMIs, Flatcar AMIs aren't
+// published to SSM, so they're discovered by owner and name. When multiple releases match, the newest is selected for
+// each architecture.
+func (f Flatcar) DescribeImageQuery(_ context.Context, _ ssm.Provider, _ string, amiVersion string) (DescribeImageQuery, error) {
+	name := fmt.Sprintf("Flatcar-stable-%s-*", amiVersion)
+	if amiVersion == v1.AliasVersionLatest {
+		name = "Flatcar-stable-*"
+	}
+	return DescribeImageQuery{
+		Owners: []string{FlatcarOwner},
+		Filters: []ec2types.Filter{{
+			Name:   aws.String("name"),
+			Values: []string{name},
+		}},
+	}, nil
+}
+
+// UserData returns the default userdata script for the AMI Family
+func (f Flatcar) UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, caBundle *string, _ []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1.InstanceStorePolicy) bootstrap.Bootstrapper {
+	return bootstrap.Flatcar{
+		Options: bootstrap.Options{
+			ClusterName:         f.Options.ClusterName,
+			ClusterEndpoint:     f.Options.ClusterEndpoint,
+			KubeletConfig:       kubeletConfig,
+			Taints:              taints,
+			Labels:              labels,
+			CABundle:            caBundle,
+			CustomUserData:      customUserData,
+			InstanceStorePolicy: instanceStorePolicy,
+		},
+	}
+}
+
+// DefaultBlockDeviceMappings returns the default block device mappings for the AMI Family
+func (f Flatcar) DefaultBlockDeviceMappings() []*v1.BlockDeviceMapping {
+	return []*v1.BlockDeviceMapping{{
+		DeviceName: f.EphemeralBlockDevice(),
+		EBS:        &DefaultEBS,
+	}}
+}
+
+func (f Flatcar) EphemeralBlockDevice() *string {
+	return aws.String("/dev/xvda")
+}
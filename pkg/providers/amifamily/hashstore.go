@@ -0,0 +1,84 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amifamily
+
+import (
+	"sync"
+
+	"github.com/mitchellh/hashstructure/v2"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// HashAMIs returns the hash of an EC2NodeClass's resolved AMIs, which changes whenever a different AMI is resolved
+func HashAMIs(amis []v1.AMI) uint64 {
+	hash, _ := hashstructure.Hash(amis, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	return hash
+}
+
+type storedHash struct {
+	resourceVersion string
+	hash            uint64
+}
+
+// HashStore holds the hash of each EC2NodeClass's resolved AMIs, which the instance types and discovered capacity
+// resolved for the EC2NodeClass are keyed by. It's updated by a controller as EC2NodeClasses change, so that the hash
+// isn't recomputed each time instance types are listed.
+type HashStore struct {
+	mu     sync.RWMutex
+	hashes map[string]storedHash
+}
+
+func NewHashStore() *HashStore {
+	return &HashStore{hashes: map[string]storedHash{}}
+}
+
+// Hash returns the hash of the EC2NodeClass's resolved AMIs. The stored hash is used if it was computed from the same
+// version of the EC2NodeClass, otherwise the hash is computed without being stored.
+func (s *HashStore) Hash(nodeClass *v1.EC2NodeClass) uint64 {
+	s.mu.RLock()
+	stored, ok := s.hashes[nodeClass.Name]
+	s.mu.RUnlock()
+	if ok && nodeClass.ResourceVersion != "" && stored.resourceVersion == nodeClass.ResourceVersion {
+		return stored.hash
+	}
+	return HashAMIs(nodeClass.Status.AMIs)
+}
+
+// Update stores the hash of the EC2NodeClass's resolved AMIs, returning whether it replaced a different hash
+func (s *HashStore) Update(nodeClass *v1.EC2NodeClass) bool {
+	if nodeClass.ResourceVersion == "" {
+		return false
+	}
+	hash := HashAMIs(nodeClass.Status.AMIs)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.hashes[nodeClass.Name]
+	s.hashes[nodeClass.Name] = storedHash{resourceVersion: nodeClass.ResourceVersion, hash: hash}
+	return ok && stored.hash != hash
+}
+
+// Delete removes the stored hash of an EC2NodeClass
+func (s *HashStore) Delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hashes, name)
+}
+
+func (s *HashStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hashes = map[string]storedHash{}
+}
@@ -0,0 +1,115 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amifamily
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/imagebuilder"
+	imagebuildertypes "github.com/aws/aws-sdk-go-v2/service/imagebuilder/types"
+	"github.com/samber/lo"
+)
+
+// imageBuilderAMIIDs returns the IDs of the AMIs output by the newest available image of an Image Builder pipeline or
+// recipe. Pipelines are resolved through the images they've built, and recipes through the build versions of the image
+// version that shares the recipe's name and version.
+func (p *DefaultProvider) imageBuilderAMIIDs(ctx context.Context, arn string) ([]string, error) {
+	key := fmt.Sprintf("imagebuilder/%s", arn)
+	if ids, ok := p.cache.Get(key); ok {
+		return ids.([]string), nil
+	}
+	var images []imagebuildertypes.ImageSummary
+	var err error
+	switch {
+	case strings.Contains(arn, ":image-pipeline/"):
+		images, err = p.listImagePipelineImages(ctx, arn)
+	case strings.Contains(arn, ":image-recipe/"):
+		images, err = p.listImageBuildVersions(ctx, strings.Replace(arn, ":image-recipe/", ":image/", 1))
+	default:
+		return nil, fmt.Errorf("unsupported image builder arn %q", arn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	newest, ok := newestAvailableImage(images)
+	if !ok {
+		p.cache.SetDefault(key, []string{})
+		return []string{}, nil
+	}
+	var amis []imagebuildertypes.Ami
+	if newest.OutputResources != nil {
+		amis = newest.OutputResources.Amis
+	}
+	ids := lo.Uniq(lo.FilterMap(amis, func(ami imagebuildertypes.Ami, _ int) (string, bool) {
+		return aws.ToString(ami.Image), aws.ToString(ami.Image) != ""
+	}))
+	p.cache.SetDefault(key, ids)
+	return ids, nil
+}
+
+func (p *DefaultProvider) listImagePipelineImages(ctx context.Context, arn string) ([]imagebuildertypes.ImageSummary, error) {
+	var images []imagebuildertypes.ImageSummary
+	paginator := imagebuilder.NewListImagePipelineImagesPaginator(p.imageBuilderAPI, &imagebuilder.ListImagePipelineImagesInput{
+		ImagePipelineArn: aws.String(arn),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing images for image pipeline %q, %w", arn, err)
+		}
+		images = append(images, page.ImageSummaryList...)
+	}
+	return images, nil
+}
+
+func (p *DefaultProvider) listImageBuildVersions(ctx context.Context, arn string) ([]imagebuildertypes.ImageSummary, error) {
+	var images []imagebuildertypes.ImageSummary
+	paginator := imagebuilder.NewListImageBuildVersionsPaginator(p.imageBuilderAPI, &imagebuilder.ListImageBuildVersionsInput{
+		ImageVersionArn: aws.String(arn),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing build versions for image %q, %w", arn, err)
+		}
+		images = append(images, page.ImageSummaryList...)
+	}
+	return images, nil
+}
+
+// newestAvailableImage returns the most recently created image that finished building and distributing its AMIs.
+// Images whose creation date can't be parsed are ignored.
+func newestAvailableImage(images []imagebuildertypes.ImageSummary) (imagebuildertypes.ImageSummary, bool) {
+	var newest imagebuildertypes.ImageSummary
+	var newestCreated time.Time
+	found := false
+	for _, image := range images {
+		if image.State == nil || image.State.Status != imagebuildertypes.ImageStatusAvailable {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, aws.ToString(image.DateCreated))
+		if err != nil {
+			continue
+		}
+		if !found || created.After(newestCreated) {
+			newest, newestCreated, found = image, created, true
+		}
+	}
+	return newest, found
+}
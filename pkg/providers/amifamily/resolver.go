@@ -34,6 +34,7 @@ import (
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily/bootstrap"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
 var DefaultEBS = v1.BlockDevice{
@@ -57,13 +58,39 @@ type Options struct {
 	InstanceProfile     string
 	CABundle            *string `hash:"ignore"`
 	InstanceStorePolicy *v1.InstanceStorePolicy
+	// BottlerocketSettings are only used by the Bottlerocket AMI family
+	BottlerocketSettings *v1.BottlerocketSettings `hash:"string"`
+	// NodeConfig is only used by the AL2023 AMI family
+	NodeConfig *v1.NodeConfig
+	// KubeletConfigDropIns are only used by the AL2023 and Bottlerocket AMI families
+	KubeletConfigDropIns []v1.KubeletConfigDropIn
+	// RegistryMirrors are resolved from the EC2NodeClass, including the credentials from their Secrets
+	RegistryMirrors []bootstrap.RegistryMirror
+	// Proxy is only used by the AL2, AL2023, Bottlerocket, and Windows AMI families
+	Proxy *v1.Proxy
+	// TrustedCABundle is resolved from the EC2NodeClass, including the bundle from its ConfigMap
+	TrustedCABundle string
+	// Swap is only used by the AL2023 AMI family
+	Swap *v1.Swap
+	// BootstrapMode is only used by the Custom AMI family
+	BootstrapMode v1.BootstrapMode
+	// UserDataMergeOrder is only used by the AMI families which merge userData with a bootstrap script
+	UserDataMergeOrder v1.UserDataMergeOrder
+	// DeviceValidation is only used by the AL2, AL2023, and Ubuntu AMI families
+	DeviceValidation *v1.DeviceValidation
+	// AMIVariants restrict the variants of the AMIs that are selected by an alias
+	AMIVariants []Variant `hash:"ignore"`
+	// FIPS selects the FIPS-enabled AMIs that an alias' family publishes
+	FIPS bool `hash:"ignore"`
 	// Level-triggered fields that may change out of sync.
 	SecurityGroups           []v1.SecurityGroup
 	Tags                     map[string]string
 	Labels                   map[string]string `hash:"ignore"`
 	KubeDNSIP                net.IP
 	AssociatePublicIPAddress *bool
-	NodeClassName            string
+	// IPv6Native is whether the instances are launched into IPv6-only subnets, and so don't have IPv4 addresses
+	IPv6Native    bool
+	NodeClassName string
 }
 
 // LaunchTemplate holds the dynamically generated launch template parameters
@@ -72,12 +99,29 @@ type LaunchTemplate struct {
 	UserData              bootstrap.Bootstrapper
 	BlockDeviceMappings   []*v1.BlockDeviceMapping
 	MetadataOptions       *v1.MetadataOptions
+	PrivateDNSNameOptions *v1.PrivateDNSNameOptions
 	AMIID                 string
 	InstanceTypes         []*cloudprovider.InstanceType `hash:"ignore"`
 	DetailedMonitoring    bool
+	ThreadsPerCore        *int32
 	EFACount              int
+	EFAOnly               bool
+	IPv6AddressCount      *int32
+	IPv6PrefixCount       *int32
 	CapacityType          string
 	CapacityReservationID string
+	// Zone is set when the launch template can only launch into one zone, since its additional network interfaces are
+	// created in subnets of that zone
+	Zone              string
+	NetworkInterfaces []NetworkInterface
+}
+
+// NetworkInterface is an additional network interface, resolved to a subnet in the zone that the launch template
+// launches into
+type NetworkInterface struct {
+	DeviceIndex      int32
+	SubnetID         string
+	SecurityGroupIDs []string
 }
 
 // AMIFamily can be implemented to override the default logic for generating dynamic launch template parameters
@@ -85,7 +129,6 @@ type AMIFamily interface {
 	DescribeImageQuery(ctx context.Context, ssmProvider ssm.Provider, k8sVersion string, amiVersion string) (DescribeImageQuery, error)
 	UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, caBundle *string, instanceTypes []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1.InstanceStorePolicy) bootstrap.Bootstrapper
 	DefaultBlockDeviceMappings() []*v1.BlockDeviceMapping
-	DefaultMetadataOptions() *v1.MetadataOptions
 	EphemeralBlockDevice() *string
 	FeatureFlags() FeatureFlags
 }
@@ -127,7 +170,7 @@ func (r DefaultResolver) Resolve(nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.N
 	if len(nodeClass.Status.AMIs) == 0 {
 		return nil, fmt.Errorf("no amis exist given constraints")
 	}
-	mappedAMIs := MapToInstanceTypes(instanceTypes, nodeClass.Status.AMIs)
+	mappedAMIs := MapToInstanceTypes(instanceTypes, RolloutAMIs(nodeClass, nodeClaim))
 	if len(mappedAMIs) == 0 {
 		return nil, fmt.Errorf("no instance types satisfy requirements of amis %v", lo.Uniq(lo.Map(nodeClass.Status.AMIs, func(a v1.AMI, _ int) string { return a.ID })))
 	}
@@ -170,7 +213,11 @@ func (r DefaultResolver) Resolve(nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.N
 
 		for params, instanceTypes := range paramsToInstanceTypes {
 			reservationIDs := strings.Split(params.reservationIDs, ",")
-			resolvedTemplates = append(resolvedTemplates, r.resolveLaunchTemplates(nodeClass, nodeClaim, instanceTypes, capacityType, amiFamily, amiID, params.maxPods, params.efaCount, reservationIDs, options)...)
+			launchTemplates, err := r.resolveLaunchTemplates(nodeClass, nodeClaim, instanceTypes, capacityType, amiFamily, amiID, params.maxPods, params.efaCount, reservationIDs, options)
+			if err != nil {
+				return nil, err
+			}
+			resolvedTemplates = append(resolvedTemplates, launchTemplates...)
 		}
 	}
 	return resolvedTemplates, nil
@@ -188,18 +235,29 @@ func GetAMIFamily(amiFamily string, options *Options) AMIFamily {
 		return &Custom{Options: options}
 	case v1.AMIFamilyAL2023:
 		return &AL2023{Options: options}
+	case v1.AMIFamilyFlatcar:
+		return &Flatcar{Options: options}
+	case v1.AMIFamilyTalos:
+		return &Talos{Options: options}
+	case v1.AMIFamilyUbuntu:
+		return &Ubuntu{Options: options}
 	default:
 		return &AL2{Options: options}
 	}
 }
 
-func (o Options) DefaultMetadataOptions() *v1.MetadataOptions {
-	return &v1.MetadataOptions{
-		HTTPEndpoint:            aws.String(string(ec2types.InstanceMetadataEndpointStateDisabled)),
-		HTTPProtocolIPv6:        aws.String(lo.Ternary(o.KubeDNSIP == nil || o.KubeDNSIP.To4() != nil, string(ec2types.LaunchTemplateInstanceMetadataProtocolIpv6Disabled), string(ec2types.LaunchTemplateInstanceMetadataProtocolIpv6Enabled))),
-		HTTPPutResponseHopLimit: aws.Int64(2),
-		HTTPTokens:              aws.String(string(ec2types.LaunchTemplateHttpTokensStateRequired)),
+// selectedVariants returns the variants which are selected from the given variants of an alias' AMI. Every variant
+// other than nvidia-open is selected by default.
+func (o *Options) selectedVariants(variants ...Variant) []Variant {
+	if o == nil || len(o.AMIVariants) == 0 {
+		return lo.Without(variants, VariantNvidiaOpen)
 	}
+	return lo.Intersect(variants, o.AMIVariants)
+}
+
+// fips returns whether the FIPS-enabled AMIs of an alias are selected
+func (o *Options) fips() bool {
+	return o != nil && o.FIPS
 }
 
 func (r DefaultResolver) defaultClusterDNS(opts *Options, kubeletConfig *v1.KubeletConfiguration) *v1.KubeletConfiguration {
@@ -230,7 +288,11 @@ func (r DefaultResolver) resolveLaunchTemplates(
 	efaCount int,
 	capacityReservationIDs []string,
 	options *Options,
-) []*LaunchTemplate {
+) ([]*LaunchTemplate, error) {
+	userData, err := r.resolveUserData(nodeClass, nodeClaim, instanceTypes, capacityType, options)
+	if err != nil {
+		return nil, err
+	}
 	kubeletConfig := &v1.KubeletConfiguration{}
 	if nodeClass.Spec.Kubelet != nil {
 		kubeletConfig = nodeClass.Spec.Kubelet.DeepCopy()
@@ -267,24 +329,95 @@ func (r DefaultResolver) resolveLaunchTemplates(
 				options.Labels,
 				options.CABundle,
 				instanceTypes,
-				nodeClass.Spec.UserData,
+				userData,
 				options.InstanceStorePolicy,
 			),
-			BlockDeviceMappings:   nodeClass.Spec.BlockDeviceMappings,
-			MetadataOptions:       nodeClass.Spec.MetadataOptions,
+			BlockDeviceMappings:   resolveBlockDeviceMappings(nodeClass, amiFamily),
+			MetadataOptions:       resolveMetadataOptions(nodeClass),
+			PrivateDNSNameOptions: nodeClass.Spec.PrivateDNSNameOptions,
 			DetailedMonitoring:    aws.ToBool(nodeClass.Spec.DetailedMonitoring),
+			ThreadsPerCore:        nodeClass.ThreadsPerCore(),
 			AMIID:                 amiID,
 			InstanceTypes:         instanceTypes,
 			EFACount:              efaCount,
+			EFAOnly:               lo.FromPtr(lo.FromPtr(nodeClass.Spec.EFA).EFAOnly),
+			IPv6AddressCount:      lo.FromPtr(nodeClass.Spec.IPv6).AddressCount,
+			IPv6PrefixCount:       lo.FromPtr(nodeClass.Spec.IPv6).PrefixCount,
 			CapacityType:          capacityType,
 			CapacityReservationID: id,
 		}
-		if len(resolved.BlockDeviceMappings) == 0 {
-			resolved.BlockDeviceMappings = amiFamily.DefaultBlockDeviceMappings()
-		}
-		if resolved.MetadataOptions == nil {
-			resolved.MetadataOptions = amiFamily.DefaultMetadataOptions()
-		}
 		return resolved
+	}), nil
+}
+
+// resolveBlockDeviceMappings returns the EC2NodeClass's block device mappings, or the AMI family's if it doesn't set any.
+// EBS volumes which leave encryption unset are encrypted when the account encrypts new volumes by default, which EC2
+// would do regardless.
+func resolveBlockDeviceMappings(nodeClass *v1.EC2NodeClass, amiFamily AMIFamily) []*v1.BlockDeviceMapping {
+	blockDeviceMappings := nodeClass.Spec.BlockDeviceMappings
+	if len(blockDeviceMappings) == 0 {
+		blockDeviceMappings = amiFamily.DefaultBlockDeviceMappings()
+	}
+	if !lo.FromPtr(lo.FromPtr(nodeClass.Status.AccountDefaults).EBSEncryptionByDefault) {
+		return blockDeviceMappings
+	}
+	return lo.Map(blockDeviceMappings, func(bdm *v1.BlockDeviceMapping, _ int) *v1.BlockDeviceMapping {
+		if bdm.EBS == nil || bdm.EBS.Encrypted != nil {
+			return bdm
+		}
+		bdm = bdm.DeepCopy()
+		bdm.EBS.Encrypted = lo.ToPtr(true)
+		return bdm
 	})
 }
+
+// defaultMetadataOptions are the documented defaults for an EC2NodeClass which omits its metadata options, applied to
+// the options the account's instance metadata defaults don't set
+var defaultMetadataOptions = v1.MetadataOptions{
+	HTTPEndpoint:            lo.ToPtr(string(ec2types.InstanceMetadataEndpointStateEnabled)),
+	HTTPProtocolIPv6:        lo.ToPtr(string(ec2types.InstanceMetadataProtocolStateDisabled)),
+	HTTPPutResponseHopLimit: lo.ToPtr[int64](1),
+	HTTPTokens:              lo.ToPtr(string(ec2types.HttpTokensStateRequired)),
+}
+
+// resolveMetadataOptions returns the EC2NodeClass's metadata options. If it omits them, the account's instance metadata
+// defaults are used, followed by the documented defaults.
+func resolveMetadataOptions(nodeClass *v1.EC2NodeClass) *v1.MetadataOptions {
+	if nodeClass.Spec.MetadataOptions != nil {
+		return nodeClass.Spec.MetadataOptions
+	}
+	metadataOptions := defaultMetadataOptions.DeepCopy()
+	if accountDefaults := nodeClass.Status.AccountDefaults; accountDefaults != nil {
+		metadataOptions.HTTPEndpoint = lo.CoalesceOrEmpty(accountDefaults.HTTPEndpoint, metadataOptions.HTTPEndpoint)
+		metadataOptions.HTTPPutResponseHopLimit = lo.CoalesceOrEmpty(accountDefaults.HTTPPutResponseHopLimit, metadataOptions.HTTPPutResponseHopLimit)
+		metadataOptions.HTTPTokens = lo.CoalesceOrEmpty(accountDefaults.HTTPTokens, metadataOptions.HTTPTokens)
+	}
+	return metadataOptions
+}
+
+// resolveUserData returns the EC2NodeClass' userData, rendered with the launch template's cluster, NodeClaim and
+// instance type variables if it's templated
+func (r DefaultResolver) resolveUserData(
+	nodeClass *v1.EC2NodeClass,
+	nodeClaim *karpv1.NodeClaim,
+	instanceTypes []*cloudprovider.InstanceType,
+	capacityType string,
+	options *Options,
+) (*string, error) {
+	if nodeClass.Spec.UserData == nil || !lo.FromPtr(nodeClass.Spec.TemplatedUserData) {
+		return nodeClass.Spec.UserData, nil
+	}
+	userData, err := utils.RenderUserData(*nodeClass.Spec.UserData, utils.UserDataTemplateData{
+		ClusterName:     options.ClusterName,
+		ClusterEndpoint: options.ClusterEndpoint,
+		NodeClass:       nodeClass.Name,
+		NodePool:        nodeClaim.Labels[karpv1.NodePoolLabelKey],
+		CapacityType:    capacityType,
+		Labels:          options.Labels,
+		InstanceTypes:   lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) string { return it.Name }),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lo.ToPtr(userData), nil
+}
@@ -0,0 +1,59 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amifamily
+
+import (
+	"hash/fnv"
+
+	"github.com/samber/lo"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// RolloutAMIs returns the AMIs that the NodeClaim is launched with. Outside of an AMI rollout these are the
+// EC2NodeClass's resolved AMIs. During a rollout, a NodeClaim is selected for the resolved AMIs if its name hashes
+// within the rollout's percentage, so that retried launches of the same NodeClaim are consistent, and the rollout's
+// node limit hasn't been reached. Otherwise, the previous AMIs are preferred, falling back to the resolved AMIs for
+// instance types which none of the previous AMIs are compatible with.
+func RolloutAMIs(nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.NodeClaim) []v1.AMI {
+	rollout := nodeClass.Status.AMIRollout
+	if rollout == nil || len(rollout.PreviousAMIs) == 0 {
+		return nodeClass.Status.AMIs
+	}
+	limited := nodeClass.Spec.AMIRollout != nil && nodeClass.Spec.AMIRollout.MaxNodes != nil && rollout.LaunchedNodes >= *nodeClass.Spec.AMIRollout.MaxNodes
+	if !limited && rolloutBucket(nodeClaim.Name) < rollout.Percentage {
+		return nodeClass.Status.AMIs
+	}
+	return lo.Flatten([][]v1.AMI{rollout.PreviousAMIs, nodeClass.Status.AMIs})
+}
+
+// RolloutEligibleAMIs returns the IDs of the AMIs that nodes of the instance type may run without being drifted. During
+// an AMI rollout, nodes running the previous AMIs aren't drifted until the rollout completes.
+func RolloutEligibleAMIs(instanceType *cloudprovider.InstanceType, nodeClass *v1.EC2NodeClass) []string {
+	eligible := EligibleAMIs(instanceType, nodeClass.Status.AMIs)
+	if rollout := nodeClass.Status.AMIRollout; rollout != nil {
+		eligible = lo.Union(eligible, EligibleAMIs(instanceType, rollout.PreviousAMIs))
+	}
+	return eligible
+}
+
+// rolloutBucket deterministically maps a NodeClaim's name to [0, 100)
+func rolloutBucket(name string) int32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return int32(h.Sum32() % 100)
+}
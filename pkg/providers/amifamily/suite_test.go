@@ -27,6 +27,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/imagebuilder"
+	imagebuildertypes "github.com/aws/aws-sdk-go-v2/service/imagebuilder/types"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -34,14 +36,17 @@ import (
 
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
@@ -171,6 +176,27 @@ var _ = Describe("AMIProvider", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(amis).To(HaveLen(5))
 	})
+	It("should succeed to resolve AMIs (Ubuntu)", func() {
+		nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "ubuntu@latest"}}
+		awsEnv.SSMAPI.Parameters = map[string]string{
+			fmt.Sprintf("/aws/service/canonical/ubuntu/eks/22.04/%s/stable/current/amd64/hvm/ebs-gp2/ami-id", version): amd64AMI,
+			fmt.Sprintf("/aws/service/canonical/ubuntu/eks/22.04/%s/stable/current/arm64/hvm/ebs-gp2/ami-id", version): arm64AMI,
+		}
+		amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(amis).To(HaveLen(2))
+	})
+	It("should resolve pinned Ubuntu AMIs by image serial", func() {
+		nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "ubuntu@20240625"}}
+		awsEnv.SSMAPI.Parameters = map[string]string{
+			fmt.Sprintf("/aws/service/canonical/ubuntu/eks/24.04/%s/stable/20240625/amd64/hvm/ebs-gp3/ami-id", version): amd64AMI,
+			fmt.Sprintf("/aws/service/canonical/ubuntu/eks/24.04/%s/stable/current/arm64/hvm/ebs-gp3/ami-id", version):  arm64AMI,
+		}
+		amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(amis).To(HaveLen(1))
+		Expect(amis[0].Name).To(Equal(amd64AMI))
+	})
 	It("should succeed to resolve AMIs (Windows2019)", func() {
 		nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2019@latest"}}
 		awsEnv.SSMAPI.Parameters = map[string]string{
@@ -307,6 +333,85 @@ var _ = Describe("AMIProvider", func() {
 			Expect(amis).To(HaveLen(4))
 		})
 	})
+	Context("AMI Variants", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/recommended/image_id", version):    amd64AMI,
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/nvidia/recommended/image_id", version):      amd64NvidiaAMI,
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/nvidia-open/recommended/image_id", version): arm64NvidiaAMI,
+			}
+		})
+		It("should not select the nvidia-open variant by default", func() {
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(ConsistOf(amd64AMI, amd64NvidiaAMI))
+		})
+		It("should only select the NodeClass's variants", func() {
+			nodeClass.Spec.AMIVariants = []v1.AMIVariant{v1.AMIVariantStandard, v1.AMIVariantNvidiaOpen}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(ConsistOf(amd64AMI, arm64NvidiaAMI))
+			nvidiaOpenAMI, ok := lo.Find(amis, func(a amifamily.AMI) bool { return a.AmiID == arm64NvidiaAMI })
+			Expect(ok).To(BeTrue())
+			Expect(nvidiaOpenAMI.Requirements.Get(v1.LabelInstanceGPUCount).Operator()).To(Equal(corev1.NodeSelectorOpExists))
+		})
+		It("should only select the requirements of the NodeClass's variants for AMIs with multiple variants", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			nodeClass.Spec.AMIVariants = []v1.AMIVariant{v1.AMIVariantStandard}
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/x86_64/latest/image_id", version):        amd64AMI,
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-nvidia/x86_64/latest/image_id", version): amd64NvidiaAMI,
+			}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].AmiID).To(Equal(amd64AMI))
+			Expect(amis[0].Requirements.Get(v1.LabelInstanceAcceleratorCount).Operator()).To(Equal(corev1.NodeSelectorOpDoesNotExist))
+		})
+		It("should fail to resolve AMIs when the family doesn't publish any of the NodeClass's variants", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "ubuntu@latest"}}
+			nodeClass.Spec.AMIVariants = []v1.AMIVariant{v1.AMIVariantNvidia}
+			_, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("FIPS", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.FIPS = lo.ToPtr(true)
+		})
+		It("should select the FIPS AMIs (AL2023)", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/recommended/image_id", version):      amd64AMI,
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/nvidia/recommended/image_id", version):        amd64NvidiaAMI,
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard-fips/recommended/image_id", version): arm64NvidiaAMI,
+			}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(ConsistOf(arm64NvidiaAMI))
+			Expect(amis[0].Requirements.Get(v1.LabelInstanceGPUCount).Operator()).To(Equal(corev1.NodeSelectorOpDoesNotExist))
+		})
+		It("should select the FIPS AMIs (Bottlerocket)", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/x86_64/latest/image_id", version):      amd64AMI,
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-fips/x86_64/latest/image_id", version): amd64NvidiaAMI,
+				fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-fips/arm64/latest/image_id", version):  arm64AMI,
+			}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(ConsistOf(amd64NvidiaAMI, arm64AMI))
+		})
+		It("should fail to resolve AMIs when no FIPS AMI is published", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/recommended/image_id", version): amd64AMI,
+			}
+			_, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).To(MatchError(ContainSubstring("failed to discover FIPS AMIs")))
+		})
+	})
 	Context("AMI Tag Requirements", func() {
 		var img ec2types.Image
 		BeforeEach(func() {
@@ -435,10 +540,11 @@ var _ = Describe("AMIProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(amis).To(HaveLen(1))
 			Expect(amis).To(ConsistOf(amifamily.AMI{
-				Name:         amd64AMI,
-				AmiID:        "ami-1234",
-				CreationDate: "2021-08-31T00:12:42.000Z",
-				Deprecated:   false,
+				Name:            amd64AMI,
+				AmiID:           "ami-1234",
+				CreationDate:    "2021-08-31T00:12:42.000Z",
+				Deprecated:      false,
+				DeprecationTime: awsEnv.Clock.Now().Add(10 * time.Minute).Format(time.RFC3339),
 				Requirements: scheduling.NewRequirements(
 					scheduling.NewRequirement(corev1.LabelArchStable, corev1.NodeSelectorOpIn, karpv1.ArchitectureAmd64),
 				),
@@ -479,10 +585,11 @@ var _ = Describe("AMIProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(amis).To(HaveLen(1))
 			Expect(amis).To(ConsistOf(amifamily.AMI{
-				Name:         "test-ami-1",
-				AmiID:        "ami-1234",
-				CreationDate: "2021-08-31T00:12:42.000Z",
-				Deprecated:   false,
+				Name:            "test-ami-1",
+				AmiID:           "ami-1234",
+				CreationDate:    "2021-08-31T00:12:42.000Z",
+				Deprecated:      false,
+				DeprecationTime: awsEnv.Clock.Now().Add(10 * time.Minute).Format(time.RFC3339),
 				Requirements: scheduling.NewRequirements(
 					scheduling.NewRequirement(corev1.LabelArchStable, corev1.NodeSelectorOpIn, karpv1.ArchitectureAmd64),
 				),
@@ -522,16 +629,444 @@ var _ = Describe("AMIProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(amis).To(HaveLen(1))
 			Expect(amis).To(ConsistOf(amifamily.AMI{
-				Name:         amd64AMI,
-				AmiID:        "ami-5678",
-				CreationDate: "2021-08-31T00:12:42.000Z",
-				Deprecated:   true,
+				Name:            amd64AMI,
+				AmiID:           "ami-5678",
+				CreationDate:    "2021-08-31T00:12:42.000Z",
+				Deprecated:      true,
+				DeprecationTime: awsEnv.Clock.Now().Add(-1 * time.Hour).Format(time.RFC3339),
 				Requirements: scheduling.NewRequirements(
 					scheduling.NewRequirement(corev1.LabelArchStable, corev1.NodeSelectorOpIn, karpv1.ArchitectureAmd64),
 				),
 			}))
 		})
 	})
+	Context("AMI Windowing", func() {
+		BeforeEach(func() {
+			awsEnv.Clock.SetTime(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+			images := lo.Map([]time.Duration{time.Hour, 3 * 24 * time.Hour, 10 * 24 * time.Hour, 100 * 24 * time.Hour}, func(age time.Duration, i int) ec2types.Image {
+				return ec2types.Image{
+					Name:         aws.String(fmt.Sprintf("ami-%d", i)),
+					ImageId:      aws.String(fmt.Sprintf("ami-%d", i)),
+					CreationDate: aws.String(awsEnv.Clock.Now().Add(-age).Format(time.RFC3339)),
+					Architecture: "x86_64",
+					State:        ec2types.ImageStateAvailable,
+				}
+			})
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: images})
+		})
+		DescribeTable("should select the AMIs within the window",
+			func(term v1.AMISelectorTerm, expected ...string) {
+				term.Tags = map[string]string{"*": "*"}
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{term}
+				amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(Equal(expected))
+			},
+			Entry("no constraints", v1.AMISelectorTerm{}, "ami-0"),
+			Entry("newest count", v1.AMISelectorTerm{NewestCount: lo.ToPtr[int32](2)}, "ami-0", "ami-1"),
+			Entry("min age", v1.AMISelectorTerm{MinAge: &metav1.Duration{Duration: 7 * 24 * time.Hour}}, "ami-2"),
+			Entry("max age", v1.AMISelectorTerm{MaxAge: &metav1.Duration{Duration: 30 * 24 * time.Hour}, NewestCount: lo.ToPtr[int32](10)}, "ami-0", "ami-1", "ami-2"),
+			Entry("min age and newest count", v1.AMISelectorTerm{MinAge: &metav1.Duration{Duration: 2 * time.Hour}, NewestCount: lo.ToPtr[int32](2)}, "ami-1", "ami-2"),
+			Entry("min and max age", v1.AMISelectorTerm{MinAge: &metav1.Duration{Duration: 2 * time.Hour}, MaxAge: &metav1.Duration{Duration: 5 * 24 * time.Hour}}, "ami-1"),
+		)
+		It("should use the largest newest count of the terms which selected an AMI", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{
+				{Tags: map[string]string{"*": "*"}},
+				{Name: "ami-*", NewestCount: lo.ToPtr[int32](3)},
+			}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(Equal([]string{"ami-0", "ami-1", "ami-2"}))
+		})
+		It("should set the window on the query", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{
+				Name:        "my-ami",
+				NewestCount: lo.ToPtr[int32](2),
+				MinAge:      &metav1.Duration{Duration: time.Hour},
+				MaxAge:      &metav1.Duration{Duration: 24 * time.Hour},
+			}}
+			queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queries).To(HaveLen(1))
+			Expect(queries[0].NewestCount).To(BeNumerically("==", 2))
+			Expect(queries[0].MinAge).To(Equal(time.Hour))
+			Expect(queries[0].MaxAge).To(Equal(24 * time.Hour))
+		})
+	})
+	Context("AMI Name Patterns and Exclusions", func() {
+		BeforeEach(func() {
+			images := lo.Map(lo.Range(4), func(i int, _ int) ec2types.Image {
+				return ec2types.Image{
+					Name:         aws.String(fmt.Sprintf("amazon-eks-node-1.31-v2024100%d", i)),
+					ImageId:      aws.String(fmt.Sprintf("ami-%d", i)),
+					CreationDate: aws.String(time.Date(2024, 10, i+1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)),
+					Architecture: "x86_64",
+					State:        ec2types.ImageStateAvailable,
+					Tags:         lo.Ternary(i == 3, []ec2types.Tag{{Key: aws.String("quarantined"), Value: aws.String("true")}}, nil),
+				}
+			})
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: images})
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Name: "amazon-eks-node-1.31-*", NewestCount: lo.ToPtr[int32](10)}}
+		})
+		It("should select the AMIs whose names match the name pattern", func() {
+			nodeClass.Spec.AMISelectorTerms[0].NamePattern = `-v2024100[12]$`
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(Equal([]string{"ami-2", "ami-1"}))
+		})
+		It("should default the owners of terms with a name pattern", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{NamePattern: "^amazon-eks-node-"}}
+			queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queries).To(HaveLen(1))
+			Expect(queries[0].Owners).To(ConsistOf("self", "amazon"))
+			Expect(queries[0].NamePattern).To(Equal("^amazon-eks-node-"))
+		})
+		It("should fail when the name pattern isn't a valid regular expression", func() {
+			nodeClass.Spec.AMISelectorTerms[0].NamePattern = "("
+			_, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).To(HaveOccurred())
+		})
+		DescribeTable("should select the newest AMI that isn't excluded",
+			func(term v1.AMIExclusionTerm, expected string) {
+				nodeClass.Spec.AMISelectorTerms[0].NewestCount = nil
+				nodeClass.Spec.AMIExclusionTerms = []v1.AMIExclusionTerm{term}
+				amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(Equal([]string{expected}))
+			},
+			Entry("by id", v1.AMIExclusionTerm{ID: "ami-3"}, "ami-2"),
+			Entry("by name", v1.AMIExclusionTerm{Name: "amazon-eks-node-1.31-v20241003"}, "ami-2"),
+			Entry("by name wildcard", v1.AMIExclusionTerm{Name: "*-1.31-*3"}, "ami-2"),
+			Entry("by name pattern", v1.AMIExclusionTerm{NamePattern: `-v2024100[23]$`}, "ami-1"),
+			Entry("by tag", v1.AMIExclusionTerm{Tags: map[string]string{"quarantined": "true"}}, "ami-2"),
+			Entry("by tag key", v1.AMIExclusionTerm{Tags: map[string]string{"quarantined": "*"}}, "ami-2"),
+			Entry("by all of the fields", v1.AMIExclusionTerm{ID: "ami-3", Tags: map[string]string{"quarantined": "false"}}, "ami-3"),
+		)
+		It("should exclude AMIs selected by id", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-2"}, {ID: "ami-3"}}
+			nodeClass.Spec.AMIExclusionTerms = []v1.AMIExclusionTerm{{Tags: map[string]string{"quarantined": "*"}}}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(Equal([]string{"ami-2"}))
+		})
+	})
+	Context("Describe Images", func() {
+		BeforeEach(func() {
+			images := lo.Map(lo.Range(5), func(i int, _ int) ec2types.Image {
+				return ec2types.Image{
+					Name:         aws.String(fmt.Sprintf("ami-%d", i)),
+					ImageId:      aws.String(fmt.Sprintf("ami-%d", i)),
+					CreationDate: aws.String(time.Date(2024, 7, 1, i, 0, 0, 0, time.UTC).Format(time.RFC3339)),
+					Architecture: "x86_64",
+					State:        ec2types.ImageStateAvailable,
+				}
+			})
+			images = append(images, ec2types.Image{
+				Name:         aws.String("ami-i386"),
+				ImageId:      aws.String("ami-i386"),
+				CreationDate: aws.String(time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)),
+				Architecture: "i386",
+				State:        ec2types.ImageStateAvailable,
+			})
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: images})
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}, NewestCount: lo.ToPtr[int32](10)}}
+		})
+		It("should filter out images of unsupported architectures in the request", func() {
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).ToNot(ContainElement("ami-i386"))
+			input := awsEnv.EC2API.CalledWithDescribeImagesInput.Pop()
+			filter, ok := lo.Find(input.Filters, func(f ec2types.Filter) bool { return aws.ToString(f.Name) == "architecture" })
+			Expect(ok).To(BeTrue())
+			Expect(filter.Values).To(ConsistOf("x86_64", "arm64"))
+		})
+		It("should stop describing images once the maximum is reached", func() {
+			limitedCtx := options.ToContext(ctx, test.Options(test.OptionsFields{MaxDescribedImages: lo.ToPtr(3)}))
+			amis, err := awsEnv.AMIProvider.List(limitedCtx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(ConsistOf("ami-0", "ami-1", "ami-2"))
+		})
+		It("should not limit the described images when the maximum is 0", func() {
+			limitedCtx := options.ToContext(ctx, test.Options(test.OptionsFields{MaxDescribedImages: lo.ToPtr(0)}))
+			amis, err := awsEnv.AMIProvider.List(limitedCtx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(5))
+		})
+		It("should combine the images described concurrently for each query", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{
+				{Name: "ami-1"},
+				{Name: "ami-3", NewestCount: lo.ToPtr[int32](2)},
+				{ID: "ami-4"},
+			}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(amis, func(a amifamily.AMI, _ int) string { return a.AmiID })).To(Equal([]string{"ami-4", "ami-3"}))
+			Expect(awsEnv.EC2API.CalledWithDescribeImagesInput.Len()).To(Equal(3))
+		})
+		It("should derive requirements from the capabilities the image declares", func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []ec2types.Image{{
+				Name:         aws.String("ami-uefi"),
+				ImageId:      aws.String("ami-uefi"),
+				CreationDate: aws.String(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)),
+				Architecture: "x86_64",
+				State:        ec2types.ImageStateAvailable,
+				BootMode:     ec2types.BootModeValuesUefi,
+				TpmSupport:   ec2types.TpmSupportValuesV20,
+				EnaSupport:   lo.ToPtr(false),
+			}}})
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].Requirements).To(Equal(scheduling.NewRequirements(
+				scheduling.NewRequirement(corev1.LabelArchStable, corev1.NodeSelectorOpIn, karpv1.ArchitectureAmd64),
+				scheduling.NewRequirement(v1.LabelInstanceBootMode, corev1.NodeSelectorOpIn, string(ec2types.BootModeValuesUefi)),
+				scheduling.NewRequirement(v1.LabelInstanceTPMSupported, corev1.NodeSelectorOpIn, "true"),
+				scheduling.NewRequirement(v1.LabelInstanceENASupport, corev1.NodeSelectorOpNotIn, string(ec2types.EnaSupportRequired)),
+			)))
+		})
+		It("should not derive a boot mode requirement from images which prefer uefi", func() {
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []ec2types.Image{{
+				Name:         aws.String("ami-uefi-preferred"),
+				ImageId:      aws.String("ami-uefi-preferred"),
+				CreationDate: aws.String(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)),
+				Architecture: "x86_64",
+				State:        ec2types.ImageStateAvailable,
+				BootMode:     ec2types.BootModeValuesUefiPreferred,
+				EnaSupport:   lo.ToPtr(true),
+			}}})
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].Requirements).To(Equal(scheduling.NewRequirements(
+				scheduling.NewRequirement(corev1.LabelArchStable, corev1.NodeSelectorOpIn, karpv1.ArchitectureAmd64),
+			)))
+		})
+		It("should return an error if describing any query fails", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Name: "ami-1"}, {Name: "ami-3"}}
+			awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+			_, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Image Builder", func() {
+		const pipelineARN = "arn:aws:imagebuilder:us-west-2:123456789012:image-pipeline/golden"
+		const recipeARN = "arn:aws:imagebuilder:us-west-2:123456789012:image-recipe/golden/1.2.0"
+		image := func(status imagebuildertypes.ImageStatus, created string, amiIDs ...string) imagebuildertypes.ImageSummary {
+			return imagebuildertypes.ImageSummary{
+				DateCreated: lo.ToPtr(created),
+				State:       &imagebuildertypes.ImageState{Status: status},
+				OutputResources: &imagebuildertypes.OutputResources{Amis: lo.Map(amiIDs, func(id string, _ int) imagebuildertypes.Ami {
+					return imagebuildertypes.Ami{Region: lo.ToPtr(fake.DefaultRegion), Image: lo.ToPtr(id)}
+				})},
+			}
+		}
+		It("should select the AMIs of the newest available image of a pipeline", func() {
+			awsEnv.ImageBuilderAPI.ListImagePipelineImagesBehavior.Output.Set(&imagebuilder.ListImagePipelineImagesOutput{
+				ImageSummaryList: []imagebuildertypes.ImageSummary{
+					image(imagebuildertypes.ImageStatusAvailable, "2024-07-01T00:00:00.000Z", "ami-old"),
+					image(imagebuildertypes.ImageStatusAvailable, "2024-07-03T00:00:00.000Z", "ami-new-1", "ami-new-2"),
+					image(imagebuildertypes.ImageStatusBuilding, "2024-07-04T00:00:00.000Z", "ami-building"),
+					image(imagebuildertypes.ImageStatusAvailable, "2024-07-02T00:00:00.000Z", "ami-older"),
+				},
+			})
+			queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, &v1.EC2NodeClass{
+				Spec: v1.EC2NodeClassSpec{AMISelectorTerms: []v1.AMISelectorTerm{{ImageBuilderARN: pipelineARN}, {ID: "ami-pinned"}}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			ExpectConsistsOfAMIQueries([]amifamily.DescribeImageQuery{
+				{Filters: []ec2types.Filter{{Name: lo.ToPtr("image-id"), Values: []string{"ami-new-1", "ami-new-2", "ami-pinned"}}}},
+			}, queries)
+			Expect(awsEnv.ImageBuilderAPI.ListImagePipelineImagesBehavior.CalledWithInput.Pop().ImagePipelineArn).To(HaveValue(Equal(pipelineARN)))
+		})
+		It("should select the AMIs of the newest available build of a recipe", func() {
+			awsEnv.ImageBuilderAPI.ListImageBuildVersionsBehavior.Output.Set(&imagebuilder.ListImageBuildVersionsOutput{
+				ImageSummaryList: []imagebuildertypes.ImageSummary{
+					image(imagebuildertypes.ImageStatusAvailable, "2024-07-01T00:00:00Z", "ami-build-1"),
+					image(imagebuildertypes.ImageStatusFailed, "2024-07-02T00:00:00Z"),
+				},
+			})
+			queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, &v1.EC2NodeClass{
+				Spec: v1.EC2NodeClassSpec{AMISelectorTerms: []v1.AMISelectorTerm{{ImageBuilderARN: recipeARN}}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			ExpectConsistsOfAMIQueries([]amifamily.DescribeImageQuery{
+				{Filters: []ec2types.Filter{{Name: lo.ToPtr("image-id"), Values: []string{"ami-build-1"}}}},
+			}, queries)
+			Expect(awsEnv.ImageBuilderAPI.ListImageBuildVersionsBehavior.CalledWithInput.Pop().ImageVersionArn).To(HaveValue(Equal("arn:aws:imagebuilder:us-west-2:123456789012:image/golden/1.2.0")))
+		})
+		It("should consider the images of every page", func() {
+			awsEnv.ImageBuilderAPI.ListImagePipelineImagesBehavior.OutputPages.Add(&imagebuilder.ListImagePipelineImagesOutput{
+				ImageSummaryList: []imagebuildertypes.ImageSummary{image(imagebuildertypes.ImageStatusAvailable, "2024-07-01T00:00:00Z", "ami-page-1")},
+			})
+			awsEnv.ImageBuilderAPI.ListImagePipelineImagesBehavior.OutputPages.Add(&imagebuilder.ListImagePipelineImagesOutput{
+				ImageSummaryList: []imagebuildertypes.ImageSummary{image(imagebuildertypes.ImageStatusAvailable, "2024-07-02T00:00:00Z", "ami-page-2")},
+			})
+			queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, &v1.EC2NodeClass{
+				Spec: v1.EC2NodeClassSpec{AMISelectorTerms: []v1.AMISelectorTerm{{ImageBuilderARN: pipelineARN}}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queries).To(HaveLen(1))
+			Expect(queries[0].Filters[0].Values).To(ConsistOf("ami-page-2"))
+			Expect(awsEnv.ImageBuilderAPI.ListImagePipelineImagesBehavior.CalledWithInput.Len()).To(Equal(2))
+		})
+		It("should cache the resolved AMIs", func() {
+			awsEnv.ImageBuilderAPI.ListImagePipelineImagesBehavior.Output.Set(&imagebuilder.ListImagePipelineImagesOutput{
+				ImageSummaryList: []imagebuildertypes.ImageSummary{image(imagebuildertypes.ImageStatusAvailable, "2024-07-01T00:00:00Z", "ami-cached")},
+			})
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ImageBuilderARN: pipelineARN}}
+			for range 2 {
+				queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, nodeClass)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(queries[0].Filters[0].Values).To(ConsistOf("ami-cached"))
+			}
+			Expect(awsEnv.ImageBuilderAPI.ListImagePipelineImagesBehavior.Calls()).To(Equal(1))
+		})
+		It("should not select any AMIs when the pipeline hasn't built an available image", func() {
+			awsEnv.ImageBuilderAPI.ListImagePipelineImagesBehavior.Output.Set(&imagebuilder.ListImagePipelineImagesOutput{
+				ImageSummaryList: []imagebuildertypes.ImageSummary{image(imagebuildertypes.ImageStatusBuilding, "2024-07-01T00:00:00Z")},
+			})
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ImageBuilderARN: pipelineARN}}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(BeEmpty())
+		})
+		It("should return an error if listing the pipeline's images fails", func() {
+			awsEnv.ImageBuilderAPI.ListImagePipelineImagesBehavior.Error.Set(fmt.Errorf("failed"))
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ImageBuilderARN: pipelineARN}}
+			_, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("SSM Parameters", func() {
+		It("should select the AMI referenced by the parameter", func() {
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				"/golden/al2023": "ami-golden",
+				"arn:aws:ssm:us-west-2:111122223333:parameter/golden/bottlerocket": "ami-shared",
+			}
+			queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, &v1.EC2NodeClass{
+				Spec: v1.EC2NodeClassSpec{AMISelectorTerms: []v1.AMISelectorTerm{
+					{SSMParameter: "/golden/al2023"},
+					{SSMParameter: "arn:aws:ssm:us-west-2:111122223333:parameter/golden/bottlerocket"},
+					{ID: "ami-pinned"},
+				}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			ExpectConsistsOfAMIQueries([]amifamily.DescribeImageQuery{
+				{Filters: []ec2types.Filter{{Name: lo.ToPtr("image-id"), Values: []string{"ami-golden", "ami-shared", "ami-pinned"}}}},
+			}, queries)
+		})
+		It("should cache parameters separately from alias parameters", func() {
+			awsEnv.SSMAPI.Parameters = map[string]string{"/golden/al2023": "ami-golden"}
+			_, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, &v1.EC2NodeClass{
+				Spec: v1.EC2NodeClassSpec{AMISelectorTerms: []v1.AMISelectorTerm{{SSMParameter: "/golden/al2023"}}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.AMIParameterCache.ItemCount()).To(Equal(1))
+			Expect(awsEnv.SSMCache.ItemCount()).To(Equal(0))
+		})
+		It("should return an error if the parameter can't be read", func() {
+			awsEnv.SSMAPI.WantErr = fmt.Errorf("access denied")
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{SSMParameter: "/golden/al2023"}}
+			_, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("SSM Parameter Paths", func() {
+		It("should select the AMIs under the path for the cluster's version", func() {
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/golden/eks/%s/x86_64", version):  "ami-amd64",
+				fmt.Sprintf("/golden/eks/%s/arm64", version):   "ami-arm64",
+				"/golden/eks/1.0/x86_64":                       "ami-other-version",
+				"/golden/eks/k8s-1.0/arm64":                    "ami-other-version-prefixed",
+				"/golden/eks/shared":                           "ami-unversioned",
+				fmt.Sprintf("/golden/eks/%s/release", version): "v20240807",
+				"/other/x86_64":                                "ami-other-path",
+			}
+			queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, &v1.EC2NodeClass{
+				Spec: v1.EC2NodeClassSpec{AMISelectorTerms: []v1.AMISelectorTerm{{SSMParameterPath: "/golden/eks"}}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queries).To(HaveLen(1))
+			Expect(queries[0].Filters[0].Values).To(ConsistOf("ami-amd64", "ami-arm64", "ami-unversioned"))
+		})
+		It("should resolve requirements from the architecture of each AMI", func() {
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				fmt.Sprintf("/golden/eks/%s/x86_64", version): "ami-amd64",
+				fmt.Sprintf("/golden/eks/%s/arm64", version):  "ami-arm64",
+			}
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []ec2types.Image{
+				{Name: aws.String("amd64"), ImageId: aws.String("ami-amd64"), Architecture: "x86_64", CreationDate: aws.String("2024-07-01T00:00:00Z"), State: ec2types.ImageStateAvailable},
+				{Name: aws.String("arm64"), ImageId: aws.String("ami-arm64"), Architecture: "arm64", CreationDate: aws.String("2024-07-01T00:00:00Z"), State: ec2types.ImageStateAvailable},
+			}})
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{SSMParameterPath: "/golden/eks"}}
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(2))
+			for _, ami := range amis {
+				Expect(ami.Requirements.Get(corev1.LabelArchStable).Values()).To(ConsistOf(lo.Ternary(ami.AmiID == "ami-amd64", karpv1.ArchitectureAmd64, karpv1.ArchitectureArm64)))
+			}
+		})
+		It("should return an error if the parameters can't be read", func() {
+			awsEnv.SSMAPI.WantErr = fmt.Errorf("access denied")
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{SSMParameterPath: "/golden/eks"}}
+			_, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("AMI Rollout", func() {
+		amd64Requirements := []corev1.NodeSelectorRequirement{{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}}}
+		arm64Requirements := []corev1.NodeSelectorRequirement{{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureArm64}}}
+		var instanceType *cloudprovider.InstanceType
+		BeforeEach(func() {
+			nodeClass.Status.AMIs = []v1.AMI{{ID: "ami-current", Requirements: amd64Requirements}}
+			nodeClass.Status.AMIRollout = &v1.AMIRolloutStatus{
+				PreviousAMIs: []v1.AMI{{ID: "ami-previous", Requirements: amd64Requirements}},
+				Percentage:   50,
+			}
+			instanceType = &cloudprovider.InstanceType{
+				Name:         "m5.large",
+				Requirements: scheduling.NewRequirements(scheduling.NewRequirement(corev1.LabelArchStable, corev1.NodeSelectorOpIn, karpv1.ArchitectureAmd64)),
+			}
+		})
+		rolledOut := func() int {
+			return lo.CountBy(lo.Range(1000), func(i int) bool {
+				amis := amifamily.RolloutAMIs(nodeClass, &karpv1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("default-%d", i)}})
+				return amifamily.MapToInstanceTypes([]*cloudprovider.InstanceType{instanceType}, amis)["ami-current"] != nil
+			})
+		}
+		It("should return the resolved AMIs when there isn't a rollout", func() {
+			nodeClass.Status.AMIRollout = nil
+			Expect(amifamily.RolloutAMIs(nodeClass, &karpv1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Name: "default"}})).To(Equal(nodeClass.Status.AMIs))
+		})
+		It("should launch the rollout's percentage of NodeClaims with the resolved AMIs", func() {
+			Expect(rolledOut()).To(BeNumerically("~", 500, 75))
+			nodeClass.Status.AMIRollout.Percentage = 0
+			Expect(rolledOut()).To(Equal(0))
+			nodeClass.Status.AMIRollout.Percentage = 100
+			Expect(rolledOut()).To(Equal(1000))
+		})
+		It("should select the same AMIs for a NodeClaim consistently", func() {
+			nodeClaim := &karpv1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Name: "default-abcde"}}
+			Expect(amifamily.RolloutAMIs(nodeClass, nodeClaim)).To(Equal(amifamily.RolloutAMIs(nodeClass, nodeClaim)))
+		})
+		It("should launch NodeClaims with the previous AMIs once the rollout's node limit is reached", func() {
+			nodeClass.Spec.AMIRollout = &v1.AMIRollout{MaxNodes: lo.ToPtr[int32](5)}
+			nodeClass.Status.AMIRollout.Percentage = 100
+			nodeClass.Status.AMIRollout.LaunchedNodes = 5
+			Expect(rolledOut()).To(Equal(0))
+		})
+		It("should fall back to the resolved AMIs for instance types the previous AMIs don't support", func() {
+			nodeClass.Status.AMIRollout.Percentage = 0
+			nodeClass.Status.AMIRollout.PreviousAMIs[0].Requirements = arm64Requirements
+			Expect(rolledOut()).To(Equal(1000))
+		})
+		It("should consider both the previous and resolved AMIs eligible during a rollout", func() {
+			Expect(amifamily.RolloutEligibleAMIs(instanceType, nodeClass)).To(ConsistOf("ami-current", "ami-previous"))
+			nodeClass.Status.AMIRollout = nil
+			Expect(amifamily.RolloutEligibleAMIs(instanceType, nodeClass)).To(ConsistOf("ami-current"))
+		})
+	})
 	Context("AMI Selectors", func() {
 		// When you tag public or shared resources, the tags you assign are available only to your AWS account; no other AWS account will have access to those tags
 		// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/Using_Tags.html#tag-restrictions
@@ -582,6 +1117,52 @@ var _ = Describe("AMIProvider", func() {
 				},
 			}, queries)
 		})
+		DescribeTable("should discover flatcar AMIs by owner and name",
+			func(alias string, name string) {
+				queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, &v1.EC2NodeClass{
+					Spec: v1.EC2NodeClassSpec{
+						AMISelectorTerms: []v1.AMISelectorTerm{{Alias: alias}},
+					},
+				})
+				Expect(err).To(BeNil())
+				ExpectConsistsOfAMIQueries([]amifamily.DescribeImageQuery{
+					{
+						Filters: []ec2types.Filter{
+							{
+								Name:   lo.ToPtr("name"),
+								Values: []string{name},
+							},
+						},
+						Owners: []string{amifamily.FlatcarOwner},
+					},
+				}, queries)
+			},
+			Entry("latest", "flatcar@latest", "Flatcar-stable-*"),
+			Entry("pinned", "flatcar@4081.2.0", "Flatcar-stable-4081.2.0-*"),
+		)
+		DescribeTable("should discover talos AMIs by owner and name",
+			func(alias string, name string) {
+				queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, &v1.EC2NodeClass{
+					Spec: v1.EC2NodeClassSpec{
+						AMISelectorTerms: []v1.AMISelectorTerm{{Alias: alias}},
+					},
+				})
+				Expect(err).To(BeNil())
+				ExpectConsistsOfAMIQueries([]amifamily.DescribeImageQuery{
+					{
+						Filters: []ec2types.Filter{
+							{
+								Name:   lo.ToPtr("name"),
+								Values: []string{name},
+							},
+						},
+						Owners: []string{amifamily.TalosOwner},
+					},
+				}, queries)
+			},
+			Entry("latest", "talos@latest", "talos-v*"),
+			Entry("pinned", "talos@v1.7.6", "talos-v1.7.6-*"),
+		)
 		It("should not set owners when legacy ids are passed", func() {
 			queries, err := awsEnv.AMIProvider.DescribeImageQueries(ctx, &v1.EC2NodeClass{
 				Spec: v1.EC2NodeClassSpec{
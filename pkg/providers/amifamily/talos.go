@@ -0,0 +1,82 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amifamily
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily/bootstrap"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
+)
+
+// TalosOwner is the account which publishes the official Talos Linux AMIs in the commercial partition
+const TalosOwner = "540036508848"
+
+type Talos struct {
+	DefaultFamily
+	*Options
+}
+
+// DescribeImageQuery discovers the official Talos AMIs by owner and name. Talos releases aren't tied to a Kubernetes
+// version, since the kubelet version is set by the machine config, so the same AMIs are used for every cluster version.
+func (t Talos) DescribeImageQuery(_ context.Context, _ ssm.Provider, _ string, amiVersion string) (DescribeImageQuery, error) {
+	name := fmt.Sprintf("talos-%s-*", amiVersion)
+	if amiVersion == v1.AliasVersionLatest {
+		name = "talos-v*"
+	}
+	return DescribeImageQuery{
+		Owners: []string{TalosOwner},
+		Filters: []ec2types.Filter{{
+			Name:   aws.String("name"),
+			Values: []string{name},
+		}},
+	}, nil
+}
+
+// UserData returns the default userdata script for the AMI Family
+func (t Talos) UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, caBundle *string, _ []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1.InstanceStorePolicy) bootstrap.Bootstrapper {
+	return bootstrap.Talos{
+		Options: bootstrap.Options{
+			ClusterName:         t.Options.ClusterName,
+			ClusterEndpoint:     t.Options.ClusterEndpoint,
+			KubeletConfig:       kubeletConfig,
+			Taints:              taints,
+			Labels:              labels,
+			CABundle:            caBundle,
+			CustomUserData:      customUserData,
+			InstanceStorePolicy: instanceStorePolicy,
+		},
+	}
+}
+
+// DefaultBlockDeviceMappings returns the default block device mappings for the AMI Family
+func (t Talos) DefaultBlockDeviceMappings() []*v1.BlockDeviceMapping {
+	return []*v1.BlockDeviceMapping{{
+		DeviceName: t.EphemeralBlockDevice(),
+		EBS:        &DefaultEBS,
+	}}
+}
+
+func (t Talos) EphemeralBlockDevice() *string {
+	return aws.String("/dev/xvda")
+}
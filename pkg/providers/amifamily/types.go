@@ -17,7 +17,9 @@ package amifamily
 import (
 	"fmt"
 	"math"
+	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -36,7 +38,25 @@ type AMI struct {
 	AmiID        string
 	CreationDate string
 	Deprecated   bool
-	Requirements scheduling.Requirements
+	// DeprecationTime is when the AMI is or was deprecated, empty if no deprecation is scheduled
+	DeprecationTime string
+	Requirements    scheduling.Requirements
+	// PlatformDetails determine the operating system license the AMI is billed for
+	PlatformDetails string
+	// BootMode, ENASupport and TPMSupport are the capabilities the AMI declares, which instance types launched with it
+	// must match
+	BootMode   string
+	ENASupport *bool
+	TPMSupport string
+}
+
+// DeprecatedWithin returns whether the AMI is deprecated, or will be within the threshold of the given time. AMIs
+// without a deprecation time are only deprecated if EC2 reports them as deprecated.
+func (a AMI) DeprecatedWithin(threshold time.Duration, now time.Time) bool {
+	if a.DeprecationTime == "" {
+		return a.Deprecated
+	}
+	return !lo.Must(time.Parse(time.RFC3339, a.DeprecationTime)).After(now.Add(threshold))
 }
 
 type AMIs []AMI
@@ -65,15 +85,16 @@ func parseTimeWithDefault(dateStr string, defaultTime time.Time) time.Time {
 type Variant string
 
 var (
-	VariantStandard Variant   = "standard"
-	VariantNvidia   Variant   = "nvidia"
-	VariantNeuron   Variant   = "neuron"
-	maxTime         time.Time = time.Unix(math.MaxInt64, 0)
-	minTime         time.Time = time.Unix(math.MinInt64, 0)
+	VariantStandard   Variant   = Variant(v1.AMIVariantStandard)
+	VariantNvidia     Variant   = Variant(v1.AMIVariantNvidia)
+	VariantNvidiaOpen Variant   = Variant(v1.AMIVariantNvidiaOpen)
+	VariantNeuron     Variant   = Variant(v1.AMIVariantNeuron)
+	maxTime           time.Time = time.Unix(math.MaxInt64, 0)
+	minTime           time.Time = time.Unix(math.MinInt64, 0)
 )
 
 func NewVariant(v string) (Variant, error) {
-	var wellKnownVariants = sets.New(VariantStandard, VariantNvidia, VariantNeuron)
+	var wellKnownVariants = sets.New(VariantStandard, VariantNvidia, VariantNvidiaOpen, VariantNeuron)
 	variant := Variant(v)
 	if !wellKnownVariants.Has(variant) {
 		return variant, fmt.Errorf("%q is not a well-known variant", variant)
@@ -88,7 +109,7 @@ func (v Variant) Requirements() scheduling.Requirements {
 			scheduling.NewRequirement(v1.LabelInstanceAcceleratorCount, corev1.NodeSelectorOpDoesNotExist),
 			scheduling.NewRequirement(v1.LabelInstanceGPUCount, corev1.NodeSelectorOpDoesNotExist),
 		)
-	case VariantNvidia:
+	case VariantNvidia, VariantNvidiaOpen:
 		return scheduling.NewRequirements(scheduling.NewRequirement(v1.LabelInstanceGPUCount, corev1.NodeSelectorOpExists))
 	case VariantNeuron:
 		return scheduling.NewRequirements(scheduling.NewRequirement(v1.LabelInstanceAcceleratorCount, corev1.NodeSelectorOpExists))
@@ -104,13 +125,33 @@ type DescribeImageQuery struct {
 	// Sometimes, an image may have multiple sets of known requirements. For example, the AL2 GPU AMI is compatible with both Neuron and Nvidia GPU
 	// instances, which means we need a set of requirements for either instance type.
 	KnownRequirements map[string][]scheduling.Requirements
+	// NewestCount is the number of AMIs selected for each set of requirements. Zero is treated as one.
+	NewestCount int32
+	// MinAge and MaxAge bound the time since an image's creation for it to be selected. Zero values don't bound it.
+	MinAge time.Duration
+	MaxAge time.Duration
+	// NamePattern is a regular expression which the names of selected images must match. Empty matches any name.
+	NamePattern string
+	// Exclusions match images which aren't selected, even though they're described for the query
+	Exclusions []v1.AMIExclusionTerm
 }
 
+// supportedArchitectures are the EC2 architectures of the images which may be selected
+var supportedArchitectures = func() []string {
+	architectures := lo.Keys(v1.AWSToKubeArchitectures)
+	sort.Strings(architectures)
+	return architectures
+}()
+
 func (q DescribeImageQuery) DescribeImagesInput() *ec2.DescribeImagesInput {
 	return &ec2.DescribeImagesInput{
+		// Images of architectures that nodes can't run are filtered out by EC2, rather than being described and discarded
 		Filters: append(q.Filters, ec2types.Filter{
 			Name:   lo.ToPtr("state"),
 			Values: []string{string(ec2types.ImageStateAvailable)},
+		}, ec2types.Filter{
+			Name:   lo.ToPtr("architecture"),
+			Values: supportedArchitectures,
 		}),
 		Owners:            lo.Ternary(len(q.Owners) > 0, q.Owners, nil),
 		IncludeDeprecated: aws.Bool(true),
@@ -118,6 +159,81 @@ func (q DescribeImageQuery) DescribeImagesInput() *ec2.DescribeImagesInput {
 	}
 }
 
+// InAgeWindow returns whether an image created at the given date may be selected by the query. The creation date is
+// only parsed when the query has an age window.
+func (q DescribeImageQuery) InAgeWindow(creationDate string, now time.Time) bool {
+	if q.MinAge == 0 && q.MaxAge == 0 {
+		return true
+	}
+	age := now.Sub(parseTimeWithDefault(creationDate, minTime))
+	if q.MinAge > 0 && age < q.MinAge {
+		return false
+	}
+	if q.MaxAge > 0 && age > q.MaxAge {
+		return false
+	}
+	return true
+}
+
+// ImageFilter returns a function which returns whether a described image may be selected by the query, according to
+// its NamePattern and Exclusions which EC2 can't filter images by
+func (q DescribeImageQuery) ImageFilter() (func(ec2types.Image) bool, error) {
+	var namePattern *regexp.Regexp
+	if q.NamePattern != "" {
+		var err error
+		if namePattern, err = regexp.Compile(q.NamePattern); err != nil {
+			return nil, fmt.Errorf("parsing namePattern %q, %w", q.NamePattern, err)
+		}
+	}
+	exclusions := make([]func(ec2types.Image) bool, 0, len(q.Exclusions))
+	for _, term := range q.Exclusions {
+		exclusion, err := exclusionFilter(term)
+		if err != nil {
+			return nil, err
+		}
+		exclusions = append(exclusions, exclusion)
+	}
+	return func(image ec2types.Image) bool {
+		if namePattern != nil && !namePattern.MatchString(lo.FromPtr(image.Name)) {
+			return false
+		}
+		return !lo.ContainsBy(exclusions, func(excluded func(ec2types.Image) bool) bool { return excluded(image) })
+	}, nil
+}
+
+// exclusionFilter returns a function which returns whether an image is matched by every field of the exclusion term
+func exclusionFilter(term v1.AMIExclusionTerm) (func(ec2types.Image) bool, error) {
+	var name, namePattern *regexp.Regexp
+	if term.Name != "" {
+		// EC2 name filters support the '*' and '?' wildcards, which are translated to their regular expressions
+		name = regexp.MustCompile("^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(term.Name)) + "$")
+	}
+	if term.NamePattern != "" {
+		var err error
+		if namePattern, err = regexp.Compile(term.NamePattern); err != nil {
+			return nil, fmt.Errorf("parsing amiExclusionTerms namePattern %q, %w", term.NamePattern, err)
+		}
+	}
+	return func(image ec2types.Image) bool {
+		if term.ID != "" && term.ID != lo.FromPtr(image.ImageId) {
+			return false
+		}
+		if name != nil && !name.MatchString(lo.FromPtr(image.Name)) {
+			return false
+		}
+		if namePattern != nil && !namePattern.MatchString(lo.FromPtr(image.Name)) {
+			return false
+		}
+		tags := lo.SliceToMap(image.Tags, func(t ec2types.Tag) (string, string) { return lo.FromPtr(t.Key), lo.FromPtr(t.Value) })
+		for k, v := range term.Tags {
+			if value, ok := tags[k]; !ok || (v != "*" && v != value) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
 func (q DescribeImageQuery) RequirementsForImageWithArchitecture(image string, arch string) []scheduling.Requirements {
 	if knownRequirements, ok := q.KnownRequirements[image]; ok {
 		return lo.Map(knownRequirements, func(r scheduling.Requirements, _ int) scheduling.Requirements {
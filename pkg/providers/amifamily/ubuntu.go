@@ -0,0 +1,116 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amifamily
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily/bootstrap"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
+)
+
+// ubuntuReleases are the Ubuntu LTS releases which Canonical publishes EKS AMIs for, along with the EBS volume type in
+// their SSM parameter paths
+var ubuntuReleases = map[string]string{
+	"22.04": "ebs-gp2",
+	"24.04": "ebs-gp3",
+}
+
+type Ubuntu struct {
+	DefaultFamily
+	*Options
+}
+
+// DescribeImageQuery discovers Canonical's EKS AMIs through the SSM parameters they publish for each release,
+// Kubernetes version, and architecture. Versions are Canonical's image serials (e.g. 20240625), with "latest"
+// resolving to the current image. When AMIs are published for multiple releases, the newest is selected.
+func (u Ubuntu) DescribeImageQuery(ctx context.Context, ssmProvider ssm.Provider, k8sVersion string, amiVersion string) (DescribeImageQuery, error) {
+	ids := map[string][]Variant{}
+	// Canonical only publishes a standard variant of its EKS AMIs
+	if len(u.Options.selectedVariants(VariantStandard)) == 0 {
+		return DescribeImageQuery{}, fmt.Errorf(`no selected AMI variants are published for alias "ubuntu@%s"`, amiVersion)
+	}
+	for release, volumeType := range ubuntuReleases {
+		for _, arch := range []string{"amd64", "arm64"} {
+			imageID, err := ssmProvider.Get(ctx, ssm.Parameter{
+				Name: fmt.Sprintf("/aws/service/canonical/ubuntu/eks/%s/%s/stable/%s/%s/hvm/%s/ami-id", release, k8sVersion, lo.Ternary(
+					amiVersion == v1.AliasVersionLatest,
+					"current",
+					amiVersion,
+				), arch, volumeType),
+				IsMutable: amiVersion == v1.AliasVersionLatest,
+			})
+			if err != nil {
+				continue
+			}
+			ids[imageID] = []Variant{VariantStandard}
+		}
+	}
+	// Failed to discover any AMIs, we should short circuit AMI discovery
+	if len(ids) == 0 {
+		return DescribeImageQuery{}, fmt.Errorf(`failed to discover any AMIs for alias "ubuntu@%s"`, amiVersion)
+	}
+
+	return DescribeImageQuery{
+		Filters: []ec2types.Filter{{
+			Name:   lo.ToPtr("image-id"),
+			Values: lo.Keys(ids),
+		}},
+		KnownRequirements: lo.MapValues(ids, func(variants []Variant, _ string) []scheduling.Requirements {
+			return lo.Map(variants, func(v Variant, _ int) scheduling.Requirements { return v.Requirements() })
+		}),
+	}, nil
+}
+
+// UserData returns the default userdata script for the AMI Family. Canonical's EKS AMIs ship the same bootstrap script
+// as the AL2 EKS optimized AMI, so the userdata is generated the same way.
+func (u Ubuntu) UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, caBundle *string, _ []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1.InstanceStorePolicy) bootstrap.Bootstrapper {
+	return bootstrap.EKS{
+		Options: bootstrap.Options{
+			ClusterName:         u.Options.ClusterName,
+			ClusterEndpoint:     u.Options.ClusterEndpoint,
+			KubeletConfig:       kubeletConfig,
+			Taints:              taints,
+			Labels:              labels,
+			CABundle:            caBundle,
+			CustomUserData:      customUserData,
+			InstanceStorePolicy: instanceStorePolicy,
+			UserDataMergeOrder:  u.Options.UserDataMergeOrder,
+			DeviceValidation:    bootstrap.NewDeviceValidation(u.Options.DeviceValidation),
+		},
+	}
+}
+
+// DefaultBlockDeviceMappings returns the default block device mappings for the AMI Family
+func (u Ubuntu) DefaultBlockDeviceMappings() []*v1.BlockDeviceMapping {
+	return []*v1.BlockDeviceMapping{{
+		DeviceName: u.EphemeralBlockDevice(),
+		EBS:        &DefaultEBS,
+	}}
+}
+
+func (u Ubuntu) EphemeralBlockDevice() *string {
+	return aws.String("/dev/sda1")
+}
@@ -0,0 +1,88 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// validateEFA checks the network configuration an instance with EFA interfaces depends on before it's launched. EC2
+// launches these instances even if EFA traffic can't flow between them, so without this check the misconfiguration
+// would only surface once the workload fails to communicate.
+func (p *DefaultProvider) validateEFA(ctx context.Context, nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, zonalSubnets map[string]*subnet.Subnet) error {
+	if !lo.Contains(lo.Keys(nodeClaim.Spec.Resources.Requests), v1.ResourceEFA) {
+		return nil
+	}
+	offeredZones := sets.New[string]()
+	for _, it := range instanceTypes {
+		for _, o := range it.Offerings.Available() {
+			offeredZones.Insert(o.Zone())
+		}
+	}
+	if !offeredZones.HasAny(lo.Keys(zonalSubnets)...) {
+		return cloudprovider.NewCreateError(
+			fmt.Errorf("no subnet in a zone offering the EFA instance types, instance types %s are offered in zones %v, subnets are in zones %v",
+				utils.PrettySlice(lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) string { return it.Name }), 5), sets.List(offeredZones), lo.Keys(zonalSubnets)),
+			"EFAZoneUnsupported",
+			fmt.Sprintf("No subnet in zones %v, where the EFA instance types are offered", sets.List(offeredZones)),
+		)
+	}
+	securityGroups, err := p.securityGroupProvider.List(ctx, nodeClass)
+	if err != nil {
+		return cloudprovider.NewCreateError(fmt.Errorf("getting security groups, %w", err), "SecurityGroupResolutionFailed", "Error getting security groups")
+	}
+	if ingress, egress := efaSelfReferencingRules(securityGroups); !ingress || !egress {
+		missing := lo.Compact([]string{lo.Ternary(!ingress, "inbound", ""), lo.Ternary(!egress, "outbound", "")})
+		ids := lo.Map(securityGroups, func(sg ec2types.SecurityGroup, _ int) string { return lo.FromPtr(sg.GroupId) })
+		sort.Strings(ids)
+		return cloudprovider.NewCreateError(
+			fmt.Errorf("security groups %v don't allow all %v traffic from the security groups themselves, which EFA requires", ids, missing),
+			"EFASecurityGroupRulesMissing",
+			fmt.Sprintf("EFA requires a security group rule allowing all %v traffic to and from the node's security groups", missing),
+		)
+	}
+	return nil
+}
+
+// efaSelfReferencingRules returns whether the security groups allow all inbound and outbound traffic between instances
+// in them. EFA traffic isn't IP based, so it isn't matched by CIDR rules and must be allowed by referencing a group.
+func efaSelfReferencingRules(securityGroups []ec2types.SecurityGroup) (ingress bool, egress bool) {
+	ids := sets.New(lo.Map(securityGroups, func(sg ec2types.SecurityGroup, _ int) string { return lo.FromPtr(sg.GroupId) })...)
+	allowsAll := func(permissions []ec2types.IpPermission) bool {
+		return lo.ContainsBy(permissions, func(permission ec2types.IpPermission) bool {
+			return lo.FromPtr(permission.IpProtocol) == "-1" && lo.ContainsBy(permission.UserIdGroupPairs, func(pair ec2types.UserIdGroupPair) bool {
+				return ids.Has(lo.FromPtr(pair.GroupId))
+			})
+		})
+	}
+	for _, sg := range securityGroups {
+		ingress = ingress || allowsAll(sg.IpPermissions)
+		egress = egress || allowsAll(sg.IpPermissionsEgress)
+	}
+	return ingress, egress
+}
@@ -16,10 +16,12 @@ package instance
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
@@ -45,6 +47,8 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/reservedinstance"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -57,6 +61,12 @@ const (
 )
 
 var (
+	// errInsufficientFreeAddresses is wrapped by launch errors where subnets ran out of free IP addresses, so that the
+	// launch is retried in the other subnets
+	errInsufficientFreeAddresses = errors.New("subnets ran out of free IP addresses")
+	// errNoCapacityOfferings is returned when none of the instance types are offered in the zones of the launch's subnets
+	errNoCapacityOfferings = errors.New("no capacity offerings are currently available given the constraints")
+
 	instanceStateFilter = ec2types.Filter{
 		Name: aws.String("instance-state-name"),
 		Values: []string{
@@ -75,6 +85,11 @@ type Provider interface {
 	List(context.Context) ([]*Instance, error)
 	Delete(context.Context, string) error
 	CreateTags(context.Context, string, map[string]string) error
+	DeleteTags(context.Context, string, ...string) error
+	GetConsoleOutput(context.Context, string) (string, error)
+	AttachedVolumes(context.Context, string) ([]string, error)
+	ModifySecurityGroups(context.Context, string, []string) error
+	DisableSourceDestCheck(context.Context, string) error
 }
 
 type DefaultProvider struct {
@@ -82,9 +97,11 @@ type DefaultProvider struct {
 	ec2api                      sdk.EC2API
 	unavailableOfferings        *cache.UnavailableOfferings
 	subnetProvider              subnet.Provider
+	securityGroupProvider       securitygroup.Provider
 	launchTemplateProvider      launchtemplate.Provider
 	ec2Batcher                  *batcher.EC2API
 	capacityReservationProvider capacityreservation.Provider
+	reservedInstanceProvider    reservedinstance.Provider
 }
 
 func NewDefaultProvider(
@@ -93,17 +110,22 @@ func NewDefaultProvider(
 	ec2api sdk.EC2API,
 	unavailableOfferings *cache.UnavailableOfferings,
 	subnetProvider subnet.Provider,
+	securityGroupProvider securitygroup.Provider,
 	launchTemplateProvider launchtemplate.Provider,
 	capacityReservationProvider capacityreservation.Provider,
+	reservedInstanceProvider reservedinstance.Provider,
+	launchLimiter *batcher.LaunchLimiter,
 ) *DefaultProvider {
 	return &DefaultProvider{
 		region:                      region,
 		ec2api:                      ec2api,
 		unavailableOfferings:        unavailableOfferings,
 		subnetProvider:              subnetProvider,
+		securityGroupProvider:       securityGroupProvider,
 		launchTemplateProvider:      launchTemplateProvider,
-		ec2Batcher:                  batcher.EC2(ctx, ec2api),
+		ec2Batcher:                  batcher.EC2(ctx, ec2api, launchLimiter),
 		capacityReservationProvider: capacityReservationProvider,
+		reservedInstanceProvider:    reservedInstanceProvider,
 	}
 }
 
@@ -132,10 +154,19 @@ func (p *DefaultProvider) Create(ctx context.Context, nodeClass *v1.EC2NodeClass
 		// cache was out-of-sync on the first try
 		fleetInstance, err = p.launchInstance(ctx, nodeClass, nodeClaim, capacityType, instanceTypes, tags)
 	}
+	// Subnets that ran out of free IP addresses are excluded from the next launch, so the launch is retried in the other
+	// subnets of the EC2NodeClass until it succeeds or every subnet is exhausted
+	for attempts := 1; errors.Is(err, errInsufficientFreeAddresses) && attempts < len(nodeClass.Status.Subnets); attempts++ {
+		log.FromContext(ctx).V(1).Info("retrying launch in subnets with free IP addresses")
+		fleetInstance, err = p.launchInstance(ctx, nodeClass, nodeClaim, capacityType, instanceTypes, tags)
+		// The subnets with free IP addresses may be in zones which don't offer any of the instance types
+		if errors.Is(err, errNoCapacityOfferings) {
+			err = cloudprovider.NewInsufficientCapacityError(fmt.Errorf("retrying launch in subnets with free IP addresses, %w", err))
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
-
 	var capacityReservation string
 	if capacityType == karpv1.CapacityTypeReserved {
 		capacityReservation = p.getCapacityReservationIDForInstance(
@@ -144,6 +175,9 @@ func (p *DefaultProvider) Create(ctx context.Context, nodeClass *v1.EC2NodeClass
 			instanceTypes,
 		)
 	}
+	if capacityType == karpv1.CapacityTypeOnDemand && options.FromContext(ctx).ReservedInstanceCoverage {
+		p.reservedInstanceProvider.MarkLaunched(fleetInstance.InstanceType, *fleetInstance.LaunchTemplateAndOverrides.Overrides.AvailabilityZone)
+	}
 	return NewInstanceFromFleet(
 		fleetInstance,
 		tags,
@@ -181,11 +215,11 @@ func (p *DefaultProvider) List(ctx context.Context) ([]*Instance, error) {
 		Filters: []ec2types.Filter{
 			{
 				Name:   aws.String("tag-key"),
-				Values: []string{v1.NodePoolTagKey},
+				Values: options.FromContext(ctx).TagKeys(v1.NodePoolTagKey),
 			},
 			{
 				Name:   aws.String("tag-key"),
-				Values: []string{v1.NodeClassTagKey},
+				Values: options.FromContext(ctx).TagKeys(v1.NodeClassTagKey),
 			},
 			{
 				Name:   aws.String(fmt.Sprintf("tag:%s", v1.EKSClusterNameTagKey)),
@@ -242,6 +276,96 @@ func (p *DefaultProvider) CreateTags(ctx context.Context, id string, tags map[st
 	return nil
 }
 
+// DeleteTags removes the tags with the given keys from the instance, regardless of their values
+func (p *DefaultProvider) DeleteTags(ctx context.Context, id string, keys ...string) error {
+	if _, err := p.ec2api.DeleteTags(ctx, &ec2.DeleteTagsInput{
+		Resources: []string{id},
+		Tags: lo.Map(keys, func(key string, _ int) ec2types.Tag {
+			return ec2types.Tag{Key: aws.String(key)}
+		}),
+	}); err != nil {
+		if awserrors.IsNotFound(err) {
+			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("untagging instance, %w", err))
+		}
+		return fmt.Errorf("untagging instance, %w", err)
+	}
+	return nil
+}
+
+// GetConsoleOutput returns the most recent serial console output for the instance. EC2 only retains the last 64 KB of
+// output, and it may take a few minutes after boot for the output to become available.
+func (p *DefaultProvider) GetConsoleOutput(ctx context.Context, id string) (string, error) {
+	out, err := p.ec2api.GetConsoleOutput(ctx, &ec2.GetConsoleOutputInput{InstanceId: aws.String(id)})
+	if err != nil {
+		if awserrors.IsNotFound(err) {
+			return "", cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("getting console output, %w", err))
+		}
+		return "", fmt.Errorf("getting console output, %w", err)
+	}
+	output, err := base64.StdEncoding.DecodeString(lo.FromPtr(out.Output))
+	if err != nil {
+		return "", fmt.Errorf("decoding console output, %w", err)
+	}
+	return string(output), nil
+}
+
+// ModifySecurityGroups replaces the security groups attached to the network interface with the provided security groups
+func (p *DefaultProvider) ModifySecurityGroups(ctx context.Context, networkInterfaceID string, securityGroupIDs []string) error {
+	if _, err := p.ec2api.ModifyNetworkInterfaceAttribute(ctx, &ec2.ModifyNetworkInterfaceAttributeInput{
+		NetworkInterfaceId: aws.String(networkInterfaceID),
+		Groups:             securityGroupIDs,
+	}); err != nil {
+		if awserrors.IsNotFound(err) {
+			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("modifying security groups, %w", err))
+		}
+		return fmt.Errorf("modifying security groups, %w", err)
+	}
+	return nil
+}
+
+// DisableSourceDestCheck disables source/destination checking on the network interface
+func (p *DefaultProvider) DisableSourceDestCheck(ctx context.Context, networkInterfaceID string) error {
+	if _, err := p.ec2api.ModifyNetworkInterfaceAttribute(ctx, &ec2.ModifyNetworkInterfaceAttributeInput{
+		NetworkInterfaceId: aws.String(networkInterfaceID),
+		SourceDestCheck:    &ec2types.AttributeBooleanValue{Value: aws.Bool(false)},
+	}); err != nil {
+		if awserrors.IsNotFound(err) {
+			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("disabling source/destination check, %w", err))
+		}
+		return fmt.Errorf("disabling source/destination check, %w", err)
+	}
+	return nil
+}
+
+// AttachedVolumes returns the IDs of the EBS volumes attached to the instance which the EBS CSI driver provisioned for
+// persistent volumes. The driver tags each volume it creates with ebs.csi.aws.com/cluster and CSIVolumeName, so a volume
+// with either tag is matched, while the root and data volumes from the launch template aren't.
+func (p *DefaultProvider) AttachedVolumes(ctx context.Context, id string) ([]string, error) {
+	var volumeIDs []string
+	paginator := ec2.NewDescribeVolumesPaginator(p.ec2api, &ec2.DescribeVolumesInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String("attachment.instance-id"),
+				Values: []string{id},
+			},
+			{
+				Name:   aws.String("tag-key"),
+				Values: []string{"ebs.csi.aws.com/cluster", "CSIVolumeName"},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing volumes, %w", err)
+		}
+		for _, volume := range out.Volumes {
+			volumeIDs = append(volumeIDs, lo.FromPtr(volume.VolumeId))
+		}
+	}
+	return volumeIDs, nil
+}
+
 func (p *DefaultProvider) launchInstance(
 	ctx context.Context,
 	nodeClass *v1.EC2NodeClass,
@@ -251,12 +375,19 @@ func (p *DefaultProvider) launchInstance(
 	tags map[string]string,
 ) (ec2types.CreateFleetInstance, error) {
 	zonalSubnets, err := p.subnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, instanceTypes, capacityType)
+	if errors.Is(err, subnet.ErrSubnetsExhausted) {
+		// Subnets are only exhausted by fleet errors, which are surfaced as insufficient capacity
+		return ec2types.CreateFleetInstance{}, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("getting subnets, %w", err))
+	}
 	if err != nil {
 		return ec2types.CreateFleetInstance{}, cloudprovider.NewCreateError(fmt.Errorf("getting subnets, %w", err), "SubnetResolutionFailed", "Error getting subnets")
 	}
+	if err := p.validateEFA(ctx, nodeClass, nodeClaim, instanceTypes, zonalSubnets); err != nil {
+		return ec2types.CreateFleetInstance{}, err
+	}
 
 	// Get Launch Template Configs, which may differ due to GPU or Architecture requirements
-	launchTemplateConfigs, err := p.getLaunchTemplateConfigs(ctx, nodeClass, nodeClaim, instanceTypes, zonalSubnets, capacityType, tags)
+	launchTemplateConfigs, launchTemplates, err := p.getLaunchTemplateConfigs(ctx, nodeClass, nodeClaim, instanceTypes, zonalSubnets, capacityType, tags)
 	if err != nil {
 		reason, message := awserrors.ToReasonMessage(err)
 		return ec2types.CreateFleetInstance{}, cloudprovider.NewCreateError(fmt.Errorf("getting launch template configs, %w", err), reason, fmt.Sprintf("Error getting launch template configs: %s", message))
@@ -266,8 +397,18 @@ func (p *DefaultProvider) launchInstance(
 	}
 	// Create fleet
 	createFleetInput := GetCreateFleetInput(nodeClass, capacityType, tags, launchTemplateConfigs)
+	fleetOptions := lo.FromPtr(nodeClass.Spec.FleetOptions)
 	if capacityType == karpv1.CapacityTypeSpot {
-		createFleetInput.SpotOptions = &ec2types.SpotOptionsRequest{AllocationStrategy: ec2types.SpotAllocationStrategyPriceCapacityOptimized}
+		strategy := spotAllocationStrategy(nodeClass)
+		createFleetInput.SpotOptions = &ec2types.SpotOptionsRequest{AllocationStrategy: strategy}
+		if strategy == ec2types.SpotAllocationStrategyCapacityOptimizedPrioritized {
+			prioritizeOverrides(createFleetInput.LaunchTemplateConfigs, instanceTypes, capacityType, fleetOptions)
+		}
+	} else if capacityType == karpv1.CapacityTypeOnDemand && (options.FromContext(ctx).ReservedInstanceCoverage || len(fleetOptions.InstanceTypePriority) != 0 || len(fleetOptions.ArchitecturePriority) != 0) {
+		// EC2's lowest-price strategy uses list prices, so the offerings' prices, which account for Reserved Instance
+		// coverage, are passed to CreateFleet as priorities instead
+		createFleetInput.OnDemandOptions = &ec2types.OnDemandOptionsRequest{AllocationStrategy: ec2types.FleetOnDemandAllocationStrategyPrioritized}
+		prioritizeOverrides(createFleetInput.LaunchTemplateConfigs, instanceTypes, capacityType, fleetOptions)
 	} else {
 		createFleetInput.OnDemandOptions = &ec2types.OnDemandOptionsRequest{AllocationStrategy: ec2types.FleetOnDemandAllocationStrategyLowestPrice}
 	}
@@ -290,9 +431,39 @@ func (p *DefaultProvider) launchInstance(
 	}
 	p.updateUnavailableOfferingsCache(ctx, createFleetOutput.Errors, capacityType, instanceTypes)
 	if len(createFleetOutput.Instances) == 0 || len(createFleetOutput.Instances[0].InstanceIds) == 0 {
+		if subnetIDs := exhaustedSubnetIDs(createFleetOutput.Errors); len(subnetIDs) != 0 {
+			p.subnetProvider.MarkExhausted(ctx, subnetIDs...)
+			return ec2types.CreateFleetInstance{}, fmt.Errorf("%w, %w", errInsufficientFreeAddresses, combineFleetErrors(createFleetOutput.Errors))
+		}
 		return ec2types.CreateFleetInstance{}, combineFleetErrors(createFleetOutput.Errors)
 	}
-	return createFleetOutput.Instances[0], nil
+	fleetInstance := createFleetOutput.Instances[0]
+	resolveLaunchTemplate(&fleetInstance, launchTemplates)
+	return fleetInstance, nil
+}
+
+// resolveLaunchTemplate fills in the name, ID, and version of the launch template that the instance was launched from.
+// Launch templates are requested at their latest version, which is resolved from the launch templates that were used
+// in the request if CreateFleet doesn't return it.
+func resolveLaunchTemplate(fleetInstance *ec2types.CreateFleetInstance, launchTemplates []*launchtemplate.LaunchTemplate) {
+	if fleetInstance.LaunchTemplateAndOverrides == nil {
+		return
+	}
+	spec := lo.FromPtr(fleetInstance.LaunchTemplateAndOverrides.LaunchTemplateSpecification)
+	launchTemplate, ok := lo.Find(launchTemplates, func(lt *launchtemplate.LaunchTemplate) bool {
+		return lo.FromPtr(spec.LaunchTemplateName) == lt.Name || (spec.LaunchTemplateId != nil && *spec.LaunchTemplateId == lt.ID)
+	})
+	if !ok {
+		return
+	}
+	spec.LaunchTemplateName = lo.ToPtr(launchTemplate.Name)
+	if launchTemplate.ID != "" {
+		spec.LaunchTemplateId = lo.ToPtr(launchTemplate.ID)
+	}
+	if (spec.Version == nil || strings.HasPrefix(*spec.Version, "$")) && launchTemplate.Version != 0 {
+		spec.Version = lo.ToPtr(strconv.FormatInt(launchTemplate.Version, 10))
+	}
+	fleetInstance.LaunchTemplateAndOverrides.LaunchTemplateSpecification = &spec
 }
 
 func GetCreateFleetInput(nodeClass *v1.EC2NodeClass, capacityType string, tags map[string]string, launchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest) *ec2.CreateFleetInput {
@@ -316,6 +487,62 @@ func GetCreateFleetInput(nodeClass *v1.EC2NodeClass, capacityType string, tags m
 	}
 }
 
+// spotAllocationStrategy returns the EC2NodeClass's spot allocation strategy, defaulting to price-capacity-optimized
+func spotAllocationStrategy(nodeClass *v1.EC2NodeClass) ec2types.SpotAllocationStrategy {
+	if nodeClass.Spec.FleetOptions == nil || nodeClass.Spec.FleetOptions.SpotAllocationStrategy == nil {
+		return ec2types.SpotAllocationStrategyPriceCapacityOptimized
+	}
+	return ec2types.SpotAllocationStrategy(lo.FromPtr(nodeClass.Spec.FleetOptions.SpotAllocationStrategy))
+}
+
+// prioritizeOverrides assigns each override a priority, where the highest priority is the lowest value. Overrides are
+// ordered by the position of their instance type in instanceTypePriority, with unlisted instance types last, and then
+// by the price of their offering, so that the cheapest offerings of each instance type are tried first. Prices are
+// weighted by the position of the instance type's architecture in architecturePriority.
+func prioritizeOverrides(launchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest, instanceTypes []*cloudprovider.InstanceType, capacityType string, fleetOptions v1.FleetOptions) {
+	instanceTypePriority := fleetOptions.InstanceTypePriority
+	prices := map[string]float64{}
+	for _, it := range instanceTypes {
+		weight := architectureWeight(it, fleetOptions)
+		for _, o := range it.Offerings.Available() {
+			if o.CapacityType() == capacityType {
+				prices[it.Name+"/"+o.Zone()] = o.Price * weight
+			}
+		}
+	}
+	ranks := lo.Uniq(lo.Values(prices))
+	sort.Float64s(ranks)
+	for i := range launchTemplateConfigs {
+		for j := range launchTemplateConfigs[i].Overrides {
+			override := &launchTemplateConfigs[i].Overrides[j]
+			price, ok := prices[string(override.InstanceType)+"/"+lo.FromPtr(override.AvailabilityZone)]
+			if !ok {
+				continue
+			}
+			typeRank := lo.IndexOf(instanceTypePriority, string(override.InstanceType))
+			if typeRank == -1 {
+				typeRank = len(instanceTypePriority)
+			}
+			override.Priority = lo.ToPtr(float64(typeRank*len(ranks) + lo.IndexOf(ranks, price)))
+		}
+	}
+}
+
+// architectureWeight returns the factor that an instance type's prices are multiplied by when ordering offerings, so
+// that offerings of each architecture in architecturePriority are preferred over those of the next architecture unless
+// they're more than the tolerance more expensive. Architectures which aren't listed are preferred least.
+func architectureWeight(instanceType *cloudprovider.InstanceType, fleetOptions v1.FleetOptions) float64 {
+	if len(fleetOptions.ArchitecturePriority) == 0 {
+		return 1
+	}
+	rank := lo.IndexOf(fleetOptions.ArchitecturePriority, instanceType.Requirements.Get(corev1.LabelArchStable).Any())
+	if rank == -1 {
+		rank = len(fleetOptions.ArchitecturePriority)
+	}
+	tolerance := float64(lo.FromPtrOr(fleetOptions.ArchitecturePriceTolerancePercent, v1.DefaultArchitecturePriceTolerancePercent)) / 100
+	return math.Pow(1+tolerance, float64(rank))
+}
+
 func (p *DefaultProvider) checkODFallback(nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, launchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest) error {
 	// only evaluate for on-demand fallback if the capacity type for the request is OD and both OD and spot are allowed in requirements
 	if p.getCapacityType(nodeClaim, instanceTypes) != karpv1.CapacityTypeOnDemand || !scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...).Get(karpv1.CapacityTypeLabelKey).Has(karpv1.CapacityTypeSpot) {
@@ -344,17 +571,17 @@ func (p *DefaultProvider) getLaunchTemplateConfigs(
 	zonalSubnets map[string]*subnet.Subnet,
 	capacityType string,
 	tags map[string]string,
-) ([]ec2types.FleetLaunchTemplateConfigRequest, error) {
+) ([]ec2types.FleetLaunchTemplateConfigRequest, []*launchtemplate.LaunchTemplate, error) {
 	var launchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest
 	launchTemplates, err := p.launchTemplateProvider.EnsureAll(ctx, nodeClass, nodeClaim, instanceTypes, capacityType, tags)
 	if err != nil {
-		return nil, fmt.Errorf("getting launch templates, %w", err)
+		return nil, nil, fmt.Errorf("getting launch templates, %w", err)
 	}
 	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
 	requirements[karpv1.CapacityTypeLabelKey] = scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType)
 	for _, launchTemplate := range launchTemplates {
 		launchTemplateConfig := ec2types.FleetLaunchTemplateConfigRequest{
-			Overrides: p.getOverrides(launchTemplate.InstanceTypes, zonalSubnets, requirements, launchTemplate.ImageID, launchTemplate.CapacityReservationID),
+			Overrides: p.getOverrides(launchTemplate.InstanceTypes, zonalSubnets, requirements, launchTemplate.ImageID, launchTemplate.CapacityReservationID, launchTemplate.Zone),
 			LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
 				LaunchTemplateName: aws.String(launchTemplate.Name),
 				Version:            aws.String("$Latest"),
@@ -365,9 +592,33 @@ func (p *DefaultProvider) getLaunchTemplateConfigs(
 		}
 	}
 	if len(launchTemplateConfigs) == 0 {
-		return nil, fmt.Errorf("no capacity offerings are currently available given the constraints")
+		return nil, nil, errNoCapacityOfferings
+	}
+	if options.FromContext(ctx).DeterministicOfferingSelection {
+		sortLaunchTemplateConfigs(launchTemplateConfigs)
+	}
+	return launchTemplateConfigs, launchTemplates, nil
+}
+
+// sortLaunchTemplateConfigs orders launch template configs by name and their overrides by instance type, zone and
+// subnet. Launch templates are resolved by iterating over maps, so they're otherwise returned in a random order.
+func sortLaunchTemplateConfigs(launchTemplateConfigs []ec2types.FleetLaunchTemplateConfigRequest) {
+	sort.SliceStable(launchTemplateConfigs, func(i, j int) bool {
+		return lo.FromPtr(launchTemplateConfigs[i].LaunchTemplateSpecification.LaunchTemplateName) <
+			lo.FromPtr(launchTemplateConfigs[j].LaunchTemplateSpecification.LaunchTemplateName)
+	})
+	for i := range launchTemplateConfigs {
+		overrides := launchTemplateConfigs[i].Overrides
+		sort.SliceStable(overrides, func(a, b int) bool {
+			if overrides[a].InstanceType != overrides[b].InstanceType {
+				return overrides[a].InstanceType < overrides[b].InstanceType
+			}
+			if lo.FromPtr(overrides[a].AvailabilityZone) != lo.FromPtr(overrides[b].AvailabilityZone) {
+				return lo.FromPtr(overrides[a].AvailabilityZone) < lo.FromPtr(overrides[b].AvailabilityZone)
+			}
+			return lo.FromPtr(overrides[a].SubnetId) < lo.FromPtr(overrides[b].SubnetId)
+		})
 	}
-	return launchTemplateConfigs, nil
 }
 
 // getOverrides creates and returns launch template overrides for the cross product of InstanceTypes and subnets (with subnets being constrained by
@@ -376,7 +627,7 @@ func (p *DefaultProvider) getOverrides(
 	instanceTypes []*cloudprovider.InstanceType,
 	zonalSubnets map[string]*subnet.Subnet,
 	reqs scheduling.Requirements,
-	image, capacityReservationID string,
+	image, capacityReservationID, zone string,
 ) []ec2types.FleetLaunchTemplateOverridesRequest {
 	// Unwrap all the offerings to a flat slice that includes a pointer
 	// to the parent instance type name
@@ -409,6 +660,11 @@ func (p *DefaultProvider) getOverrides(
 		if !ok {
 			continue
 		}
+		// If the launch template can only launch into one zone, such as when it has additional network interfaces, we
+		// only want to include the offerings in that zone.
+		if zone != "" && subnet.Zone != zone {
+			continue
+		}
 		overrides = append(overrides, ec2types.FleetLaunchTemplateOverridesRequest{
 			InstanceType: offering.parentInstanceTypeName,
 			SubnetId:     lo.ToPtr(subnet.ID),
@@ -629,6 +885,16 @@ func instancesFromOutput(ctx context.Context, out *ec2.DescribeInstancesOutput)
 	return lo.Map(instances, func(i ec2types.Instance, _ int) *Instance { return NewInstance(ctx, i) }), nil
 }
 
+// exhaustedSubnetIDs returns the subnets which fleet couldn't launch into because they ran out of free IP addresses
+func exhaustedSubnetIDs(fleetErrs []ec2types.CreateFleetError) []string {
+	return lo.Uniq(lo.FilterMap(fleetErrs, func(err ec2types.CreateFleetError, _ int) (string, bool) {
+		if !awserrors.IsInsufficientFreeAddresses(err) || err.LaunchTemplateAndOverrides == nil || err.LaunchTemplateAndOverrides.Overrides == nil {
+			return "", false
+		}
+		return lo.FromPtr(err.LaunchTemplateAndOverrides.Overrides.SubnetId), err.LaunchTemplateAndOverrides.Overrides.SubnetId != nil
+	}))
+}
+
 func combineFleetErrors(fleetErrs []ec2types.CreateFleetError) (errs error) {
 	unique := sets.NewString()
 	for _, err := range fleetErrs {
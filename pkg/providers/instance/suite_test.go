@@ -16,6 +16,7 @@ package instance_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"github.com/awslabs/operatorpkg/object"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
@@ -68,7 +70,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	awsEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
 })
 
 var _ = AfterSuite(func() {
@@ -182,6 +184,52 @@ var _ = Describe("InstanceProvider", func() {
 		// Ensure we marked the reservation as unavailable after encountering the error
 		Expect(awsEnv.CapacityReservationProvider.GetAvailableInstanceCount(targetReservationID)).To(Equal(0))
 	})
+	It("should retry the launch in another subnet when a subnet runs out of free IP addresses", func() {
+		nodeClass.Status.Subnets = append(nodeClass.Status.Subnets, v1.Subnet{ID: "subnet-test5", Zone: "test-zone-1a", ZoneID: "tstz1-1a"})
+		nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+				Key:      corev1.LabelTopologyZone,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{"test-zone-1a"},
+			},
+		})
+		awsEnv.EC2API.InsufficientFreeAddressesSubnets.Set([]string{"subnet-test1"})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.xlarge" })
+
+		instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instance.SubnetID).To(Equal("subnet-test5"))
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(2))
+		// The exhausted subnet isn't launched into until it expires from the cache
+		_, found := awsEnv.ExhaustedSubnetsCache.Get("subnet-test1")
+		Expect(found).To(BeTrue())
+	})
+	It("should return an ICE error when every subnet runs out of free IP addresses", func() {
+		awsEnv.EC2API.InsufficientFreeAddressesSubnets.Set([]string{"subnet-test1", "subnet-test2", "subnet-test3"})
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "m5.xlarge" })
+
+		instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+		Expect(instance).To(BeNil())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+	})
+	It("should launch without modifying network interfaces when source/destination check is disabled", func() {
+		// Source/destination check is disabled by a controller once EC2 reports the instance's network interfaces
+		nodeClass.Spec.SourceDestCheck = lo.ToPtr(false)
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instance).ToNot(BeNil())
+		Expect(awsEnv.EC2API.ModifyNetworkInterfaceBehavior.Calls()).To(Equal(0))
+	})
 	It("should filter compatible reserved offerings such that only one offering per capacity pool is included in the CreateFleet request", func() {
 		const targetReservationID = "cr-m5.large-1a-2"
 		awsEnv.EC2API.DescribeCapacityReservationsOutput.Set(&ec2.DescribeCapacityReservationsOutput{
@@ -348,4 +396,53 @@ var _ = Describe("InstanceProvider", func() {
 		retrievedIDs := sets.New[string](lo.Map(instances, func(i *instance.Instance, _ int) string { return i.ID })...)
 		Expect(ids.Equal(retrievedIDs)).To(BeTrue())
 	})
+	Context("EFA Validation", func() {
+		var instanceTypes []*corecloudprovider.InstanceType
+		BeforeEach(func() {
+			nodeClaim.Spec.Resources.Requests = corev1.ResourceList{v1.ResourceEFA: resource.MustParse("1")}
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			var err error
+			instanceTypes, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			instanceTypes = lo.Filter(instanceTypes, func(i *corecloudprovider.InstanceType, _ int) bool { return i.Name == "g4dn.8xlarge" })
+		})
+		It("should launch when the security groups allow traffic between EFA interfaces", func() {
+			instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance.EFAEnabled).To(BeTrue())
+		})
+		It("should fail to launch when the security groups don't allow traffic between EFA interfaces", func() {
+			awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{{
+				GroupId: aws.String("sg-test1"),
+				Tags:    []ec2types.Tag{{Key: aws.String("foo"), Value: aws.String("bar")}},
+				// A CIDR rule doesn't match EFA traffic, which isn't IP based
+				IpPermissions:       []ec2types.IpPermission{{IpProtocol: aws.String("-1"), IpRanges: []ec2types.IpRange{{CidrIp: aws.String("10.0.0.0/16")}}}},
+				IpPermissionsEgress: []ec2types.IpPermission{{IpProtocol: aws.String("-1"), UserIdGroupPairs: []ec2types.UserIdGroupPair{{GroupId: aws.String("sg-test1")}}}},
+			}}})
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+			createErr := &corecloudprovider.CreateError{}
+			Expect(errors.As(err, &createErr)).To(BeTrue())
+			Expect(createErr.ConditionReason).To(Equal("EFASecurityGroupRulesMissing"))
+			Expect(createErr.ConditionMessage).To(ContainSubstring("inbound"))
+			Expect(createErr.ConditionMessage).ToNot(ContainSubstring("outbound"))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should fail to launch when there's no subnet in a zone offering the EFA instance types", func() {
+			nodeClass.Status.Subnets = []v1.Subnet{{ID: "subnet-test3", Zone: "test-zone-1c", ZoneID: "tstz1-1c"}}
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+			createErr := &corecloudprovider.CreateError{}
+			Expect(errors.As(err, &createErr)).To(BeTrue())
+			Expect(createErr.ConditionReason).To(Equal("EFAZoneUnsupported"))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should not validate instances which don't request EFA", func() {
+			nodeClaim.Spec.Resources.Requests = corev1.ResourceList{}
+			awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{{
+				GroupId: aws.String("sg-test1"),
+				Tags:    []ec2types.Tag{{Key: aws.String("foo"), Value: aws.String("bar")}},
+			}}})
+			_, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
 })
@@ -25,6 +25,11 @@ import (
 	"sigs.k8s.io/karpenter/pkg/operator/options"
 )
 
+const (
+	launchTemplateIDTagKey      = "aws:ec2launchtemplate:id"
+	launchTemplateVersionTagKey = "aws:ec2launchtemplate:version"
+)
+
 // Instance is an internal data representation of either an ec2.Instance or an ec2.FleetInstance
 // It contains all the common data that is needed to inject into the Machine from either of these responses
 type Instance struct {
@@ -40,9 +45,31 @@ type Instance struct {
 	SubnetID              string
 	Tags                  map[string]string
 	EFAEnabled            bool
+	// LaunchTemplateName, LaunchTemplateID, and LaunchTemplateVersion identify the launch template that the instance
+	// was launched from. The name is only known when the instance is launched.
+	LaunchTemplateName    string
+	LaunchTemplateID      string
+	LaunchTemplateVersion string
+	// VCPUs is the number of vCPUs of the instance from its CPU options, which is 0 if they aren't known
+	VCPUs int32
+	// PrimaryNetworkInterfaceID is the network interface at device index 0 of the first network card, which is empty if
+	// the network interfaces of the instance aren't known. When it's known, SecurityGroupIDs are the security groups of
+	// the primary network interface rather than of every network interface of the instance.
+	PrimaryNetworkInterfaceID string
+	// SourceDestCheckNetworkInterfaceIDs are the network interfaces of the instance which have source/destination
+	// checking enabled
+	SourceDestCheckNetworkInterfaceIDs []string
 }
 
 func NewInstance(ctx context.Context, out ec2types.Instance) *Instance {
+	tags := lo.SliceToMap(out.Tags, func(t ec2types.Tag) (string, string) { return lo.FromPtr(t.Key), lo.FromPtr(t.Value) })
+	securityGroups := out.SecurityGroups
+	primaryNetworkInterface, ok := lo.Find(out.NetworkInterfaces, func(ni ec2types.InstanceNetworkInterface) bool {
+		return ni.Attachment != nil && lo.FromPtr(ni.Attachment.DeviceIndex) == 0 && lo.FromPtr(ni.Attachment.NetworkCardIndex) == 0
+	})
+	if ok {
+		securityGroups = primaryNetworkInterface.Groups
+	}
 	return &Instance{
 		LaunchTime: lo.FromPtr(out.LaunchTime),
 		State:      out.State.Name,
@@ -61,18 +88,33 @@ func NewInstance(ctx context.Context, out ec2types.Instance) *Instance {
 			lo.FromPtr(out.CapacityReservationId),
 			"",
 		),
-		SecurityGroupIDs: lo.Map(out.SecurityGroups, func(securitygroup ec2types.GroupIdentifier, _ int) string {
+		SecurityGroupIDs: lo.Map(securityGroups, func(securitygroup ec2types.GroupIdentifier, _ int) string {
 			return lo.FromPtr(securitygroup.GroupId)
 		}),
 		SubnetID: lo.FromPtr(out.SubnetId),
-		Tags:     lo.SliceToMap(out.Tags, func(t ec2types.Tag) (string, string) { return lo.FromPtr(t.Key), lo.FromPtr(t.Value) }),
+		Tags:     tags,
 		EFAEnabled: lo.ContainsBy(out.NetworkInterfaces, func(item ec2types.InstanceNetworkInterface) bool {
 			return item.InterfaceType != nil && *item.InterfaceType == string(ec2types.NetworkInterfaceTypeEfa)
 		}),
+		// EC2 tags instances with the launch template that they were launched from
+		LaunchTemplateID:          tags[launchTemplateIDTagKey],
+		LaunchTemplateVersion:     tags[launchTemplateVersionTagKey],
+		VCPUs:                     vcpus(out.CpuOptions),
+		PrimaryNetworkInterfaceID: lo.FromPtr(primaryNetworkInterface.NetworkInterfaceId),
+		SourceDestCheckNetworkInterfaceIDs: lo.FilterMap(out.NetworkInterfaces, func(ni ec2types.InstanceNetworkInterface, _ int) (string, bool) {
+			return lo.FromPtr(ni.NetworkInterfaceId), lo.FromPtrOr(ni.SourceDestCheck, true)
+		}),
 	}
 
 }
 
+func vcpus(cpuOptions *ec2types.CpuOptions) int32 {
+	if cpuOptions == nil {
+		return 0
+	}
+	return lo.FromPtr(cpuOptions.CoreCount) * lo.FromPtr(cpuOptions.ThreadsPerCore)
+}
+
 func NewInstanceFromFleet(
 	out ec2types.CreateFleetInstance,
 	tags map[string]string,
@@ -80,6 +122,7 @@ func NewInstanceFromFleet(
 	capacityReservationID string,
 	efaEnabled bool,
 ) *Instance {
+	launchTemplate := lo.FromPtr(out.LaunchTemplateAndOverrides.LaunchTemplateSpecification)
 	return &Instance{
 		LaunchTime:            time.Now(), // estimate the launch time since we just launched
 		State:                 ec2types.InstanceStateNamePending,
@@ -92,5 +135,8 @@ func NewInstanceFromFleet(
 		SubnetID:              lo.FromPtr(out.LaunchTemplateAndOverrides.Overrides.SubnetId),
 		Tags:                  tags,
 		EFAEnabled:            efaEnabled,
+		LaunchTemplateName:    lo.FromPtr(launchTemplate.LaunchTemplateName),
+		LaunchTemplateID:      lo.FromPtr(launchTemplate.LaunchTemplateId),
+		LaunchTemplateVersion: lo.FromPtr(launchTemplate.Version),
 	}
 }
@@ -27,6 +27,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
@@ -64,6 +65,12 @@ func (p *DefaultProvider) Create(ctx context.Context, m ResourceOwner) (string,
 	tags := map[string]string{}
 	if len(m.InstanceProfileTags(options.FromContext(ctx).ClusterName)) != 0 {
 		tags = lo.Assign(m.InstanceProfileTags(options.FromContext(ctx).ClusterName), map[string]string{corev1.LabelTopologyRegion: p.region})
+		tags = lo.MapKeys(tags, func(_ string, key string) string {
+			if lo.Contains(v1.OwnershipTagKeys, key) {
+				return options.FromContext(ctx).TagKey(key)
+			}
+			return key
+		})
 	}
 	// An instance profile exists for this NodeClass
 	if _, ok := p.cache.Get(string(m.GetUID())); ok {
@@ -0,0 +1,115 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancetype
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// ResolverMiddleware wraps a Resolver, adjusting the instance types it resolves. A middleware that makes its
+// adjustments based on the NodeClass must include those fields in its CacheKey, in addition to the wrapped resolver's
+// key, since resolved instance types are cached until the key changes.
+type ResolverMiddleware func(next Resolver) Resolver
+
+// InstanceTypeMutator adjusts a resolved instance type in place, e.g. to change its overhead, capacity, or
+// requirements. The instance type is never nil.
+type InstanceTypeMutator func(ctx context.Context, it *cloudprovider.InstanceType, info ec2types.InstanceTypeInfo, nodeClass *v1.EC2NodeClass)
+
+type namedResolverMiddleware struct {
+	name       string
+	middleware ResolverMiddleware
+}
+
+var (
+	muResolverMiddlewares sync.RWMutex
+	resolverMiddlewares   []namedResolverMiddleware
+)
+
+// RegisterResolverMiddleware adds a middleware to the chain returned by NewRegisteredResolver. Like pricing providers,
+// middlewares are intended to be registered from the init function of a package imported by a controller binary built
+// around this module, and are applied in the order they're registered. RegisterResolverMiddleware panics if the name
+// has already been registered.
+func RegisterResolverMiddleware(name string, middleware ResolverMiddleware) {
+	muResolverMiddlewares.Lock()
+	defer muResolverMiddlewares.Unlock()
+	if middleware == nil {
+		panic(fmt.Sprintf("registering instance type resolver middleware %q, middleware is nil", name))
+	}
+	if lo.ContainsBy(resolverMiddlewares, func(m namedResolverMiddleware) bool { return m.name == name }) {
+		panic(fmt.Sprintf("registering instance type resolver middleware %q, already registered", name))
+	}
+	resolverMiddlewares = append(resolverMiddlewares, namedResolverMiddleware{name: name, middleware: middleware})
+}
+
+// RegisteredResolverMiddlewares returns the names of the registered middlewares, in the order they're applied
+func RegisteredResolverMiddlewares() []string {
+	muResolverMiddlewares.RLock()
+	defer muResolverMiddlewares.RUnlock()
+	return lo.Map(resolverMiddlewares, func(m namedResolverMiddleware, _ int) string { return m.name })
+}
+
+// NewRegisteredResolver wraps the resolver with the registered middlewares
+func NewRegisteredResolver(resolver Resolver) Resolver {
+	muResolverMiddlewares.RLock()
+	defer muResolverMiddlewares.RUnlock()
+	return NewResolverChain(resolver, lo.Map(resolverMiddlewares, func(m namedResolverMiddleware, _ int) ResolverMiddleware { return m.middleware })...)
+}
+
+// NewResolverChain wraps the resolver with the middlewares. The first middleware wraps the resolver directly, so each
+// middleware sees the instance types produced by the ones before it.
+func NewResolverChain(resolver Resolver, middlewares ...ResolverMiddleware) Resolver {
+	for _, middleware := range middlewares {
+		resolver = middleware(resolver)
+	}
+	return resolver
+}
+
+// MutatingMiddleware returns a middleware which applies the mutator to each instance type resolved by the wrapped
+// resolver. The cache key function returns the NodeClass fields the mutator depends on, and may be nil if it doesn't
+// depend on the NodeClass.
+func MutatingMiddleware(cacheKey func(*v1.EC2NodeClass) string, mutate InstanceTypeMutator) ResolverMiddleware {
+	return func(next Resolver) Resolver {
+		return &mutatingResolver{next: next, cacheKey: cacheKey, mutate: mutate}
+	}
+}
+
+type mutatingResolver struct {
+	next     Resolver
+	cacheKey func(*v1.EC2NodeClass) string
+	mutate   InstanceTypeMutator
+}
+
+func (m *mutatingResolver) CacheKey(nodeClass *v1.EC2NodeClass) string {
+	if m.cacheKey == nil {
+		return m.next.CacheKey(nodeClass)
+	}
+	return fmt.Sprintf("%s-%s", m.next.CacheKey(nodeClass), m.cacheKey(nodeClass))
+}
+
+func (m *mutatingResolver) Resolve(ctx context.Context, info ec2types.InstanceTypeInfo, zones []string, zonesToZoneIDs map[string]string, nodeClass *v1.EC2NodeClass) *cloudprovider.InstanceType {
+	it := m.next.Resolve(ctx, info, zones, zonesToZoneIDs, nodeClass)
+	if it != nil {
+		m.mutate(ctx, it, info, nodeClass)
+	}
+	return it
+}
@@ -17,6 +17,7 @@ package instancetype
 import (
 	"context"
 	"fmt"
+	"maps"
 	"sync"
 	"sync/atomic"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype/offering"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/reservedinstance"
 
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/patrickmn/go-cache"
@@ -36,28 +38,49 @@ import (
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/samber/lo"
+	"go.uber.org/multierr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 )
 
+const (
+	// maxConcurrentDescribeRequests bounds the number of partitions of instance types and offerings that are
+	// paginated at EC2 concurrently
+	maxConcurrentDescribeRequests = 10
+)
+
+// supportedArchitectures partitions the instance types described at EC2 so that each architecture can be paginated
+// concurrently
+var supportedArchitectures = []string{"x86_64", "arm64"}
+
 type Provider interface {
 	List(context.Context, *v1.EC2NodeClass) ([]*cloudprovider.InstanceType, error)
+	IncompatibleInstanceTypes(context.Context, *v1.EC2NodeClass) (map[string]string, error)
 }
 
 type DefaultProvider struct {
 	ec2api                sdk.EC2API
 	subnetProvider        subnet.Provider
 	instanceTypesResolver Resolver
+	// amiHashStore holds the hash of each EC2NodeClass's AMIs, which the cached instance types are keyed by
+	amiHashStore *amifamily.HashStore
+	// retryer is shared between the concurrent describe requests so that throttling from any one of them slows down
+	// the rest. It's built from the client's retryer on the first request.
+	retryer     aws.Retryer
+	retryerOnce sync.Once
 
 	// Values stored *before* considering insufficient capacity errors from the unavailableOfferings cache.
 	// Fully initialized Instance Types are also cached based on the set of all instance types, zones, unavailableOfferings cache,
@@ -71,9 +94,16 @@ type DefaultProvider struct {
 	instanceTypesOfferings   map[string]sets.Set[string]
 	allZones                 sets.Set[string]
 
-	instanceTypesCache      *cache.Cache
-	discoveredCapacityCache *cache.Cache
-	cm                      *pretty.ChangeMonitor
+	instanceTypesCache *awscache.LRU
+	muNodeClassKeys    sync.Mutex
+	// nodeClassKeys are the instance types cache keys which have been resolved for each EC2NodeClass, so that they can
+	// be dropped when the EC2NodeClass is invalidated
+	nodeClassKeys map[string]sets.Set[string]
+	// incompatibleInstanceTypes are the instance types which were excluded when resolving each instance types cache
+	// key, because the AMI they'd be launched with doesn't support them, mapped to the reason they were excluded
+	incompatibleInstanceTypes map[string]map[string]string
+	discoveredCapacityCache   *cache.Cache
+	cm                        *pretty.ChangeMonitor
 	// instanceTypesSeqNum is a monotonically increasing change counter used to avoid the expensive hashing operation on instance types
 	instanceTypesSeqNum uint64
 	// instanceTypesOfferingsSeqNum is a monotonically increasing change counter used to avoid the expensive hashing operation on instance types
@@ -83,50 +113,137 @@ type DefaultProvider struct {
 }
 
 func NewDefaultProvider(
-	instanceTypesCache *cache.Cache,
+	instanceTypesCache *awscache.LRU,
 	offeringCache *cache.Cache,
 	discoveredCapacityCache *cache.Cache,
 	ec2api sdk.EC2API,
 	subnetProvider subnet.Provider,
 	pricingProvider pricing.Provider,
 	capacityReservationProvider capacityreservation.Provider,
+	reservedInstanceProvider reservedinstance.Provider,
 	unavailableOfferingsCache *awscache.UnavailableOfferings,
 	instanceTypesResolver Resolver,
+	amiHashStore *amifamily.HashStore,
+	invalidations *awscache.InvalidationBus,
 ) *DefaultProvider {
-	return &DefaultProvider{
-		ec2api:                  ec2api,
-		subnetProvider:          subnetProvider,
-		instanceTypesInfo:       []ec2types.InstanceTypeInfo{},
-		instanceTypesOfferings:  map[string]sets.Set[string]{},
-		instanceTypesResolver:   instanceTypesResolver,
-		instanceTypesCache:      instanceTypesCache,
-		discoveredCapacityCache: discoveredCapacityCache,
-		cm:                      pretty.NewChangeMonitor(),
-		instanceTypesSeqNum:     0,
+	p := &DefaultProvider{
+		ec2api:                    ec2api,
+		subnetProvider:            subnetProvider,
+		instanceTypesInfo:         []ec2types.InstanceTypeInfo{},
+		instanceTypesOfferings:    map[string]sets.Set[string]{},
+		instanceTypesResolver:     instanceTypesResolver,
+		amiHashStore:              amiHashStore,
+		instanceTypesCache:        instanceTypesCache,
+		nodeClassKeys:             map[string]sets.Set[string]{},
+		incompatibleInstanceTypes: map[string]map[string]string{},
+		discoveredCapacityCache:   discoveredCapacityCache,
+		cm:                        pretty.NewChangeMonitor(),
+		instanceTypesSeqNum:       0,
 		offeringProvider: offering.NewDefaultProvider(
 			pricingProvider,
 			capacityReservationProvider,
+			reservedInstanceProvider,
 			unavailableOfferingsCache,
 			offeringCache,
+			invalidations,
 		),
 	}
+	invalidations.Subscribe(p.invalidate)
+	return p
+}
+
+// invalidate drops the instance types resolved for an invalidated EC2NodeClass. Keys which are also in use by other
+// EC2NodeClasses with the same configuration are retained.
+func (p *DefaultProvider) invalidate(invalidation awscache.Invalidation) {
+	if invalidation.NodeClass == "" {
+		return
+	}
+	p.muNodeClassKeys.Lock()
+	defer p.muNodeClassKeys.Unlock()
+	keys, ok := p.nodeClassKeys[invalidation.NodeClass]
+	if !ok {
+		return
+	}
+	delete(p.nodeClassKeys, invalidation.NodeClass)
+	for _, otherKeys := range p.nodeClassKeys {
+		keys = keys.Difference(otherKeys)
+	}
+	for key := range keys {
+		p.instanceTypesCache.Delete(key)
+		delete(p.incompatibleInstanceTypes, key)
+	}
+}
+
+func (p *DefaultProvider) trackNodeClassKey(nodeClass *v1.EC2NodeClass, key string) {
+	p.muNodeClassKeys.Lock()
+	defer p.muNodeClassKeys.Unlock()
+	keys, ok := p.nodeClassKeys[nodeClass.Name]
+	if !ok {
+		keys = sets.New[string]()
+		p.nodeClassKeys[nodeClass.Name] = keys
+	}
+	if keys.Has(key) {
+		return
+	}
+	// Forget the keys which have since been evicted, so that the tracked keys don't outgrow the cache
+	for k := range keys {
+		if !p.instanceTypesCache.Contains(k) {
+			keys.Delete(k)
+		}
+	}
+	keys.Insert(key)
 }
 
-//nolint:gocyclo
 func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass) ([]*cloudprovider.InstanceType, error) {
 	p.muInstanceTypesInfo.RLock()
 	p.muInstanceTypesOfferings.RLock()
 	defer p.muInstanceTypesInfo.RUnlock()
 	defer p.muInstanceTypesOfferings.RUnlock()
 
+	instanceTypes, _, err := p.resolve(ctx, nodeClass)
+	if err != nil {
+		return nil, err
+	}
+	// Offerings aren't cached along with the rest of the instance type info because reserved offerings need to have up to
+	// date capacity information. Rather than incurring a cache miss each time an instance is launched into a reserved
+	// offering (or terminated), offerings are injected to the cached instance types on each call. Note that on-demand and
+	// spot offerings are still cached - only reserved offerings are generated each time.
+	return p.offeringProvider.InjectOfferings(
+		ctx,
+		instanceTypes,
+		nodeClass,
+		p.allZones,
+	), nil
+}
+
+// IncompatibleInstanceTypes returns the instance types which are excluded from the EC2NodeClass because the AMI they'd
+// be launched with doesn't support them, mapped to the reason they're excluded
+func (p *DefaultProvider) IncompatibleInstanceTypes(ctx context.Context, nodeClass *v1.EC2NodeClass) (map[string]string, error) {
+	p.muInstanceTypesInfo.RLock()
+	p.muInstanceTypesOfferings.RLock()
+	defer p.muInstanceTypesInfo.RUnlock()
+	defer p.muInstanceTypesOfferings.RUnlock()
+
+	_, key, err := p.resolve(ctx, nodeClass)
+	if err != nil {
+		return nil, err
+	}
+	p.muNodeClassKeys.Lock()
+	defer p.muNodeClassKeys.Unlock()
+	return maps.Clone(p.incompatibleInstanceTypes[key]), nil
+}
+
+// resolve returns the instance types resolved for the EC2NodeClass, without offerings, along with the cache key they're
+// stored under. The caller must hold the instance types and offerings read locks.
+func (p *DefaultProvider) resolve(ctx context.Context, nodeClass *v1.EC2NodeClass) ([]*cloudprovider.InstanceType, string, error) {
 	if len(p.instanceTypesInfo) == 0 {
-		return nil, fmt.Errorf("no instance types found")
+		return nil, "", fmt.Errorf("no instance types found")
 	}
 	if len(p.instanceTypesOfferings) == 0 {
-		return nil, fmt.Errorf("no instance types offerings found")
+		return nil, "", fmt.Errorf("no instance types offerings found")
 	}
 	if len(nodeClass.Status.Subnets) == 0 {
-		return nil, fmt.Errorf("no subnets found")
+		return nil, "", fmt.Errorf("no subnets found")
 	}
 
 	subnetZones := sets.New(lo.Map(nodeClass.Status.Subnets, func(s v1.Subnet, _ int) string {
@@ -135,8 +252,8 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass)
 
 	// Compute fully initialized instance types hash key
 	subnetZonesHash, _ := hashstructure.Hash(subnetZones, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
-	// Compute hash key against node class AMIs (used to force cache rebuild when AMIs change)
-	amiHash, _ := hashstructure.Hash(nodeClass.Status.AMIs, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	// Hash key against node class AMIs (used to force cache rebuild when AMIs change)
+	amiHash := p.amiHashStore.Hash(nodeClass)
 	key := fmt.Sprintf("%d-%d-%016x-%016x-%016x",
 		p.instanceTypesSeqNum,
 		p.instanceTypesOfferingsSeqNum,
@@ -150,31 +267,39 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass)
 		// so that modifications to the ordering of the data don't affect the original
 		instanceTypes = item.([]*cloudprovider.InstanceType)
 	} else {
-		instanceTypes = p.resolveInstanceTypes(ctx, nodeClass, amiHash)
+		var incompatible map[string]string
+		instanceTypes, incompatible = p.resolveInstanceTypes(ctx, nodeClass, amiHash)
 		p.instanceTypesCache.SetDefault(key, instanceTypes)
+		p.storeIncompatibleInstanceTypes(key, incompatible)
 	}
-	// Offerings aren't cached along with the rest of the instance type info because reserved offerings need to have up to
-	// date capacity information. Rather than incurring a cache miss each time an instance is launched into a reserved
-	// offering (or terminated), offerings are injected to the cached instance types on each call. Note that on-demand and
-	// spot offerings are still cached - only reserved offerings are generated each time.
-	return p.offeringProvider.InjectOfferings(
-		ctx,
-		instanceTypes,
-		nodeClass,
-		p.allZones,
-	), nil
+	p.trackNodeClassKey(nodeClass, key)
+	return instanceTypes, key, nil
 }
 
+// resolveInstanceTypes resolves the instance types for the EC2NodeClass. Instance types which the AMI they're mapped to
+// or the EC2NodeClass's CPU and boot options don't support are excluded rather than being left for EC2 to reject at
+// launch, and are returned along with the reason they were excluded.
 func (p *DefaultProvider) resolveInstanceTypes(
 	ctx context.Context,
 	nodeClass *v1.EC2NodeClass,
 	amiHash uint64,
-) []*cloudprovider.InstanceType {
+) ([]*cloudprovider.InstanceType, map[string]string) {
 	zonesToZoneIDs := lo.SliceToMap(nodeClass.Status.Subnets, func(s v1.Subnet) (string, string) {
 		return s.Zone, s.ZoneID
 	})
-	return lo.Map(p.instanceTypesInfo, func(info ec2types.InstanceTypeInfo, _ int) *cloudprovider.InstanceType {
+	incompatible := map[string]string{}
+	return lo.FilterMap(p.instanceTypesInfo, func(info ec2types.InstanceTypeInfo, _ int) (*cloudprovider.InstanceType, bool) {
+		if reason := nodeClassIncompatibilityReason(nodeClass, info); reason != "" {
+			incompatible[string(info.InstanceType)] = reason
+			return nil, false
+		}
 		it := p.instanceTypesResolver.Resolve(ctx, info, p.instanceTypesOfferings[string(info.InstanceType)].UnsortedList(), zonesToZoneIDs, nodeClass)
+		if ami, ok := amifamily.MappedAMI(it, nodeClass.Status.AMIs); ok {
+			if reason := amifamily.IncompatibilityReason(ami, info); reason != "" {
+				incompatible[it.Name] = reason
+				return nil, false
+			}
+		}
 		if cached, ok := p.discoveredCapacityCache.Get(fmt.Sprintf("%s-%016x", it.Name, amiHash)); ok {
 			it.Capacity[corev1.ResourceMemory] = cached.(resource.Quantity)
 		}
@@ -184,8 +309,36 @@ func (p *DefaultProvider) resolveInstanceTypes(
 		InstanceTypeMemory.Set(float64(lo.FromPtr(info.MemoryInfo.SizeInMiB)*1024*1024), map[string]string{
 			instanceTypeLabel: string(info.InstanceType),
 		})
-		return it
-	})
+		return it, true
+	}), incompatible
+}
+
+// nodeClassIncompatibilityReason returns why instances of the instance type can't be launched with the EC2NodeClass's
+// CPU and boot options, or an empty string if they can be
+func nodeClassIncompatibilityReason(nodeClass *v1.EC2NodeClass, info ec2types.InstanceTypeInfo) string {
+	if threadsPerCore := nodeClass.ThreadsPerCore(); threadsPerCore != nil && !lo.Contains(info.VCpuInfo.ValidThreadsPerCore, *threadsPerCore) {
+		return fmt.Sprintf("doesn't support setting threadsPerCore to %d", *threadsPerCore)
+	}
+	if nodeClass.RequiresUEFI() && !lo.Contains(info.SupportedBootModes, ec2types.BootModeTypeUefi) {
+		return "doesn't support the uefi boot mode"
+	}
+	if nodeClass.RequiresNitroTPM() && info.NitroTpmSupport != ec2types.NitroTpmSupportSupported {
+		return "doesn't support NitroTPM"
+	}
+	return ""
+}
+
+// storeIncompatibleInstanceTypes records the instance types which were excluded when resolving the cache key
+func (p *DefaultProvider) storeIncompatibleInstanceTypes(key string, incompatible map[string]string) {
+	p.muNodeClassKeys.Lock()
+	defer p.muNodeClassKeys.Unlock()
+	// Forget the keys which have since been evicted, so that the recorded exclusions don't outgrow the cache
+	for k := range p.incompatibleInstanceTypes {
+		if !p.instanceTypesCache.Contains(k) {
+			delete(p.incompatibleInstanceTypes, k)
+		}
+	}
+	p.incompatibleInstanceTypes[key] = incompatible
 }
 
 func (p *DefaultProvider) UpdateInstanceTypes(ctx context.Context) error {
@@ -195,26 +348,19 @@ func (p *DefaultProvider) UpdateInstanceTypes(ctx context.Context) error {
 	p.muInstanceTypesInfo.Lock()
 	defer p.muInstanceTypesInfo.Unlock()
 
-	var instanceTypes []ec2types.InstanceTypeInfo
-	paginator := ec2.NewDescribeInstanceTypesPaginator(p.ec2api, &ec2.DescribeInstanceTypesInput{
-		Filters: []ec2types.Filter{
-			{
-				Name:   aws.String("supported-virtualization-type"),
-				Values: []string{"hvm"},
-			},
-			{
-				Name:   aws.String("processor-info.supported-architecture"),
-				Values: []string{"x86_64", "arm64"},
-			},
-		},
+	partitions := make([][]ec2types.InstanceTypeInfo, len(supportedArchitectures))
+	errs := make([]error, len(supportedArchitectures))
+	workqueue.ParallelizeUntil(ctx, maxConcurrentDescribeRequests, len(supportedArchitectures), func(i int) {
+		partitions[i], errs[i] = p.describeInstanceTypes(ctx, supportedArchitectures[i])
 	})
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
-		if err != nil {
-			return fmt.Errorf("describing instance types, %w", err)
-		}
-		instanceTypes = append(instanceTypes, page.InstanceTypes...)
+	if err := multierr.Combine(errs...); err != nil {
+		return fmt.Errorf("describing instance types, %w", err)
 	}
+	// Instance types which support more than one architecture are returned for each of them. The partitions are
+	// flattened in the order of the architectures, so the order in which they complete doesn't register as a change.
+	instanceTypes := lo.UniqBy(lo.Flatten(partitions), func(info ec2types.InstanceTypeInfo) ec2types.InstanceType {
+		return info.InstanceType
+	})
 
 	if p.cm.HasChanged("instance-types", instanceTypes) {
 		// Only update instanceTypesSeqNun with the instance types have been changed
@@ -226,6 +372,13 @@ func (p *DefaultProvider) UpdateInstanceTypes(ctx context.Context) error {
 	return nil
 }
 
+// InstanceTypeNames returns the names of the instance types discovered by the last successful refresh
+func (p *DefaultProvider) InstanceTypeNames() sets.Set[string] {
+	p.muInstanceTypesInfo.RLock()
+	defer p.muInstanceTypesInfo.RUnlock()
+	return sets.New(lo.Map(p.instanceTypesInfo, func(info ec2types.InstanceTypeInfo, _ int) string { return string(info.InstanceType) })...)
+}
+
 func (p *DefaultProvider) UpdateInstanceTypeOfferings(ctx context.Context) error {
 	// DO NOT REMOVE THIS LOCK ----------------------------------------------------------------------------
 	// We lock here so that multiple callers to GetInstanceTypes do not result in cache misses and multiple
@@ -235,25 +388,37 @@ func (p *DefaultProvider) UpdateInstanceTypeOfferings(ctx context.Context) error
 	p.muInstanceTypesOfferings.Lock()
 	defer p.muInstanceTypesOfferings.Unlock()
 
-	// Get offerings from EC2
+	// Get offerings from EC2, paginating the offerings for each zone concurrently
+	filterSets, err := p.offeringFilterSets(ctx)
+	if err != nil {
+		return err
+	}
+	var mu sync.Mutex
 	instanceTypeOfferings := map[string]sets.Set[string]{}
-
-	paginator := ec2.NewDescribeInstanceTypeOfferingsPaginator(p.ec2api, &ec2.DescribeInstanceTypeOfferingsInput{
-		LocationType: ec2types.LocationTypeAvailabilityZone,
-	})
-
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
-		if err != nil {
-			return fmt.Errorf("describing instance type zone offerings, %w", err)
-		}
-
-		for _, offering := range page.InstanceTypeOfferings {
-			if _, ok := instanceTypeOfferings[string(offering.InstanceType)]; !ok {
-				instanceTypeOfferings[string(offering.InstanceType)] = sets.New[string]()
+	errs := make([]error, len(filterSets))
+	workqueue.ParallelizeUntil(ctx, maxConcurrentDescribeRequests, len(filterSets), func(i int) {
+		paginator := ec2.NewDescribeInstanceTypeOfferingsPaginator(p.ec2api, &ec2.DescribeInstanceTypeOfferingsInput{
+			LocationType: ec2types.LocationTypeAvailabilityZone,
+			Filters:      filterSets[i],
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx, p.withRetryer)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			mu.Lock()
+			for _, offering := range page.InstanceTypeOfferings {
+				if _, ok := instanceTypeOfferings[string(offering.InstanceType)]; !ok {
+					instanceTypeOfferings[string(offering.InstanceType)] = sets.New[string]()
+				}
+				instanceTypeOfferings[string(offering.InstanceType)].Insert(lo.FromPtr(offering.Location))
 			}
-			instanceTypeOfferings[string(offering.InstanceType)].Insert(lo.FromPtr(offering.Location))
+			mu.Unlock()
 		}
+	})
+	if err := multierr.Combine(errs...); err != nil {
+		return fmt.Errorf("describing instance type zone offerings, %w", err)
 	}
 
 	if p.cm.HasChanged("instance-type-offering", instanceTypeOfferings) {
@@ -277,6 +442,74 @@ func (p *DefaultProvider) UpdateInstanceTypeOfferings(ctx context.Context) error
 	return nil
 }
 
+func (p *DefaultProvider) describeInstanceTypes(ctx context.Context, architecture string) ([]ec2types.InstanceTypeInfo, error) {
+	var instanceTypes []ec2types.InstanceTypeInfo
+	paginator := ec2.NewDescribeInstanceTypesPaginator(p.ec2api, &ec2.DescribeInstanceTypesInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String("supported-virtualization-type"),
+				Values: []string{"hvm"},
+			},
+			{
+				Name:   aws.String("processor-info.supported-architecture"),
+				Values: []string{architecture},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx, p.withRetryer)
+		if err != nil {
+			return nil, err
+		}
+		instanceTypes = append(instanceTypes, page.InstanceTypes...)
+	}
+	return instanceTypes, nil
+}
+
+// offeringFilterSets returns a set of filters for each zone in the region, so that the offerings for each zone can be
+// described concurrently. If the zones can't be described, a single unfiltered set is returned and all offerings are
+// paginated serially.
+func (p *DefaultProvider) offeringFilterSets(ctx context.Context) ([][]ec2types.Filter, error) {
+	out, err := p.ec2api.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{}, p.withRetryer)
+	if err != nil {
+		if awserrors.IsUnauthorizedOperationError(err) {
+			return [][]ec2types.Filter{nil}, nil
+		}
+		return nil, fmt.Errorf("describing availability zones, %w", err)
+	}
+	if len(out.AvailabilityZones) == 0 {
+		return [][]ec2types.Filter{nil}, nil
+	}
+	return lo.Map(out.AvailabilityZones, func(zone ec2types.AvailabilityZone, _ int) []ec2types.Filter {
+		return []ec2types.Filter{{Name: aws.String("location"), Values: []string{lo.FromPtr(zone.ZoneName)}}}
+	}), nil
+}
+
+func (p *DefaultProvider) withRetryer(o *ec2.Options) {
+	p.retryerOnce.Do(func() {
+		p.retryer = adaptiveRetryer(o.Retryer)
+	})
+	o.Retryer = p.retryer
+}
+
+// adaptiveRetryer returns an adaptive retryer which keeps the max attempts, retryable errors and backoff of the
+// client's retryer, so that the operator's retry configuration still applies, and only adds client side rate limiting
+// once requests are throttled.
+func adaptiveRetryer(retryer aws.Retryer) aws.Retryer {
+	if retryer == nil {
+		return retry.NewAdaptiveMode()
+	}
+	return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+		o.StandardOptions = append(o.StandardOptions, func(so *retry.StandardOptions) {
+			so.MaxAttempts = retryer.MaxAttempts()
+			so.Retryables = []retry.IsErrorRetryable{retry.IsErrorRetryableFunc(func(err error) aws.Ternary {
+				return aws.BoolTernary(retryer.IsErrorRetryable(err))
+			})}
+			so.Backoff = retry.BackoffDelayerFunc(retryer.RetryDelay)
+		})
+	})
+}
+
 func (p *DefaultProvider) UpdateInstanceTypeCapacityFromNode(ctx context.Context, node *corev1.Node, nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass) error {
 	// Get mappings for most recent AMIs
 	instanceTypeName := node.Labels[corev1.LabelInstanceTypeStable]
@@ -291,13 +524,15 @@ func (p *DefaultProvider) UpdateInstanceTypeCapacityFromNode(ctx context.Context
 		return nil
 	}
 
-	amiHash, _ := hashstructure.Hash(nodeClass.Status.AMIs, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
-	key := fmt.Sprintf("%s-%016x", instanceTypeName, amiHash)
+	key := fmt.Sprintf("%s-%016x", instanceTypeName, p.amiHashStore.Hash(nodeClass))
 
-	// Update cache if non-existent or actual capacity is less than or equal to cached value
+	// Update cache if non-existent or actual capacity is less than or equal to cached value. Each node which registers
+	// with the cached capacity refreshes the entry, so it's only logged when the capacity changes.
 	actualCapacity := node.Status.Capacity.Memory()
 	if cachedCapacity, ok := p.discoveredCapacityCache.Get(key); !ok || actualCapacity.Cmp(cachedCapacity.(resource.Quantity)) < 1 {
-		log.FromContext(ctx).WithValues("memory-capacity", actualCapacity, "instance-type", instanceTypeName).V(1).Info("updating discovered capacity cache")
+		if !ok || actualCapacity.Cmp(cachedCapacity.(resource.Quantity)) < 0 {
+			log.FromContext(ctx).WithValues("memory-capacity", actualCapacity, "instance-type", instanceTypeName).V(1).Info("updating discovered capacity cache")
+		}
 		p.discoveredCapacityCache.SetDefault(key, *actualCapacity)
 	}
 	return nil
@@ -308,4 +543,40 @@ func (p *DefaultProvider) Reset() {
 	p.instanceTypesOfferings = map[string]sets.Set[string]{}
 	p.instanceTypesCache.Flush()
 	p.discoveredCapacityCache.Flush()
+	p.muNodeClassKeys.Lock()
+	p.nodeClassKeys = map[string]sets.Set[string]{}
+	p.incompatibleInstanceTypes = map[string]map[string]string{}
+	p.muNodeClassKeys.Unlock()
+}
+
+// Rough per-object heap costs used when estimating the size of resolved instance types. These don't need to be
+// exact, they only need to scale with the amount of data that's retained so that the cache's size bound is meaningful.
+const (
+	instanceTypeBaseSize = 256
+	requirementBaseSize  = 128
+	setMemberBaseSize    = 48
+	quantityBaseSize     = 96
+)
+
+// EstimateInstanceTypesSize approximates the number of bytes retained by a cached slice of resolved instance types
+func EstimateInstanceTypesSize(v interface{}) int64 {
+	instanceTypes, ok := v.([]*cloudprovider.InstanceType)
+	if !ok {
+		return 0
+	}
+	var size int64
+	for _, it := range instanceTypes {
+		size += instanceTypeBaseSize + int64(len(it.Name))
+		for key, req := range it.Requirements {
+			size += requirementBaseSize + int64(len(key))
+			for _, value := range req.Values() {
+				size += setMemberBaseSize + int64(len(value))
+			}
+		}
+		size += int64(len(it.Capacity)) * quantityBaseSize
+		if it.Overhead != nil {
+			size += int64(len(it.Overhead.KubeReserved)+len(it.Overhead.SystemReserved)+len(it.Overhead.EvictionThreshold)) * quantityBaseSize
+		}
+	}
+	return size
 }
@@ -17,6 +17,9 @@ package offering
 import (
 	"context"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/mitchellh/hashstructure/v2"
@@ -26,15 +29,22 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
-	"sigs.k8s.io/karpenter/pkg/operator/options"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/reservedinstance"
 )
 
+// cacheKeySeparator separates the components of the offering cache keys. Instance type names contain '.' and '-', so
+// a separator which doesn't appear in them is used to recover the instance type from a key.
+const cacheKeySeparator = "/"
+
 type Provider interface {
 	InjectOfferings(context.Context, []*cloudprovider.InstanceType, *v1.EC2NodeClass, []string) []*cloudprovider.InstanceType
 }
@@ -42,21 +52,51 @@ type Provider interface {
 type DefaultProvider struct {
 	pricingProvider             pricing.Provider
 	capacityReservationProvider capacityreservation.Provider
+	reservedInstanceProvider    reservedinstance.Provider
 	unavailableOfferings        *awscache.UnavailableOfferings
 	cache                       *cache.Cache
+
+	muGenerations sync.RWMutex
+	// generations counts the invalidations of each instance type's offerings. It's included in the cache key so that
+	// offerings computed concurrently with an invalidation aren't served once it has been published.
+	generations map[string]uint64
 }
 
 func NewDefaultProvider(
 	pricingProvider pricing.Provider,
 	capacityReservationProvider capacityreservation.Provider,
+	reservedInstanceProvider reservedinstance.Provider,
 	unavailableOfferingsCache *awscache.UnavailableOfferings,
 	offeringCache *cache.Cache,
+	invalidations *awscache.InvalidationBus,
 ) *DefaultProvider {
-	return &DefaultProvider{
+	p := &DefaultProvider{
 		pricingProvider:             pricingProvider,
 		capacityReservationProvider: capacityReservationProvider,
+		reservedInstanceProvider:    reservedInstanceProvider,
 		unavailableOfferings:        unavailableOfferingsCache,
 		cache:                       offeringCache,
+		generations:                 map[string]uint64{},
+	}
+	invalidations.Subscribe(p.invalidate)
+	return p
+}
+
+// invalidate drops the cached offerings of the invalidated instance types
+func (p *DefaultProvider) invalidate(invalidation awscache.Invalidation) {
+	if len(invalidation.InstanceTypes) == 0 {
+		return
+	}
+	p.muGenerations.Lock()
+	for _, instanceType := range invalidation.InstanceTypes {
+		p.generations[instanceType]++
+	}
+	p.muGenerations.Unlock()
+	instanceTypes := sets.New(invalidation.InstanceTypes...)
+	for key := range p.cache.Items() {
+		if instanceTypes.Has(strings.SplitN(key, cacheKeySeparator, 2)[0]) {
+			p.cache.Delete(key)
+		}
 	}
 }
 
@@ -69,6 +109,10 @@ func (p *DefaultProvider) InjectOfferings(
 	subnetZones := lo.SliceToMap(nodeClass.Status.Subnets, func(s v1.Subnet) (string, string) {
 		return s.Zone, s.ZoneID
 	})
+	var volumeCost float64
+	if options.FromContext(ctx).IncludeVolumeCost {
+		volumeCost = p.volumeCost(nodeClass)
+	}
 	var its []*cloudprovider.InstanceType
 	for _, it := range instanceTypes {
 		offerings := p.createOfferings(
@@ -78,6 +122,14 @@ func (p *DefaultProvider) InjectOfferings(
 			allZones,
 			subnetZones,
 		)
+		if volumeCost != 0 {
+			offerings = applyVolumeCost(offerings, volumeCost)
+		}
+		if options.FromContext(ctx).IncludeLicenseCost {
+			if licenseCost := p.licenseCost(it, nodeClass); licenseCost != 0 {
+				offerings = applyLicenseCost(offerings, licenseCost)
+			}
+		}
 
 		reservedAvailability := map[string]bool{}
 		for _, of := range offerings {
@@ -135,7 +187,11 @@ func (p *DefaultProvider) createOfferings(
 		offerings = append(offerings, ofs.([]*cloudprovider.Offering)...)
 	} else {
 		var cachedOfferings []*cloudprovider.Offering
-		for zone := range allZones {
+		zones := allZones.UnsortedList()
+		if options.FromContext(ctx).DeterministicOfferingSelection {
+			zones = sets.List(allZones)
+		}
+		for _, zone := range zones {
 			for _, capacityType := range it.Requirements.Get(karpv1.CapacityTypeLabelKey).Values() {
 				// Reserved capacity types are constructed separately
 				if capacityType == karpv1.CapacityTypeReserved {
@@ -146,7 +202,7 @@ func (p *DefaultProvider) createOfferings(
 				var hasPrice bool
 				switch capacityType {
 				case karpv1.CapacityTypeOnDemand:
-					price, hasPrice = p.pricingProvider.OnDemandPrice(ec2types.InstanceType(it.Name))
+					price, hasPrice = p.pricingProvider.ZonalOnDemandPrice(ec2types.InstanceType(it.Name), zone)
 				case karpv1.CapacityTypeSpot:
 					price, hasPrice = p.pricingProvider.SpotPrice(ec2types.InstanceType(it.Name), zone)
 				default:
@@ -170,7 +226,10 @@ func (p *DefaultProvider) createOfferings(
 		p.cache.SetDefault(p.cacheKeyFromInstanceType(it), cachedOfferings)
 		offerings = append(offerings, cachedOfferings...)
 	}
-	if !options.FromContext(ctx).FeatureGates.ReservedCapacity {
+	if options.FromContext(ctx).ReservedInstanceCoverage {
+		offerings = p.applyReservedInstanceCoverage(it, offerings)
+	}
+	if !coreoptions.FromContext(ctx).FeatureGates.ReservedCapacity {
 		return offerings
 	}
 
@@ -186,6 +245,11 @@ func (p *DefaultProvider) createOfferings(
 			// still succeed to create the offering and leave the price at zero. This will break consolidation, but will allow
 			// users to utilize the instances they're already paying for.
 			price = odPrice / 10_000_000.0
+			// Operators who would rather launch the cheapest capacity, e.g. spot, than fill their reservations can opt out
+			// of treating reservations as free.
+			if options.FromContext(ctx).ReservedCapacityOnDemandPricing {
+				price = odPrice
+			}
 		}
 		reservationCapacity := p.capacityReservationProvider.GetAvailableInstanceCount(reservation.ID)
 		offering := &cloudprovider.Offering{
@@ -206,6 +270,81 @@ func (p *DefaultProvider) createOfferings(
 	return offerings
 }
 
+// applyReservedInstanceCoverage discounts the on-demand offerings which would be covered by an unused Reserved Instance,
+// so that they're preferred over other offerings. Reserved Instances are billed whether or not they're used, so the
+// covered offerings are treated as free in the same way as capacity reservations. Coverage changes independently of
+// the cached offerings, so covered offerings are copied rather than modified.
+func (p *DefaultProvider) applyReservedInstanceCoverage(it *cloudprovider.InstanceType, offerings []*cloudprovider.Offering) []*cloudprovider.Offering {
+	return lo.Map(offerings, func(o *cloudprovider.Offering, _ int) *cloudprovider.Offering {
+		if o.CapacityType() != karpv1.CapacityTypeOnDemand || p.reservedInstanceProvider.Coverage(ec2types.InstanceType(it.Name), o.Zone()) == 0 {
+			return o
+		}
+		covered := *o
+		covered.Price = o.Price / 10_000_000.0
+		return &covered
+	})
+}
+
+// volumeCost returns the hourly cost of the EBS volumes attached to each instance launched with the EC2NodeClass. Volumes
+// without a size or type inherit them from the AMI or snapshot, and aren't priced.
+func (p *DefaultProvider) volumeCost(nodeClass *v1.EC2NodeClass) float64 {
+	blockDeviceMappings := nodeClass.Spec.BlockDeviceMappings
+	if len(blockDeviceMappings) == 0 {
+		blockDeviceMappings = amifamily.GetAMIFamily(nodeClass.AMIFamily(), &amifamily.Options{}).DefaultBlockDeviceMappings()
+	}
+	cost := 0.0
+	for _, bdm := range blockDeviceMappings {
+		if bdm == nil || bdm.EBS == nil || bdm.EBS.VolumeSize == nil || bdm.EBS.VolumeType == nil {
+			continue
+		}
+		price, ok := p.pricingProvider.VolumePrice(ec2types.VolumeType(lo.FromPtr(bdm.EBS.VolumeType)))
+		if !ok {
+			continue
+		}
+		// Volumes are provisioned in whole GiB, rounding up
+		size := int64(math.Ceil(bdm.EBS.VolumeSize.AsApproximateFloat64() / math.Pow(2, 30)))
+		cost += price.HourlyCost(size, lo.FromPtr(bdm.EBS.IOPS), lo.FromPtr(bdm.EBS.Throughput))
+	}
+	return cost
+}
+
+// applyVolumeCost adds the hourly cost of an instance's volumes to the price of each offering. The offerings may be
+// cached, so they're copied rather than modified.
+func applyVolumeCost(offerings []*cloudprovider.Offering, volumeCost float64) []*cloudprovider.Offering {
+	return lo.Map(offerings, func(o *cloudprovider.Offering, _ int) *cloudprovider.Offering {
+		withVolumes := *o
+		withVolumes.Price = o.Price + volumeCost
+		return &withVolumes
+	})
+}
+
+// licenseCost returns the hourly cost of the operating system license of the AMI an instance type would be launched
+// with, which is the first of the EC2NodeClass's AMIs it's compatible with
+func (p *DefaultProvider) licenseCost(it *cloudprovider.InstanceType, nodeClass *v1.EC2NodeClass) float64 {
+	for _, ami := range nodeClass.Status.AMIs {
+		if err := it.Requirements.Compatible(scheduling.NewNodeSelectorRequirements(ami.Requirements...), scheduling.AllowUndefinedWellKnownLabels); err != nil {
+			continue
+		}
+		price, _ := p.pricingProvider.LicensePrice(ec2types.InstanceType(it.Name), ami.PlatformDetails)
+		return price
+	}
+	return 0
+}
+
+// applyLicenseCost adds the hourly cost of an instance's operating system license to the price of each on-demand and
+// spot offering. Reserved offerings are priced to be preferred over other capacity, so they're left as is. The offerings
+// may be cached, so they're copied rather than modified.
+func applyLicenseCost(offerings []*cloudprovider.Offering, licenseCost float64) []*cloudprovider.Offering {
+	return lo.Map(offerings, func(o *cloudprovider.Offering, _ int) *cloudprovider.Offering {
+		if o.CapacityType() == karpv1.CapacityTypeReserved {
+			return o
+		}
+		licensed := *o
+		licensed.Price = o.Price + licenseCost
+		return &licensed
+	})
+}
+
 func (p *DefaultProvider) cacheKeyFromInstanceType(it *cloudprovider.InstanceType) string {
 	zonesHash, _ := hashstructure.Hash(
 		it.Requirements.Get(corev1.LabelTopologyZone).Values(),
@@ -217,11 +356,13 @@ func (p *DefaultProvider) cacheKeyFromInstanceType(it *cloudprovider.InstanceTyp
 		hashstructure.FormatV2,
 		&hashstructure.HashOptions{SlicesAsSets: true},
 	)
-	return fmt.Sprintf(
-		"%s-%016x-%016x-%d",
+	p.muGenerations.RLock()
+	generation := p.generations[it.Name]
+	p.muGenerations.RUnlock()
+	return strings.Join([]string{
 		it.Name,
-		zonesHash,
-		capacityTypesHash,
-		p.unavailableOfferings.SeqNum,
-	)
+		fmt.Sprintf("%016x", zonesHash),
+		fmt.Sprintf("%016x", capacityTypesHash),
+		fmt.Sprintf("%d", generation),
+	}, cacheKeySeparator)
 }
@@ -31,11 +31,14 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	awspricing "github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/smithy-go"
 	"github.com/awslabs/operatorpkg/object"
 	"github.com/awslabs/operatorpkg/status"
 	"github.com/imdario/mergo"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -60,10 +63,12 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 )
 
@@ -75,6 +80,12 @@ var prov *provisioning.Provisioner
 var cluster *state.Cluster
 var cloudProvider *cloudprovider.CloudProvider
 
+func init() {
+	instancetype.RegisterResolverMiddleware("test-label-injector", instancetype.MutatingMiddleware(nil, func(_ context.Context, it *corecloudprovider.InstanceType, _ ec2types.InstanceTypeInfo, _ *v1.EC2NodeClass) {
+		it.Requirements.Add(scheduling.NewRequirement("test.karpenter.sh/registered", corev1.NodeSelectorOpIn, "true"))
+	}))
+}
+
 func TestAWS(t *testing.T) {
 	ctx = TestContextWithLogger(t)
 	RegisterFailHandler(Fail)
@@ -88,7 +99,7 @@ var _ = BeforeSuite(func() {
 	awsEnv = test.NewEnvironment(ctx, env)
 	fakeClock = &clock.FakeClock{}
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster, fakeClock)
 })
@@ -269,6 +280,10 @@ var _ = Describe("InstanceTypeProvider", func() {
 		Expect(lo.Keys(nodeSelector)).To(ContainElements(append(karpv1.WellKnownLabels.Difference(sets.New(
 			// TODO: add back to test with a preconfigured reserved instance type
 			v1.LabelCapacityReservationID,
+			// The fake instance types don't declare their AMI capabilities
+			v1.LabelInstanceBootMode,
+			v1.LabelInstanceTPMSupported,
+			v1.LabelInstanceENASupport,
 		)).UnsortedList(), lo.Keys(karpv1.NormalizedLabels)...)))
 
 		var pods []*corev1.Pod
@@ -320,11 +335,14 @@ var _ = Describe("InstanceTypeProvider", func() {
 			"topology.ebs.csi.aws.com/zone":     "test-zone-1a",
 		}
 
-		// Ensure that we're exercising all well known labels except for the accelerator and capacity reservation labels
+		// Ensure that we're exercising all well known labels except for the accelerator, AMI capability and capacity reservation labels
 		Expect(lo.Keys(nodeSelector)).To(ContainElements(
 			append(
 				karpv1.WellKnownLabels.Difference(sets.New(
 					v1.LabelCapacityReservationID,
+					v1.LabelInstanceBootMode,
+					v1.LabelInstanceTPMSupported,
+					v1.LabelInstanceENASupport,
 					v1.LabelInstanceAcceleratorCount,
 					v1.LabelInstanceAcceleratorName,
 					v1.LabelInstanceAcceleratorManufacturer,
@@ -373,9 +391,12 @@ var _ = Describe("InstanceTypeProvider", func() {
 			"topology.ebs.csi.aws.com/zone":     "test-zone-1a",
 		}
 
-		// Ensure that we're exercising all well known labels except for the gpu, nvme and capacity reservation id labels
+		// Ensure that we're exercising all well known labels except for the gpu, nvme, AMI capability and capacity reservation id labels
 		expectedLabels := append(karpv1.WellKnownLabels.Difference(sets.New(
 			v1.LabelCapacityReservationID,
+			v1.LabelInstanceBootMode,
+			v1.LabelInstanceTPMSupported,
+			v1.LabelInstanceENASupport,
 			v1.LabelInstanceGPUCount,
 			v1.LabelInstanceGPUName,
 			v1.LabelInstanceGPUManufacturer,
@@ -403,6 +424,26 @@ var _ = Describe("InstanceTypeProvider", func() {
 		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
 		ExpectNotScheduled(ctx, env.Client, pod)
 	})
+	It("should merge instance types described for each architecture", func() {
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		its, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).To(BeNil())
+		names := lo.Map(its, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+		Expect(names).To(HaveLen(len(lo.Uniq(names))))
+	})
+	It("should describe all offerings when not authorized to describe availability zones", func() {
+		awsEnv.EC2API.NextError.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		its, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).To(BeNil())
+		Expect(its).ToNot(BeEmpty())
+	})
+	It("should return an error when describing availability zones fails", func() {
+		awsEnv.EC2API.NextError.Set(fmt.Errorf("internal error"))
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).ToNot(Succeed())
+	})
 	It("should order the instance types by price and only consider the cheapest ones", func() {
 		instances := fake.MakeInstances()
 		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{
@@ -617,6 +658,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 		ExpectScheduled(ctx, env.Client, pod)
 	})
 	It("should launch vpc.amazonaws.com/pod-eni on a compatible instance type", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{PodENI: lo.ToPtr(true)}))
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 		pod := coretest.UnschedulablePod(coretest.PodOptions{
 			ResourceRequirements: corev1.ResourceRequirements{
@@ -633,6 +675,28 @@ var _ = Describe("InstanceTypeProvider", func() {
 		}
 		Expect(supportsPodENI()).To(Equal(true))
 	})
+	It("should not launch vpc.amazonaws.com/pod-eni when pod ENIs aren't enabled", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{
+			ResourceRequirements: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{v1.ResourceAWSPodENI: resource.MustParse("1")},
+				Limits:   corev1.ResourceList{v1.ResourceAWSPodENI: resource.MustParse("1")},
+			},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectNotScheduled(ctx, env.Client, pod)
+	})
+	It("should advertise the branch interfaces of trunking compatible instance types as vpc.amazonaws.com/pod-eni", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{PodENI: lo.ToPtr(true)}))
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+		Expect(err).To(BeNil())
+		m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+		Expect(ok).To(BeTrue())
+		Expect(lo.ToPtr(m5Large.Capacity[v1.ResourceAWSPodENI]).Value()).To(BeEquivalentTo(9))
+		t3Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "t3.large" })
+		Expect(ok).To(BeTrue())
+		Expect(lo.ToPtr(t3Large.Capacity[v1.ResourceAWSPodENI]).Value()).To(BeZero())
+	})
 	It("should launch vpc.amazonaws.com/PrivateIPv4Address on a compatible instance type", func() {
 		nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2022@latest"}}
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -893,6 +957,34 @@ var _ = Describe("InstanceTypeProvider", func() {
 		}
 		Expect(nodes.Len()).To(Equal(1))
 	})
+	It("should limit the vpc.amazonaws.com/efa resource to the configured EFA count", func() {
+		nodeClass.Spec.EFA = &v1.EFA{Count: lo.ToPtr[int32](2)}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+		Expect(err).To(BeNil())
+		efaInstanceTypes := lo.Filter(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) bool {
+			return lo.ToPtr(it.Capacity[v1.ResourceEFA]).Value() != 0
+		})
+		Expect(efaInstanceTypes).ToNot(BeEmpty())
+		for _, it := range efaInstanceTypes {
+			Expect(lo.ToPtr(it.Capacity[v1.ResourceEFA]).Value()).To(BeNumerically("<=", 2))
+		}
+		dl1, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "dl1.24xlarge" })
+		Expect(ok).To(BeTrue())
+		Expect(lo.ToPtr(dl1.Capacity[v1.ResourceEFA]).Value()).To(BeEquivalentTo(2))
+	})
+	It("should not launch instances for more vpc.amazonaws.com/efa than the configured EFA count", func() {
+		nodeClass.Spec.EFA = &v1.EFA{Count: lo.ToPtr[int32](2)}
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod(coretest.PodOptions{
+			ResourceRequirements: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{v1.ResourceEFA: resource.MustParse("3")},
+				Limits:   corev1.ResourceList{v1.ResourceEFA: resource.MustParse("3")},
+			},
+		})
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectNotScheduled(ctx, env.Client, pod)
+	})
 	It("should launch instances for amd.com/gpu resource requests", func() {
 		nodeNames := sets.NewString()
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -969,6 +1061,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 				nodeClass.Spec.Kubelet.EvictionSoft,
 				nodeClass.AMIFamily(),
 				nil,
+				nil,
 			)
 			Expect(it.Capacity.Pods().Value()).ToNot(BeNumerically("==", 110))
 		}
@@ -993,6 +1086,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 				nodeClass.Spec.Kubelet.EvictionSoft,
 				windowsNodeClass.AMIFamily(),
 				nil,
+				nil,
 			)
 			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 110))
 		}
@@ -1114,6 +1208,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Overhead.SystemReserved.Cpu().String()).To(Equal("0"))
 				Expect(it.Overhead.SystemReserved.Memory().String()).To(Equal("0"))
@@ -1142,6 +1237,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Overhead.SystemReserved.Cpu().String()).To(Equal("2"))
 				Expect(it.Overhead.SystemReserved.Memory().String()).To(Equal("20Gi"))
@@ -1166,6 +1262,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Overhead.KubeReserved.Cpu().String()).To(Equal("80m"))
 				Expect(it.Overhead.KubeReserved.Memory().String()).To(Equal("893Mi"))
@@ -1199,6 +1296,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Overhead.KubeReserved.Cpu().String()).To(Equal("2"))
 				Expect(it.Overhead.KubeReserved.Memory().String()).To(Equal("10Gi"))
@@ -1239,6 +1337,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Overhead.EvictionThreshold.Memory().String()).To(Equal("500Mi"))
 				})
@@ -1269,6 +1368,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Overhead.EvictionThreshold.Memory().Value()).To(BeNumerically("~", float64(it.Capacity.Memory().Value())*0.1, 10))
 				})
@@ -1299,6 +1399,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Overhead.EvictionThreshold.Memory().String()).To(Equal("0"))
 				})
@@ -1329,6 +1430,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Overhead.EvictionThreshold.Memory().String()).To(Equal("50Mi"))
 				})
@@ -1361,6 +1463,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Overhead.EvictionThreshold.Memory().String()).To(Equal("500Mi"))
 				})
@@ -1394,6 +1497,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Overhead.EvictionThreshold.Memory().Value()).To(BeNumerically("~", float64(it.Capacity.Memory().Value())*0.1, 10))
 				})
@@ -1424,6 +1528,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Overhead.EvictionThreshold.Memory().String()).To(Equal("0"))
 				})
@@ -1458,6 +1563,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Overhead.EvictionThreshold.Memory().String()).To(Equal("1Gi"))
 				})
@@ -1479,6 +1585,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Overhead.EvictionThreshold.Cpu().String()).To(Equal("0"))
 				Expect(it.Overhead.EvictionThreshold.Memory().String()).To(Equal("100Mi"))
@@ -1514,6 +1621,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Overhead.EvictionThreshold.Memory().String()).To(Equal("3Gi"))
 			})
@@ -1547,6 +1655,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Overhead.EvictionThreshold.Memory().Value()).To(BeNumerically("~", float64(it.Capacity.Memory().Value())*0.05, 10))
 			})
@@ -1580,6 +1689,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Overhead.EvictionThreshold.Memory().Value()).To(BeNumerically("~", float64(it.Capacity.Memory().Value())*0.1, 10))
 			})
@@ -1605,6 +1715,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 35))
 				}
@@ -1624,6 +1735,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 394))
 				}
@@ -1651,6 +1763,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 10))
 			}
@@ -1677,6 +1790,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 10))
 			}
@@ -1708,6 +1822,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 				nodeClass.Spec.Kubelet.EvictionSoft,
 				nodeClass.AMIFamily(),
 				nil,
+				nil,
 			)
 			// t3.large
 			// maxInterfaces = 3
@@ -1717,6 +1832,49 @@ var _ = Describe("InstanceTypeProvider", func() {
 			maxPods := 24
 			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", maxPods))
 		})
+		It("should reserve the trunk ENI of trunking compatible instance types in max-pods calculation when pod ENIs are enabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				PodENI: lo.ToPtr(true),
+			}))
+
+			instanceInfo, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{}
+			for instanceType, maxPods := range map[ec2types.InstanceType]int{
+				// m5.large
+				// maxInterfaces = 3
+				// maxIPv4PerInterface = 10
+				// trunk ENI = 1
+				// (3 - 1) * (10 - 1) + 2 = 20
+				"m5.large": 20,
+				// t3.large isn't trunking compatible
+				// (3 - 0) * (12 - 1) + 2 = 35
+				"t3.large": 35,
+			} {
+				info, ok := lo.Find(instanceInfo.InstanceTypes, func(info ec2types.InstanceTypeInfo) bool {
+					return info.InstanceType == instanceType
+				})
+				Expect(ok).To(Equal(true))
+				it := instancetype.NewInstanceType(ctx,
+					info,
+					fake.DefaultRegion,
+					nil,
+					nil,
+					nodeClass.Spec.BlockDeviceMappings,
+					nodeClass.Spec.InstanceStorePolicy,
+					nodeClass.Spec.Kubelet.MaxPods,
+					nodeClass.Spec.Kubelet.PodsPerCore,
+					nodeClass.Spec.Kubelet.KubeReserved,
+					nodeClass.Spec.Kubelet.SystemReserved,
+					nodeClass.Spec.Kubelet.EvictionHard,
+					nodeClass.Spec.Kubelet.EvictionSoft,
+					nodeClass.AMIFamily(),
+					nil,
+					nil,
+				)
+				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", maxPods), string(instanceType))
+			}
+		})
 		It("should reserve ENIs when aws.reservedENIs is set and not go below 0 ENIs in max-pods calculation", func() {
 			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
 				ReservedENIs: lo.ToPtr(1_000_000),
@@ -1744,6 +1902,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 				nodeClass.Spec.Kubelet.EvictionSoft,
 				nodeClass.AMIFamily(),
 				nil,
+				nil,
 			)
 			// t3.large
 			// maxInterfaces = 3
@@ -1776,6 +1935,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", lo.FromPtr(info.VCpuInfo.DefaultVCpus)))
 			}
@@ -1803,6 +1963,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", lo.Min([]int32{20, lo.FromPtr(info.VCpuInfo.DefaultVCpus) * 4})))
 			}
@@ -1830,6 +1991,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 					nodeClass.Spec.Kubelet.EvictionSoft,
 					nodeClass.AMIFamily(),
 					nil,
+					nil,
 				)
 				limitedPods := instancetype.ENILimitedPods(ctx, info)
 				Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", limitedPods.Value()))
@@ -1858,6 +2020,7 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 35))
 				}
@@ -1877,11 +2040,71 @@ var _ = Describe("InstanceTypeProvider", func() {
 						nodeClass.Spec.Kubelet.EvictionSoft,
 						nodeClass.AMIFamily(),
 						nil,
+						nil,
 					)
 					Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 394))
 				}
 			}
 		})
+		It("should take prefix delegation pod density to be the default pods number for IPv6-only subnets", func() {
+			instanceInfo, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			nodeClass.Status.Subnets = []v1.Subnet{{ID: "subnet-test1", Zone: "test-zone-1a", IPv6Native: true}}
+			resolver := instancetype.NewDefaultResolver(fake.DefaultRegion)
+			for _, info := range instanceInfo.InstanceTypes {
+				if info.InstanceType == "t3.large" {
+					it := resolver.Resolve(ctx, info, nil, nil, nodeClass)
+					Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 110))
+				}
+				if info.InstanceType == "m6idn.32xlarge" {
+					it := resolver.Resolve(ctx, info, nil, nil, nodeClass)
+					Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 250))
+				}
+			}
+		})
+		It("should prefer max pods over prefix delegation pod density for IPv6-only subnets", func() {
+			instanceInfo, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			nodeClass.Status.Subnets = []v1.Subnet{{ID: "subnet-test1", Zone: "test-zone-1a", IPv6Native: true}}
+			nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](20)}
+			info, ok := lo.Find(instanceInfo.InstanceTypes, func(i ec2types.InstanceTypeInfo) bool { return i.InstanceType == "t3.large" })
+			Expect(ok).To(BeTrue())
+			it := instancetype.NewDefaultResolver(fake.DefaultRegion).Resolve(ctx, info, nil, nil, nodeClass)
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 20))
+		})
+		It("should take prefix delegation pod density to be the default pods number when prefix delegation is enabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				PrefixDelegation: lo.ToPtr(true),
+			}))
+			instanceInfo, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			resolver := instancetype.NewDefaultResolver(fake.DefaultRegion)
+			for _, info := range instanceInfo.InstanceTypes {
+				switch info.InstanceType {
+				case "t3.large":
+					// (3 * (12 - 1) * 16) + 2 = 530, which is capped at 110 for instance types with fewer than 30 vCPUs
+					Expect(resolver.Resolve(ctx, info, nil, nil, nodeClass).Capacity.Pods().Value()).To(BeNumerically("==", 110))
+				case "m5.metal":
+					// Bare metal instances have no hypervisor, but are assigned prefixes
+					Expect(resolver.Resolve(ctx, info, nil, nil, nodeClass).Capacity.Pods().Value()).To(BeNumerically("==", 250))
+				case "p3.8xlarge":
+					// Prefixes aren't assigned to the network interfaces of Xen instances
+					Expect(resolver.Resolve(ctx, info, nil, nil, nodeClass).Capacity.Pods().Value()).To(Equal(instancetype.ENILimitedPods(ctx, info).Value()))
+				}
+			}
+		})
+		It("should prefer max pods over prefix delegation pod density when prefix delegation is enabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				PrefixDelegation: lo.ToPtr(true),
+			}))
+			instanceInfo, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).To(BeNil())
+			nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](20)}
+			info, ok := lo.Find(instanceInfo.InstanceTypes, func(i ec2types.InstanceTypeInfo) bool { return i.InstanceType == "t3.large" })
+			Expect(ok).To(BeTrue())
+			it := instancetype.NewDefaultResolver(fake.DefaultRegion).Resolve(ctx, info, nil, nil, nodeClass)
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 20))
+		})
 		It("shouldn't report more resources than are actually available on instances", func() {
 			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{
 				Subnets: []ec2types.Subnet{
@@ -2409,6 +2632,561 @@ var _ = Describe("InstanceTypeProvider", func() {
 				}
 			}
 		})
+		Context("Fleet Options", func() {
+			launch := func(capacityType string, instanceTypes ...string) *ec2.CreateFleetInput {
+				GinkgoHelper()
+				if len(instanceTypes) == 0 {
+					instanceTypes = []string{"m5.large", "m5.xlarge"}
+				}
+				nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{
+					NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+						Key:      karpv1.CapacityTypeLabelKey,
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{capacityType},
+					},
+				}}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{NodeRequirements: []corev1.NodeSelectorRequirement{{
+					Key:      corev1.LabelInstanceTypeStable,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   instanceTypes,
+				}}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				return awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			}
+			priorities := func(call *ec2.CreateFleetInput) map[string]float64 {
+				result := map[string]float64{}
+				for _, ltc := range call.LaunchTemplateConfigs {
+					for _, override := range ltc.Overrides {
+						Expect(override.Priority).ToNot(BeNil())
+						key := string(override.InstanceType)
+						if p, ok := result[key]; !ok || lo.FromPtr(override.Priority) < p {
+							result[key] = lo.FromPtr(override.Priority)
+						}
+					}
+				}
+				return result
+			}
+			It("should default to the price-capacity-optimized spot allocation strategy", func() {
+				call := launch(karpv1.CapacityTypeSpot)
+				Expect(call.SpotOptions.AllocationStrategy).To(Equal(ec2types.SpotAllocationStrategyPriceCapacityOptimized))
+				for _, override := range call.LaunchTemplateConfigs[0].Overrides {
+					Expect(override.Priority).To(BeNil())
+				}
+			})
+			It("should use the configured spot allocation strategy", func() {
+				nodeClass.Spec.FleetOptions = &v1.FleetOptions{SpotAllocationStrategy: lo.ToPtr("lowest-price")}
+				call := launch(karpv1.CapacityTypeSpot)
+				Expect(call.SpotOptions.AllocationStrategy).To(Equal(ec2types.SpotAllocationStrategyLowestPrice))
+			})
+			It("should prioritize spot overrides by instance type with the capacity-optimized-prioritized strategy", func() {
+				nodeClass.Spec.FleetOptions = &v1.FleetOptions{
+					SpotAllocationStrategy: lo.ToPtr("capacity-optimized-prioritized"),
+					InstanceTypePriority:   []string{"m5.xlarge", "m5.large"},
+				}
+				call := launch(karpv1.CapacityTypeSpot)
+				Expect(call.SpotOptions.AllocationStrategy).To(Equal(ec2types.SpotAllocationStrategyCapacityOptimizedPrioritized))
+				p := priorities(call)
+				Expect(p["m5.xlarge"]).To(BeNumerically("<", p["m5.large"]))
+			})
+			It("should prioritize on-demand overrides by instance type", func() {
+				nodeClass.Spec.FleetOptions = &v1.FleetOptions{InstanceTypePriority: []string{"m5.xlarge"}}
+				call := launch(karpv1.CapacityTypeOnDemand)
+				Expect(call.OnDemandOptions.AllocationStrategy).To(Equal(ec2types.FleetOnDemandAllocationStrategyPrioritized))
+				p := priorities(call)
+				Expect(p["m5.xlarge"]).To(BeNumerically("<", p["m5.large"]))
+			})
+			It("should prefer a more expensive architecture within the price tolerance", func() {
+				// t3.large (amd64) costs about 22% more than c6g.large (arm64)
+				nodeClass.Spec.FleetOptions = &v1.FleetOptions{
+					ArchitecturePriority:              []string{karpv1.ArchitectureAmd64, karpv1.ArchitectureArm64},
+					ArchitecturePriceTolerancePercent: lo.ToPtr[int32](25),
+				}
+				call := launch(karpv1.CapacityTypeOnDemand, "t3.large", "c6g.large")
+				Expect(call.OnDemandOptions.AllocationStrategy).To(Equal(ec2types.FleetOnDemandAllocationStrategyPrioritized))
+				p := priorities(call)
+				Expect(p["t3.large"]).To(BeNumerically("<", p["c6g.large"]))
+			})
+			It("should prefer a cheaper architecture beyond the price tolerance", func() {
+				nodeClass.Spec.FleetOptions = &v1.FleetOptions{ArchitecturePriority: []string{karpv1.ArchitectureAmd64, karpv1.ArchitectureArm64}}
+				p := priorities(launch(karpv1.CapacityTypeOnDemand, "t3.large", "c6g.large"))
+				Expect(p["c6g.large"]).To(BeNumerically("<", p["t3.large"]))
+			})
+			It("should prefer the instance type priority over the architecture priority", func() {
+				nodeClass.Spec.FleetOptions = &v1.FleetOptions{
+					InstanceTypePriority:              []string{"c6g.large"},
+					ArchitecturePriority:              []string{karpv1.ArchitectureAmd64},
+					ArchitecturePriceTolerancePercent: lo.ToPtr[int32](100),
+				}
+				p := priorities(launch(karpv1.CapacityTypeOnDemand, "t3.large", "c6g.large"))
+				Expect(p["c6g.large"]).To(BeNumerically("<", p["t3.large"]))
+			})
+			It("should use the lowest-price on-demand allocation strategy without an instance type priority", func() {
+				call := launch(karpv1.CapacityTypeOnDemand)
+				Expect(call.OnDemandOptions.AllocationStrategy).To(Equal(ec2types.FleetOnDemandAllocationStrategyLowestPrice))
+			})
+		})
+		Context("Deterministic Offering Selection", func() {
+			It("should order overrides by instance type, zone and subnet", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{DeterministicOfferingSelection: lo.ToPtr(true)}))
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{NodeRequirements: []corev1.NodeSelectorRequirement{{
+					Key:      corev1.LabelInstanceTypeStable,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"m5.xlarge", "m5.large", "c5.large"},
+				}}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+
+				call := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+				Expect(call.LaunchTemplateConfigs).ToNot(BeEmpty())
+				for _, ltc := range call.LaunchTemplateConfigs {
+					keys := lo.Map(ltc.Overrides, func(o ec2types.FleetLaunchTemplateOverridesRequest, _ int) string {
+						return fmt.Sprintf("%s/%s/%s", o.InstanceType, lo.FromPtr(o.AvailabilityZone), lo.FromPtr(o.SubnetId))
+					})
+					Expect(sort.StringsAreSorted(keys)).To(BeTrue())
+				}
+			})
+		})
+		Context("Volume Cost", func() {
+			BeforeEach(func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IncludeVolumeCost: lo.ToPtr(true)}))
+			})
+			offering := func(capacityType string) *corecloudprovider.Offering {
+				GinkgoHelper()
+				ExpectApplied(ctx, env.Client, nodeClass)
+				instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+				Expect(err).ToNot(HaveOccurred())
+				it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+				Expect(ok).To(BeTrue())
+				o, ok := lo.Find(it.Offerings, func(o *corecloudprovider.Offering) bool {
+					return o.CapacityType() == capacityType && o.Zone() == "test-zone-1a"
+				})
+				Expect(ok).To(BeTrue())
+				return o
+			}
+			It("should include the cost of the default block device mappings", func() {
+				odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+				// AL2023 uses a single 20Gi gp3 volume by default
+				Expect(offering(karpv1.CapacityTypeOnDemand).Price).To(BeNumerically("~", odPrice+20*0.08/pricing.HoursPerMonth))
+			})
+			It("should include the cost of provisioned IOPS and throughput", func() {
+				nodeClass.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{{
+					DeviceName: lo.ToPtr("/dev/xvda"),
+					EBS: &v1.BlockDevice{
+						VolumeType: lo.ToPtr("gp3"),
+						VolumeSize: lo.ToPtr(resource.MustParse("100Gi")),
+						IOPS:       lo.ToPtr(int64(6000)),
+						Throughput: lo.ToPtr(int64(250)),
+					},
+				}}
+				spotPrice := lo.Must(awsEnv.PricingProvider.SpotPrice("m5.large", "test-zone-1a"))
+				Expect(offering(karpv1.CapacityTypeSpot).Price).To(BeNumerically("~", spotPrice+(100*0.08+3000*0.005+125*0.04)/pricing.HoursPerMonth))
+			})
+			It("should not price volumes which inherit their size from the snapshot", func() {
+				nodeClass.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{{
+					DeviceName: lo.ToPtr("/dev/xvda"),
+					EBS:        &v1.BlockDevice{VolumeType: lo.ToPtr("gp3"), SnapshotID: lo.ToPtr("snap-0123456789")},
+				}}
+				odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+				Expect(offering(karpv1.CapacityTypeOnDemand).Price).To(BeNumerically("==", odPrice))
+			})
+			It("should not include volume costs unless enabled", func() {
+				ctx = options.ToContext(ctx, test.Options())
+				odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+				Expect(offering(karpv1.CapacityTypeOnDemand).Price).To(BeNumerically("==", odPrice))
+			})
+		})
+		Context("License Cost", func() {
+			BeforeEach(func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IncludeLicenseCost: lo.ToPtr(true)}))
+				awsEnv.PricingAPI.GetProductsBehavior.Output.Set(&awspricing.GetProductsOutput{
+					PriceList: []string{fake.NewOnDemandPrice("m5.large", 0.096)},
+				})
+				awsEnv.PricingAPI.OperatingSystemOutputs.Store("RHEL", &awspricing.GetProductsOutput{
+					PriceList: []string{fake.NewOnDemandPrice("m5.large", 0.1248)},
+				})
+				awsEnv.PricingAPI.OperatingSystemOutputs.Store("Windows", &awspricing.GetProductsOutput{
+					PriceList: []string{fake.NewOnDemandPrice("m5.large", 0.188)},
+				})
+				awsEnv.PricingAPI.OperatingSystemOutputs.Store("SUSE", &awspricing.GetProductsOutput{
+					PriceList: []string{fake.NewOnDemandPrice("m5.large", 0.126)},
+				})
+				Expect(awsEnv.PricingProvider.UpdateLicensePricing(ctx)).To(Succeed())
+			})
+			offering := func(capacityType string) *corecloudprovider.Offering {
+				GinkgoHelper()
+				ExpectApplied(ctx, env.Client, nodeClass)
+				instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+				Expect(err).ToNot(HaveOccurred())
+				it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+				Expect(ok).To(BeTrue())
+				o, ok := lo.Find(it.Offerings, func(o *corecloudprovider.Offering) bool {
+					return o.CapacityType() == capacityType && o.Zone() == "test-zone-1a"
+				})
+				Expect(ok).To(BeTrue())
+				return o
+			}
+			It("should include the license cost of the AMI the instance type launches with", func() {
+				nodeClass.Status.AMIs[0].PlatformDetails = "Red Hat Enterprise Linux"
+				odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+				Expect(offering(karpv1.CapacityTypeOnDemand).Price).To(BeNumerically("~", odPrice+(0.1248-0.096)))
+			})
+			It("should include the license cost in spot offerings", func() {
+				nodeClass.Status.AMIs[0].PlatformDetails = "SUSE Linux"
+				nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{
+					NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: karpv1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.CapacityTypeSpot}},
+				}}
+				spotPrice := lo.Must(awsEnv.PricingProvider.SpotPrice("m5.large", "test-zone-1a"))
+				Expect(offering(karpv1.CapacityTypeSpot).Price).To(BeNumerically("~", spotPrice+(0.126-0.096)))
+			})
+			It("should not include a license cost for Linux AMIs", func() {
+				nodeClass.Status.AMIs[0].PlatformDetails = "Linux/UNIX"
+				odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+				Expect(offering(karpv1.CapacityTypeOnDemand).Price).To(BeNumerically("==", odPrice))
+			})
+			It("should not include license costs unless enabled", func() {
+				ctx = options.ToContext(ctx, test.Options())
+				nodeClass.Status.AMIs[0].PlatformDetails = "Red Hat Enterprise Linux"
+				odPrice := lo.Must(awsEnv.PricingProvider.OnDemandPrice("m5.large"))
+				Expect(offering(karpv1.CapacityTypeOnDemand).Price).To(BeNumerically("==", odPrice))
+			})
+		})
+		Context("Reserved Instance Coverage", func() {
+			BeforeEach(func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ReservedInstanceCoverage: lo.ToPtr(true)}))
+				awsEnv.EC2API.DescribeReservedInstancesBehavior.Output.Set(&ec2.DescribeReservedInstancesOutput{
+					ReservedInstances: []ec2types.ReservedInstances{{
+						InstanceType:     "m5.large",
+						InstanceCount:    lo.ToPtr[int32](1),
+						Scope:            ec2types.ScopeAvailabilityZone,
+						AvailabilityZone: lo.ToPtr("test-zone-1a"),
+					}},
+				})
+				Expect(awsEnv.ReservedInstanceProvider.UpdateReservedInstances(ctx)).To(Succeed())
+			})
+			It("should discount on-demand offerings covered by unused reserved instances", func() {
+				ExpectApplied(ctx, env.Client, nodeClass)
+				instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+				Expect(err).ToNot(HaveOccurred())
+				it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+				Expect(ok).To(BeTrue())
+				odPrice, ok := awsEnv.PricingProvider.OnDemandPrice("m5.large")
+				Expect(ok).To(BeTrue())
+				for _, o := range it.Offerings {
+					if o.CapacityType() == karpv1.CapacityTypeOnDemand && o.Zone() == "test-zone-1a" {
+						Expect(o.Price).To(BeNumerically("<", odPrice))
+					} else if o.CapacityType() == karpv1.CapacityTypeOnDemand {
+						Expect(o.Price).To(Equal(odPrice))
+					}
+				}
+			})
+			It("should prioritize covered offerings when launching on-demand instances", func() {
+				nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{
+					NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+						Key:      karpv1.CapacityTypeLabelKey,
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{karpv1.CapacityTypeOnDemand},
+					},
+				}}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{corev1.LabelInstanceTypeStable: "m5.large"}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+
+				call := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+				Expect(call.OnDemandOptions.AllocationStrategy).To(Equal(ec2types.FleetOnDemandAllocationStrategyPrioritized))
+				for _, override := range call.LaunchTemplateConfigs[0].Overrides {
+					Expect(override.Priority).ToNot(BeNil())
+					Expect(lo.FromPtr(override.Priority) == 0).To(Equal(lo.FromPtr(override.AvailabilityZone) == "test-zone-1a"))
+				}
+			})
+		})
+		Context("Capacity Reservation Pricing", func() {
+			BeforeEach(func() {
+				nodeClass.Status.CapacityReservations = []v1.CapacityReservation{{
+					AvailabilityZone:      "test-zone-1a",
+					ID:                    "cr-m5large1a",
+					InstanceMatchCriteria: string(ec2types.InstanceMatchCriteriaTargeted),
+					InstanceType:          "m5.large",
+					OwnerID:               "012345678901",
+				}}
+			})
+			reservedOffering := func() *corecloudprovider.Offering {
+				ExpectApplied(ctx, env.Client, nodeClass)
+				instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+				Expect(err).ToNot(HaveOccurred())
+				it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+				Expect(ok).To(BeTrue())
+				o, ok := lo.Find(it.Offerings, func(o *corecloudprovider.Offering) bool { return o.CapacityType() == karpv1.CapacityTypeReserved })
+				Expect(ok).To(BeTrue())
+				return o
+			}
+			It("should price reserved offerings at close to zero", func() {
+				odPrice, ok := awsEnv.PricingProvider.OnDemandPrice("m5.large")
+				Expect(ok).To(BeTrue())
+				Expect(reservedOffering().Price).To(BeNumerically("<", odPrice/1000))
+			})
+			It("should price reserved offerings at the on-demand price when zero-cost pricing is disabled", func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ReservedCapacityOnDemandPricing: lo.ToPtr(true)}))
+				odPrice, ok := awsEnv.PricingProvider.OnDemandPrice("m5.large")
+				Expect(ok).To(BeTrue())
+				Expect(reservedOffering().Price).To(Equal(odPrice))
+			})
+		})
+	})
+	Context("Resolver Chain", func() {
+		var info ec2types.InstanceTypeInfo
+		BeforeEach(func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).ToNot(HaveOccurred())
+			info = lo.Must(lo.Find(out.InstanceTypes, func(i ec2types.InstanceTypeInfo) bool { return i.InstanceType == "m5.large" }))
+		})
+		labelInjector := func(key string) instancetype.ResolverMiddleware {
+			return instancetype.MutatingMiddleware(nil, func(_ context.Context, it *corecloudprovider.InstanceType, _ ec2types.InstanceTypeInfo, _ *v1.EC2NodeClass) {
+				// Record the labels injected before this one, to validate the order the chain is applied in
+				previous := lo.Filter(it.Requirements.Keys().UnsortedList(), func(k string, _ int) bool { return strings.HasPrefix(k, "test.karpenter.sh/") })
+				sort.Strings(previous)
+				it.Requirements.Add(scheduling.NewRequirement(key, corev1.NodeSelectorOpIn, strings.Join(previous, ".")))
+			})
+		}
+
+		It("should apply middlewares in order", func() {
+			resolver := instancetype.NewResolverChain(awsEnv.InstanceTypesResolver, labelInjector("test.karpenter.sh/first"), labelInjector("test.karpenter.sh/second"))
+			it := resolver.Resolve(ctx, info, []string{"test-zone-1a"}, map[string]string{"test-zone-1a": "tstz1-1a"}, nodeClass)
+			Expect(it.Requirements.Get("test.karpenter.sh/first").Values()).To(ConsistOf(""))
+			Expect(it.Requirements.Get("test.karpenter.sh/second").Values()).To(ConsistOf("test.karpenter.sh/first"))
+		})
+		It("should adjust the overhead and capacity of resolved instance types", func() {
+			base := awsEnv.InstanceTypesResolver.Resolve(ctx, info, []string{"test-zone-1a"}, map[string]string{"test-zone-1a": "tstz1-1a"}, nodeClass)
+			resolver := instancetype.NewResolverChain(awsEnv.InstanceTypesResolver, instancetype.MutatingMiddleware(nil, func(_ context.Context, it *corecloudprovider.InstanceType, _ ec2types.InstanceTypeInfo, _ *v1.EC2NodeClass) {
+				it.Overhead.SystemReserved[corev1.ResourceMemory] = resource.MustParse("1Gi")
+				it.Capacity[corev1.ResourcePods] = resource.MustParse("8")
+			}))
+			it := resolver.Resolve(ctx, info, []string{"test-zone-1a"}, map[string]string{"test-zone-1a": "tstz1-1a"}, nodeClass)
+			Expect(it.Overhead.SystemReserved.Memory().String()).To(Equal("1Gi"))
+			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 8))
+			Expect(it.Capacity.Cpu().Equal(*base.Capacity.Cpu())).To(BeTrue())
+		})
+		It("should extend the cache key with the middleware's key", func() {
+			resolver := instancetype.NewResolverChain(awsEnv.InstanceTypesResolver,
+				instancetype.MutatingMiddleware(func(nc *v1.EC2NodeClass) string { return nc.Annotations["test.karpenter.sh/overhead"] }, func(context.Context, *corecloudprovider.InstanceType, ec2types.InstanceTypeInfo, *v1.EC2NodeClass) {}),
+			)
+			key := resolver.CacheKey(nodeClass)
+			Expect(key).To(HavePrefix(awsEnv.InstanceTypesResolver.CacheKey(nodeClass)))
+			nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{"test.karpenter.sh/overhead": "1Gi"})
+			Expect(resolver.CacheKey(nodeClass)).ToNot(Equal(key))
+		})
+		It("should not change the cache key for middlewares without one", func() {
+			resolver := instancetype.NewResolverChain(awsEnv.InstanceTypesResolver, labelInjector("test.karpenter.sh/first"))
+			Expect(resolver.CacheKey(nodeClass)).To(Equal(awsEnv.InstanceTypesResolver.CacheKey(nodeClass)))
+		})
+		It("should resolve instance types through registered middlewares", func() {
+			Expect(instancetype.RegisteredResolverMiddlewares()).To(Equal([]string{"test-label-injector"}))
+			it := instancetype.NewRegisteredResolver(awsEnv.InstanceTypesResolver).Resolve(ctx, info, []string{"test-zone-1a"}, map[string]string{"test-zone-1a": "tstz1-1a"}, nodeClass)
+			Expect(it.Requirements.Get("test.karpenter.sh/registered").Values()).To(ConsistOf("true"))
+		})
+		It("should panic when registering a middleware twice", func() {
+			Expect(func() {
+				instancetype.RegisterResolverMiddleware("test-label-injector", labelInjector("test.karpenter.sh/duplicate"))
+			}).To(Panic())
+		})
+		It("should panic when registering a nil middleware", func() {
+			Expect(func() { instancetype.RegisterResolverMiddleware("test-nil", nil) }).To(Panic())
+		})
+	})
+	Context("AMI Compatibility", func() {
+		BeforeEach(func() {
+			instances := lo.Map(fake.MakeInstances(), func(info ec2types.InstanceTypeInfo, _ int) ec2types.InstanceTypeInfo {
+				if info.InstanceType == "m5.large" {
+					networkInfo := *info.NetworkInfo
+					networkInfo.EnaSupport = ec2types.EnaSupportRequired
+					info.NetworkInfo = &networkInfo
+					info.SupportedBootModes = []ec2types.BootModeType{ec2types.BootModeTypeLegacyBios, ec2types.BootModeTypeUefi}
+				}
+				if info.InstanceType == "m5.xlarge" {
+					info.SupportedBootModes = []ec2types.BootModeType{ec2types.BootModeTypeLegacyBios}
+				}
+				return info
+			})
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: instances})
+			awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+				InstanceTypeOfferings: fake.MakeInstanceOfferings(instances),
+			})
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		})
+		It("should exclude instance types which require ENA when their AMI doesn't support it", func() {
+			nodeClass.Status.AMIs[0].ENASupport = lo.ToPtr(false)
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			names := lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+			Expect(names).ToNot(ContainElement("m5.large"))
+			Expect(names).To(ContainElement("m5.xlarge"))
+			incompatible, err := awsEnv.InstanceTypesProvider.IncompatibleInstanceTypes(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(incompatible).To(Equal(map[string]string{"m5.large": "ami-test1 doesn't support ENA"}))
+		})
+		It("should exclude instance types which don't support their AMI's boot mode", func() {
+			nodeClass.Status.AMIs[0].BootMode = string(ec2types.BootModeValuesUefi)
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			names := lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+			Expect(names).ToNot(ContainElement("m5.xlarge"))
+			Expect(names).To(ContainElement("m5.large"))
+			incompatible, err := awsEnv.InstanceTypesProvider.IncompatibleInstanceTypes(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(incompatible).To(Equal(map[string]string{"m5.xlarge": "ami-test1 requires the uefi boot mode"}))
+		})
+		It("should only define the AMI capability requirements that the instance type declares", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+			Expect(ok).To(BeTrue())
+			Expect(m5Large.Requirements.Get(v1.LabelInstanceBootMode).Values()).To(ConsistOf(string(ec2types.BootModeTypeLegacyBios), string(ec2types.BootModeTypeUefi)))
+			Expect(m5Large.Requirements.Get(v1.LabelInstanceENASupport).Values()).To(ConsistOf(string(ec2types.EnaSupportRequired)))
+			Expect(m5Large.Requirements.Has(v1.LabelInstanceTPMSupported)).To(BeFalse())
+		})
+		It("should not exclude instance types when their AMI doesn't declare its capabilities", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			names := lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })
+			Expect(names).To(ContainElements("m5.large", "m5.xlarge"))
+			incompatible, err := awsEnv.InstanceTypesProvider.IncompatibleInstanceTypes(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(incompatible).To(BeEmpty())
+		})
+	})
+	Context("CPU Options", func() {
+		BeforeEach(func() {
+			instances := lo.Map(fake.MakeInstances(), func(info ec2types.InstanceTypeInfo, _ int) ec2types.InstanceTypeInfo {
+				if info.InstanceType == "m5.large" {
+					info.VCpuInfo = &ec2types.VCpuInfo{DefaultCores: lo.ToPtr[int32](1), DefaultVCpus: lo.ToPtr[int32](2), ValidThreadsPerCore: []int32{1, 2}}
+				}
+				return info
+			})
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: instances})
+			awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+				InstanceTypeOfferings: fake.MakeInstanceOfferings(instances),
+			})
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		})
+		It("should advertise vCPUs when the threads per core aren't set", func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+			Expect(ok).To(BeTrue())
+			Expect(m5Large.Capacity.Cpu().Value()).To(BeNumerically("==", 2))
+		})
+		It("should advertise physical cores when multithreading is disabled", func() {
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{ThreadsPerCore: lo.ToPtr[int32](1)}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+			Expect(ok).To(BeTrue())
+			Expect(m5Large.Capacity.Cpu().Value()).To(BeNumerically("==", 1))
+		})
+		It("should exclude instance types which don't support the threads per core", func() {
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{ThreadsPerCore: lo.ToPtr[int32](1)}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("m5.large"))
+			incompatible, err := awsEnv.InstanceTypesProvider.IncompatibleInstanceTypes(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(incompatible).To(HaveKeyWithValue("m5.xlarge", "doesn't support setting threadsPerCore to 1"))
+		})
+	})
+	Context("Extended Resources", func() {
+		It("should advertise extended resources on the instance types of the selected families", func() {
+			nodeClass.Spec.ExtendedResources = []v1.ExtendedResource{
+				{InstanceFamilies: []string{"m5"}, Resources: corev1.ResourceList{"example.com/asic": resource.MustParse("4")}},
+			}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			for _, it := range instanceTypes {
+				if strings.HasPrefix(it.Name, "m5.") {
+					Expect(it.Capacity).To(HaveKeyWithValue(corev1.ResourceName("example.com/asic"), resource.MustParse("4")))
+					Expect(it.Allocatable()).To(HaveKeyWithValue(corev1.ResourceName("example.com/asic"), resource.MustParse("4")))
+				} else {
+					Expect(it.Capacity).ToNot(HaveKey(corev1.ResourceName("example.com/asic")))
+				}
+			}
+		})
+		It("should prefer the quantities of later entries", func() {
+			nodeClass.Spec.ExtendedResources = []v1.ExtendedResource{
+				{InstanceFamilies: []string{"m5"}, Resources: corev1.ResourceList{"example.com/asic": resource.MustParse("4")}},
+				{InstanceTypes: []string{"m5.xlarge"}, Resources: corev1.ResourceList{"example.com/asic": resource.MustParse("8")}},
+			}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			m5Large, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+			Expect(ok).To(BeTrue())
+			Expect(m5Large.Capacity).To(HaveKeyWithValue(corev1.ResourceName("example.com/asic"), resource.MustParse("4")))
+			m5XLarge, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(m5XLarge.Capacity).To(HaveKeyWithValue(corev1.ResourceName("example.com/asic"), resource.MustParse("8")))
+		})
+		It("should launch instances for extended resource requests", func() {
+			nodeClass.Spec.ExtendedResources = []v1.ExtendedResource{
+				{InstanceTypes: []string{"m5.xlarge"}, Resources: corev1.ResourceList{"example.com/asic": resource.MustParse("4")}},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				ResourceRequirements: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{"example.com/asic": resource.MustParse("2")},
+					Limits:   corev1.ResourceList{"example.com/asic": resource.MustParse("2")},
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(corev1.LabelInstanceTypeStable, "m5.xlarge"))
+		})
+	})
+	Context("Boot Options", func() {
+		BeforeEach(func() {
+			instances := lo.Map(fake.MakeInstances(), func(info ec2types.InstanceTypeInfo, _ int) ec2types.InstanceTypeInfo {
+				if info.InstanceType == "m5.large" {
+					info.SupportedBootModes = []ec2types.BootModeType{ec2types.BootModeTypeLegacyBios, ec2types.BootModeTypeUefi}
+					info.NitroTpmSupport = ec2types.NitroTpmSupportSupported
+				}
+				if info.InstanceType == "m5.xlarge" {
+					info.SupportedBootModes = []ec2types.BootModeType{ec2types.BootModeTypeLegacyBios, ec2types.BootModeTypeUefi}
+					info.NitroTpmSupport = ec2types.NitroTpmSupportUnsupported
+				}
+				return info
+			})
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: instances})
+			awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+				InstanceTypeOfferings: fake.MakeInstanceOfferings(instances),
+			})
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		})
+		It("should exclude instance types which don't support the uefi boot mode", func() {
+			nodeClass.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI)}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("m5.large", "m5.xlarge"))
+			incompatible, err := awsEnv.InstanceTypesProvider.IncompatibleInstanceTypes(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(incompatible).To(HaveKeyWithValue("c6g.large", "doesn't support the uefi boot mode"))
+		})
+		It("should exclude instance types which don't support NitroTPM", func() {
+			nodeClass.Spec.BootOptions = &v1.BootOptions{BootMode: lo.ToPtr(v1.BootModeUEFI), NitroTPM: lo.ToPtr(true)}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("m5.large"))
+			incompatible, err := awsEnv.InstanceTypesProvider.IncompatibleInstanceTypes(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(incompatible).To(HaveKeyWithValue("m5.xlarge", "doesn't support NitroTPM"))
+		})
+		It("should not exclude instance types when the boot options aren't set", func() {
+			incompatible, err := awsEnv.InstanceTypesProvider.IncompatibleInstanceTypes(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(incompatible).To(BeEmpty())
+		})
 	})
 	Context("Provider Cache", func() {
 		// Keeping the Cache testing in one IT block to validate the combinatorial expansion of instance types generated by different configs
@@ -2547,6 +3325,82 @@ var _ = Describe("InstanceTypeProvider", func() {
 			// Based on the nodeclass configuration, we expect to have 5 unique set of instance types
 			uniqueInstanceTypeList(instanceTypeResult)
 		})
+		It("should evict the least recently used instance types when the cache is full", func() {
+			instanceTypesCache := awscache.NewLRU(awscache.InstanceTypesCacheName, awscache.DefaultTTL, 2, 0, instancetype.EstimateInstanceTypesSize)
+			provider := instancetype.NewDefaultProvider(
+				instanceTypesCache,
+				cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval),
+				cache.New(awscache.DiscoveredCapacityCacheTTL, awscache.DefaultCleanupInterval),
+				awsEnv.EC2API,
+				awsEnv.SubnetProvider,
+				awsEnv.PricingProvider,
+				awsEnv.CapacityReservationProvider,
+				awsEnv.ReservedInstanceProvider,
+				awsEnv.UnavailableOfferingsCache,
+				awsEnv.InstanceTypesResolver,
+				awsEnv.AMIHashStore,
+				nil,
+			)
+			Expect(provider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(provider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+
+			// Each distinct max pods configuration resolves to its own set of instance types
+			nodeClasses := lo.Times(3, func(i int) *v1.EC2NodeClass {
+				nc := nodeClass.DeepCopy()
+				nc.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr(int32(10 + i))}
+				return nc
+			})
+			for i, nc := range nodeClasses {
+				_, err := provider.List(ctx, nc)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instanceTypesCache.ItemCount()).To(Equal(min(i+1, 2)))
+			}
+		})
+		It("should only drop the cache entries affected by an invalidation", func() {
+			instanceTypesCache := awscache.NewLRU(awscache.InstanceTypesCacheName, awscache.DefaultTTL, 0, 0, instancetype.EstimateInstanceTypesSize)
+			offeringCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+			invalidations := awscache.NewInvalidationBus()
+			unavailableOfferings := awscache.NewUnavailableOfferings(invalidations)
+			provider := instancetype.NewDefaultProvider(
+				instanceTypesCache,
+				offeringCache,
+				cache.New(awscache.DiscoveredCapacityCacheTTL, awscache.DefaultCleanupInterval),
+				awsEnv.EC2API,
+				awsEnv.SubnetProvider,
+				awsEnv.PricingProvider,
+				awsEnv.CapacityReservationProvider,
+				awsEnv.ReservedInstanceProvider,
+				unavailableOfferings,
+				awsEnv.InstanceTypesResolver,
+				awsEnv.AMIHashStore,
+				invalidations,
+			)
+			Expect(provider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(provider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+
+			nodeClasses := lo.Times(2, func(i int) *v1.EC2NodeClass {
+				nc := nodeClass.DeepCopy()
+				nc.Name = fmt.Sprintf("%s-%d", nodeClass.Name, i)
+				nc.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr(int32(10 + i))}
+				return nc
+			})
+			for _, nc := range nodeClasses {
+				_, err := provider.List(ctx, nc)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(instanceTypesCache.ItemCount()).To(Equal(2))
+			invalidations.Publish(awscache.Invalidation{NodeClass: nodeClasses[0].Name})
+			Expect(instanceTypesCache.ItemCount()).To(Equal(1))
+
+			hasOfferings := func(instanceType string) bool {
+				return lo.ContainsBy(lo.Keys(offeringCache.Items()), func(key string) bool { return strings.HasPrefix(key, instanceType+"/") })
+			}
+			Expect(hasOfferings("m5.large")).To(BeTrue())
+			Expect(hasOfferings("m5.xlarge")).To(BeTrue())
+			unavailableOfferings.MarkUnavailable(ctx, "InsufficientInstanceCapacity", "m5.large", "test-zone-1a", karpv1.CapacityTypeSpot)
+			Expect(hasOfferings("m5.large")).To(BeFalse())
+			Expect(hasOfferings("m5.xlarge")).To(BeTrue())
+		})
 	})
 	It("should not cause data races when calling List() simultaneously", func() {
 		mu := sync.RWMutex{}
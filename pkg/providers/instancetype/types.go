@@ -80,13 +80,23 @@ func (d *DefaultResolver) CacheKey(nodeClass *v1.EC2NodeClass) string {
 	kcHash, _ := hashstructure.Hash(kc, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	blockDeviceMappingsHash, _ := hashstructure.Hash(nodeClass.Spec.BlockDeviceMappings, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	capacityReservationHash, _ := hashstructure.Hash(nodeClass.Status.CapacityReservations, hashstructure.FormatV2, nil)
+	// Quantities are hashed as strings since hashstructure ignores their unexported fields
+	extendedResourcesHash, _ := hashstructure.Hash(lo.Map(nodeClass.Spec.ExtendedResources, func(e v1.ExtendedResource, _ int) []interface{} {
+		return []interface{}{e.InstanceFamilies, e.InstanceTypes, lo.MapValues(e.Resources, func(q resource.Quantity, _ corev1.ResourceName) string { return q.String() })}
+	}), hashstructure.FormatV2, nil)
 	return fmt.Sprintf(
-		"%016x-%016x-%016x-%s-%s",
+		"%016x-%016x-%016x-%016x-%s-%s-%d-%t-%t-%t-%d",
 		kcHash,
 		blockDeviceMappingsHash,
 		capacityReservationHash,
+		extendedResourcesHash,
 		lo.FromPtr((*string)(nodeClass.Spec.InstanceStorePolicy)),
 		nodeClass.AMIFamily(),
+		lo.FromPtr(nodeClass.ThreadsPerCore()),
+		nodeClass.RequiresUEFI(),
+		nodeClass.RequiresNitroTPM(),
+		nodeClass.IPv6Native(),
+		lo.FromPtr(lo.FromPtr(nodeClass.Spec.EFA).Count),
 	)
 }
 
@@ -99,7 +109,14 @@ func (d *DefaultResolver) Resolve(ctx context.Context, info ec2types.InstanceTyp
 	if nodeClass.Spec.Kubelet != nil {
 		kc = nodeClass.Spec.Kubelet
 	}
-	return NewInstanceType(
+	maxPods := kc.MaxPods
+	// The VPC CNI assigns prefixes, rather than individual addresses, to the network interfaces of IPv6 nodes, and of
+	// IPv4 nodes when prefix delegation is enabled
+	if maxPods == nil && (nodeClass.IPv6Native() || prefixDelegation(ctx, info)) && amifamily.GetAMIFamily(nodeClass.AMIFamily(), &amifamily.Options{}).FeatureFlags().SupportsENILimitedPodDensity {
+		//nolint:gosec
+		maxPods = lo.ToPtr(int32(PrefixDelegationPods(ctx, info).Value()))
+	}
+	it := NewInstanceType(
 		ctx,
 		info,
 		d.region,
@@ -107,7 +124,7 @@ func (d *DefaultResolver) Resolve(ctx context.Context, info ec2types.InstanceTyp
 		zonesToZoneIDs,
 		nodeClass.Spec.BlockDeviceMappings,
 		nodeClass.Spec.InstanceStorePolicy,
-		kc.MaxPods,
+		maxPods,
 		kc.PodsPerCore,
 		kc.KubeReserved,
 		kc.SystemReserved,
@@ -117,7 +134,18 @@ func (d *DefaultResolver) Resolve(ctx context.Context, info ec2types.InstanceTyp
 		lo.Filter(nodeClass.Status.CapacityReservations, func(cr v1.CapacityReservation, _ int) bool {
 			return cr.InstanceType == string(info.InstanceType)
 		}),
+		nodeClass.ThreadsPerCore(),
 	)
+	// Only the configured number of EFA interfaces are attached, so only as many EFA devices are advertised
+	if count := lo.FromPtr(lo.FromPtr(nodeClass.Spec.EFA).Count); count != 0 {
+		if efa := it.Capacity[v1.ResourceEFA]; efa.Value() > int64(count) {
+			it.Capacity[v1.ResourceEFA] = *resources.Quantity(fmt.Sprint(count))
+		}
+	}
+	for name, quantity := range nodeClass.ExtendedResourcesFor(string(info.InstanceType)) {
+		it.Capacity[name] = quantity
+	}
+	return it
 }
 
 func NewInstanceType(
@@ -136,14 +164,15 @@ func NewInstanceType(
 	evictionSoft map[string]string,
 	amiFamilyType string,
 	capacityReservations []v1.CapacityReservation,
+	threadsPerCore *int32,
 ) *cloudprovider.InstanceType {
 	amiFamily := amifamily.GetAMIFamily(amiFamilyType, &amifamily.Options{})
 	it := &cloudprovider.InstanceType{
 		Name:         string(info.InstanceType),
 		Requirements: computeRequirements(info, region, offeringZones, subnetZonesToZoneIDs, amiFamily, capacityReservations),
-		Capacity:     computeCapacity(ctx, info, amiFamily, blockDeviceMappings, instanceStorePolicy, maxPods, podsPerCore),
+		Capacity:     computeCapacity(ctx, info, amiFamily, blockDeviceMappings, instanceStorePolicy, maxPods, podsPerCore, threadsPerCore),
 		Overhead: &cloudprovider.InstanceTypeOverhead{
-			KubeReserved:      kubeReservedResources(cpu(info), pods(ctx, info, amiFamily, maxPods, podsPerCore), ENILimitedPods(ctx, info), amiFamily, kubeReserved),
+			KubeReserved:      kubeReservedResources(cpu(info, threadsPerCore), pods(ctx, info, amiFamily, maxPods, podsPerCore, threadsPerCore), ENILimitedPods(ctx, info), amiFamily, kubeReserved),
 			SystemReserved:    systemReservedResources(systemReserved),
 			EvictionThreshold: evictionThreshold(memory(ctx, info), ephemeralStorage(info, amiFamily, blockDeviceMappings, instanceStorePolicy), amiFamily, evictionHard, evictionSoft),
 		},
@@ -280,6 +309,19 @@ func computeRequirements(
 	if info.EbsInfo != nil && info.EbsInfo.EbsOptimizedInfo != nil && info.EbsInfo.EbsOptimizedSupport == ec2types.EbsOptimizedSupportDefault {
 		requirements.Get(v1.LabelInstanceEBSBandwidth).Insert(fmt.Sprint(lo.FromPtr(info.EbsInfo.EbsOptimizedInfo.MaximumBandwidthInMbps)))
 	}
+	// AMI capabilities. Unlike the labels above, these are left undefined when the instance type doesn't declare them,
+	// so that the requirements inferred from an AMI's capabilities don't exclude the instance type.
+	if len(info.SupportedBootModes) != 0 {
+		requirements.Add(scheduling.NewRequirement(v1.LabelInstanceBootMode, corev1.NodeSelectorOpIn, lo.Map(info.SupportedBootModes, func(m ec2types.BootModeType, _ int) string {
+			return string(m)
+		})...))
+	}
+	if info.NitroTpmSupport != "" {
+		requirements.Add(scheduling.NewRequirement(v1.LabelInstanceTPMSupported, corev1.NodeSelectorOpIn, fmt.Sprint(info.NitroTpmSupport == ec2types.NitroTpmSupportSupported)))
+	}
+	if info.NetworkInfo != nil && info.NetworkInfo.EnaSupport != "" {
+		requirements.Add(scheduling.NewRequirement(v1.LabelInstanceENASupport, corev1.NodeSelectorOpIn, string(info.NetworkInfo.EnaSupport)))
+	}
 	return requirements
 }
 
@@ -304,14 +346,14 @@ func getArchitecture(info ec2types.InstanceTypeInfo) string {
 
 func computeCapacity(ctx context.Context, info ec2types.InstanceTypeInfo, amiFamily amifamily.AMIFamily,
 	blockDeviceMapping []*v1.BlockDeviceMapping, instanceStorePolicy *v1.InstanceStorePolicy,
-	maxPods *int32, podsPerCore *int32) corev1.ResourceList {
+	maxPods *int32, podsPerCore *int32, threadsPerCore *int32) corev1.ResourceList {
 
 	resourceList := corev1.ResourceList{
-		corev1.ResourceCPU:              *cpu(info),
+		corev1.ResourceCPU:              *cpu(info, threadsPerCore),
 		corev1.ResourceMemory:           *memory(ctx, info),
 		corev1.ResourceEphemeralStorage: *ephemeralStorage(info, amiFamily, blockDeviceMapping, instanceStorePolicy),
-		corev1.ResourcePods:             *pods(ctx, info, amiFamily, maxPods, podsPerCore),
-		v1.ResourceAWSPodENI:            *awsPodENI(string(info.InstanceType)),
+		corev1.ResourcePods:             *pods(ctx, info, amiFamily, maxPods, podsPerCore, threadsPerCore),
+		v1.ResourceAWSPodENI:            *awsPodENI(ctx, string(info.InstanceType)),
 		v1.ResourceNVIDIAGPU:            *nvidiaGPUs(info),
 		v1.ResourceAMDGPU:               *amdGPUs(info),
 		v1.ResourceAWSNeuron:            *awsNeuronDevices(info),
@@ -322,7 +364,12 @@ func computeCapacity(ctx context.Context, info ec2types.InstanceTypeInfo, amiFam
 	return resourceList
 }
 
-func cpu(info ec2types.InstanceTypeInfo) *resource.Quantity {
+// cpu returns the number of CPUs the instance type's nodes have. When the number of threads per core is set, this is
+// the number of threads its cores run rather than its default number of vCPUs.
+func cpu(info ec2types.InstanceTypeInfo, threadsPerCore *int32) *resource.Quantity {
+	if threadsPerCore != nil && info.VCpuInfo.DefaultCores != nil {
+		return resources.Quantity(fmt.Sprint(*info.VCpuInfo.DefaultCores * *threadsPerCore))
+	}
 	return resources.Quantity(fmt.Sprint(*info.VCpuInfo.DefaultVCpus))
 }
 
@@ -376,16 +423,23 @@ func ephemeralStorage(info ec2types.InstanceTypeInfo, amiFamily amifamily.AMIFam
 	return amifamily.DefaultEBS.VolumeSize
 }
 
-// awsPodENI relies on the VPC resource controller to populate the vpc.amazonaws.com/pod-eni resource
-func awsPodENI(instanceTypeName string) *resource.Quantity {
+// awsPodENI relies on the VPC resource controller to populate the vpc.amazonaws.com/pod-eni resource, which it only
+// does when the VPC CNI's Pod ENI feature is enabled
+func awsPodENI(ctx context.Context, instanceTypeName string) *resource.Quantity {
 	// https://docs.aws.amazon.com/eks/latest/userguide/security-groups-for-pods.html#supported-instance-types
-	limits, ok := Limits[instanceTypeName]
-	if ok && limits.IsTrunkingCompatible {
-		return resources.Quantity(fmt.Sprint(limits.BranchInterface))
+	if trunkingCompatible(ctx, instanceTypeName) {
+		return resources.Quantity(fmt.Sprint(Limits[instanceTypeName].BranchInterface))
 	}
 	return resources.Quantity("0")
 }
 
+// trunkingCompatible returns true if the VPC resource controller attaches a trunk network interface to the instance
+// type's nodes, which the pod ENIs are attached to as branch network interfaces
+func trunkingCompatible(ctx context.Context, instanceTypeName string) bool {
+	limits, ok := Limits[instanceTypeName]
+	return options.FromContext(ctx).PodENI && ok && limits.IsTrunkingCompatible
+}
+
 func nvidiaGPUs(info ec2types.InstanceTypeInfo) *resource.Quantity {
 	count := int32(0)
 	if info.GpuInfo != nil {
@@ -458,7 +512,12 @@ func ENILimitedPods(ctx context.Context, info ec2types.InstanceTypeInfo) *resour
 	// VPC CNI only uses the default network interface
 	// https://github.com/aws/amazon-vpc-cni-k8s/blob/3294231c0dce52cfe473bf6c62f47956a3b333b6/scripts/gen_vpc_ip_limits.go#L162
 	networkInterfaces := *info.NetworkInfo.NetworkCards[*info.NetworkInfo.DefaultNetworkCardIndex].MaximumNetworkInterfaces
-	usableNetworkInterfaces := lo.Max([]int64{int64(int(networkInterfaces) - options.FromContext(ctx).ReservedENIs), 0})
+	reservedNetworkInterfaces := options.FromContext(ctx).ReservedENIs
+	// The trunk network interface can't be used by the VPC CNI to assign addresses to pods
+	if trunkingCompatible(ctx, string(info.InstanceType)) {
+		reservedNetworkInterfaces++
+	}
+	usableNetworkInterfaces := lo.Max([]int64{int64(int(networkInterfaces) - reservedNetworkInterfaces), 0})
 	if usableNetworkInterfaces == 0 {
 		return resource.NewQuantity(0, resource.DecimalSI)
 	}
@@ -466,6 +525,26 @@ func ENILimitedPods(ctx context.Context, info ec2types.InstanceTypeInfo) *resour
 	return resources.Quantity(fmt.Sprint(usableNetworkInterfaces*(int64(addressesPerInterface)-1) + 2))
 }
 
+// prefixDelegation returns true if the VPC CNI assigns prefixes to the instance type's network interfaces. Prefixes
+// are only assigned to the network interfaces of Nitro and bare metal instances.
+func prefixDelegation(ctx context.Context, info ec2types.InstanceTypeInfo) bool {
+	return options.FromContext(ctx).PrefixDelegation && (info.Hypervisor == ec2types.InstanceTypeHypervisorNitro || lo.FromPtr(info.BareMetal))
+}
+
+// PrefixDelegationPods returns the number of pods that the VPC CNI can assign addresses to when it assigns prefixes to
+// the instance's network interfaces, rather than individual addresses, as it does for IPv6 nodes. This is capped at the
+// kubelet's recommended maximum, since the prefixes hold more addresses than an instance can run pods.
+// https://github.com/awslabs/amazon-eks-ami/blob/main/templates/al2/runtime/max-pods-calculator.sh
+func PrefixDelegationPods(ctx context.Context, info ec2types.InstanceTypeInfo) *resource.Quantity {
+	eniLimitedPods := ENILimitedPods(ctx, info).Value()
+	if eniLimitedPods == 0 {
+		return resource.NewQuantity(0, resource.DecimalSI)
+	}
+	// Each address slot of the network interfaces holds a prefix of at least 16 addresses
+	count := (eniLimitedPods-2)*16 + 2
+	return resources.Quantity(fmt.Sprint(lo.Min([]int64{count, lo.Ternary[int64](lo.FromPtr(info.VCpuInfo.DefaultVCpus) < 30, 110, 250)})))
+}
+
 func privateIPv4Address(instanceTypeName string) *resource.Quantity {
 	//https://github.com/aws/amazon-vpc-resource-controller-k8s/blob/ecbd6965a0100d9a070110233762593b16023287/pkg/provider/ip/provider.go#L297
 	limits, ok := Limits[instanceTypeName]
@@ -545,7 +624,7 @@ func evictionThreshold(memory *resource.Quantity, storage *resource.Quantity, am
 	return lo.Assign(overhead, override)
 }
 
-func pods(ctx context.Context, info ec2types.InstanceTypeInfo, amiFamily amifamily.AMIFamily, maxPods *int32, podsPerCore *int32) *resource.Quantity {
+func pods(ctx context.Context, info ec2types.InstanceTypeInfo, amiFamily amifamily.AMIFamily, maxPods *int32, podsPerCore *int32, threadsPerCore *int32) *resource.Quantity {
 	var count int64
 	switch {
 	case maxPods != nil:
@@ -557,7 +636,7 @@ func pods(ctx context.Context, info ec2types.InstanceTypeInfo, amiFamily amifami
 
 	}
 	if lo.FromPtr(podsPerCore) > 0 && amiFamily.FeatureFlags().PodsPerCoreEnabled {
-		count = lo.Min([]int64{int64(lo.FromPtr(podsPerCore)) * int64(lo.FromPtr(info.VCpuInfo.DefaultVCpus)), count})
+		count = lo.Min([]int64{int64(lo.FromPtr(podsPerCore)) * cpu(info, threadsPerCore).Value(), count})
 	}
 	return resources.Quantity(fmt.Sprint(count))
 }
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -36,6 +37,8 @@ import (
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	karpoptions "sigs.k8s.io/karpenter/pkg/operator/options"
@@ -44,6 +47,7 @@ import (
 	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily/bootstrap"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
@@ -54,6 +58,9 @@ import (
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 )
 
+// efaOnlyInterfaceType is the interface type of EFA interfaces which only carry EFA traffic and have no IP addresses
+const efaOnlyInterfaceType = "efa-only"
+
 type Provider interface {
 	EnsureAll(context.Context, *v1.EC2NodeClass, *karpv1.NodeClaim,
 		[]*cloudprovider.InstanceType, string, map[string]string) ([]*LaunchTemplate, error)
@@ -64,9 +71,13 @@ type Provider interface {
 }
 type LaunchTemplate struct {
 	Name                  string
+	ID                    string
+	Version               int64
 	InstanceTypes         []*cloudprovider.InstanceType
 	ImageID               string
 	CapacityReservationID string
+	// Zone is set when the launch template can only launch into one zone
+	Zone string
 }
 
 type DefaultProvider struct {
@@ -83,11 +94,15 @@ type DefaultProvider struct {
 	ClusterEndpoint       string
 	ClusterCIDR           atomic.Pointer[string]
 	ClusterIPFamily       corev1.IPFamily
+	// kubeReader and namespace are used to read the Secrets referenced by registry mirrors, and the ConfigMap referenced by
+	// the trusted CA bundle
+	kubeReader client.Reader
+	namespace  string
 }
 
 func NewDefaultProvider(ctx context.Context, cache *cache.Cache, ec2api sdk.EC2API, eksapi sdk.EKSAPI, amiFamily amifamily.Resolver,
 	securityGroupProvider securitygroup.Provider, subnetProvider subnet.Provider,
-	caBundle *string, startAsync <-chan struct{}, kubeDNSIP net.IP, clusterEndpoint string) *DefaultProvider {
+	caBundle *string, startAsync <-chan struct{}, kubeDNSIP net.IP, clusterEndpoint string, kubeReader client.Reader, namespace string) *DefaultProvider {
 	l := &DefaultProvider{
 		ec2api:                ec2api,
 		eksapi:                eksapi,
@@ -100,6 +115,8 @@ func NewDefaultProvider(ctx context.Context, cache *cache.Cache, ec2api sdk.EC2A
 		KubeDNSIP:             kubeDNSIP,
 		ClusterEndpoint:       clusterEndpoint,
 		ClusterIPFamily:       lo.Ternary(kubeDNSIP != nil && kubeDNSIP.To4() == nil, corev1.IPv6Protocol, corev1.IPv4Protocol),
+		kubeReader:            kubeReader,
+		namespace:             namespace,
 	}
 	l.cache.OnEvicted(l.cachedEvictedFunc(ctx))
 	go func() {
@@ -132,7 +149,9 @@ func (p *DefaultProvider) EnsureAll(
 		return nil, err
 	}
 	var launchTemplates []*LaunchTemplate
-	for _, resolvedLaunchTemplate := range resolvedLaunchTemplates {
+	for _, resolvedLaunchTemplate := range lo.FlatMap(resolvedLaunchTemplates, func(lt *amifamily.LaunchTemplate, _ int) []*amifamily.LaunchTemplate {
+		return zonalLaunchTemplates(nodeClass, lt)
+	}) {
 		// Ensure the launch template exists, or create it
 		ec2LaunchTemplate, err := p.ensureLaunchTemplate(ctx, resolvedLaunchTemplate)
 		if err != nil {
@@ -140,14 +159,63 @@ func (p *DefaultProvider) EnsureAll(
 		}
 		launchTemplates = append(launchTemplates, &LaunchTemplate{
 			Name:                  *ec2LaunchTemplate.LaunchTemplateName,
+			ID:                    lo.FromPtr(ec2LaunchTemplate.LaunchTemplateId),
+			Version:               lo.FromPtr(ec2LaunchTemplate.LatestVersionNumber),
 			InstanceTypes:         resolvedLaunchTemplate.InstanceTypes,
 			ImageID:               resolvedLaunchTemplate.AMIID,
 			CapacityReservationID: resolvedLaunchTemplate.CapacityReservationID,
+			Zone:                  resolvedLaunchTemplate.Zone,
 		})
 	}
 	return launchTemplates, nil
 }
 
+// zonalLaunchTemplates returns a copy of the launch template for each zone when the EC2NodeClass has additional network
+// interfaces. The subnet of the primary network interface is set by the fleet's overrides, but the subnets of the others
+// are set in the launch template, so the launch template can only launch into their zone.
+func zonalLaunchTemplates(nodeClass *v1.EC2NodeClass, launchTemplate *amifamily.LaunchTemplate) []*amifamily.LaunchTemplate {
+	if len(nodeClass.Status.NetworkInterfaces) == 0 {
+		return []*amifamily.LaunchTemplate{launchTemplate}
+	}
+	zones := lo.Uniq(lo.Map(nodeClass.Status.Subnets, func(s v1.Subnet, _ int) string { return s.Zone }))
+	sort.Strings(zones)
+	var launchTemplates []*amifamily.LaunchTemplate
+	for _, zone := range zones {
+		networkInterfaces, ok := networkInterfacesInZone(nodeClass.Status.NetworkInterfaces, zone, launchTemplate.SecurityGroups)
+		if !ok {
+			continue
+		}
+		zonal := *launchTemplate
+		zonal.Zone = zone
+		zonal.NetworkInterfaces = networkInterfaces
+		launchTemplates = append(launchTemplates, &zonal)
+	}
+	return launchTemplates
+}
+
+// networkInterfacesInZone returns the additional network interfaces in the subnet of each with the most available IP
+// addresses in the zone, or false if one of them doesn't have a subnet in the zone. Network interfaces without security
+// groups of their own use the node's security groups.
+func networkInterfacesInZone(statuses []v1.NetworkInterfaceStatus, zone string, securityGroups []v1.SecurityGroup) ([]amifamily.NetworkInterface, bool) {
+	var networkInterfaces []amifamily.NetworkInterface
+	for _, status := range statuses {
+		// The subnets in the status are ordered by their available IP addresses
+		subnet, ok := lo.Find(status.Subnets, func(s v1.Subnet) bool { return s.Zone == zone })
+		if !ok {
+			return nil, false
+		}
+		groups := lo.Ternary(len(status.SecurityGroups) != 0, status.SecurityGroups, securityGroups)
+		for i := range status.Count {
+			networkInterfaces = append(networkInterfaces, amifamily.NetworkInterface{
+				DeviceIndex:      status.DeviceIndex + i,
+				SubnetID:         subnet.ID,
+				SecurityGroupIDs: lo.Map(groups, func(s v1.SecurityGroup, _ int) string { return s.ID }),
+			})
+		}
+	}
+	return networkInterfaces, true
+}
+
 // InvalidateCache deletes a launch template from cache if it exists
 func (p *DefaultProvider) InvalidateCache(ctx context.Context, ltName string, ltID string) {
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("launch-template-name", ltName, "launch-template-id", ltID))
@@ -180,22 +248,91 @@ func (p *DefaultProvider) CreateAMIOptions(ctx context.Context, nodeClass *v1.EC
 	if len(nodeClass.Status.SecurityGroups) == 0 {
 		return nil, fmt.Errorf("no security groups are present in the status")
 	}
+	registryMirrors, err := p.resolveRegistryMirrors(ctx, nodeClass)
+	if err != nil {
+		return nil, err
+	}
+	trustedCABundle, err := p.resolveTrustedCABundle(ctx, nodeClass)
+	if err != nil {
+		return nil, err
+	}
 	return &amifamily.Options{
 		ClusterName:              options.FromContext(ctx).ClusterName,
 		ClusterEndpoint:          p.ClusterEndpoint,
 		ClusterCIDR:              p.ClusterCIDR.Load(),
 		InstanceProfile:          nodeClass.Status.InstanceProfile,
 		InstanceStorePolicy:      nodeClass.Spec.InstanceStorePolicy,
+		BottlerocketSettings:     nodeClass.Spec.BottlerocketSettings,
+		NodeConfig:               nodeClass.Spec.NodeConfig,
+		KubeletConfigDropIns:     nodeClass.Spec.KubeletConfigDropIns,
+		RegistryMirrors:          registryMirrors,
+		Proxy:                    nodeClass.Spec.Proxy,
+		TrustedCABundle:          trustedCABundle,
+		Swap:                     nodeClass.Spec.Swap,
+		BootstrapMode:            lo.FromPtr(nodeClass.Spec.BootstrapMode),
+		UserDataMergeOrder:       lo.FromPtr(nodeClass.Spec.UserDataMergeOrder),
+		DeviceValidation:         nodeClass.Spec.DeviceValidation,
 		SecurityGroups:           nodeClass.Status.SecurityGroups,
 		Tags:                     tags,
 		Labels:                   labels,
 		CABundle:                 p.CABundle,
 		KubeDNSIP:                p.KubeDNSIP,
 		AssociatePublicIPAddress: nodeClass.Spec.AssociatePublicIPAddress,
+		IPv6Native:               nodeClass.IPv6Native(),
 		NodeClassName:            nodeClass.Name,
 	}, nil
 }
 
+// resolveRegistryMirrors reads the credentials of the EC2NodeClass's registry mirrors from their Secrets
+func (p *DefaultProvider) resolveRegistryMirrors(ctx context.Context, nodeClass *v1.EC2NodeClass) ([]bootstrap.RegistryMirror, error) {
+	var mirrors []bootstrap.RegistryMirror
+	for _, m := range nodeClass.Spec.RegistryMirrors {
+		mirror := bootstrap.RegistryMirror{Registry: m.Registry, Endpoints: m.Endpoints}
+		if m.CredentialsSecretName != nil {
+			if p.kubeReader == nil {
+				return nil, fmt.Errorf("reading credentials for registry %q, secrets can't be read", m.Registry)
+			}
+			secret := &corev1.Secret{}
+			if err := p.kubeReader.Get(ctx, types.NamespacedName{Namespace: p.namespace, Name: *m.CredentialsSecretName}, secret); err != nil {
+				return nil, fmt.Errorf("reading credentials for registry %q, %w", m.Registry, err)
+			}
+			username, password := string(secret.Data["username"]), string(secret.Data["password"])
+			if username == "" || password == "" {
+				return nil, fmt.Errorf("reading credentials for registry %q, secret %q must contain username and password keys", m.Registry, *m.CredentialsSecretName)
+			}
+			mirror.Username, mirror.Password = username, password
+		}
+		mirrors = append(mirrors, mirror)
+	}
+	return mirrors, nil
+}
+
+// resolveTrustedCABundle returns the certificates of the EC2NodeClass's trusted CA bundle, reading them from its
+// ConfigMap if it references one
+func (p *DefaultProvider) resolveTrustedCABundle(ctx context.Context, nodeClass *v1.EC2NodeClass) (string, error) {
+	if nodeClass.Spec.TrustedCABundle == nil {
+		return "", nil
+	}
+	bundle := lo.FromPtr(nodeClass.Spec.TrustedCABundle.PEM)
+	if name := nodeClass.Spec.TrustedCABundle.ConfigMapName; name != nil {
+		if p.kubeReader == nil {
+			return "", fmt.Errorf("reading trusted CA bundle, configmaps can't be read")
+		}
+		configMap := &corev1.ConfigMap{}
+		if err := p.kubeReader.Get(ctx, types.NamespacedName{Namespace: p.namespace, Name: *name}, configMap); err != nil {
+			return "", fmt.Errorf("reading trusted CA bundle, %w", err)
+		}
+		if bundle = configMap.Data["ca-bundle.crt"]; bundle == "" {
+			return "", fmt.Errorf("reading trusted CA bundle, configmap %q must contain a ca-bundle.crt key", *name)
+		}
+	}
+	bundle, err := bootstrap.ParseTrustedCABundle(bundle)
+	if err != nil {
+		return "", fmt.Errorf("reading trusted CA bundle, %w", err)
+	}
+	return bundle, nil
+}
+
 func (p *DefaultProvider) ensureLaunchTemplate(ctx context.Context, options *amifamily.LaunchTemplate) (ec2types.LaunchTemplate, error) {
 	var launchTemplate ec2types.LaunchTemplate
 	name := LaunchTemplateName(options)
@@ -267,13 +404,16 @@ func GetCreateLaunchTemplateInput(
 			Monitoring: &ec2types.LaunchTemplatesMonitoringRequest{
 				Enabled: aws.Bool(options.DetailedMonitoring),
 			},
+			// The core count is left to the instance type's default, since the launch template is shared between instance types
+			CpuOptions: lo.Ternary(options.ThreadsPerCore != nil, &ec2types.LaunchTemplateCpuOptionsRequest{ThreadsPerCore: options.ThreadsPerCore}, nil),
 			// If the network interface is defined, the security groups are defined within it
 			SecurityGroupIds: lo.Ternary(networkInterfaces != nil, nil, lo.Map(options.SecurityGroups, func(s v1.SecurityGroup, _ int) string { return s.ID })),
 			UserData:         aws.String(userData),
 			ImageId:          aws.String(options.AMIID),
 			MetadataOptions: &ec2types.LaunchTemplateInstanceMetadataOptionsRequest{
-				HttpEndpoint:     ec2types.LaunchTemplateInstanceMetadataEndpointState(lo.FromPtr(options.MetadataOptions.HTTPEndpoint)),
-				HttpProtocolIpv6: ec2types.LaunchTemplateInstanceMetadataProtocolIpv6(lo.FromPtr(options.MetadataOptions.HTTPProtocolIPv6)),
+				HttpEndpoint: ec2types.LaunchTemplateInstanceMetadataEndpointState(lo.FromPtr(options.MetadataOptions.HTTPEndpoint)),
+				// Instances in IPv6-only subnets can only reach the instance metadata service over its IPv6 endpoint
+				HttpProtocolIpv6: lo.Ternary(options.IPv6Native, ec2types.LaunchTemplateInstanceMetadataProtocolIpv6Enabled, ec2types.LaunchTemplateInstanceMetadataProtocolIpv6(lo.FromPtr(options.MetadataOptions.HTTPProtocolIPv6))),
 				//Will be removed when we update options.MetadataOptions.HTTPPutResponseHopLimit type to be int32
 				//nolint: gosec
 				HttpPutResponseHopLimit: lo.ToPtr(int32(lo.FromPtr(options.MetadataOptions.HTTPPutResponseHopLimit))),
@@ -295,6 +435,7 @@ func GetCreateLaunchTemplateInput(
 			},
 		},
 	}
+	lt.LaunchTemplateData.PrivateDnsNameOptions = privateDNSNameOptions(options)
 	// Gate this specifically since the update to CapacityReservationPreference will opt od / spot launches out of open
 	// ODCRs, which is a breaking change from the pre-native ODCR support behavior.
 	if karpoptions.FromContext(ctx).FeatureGates.ReservedCapacity {
@@ -316,10 +457,67 @@ func GetCreateLaunchTemplateInput(
 	return lt
 }
 
+// privateDNSNameOptions returns the private DNS name options of the launch template, which are only set when they
+// differ from the subnet's. Instances in IPv6-only subnets don't have an IPv4 address to derive an ip-name hostname from,
+// so they default to resource-name hostnames with an AAAA record. The options of the EC2NodeClass take precedence.
+func privateDNSNameOptions(options *amifamily.LaunchTemplate) *ec2types.LaunchTemplatePrivateDnsNameOptionsRequest {
+	var dnsNameOptions *ec2types.LaunchTemplatePrivateDnsNameOptionsRequest
+	if options.IPv6Native {
+		dnsNameOptions = &ec2types.LaunchTemplatePrivateDnsNameOptionsRequest{
+			HostnameType:                    ec2types.HostnameTypeResourceName,
+			EnableResourceNameDnsAAAARecord: lo.ToPtr(true),
+		}
+	}
+	nodeClassOptions := options.PrivateDNSNameOptions
+	if nodeClassOptions == nil || lo.FromPtr(nodeClassOptions) == (v1.PrivateDNSNameOptions{}) {
+		return dnsNameOptions
+	}
+	if dnsNameOptions == nil {
+		dnsNameOptions = &ec2types.LaunchTemplatePrivateDnsNameOptionsRequest{}
+	}
+	if nodeClassOptions.HostnameType != nil {
+		dnsNameOptions.HostnameType = ec2types.HostnameType(lo.FromPtr(nodeClassOptions.HostnameType))
+	}
+	if nodeClassOptions.EnableResourceNameDNSARecord != nil {
+		dnsNameOptions.EnableResourceNameDnsARecord = nodeClassOptions.EnableResourceNameDNSARecord
+	}
+	if nodeClassOptions.EnableResourceNameDNSAAAARecord != nil {
+		dnsNameOptions.EnableResourceNameDnsAAAARecord = nodeClassOptions.EnableResourceNameDNSAAAARecord
+	}
+	return dnsNameOptions
+}
+
 // generateNetworkInterfaces generates network interfaces for the launch template.
 func generateNetworkInterfaces(options *amifamily.LaunchTemplate, clusterIPFamily corev1.IPFamily) []ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+	ipv6 := clusterIPFamily == corev1.IPv6Protocol || options.IPv6Native
+	// Instances in IPv6-only subnets can't be assigned a public IPv4 address
+	associatePublicIPAddress := lo.Ternary(options.IPv6Native, nil, options.AssociatePublicIPAddress)
+	// IPv6 clusters need an IPv6 address for the primary IP of the node, while other clusters only get the IPv6 addresses
+	// that the EC2NodeClass asks for
+	ipv6AddressCount := lo.Ternary(ipv6, lo.ToPtr(int32(1)), nil)
+	if options.IPv6AddressCount != nil {
+		ipv6AddressCount = options.IPv6AddressCount
+	}
+	// Additional network interfaces are attached to the first network card, after the primary network interface
+	additional := lo.Map(options.NetworkInterfaces, func(ni amifamily.NetworkInterface, _ int) ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+		return ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+			DeviceIndex: lo.ToPtr(ni.DeviceIndex),
+			SubnetId:    lo.ToPtr(ni.SubnetID),
+			Groups:      ni.SecurityGroupIDs,
+		}
+	})
 	if options.EFACount != 0 {
-		return lo.Times(options.EFACount, func(i int) ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+		return append(lo.Times(options.EFACount, func(i int) ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest {
+			// EFA-only interfaces don't have IP addresses, so they're only attached to the secondary network cards
+			if options.EFAOnly && i != 0 {
+				return ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+					//nolint: gosec
+					NetworkCardIndex: lo.ToPtr(int32(i)),
+					DeviceIndex:      lo.ToPtr(int32(1)),
+					InterfaceType:    lo.ToPtr(efaOnlyInterfaceType),
+					Groups:           lo.Map(options.SecurityGroups, func(s v1.SecurityGroup, _ int) string { return s.ID }),
+				}
+			}
 			return ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
 				//nolint: gosec
 				NetworkCardIndex: lo.ToPtr(int32(i)),
@@ -329,24 +527,27 @@ func generateNetworkInterfaces(options *amifamily.LaunchTemplate, clusterIPFamil
 				Groups:        lo.Map(options.SecurityGroups, func(s v1.SecurityGroup, _ int) string { return s.ID }),
 				// Instances launched with multiple pre-configured network interfaces cannot set AssociatePublicIPAddress to true. This is an EC2 limitation. However, this does not apply for instances
 				// with a single EFA network interface, and we should support those use cases. Launch failures with multiple enis should be considered user misconfiguration.
-				AssociatePublicIpAddress: options.AssociatePublicIPAddress,
-				PrimaryIpv6:              lo.Ternary(clusterIPFamily == corev1.IPv6Protocol, lo.ToPtr(true), nil),
-				Ipv6AddressCount:         lo.Ternary(clusterIPFamily == corev1.IPv6Protocol, lo.ToPtr(int32(1)), nil),
+				AssociatePublicIpAddress: associatePublicIPAddress,
+				PrimaryIpv6:              lo.Ternary(ipv6, lo.ToPtr(true), nil),
+				// The IPv6 addresses and prefixes of the EC2NodeClass are only assigned to the primary network interface
+				Ipv6AddressCount: lo.Ternary(i == 0, ipv6AddressCount, lo.Ternary(ipv6, lo.ToPtr(int32(1)), nil)),
+				Ipv6PrefixCount:  lo.Ternary(i == 0, options.IPv6PrefixCount, nil),
 			}
-		})
+		}), additional...)
 	}
 
-	return []ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+	return append([]ec2types.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
 		{
-			AssociatePublicIpAddress: options.AssociatePublicIPAddress,
+			AssociatePublicIpAddress: associatePublicIPAddress,
 			DeviceIndex:              aws.Int32(0),
 			Groups: lo.Map(options.SecurityGroups, func(s v1.SecurityGroup, _ int) string {
 				return s.ID
 			}),
-			PrimaryIpv6:      lo.Ternary(clusterIPFamily == corev1.IPv6Protocol, lo.ToPtr(true), nil),
-			Ipv6AddressCount: lo.Ternary(clusterIPFamily == corev1.IPv6Protocol, lo.ToPtr(int32(1)), nil),
+			PrimaryIpv6:      lo.Ternary(ipv6, lo.ToPtr(true), nil),
+			Ipv6AddressCount: ipv6AddressCount,
+			Ipv6PrefixCount:  options.IPv6PrefixCount,
 		},
-	}
+	}, additional...)
 }
 
 func blockDeviceMappings(blockDeviceMappings []*v1.BlockDeviceMapping) []ec2types.LaunchTemplateBlockDeviceMappingRequest {
@@ -438,27 +639,30 @@ func (p *DefaultProvider) DeleteAll(ctx context.Context, nodeClass *v1.EC2NodeCl
 	clusterName := options.FromContext(ctx).ClusterName
 	var ltNames []*string
 
-	paginator := ec2.NewDescribeLaunchTemplatesPaginator(p.ec2api, &ec2.DescribeLaunchTemplatesInput{
-		Filters: []ec2types.Filter{
-			{
-				Name:   aws.String(fmt.Sprintf("tag:%s", v1.EKSClusterNameTagKey)),
-				Values: []string{clusterName},
-			},
-			{
-				Name:   aws.String(fmt.Sprintf("tag:%s", v1.NodeClassTagKey)),
-				Values: []string{nodeClass.Name},
+	// Launch templates created before the tag key prefix was configured are tagged with the unprefixed key
+	for _, tagKey := range options.FromContext(ctx).TagKeys(v1.NodeClassTagKey) {
+		paginator := ec2.NewDescribeLaunchTemplatesPaginator(p.ec2api, &ec2.DescribeLaunchTemplatesInput{
+			Filters: []ec2types.Filter{
+				{
+					Name:   aws.String(fmt.Sprintf("tag:%s", v1.EKSClusterNameTagKey)),
+					Values: []string{clusterName},
+				},
+				{
+					Name:   aws.String(fmt.Sprintf("tag:%s", tagKey)),
+					Values: []string{nodeClass.Name},
+				},
 			},
-		},
-	})
+		})
 
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
-		if err != nil {
-			return fmt.Errorf("fetching launch templates, %w", err)
-		}
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return fmt.Errorf("fetching launch templates, %w", err)
+			}
 
-		for _, lt := range page.LaunchTemplates {
-			ltNames = append(ltNames, lt.LaunchTemplateName)
+			for _, lt := range page.LaunchTemplates {
+				ltNames = append(ltNames, lt.LaunchTemplateName)
+			}
 		}
 	}
 
@@ -16,9 +16,16 @@ package launchtemplate_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
 	"net"
 	"os"
 	"strconv"
@@ -39,6 +46,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -99,7 +107,7 @@ var _ = BeforeSuite(func() {
 	fakeClock = &clock.FakeClock{}
 	recorder = events.NewRecorder(&record.FakeRecorder{})
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, recorder,
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CapacityReservationProvider, awsEnv.Clock)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, recorder, cloudProvider, cluster, fakeClock)
 })
@@ -116,6 +124,7 @@ var _ = BeforeEach(func() {
 	awsEnv.Reset()
 
 	awsEnv.LaunchTemplateProvider.KubeDNSIP = net.ParseIP("10.0.100.10")
+	awsEnv.LaunchTemplateProvider.ClusterIPFamily = corev1.IPv4Protocol
 	awsEnv.LaunchTemplateProvider.ClusterEndpoint = "https://test-cluster"
 	awsEnv.LaunchTemplateProvider.CABundle = lo.ToPtr("ca-bundle")
 })
@@ -283,6 +292,23 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			}
 		})
 	})
+	It("should encrypt volumes which leave encryption unset when the account encrypts volumes by default", func() {
+		nodeClass.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{
+			{DeviceName: lo.ToPtr("/dev/xvda"), EBS: &v1.BlockDevice{VolumeSize: lo.ToPtr(resource.MustParse("20Gi"))}},
+			{DeviceName: lo.ToPtr("/dev/xvdb"), EBS: &v1.BlockDevice{VolumeSize: lo.ToPtr(resource.MustParse("20Gi")), Encrypted: lo.ToPtr(false)}},
+		}
+		nodeClass.Status.AccountDefaults = &v1.AccountDefaults{EBSEncryptionByDefault: lo.ToPtr(true)}
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod()
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectScheduled(ctx, env.Client, pod)
+		Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+		awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+			Expect(ltInput.LaunchTemplateData.BlockDeviceMappings).To(HaveLen(2))
+			Expect(ltInput.LaunchTemplateData.BlockDeviceMappings[0].Ebs.Encrypted).To(Equal(aws.Bool(true)))
+			Expect(ltInput.LaunchTemplateData.BlockDeviceMappings[1].Ebs.Encrypted).To(Equal(aws.Bool(false)))
+		})
+	})
 	It("should default to a generated launch template", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 		pod := coretest.UnschedulablePod()
@@ -598,6 +624,24 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			Expect(createFleetInput.TagSpecifications[2].ResourceType).To(Equal(ec2types.ResourceTypeFleet))
 			ExpectTags(createFleetInput.TagSpecifications[2].Tags, nodeClass.Spec.Tags)
 		})
+		It("should apply the tag key prefix to ownership tags", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				TagKeyPrefix: lo.ToPtr("example.com/karpenter"),
+			}))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			for _, tagSpecification := range createFleetInput.TagSpecifications {
+				ExpectTags(tagSpecification.Tags, map[string]string{
+					"example.com/karpenter/nodepool":     nodePool.Name,
+					"example.com/karpenter/ec2nodeclass": nodeClass.Name,
+				})
+				Expect(tagSpecification.Tags).ToNot(ContainElement(HaveField("Key", HaveValue(BeElementOf(v1.NodePoolTagKey, v1.NodeClassTagKey)))))
+			}
+		})
 	})
 	Context("Block Device Mappings", func() {
 		It("should default AL2 block device mappings", func() {
@@ -1028,6 +1072,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				nodeClass.Spec.Kubelet.EvictionSoft,
 				nodeClass.AMIFamily(),
 				nil,
+				nil,
 			)
 
 			overhead := it.Overhead.Total()
@@ -1081,6 +1126,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				nodeClass.Spec.Kubelet.EvictionSoft,
 				nodeClass.AMIFamily(),
 				nil,
+				nil,
 			)
 
 			overhead := it.Overhead.Total()
@@ -1108,6 +1154,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				nodeClass.Spec.Kubelet.EvictionSoft,
 				nodeClass.AMIFamily(),
 				nil,
+				nil,
 			)
 			overhead := it.Overhead.Total()
 			Expect(overhead.Memory().String()).To(Equal("1565Mi"))
@@ -1402,6 +1449,39 @@ mode = 'always'
 essential = true
 `)
 		})
+		It("should move containerd and kubelet state to the instance store when instance-store policy is set on Windows", func() {
+			nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelOSStable, Operator: corev1.NodeSelectorOpIn, Values: []string{string(corev1.Windows)}}}}
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2022@latest"}}
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyRAID0)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				NodeSelector: map[string]string{
+					corev1.LabelOSStable:     string(corev1.Windows),
+					corev1.LabelWindowsBuild: "10.0.20348",
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(
+				`New-VirtualDisk -StoragePoolFriendlyName "InstanceStore"`,
+				`foreach ($StateDir in @("$env:ProgramData\containerd", "C:\var\lib\kubelet"))`,
+				"New-Item -ItemType Junction",
+			)
+		})
+		It("should not configure the instance store when instance-store policy isn't set on Windows", func() {
+			nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelOSStable, Operator: corev1.NodeSelectorOpIn, Values: []string{string(corev1.Windows)}}}}
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2022@latest"}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				NodeSelector: map[string]string{
+					corev1.LabelOSStable:     string(corev1.Windows),
+					corev1.LabelWindowsBuild: "10.0.20348",
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("InstanceStore")
+		})
 		It("should merge bootstrap-commands when instance-store policy is set on Bottlerocket", func() {
 			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
 			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyRAID0)
@@ -1485,6 +1565,89 @@ essential = true
 				// This will not be scheduled since userData cannot be generated for the prospective node.
 				ExpectNotScheduled(ctx, env.Client, pod)
 			})
+			It("should merge bottlerocketSettings into user data", func() {
+				nodeClass.Spec.UserData = aws.String(`
+[settings.host-containers.admin]
+enabled = true
+`)
+				nodeClass.Spec.BottlerocketSettings = &v1.BottlerocketSettings{JSON: apiextensionsv1.JSON{Raw: []byte(
+					`{"kernel":{"sysctl":{"net.core.somaxconn":"1024"}},"host-containers":{"admin":{"enabled":true},"control":{"enabled":false}}}`,
+				)}}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+					Expect(err).To(BeNil())
+					config := &bootstrap.BottlerocketConfig{}
+					Expect(config.UnmarshalTOML(userData)).To(Succeed())
+					Expect(config.SettingsRaw).To(HaveKeyWithValue("kernel", map[string]interface{}{"sysctl": map[string]interface{}{"net.core.somaxconn": "1024"}}))
+					Expect(config.SettingsRaw).To(HaveKeyWithValue("host-containers", map[string]interface{}{
+						"admin":   map[string]interface{}{"enabled": true},
+						"control": map[string]interface{}{"enabled": false},
+					}))
+					Expect(config.Settings.Kubernetes.MaxPods).To(Equal(lo.ToPtr(110)))
+				})
+			})
+			It("should not bootstrap when bottlerocketSettings conflict with user data", func() {
+				nodeClass.Spec.UserData = aws.String(`
+[settings.host-containers.admin]
+enabled = false
+`)
+				nodeClass.Spec.BottlerocketSettings = &v1.BottlerocketSettings{JSON: apiextensionsv1.JSON{Raw: []byte(`{"host-containers":{"admin":{"enabled":true}}}`)}}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+			})
+			It("should not bootstrap when bottlerocketSettings set settings managed by Karpenter", func() {
+				nodeClass.Spec.BottlerocketSettings = &v1.BottlerocketSettings{JSON: apiextensionsv1.JSON{Raw: []byte(`{"kubernetes":{"max-pods":20}}`)}}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+			})
+			It("should render kubelet config drop-ins as settings in user data", func() {
+				nodeClass.Spec.UserData = aws.String(`
+[settings.kubernetes]
+shutdown-grace-period = "10s"
+`)
+				nodeClass.Spec.KubeletConfigDropIns = []v1.KubeletConfigDropIn{
+					{Name: "logs", Config: map[string]apiextensionsv1.JSON{
+						"containerLogMaxSize":  {Raw: []byte(`"50Mi"`)},
+						"containerLogMaxFiles": {Raw: []byte(`10`)},
+						"shutdownGracePeriod":  {Raw: []byte(`"30s"`)},
+					}},
+					{Name: "shutdown", Config: map[string]apiextensionsv1.JSON{
+						"shutdownGracePeriod": {Raw: []byte(`"60s"`)},
+					}},
+				}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+					Expect(err).To(BeNil())
+					config := &bootstrap.BottlerocketConfig{}
+					Expect(config.UnmarshalTOML(userData)).To(Succeed())
+					Expect(config.Settings.Kubernetes.ContainerLogMaxSize).To(Equal(lo.ToPtr("50Mi")))
+					Expect(config.Settings.Kubernetes.ContainerLogMaxFiles).To(Equal(lo.ToPtr(10)))
+					Expect(config.Settings.Kubernetes.ShutdownGracePeriod).To(Equal(lo.ToPtr("60s")))
+				})
+			})
+			It("should not bootstrap when kubelet config drop-ins set fields Bottlerocket doesn't support", func() {
+				nodeClass.Spec.KubeletConfigDropIns = []v1.KubeletConfigDropIn{
+					{Name: "swap", Config: map[string]apiextensionsv1.JSON{"failSwapOn": {Raw: []byte(`false`)}}},
+				}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+			})
 			It("should override system reserved values in user data", func() {
 				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{
 					SystemReserved: map[string]string{
@@ -1692,6 +1855,111 @@ essential = true
 				expectedUserData := fmt.Sprintf(string(content), nodeClass.Name, karpv1.NodePoolLabelKey, nodePool.Name)
 				ExpectLaunchTemplatesCreatedWithUserData(expectedUserData)
 			})
+			It("should merge in custom user data after the bootstrap script", func() {
+				nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho custom\n")
+				nodeClass.Spec.UserDataMergeOrder = lo.ToPtr(v1.UserDataMergeOrderAfterBootstrap)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					userData, err := base64.StdEncoding.DecodeString(*input.LaunchTemplateData.UserData)
+					Expect(err).To(BeNil())
+					Expect(strings.Index(string(userData), "echo custom")).To(BeNumerically(">", strings.Index(string(userData), "/etc/eks/bootstrap.sh")))
+				})
+			})
+			It("should merge in custom user data parts in the order set by their merge order header", func() {
+				nodeClass.Spec.UserData = aws.String(`MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: text/x-shellscript; charset="us-ascii"
+X-Karpenter-Merge-Order: AfterBootstrap
+
+#!/bin/bash
+echo after
+
+--BOUNDARY
+Content-Type: text/x-shellscript; charset="us-ascii"
+
+#!/bin/bash
+echo before
+
+--BOUNDARY--
+`)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					userData, err := base64.StdEncoding.DecodeString(*input.LaunchTemplateData.UserData)
+					Expect(err).To(BeNil())
+					bootstrap := strings.Index(string(userData), "/etc/eks/bootstrap.sh")
+					Expect(strings.Index(string(userData), "echo before")).To(BeNumerically("<", bootstrap))
+					Expect(strings.Index(string(userData), "echo after")).To(BeNumerically(">", bootstrap))
+					Expect(string(userData)).ToNot(ContainSubstring("X-Karpenter-Merge-Order"))
+				})
+			})
+			It("should render templated custom user data", func() {
+				nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho {{ .ClusterName }} {{ .ClusterEndpoint }} {{ .NodeClass }} {{ .NodePool }} {{ index .Labels \"karpenter.sh/nodepool\" }} {{ index .Labels \"unknown\" }}\n")
+				nodeClass.Spec.TemplatedUserData = lo.ToPtr(true)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					userData, err := base64.StdEncoding.DecodeString(*input.LaunchTemplateData.UserData)
+					Expect(err).To(BeNil())
+					Expect(string(userData)).To(ContainSubstring(fmt.Sprintf("echo %s %s %s %s %s \n", options.FromContext(ctx).ClusterName, "https://test-cluster", nodeClass.Name, nodePool.Name, nodePool.Name)))
+				})
+			})
+			It("should not render custom user data that isn't templated", func() {
+				nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho {{ .ClusterName }}\n")
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					userData, err := base64.StdEncoding.DecodeString(*input.LaunchTemplateData.UserData)
+					Expect(err).To(BeNil())
+					Expect(string(userData)).To(ContainSubstring("echo {{ .ClusterName }}"))
+				})
+			})
+			It("should validate devices after custom user data and before the bootstrap script when device validation is specified", func() {
+				nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho custom\n")
+				nodeClass.Spec.DeviceValidation = &v1.DeviceValidation{
+					Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceGPU, v1.DeviceValidationDeviceEFA},
+					Timeout: &metav1.Duration{Duration: 10 * time.Minute},
+				}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					Expect(userData).To(ContainSubstring("deadline=$((SECONDS+600))"))
+					Expect(userData).To(ContainSubstring("GPU devices aren't available"))
+					Expect(userData).To(ContainSubstring("EFA devices aren't available"))
+					validation := strings.Index(userData, bootstrap.DeviceValidationFailedMessage)
+					Expect(strings.Index(userData, "echo custom")).To(BeNumerically("<", validation))
+					Expect(strings.Index(userData, "/etc/eks/bootstrap.sh")).To(BeNumerically(">", validation))
+				}
+			})
+			It("should validate instance store devices before the bootstrap script sets up RAID0", func() {
+				nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyRAID0)
+				nodeClass.Spec.DeviceValidation = &v1.DeviceValidation{Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceNVMe}}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					Expect(userData).To(ContainSubstring("--local-disks raid0"))
+					Expect(strings.Index(userData, "NVMe devices aren't available")).To(BeNumerically("<", strings.Index(userData, "--local-disks raid0")))
+				}
+			})
 			It("should handle empty custom user data", func() {
 				nodeClass.Spec.UserData = nil
 				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
@@ -1704,6 +1972,157 @@ essential = true
 				ExpectLaunchTemplatesCreatedWithUserData(expectedUserData)
 			})
 		})
+		Context("Flatcar", func() {
+			BeforeEach(func() {
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "flatcar@latest"}}
+				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](110)}
+			})
+			It("should bootstrap the node from a systemd unit", func() {
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, config := range ExpectIgnitionConfigsFromCreatedLaunchTemplates() {
+					Expect(config.Ignition.Version).To(Equal(bootstrap.IgnitionVersion))
+					Expect(config.Ignition.Config).To(BeNil())
+					Expect(config.Systemd.Units).To(HaveLen(1))
+					Expect(config.Systemd.Units[0].Name).To(Equal(bootstrap.FlatcarBootstrapUnit))
+					Expect(lo.FromPtr(config.Systemd.Units[0].Enabled)).To(BeTrue())
+					Expect(config.Storage.Files).To(HaveLen(1))
+					Expect(config.Systemd.Units[0].Contents).To(ContainSubstring(fmt.Sprintf("ExecStart=%s", config.Storage.Files[0].Path)))
+					script := ExpectDecodedDataURL(config.Storage.Files[0].Contents.Source)
+					Expect(script).To(ContainSubstring(fmt.Sprintf("%s '%s' --apiserver-endpoint 'https://test-cluster'", bootstrap.FlatcarBootstrapScriptPath, options.FromContext(ctx).ClusterName)))
+					Expect(script).To(ContainSubstring("--max-pods=110"))
+					Expect(script).To(ContainSubstring(fmt.Sprintf("%s=%s", karpv1.NodePoolLabelKey, nodePool.Name)))
+				}
+			})
+			It("should merge in custom ignition configs", func() {
+				customUserData := `{"ignition":{"version":"3.4.0"},"systemd":{"units":[{"name":"custom.service","enabled":true}]}}`
+				nodeClass.Spec.UserData = aws.String(customUserData)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, config := range ExpectIgnitionConfigsFromCreatedLaunchTemplates() {
+					Expect(config.Ignition.Config).ToNot(BeNil())
+					Expect(config.Ignition.Config.Merge).To(HaveLen(1))
+					Expect(ExpectDecodedDataURL(config.Ignition.Config.Merge[0].Source)).To(Equal(customUserData))
+					Expect(config.Systemd.Units[0].Name).To(Equal(bootstrap.FlatcarBootstrapUnit))
+				}
+			})
+			DescribeTable("should not bootstrap on invalid custom user data", func(userData string) {
+				nodeClass.Spec.UserData = aws.String(userData)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				// This will not be scheduled since userData cannot be generated for the prospective node.
+				ExpectNotScheduled(ctx, env.Client, pod)
+			},
+				Entry("shell script", "#!/bin/bash\n./not-ignition.sh"),
+				Entry("unsupported spec version", `{"ignition":{"version":"2.3.0"}}`),
+			)
+		})
+		Context("Ubuntu", func() {
+			BeforeEach(func() {
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "ubuntu@latest"}}
+			})
+			It("should bootstrap the node with the EKS bootstrap script", func() {
+				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](110)}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining(
+					fmt.Sprintf("/etc/eks/bootstrap.sh '%s' --apiserver-endpoint 'https://test-cluster'", options.FromContext(ctx).ClusterName),
+					"--max-pods=110",
+				)
+			})
+			It("should default the root volume to /dev/sda1", func() {
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					Expect(ltInput.LaunchTemplateData.BlockDeviceMappings).To(HaveLen(1))
+					Expect(lo.FromPtr(ltInput.LaunchTemplateData.BlockDeviceMappings[0].DeviceName)).To(Equal("/dev/sda1"))
+					Expect(lo.FromPtr(ltInput.LaunchTemplateData.BlockDeviceMappings[0].Ebs.VolumeSize)).To(Equal(int32(20)))
+					Expect(ltInput.LaunchTemplateData.BlockDeviceMappings[0].Ebs.VolumeType).To(Equal(ec2types.VolumeType("gp3")))
+				})
+			})
+		})
+		Context("Talos", func() {
+			const machineConfig = `version: v1alpha1
+machine:
+  type: worker
+  token: test-token
+  kubelet:
+    extraConfig:
+      serializeImagePulls: false
+      maxPods: 50
+cluster:
+  controlPlane:
+    endpoint: https://talos-cluster:6443
+---
+apiVersion: v1alpha1
+kind: VolumeConfig
+name: EPHEMERAL
+`
+			BeforeEach(func() {
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "talos@latest"}}
+				nodeClass.Spec.UserData = aws.String(machineConfig)
+				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](110), ClusterDNS: []string{"10.0.10.100"}}
+			})
+			It("should patch the machine config with the node's labels, taints, and kubelet configuration", func() {
+				nodePool.Spec.Template.Spec.Taints = []corev1.Taint{{Key: "foo", Value: "bar", Effect: corev1.TaintEffectNoExecute}}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}}})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					documents := strings.Split(userData, "\n---\n")
+					Expect(documents).To(HaveLen(2))
+					Expect(documents[1]).To(Equal("apiVersion: v1alpha1\nkind: VolumeConfig\nname: EPHEMERAL\n"))
+					config := map[string]interface{}{}
+					Expect(yaml.Unmarshal([]byte(documents[0]), &config)).To(Succeed())
+					machine := config["machine"].(map[string]interface{})
+					Expect(machine["token"]).To(Equal("test-token"))
+					Expect(machine["nodeLabels"]).To(HaveKeyWithValue(karpv1.NodePoolLabelKey, nodePool.Name))
+					kubelet := machine["kubelet"].(map[string]interface{})
+					Expect(kubelet["clusterDNS"]).To(ConsistOf("10.0.10.100"))
+					extraConfig := kubelet["extraConfig"].(map[string]interface{})
+					Expect(extraConfig).To(HaveKeyWithValue("serializeImagePulls", false))
+					Expect(extraConfig).To(HaveKeyWithValue("maxPods", BeNumerically("==", 110)))
+					Expect(extraConfig["registerWithTaints"]).To(ConsistOf(
+						map[string]interface{}{"key": "foo", "value": "bar", "effect": "NoExecute"},
+						map[string]interface{}{"key": karpv1.UnregisteredTaintKey, "effect": "NoExecute"},
+					))
+				}
+			})
+			It("should use the default block device mappings", func() {
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					Expect(ltInput.LaunchTemplateData.BlockDeviceMappings).To(HaveLen(1))
+					Expect(lo.FromPtr(ltInput.LaunchTemplateData.BlockDeviceMappings[0].DeviceName)).To(Equal("/dev/xvda"))
+				})
+			})
+			DescribeTable("should not bootstrap on invalid custom user data", func(userData *string) {
+				nodeClass.Spec.UserData = userData
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				// This will not be scheduled since userData cannot be generated for the prospective node.
+				ExpectNotScheduled(ctx, env.Client, pod)
+			},
+				Entry("missing", nil),
+				Entry("shell script", aws.String("#!/bin/bash\n./not-talos.sh")),
+				Entry("no machine config document", aws.String("apiVersion: v1alpha1\nkind: VolumeConfig\nname: EPHEMERAL\n")),
+				Entry("control plane machine config", aws.String("version: v1alpha1\nmachine:\n  type: controlplane\n")),
+			)
+		})
 		Context("AL2023", func() {
 			BeforeEach(func() {
 				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
@@ -1873,64 +2292,190 @@ essential = true
 					Expect(configs[0].Spec.Instance.LocalStorage.Strategy).To(Equal(admv1alpha1.LocalStorageRAID0))
 				}
 			})
-			DescribeTable(
-				"should merge custom user data",
-				func(inputFile *string, mergedFile string) {
-					if inputFile != nil {
-						content, err := os.ReadFile("testdata/" + *inputFile)
-						Expect(err).To(BeNil())
-						nodeClass.Spec.UserData = lo.ToPtr(string(content))
-					}
-					nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](110)}
-					ExpectApplied(ctx, env.Client, nodeClass, nodePool)
-					pod := coretest.UnschedulablePod()
-					ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
-					ExpectScheduled(ctx, env.Client, pod)
-					content, err := os.ReadFile("testdata/" + mergedFile)
-					Expect(err).To(BeNil())
-					expectedUserData := fmt.Sprintf(string(content), nodeClass.Name, karpv1.NodePoolLabelKey, nodePool.Name)
-					ExpectLaunchTemplatesCreatedWithUserData(expectedUserData)
-				},
-				Entry("MIME", lo.ToPtr("al2023_mime_userdata_input.golden"), "al2023_mime_userdata_merged.golden"),
-				Entry("YAML", lo.ToPtr("al2023_yaml_userdata_input.golden"), "al2023_yaml_userdata_merged.golden"),
-				Entry("shell", lo.ToPtr("al2023_shell_userdata_input.golden"), "al2023_shell_userdata_merged.golden"),
-				Entry("empty", nil, "al2023_userdata_unmerged.golden"),
-			)
-			It("should fail to create launch templates if cluster CIDR is unresolved", func() {
-				awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(nil)
+			It("should validate devices before nodeadm starts the kubelet when device validation is specified", func() {
+				nodeClass.Spec.DeviceValidation = &v1.DeviceValidation{Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceNVMe}}
 				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
 				pod := coretest.UnschedulablePod()
 				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
-				ExpectNotScheduled(ctx, env.Client, pod)
-				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(Equal(0))
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					Expect(userData).To(ContainSubstring("deadline=$((SECONDS+300))"))
+					Expect(userData).To(ContainSubstring("NVMe devices aren't available"))
+					Expect(strings.Index(userData, bootstrap.DeviceValidationFailedMessage)).To(BeNumerically("<", strings.Index(userData, "kind: NodeConfig")))
+				}
 			})
-		})
-		Context("Custom AMI Selector", func() {
-			It("should use ami selector specified in EC2NodeClass", func() {
-				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
-				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
-				nodeClass.Status.AMIs = []v1.AMI{
-					{
-						ID: "ami-123",
-						Requirements: []corev1.NodeSelectorRequirement{
-							{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}},
-						},
-					},
+			It("should validate instance store devices before nodeadm sets up RAID0 local storage", func() {
+				nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyRAID0)
+				nodeClass.Spec.DeviceValidation = &v1.DeviceValidation{Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceNVMe}}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+					Expect(configs).To(HaveLen(1))
+					Expect(configs[0].Spec.Instance.LocalStorage.Strategy).To(Equal(admv1alpha1.LocalStorageRAID0))
+					Expect(strings.Index(userData, "NVMe devices aren't available")).To(BeNumerically("<", strings.Index(userData, "kind: NodeConfig")))
 				}
+			})
+			It("should only count instance store volumes, and not EBS volumes, when validating NVMe devices", func() {
+				nodeClass.Spec.DeviceValidation = &v1.DeviceValidation{Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceNVMe}}
 				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
 				pod := coretest.UnschedulablePod()
 				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
 				ExpectScheduled(ctx, env.Client, pod)
-				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically("==", 1))
-				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
-					Expect("ami-123").To(Equal(*ltInput.LaunchTemplateData.ImageId))
-				})
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					// Instance store and EBS volumes are both NVMe controllers from Amazon, but only instance store
+					// controllers have the 0xcd01 device ID and the "Instance Storage" model
+					Expect(userData).To(ContainSubstring("pci_devices 0x1d0f '^0xcd01$'"))
+					Expect(userData).To(ContainSubstring("grep -l 'Instance Storage' /sys/class/nvme/nvme*/model"))
+					Expect(userData).ToNot(ContainSubstring("0x8061"))
+				}
 			})
-			It("should copy over userData untouched when AMIFamily is Custom", func() {
-				nodeClass.Spec.UserData = aws.String("special user data")
-				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
-				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
-				nodeClass.Status.AMIs = []v1.AMI{
+			It("should validate each device once in the order they're listed", func() {
+				nodeClass.Spec.DeviceValidation = &v1.DeviceValidation{
+					Devices: []v1.DeviceValidationDevice{v1.DeviceValidationDeviceNVMe, v1.DeviceValidationDeviceGPU},
+				}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					Expect(strings.Count(userData, "NVMe devices aren't available")).To(Equal(1))
+					Expect(strings.Count(userData, "GPU devices aren't available")).To(Equal(1))
+					Expect(strings.Count(userData, "EFA devices aren't available")).To(Equal(0))
+					Expect(strings.Index(userData, "NVMe devices aren't available")).To(BeNumerically("<", strings.Index(userData, "GPU devices aren't available")))
+				}
+			})
+			It("should write kubelet config drop-ins in the order they're listed", func() {
+				nodeClass.Spec.KubeletConfigDropIns = []v1.KubeletConfigDropIn{
+					{Name: "swap", Config: map[string]apiextensionsv1.JSON{"failSwapOn": {Raw: []byte(`false`)}}},
+					{Name: "logs", Config: map[string]apiextensionsv1.JSON{"containerLogMaxSize": {Raw: []byte(`"50Mi"`)}}},
+				}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					Expect(userData).To(ContainSubstring("cat > '/etc/kubernetes/kubelet/config.json.d/10-swap.conf'"))
+					Expect(userData).To(ContainSubstring("cat > '/etc/kubernetes/kubelet/config.json.d/11-logs.conf'"))
+					Expect(userData).To(ContainSubstring(`"kind": "KubeletConfiguration"`))
+					Expect(userData).To(ContainSubstring(`"containerLogMaxSize": "50Mi"`))
+					Expect(userData).To(ContainSubstring(`"failSwapOn": false`))
+				}
+			})
+			Context("NodeConfig", func() {
+				It("should merge kubelet config and flags into the generated NodeConfig", func() {
+					nodeClass.Spec.NodeConfig = &v1.NodeConfig{
+						Kubelet: &v1.NodeConfigKubelet{
+							Config: map[string]apiextensionsv1.JSON{
+								"shutdownGracePeriod": {Raw: []byte(`"30s"`)},
+								"featureGates":        {Raw: []byte(`{"DisableKubeletCloudCredentialProviders":true}`)},
+							},
+							Flags: []string{"--v=4"},
+						},
+					}
+					nodePool.Spec.Template.Labels = map[string]string{"test-label": "value"}
+					ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+					pod := coretest.UnschedulablePod()
+					ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+					ExpectScheduled(ctx, env.Client, pod)
+					for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+						configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+						Expect(len(configs)).To(Equal(1))
+						Expect(string(configs[0].Spec.Kubelet.Config["shutdownGracePeriod"].Raw)).To(Equal(`"30s"`))
+						Expect(string(configs[0].Spec.Kubelet.Config["featureGates"].Raw)).To(Equal(`{"DisableKubeletCloudCredentialProviders":true}`))
+						Expect(configs[0].Spec.Kubelet.Config).To(HaveKey("registerWithTaints"))
+						Expect(configs[0].Spec.Kubelet.Flags).To(HaveLen(2))
+						Expect(configs[0].Spec.Kubelet.Flags[0]).To(HavePrefix("--node-labels"))
+						Expect(configs[0].Spec.Kubelet.Flags[1]).To(Equal("--v=4"))
+					}
+				})
+				It("should set the containerd config and local storage strategy", func() {
+					nodeClass.Spec.NodeConfig = &v1.NodeConfig{
+						Containerd: &v1.NodeConfigContainerd{Config: "[plugins.\"io.containerd.grpc.v1.cri\".containerd]\ndiscard_unpacked_layers = false\n"},
+						Instance:   &v1.NodeConfigInstance{LocalStorage: &v1.NodeConfigLocalStorage{Strategy: "Mount"}},
+					}
+					ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+					pod := coretest.UnschedulablePod()
+					ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+					ExpectScheduled(ctx, env.Client, pod)
+					for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+						configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+						Expect(len(configs)).To(Equal(1))
+						Expect(configs[0].Spec.Containerd.Config).To(Equal(nodeClass.Spec.NodeConfig.Containerd.Config))
+						Expect(configs[0].Spec.Instance.LocalStorage.Strategy).To(Equal(admv1alpha1.LocalStorageMount))
+					}
+				})
+				It("should fail to create launch templates when the containerd config isn't valid TOML", func() {
+					nodeClass.Spec.NodeConfig = &v1.NodeConfig{
+						Containerd: &v1.NodeConfigContainerd{Config: "[plugins"},
+					}
+					ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+					pod := coretest.UnschedulablePod()
+					ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+					ExpectNotScheduled(ctx, env.Client, pod)
+					Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(Equal(0))
+				})
+			})
+			DescribeTable(
+				"should merge custom user data",
+				func(inputFile *string, mergedFile string) {
+					if inputFile != nil {
+						content, err := os.ReadFile("testdata/" + *inputFile)
+						Expect(err).To(BeNil())
+						nodeClass.Spec.UserData = lo.ToPtr(string(content))
+					}
+					nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](110)}
+					ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+					pod := coretest.UnschedulablePod()
+					ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+					ExpectScheduled(ctx, env.Client, pod)
+					content, err := os.ReadFile("testdata/" + mergedFile)
+					Expect(err).To(BeNil())
+					expectedUserData := fmt.Sprintf(string(content), nodeClass.Name, karpv1.NodePoolLabelKey, nodePool.Name)
+					ExpectLaunchTemplatesCreatedWithUserData(expectedUserData)
+				},
+				Entry("MIME", lo.ToPtr("al2023_mime_userdata_input.golden"), "al2023_mime_userdata_merged.golden"),
+				Entry("YAML", lo.ToPtr("al2023_yaml_userdata_input.golden"), "al2023_yaml_userdata_merged.golden"),
+				Entry("shell", lo.ToPtr("al2023_shell_userdata_input.golden"), "al2023_shell_userdata_merged.golden"),
+				Entry("empty", nil, "al2023_userdata_unmerged.golden"),
+			)
+			It("should fail to create launch templates if cluster CIDR is unresolved", func() {
+				awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(nil)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(Equal(0))
+			})
+		})
+		Context("Custom AMI Selector", func() {
+			It("should use ami selector specified in EC2NodeClass", func() {
+				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+				nodeClass.Status.AMIs = []v1.AMI{
+					{
+						ID: "ami-123",
+						Requirements: []corev1.NodeSelectorRequirement{
+							{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}},
+						},
+					},
+				}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically("==", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					Expect("ami-123").To(Equal(*ltInput.LaunchTemplateData.ImageId))
+				})
+			})
+			It("should copy over userData untouched when AMIFamily is Custom", func() {
+				nodeClass.Spec.UserData = aws.String("special user data")
+				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+				nodeClass.Status.AMIs = []v1.AMI{
 					{
 						ID: "ami-123",
 						Requirements: []corev1.NodeSelectorRequirement{
@@ -1944,6 +2489,45 @@ essential = true
 				ExpectScheduled(ctx, env.Client, pod)
 				ExpectLaunchTemplatesCreatedWithUserData("special user data")
 			})
+			It("should generate a NodeConfig when AMIFamily is Custom with the Nodeadm bootstrap mode", func() {
+				nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho custom")
+				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+				nodeClass.Spec.BootstrapMode = lo.ToPtr(v1.BootstrapModeNodeadm)
+				awsEnv.LaunchTemplateProvider.CABundle = lo.ToPtr("Y2EtYnVuZGxlCg==")
+				awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(lo.ToPtr("10.100.0.0/16"))
+				nodeClass.Spec.NodeConfig = &v1.NodeConfig{
+					Kubelet: &v1.NodeConfigKubelet{Flags: []string{"--v=4"}},
+				}
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+				nodeClass.Status.AMIs = []v1.AMI{
+					{
+						ID: "ami-123",
+						Requirements: []corev1.NodeSelectorRequirement{
+							{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}},
+						},
+					},
+				}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					archive, err := mime.NewArchive(userData)
+					Expect(err).To(BeNil())
+					Expect(archive).To(HaveLen(2))
+					Expect(archive[0].ContentType).To(Equal(mime.ContentTypeShellScript))
+					Expect(archive[0].Content).To(ContainSubstring("echo custom"))
+					Expect(archive[1].ContentType).To(Equal(mime.ContentTypeNodeConfig))
+					configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+					Expect(configs).To(HaveLen(1))
+					Expect(configs[0].Spec.Cluster.Name).To(Equal("test-cluster"))
+					Expect(configs[0].Spec.Kubelet.Config).To(HaveKey("registerWithTaints"))
+					Expect(configs[0].Spec.Kubelet.Flags).To(ContainElement("--v=4"))
+				}
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+					Expect(ltInput.LaunchTemplateData.BlockDeviceMappings).To(BeEmpty())
+				})
+			})
 			It("should correctly use ami selector with specific IDs in EC2NodeClass", func() {
 				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
 				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-123"}, {ID: "ami-456"}}
@@ -1983,7 +2567,9 @@ essential = true
 						Values: []string{string(ec2types.ImageStateAvailable)},
 					},
 				}
-				Expect(actualFilter).To(Equal(expectedFilter))
+				Expect(actualFilter[:2]).To(Equal(expectedFilter))
+				Expect(aws.ToString(actualFilter[2].Name)).To(Equal("architecture"))
+				Expect(actualFilter[2].Values).To(ConsistOf("x86_64", "arm64"))
 			})
 			It("should create multiple launch templates when multiple amis are discovered with non-equivalent requirements", func() {
 				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
@@ -2042,7 +2628,7 @@ essential = true
 				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
 				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
 				ExpectApplied(ctx, env.Client, nodeClass)
-				controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver)
+				controller := nodeclass.NewController(awsEnv.Clock, env.Client, recorder, awsEnv.SubnetProvider, awsEnv.VPCEndpointProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.CapacityReservationProvider, awsEnv.AccountSettingsProvider, awsEnv.EC2API, awsEnv.ValidationCache, awsEnv.AMIResolver, awsEnv.HealthTracker, awsEnv.InstanceTypesProvider)
 				ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
 				nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
 					{
@@ -2138,6 +2724,65 @@ essential = true
 				Entry("AssociatePublicIPAddress is false (EFA)", false, false, true),
 			)
 		})
+		Context("EFA", func() {
+			var pod *corev1.Pod
+			BeforeEach(func() {
+				nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+					{
+						NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+							Key:      corev1.LabelInstanceTypeStable,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"dl1.24xlarge"},
+						},
+					},
+				}
+				pod = coretest.UnschedulablePod(coretest.PodOptions{
+					ResourceRequirements: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{v1.ResourceEFA: resource.MustParse("1")},
+						Limits:   corev1.ResourceList{v1.ResourceEFA: resource.MustParse("1")},
+					},
+				})
+			})
+			It("should attach every EFA interface the instance type supports by default", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(4))
+				for _, ni := range input.LaunchTemplateData.NetworkInterfaces {
+					Expect(lo.FromPtr(ni.InterfaceType)).To(Equal(string(ec2types.NetworkInterfaceTypeEfa)))
+				}
+			})
+			It("should attach the configured number of EFA interfaces", func() {
+				nodeClass.Spec.EFA = &v1.EFA{Count: lo.ToPtr[int32](2)}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(2))
+				for i, ni := range input.LaunchTemplateData.NetworkInterfaces {
+					Expect(lo.FromPtr(ni.NetworkCardIndex)).To(BeEquivalentTo(i))
+				}
+			})
+			It("should attach EFA-only interfaces to the secondary network cards", func() {
+				nodeClass.Spec.EFA = &v1.EFA{EFAOnly: lo.ToPtr(true)}
+				nodeClass.Spec.AssociatePublicIPAddress = lo.ToPtr(true)
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Pop()
+				Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(4))
+				primary := input.LaunchTemplateData.NetworkInterfaces[0]
+				Expect(lo.FromPtr(primary.InterfaceType)).To(Equal(string(ec2types.NetworkInterfaceTypeEfa)))
+				Expect(lo.FromPtr(primary.AssociatePublicIpAddress)).To(BeTrue())
+				for _, ni := range input.LaunchTemplateData.NetworkInterfaces[1:] {
+					Expect(lo.FromPtr(ni.InterfaceType)).To(Equal("efa-only"))
+					Expect(lo.FromPtr(ni.DeviceIndex)).To(BeEquivalentTo(1))
+					Expect(ni.AssociatePublicIpAddress).To(BeNil())
+					Expect(ni.Groups).To(ConsistOf(lo.Map(nodeClass.Status.SecurityGroups, func(s v1.SecurityGroup, _ int) string { return s.ID })))
+				}
+			})
+		})
 		Context("Kubelet Args", func() {
 			It("should specify the --dns-cluster-ip flag when clusterDNSIP is set", func() {
 				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{ClusterDNS: []string{"10.0.10.100"}}
@@ -2189,6 +2834,368 @@ essential = true
 			})
 		})
 	})
+	Context("Registry Mirrors", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.RegistryMirrors = []v1.RegistryMirror{{
+				Registry:  "docker.io",
+				Endpoints: []string{"https://mirror.example.com"},
+			}}
+		})
+		It("should write containerd hosts configuration for AL2", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2@latest"}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(
+				"/etc/containerd/certs.d/docker.io/hosts.toml",
+				`[host."https://mirror.example.com"]`,
+			)
+		})
+		It("should write containerd hosts configuration for AL2023 before nodeadm runs", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			awsEnv.LaunchTemplateProvider.CABundle = lo.ToPtr("Y2EtYnVuZGxlCg==")
+			awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(lo.ToPtr("10.100.0.0/16"))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+				archive, err := mime.NewArchive(userData)
+				Expect(err).To(BeNil())
+				Expect(archive).To(HaveLen(2))
+				Expect(archive[0].ContentType).To(Equal(mime.ContentTypeShellScript))
+				Expect(archive[0].Content).To(ContainSubstring("/etc/containerd/certs.d/docker.io/hosts.toml"))
+				Expect(archive[1].ContentType).To(Equal(mime.ContentTypeNodeConfig))
+			}
+		})
+		It("should configure container-registry settings for Bottlerocket", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			nodeClass.Spec.UserData = aws.String(`
+[[settings.container-registry.mirrors]]
+registry = "quay.io"
+endpoint = ["https://quay-mirror.example.com"]
+`)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+				Expect(err).To(BeNil())
+				config := &bootstrap.BottlerocketConfig{}
+				Expect(config.UnmarshalTOML(userData)).To(Succeed())
+				Expect(config.SettingsRaw).To(HaveKeyWithValue("container-registry", map[string]interface{}{
+					"mirrors": []interface{}{
+						map[string]interface{}{"registry": "quay.io", "endpoint": []interface{}{"https://quay-mirror.example.com"}},
+						map[string]interface{}{"registry": "docker.io", "endpoint": []interface{}{"https://mirror.example.com"}},
+					},
+				}))
+			})
+		})
+		It("should fail to create launch templates when Bottlerocket userData mirrors the same registry", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			nodeClass.Spec.UserData = aws.String(`
+[[settings.container-registry.mirrors]]
+registry = "docker.io"
+endpoint = ["https://other-mirror.example.com"]
+`)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should write containerd hosts configuration for Windows", func() {
+			nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelOSStable, Operator: corev1.NodeSelectorOpIn, Values: []string{string(corev1.Windows)}}}}
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2022@latest"}}
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				NodeSelector: map[string]string{
+					corev1.LabelOSStable:     string(corev1.Windows),
+					corev1.LabelWindowsBuild: "10.0.20348",
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(`Set-Content -Path "$env:ProgramFiles\containerd\certs.d\docker.io\hosts.toml"`)
+		})
+		It("should write the credentials from the referenced Secret", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2@latest"}}
+			nodeClass.Spec.RegistryMirrors[0].CredentialsSecretName = lo.ToPtr("mirror-credentials")
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "mirror-credentials", Namespace: "default"},
+				Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+			}
+			ExpectApplied(ctx, env.Client, secret, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(`Authorization = ["Basic dXNlcjpwYXNz"]`)
+			ExpectDeleted(ctx, env.Client, secret)
+		})
+		It("should fail to create launch templates when the referenced Secret doesn't exist", func() {
+			nodeClass.Spec.RegistryMirrors[0].CredentialsSecretName = lo.ToPtr("missing-credentials")
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+	})
+	Context("Proxy", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.Proxy = &v1.Proxy{
+				HTTPSProxy: lo.ToPtr("http://proxy.example.com:3128"),
+				NoProxy:    []string{".example.com"},
+			}
+		})
+		It("should set the proxy environment of containerd and the kubelet for AL2", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2@latest"}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(
+				"/etc/systemd/system/containerd.service.d/http-proxy.conf",
+				"/etc/systemd/system/kubelet.service.d/http-proxy.conf",
+				`Environment="HTTPS_PROXY=http://proxy.example.com:3128"`,
+				`Environment="NO_PROXY=localhost,127.0.0.1,169.254.169.254,.internal,`,
+				",.example.com\"",
+			)
+		})
+		It("should set the proxy environment for AL2023 before nodeadm runs", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			awsEnv.LaunchTemplateProvider.CABundle = lo.ToPtr("Y2EtYnVuZGxlCg==")
+			awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(lo.ToPtr("10.100.0.0/16"))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+				archive, err := mime.NewArchive(userData)
+				Expect(err).To(BeNil())
+				Expect(archive).To(HaveLen(2))
+				Expect(archive[0].ContentType).To(Equal(mime.ContentTypeShellScript))
+				Expect(archive[0].Content).To(ContainSubstring(`Environment="HTTPS_PROXY=http://proxy.example.com:3128"`))
+				Expect(archive[1].ContentType).To(Equal(mime.ContentTypeNodeConfig))
+			}
+		})
+		It("should configure network settings for Bottlerocket", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+				Expect(err).To(BeNil())
+				config := &bootstrap.BottlerocketConfig{}
+				Expect(config.UnmarshalTOML(userData)).To(Succeed())
+				Expect(config.SettingsRaw).To(HaveKeyWithValue("network", map[string]interface{}{
+					"https-proxy": "http://proxy.example.com:3128",
+					"no-proxy":    []interface{}{".example.com"},
+				}))
+			})
+		})
+		It("should fail to create launch templates when Bottlerocket userData configures a proxy", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			nodeClass.Spec.UserData = aws.String(`
+[settings.network]
+https-proxy = "http://other-proxy.example.com:3128"
+`)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should set the machine proxy for Windows", func() {
+			nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelOSStable, Operator: corev1.NodeSelectorOpIn, Values: []string{string(corev1.Windows)}}}}
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2022@latest"}}
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				NodeSelector: map[string]string{
+					corev1.LabelOSStable:     string(corev1.Windows),
+					corev1.LabelWindowsBuild: "10.0.20348",
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(
+				"[Environment]::SetEnvironmentVariable('HTTPS_PROXY', 'http://proxy.example.com:3128', 'Machine')",
+				`HKLM:\SYSTEM\CurrentControlSet\Services\containerd`,
+				`HKLM:\SYSTEM\CurrentControlSet\Services\kubelet`,
+				`netsh winhttp set proxy proxy-server="proxy.example.com:3128"`,
+			)
+		})
+	})
+	Context("Trusted CA Bundle", func() {
+		var certificate string
+		BeforeEach(func() {
+			certificate = selfSignedCertificate()
+			nodeClass.Spec.TrustedCABundle = &v1.TrustedCABundle{PEM: lo.ToPtr("# Corporate proxy\n" + certificate)}
+		})
+		It("should add the bundle to the trust store for AL2", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2@latest"}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(
+				"/etc/pki/ca-trust/source/anchors/karpenter-trusted-ca-bundle.crt",
+				certificate,
+				"update-ca-trust extract",
+			)
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("# Corporate proxy")
+		})
+		It("should add the bundle to the trust store for AL2023 before nodeadm runs", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			awsEnv.LaunchTemplateProvider.CABundle = lo.ToPtr("Y2EtYnVuZGxlCg==")
+			awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(lo.ToPtr("10.100.0.0/16"))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+				archive, err := mime.NewArchive(userData)
+				Expect(err).To(BeNil())
+				Expect(archive).To(HaveLen(2))
+				Expect(archive[0].ContentType).To(Equal(mime.ContentTypeShellScript))
+				Expect(archive[0].Content).To(ContainSubstring(certificate))
+				Expect(archive[0].Content).To(ContainSubstring("update-ca-trust extract"))
+				Expect(archive[1].ContentType).To(Equal(mime.ContentTypeNodeConfig))
+			}
+		})
+		It("should configure pki settings for Bottlerocket", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+				Expect(err).To(BeNil())
+				config := &bootstrap.BottlerocketConfig{}
+				Expect(config.UnmarshalTOML(userData)).To(Succeed())
+				Expect(config.SettingsRaw).To(HaveKeyWithValue("pki", map[string]interface{}{
+					"karpenter-trusted-ca-bundle": map[string]interface{}{
+						"data":    base64.StdEncoding.EncodeToString([]byte(certificate)),
+						"trusted": true,
+					},
+				}))
+			})
+		})
+		It("should import the certificates into the root store for Windows", func() {
+			nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelOSStable, Operator: corev1.NodeSelectorOpIn, Values: []string{string(corev1.Windows)}}}}
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2022@latest"}}
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				NodeSelector: map[string]string{
+					corev1.LabelOSStable:     string(corev1.Windows),
+					corev1.LabelWindowsBuild: "10.0.20348",
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(
+				certificate,
+				`Import-Certificate -FilePath $CertificatePath -CertStoreLocation Cert:\LocalMachine\Root`,
+			)
+		})
+		It("should read the bundle from the referenced ConfigMap", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2@latest"}}
+			nodeClass.Spec.TrustedCABundle = &v1.TrustedCABundle{ConfigMapName: lo.ToPtr("trusted-ca-bundle")}
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "trusted-ca-bundle", Namespace: "default"},
+				Data:       map[string]string{"ca-bundle.crt": certificate},
+			}
+			ExpectApplied(ctx, env.Client, configMap, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(certificate)
+			ExpectDeleted(ctx, env.Client, configMap)
+		})
+		It("should fail to create launch templates when the referenced ConfigMap doesn't exist", func() {
+			nodeClass.Spec.TrustedCABundle = &v1.TrustedCABundle{ConfigMapName: lo.ToPtr("missing-ca-bundle")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should fail to create launch templates when the bundle contains an invalid certificate", func() {
+			nodeClass.Spec.TrustedCABundle = &v1.TrustedCABundle{PEM: lo.ToPtr("-----BEGIN CERTIFICATE-----\naW52YWxpZA==\n-----END CERTIFICATE-----\n")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+	})
+	Context("Swap", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+			awsEnv.LaunchTemplateProvider.CABundle = lo.ToPtr("Y2EtYnVuZGxlCg==")
+			awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(lo.ToPtr("10.100.0.0/16"))
+		})
+		It("should create a swapfile before nodeadm runs", func() {
+			nodeClass.Spec.Swap = &v1.Swap{Source: v1.SwapSourceSwapfile, Size: lo.ToPtr(resource.MustParse("4Gi"))}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+				archive, err := mime.NewArchive(userData)
+				Expect(err).To(BeNil())
+				Expect(archive).To(HaveLen(2))
+				Expect(archive[0].ContentType).To(Equal(mime.ContentTypeShellScript))
+				Expect(archive[0].Content).To(ContainSubstring("fallocate -l 4294967296 '/swapfile'"))
+				Expect(archive[0].Content).To(ContainSubstring("swapon '/swapfile'"))
+				Expect(archive[1].ContentType).To(Equal(mime.ContentTypeNodeConfig))
+			}
+		})
+		It("should use the instance store volumes as swap devices", func() {
+			nodeClass.Spec.Swap = &v1.Swap{Source: v1.SwapSourceInstanceStore}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining(
+				"/dev/disk/by-id/nvme-Amazon_EC2_NVMe_Instance_Storage_*",
+				`mkswap "$device"`,
+			)
+		})
+		It("should configure the kubelet to run with swap", func() {
+			nodeClass.Spec.Swap = &v1.Swap{Source: v1.SwapSourceSwapfile, Size: lo.ToPtr(resource.MustParse("512Mi"))}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+				configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+				Expect(configs).To(HaveLen(1))
+				Expect(string(configs[0].Spec.Kubelet.Config["failSwapOn"].Raw)).To(Equal(`false`))
+				Expect(string(configs[0].Spec.Kubelet.Config["memorySwap"].Raw)).To(Equal(`{"swapBehavior":"LimitedSwap"}`))
+			}
+		})
+		It("should configure the kubelet's swap behavior", func() {
+			nodeClass.Spec.Swap = &v1.Swap{Source: v1.SwapSourceInstanceStore, SwapBehavior: lo.ToPtr(v1.SwapBehaviorNoSwap)}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+				configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+				Expect(configs).To(HaveLen(1))
+				Expect(string(configs[0].Spec.Kubelet.Config["memorySwap"].Raw)).To(Equal(`{"swapBehavior":"NoSwap"}`))
+			}
+		})
+	})
 	Context("Detailed Monitoring", func() {
 		It("should default detailed monitoring to off", func() {
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -2212,6 +3219,39 @@ essential = true
 			})
 		})
 	})
+	Context("CPU Options", func() {
+		It("should not set CPU options by default", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.CpuOptions).To(BeNil())
+			})
+		})
+		It("should pass the threads per core to the launch template at creation", func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).ToNot(HaveOccurred())
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{
+				InstanceTypes: lo.Map(out.InstanceTypes, func(info ec2types.InstanceTypeInfo, _ int) ec2types.InstanceTypeInfo {
+					vCPUInfo := *info.VCpuInfo
+					vCPUInfo.ValidThreadsPerCore = []int32{1, 2}
+					info.VCpuInfo = &vCPUInfo
+					return info
+				}),
+			})
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{ThreadsPerCore: lo.ToPtr[int32](1)}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.CpuOptions).To(Equal(&ec2types.LaunchTemplateCpuOptionsRequest{ThreadsPerCore: lo.ToPtr[int32](1)}))
+			})
+		})
+	})
 	Context("Instance Metadata", func() {
 		It("should set the default instance metadata settings on instances", func() {
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -2226,6 +3266,43 @@ essential = true
 				Expect(ltInput.LaunchTemplateData.MetadataOptions.HttpTokens).To(Equal(ec2types.LaunchTemplateHttpTokensStateRequired))
 			})
 		})
+		It("should use the account's instance metadata defaults for the settings the EC2NodeClass leaves unset", func() {
+			nodeClass.Status.AccountDefaults = &v1.AccountDefaults{
+				HTTPPutResponseHopLimit: lo.ToPtr[int64](2),
+				HTTPTokens:              lo.ToPtr("optional"),
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.MetadataOptions.HttpEndpoint).To(Equal(ec2types.LaunchTemplateInstanceMetadataEndpointStateEnabled))
+				Expect(lo.FromPtr(ltInput.LaunchTemplateData.MetadataOptions.HttpPutResponseHopLimit)).To(BeNumerically("==", 2))
+				Expect(ltInput.LaunchTemplateData.MetadataOptions.HttpTokens).To(Equal(ec2types.LaunchTemplateHttpTokensStateOptional))
+			})
+		})
+		It("should prefer the EC2NodeClass's instance metadata settings over the account's defaults", func() {
+			nodeClass.Spec.MetadataOptions = &v1.MetadataOptions{
+				HTTPEndpoint:            lo.ToPtr("enabled"),
+				HTTPProtocolIPv6:        lo.ToPtr("disabled"),
+				HTTPPutResponseHopLimit: lo.ToPtr[int64](1),
+				HTTPTokens:              lo.ToPtr("required"),
+			}
+			nodeClass.Status.AccountDefaults = &v1.AccountDefaults{
+				HTTPPutResponseHopLimit: lo.ToPtr[int64](2),
+				HTTPTokens:              lo.ToPtr("optional"),
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(lo.FromPtr(ltInput.LaunchTemplateData.MetadataOptions.HttpPutResponseHopLimit)).To(BeNumerically("==", 1))
+				Expect(ltInput.LaunchTemplateData.MetadataOptions.HttpTokens).To(Equal(ec2types.LaunchTemplateHttpTokensStateRequired))
+			})
+		})
 		It("should set instance metadata tags to disabled", func() {
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 			pod := coretest.UnschedulablePod()
@@ -2237,6 +3314,45 @@ essential = true
 			})
 		})
 	})
+	Context("Private DNS Name Options", func() {
+		It("should not set private DNS name options by default", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.PrivateDnsNameOptions).To(BeNil())
+			})
+		})
+		It("should set the hostname type", func() {
+			nodeClass.Spec.PrivateDNSNameOptions = &v1.PrivateDNSNameOptions{HostnameType: lo.ToPtr("resource-name")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.PrivateDnsNameOptions.HostnameType).To(Equal(ec2types.HostnameTypeResourceName))
+			})
+		})
+		It("should enable resource-name DNS records", func() {
+			nodeClass.Spec.PrivateDNSNameOptions = &v1.PrivateDNSNameOptions{
+				HostnameType:                    lo.ToPtr("resource-name"),
+				EnableResourceNameDNSARecord:    lo.ToPtr(true),
+				EnableResourceNameDNSAAAARecord: lo.ToPtr(false),
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.PrivateDnsNameOptions.HostnameType).To(Equal(ec2types.HostnameTypeResourceName))
+				Expect(lo.FromPtr(ltInput.LaunchTemplateData.PrivateDnsNameOptions.EnableResourceNameDnsARecord)).To(BeTrue())
+				Expect(ltInput.LaunchTemplateData.PrivateDnsNameOptions.EnableResourceNameDnsAAAARecord).To(Equal(lo.ToPtr(false)))
+			})
+		})
+	})
 	Context("Networking", func() {
 		Context("launch template respect to DNS ip for ipfamily selection", func() {
 			DescribeTable(
@@ -2254,6 +3370,8 @@ essential = true
 						make(chan struct{}),
 						net.ParseIP(lo.Ternary(ipFamily == corev1.IPv4Protocol, "10.0.100.10", "fd01:99f0:d47b::a")),
 						"https://test-cluster",
+						env.Client,
+						"default",
 					)
 					Expect(provider.ClusterIPFamily).To(Equal(ipFamily))
 				},
@@ -2299,6 +3417,183 @@ essential = true
 				Entry("AssociatePublicIPAddress is set as false and EFA is false", true, false, false),
 			)
 		})
+		Context("IPv6-only subnets", func() {
+			BeforeEach(func() {
+				awsEnv.LaunchTemplateProvider.KubeDNSIP = net.ParseIP("fd4b:121b:812b::a")
+				awsEnv.LaunchTemplateProvider.ClusterIPFamily = corev1.IPv6Protocol
+				awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(lo.ToPtr("fd4b:121b:812b::/108"))
+				for i := range nodeClass.Status.Subnets {
+					nodeClass.Status.Subnets[i].IPv6Native = true
+				}
+			})
+			AfterEach(func() {
+				awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(nil)
+			})
+			It("should launch instances without IPv4 addresses", func() {
+				nodeClass.Spec.AssociatePublicIPAddress = lo.ToPtr(true)
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(1))
+					Expect(input.LaunchTemplateData.NetworkInterfaces[0].AssociatePublicIpAddress).To(BeNil())
+					Expect(lo.FromPtr(input.LaunchTemplateData.NetworkInterfaces[0].PrimaryIpv6)).To(BeTrue())
+					Expect(lo.FromPtr(input.LaunchTemplateData.NetworkInterfaces[0].Ipv6AddressCount)).To(Equal(int32(1)))
+					Expect(input.LaunchTemplateData.MetadataOptions.HttpProtocolIpv6).To(Equal(ec2types.LaunchTemplateInstanceMetadataProtocolIpv6Enabled))
+				})
+			})
+			It("should default to resource-name hostnames", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					Expect(input.LaunchTemplateData.PrivateDnsNameOptions).ToNot(BeNil())
+					Expect(input.LaunchTemplateData.PrivateDnsNameOptions.HostnameType).To(Equal(ec2types.HostnameTypeResourceName))
+					Expect(lo.FromPtr(input.LaunchTemplateData.PrivateDnsNameOptions.EnableResourceNameDnsAAAARecord)).To(BeTrue())
+				})
+			})
+			It("should keep the AAAA record when only enabling the A record", func() {
+				nodeClass.Spec.PrivateDNSNameOptions = &v1.PrivateDNSNameOptions{EnableResourceNameDNSARecord: lo.ToPtr(true)}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					Expect(input.LaunchTemplateData.PrivateDnsNameOptions.HostnameType).To(Equal(ec2types.HostnameTypeResourceName))
+					Expect(lo.FromPtr(input.LaunchTemplateData.PrivateDnsNameOptions.EnableResourceNameDnsARecord)).To(BeTrue())
+					Expect(lo.FromPtr(input.LaunchTemplateData.PrivateDnsNameOptions.EnableResourceNameDnsAAAARecord)).To(BeTrue())
+				})
+			})
+			It("should pass the service IPv6 CIDR to the AL2 bootstrap script", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining("--ip-family ipv6", "--service-ipv6-cidr 'fd4b:121b:812b::/108'")
+			})
+		})
+		Context("IPv6 Addresses and Prefixes", func() {
+			It("should assign the configured IPv6 addresses and prefixes to the primary network interface", func() {
+				nodeClass.Spec.IPv6 = &v1.IPv6{AddressCount: lo.ToPtr[int32](2), PrefixCount: lo.ToPtr[int32](1)}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(1))
+					Expect(input.LaunchTemplateData.NetworkInterfaces[0].PrimaryIpv6).To(BeNil())
+					Expect(lo.FromPtr(input.LaunchTemplateData.NetworkInterfaces[0].Ipv6AddressCount)).To(Equal(int32(2)))
+					Expect(lo.FromPtr(input.LaunchTemplateData.NetworkInterfaces[0].Ipv6PrefixCount)).To(Equal(int32(1)))
+				})
+			})
+			It("should keep the primary IPv6 address when only assigning prefixes in an IPv6 cluster", func() {
+				awsEnv.LaunchTemplateProvider.KubeDNSIP = net.ParseIP("fd4b:121b:812b::a")
+				awsEnv.LaunchTemplateProvider.ClusterIPFamily = corev1.IPv6Protocol
+				nodeClass.Spec.IPv6 = &v1.IPv6{PrefixCount: lo.ToPtr[int32](2)}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					Expect(lo.FromPtr(input.LaunchTemplateData.NetworkInterfaces[0].PrimaryIpv6)).To(BeTrue())
+					Expect(lo.FromPtr(input.LaunchTemplateData.NetworkInterfaces[0].Ipv6AddressCount)).To(Equal(int32(1)))
+					Expect(lo.FromPtr(input.LaunchTemplateData.NetworkInterfaces[0].Ipv6PrefixCount)).To(Equal(int32(2)))
+				})
+			})
+			It("should only assign the configured prefixes to the primary EFA interface", func() {
+				nodeClass.Spec.IPv6 = &v1.IPv6{PrefixCount: lo.ToPtr[int32](1)}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{
+					ResourceRequirements: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{v1.ResourceEFA: resource.MustParse("2")},
+						Limits:   corev1.ResourceList{v1.ResourceEFA: resource.MustParse("2")},
+					},
+				})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				input := awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Pop()
+				Expect(len(input.LaunchTemplateData.NetworkInterfaces)).To(BeNumerically(">", 1))
+				Expect(lo.FromPtr(input.LaunchTemplateData.NetworkInterfaces[0].Ipv6PrefixCount)).To(Equal(int32(1)))
+				for _, ni := range input.LaunchTemplateData.NetworkInterfaces[1:] {
+					Expect(ni.Ipv6PrefixCount).To(BeNil())
+				}
+			})
+		})
+		Context("Additional Network Interfaces", func() {
+			BeforeEach(func() {
+				nodeClass.Spec.NetworkInterfaces = []v1.NetworkInterface{
+					{
+						DeviceIndex:         1,
+						Count:               lo.ToPtr[int32](2),
+						SubnetSelectorTerms: []v1.SubnetSelectorTerm{{Tags: map[string]string{"Name": "data-plane"}}},
+					},
+				}
+				nodeClass.Status.NetworkInterfaces = []v1.NetworkInterfaceStatus{
+					{
+						DeviceIndex: 1,
+						Count:       2,
+						Subnets: []v1.Subnet{
+							{ID: "subnet-data-plane-1a", Zone: "test-zone-1a", ZoneID: "tstz1-1a"},
+							{ID: "subnet-data-plane-1b", Zone: "test-zone-1b", ZoneID: "tstz1-1b"},
+						},
+					},
+				}
+				// Readiness is recomputed with the network interfaces condition, which the suite doesn't set
+				nodeClass.StatusConditions().SetTrue(opstatus.ConditionReady)
+			})
+			It("should create a launch template with the network interfaces for each zone", func() {
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 2))
+				subnetsByLaunchTemplate := map[string]string{}
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(3))
+					Expect(input.LaunchTemplateData.SecurityGroupIds).To(BeEmpty())
+					Expect(input.LaunchTemplateData.NetworkInterfaces[0].SubnetId).To(BeNil())
+					for i, ni := range input.LaunchTemplateData.NetworkInterfaces[1:] {
+						Expect(lo.FromPtr(ni.DeviceIndex)).To(BeEquivalentTo(i + 1))
+						Expect(lo.FromPtr(ni.SubnetId)).To(BeElementOf("subnet-data-plane-1a", "subnet-data-plane-1b"))
+						Expect(ni.Groups).To(ConsistOf(lo.Map(nodeClass.Status.SecurityGroups, func(s v1.SecurityGroup, _ int) string { return s.ID })))
+					}
+					Expect(input.LaunchTemplateData.NetworkInterfaces[1].SubnetId).To(Equal(input.LaunchTemplateData.NetworkInterfaces[2].SubnetId))
+					subnetsByLaunchTemplate[lo.FromPtr(input.LaunchTemplateName)] = lo.FromPtr(input.LaunchTemplateData.NetworkInterfaces[1].SubnetId)
+				})
+				Expect(lo.Uniq(lo.Values(subnetsByLaunchTemplate))).To(ConsistOf("subnet-data-plane-1a", "subnet-data-plane-1b"))
+
+				// Each launch template only launches into the zone of its network interfaces' subnet
+				Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+				createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+				for _, config := range createFleetInput.LaunchTemplateConfigs {
+					zone := lo.Ternary(subnetsByLaunchTemplate[lo.FromPtr(config.LaunchTemplateSpecification.LaunchTemplateName)] == "subnet-data-plane-1a", "test-zone-1a", "test-zone-1b")
+					for _, override := range config.Overrides {
+						Expect(lo.FromPtr(override.AvailabilityZone)).To(Equal(zone))
+					}
+				}
+			})
+			It("should use the security groups of the network interfaces", func() {
+				nodeClass.Status.NetworkInterfaces[0].SecurityGroups = []v1.SecurityGroup{{ID: "sg-data-plane"}}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
+				awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+					Expect(input.LaunchTemplateData.NetworkInterfaces).To(HaveLen(3))
+					Expect(input.LaunchTemplateData.NetworkInterfaces[0].Groups).ToNot(ContainElement("sg-data-plane"))
+					Expect(input.LaunchTemplateData.NetworkInterfaces[1].Groups).To(ConsistOf("sg-data-plane"))
+					Expect(input.LaunchTemplateData.NetworkInterfaces[2].Groups).To(ConsistOf("sg-data-plane"))
+				})
+			})
+		})
 	})
 	It("should generate a unique launch template per capacity reservation", func() {
 		crs := []ec2types.CapacityReservation{
@@ -2473,6 +3768,25 @@ func ExpectLaunchTemplatesCreatedWithUserData(expected string) {
 	})
 }
 
+func ExpectIgnitionConfigsFromCreatedLaunchTemplates() []*bootstrap.IgnitionConfig {
+	GinkgoHelper()
+	configs := []*bootstrap.IgnitionConfig{}
+	for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+		config := &bootstrap.IgnitionConfig{}
+		Expect(json.Unmarshal([]byte(userData), config)).To(Succeed())
+		configs = append(configs, config)
+	}
+	return configs
+}
+
+func ExpectDecodedDataURL(source string) string {
+	GinkgoHelper()
+	Expect(source).To(HavePrefix("data:;base64,"))
+	contents, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(source, "data:;base64,"))
+	Expect(err).ToNot(HaveOccurred())
+	return string(contents)
+}
+
 func ExpectUserDataExistsFromCreatedLaunchTemplates() []string {
 	GinkgoHelper()
 	Expect(awsEnv.EC2API.CreateLaunchTemplateBehavior.CalledWithInput.Len()).To(BeNumerically(">=", 1))
@@ -2501,3 +3815,20 @@ func ExpectUserDataCreatedWithNodeConfigs(userData string) []admv1alpha1.NodeCon
 	Expect(len(nodeConfigs)).To(BeNumerically(">=", 1))
 	return nodeConfigs
 }
+
+// selfSignedCertificate returns a PEM encoded self-signed certificate authority
+func selfSignedCertificate() string {
+	GinkgoHelper()
+	key := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Corporate Proxy CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der := lo.Must(x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key))
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
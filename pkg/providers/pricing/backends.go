@@ -0,0 +1,152 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+const (
+	// StaticProviderName is the name of the provider which only serves the prices embedded at build time
+	StaticProviderName = "static"
+	// HTTPProviderName is the name of the provider which retrieves prices from a rate card served over HTTP
+	HTTPProviderName = "http"
+)
+
+// StaticProvider serves the prices embedded at build time, without calling the pricing or EC2 APIs. It's intended for
+// environments where neither API is reachable, and for testing. Price overrides and snapshots are still applied.
+type StaticProvider struct {
+	*DefaultProvider
+}
+
+func NewStaticProvider(ctx context.Context, region string) *StaticProvider {
+	return &StaticProvider{DefaultProvider: NewDefaultProvider(ctx, nil, nil, nil, nil, region)}
+}
+
+func (*StaticProvider) UpdateOnDemandPricing(context.Context) error     { return nil }
+func (*StaticProvider) UpdateSpotPricing(context.Context) error         { return nil }
+func (*StaticProvider) UpdateSavingsPlanPricing(context.Context) error  { return nil }
+func (*StaticProvider) UpdateBulkOnDemandPricing(context.Context) error { return nil }
+func (*StaticProvider) UpdateVolumePricing(context.Context) error       { return nil }
+func (*StaticProvider) UpdateLicensePricing(context.Context) error      { return nil }
+
+// HTTPProvider retrieves prices from a rate card served over HTTP, such as an internal service publishing negotiated
+// rates. The rate card is a JSON document in the same format as a pricing snapshot. Its on-demand prices replace those
+// from the pricing API, and its spot prices, if it has any, replace those from the EC2 API. Instance types missing from
+// the rate card keep their previous prices.
+type HTTPProvider struct {
+	*DefaultProvider
+	url string
+
+	muRateCard sync.RWMutex
+	// rateCardSpot is whether the last rate card retrieved contained spot prices
+	rateCardSpot bool
+}
+
+func NewHTTPProvider(ctx context.Context, url string, opts ProviderOptions) *HTTPProvider {
+	return &HTTPProvider{
+		DefaultProvider: NewDefaultProvider(ctx, opts.PricingAPI, opts.EC2API, opts.SavingsPlansAPI, opts.ZoneProvider, opts.Region),
+		url:             url,
+	}
+}
+
+// UpdateOnDemandPricing retrieves the rate card and applies its prices
+func (p *HTTPProvider) UpdateOnDemandPricing(ctx context.Context) error {
+	rateCard, err := p.fetchRateCard(ctx)
+	if err != nil {
+		return err
+	}
+	if len(rateCard.OnDemand) == 0 {
+		return fmt.Errorf("no on-demand pricing found in rate card")
+	}
+	p.muOnDemand.Lock()
+	p.onDemandPrices = lo.Assign(p.onDemandPrices, rateCard.OnDemand)
+	p.onDemandPricingUpdated = true
+	if p.cm.HasChanged("on-demand-prices", p.onDemandPrices) {
+		log.FromContext(ctx).WithValues("instance-type-count", len(p.onDemandPrices)).V(1).Info("updated on-demand pricing from rate card")
+	}
+	p.muOnDemand.Unlock()
+
+	p.muRateCard.Lock()
+	p.rateCardSpot = len(rateCard.Spot) != 0
+	p.muRateCard.Unlock()
+	if len(rateCard.Spot) != 0 {
+		p.muSpot.Lock()
+		for it, prices := range rateCard.Spot {
+			p.spotPrices[it] = combineZonalPricing(p.spotPrices[it], zonal{prices: prices})
+		}
+		p.spotPricingUpdated = true
+		if p.cm.HasChanged("spot-prices", p.spotPrices) {
+			log.FromContext(ctx).WithValues("instance-type-count", len(p.spotPrices)).V(1).Info("updated spot pricing from rate card")
+		}
+		p.muSpot.Unlock()
+	}
+	return nil
+}
+
+// UpdateSpotPricing retrieves spot prices from the EC2 API, unless the rate card contains spot prices
+func (p *HTTPProvider) UpdateSpotPricing(ctx context.Context) error {
+	p.muRateCard.RLock()
+	rateCardSpot := p.rateCardSpot
+	p.muRateCard.RUnlock()
+	if rateCardSpot {
+		return nil
+	}
+	return p.DefaultProvider.UpdateSpotPricing(ctx)
+}
+
+// UpdateBulkOnDemandPricing is a no-op, since on-demand prices are retrieved from the rate card
+func (*HTTPProvider) UpdateBulkOnDemandPricing(context.Context) error { return nil }
+
+func (p *HTTPProvider) fetchRateCard(ctx context.Context) (Snapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("creating rate card request, %w", err)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("retrieving rate card, %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Snapshot{}, fmt.Errorf("retrieving rate card, unexpected status %s", resp.Status)
+	}
+	rateCard := Snapshot{}
+	if err := json.NewDecoder(resp.Body).Decode(&rateCard); err != nil {
+		return Snapshot{}, fmt.Errorf("decoding rate card, %w", err)
+	}
+	// A rate card without a region is assumed to be for the current region
+	if rateCard.Region != "" && rateCard.Region != p.region {
+		return Snapshot{}, fmt.Errorf("rate card is for region %q, expected %q", rateCard.Region, p.region)
+	}
+	return rateCard, nil
+}
+
+func newHTTPProvider(ctx context.Context, opts ProviderOptions) (Provider, error) {
+	url := options.FromContext(ctx).PricingEndpoint
+	if url == "" {
+		return nil, fmt.Errorf("pricing-endpoint must be set")
+	}
+	return NewHTTPProvider(ctx, url, opts), nil
+}
@@ -0,0 +1,248 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// PriceListAPIAvailable returns whether the pricing API can be used to retrieve prices for the region. The pricing API
+// is only available in the commercial and China partitions.
+func PriceListAPIAvailable(region string) bool {
+	return !strings.HasPrefix(region, "us-gov-") && !strings.Contains(region, "-iso")
+}
+
+// UseBulkPricing returns whether on-demand prices should be retrieved from the Price List bulk offer file rather than
+// the pricing API
+func UseBulkPricing(ctx context.Context, region string) bool {
+	if options.FromContext(ctx).BulkPricingRefreshInterval == 0 {
+		return false
+	}
+	// A configured offer file may be a mirror within the VPC, but the public offer files aren't reachable from an
+	// isolated VPC
+	if options.FromContext(ctx).BulkPricingURL != "" {
+		return true
+	}
+	return !options.FromContext(ctx).IsolatedVPC && !PriceListAPIAvailable(region) && BulkOfferURL(region) != ""
+}
+
+// BulkOfferURL returns the URL of the public EC2 offer file for the region. The offer files for the commercial and
+// GovCloud regions are published in the commercial partition, while those for the China regions are published in the
+// China partition. Offer files aren't published for the isolated partitions.
+func BulkOfferURL(region string) string {
+	switch {
+	case strings.Contains(region, "-iso"):
+		return ""
+	case strings.HasPrefix(region, "cn-"):
+		return fmt.Sprintf("https://pricing.cn-north-1.amazonaws.com.cn/offers/v1.0/cn/AmazonEC2/current/%s/index.json", region)
+	default:
+		return fmt.Sprintf("https://pricing.us-east-1.amazonaws.com/offers/v1.0/aws/AmazonEC2/current/%s/index.json", region)
+	}
+}
+
+// UpdateBulkOnDemandPricing retrieves on-demand prices from the Price List bulk offer file for the region
+func (p *DefaultProvider) UpdateBulkOnDemandPricing(ctx context.Context) error {
+	url := lo.Ternary(options.FromContext(ctx).BulkPricingURL != "", options.FromContext(ctx).BulkPricingURL, BulkOfferURL(p.region))
+	if url == "" {
+		return fmt.Errorf("no offer file is published for region %s", p.region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("creating offer file request, %w", err)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("getting offer file, %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("getting offer file, unexpected status %s", resp.Status)
+	}
+	prices, err := parseOfferFile(resp.Body, p.region, p.currency())
+	if err != nil {
+		return fmt.Errorf("parsing offer file, %w", err)
+	}
+	if len(prices) == 0 {
+		return fmt.Errorf("no on-demand pricing found in offer file")
+	}
+
+	p.muOnDemand.Lock()
+	defer p.muOnDemand.Unlock()
+	// Maintain previously retrieved pricing data
+	p.onDemandPrices = lo.Assign(p.onDemandPrices, prices)
+	p.onDemandPricingUpdated = true
+	if p.cm.HasChanged("on-demand-prices", p.onDemandPrices) {
+		log.FromContext(ctx).WithValues("instance-type-count", len(p.onDemandPrices), "url", url).V(1).Info("updated on-demand pricing from offer file")
+	}
+	return nil
+}
+
+// offerProduct is the portion of a product in an offer file that's used to select Linux on-demand compute prices. The
+// attributes match the filters used when retrieving prices from the pricing API.
+type offerProduct struct {
+	ProductFamily string `json:"productFamily"`
+	Attributes    struct {
+		InstanceType    string `json:"instanceType"`
+		RegionCode      string `json:"regionCode"`
+		Tenancy         string `json:"tenancy"`
+		OperatingSystem string `json:"operatingSystem"`
+		PreInstalledSw  string `json:"preInstalledSw"`
+		CapacityStatus  string `json:"capacitystatus"`
+		MarketOption    string `json:"marketoption"`
+	} `json:"attributes"`
+}
+
+func (o offerProduct) matches(region string) bool {
+	a := o.Attributes
+	if a.InstanceType == "" || a.RegionCode != region || a.OperatingSystem != "Linux" || a.PreInstalledSw != "NA" || a.CapacityStatus != "Used" {
+		return false
+	}
+	// Older offer files don't include the market option
+	if a.MarketOption != "" && a.MarketOption != "OnDemand" {
+		return false
+	}
+	return (o.ProductFamily == "Compute Instance" && a.Tenancy == "Shared") ||
+		(o.ProductFamily == "Compute Instance (bare metal)" && a.Tenancy == "Dedicated")
+}
+
+type offerTerm struct {
+	PriceDimensions map[string]struct {
+		PricePerUnit map[string]string `json:"pricePerUnit"`
+	} `json:"priceDimensions"`
+}
+
+// parseOfferFile returns the on-demand prices in an offer file. Offer files for EC2 are hundreds of megabytes, so the
+// file is decoded as a stream and only the matching products and their on-demand terms are retained. Products are
+// listed before terms in offer files, which allows the terms to be filtered as they're decoded.
+func parseOfferFile(r io.Reader, region, currency string) (map[ec2types.InstanceType]float64, error) {
+	dec := json.NewDecoder(r)
+	skus := map[string]ec2types.InstanceType{}
+	prices := map[ec2types.InstanceType]float64{}
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "products":
+			err = decodeObject(dec, func(sku string) error {
+				product := offerProduct{}
+				if err := dec.Decode(&product); err != nil {
+					return err
+				}
+				if product.matches(region) {
+					skus[sku] = ec2types.InstanceType(product.Attributes.InstanceType)
+				}
+				return nil
+			})
+		case "terms":
+			err = decodeObject(dec, func(termType string) error {
+				if termType != "OnDemand" {
+					return skipValue(dec)
+				}
+				return decodeObject(dec, func(sku string) error {
+					instanceType, ok := skus[sku]
+					if !ok {
+						return skipValue(dec)
+					}
+					terms := map[string]offerTerm{}
+					if err := dec.Decode(&terms); err != nil {
+						return err
+					}
+					for _, term := range terms {
+						for _, dimension := range term.PriceDimensions {
+							price, err := strconv.ParseFloat(dimension.PricePerUnit[currency], 64)
+							if err != nil || price == 0 {
+								continue
+							}
+							prices[instanceType] = price
+						}
+					}
+					return nil
+				})
+			})
+		default:
+			err = skipValue(dec)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return prices, nil
+}
+
+// decodeObject calls f with each key of the next object in the stream. f must consume the value of the key.
+func decodeObject(dec *json.Decoder, f func(string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := f(key.(string)); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// skipValue consumes the next value in the stream without retaining it
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %q, got %v", delim, token)
+	}
+	return nil
+}
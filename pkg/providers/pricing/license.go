@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	pricingtypes "github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// licensedPlatforms maps the platform details of an AMI, as reported by DescribeImages, to the operating system the
+// pricing API lists it under. AMIs with other platform details, including Linux/UNIX and bring-your-own-license
+// platforms, aren't billed for a license.
+var licensedPlatforms = map[string]string{
+	"Windows":                  "Windows",
+	"Red Hat Enterprise Linux": "RHEL",
+	"SUSE Linux":               "SUSE",
+}
+
+var licenseIncludedFilter = pricingtypes.Filter{
+	Field: aws.String("licenseModel"),
+	Type:  "TERM_MATCH",
+	Value: aws.String("No License required"),
+}
+
+// LicensePrice returns the hourly license cost of an instance type running an AMI with the platform details, which is
+// the difference between the on-demand price of the platform and of Linux. Platforms which aren't licensed have no cost.
+func (p *DefaultProvider) LicensePrice(instanceType ec2types.InstanceType, platformDetails string) (float64, bool) {
+	if _, ok := licensedPlatforms[platformDetails]; !ok {
+		return 0, true
+	}
+	p.muLicense.RLock()
+	defer p.muLicense.RUnlock()
+	price, ok := p.licensePrices[platformDetails][instanceType]
+	return price, ok
+}
+
+// UpdateLicensePricing retrieves the license costs of the licensed platforms in the region. Costs are only retrieved
+// when license costs are included in offering prices.
+func (p *DefaultProvider) UpdateLicensePricing(ctx context.Context) error {
+	if !options.FromContext(ctx).IncludeLicenseCost || options.FromContext(ctx).IsolatedVPC || !PriceListAPIAvailable(p.region) {
+		return nil
+	}
+	filters := append(slices.Clone(sharedTenancyFilters), licenseIncludedFilter)
+	// Linux prices are retrieved alongside the licensed platforms' rather than taken from the on-demand prices, which
+	// may come from a rate card or have Savings Plans applied
+	linuxPrices, err := p.fetchOnDemandPricingForOS(ctx, p.region, "Linux", filters...)
+	if err != nil {
+		return fmt.Errorf("retrieving license pricing data, %w", err)
+	}
+	licensePrices := map[string]map[ec2types.InstanceType]float64{}
+	for platformDetails, operatingSystem := range licensedPlatforms {
+		prices, err := p.fetchOnDemandPricingForOS(ctx, p.region, operatingSystem, filters...)
+		if err != nil {
+			return fmt.Errorf("retrieving license pricing data for %s, %w", operatingSystem, err)
+		}
+		licensePrices[platformDetails] = map[ec2types.InstanceType]float64{}
+		for instanceType, price := range prices {
+			linuxPrice, ok := linuxPrices[instanceType]
+			if !ok {
+				continue
+			}
+			licensePrices[platformDetails][instanceType] = max(price-linuxPrice, 0)
+		}
+	}
+	p.muLicense.Lock()
+	defer p.muLicense.Unlock()
+	// Maintain previously retrieved pricing data
+	for platformDetails, prices := range licensePrices {
+		if p.licensePrices[platformDetails] == nil {
+			p.licensePrices[platformDetails] = map[ec2types.InstanceType]float64{}
+		}
+		for instanceType, price := range prices {
+			p.licensePrices[platformDetails][instanceType] = price
+		}
+	}
+	if p.cm.HasChanged("license-prices", p.licensePrices) {
+		log.FromContext(ctx).WithValues("platforms", len(p.licensePrices)).V(1).Info("updated license pricing")
+	}
+	return nil
+}
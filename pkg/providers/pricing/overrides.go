@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// PriceOverridesKey is the ConfigMap data key which contains the price overrides
+const PriceOverridesKey = "overrides"
+
+// PriceOverride replaces the price of an instance type for a capacity type, either in a single zone or, if the zone is
+// omitted, in all zones. Overrides take precedence over the prices retrieved from the pricing and EC2 APIs, and allow
+// instance types without any known price to be launched.
+type PriceOverride struct {
+	InstanceType string  `json:"instanceType"`
+	CapacityType string  `json:"capacityType"`
+	Zone         string  `json:"zone,omitempty"`
+	Price        float64 `json:"price"`
+}
+
+type overrideKey struct {
+	instanceType ec2types.InstanceType
+	capacityType string
+	zone         string
+}
+
+// ParsePriceOverrides parses a YAML or JSON list of price overrides
+func ParsePriceOverrides(data string) ([]PriceOverride, error) {
+	var overrides []PriceOverride
+	if err := yaml.UnmarshalStrict([]byte(data), &overrides); err != nil {
+		return nil, fmt.Errorf("parsing price overrides, %w", err)
+	}
+	for i, o := range overrides {
+		if o.InstanceType == "" {
+			return nil, fmt.Errorf("price override %d is missing an instance type", i)
+		}
+		if o.CapacityType != karpv1.CapacityTypeOnDemand && o.CapacityType != karpv1.CapacityTypeSpot {
+			return nil, fmt.Errorf("price override %d has capacity type %q, must be one of %q or %q", i, o.CapacityType, karpv1.CapacityTypeOnDemand, karpv1.CapacityTypeSpot)
+		}
+		if o.Price < 0 {
+			return nil, fmt.Errorf("price override %d has negative price %v", i, o.Price)
+		}
+	}
+	return overrides, nil
+}
+
+// SetPriceOverrides replaces the current price overrides
+func (p *DefaultProvider) SetPriceOverrides(ctx context.Context, overrides []PriceOverride) {
+	p.muOverrides.Lock()
+	defer p.muOverrides.Unlock()
+	p.overrides = lo.SliceToMap(overrides, func(o PriceOverride) (overrideKey, float64) {
+		return overrideKey{instanceType: ec2types.InstanceType(o.InstanceType), capacityType: o.CapacityType, zone: o.Zone}, o.Price
+	})
+	if p.cm.HasChanged("price-overrides", p.overrides) {
+		log.FromContext(ctx).WithValues("count", len(p.overrides)).V(1).Info("updated price overrides")
+	}
+}
+
+// priceOverride returns the override for the instance type and capacity type in the zone, falling back to the override
+// for all zones
+func (p *DefaultProvider) priceOverride(instanceType ec2types.InstanceType, capacityType, zone string) (float64, bool) {
+	p.muOverrides.RLock()
+	defer p.muOverrides.RUnlock()
+	if price, ok := p.overrides[overrideKey{instanceType: instanceType, capacityType: capacityType, zone: zone}]; ok && zone != "" {
+		return price, true
+	}
+	price, ok := p.overrides[overrideKey{instanceType: instanceType, capacityType: capacityType}]
+	return price, ok
+}
+
+func (p *DefaultProvider) overriddenInstanceTypes() []ec2types.InstanceType {
+	p.muOverrides.RLock()
+	defer p.muOverrides.RUnlock()
+	return lo.Uniq(lo.Map(lo.Keys(p.overrides), func(k overrideKey, _ int) ec2types.InstanceType { return k.instanceType }))
+}
@@ -28,26 +28,63 @@ import (
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/pricing"
 	pricingtypes "github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"github.com/aws/aws-sdk-go-v2/service/savingsplans"
+	savingsplanstypes "github.com/aws/aws-sdk-go-v2/service/savingsplans/types"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 )
 
 var initialOnDemandPrices = lo.Assign(InitialOnDemandPricesAWS, InitialOnDemandPricesUSGov, InitialOnDemandPricesCN)
 
+// Provider supplies the prices that offerings are ranked by when launching and consolidating nodes. Prices are
+// hourly, in the currency of the region. Lookups are made on the scheduling path and must be served from memory; the
+// Update methods are called periodically by the pricing controllers to refresh the prices.
+//
+// Implementations other than the DefaultProvider can be made available with Register. Implementations which only
+// replace some of the prices can embed a *DefaultProvider and override the corresponding lookups.
 type Provider interface {
+	// LivenessProbe returns an error if the provider is unable to serve prices
 	LivenessProbe(*http.Request) error
+	// InstanceTypes returns the instance types with a known on-demand or spot price
 	InstanceTypes() []ec2types.InstanceType
+	// OnDemandPrice returns the regional on-demand price of an instance type
 	OnDemandPrice(ec2types.InstanceType) (float64, bool)
+	// ZonalOnDemandPrice returns the on-demand price of an instance type in a zone
+	ZonalOnDemandPrice(ec2types.InstanceType, string) (float64, bool)
+	// SpotPrice returns the spot price of an instance type in a zone
 	SpotPrice(ec2types.InstanceType, string) (float64, bool)
+	// UpdateOnDemandPricing refreshes the on-demand prices
 	UpdateOnDemandPricing(context.Context) error
+	// UpdateSpotPricing refreshes the spot prices
 	UpdateSpotPricing(context.Context) error
+	// UpdateSavingsPlanPricing refreshes the rates of the account's Savings Plans
+	UpdateSavingsPlanPricing(context.Context) error
+	// UpdateBulkOnDemandPricing refreshes the on-demand prices from a bulk offer file
+	UpdateBulkOnDemandPricing(context.Context) error
+	// VolumePrice returns the price of an EBS volume type
+	VolumePrice(ec2types.VolumeType) (VolumePrice, bool)
+	// UpdateVolumePricing refreshes the prices of EBS volume types
+	UpdateVolumePricing(context.Context) error
+	// LicensePrice returns the hourly license cost of an instance type running an AMI with the platform details
+	LicensePrice(ec2types.InstanceType, string) (float64, bool)
+	// UpdateLicensePricing refreshes the license costs of licensed platforms
+	UpdateLicensePricing(context.Context) error
+	// SetPriceOverrides replaces the prices which take precedence over all others
+	SetPriceOverrides(context.Context, []PriceOverride)
+	// Snapshot returns the current prices so that they can be persisted across restarts
+	Snapshot() Snapshot
+	// LoadSnapshot restores prices persisted by a previous Snapshot
+	LoadSnapshot(context.Context, Snapshot)
 }
 
 // DefaultProvider provides actual pricing data to the AWS cloud provider to allow it to make more informed decisions
@@ -57,17 +94,36 @@ type Provider interface {
 // fails, the previous pricing information is retained and used which may be the static initial pricing data if pricing
 // updates never succeed.
 type DefaultProvider struct {
-	ec2     sdk.EC2API
-	pricing sdk.PricingAPI
-	region  string
-	cm      *pretty.ChangeMonitor
-
-	muOnDemand     sync.RWMutex
-	onDemandPrices map[ec2types.InstanceType]float64
+	ec2          sdk.EC2API
+	pricing      sdk.PricingAPI
+	savingsPlans sdk.SavingsPlansAPI
+	zoneProvider zone.Provider
+	httpClient   *http.Client
+	region       string
+	cm           *pretty.ChangeMonitor
+
+	muOnDemand             sync.RWMutex
+	onDemandPrices         map[ec2types.InstanceType]float64
+	onDemandPricingUpdated bool
+	// localZonePrices are the on-demand prices in each Local Zone, keyed by zone name
+	localZonePrices map[string]map[ec2types.InstanceType]float64
 
 	muSpot             sync.RWMutex
 	spotPrices         map[ec2types.InstanceType]zonal
 	spotPricingUpdated bool
+
+	muSavingsPlans    sync.RWMutex
+	savingsPlanPrices map[ec2types.InstanceType]float64
+
+	muVolume     sync.RWMutex
+	volumePrices map[ec2types.VolumeType]VolumePrice
+
+	muOverrides sync.RWMutex
+	overrides   map[overrideKey]float64
+
+	muLicense sync.RWMutex
+	// licensePrices are the hourly license costs of each licensed platform, keyed by the AMI platform details
+	licensePrices map[string]map[ec2types.InstanceType]float64
 }
 
 // zonalPricing is used to capture the per-zone price
@@ -101,7 +157,7 @@ func newZonalPricing(defaultPrice float64) zonal {
 }
 
 // NewPricingAPI returns a pricing API configured based on a particular region
-func NewAPI(cfg aws.Config) *pricing.Client {
+func NewAPI(cfg aws.Config, optFns ...func(*pricing.Options)) *pricing.Client {
 	// pricing API doesn't have an endpoint in all regions
 	pricingAPIRegion := "us-east-1"
 	if strings.HasPrefix(cfg.Region, "ap-") {
@@ -114,15 +170,18 @@ func NewAPI(cfg aws.Config) *pricing.Client {
 	//create pricing config using pricing endpoint
 	pricingCfg := cfg.Copy()
 	pricingCfg.Region = pricingAPIRegion
-	return pricing.NewFromConfig(pricingCfg)
+	return pricing.NewFromConfig(pricingCfg, optFns...)
 }
 
-func NewDefaultProvider(_ context.Context, pricing sdk.PricingAPI, ec2Api sdk.EC2API, region string) *DefaultProvider {
+func NewDefaultProvider(_ context.Context, pricing sdk.PricingAPI, ec2Api sdk.EC2API, savingsPlans sdk.SavingsPlansAPI, zoneProvider zone.Provider, region string) *DefaultProvider {
 	p := &DefaultProvider{
-		region:  region,
-		ec2:     ec2Api,
-		pricing: pricing,
-		cm:      pretty.NewChangeMonitor(),
+		region:       region,
+		ec2:          ec2Api,
+		pricing:      pricing,
+		savingsPlans: savingsPlans,
+		zoneProvider: zoneProvider,
+		httpClient:   http.DefaultClient,
+		cm:           pretty.NewChangeMonitor(),
 	}
 	// sets the pricing data from the static default state for the provider
 	p.Reset()
@@ -136,24 +195,52 @@ func (p *DefaultProvider) InstanceTypes() []ec2types.InstanceType {
 	p.muSpot.RLock()
 	defer p.muOnDemand.RUnlock()
 	defer p.muSpot.RUnlock()
-	return lo.Union(lo.Keys(p.onDemandPrices), lo.Keys(p.spotPrices))
+	return lo.Union(lo.Keys(p.onDemandPrices), lo.Keys(p.spotPrices), p.overriddenInstanceTypes())
 }
 
 // OnDemandPrice returns the last known on-demand price for a given instance type, returning an error if there is no
-// known on-demand pricing for the instance type.
+// known on-demand pricing for the instance type. If the instance type is covered by one of the account's Savings Plans,
+// the lower Savings Plan rate is returned instead. Price overrides take precedence over both.
 func (p *DefaultProvider) OnDemandPrice(instanceType ec2types.InstanceType) (float64, bool) {
+	if price, ok := p.priceOverride(instanceType, karpv1.CapacityTypeOnDemand, ""); ok {
+		return price, true
+	}
 	p.muOnDemand.RLock()
 	defer p.muOnDemand.RUnlock()
 	price, ok := p.onDemandPrices[instanceType]
 	if !ok {
 		return 0.0, false
 	}
+	p.muSavingsPlans.RLock()
+	defer p.muSavingsPlans.RUnlock()
+	if rate, ok := p.savingsPlanPrices[instanceType]; ok && rate < price {
+		return rate, true
+	}
 	return price, true
 }
 
+// ZonalOnDemandPrice returns the on-demand price for a given instance type in a zone. This only differs from the
+// OnDemandPrice when a price override has been configured for the zone, or when the zone is a Local Zone with its own
+// price for the instance type. Savings Plans rates aren't applied to Local Zone prices.
+func (p *DefaultProvider) ZonalOnDemandPrice(instanceType ec2types.InstanceType, zone string) (float64, bool) {
+	if price, ok := p.priceOverride(instanceType, karpv1.CapacityTypeOnDemand, zone); ok {
+		return price, true
+	}
+	p.muOnDemand.RLock()
+	price, ok := p.localZonePrices[zone][instanceType]
+	p.muOnDemand.RUnlock()
+	if ok {
+		return price, true
+	}
+	return p.OnDemandPrice(instanceType)
+}
+
 // SpotPrice returns the last known spot price for a given instance type and zone, returning an error
 // if there is no known spot pricing for that instance type or zone
 func (p *DefaultProvider) SpotPrice(instanceType ec2types.InstanceType, zone string) (float64, bool) {
+	if price, ok := p.priceOverride(instanceType, karpv1.CapacityTypeSpot, zone); ok {
+		return price, true
+	}
 	p.muSpot.RLock()
 	defer p.muSpot.RUnlock()
 	if val, ok := p.spotPrices[instanceType]; ok {
@@ -168,6 +255,20 @@ func (p *DefaultProvider) SpotPrice(instanceType ec2types.InstanceType, zone str
 	return 0.0, false
 }
 
+// InstancePrice returns the hourly price of an instance launched with the capacity type. Capacity reservations are
+// billed at the on-demand price whether or not they're used, so reserved instances are priced as on-demand rather than
+// at the discount used to prefer them.
+func InstancePrice(p Provider, instanceType ec2types.InstanceType, capacityType, zone string) (float64, bool) {
+	switch capacityType {
+	case karpv1.CapacityTypeSpot:
+		return p.SpotPrice(instanceType, zone)
+	case karpv1.CapacityTypeOnDemand, karpv1.CapacityTypeReserved:
+		return p.ZonalOnDemandPrice(instanceType, zone)
+	default:
+		return 0, false
+	}
+}
+
 func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 	// standard on-demand instances
 	var wg sync.WaitGroup
@@ -182,6 +283,15 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 		}
 		return nil
 	}
+	if UseBulkPricing(ctx, p.region) {
+		if p.cm.HasChanged("on-demand-prices", nil) {
+			log.FromContext(ctx).V(1).Info("on-demand pricing information will be retrieved from the price list offer file")
+		}
+		return nil
+	}
+
+	// Local Zone prices require a request per zone group, so they're retrieved before taking the lock
+	localZonePrices := p.fetchLocalZoneOnDemandPricing(ctx)
 
 	p.muOnDemand.Lock()
 	defer p.muOnDemand.Unlock()
@@ -189,24 +299,14 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		onDemandPrices, onDemandErr = p.fetchOnDemandPricing(ctx,
-			pricingtypes.Filter{
-				Field: aws.String("tenancy"),
-				Type:  "TERM_MATCH",
-				Value: aws.String("Shared"),
-			},
-			pricingtypes.Filter{
-				Field: aws.String("productFamily"),
-				Type:  "TERM_MATCH",
-				Value: aws.String("Compute Instance"),
-			})
+		onDemandPrices, onDemandErr = p.fetchOnDemandPricing(ctx, p.region, sharedTenancyFilters...)
 	}()
 
 	// bare metal on-demand prices
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		onDemandMetalPrices, onDemandMetalErr = p.fetchOnDemandPricing(ctx,
+		onDemandMetalPrices, onDemandMetalErr = p.fetchOnDemandPricing(ctx, p.region,
 			pricingtypes.Filter{
 				Field: aws.String("tenancy"),
 				Type:  "TERM_MATCH",
@@ -232,19 +332,73 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 
 	// Maintain previously retrieved pricing data
 	p.onDemandPrices = lo.Assign(p.onDemandPrices, onDemandPrices, onDemandMetalPrices)
+	p.onDemandPricingUpdated = true
 	if p.cm.HasChanged("on-demand-prices", p.onDemandPrices) {
 		log.FromContext(ctx).WithValues("instance-type-count", len(p.onDemandPrices)).V(1).Info("updated on-demand pricing")
 	}
+	for zone, prices := range localZonePrices {
+		// Maintain previously retrieved pricing data
+		p.localZonePrices[zone] = lo.Assign(p.localZonePrices[zone], prices)
+	}
+	if p.cm.HasChanged("local-zone-on-demand-prices", p.localZonePrices) {
+		log.FromContext(ctx).WithValues("zones", lo.Keys(p.localZonePrices)).V(1).Info("updated local zone on-demand pricing")
+	}
 	return nil
 }
 
-func (p *DefaultProvider) fetchOnDemandPricing(ctx context.Context, additionalFilters ...pricingtypes.Filter) (map[ec2types.InstanceType]float64, error) {
+var sharedTenancyFilters = []pricingtypes.Filter{
+	{
+		Field: aws.String("tenancy"),
+		Type:  "TERM_MATCH",
+		Value: aws.String("Shared"),
+	},
+	{
+		Field: aws.String("productFamily"),
+		Type:  "TERM_MATCH",
+		Value: aws.String("Compute Instance"),
+	},
+}
+
+// fetchLocalZoneOnDemandPricing retrieves the on-demand prices in each of the Local Zones enabled for the account, keyed
+// by zone name. The pricing API lists Local Zones as their own locations, identified by the zone group name, and their
+// prices can differ significantly from the parent region's. Instance types without a price in a Local Zone, including
+// bare metal instance types, fall back to the regional price, as do the Local Zones whose prices can't be retrieved.
+func (p *DefaultProvider) fetchLocalZoneOnDemandPricing(ctx context.Context) map[string]map[ec2types.InstanceType]float64 {
+	groups := map[string][]string{}
+	for _, z := range p.zoneProvider.List() {
+		if z.Type != "local-zone" || z.GroupName == "" || !z.Enabled {
+			continue
+		}
+		groups[z.GroupName] = append(groups[z.GroupName], z.Name)
+	}
+	localZonePrices := map[string]map[ec2types.InstanceType]float64{}
+	for group, zones := range groups {
+		prices, err := p.fetchOnDemandPricing(ctx, group, sharedTenancyFilters...)
+		if err != nil {
+			log.FromContext(ctx).WithValues("zone-group", group).Error(err, "failed retrieving local zone on-demand pricing, falling back to regional pricing")
+			continue
+		}
+		for _, zone := range zones {
+			localZonePrices[zone] = prices
+		}
+	}
+	return localZonePrices
+}
+
+// fetchOnDemandPricing retrieves the on-demand Linux prices at a location, which is either a region or a Local Zone group
+func (p *DefaultProvider) fetchOnDemandPricing(ctx context.Context, location string, additionalFilters ...pricingtypes.Filter) (map[ec2types.InstanceType]float64, error) {
+	return p.fetchOnDemandPricingForOS(ctx, location, "Linux", additionalFilters...)
+}
+
+// fetchOnDemandPricingForOS retrieves the on-demand prices at a location for an operating system, as named by the
+// pricing API
+func (p *DefaultProvider) fetchOnDemandPricingForOS(ctx context.Context, location, operatingSystem string, additionalFilters ...pricingtypes.Filter) (map[ec2types.InstanceType]float64, error) {
 	prices := map[ec2types.InstanceType]float64{}
 	filters := append([]pricingtypes.Filter{
 		{
 			Field: aws.String("regionCode"),
 			Type:  "TERM_MATCH",
-			Value: aws.String(p.region),
+			Value: aws.String(location),
 		},
 		{
 			Field: aws.String("serviceCode"),
@@ -259,7 +413,7 @@ func (p *DefaultProvider) fetchOnDemandPricing(ctx context.Context, additionalFi
 		{
 			Field: aws.String("operatingSystem"),
 			Type:  "TERM_MATCH",
-			Value: aws.String("Linux"),
+			Value: aws.String(operatingSystem),
 		},
 		{
 			Field: aws.String("capacitystatus"),
@@ -291,14 +445,30 @@ func (p *DefaultProvider) fetchOnDemandPricing(ctx context.Context, additionalFi
 	return prices, nil
 }
 
+// logSkippedSpotPrices logs a single summary of the records in a page of spot price history which couldn't be parsed,
+// rather than one line for each record
+func logSkippedSpotPrices(ctx context.Context, skipped []ec2types.SpotPrice) {
+	if len(skipped) == 0 {
+		return
+	}
+	log.FromContext(ctx).WithValues(
+		"skipped-record-count", len(skipped),
+		"skipped-records", utils.PrettySlice(lo.Map(skipped, func(sph ec2types.SpotPrice, _ int) string {
+			return fmt.Sprintf("%s/%s=%s", sph.InstanceType, aws.ToString(sph.AvailabilityZone), aws.ToString(sph.SpotPrice))
+		}), 5),
+	).V(1).Info("unable to parse spot price records")
+}
+
 func (p *DefaultProvider) spotPage(ctx context.Context, output *ec2.DescribeSpotPriceHistoryOutput) map[ec2types.InstanceType]zonal {
 	result := map[ec2types.InstanceType]zonal{}
+	var skipped []ec2types.SpotPrice
+	defer func() { logSkippedSpotPrices(ctx, skipped) }()
 	for _, sph := range output.SpotPriceHistory {
 		spotPriceStr := aws.ToString(sph.SpotPrice)
 		spotPrice, err := strconv.ParseFloat(spotPriceStr, 64)
 		// these errors shouldn't occur, but if pricing API does have an error, we ignore the record
 		if err != nil {
-			log.FromContext(ctx).V(1).Info(fmt.Sprintf("unable to parse price record %#v", sph))
+			skipped = append(skipped, sph)
 			continue
 		}
 		if sph.Timestamp == nil {
@@ -339,10 +509,7 @@ func (p *DefaultProvider) onDemandPage(ctx context.Context, output *pricing.GetP
 	}
 
 	result := map[ec2types.InstanceType]float64{}
-	currency := "USD"
-	if strings.HasPrefix(p.region, "cn-") {
-		currency = "CNY"
-	}
+	currency := p.currency()
 	for _, outer := range output.PriceList {
 		pItem := &priceItem{}
 		if err := json.Unmarshal([]byte(outer), pItem); err != nil {
@@ -365,6 +532,14 @@ func (p *DefaultProvider) onDemandPage(ctx context.Context, output *pricing.GetP
 	return result
 }
 
+// currency returns the currency that prices in the region are published in
+func (p *DefaultProvider) currency() string {
+	if strings.HasPrefix(p.region, "cn-") {
+		return "CNY"
+	}
+	return "USD"
+}
+
 // nolint: gocyclo
 func (p *DefaultProvider) UpdateSpotPricing(ctx context.Context) error {
 	prices := map[ec2types.InstanceType]zonal{}
@@ -372,13 +547,21 @@ func (p *DefaultProvider) UpdateSpotPricing(ctx context.Context) error {
 	p.muSpot.Lock()
 	defer p.muSpot.Unlock()
 
+	now := time.Now()
+	percentile := options.FromContext(ctx).SpotPricePercentile
 	input := &ec2.DescribeSpotPriceHistoryInput{
 		ProductDescriptions: []string{
 			"Linux/UNIX",
 			"Linux/UNIX (Amazon VPC)",
 		},
 		// get the latest spot price for each instance type
-		StartTime: aws.Time(time.Now()),
+		StartTime: aws.Time(now),
+	}
+	history := map[ec2types.InstanceType]map[string][]spotPriceChange{}
+	if percentile != 0 {
+		// get every spot price change over the window, which the percentile is computed from
+		input.StartTime = aws.Time(now.Add(-SpotPriceHistoryWindow))
+		input.EndTime = aws.Time(now)
 	}
 
 	paginator := ec2.NewDescribeSpotPriceHistoryPaginator(p.ec2, input)
@@ -387,10 +570,17 @@ func (p *DefaultProvider) UpdateSpotPricing(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("retrieving spot pricing data, %w", err)
 		}
+		if percentile != 0 {
+			p.spotHistoryPage(ctx, output, history)
+			continue
+		}
 		for it, z := range p.spotPage(ctx, output) {
 			prices[it] = combineZonalPricing(prices[it], z)
 		}
 	}
+	if percentile != 0 {
+		prices = spotPricePercentiles(history, percentile, now.Add(-SpotPriceHistoryWindow), now)
+	}
 	if len(prices) == 0 {
 		return fmt.Errorf("no spot pricing found")
 	}
@@ -410,13 +600,123 @@ func (p *DefaultProvider) UpdateSpotPricing(ctx context.Context) error {
 	return nil
 }
 
+// UpdateSavingsPlanPricing discovers the rates for Linux instance types in the current region under each of the
+// account's active EC2 Instance and Compute Savings Plans. The rate is applied to all usage of the covered instance
+// types, without accounting for usage beyond the plans' hourly commitments, which is billed at the on-demand price.
+func (p *DefaultProvider) UpdateSavingsPlanPricing(ctx context.Context) error {
+	if !options.FromContext(ctx).SavingsPlansPricing || options.FromContext(ctx).IsolatedVPC {
+		return nil
+	}
+	p.muSavingsPlans.Lock()
+	defer p.muSavingsPlans.Unlock()
+
+	plans, err := p.describeSavingsPlans(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving savings plans, %w", err)
+	}
+	prices := map[ec2types.InstanceType]float64{}
+	for _, plan := range plans {
+		rates, err := p.describeSavingsPlanRates(ctx, lo.FromPtr(plan.SavingsPlanId))
+		if err != nil {
+			return fmt.Errorf("retrieving savings plan rates, %w", err)
+		}
+		for instanceType, rate := range rates {
+			if price, ok := prices[instanceType]; !ok || rate < price {
+				prices[instanceType] = rate
+			}
+		}
+	}
+	// Savings Plans expire, so previously retrieved rates are replaced rather than maintained
+	p.savingsPlanPrices = prices
+	if p.cm.HasChanged("savings-plan-prices", p.savingsPlanPrices) {
+		log.FromContext(ctx).WithValues(
+			"savings-plan-count", len(plans),
+			"instance-type-count", len(p.savingsPlanPrices)).V(1).Info("updated savings plan pricing")
+	}
+	return nil
+}
+
+// describeSavingsPlans returns the account's active Savings Plans which apply to EC2 usage in the current region
+func (p *DefaultProvider) describeSavingsPlans(ctx context.Context) ([]savingsplanstypes.SavingsPlan, error) {
+	var plans []savingsplanstypes.SavingsPlan
+	input := &savingsplans.DescribeSavingsPlansInput{States: []savingsplanstypes.SavingsPlanState{savingsplanstypes.SavingsPlanStateActive}}
+	for {
+		out, err := p.savingsPlans.DescribeSavingsPlans(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, lo.Filter(out.SavingsPlans, func(plan savingsplanstypes.SavingsPlan, _ int) bool {
+			// EC2 Instance Savings Plans are scoped to a single region, while Compute Savings Plans apply to all regions
+			return plan.SavingsPlanType == savingsplanstypes.SavingsPlanTypeCompute ||
+				(plan.SavingsPlanType == savingsplanstypes.SavingsPlanTypeEc2Instance && lo.FromPtr(plan.Region) == p.region)
+		})...)
+		if lo.FromPtr(out.NextToken) == "" {
+			return plans, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+func (p *DefaultProvider) describeSavingsPlanRates(ctx context.Context, id string) (map[ec2types.InstanceType]float64, error) {
+	rates := map[ec2types.InstanceType]float64{}
+	input := &savingsplans.DescribeSavingsPlanRatesInput{
+		SavingsPlanId: aws.String(id),
+		Filters: []savingsplanstypes.SavingsPlanRateFilter{
+			{Name: savingsplanstypes.SavingsPlanRateFilterNameRegion, Values: []string{p.region}},
+			{Name: savingsplanstypes.SavingsPlanRateFilterNameProductDescription, Values: []string{"Linux/UNIX"}},
+			{Name: savingsplanstypes.SavingsPlanRateFilterNameTenancy, Values: []string{"shared"}},
+		},
+	}
+	var skipped []string
+	for {
+		out, err := p.savingsPlans.DescribeSavingsPlanRates(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, rate := range out.SearchResults {
+			if rate.ProductType != savingsplanstypes.SavingsPlanProductTypeEc2 {
+				continue
+			}
+			instanceType := savingsPlanRateProperty(rate, savingsplanstypes.SavingsPlanRatePropertyKeyInstanceType)
+			price, err := strconv.ParseFloat(lo.FromPtr(rate.Rate), 64)
+			if err != nil || instanceType == "" || price == 0 {
+				skipped = append(skipped, fmt.Sprintf("%s=%s", instanceType, lo.FromPtr(rate.Rate)))
+				continue
+			}
+			rates[ec2types.InstanceType(instanceType)] = price
+		}
+		if lo.FromPtr(out.NextToken) == "" {
+			if len(skipped) != 0 {
+				log.FromContext(ctx).WithValues("savings-plan-id", id, "skipped-rate-count", len(skipped), "skipped-rates", utils.PrettySlice(skipped, 5)).V(1).Info("skipped savings plan rates")
+			}
+			return rates, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+func savingsPlanRateProperty(rate savingsplanstypes.SavingsPlanRate, key savingsplanstypes.SavingsPlanRatePropertyKey) string {
+	property, _ := lo.Find(rate.Properties, func(property savingsplanstypes.SavingsPlanRateProperty) bool {
+		return property.Name == key
+	})
+	return lo.FromPtr(property.Value)
+}
+
 func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
 	// ensure we don't deadlock and nolint for the empty critical section
 	p.muOnDemand.Lock()
 	p.muSpot.Lock()
+	p.muSavingsPlans.Lock()
+	p.muOverrides.Lock()
+	p.muVolume.Lock()
+	p.muLicense.Lock()
 	//nolint: staticcheck
 	p.muOnDemand.Unlock()
 	p.muSpot.Unlock()
+	p.muSavingsPlans.Unlock()
+	p.muOverrides.Unlock()
+	p.muVolume.Unlock()
+	p.muLicense.Unlock()
 	return nil
 }
 
@@ -437,7 +737,13 @@ func (p *DefaultProvider) Reset() {
 	}
 
 	p.onDemandPrices = staticPricing
+	p.onDemandPricingUpdated = false
+	p.localZonePrices = map[string]map[ec2types.InstanceType]float64{}
 	// default our spot pricing to the same as the on-demand pricing until a price update
 	p.spotPrices = populateInitialSpotPricing(staticPricing)
 	p.spotPricingUpdated = false
+	p.savingsPlanPrices = map[ec2types.InstanceType]float64{}
+	p.overrides = map[overrideKey]float64{}
+	p.volumePrices = lo.Assign(initialVolumePrices)
+	p.licensePrices = map[string]map[ec2types.InstanceType]float64{}
 }
@@ -0,0 +1,44 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// NextRefresh returns the delay until prices are next refreshed, given the refresh interval and the number of
+// consecutive refreshes which have failed. Failed refreshes are retried after the pricing-retry-backoff, which doubles
+// with each failure up to the refresh interval. The delay is extended by a random fraction of up to the
+// pricing-refresh-jitter, so that clusters which started at the same time don't call the pricing APIs at the same time.
+func NextRefresh(ctx context.Context, interval time.Duration, failures int) time.Duration {
+	delay := interval
+	if backoff := options.FromContext(ctx).PricingRetryBackoff; failures > 0 && backoff > 0 {
+		delay = backoff
+		for i := 1; i < failures && delay < interval; i++ {
+			delay *= 2
+		}
+		delay = min(delay, interval)
+	}
+	if jitter := options.FromContext(ctx).PricingRefreshJitter; jitter > 0 {
+		// wait.Jitter treats a factor of 0 as 1, so it's only applied when jitter is configured
+		delay = wait.Jitter(delay, jitter)
+	}
+	return delay
+}
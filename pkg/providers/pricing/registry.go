@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/samber/lo"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
+)
+
+// DefaultProviderName is the name of the built-in provider, which retrieves prices from the AWS Price List and EC2 APIs
+const DefaultProviderName = "aws"
+
+// ProviderOptions are the clients and configuration available to a pricing provider when it's constructed
+type ProviderOptions struct {
+	Region          string
+	EC2API          sdk.EC2API
+	PricingAPI      sdk.PricingAPI
+	SavingsPlansAPI sdk.SavingsPlansAPI
+	ZoneProvider    zone.Provider
+}
+
+// ProviderFactory constructs a pricing provider. It's called once at startup, and an error prevents the controller
+// from starting.
+type ProviderFactory func(context.Context, ProviderOptions) (Provider, error)
+
+var (
+	muFactories sync.RWMutex
+	factories   = map[string]ProviderFactory{
+		DefaultProviderName: func(ctx context.Context, opts ProviderOptions) (Provider, error) {
+			return NewDefaultProvider(ctx, opts.PricingAPI, opts.EC2API, opts.SavingsPlansAPI, opts.ZoneProvider, opts.Region), nil
+		},
+		StaticProviderName: func(ctx context.Context, opts ProviderOptions) (Provider, error) {
+			return NewStaticProvider(ctx, opts.Region), nil
+		},
+		HTTPProviderName: newHTTPProvider,
+	}
+)
+
+// Register makes a pricing provider available to be selected with the pricing-provider option. It's intended to be
+// called from the init function of the package implementing the provider, which is then imported by a controller
+// binary built around this module. Register panics if the name has already been registered.
+func Register(name string, factory ProviderFactory) {
+	muFactories.Lock()
+	defer muFactories.Unlock()
+	if factory == nil {
+		panic(fmt.Sprintf("registering pricing provider %q, factory is nil", name))
+	}
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("registering pricing provider %q, already registered", name))
+	}
+	factories[name] = factory
+}
+
+// Registered returns the sorted names of the registered pricing providers
+func Registered() []string {
+	muFactories.RLock()
+	defer muFactories.RUnlock()
+	names := lo.Keys(factories)
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider constructs the pricing provider registered with the name
+func NewProvider(ctx context.Context, name string, opts ProviderOptions) (Provider, error) {
+	muFactories.RLock()
+	factory, ok := factories[name]
+	muFactories.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("pricing provider %q is not registered, must be one of %v", name, Registered())
+	}
+	provider, err := factory(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("constructing pricing provider %q, %w", name, err)
+	}
+	return provider, nil
+}
@@ -0,0 +1,104 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"maps"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// SnapshotKey is the ConfigMap data key which contains the pricing snapshot
+const SnapshotKey = "snapshot"
+
+// Snapshot contains the prices retrieved from the pricing and EC2 APIs. Snapshots are persisted so that, after a
+// restart, the previously retrieved prices are used until pricing is next retrieved rather than the initial prices
+// embedded at build time. Prices which haven't been retrieved are omitted.
+type Snapshot struct {
+	Region   string                                       `json:"region"`
+	OnDemand map[ec2types.InstanceType]float64            `json:"onDemand,omitempty"`
+	Spot     map[ec2types.InstanceType]map[string]float64 `json:"spot,omitempty"`
+}
+
+// IsEmpty returns whether the snapshot doesn't contain any prices
+func (s Snapshot) IsEmpty() bool {
+	return len(s.OnDemand) == 0 && len(s.Spot) == 0
+}
+
+// Snapshot returns the prices which have been retrieved, or loaded from a previous snapshot. Savings Plans rates and
+// price overrides aren't included, since they're retrieved shortly after startup.
+func (p *DefaultProvider) Snapshot() Snapshot {
+	snapshot := Snapshot{Region: p.region}
+	p.muOnDemand.RLock()
+	if p.onDemandPricingUpdated {
+		snapshot.OnDemand = maps.Clone(p.onDemandPrices)
+	}
+	p.muOnDemand.RUnlock()
+	p.muSpot.RLock()
+	if p.spotPricingUpdated {
+		snapshot.Spot = lo.MapValues(p.spotPrices, func(z zonal, _ ec2types.InstanceType) map[string]float64 {
+			return maps.Clone(z.prices)
+		})
+	}
+	p.muSpot.RUnlock()
+	return snapshot
+}
+
+// LoadSnapshot replaces the initial prices with the prices in a snapshot. Prices which have already been retrieved
+// since startup aren't replaced, and snapshots taken in another region are ignored.
+func (p *DefaultProvider) LoadSnapshot(ctx context.Context, snapshot Snapshot) {
+	if snapshot.Region != p.region {
+		log.FromContext(ctx).WithValues("region", snapshot.Region).Info("ignoring pricing snapshot from another region")
+		return
+	}
+	p.muOnDemand.Lock()
+	if !p.onDemandPricingUpdated && len(snapshot.OnDemand) != 0 {
+		p.onDemandPrices = lo.Assign(p.onDemandPrices, snapshot.OnDemand)
+		p.onDemandPricingUpdated = true
+		log.FromContext(ctx).WithValues("instance-type-count", len(snapshot.OnDemand)).V(1).Info("loaded on-demand pricing from snapshot")
+	}
+	p.muOnDemand.Unlock()
+	p.muSpot.Lock()
+	if !p.spotPricingUpdated && len(snapshot.Spot) != 0 {
+		for it, prices := range snapshot.Spot {
+			p.spotPrices[it] = combineZonalPricing(p.spotPrices[it], zonal{prices: prices})
+		}
+		p.spotPricingUpdated = true
+		log.FromContext(ctx).WithValues("instance-type-count", len(snapshot.Spot)).V(1).Info("loaded spot pricing from snapshot")
+	}
+	p.muSpot.Unlock()
+}
+
+// ChangedInstanceTypes returns the instance types whose on-demand or spot prices differ between the snapshots
+func (s Snapshot) ChangedInstanceTypes(other Snapshot) []ec2types.InstanceType {
+	changed := sets.New[ec2types.InstanceType]()
+	for _, it := range lo.Union(lo.Keys(s.OnDemand), lo.Keys(other.OnDemand)) {
+		price, ok := s.OnDemand[it]
+		otherPrice, otherOK := other.OnDemand[it]
+		if ok != otherOK || price != otherPrice {
+			changed.Insert(it)
+		}
+	}
+	for _, it := range lo.Union(lo.Keys(s.Spot), lo.Keys(other.Spot)) {
+		if !maps.Equal(s.Spot[it], other.Spot[it]) {
+			changed.Insert(it)
+		}
+	}
+	return sets.List(changed)
+}
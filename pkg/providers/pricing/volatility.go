@@ -0,0 +1,115 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// SpotPriceHistoryWindow is the period of spot price history which the spot price percentile is computed over
+const SpotPriceHistoryWindow = 7 * 24 * time.Hour
+
+type spotPriceChange struct {
+	timestamp time.Time
+	price     float64
+}
+
+// spotHistoryPage adds the spot price changes in the page to the history for each instance type and zone
+func (p *DefaultProvider) spotHistoryPage(ctx context.Context, output *ec2.DescribeSpotPriceHistoryOutput, history map[ec2types.InstanceType]map[string][]spotPriceChange) {
+	var skipped []ec2types.SpotPrice
+	defer func() { logSkippedSpotPrices(ctx, skipped) }()
+	for _, sph := range output.SpotPriceHistory {
+		spotPrice, err := strconv.ParseFloat(aws.ToString(sph.SpotPrice), 64)
+		// these errors shouldn't occur, but if pricing API does have an error, we ignore the record
+		if err != nil {
+			skipped = append(skipped, sph)
+			continue
+		}
+		if sph.Timestamp == nil {
+			continue
+		}
+		if _, ok := history[sph.InstanceType]; !ok {
+			history[sph.InstanceType] = map[string][]spotPriceChange{}
+		}
+		az := aws.ToString(sph.AvailabilityZone)
+		history[sph.InstanceType][az] = append(history[sph.InstanceType][az], spotPriceChange{timestamp: *sph.Timestamp, price: spotPrice})
+	}
+}
+
+// spotPricePercentiles returns the given percentile of the spot price for each instance type and zone between start and
+// end, weighted by how long each price was in effect
+func spotPricePercentiles(history map[ec2types.InstanceType]map[string][]spotPriceChange, percentile int, start, end time.Time) map[ec2types.InstanceType]zonal {
+	prices := map[ec2types.InstanceType]zonal{}
+	for instanceType, zones := range history {
+		z := newZonalPricing(0)
+		for zone, changes := range zones {
+			z.prices[zone] = timeWeightedPercentile(changes, percentile, start, end)
+		}
+		prices[instanceType] = z
+	}
+	return prices
+}
+
+// timeWeightedPercentile returns the lowest price which was in effect for at least the given percentage of the time
+// between start and end. Each price is in effect from its timestamp until the next change. The history may include a
+// change from before start, which is the price that was in effect at start.
+func timeWeightedPercentile(changes []spotPriceChange, percentile int, start, end time.Time) float64 {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].timestamp.Before(changes[j].timestamp)
+	})
+	type weightedPrice struct {
+		price    float64
+		duration time.Duration
+	}
+	var weighted []weightedPrice
+	var total time.Duration
+	for i, change := range changes {
+		from, to := change.timestamp, end
+		if i+1 < len(changes) {
+			to = changes[i+1].timestamp
+		}
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(from) {
+			weighted = append(weighted, weightedPrice{price: change.price, duration: to.Sub(from)})
+			total += to.Sub(from)
+		}
+	}
+	// None of the prices were in effect for a measurable period, so the latest price is the best estimate
+	if total == 0 {
+		return changes[len(changes)-1].price
+	}
+	sort.Slice(weighted, func(i, j int) bool {
+		return weighted[i].price < weighted[j].price
+	})
+	threshold := time.Duration(math.Ceil(float64(total) * float64(percentile) / 100))
+	var elapsed time.Duration
+	for _, w := range weighted {
+		elapsed += w.duration
+		if elapsed >= threshold {
+			return w.price
+		}
+	}
+	return weighted[len(weighted)-1].price
+}
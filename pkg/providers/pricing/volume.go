@@ -0,0 +1,199 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	pricingtypes "github.com/aws/aws-sdk-go-v2/service/pricing/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// HoursPerMonth is the number of hours which EBS uses to prorate monthly prices
+const HoursPerMonth = 730
+
+// VolumePrice is the monthly price of an EBS volume type. Volumes are billed for their provisioned size, along with
+// their provisioned IOPS and throughput above the baseline included with the volume type.
+type VolumePrice struct {
+	// GiBMonth is the price of a GiB of storage per month
+	GiBMonth float64
+	// IOPSMonth is the price of a provisioned IOPS per month
+	IOPSMonth float64
+	// ThroughputMonth is the price of a provisioned MiB/s of throughput per month
+	ThroughputMonth float64
+	// BaselineIOPS is the IOPS included in the price of the storage
+	BaselineIOPS int64
+	// BaselineThroughput is the throughput in MiB/s included in the price of the storage
+	BaselineThroughput int64
+}
+
+// HourlyCost returns the price of a volume per hour
+func (v VolumePrice) HourlyCost(sizeGiB, iops, throughput int64) float64 {
+	monthly := float64(sizeGiB)*v.GiBMonth +
+		float64(max(iops-v.BaselineIOPS, 0))*v.IOPSMonth +
+		float64(max(throughput-v.BaselineThroughput, 0))*v.ThroughputMonth
+	return monthly / HoursPerMonth
+}
+
+// initialVolumePrices are the prices of EBS volume types in us-east-1, which are used until prices for the region have
+// been retrieved. io2 IOPS are priced at the first tier.
+var initialVolumePrices = map[ec2types.VolumeType]VolumePrice{
+	ec2types.VolumeTypeGp3:      {GiBMonth: 0.08, IOPSMonth: 0.005, ThroughputMonth: 0.04, BaselineIOPS: 3000, BaselineThroughput: 125},
+	ec2types.VolumeTypeGp2:      {GiBMonth: 0.10},
+	ec2types.VolumeTypeIo1:      {GiBMonth: 0.125, IOPSMonth: 0.065},
+	ec2types.VolumeTypeIo2:      {GiBMonth: 0.125, IOPSMonth: 0.065},
+	ec2types.VolumeTypeSt1:      {GiBMonth: 0.045},
+	ec2types.VolumeTypeSc1:      {GiBMonth: 0.015},
+	ec2types.VolumeTypeStandard: {GiBMonth: 0.05},
+}
+
+// VolumePrice returns the price of an EBS volume type
+func (p *DefaultProvider) VolumePrice(volumeType ec2types.VolumeType) (VolumePrice, bool) {
+	p.muVolume.RLock()
+	defer p.muVolume.RUnlock()
+	price, ok := p.volumePrices[volumeType]
+	return price, ok
+}
+
+// UpdateVolumePricing retrieves the prices of EBS volume types in the region. Prices are only retrieved when volume
+// costs are included in offering prices.
+func (p *DefaultProvider) UpdateVolumePricing(ctx context.Context) error {
+	if !options.FromContext(ctx).IncludeVolumeCost || options.FromContext(ctx).IsolatedVPC || !PriceListAPIAvailable(p.region) {
+		return nil
+	}
+	products, err := p.fetchVolumeProducts(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieving volume pricing data, %w", err)
+	}
+	p.muVolume.Lock()
+	defer p.muVolume.Unlock()
+	prices := maps.Clone(p.volumePrices)
+	iopsPrices := map[ec2types.VolumeType]float64{}
+	for _, product := range products {
+		volumeType := ec2types.VolumeType(product.volumeType)
+		price, ok := prices[volumeType]
+		if !ok {
+			continue
+		}
+		switch product.family {
+		case "Storage":
+			price.GiBMonth = product.price
+		case "System Operation":
+			// io2 IOPS are priced in tiers, of which the first is the most expensive
+			iopsPrices[volumeType] = max(iopsPrices[volumeType], product.price)
+		case "Provisioned Throughput":
+			price.ThroughputMonth = product.price
+		}
+		prices[volumeType] = price
+	}
+	for volumeType, iopsPrice := range iopsPrices {
+		price := prices[volumeType]
+		price.IOPSMonth = iopsPrice
+		prices[volumeType] = price
+	}
+	p.volumePrices = prices
+	if p.cm.HasChanged("volume-prices", p.volumePrices) {
+		log.FromContext(ctx).WithValues("volume-type-count", len(p.volumePrices)).V(1).Info("updated volume pricing")
+	}
+	return nil
+}
+
+type volumeProduct struct {
+	family     string
+	volumeType string
+	price      float64
+}
+
+// fetchVolumeProducts retrieves the prices of EBS storage, provisioned IOPS and provisioned throughput in the region
+func (p *DefaultProvider) fetchVolumeProducts(ctx context.Context) ([]volumeProduct, error) {
+	var products []volumeProduct
+	for _, family := range []string{"Storage", "System Operation", "Provisioned Throughput"} {
+		filters := []pricingtypes.Filter{
+			{Field: aws.String("regionCode"), Type: "TERM_MATCH", Value: aws.String(p.region)},
+			{Field: aws.String("serviceCode"), Type: "TERM_MATCH", Value: aws.String("AmazonEC2")},
+			{Field: aws.String("productFamily"), Type: "TERM_MATCH", Value: aws.String(family)},
+		}
+		if family == "System Operation" {
+			filters = append(filters, pricingtypes.Filter{Field: aws.String("group"), Type: "TERM_MATCH", Value: aws.String("EBS IOPS")})
+		}
+		paginator := pricing.NewGetProductsPaginator(p.pricing, &pricing.GetProductsInput{
+			Filters:     filters,
+			ServiceCode: aws.String("AmazonEC2"),
+		})
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("getting pricing data, %w", err)
+			}
+			products = append(products, p.volumePage(ctx, output)...)
+		}
+	}
+	return products, nil
+}
+
+func (p *DefaultProvider) volumePage(ctx context.Context, output *pricing.GetProductsOutput) []volumeProduct {
+	// this isn't the full pricing struct, just the portions we care about
+	type priceItem struct {
+		Product struct {
+			ProductFamily string `json:"productFamily"`
+			Attributes    struct {
+				VolumeAPIName string `json:"volumeApiName"`
+				Group         string `json:"group"`
+			}
+		}
+		Terms struct {
+			OnDemand map[string]struct {
+				PriceDimensions map[string]struct {
+					PricePerUnit map[string]string
+				}
+			}
+		}
+	}
+	var result []volumeProduct
+	currency := p.currency()
+	for _, outer := range output.PriceList {
+		pItem := &priceItem{}
+		if err := json.Unmarshal([]byte(outer), pItem); err != nil {
+			log.FromContext(ctx).Error(err, "failed unmarshaling pricing data")
+			continue
+		}
+		if pItem.Product.Attributes.VolumeAPIName == "" {
+			continue
+		}
+		// Other system operations, such as I/O requests to magnetic volumes, are billed by usage rather than provisioned
+		if pItem.Product.ProductFamily == "System Operation" && pItem.Product.Attributes.Group != "EBS IOPS" {
+			continue
+		}
+		for _, term := range pItem.Terms.OnDemand {
+			for _, v := range term.PriceDimensions {
+				price, err := strconv.ParseFloat(v.PricePerUnit[currency], 64)
+				if err != nil || price == 0 {
+					continue
+				}
+				result = append(result, volumeProduct{family: pItem.Product.ProductFamily, volumeType: pItem.Product.Attributes.VolumeAPIName, price: price})
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,178 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reservedinstance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+type Provider interface {
+	// Coverage returns the number of unused Reserved Instances which would apply to an instance of the given type
+	// launched in the given zone
+	Coverage(ec2types.InstanceType, string) int
+	MarkLaunched(ec2types.InstanceType, string)
+	UpdateReservedInstances(context.Context) error
+}
+
+type zonalKey struct {
+	instanceType ec2types.InstanceType
+	zone         string
+}
+
+// DefaultProvider tracks the account's unused Reserved Instances. Only Linux, default tenancy Reserved Instances are
+// considered, and regional Reserved Instances are only matched against their exact instance type rather than using
+// size flexibility within the instance family.
+type DefaultProvider struct {
+	sync.RWMutex
+
+	ec2api   sdk.EC2API
+	cm       *pretty.ChangeMonitor
+	zonal    map[zonalKey]int
+	regional map[ec2types.InstanceType]int
+}
+
+func NewDefaultProvider(ec2api sdk.EC2API) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api:   ec2api,
+		cm:       pretty.NewChangeMonitor(),
+		zonal:    map[zonalKey]int{},
+		regional: map[ec2types.InstanceType]int{},
+	}
+}
+
+func (p *DefaultProvider) Coverage(instanceType ec2types.InstanceType, zone string) int {
+	p.RLock()
+	defer p.RUnlock()
+	return p.zonal[zonalKey{instanceType: instanceType, zone: zone}] + p.regional[instanceType]
+}
+
+// MarkLaunched consumes the coverage for a newly launched instance, so that it isn't over-subscribed before the
+// Reserved Instances are next refreshed. Zonal Reserved Instances are consumed first, matching how EC2 applies them.
+func (p *DefaultProvider) MarkLaunched(instanceType ec2types.InstanceType, zone string) {
+	p.Lock()
+	defer p.Unlock()
+	key := zonalKey{instanceType: instanceType, zone: zone}
+	if p.zonal[key] > 0 {
+		p.zonal[key]--
+	} else if p.regional[instanceType] > 0 {
+		p.regional[instanceType]--
+	}
+}
+
+// UpdateReservedInstances discovers the account's active Reserved Instances and subtracts the running instances which
+// already consume them.
+func (p *DefaultProvider) UpdateReservedInstances(ctx context.Context) error {
+	if !options.FromContext(ctx).ReservedInstanceCoverage {
+		p.Reset()
+		return nil
+	}
+	out, err := p.ec2api.DescribeReservedInstances(ctx, &ec2.DescribeReservedInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("state"), Values: []string{string(ec2types.ReservedInstanceStateActive)}},
+			{Name: aws.String("product-description"), Values: []string{"Linux/UNIX", "Linux/UNIX (Amazon VPC)"}},
+			{Name: aws.String("instance-tenancy"), Values: []string{string(ec2types.TenancyDefault)}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("describing reserved instances, %w", err)
+	}
+	zonal := map[zonalKey]int{}
+	regional := map[ec2types.InstanceType]int{}
+	for _, ri := range out.ReservedInstances {
+		count := int(lo.FromPtr(ri.InstanceCount))
+		if ri.Scope == ec2types.ScopeAvailabilityZone {
+			zonal[zonalKey{instanceType: ri.InstanceType, zone: lo.FromPtr(ri.AvailabilityZone)}] += count
+		} else {
+			regional[ri.InstanceType] += count
+		}
+	}
+	if len(zonal) != 0 || len(regional) != 0 {
+		running, err := p.runningInstances(ctx, lo.Uniq(append(lo.Keys(regional), lo.Map(lo.Keys(zonal), func(k zonalKey, _ int) ec2types.InstanceType {
+			return k.instanceType
+		})...)))
+		if err != nil {
+			return err
+		}
+		consume(zonal, regional, running)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	p.zonal = lo.PickBy(zonal, func(_ zonalKey, v int) bool { return v > 0 })
+	p.regional = lo.PickBy(regional, func(_ ec2types.InstanceType, v int) bool { return v > 0 })
+	if p.cm.HasChanged("reserved-instances", []any{p.zonal, p.regional}) {
+		log.FromContext(ctx).WithValues(
+			"zonal-count", lo.Sum(lo.Values(p.zonal)),
+			"regional-count", lo.Sum(lo.Values(p.regional))).V(1).Info("discovered unused reserved instances")
+	}
+	return nil
+}
+
+// runningInstances returns the number of pending or running instances of each of the given types in each zone
+func (p *DefaultProvider) runningInstances(ctx context.Context, instanceTypes []ec2types.InstanceType) (map[zonalKey]int, error) {
+	running := map[zonalKey]int{}
+	paginator := ec2.NewDescribeInstancesPaginator(p.ec2api, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("instance-state-name"), Values: []string{string(ec2types.InstanceStateNamePending), string(ec2types.InstanceStateNameRunning)}},
+			{Name: aws.String("instance-type"), Values: lo.Map(instanceTypes, func(t ec2types.InstanceType, _ int) string { return string(t) })},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing instances, %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				// Spot and capacity block instances aren't billed at the on-demand rate, so they don't consume Reserved Instances
+				if instance.InstanceLifecycle != "" {
+					continue
+				}
+				running[zonalKey{instanceType: instance.InstanceType, zone: lo.FromPtr(lo.FromPtr(instance.Placement).AvailabilityZone)}]++
+			}
+		}
+	}
+	return running, nil
+}
+
+// consume subtracts the running instances from the Reserved Instances in place, applying zonal Reserved Instances before
+// regional ones
+func consume(zonal map[zonalKey]int, regional map[ec2types.InstanceType]int, running map[zonalKey]int) {
+	for key, count := range running {
+		applied := lo.Min([]int{zonal[key], count})
+		zonal[key] -= applied
+		applied = lo.Min([]int{regional[key.instanceType], count - applied})
+		regional[key.instanceType] -= applied
+	}
+}
+
+func (p *DefaultProvider) Reset() {
+	p.Lock()
+	defer p.Unlock()
+	p.zonal = map[zonalKey]int{}
+	p.regional = map[ec2types.InstanceType]int{}
+}
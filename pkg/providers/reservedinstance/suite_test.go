@@ -0,0 +1,129 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reservedinstance_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ReservedInstanceProvider")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ReservedInstanceCoverage: lo.ToPtr(true)}))
+	awsEnv = test.NewEnvironment(ctx, env)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+func runningInstance(instanceType ec2types.InstanceType, zone string) ec2types.Instance {
+	return ec2types.Instance{
+		InstanceId:   lo.ToPtr(fake.InstanceID()),
+		InstanceType: instanceType,
+		Placement:    &ec2types.Placement{AvailabilityZone: lo.ToPtr(zone)},
+		State:        &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+	}
+}
+
+var _ = Describe("ReservedInstanceProvider", func() {
+	BeforeEach(func() {
+		awsEnv.EC2API.DescribeReservedInstancesBehavior.Output.Set(&ec2.DescribeReservedInstancesOutput{
+			ReservedInstances: []ec2types.ReservedInstances{
+				{
+					InstanceType:     "m5.large",
+					InstanceCount:    lo.ToPtr[int32](2),
+					Scope:            ec2types.ScopeAvailabilityZone,
+					AvailabilityZone: lo.ToPtr("test-zone-1a"),
+				},
+				{
+					InstanceType:  "m5.large",
+					InstanceCount: lo.ToPtr[int32](1),
+					Scope:         ec2types.ScopeRegional,
+				},
+			},
+		})
+	})
+	It("should report zonal and regional coverage", func() {
+		Expect(awsEnv.ReservedInstanceProvider.UpdateReservedInstances(ctx)).To(Succeed())
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("m5.large", "test-zone-1a")).To(Equal(3))
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("m5.large", "test-zone-1b")).To(Equal(1))
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("c5.large", "test-zone-1a")).To(Equal(0))
+	})
+	It("should subtract running instances from zonal coverage before regional coverage", func() {
+		for _, instance := range []ec2types.Instance{
+			runningInstance("m5.large", "test-zone-1a"),
+			runningInstance("m5.large", "test-zone-1b"),
+		} {
+			awsEnv.EC2API.Instances.Store(*instance.InstanceId, instance)
+		}
+		Expect(awsEnv.ReservedInstanceProvider.UpdateReservedInstances(ctx)).To(Succeed())
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("m5.large", "test-zone-1a")).To(Equal(1))
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("m5.large", "test-zone-1b")).To(Equal(0))
+	})
+	It("should not subtract spot instances from coverage", func() {
+		instance := runningInstance("m5.large", "test-zone-1b")
+		instance.InstanceLifecycle = ec2types.InstanceLifecycleTypeSpot
+		awsEnv.EC2API.Instances.Store(*instance.InstanceId, instance)
+		Expect(awsEnv.ReservedInstanceProvider.UpdateReservedInstances(ctx)).To(Succeed())
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("m5.large", "test-zone-1b")).To(Equal(1))
+	})
+	It("should consume coverage when instances are launched", func() {
+		Expect(awsEnv.ReservedInstanceProvider.UpdateReservedInstances(ctx)).To(Succeed())
+		awsEnv.ReservedInstanceProvider.MarkLaunched("m5.large", "test-zone-1a")
+		awsEnv.ReservedInstanceProvider.MarkLaunched("m5.large", "test-zone-1a")
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("m5.large", "test-zone-1a")).To(Equal(1))
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("m5.large", "test-zone-1b")).To(Equal(1))
+		awsEnv.ReservedInstanceProvider.MarkLaunched("m5.large", "test-zone-1b")
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("m5.large", "test-zone-1a")).To(Equal(0))
+	})
+	It("should not discover reserved instances when coverage is disabled", func() {
+		disabledCtx := options.ToContext(ctx, test.Options())
+		Expect(awsEnv.ReservedInstanceProvider.UpdateReservedInstances(disabledCtx)).To(Succeed())
+		Expect(awsEnv.EC2API.DescribeReservedInstancesBehavior.CalledWithInput.Len()).To(Equal(0))
+		Expect(awsEnv.ReservedInstanceProvider.Coverage("m5.large", "test-zone-1a")).To(Equal(0))
+	})
+})
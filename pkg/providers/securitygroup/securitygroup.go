@@ -35,6 +35,7 @@ import (
 
 type Provider interface {
 	List(context.Context, *v1.EC2NodeClass) ([]ec2types.SecurityGroup, error)
+	ListByTerms(context.Context, []v1.SecurityGroupSelectorTerm) ([]ec2types.SecurityGroup, error)
 }
 
 type DefaultProvider struct {
@@ -72,6 +73,14 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass)
 	return securityGroups, nil
 }
 
+// ListByTerms returns the security groups which the selector terms select, such as the security groups of an
+// EC2NodeClass's additional network interfaces
+func (p *DefaultProvider) ListByTerms(ctx context.Context, terms []v1.SecurityGroupSelectorTerm) ([]ec2types.SecurityGroup, error) {
+	p.Lock()
+	defer p.Unlock()
+	return p.getSecurityGroups(ctx, getFilterSets(terms))
+}
+
 func (p *DefaultProvider) getSecurityGroups(ctx context.Context, filterSets [][]ec2types.Filter) ([]ec2types.SecurityGroup, error) {
 	hash, err := hashstructure.Hash(filterSets, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	if err != nil {
@@ -103,10 +112,22 @@ func getFilterSets(terms []v1.SecurityGroupSelectorTerm) (res [][]ec2types.Filte
 		switch {
 		case term.ID != "":
 			idFilter.Values = append(idFilter.Values, term.ID)
-		case term.Name != "":
+		case term.Name != "" && term.VPCID == "":
 			nameFilter.Values = append(nameFilter.Values, term.Name)
 		default:
 			var filters []ec2types.Filter
+			if term.Name != "" {
+				filters = append(filters, ec2types.Filter{
+					Name:   aws.String("group-name"),
+					Values: []string{term.Name},
+				})
+			}
+			if term.VPCID != "" {
+				filters = append(filters, ec2types.Filter{
+					Name:   aws.String("vpc-id"),
+					Values: []string{term.VPCID},
+				})
+			}
 			for k, v := range term.Tags {
 				if v == "*" {
 					filters = append(filters, ec2types.Filter{
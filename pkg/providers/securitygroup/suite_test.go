@@ -271,6 +271,39 @@ var _ = Describe("SecurityGroupProvider", func() {
 			},
 		}, securityGroups)
 	})
+	It("should discover security groups by name wildcards", func() {
+		nodeClass.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{
+			{
+				Name: "securityGroup-test?",
+			},
+		}
+		securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
+		Expect(err).To(BeNil())
+		Expect(lo.Map(securityGroups, func(sg ec2types.SecurityGroup, _ int) string { return aws.ToString(sg.GroupId) })).To(ConsistOf("sg-test1", "sg-test2", "sg-test3"))
+	})
+	Context("VPC ID", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeSecurityGroupsOutput.Set(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{
+				{GroupId: aws.String("sg-nodes-a"), GroupName: aws.String("eks-nodes"), VpcId: aws.String("vpc-a"),
+					Tags: []ec2types.Tag{{Key: aws.String("team"), Value: aws.String("platform")}}},
+				{GroupId: aws.String("sg-nodes-b"), GroupName: aws.String("eks-nodes"), VpcId: aws.String("vpc-b"),
+					Tags: []ec2types.Tag{{Key: aws.String("team"), Value: aws.String("platform")}}},
+				{GroupId: aws.String("sg-pods-b"), GroupName: aws.String("eks-pods"), VpcId: aws.String("vpc-b")},
+			}})
+		})
+		DescribeTable("should discover security groups scoped to a vpc",
+			func(term v1.SecurityGroupSelectorTerm, expected ...string) {
+				nodeClass.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{term}
+				securityGroups, err := awsEnv.SecurityGroupProvider.List(ctx, nodeClass)
+				Expect(err).To(BeNil())
+				Expect(lo.Map(securityGroups, func(sg ec2types.SecurityGroup, _ int) string { return aws.ToString(sg.GroupId) })).To(ConsistOf(expected))
+			},
+			Entry("without a vpc", v1.SecurityGroupSelectorTerm{Name: "eks-nodes"}, "sg-nodes-a", "sg-nodes-b"),
+			Entry("by name", v1.SecurityGroupSelectorTerm{Name: "eks-nodes", VPCID: "vpc-b"}, "sg-nodes-b"),
+			Entry("by name wildcards", v1.SecurityGroupSelectorTerm{Name: "eks-*", VPCID: "vpc-b"}, "sg-nodes-b", "sg-pods-b"),
+			Entry("by tags", v1.SecurityGroupSelectorTerm{Tags: map[string]string{"team": "platform"}, VPCID: "vpc-a"}, "sg-nodes-a"),
+		)
+	})
 	Context("Provider Cache", func() {
 		It("should resolve security groups from cache that are filtered by id", func() {
 			expectedSecurityGroups := awsEnv.EC2API.DescribeSecurityGroupsOutput.Clone().SecurityGroups
@@ -365,6 +398,8 @@ var _ = Describe("SecurityGroupProvider", func() {
 								Value: lo.ToPtr("bar"),
 							},
 						},
+						IpPermissions:       []ec2types.IpPermission{{IpProtocol: lo.ToPtr("-1"), UserIdGroupPairs: []ec2types.UserIdGroupPair{{GroupId: lo.ToPtr("sg-test1")}}}},
+						IpPermissionsEgress: []ec2types.IpPermission{{IpProtocol: lo.ToPtr("-1"), UserIdGroupPairs: []ec2types.UserIdGroupPair{{GroupId: lo.ToPtr("sg-test1")}}}},
 					},
 					{
 						GroupId:   lo.ToPtr("sg-test2"),
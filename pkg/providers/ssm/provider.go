@@ -19,27 +19,32 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 )
 
 type Provider interface {
 	Get(context.Context, Parameter) (string, error)
+	GetByPath(context.Context, string) (map[string]string, error)
 }
 
 type DefaultProvider struct {
 	sync.Mutex
 	cache  *cache.Cache
 	ssmapi sdk.SSMAPI
+	cm     *pretty.ChangeMonitor
 }
 
 func NewDefaultProvider(ssmapi sdk.SSMAPI, cache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		ssmapi: ssmapi,
 		cache:  cache,
+		cm:     pretty.NewChangeMonitor(),
 	}
 }
 
@@ -57,6 +62,38 @@ func (p *DefaultProvider) Get(ctx context.Context, parameter Parameter) (string,
 		Parameter: parameter,
 		Value:     lo.FromPtr(result.Parameter.Value),
 	})
-	log.FromContext(ctx).WithValues("parameter", parameter.Name, "value", result.Parameter.Value).Info("discovered ssm parameter")
+	// Parameters are read again each time their cache entry expires, so they're only logged when their value changes
+	if p.cm.HasChanged(fmt.Sprintf("parameter/%s", parameter.Name), lo.FromPtr(result.Parameter.Value)) {
+		log.FromContext(ctx).WithValues("parameter", parameter.Name, "value", lo.FromPtr(result.Parameter.Value)).V(1).Info("discovered ssm parameter")
+	}
 	return lo.FromPtr(result.Parameter.Value), nil
 }
+
+// GetByPath returns the values of every parameter under the path, including those nested in sub-paths, by name
+func (p *DefaultProvider) GetByPath(ctx context.Context, path string) (map[string]string, error) {
+	p.Lock()
+	defer p.Unlock()
+	key := PathCacheKey(path)
+	if entry, ok := p.cache.Get(key); ok {
+		return entry.(PathCacheEntry).Values, nil
+	}
+	values := map[string]string{}
+	paginator := ssm.NewGetParametersByPathPaginator(p.ssmapi, &ssm.GetParametersByPathInput{
+		Path:      lo.ToPtr(path),
+		Recursive: lo.ToPtr(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting ssm parameters by path %q, %w", path, err)
+		}
+		for _, parameter := range page.Parameters {
+			values[lo.FromPtr(parameter.Name)] = lo.FromPtr(parameter.Value)
+		}
+	}
+	p.cache.SetDefault(key, PathCacheEntry{Path: path, Values: values})
+	if p.cm.HasChanged(fmt.Sprintf("path/%s", path), values) {
+		log.FromContext(ctx).WithValues("path", path, "parameter-count", len(values)).V(1).Info("discovered ssm parameters by path")
+	}
+	return values, nil
+}
@@ -41,3 +41,14 @@ type CacheEntry struct {
 	Parameter Parameter
 	Value     string
 }
+
+// PathCacheKey is the key that the parameters under a path are cached with, which can't conflict with a parameter's
+// name since names can't contain spaces
+func PathCacheKey(path string) string {
+	return "path " + path
+}
+
+type PathCacheEntry struct {
+	Path   string
+	Values map[string]string
+}
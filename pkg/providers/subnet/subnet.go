@@ -16,8 +16,10 @@ package subnet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -38,21 +40,29 @@ import (
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
 )
 
 type Provider interface {
 	LivenessProbe(*http.Request) error
 	List(context.Context, *v1.EC2NodeClass) ([]ec2types.Subnet, error)
+	ListByTerms(context.Context, []v1.SubnetSelectorTerm) ([]ec2types.Subnet, error)
 	ZonalSubnetsForLaunch(context.Context, *v1.EC2NodeClass, []*cloudprovider.InstanceType, string) (map[string]*Subnet, error)
 	UpdateInflightIPs(*ec2.CreateFleetInput, *ec2.CreateFleetOutput, []*cloudprovider.InstanceType, []*Subnet, string)
+	MarkExhausted(context.Context, ...string)
 }
 
+// ErrSubnetsExhausted is returned when every subnet that could be launched into has recently run out of free IP addresses
+var ErrSubnetsExhausted = errors.New("subnets ran out of free IP addresses")
+
 type DefaultProvider struct {
 	sync.Mutex
 	ec2api                        sdk.EC2API
 	cache                         *cache.Cache
 	availableIPAddressCache       *cache.Cache
 	associatePublicIPAddressCache *cache.Cache
+	exhaustedSubnetsCache         *cache.Cache
 	cm                            *pretty.ChangeMonitor
 	inflightIPs                   map[string]int32
 }
@@ -62,9 +72,17 @@ type Subnet struct {
 	Zone                    string
 	ZoneID                  string
 	AvailableIPAddressCount int32
+	IPv6Native              bool
+}
+
+// filterSet is the EC2 filters of a subnet selector term, along with the CIDR block which contains the CIDR blocks of
+// the subnets it selects, since EC2 can only filter subnets by their exact CIDR blocks
+type filterSet struct {
+	Filters        []ec2types.Filter
+	ContainingCIDR string
 }
 
-func NewDefaultProvider(ec2api sdk.EC2API, cache *cache.Cache, availableIPAddressCache *cache.Cache, associatePublicIPAddressCache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(ec2api sdk.EC2API, cache *cache.Cache, availableIPAddressCache *cache.Cache, associatePublicIPAddressCache *cache.Cache, exhaustedSubnetsCache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
 		ec2api: ec2api,
 		cm:     pretty.NewChangeMonitor(),
@@ -73,6 +91,8 @@ func NewDefaultProvider(ec2api sdk.EC2API, cache *cache.Cache, availableIPAddres
 		cache:                         cache,
 		availableIPAddressCache:       availableIPAddressCache,
 		associatePublicIPAddressCache: associatePublicIPAddressCache,
+		// Subnets that ran out of free IP addresses during a launch aren't launched into until they expire
+		exhaustedSubnetsCache: exhaustedSubnetsCache,
 		// inflightIPs is used to track IPs from known launched instances
 		inflightIPs: map[string]int32{},
 	}
@@ -81,7 +101,42 @@ func NewDefaultProvider(ec2api sdk.EC2API, cache *cache.Cache, availableIPAddres
 func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass) ([]ec2types.Subnet, error) {
 	p.Lock()
 	defer p.Unlock()
-	filterSets := getFilterSets(nodeClass.Spec.SubnetSelectorTerms)
+	excluded, err := exclusionFilter(nodeClass.Spec.SubnetExclusionTerms)
+	if err != nil {
+		return nil, err
+	}
+	subnets, err := p.list(ctx, nodeClass.Spec.SubnetSelectorTerms)
+	if err != nil {
+		return nil, err
+	}
+	// Exclusions are applied after caching, since the subnets that are described don't depend on them
+	subnets = lo.Reject(subnets, func(s ec2types.Subnet, _ int) bool { return excluded(s) })
+	if p.cm.HasChanged(fmt.Sprintf("subnets/%s", nodeClass.Name), lo.Map(subnets, func(s ec2types.Subnet, _ int) string { return lo.FromPtr(s.SubnetId) })) {
+		log.FromContext(ctx).
+			WithValues("subnets", lo.Map(subnets, func(s ec2types.Subnet, _ int) v1.Subnet {
+				return v1.Subnet{
+					ID:     lo.FromPtr(s.SubnetId),
+					Zone:   lo.FromPtr(s.AvailabilityZone),
+					ZoneID: lo.FromPtr(s.AvailabilityZoneId),
+				}
+			})).V(1).Info("discovered subnets")
+	}
+	return subnets, nil
+}
+
+// ListByTerms returns the subnets which the selector terms select, such as the subnets of an EC2NodeClass's additional
+// network interfaces
+func (p *DefaultProvider) ListByTerms(ctx context.Context, terms []v1.SubnetSelectorTerm) ([]ec2types.Subnet, error) {
+	p.Lock()
+	defer p.Unlock()
+	return p.list(ctx, terms)
+}
+
+func (p *DefaultProvider) list(ctx context.Context, terms []v1.SubnetSelectorTerm) ([]ec2types.Subnet, error) {
+	filterSets, err := getFilterSets(terms)
+	if err != nil {
+		return nil, err
+	}
 	if len(filterSets) == 0 {
 		return []ec2types.Subnet{}, nil
 	}
@@ -96,12 +151,15 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass)
 	}
 	// Ensure that all the subnets that are returned here are unique
 	subnets := map[string]ec2types.Subnet{}
-	for _, filters := range filterSets {
-		output, err := p.ec2api.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: filters})
+	for _, filterSet := range filterSets {
+		output, err := p.ec2api.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: filterSet.Filters})
 		if err != nil {
-			return nil, fmt.Errorf("describing subnets %s, %w", pretty.Concise(filters), err)
+			return nil, fmt.Errorf("describing subnets %s, %w", pretty.Concise(filterSet.Filters), err)
 		}
 		for i := range output.Subnets {
+			if !filterSet.contains(output.Subnets[i]) {
+				continue
+			}
 			subnets[lo.FromPtr(output.Subnets[i].SubnetId)] = output.Subnets[i]
 			p.availableIPAddressCache.SetDefault(lo.FromPtr(output.Subnets[i].SubnetId), lo.FromPtr(output.Subnets[i].AvailableIpAddressCount))
 			p.associatePublicIPAddressCache.SetDefault(lo.FromPtr(output.Subnets[i].SubnetId), lo.FromPtr(output.Subnets[i].MapPublicIpOnLaunch))
@@ -111,20 +169,13 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass)
 		}
 	}
 	p.cache.SetDefault(fmt.Sprint(hash), lo.Values(subnets))
-	if p.cm.HasChanged(fmt.Sprintf("subnets/%s", nodeClass.Name), lo.Keys(subnets)) {
-		log.FromContext(ctx).
-			WithValues("subnets", lo.Map(lo.Values(subnets), func(s ec2types.Subnet, _ int) v1.Subnet {
-				return v1.Subnet{
-					ID:     lo.FromPtr(s.SubnetId),
-					Zone:   lo.FromPtr(s.AvailabilityZone),
-					ZoneID: lo.FromPtr(s.AvailabilityZoneId),
-				}
-			})).V(1).Info("discovered subnets")
-	}
 	return lo.Values(subnets), nil
 }
 
-// ZonalSubnetsForLaunch returns a mapping of zone to the subnet with the most available IP addresses and deducts the passed ips from the available count
+// ZonalSubnetsForLaunch returns a mapping of zone to the subnet with the most available IP addresses and deducts the passed ips from the available count.
+// Subnets with fewer available IP addresses than the EC2NodeClass's minimum, or that recently ran out of free IP addresses
+// during a launch, aren't launched into. IPv6-only subnets don't run short of addresses, so they're preferred over the
+// other subnets in their zone and their IPv4 addresses aren't counted.
 func (p *DefaultProvider) ZonalSubnetsForLaunch(ctx context.Context, nodeClass *v1.EC2NodeClass, instanceTypes []*cloudprovider.InstanceType, capacityType string) (map[string]*Subnet, error) {
 	if len(nodeClass.Status.Subnets) == 0 {
 		return nil, fmt.Errorf("no subnets matched selector %v", nodeClass.Spec.SubnetSelectorTerms)
@@ -141,8 +192,33 @@ func (p *DefaultProvider) ZonalSubnetsForLaunch(ctx context.Context, nodeClass *
 		}
 	}
 
+	minAvailableIPAddresses := lo.FromPtr(nodeClass.Spec.MinSubnetAvailableIPAddresses)
+	var belowMinimum, exhausted []string
 	for _, subnet := range nodeClass.Status.Subnets {
+		if _, ok := p.exhaustedSubnetsCache.Get(subnet.ID); ok {
+			exhausted = append(exhausted, subnet.ID)
+			continue
+		}
+		if subnet.IPv6Native {
+			if v, ok := zonalSubnets[subnet.Zone]; !ok || !v.IPv6Native {
+				zonalSubnets[subnet.Zone] = &Subnet{ID: subnet.ID, Zone: subnet.Zone, ZoneID: subnet.ZoneID, IPv6Native: true}
+			}
+			continue
+		}
+		ips, ok := availableIPAddressCount[subnet.ID]
+		if trackedIPs, tracked := p.inflightIPs[subnet.ID]; ok && tracked {
+			ips = trackedIPs
+		}
+		// Subnets whose available IP addresses aren't known, since they haven't been discovered recently, aren't skipped.
+		// Without a minimum no subnet is skipped, since the IP addresses tracked for launches are an overestimate.
+		if minAvailableIPAddresses > 0 && ok && ips < minAvailableIPAddresses {
+			belowMinimum = append(belowMinimum, subnet.ID)
+			continue
+		}
 		if v, ok := zonalSubnets[subnet.Zone]; ok {
+			if v.IPv6Native {
+				continue
+			}
 			currentZonalSubnetIPAddressCount := v.AvailableIPAddressCount
 			newZonalSubnetIPAddressCount := availableIPAddressCount[subnet.ID]
 			if ips, ok := p.inflightIPs[v.ID]; ok {
@@ -158,8 +234,17 @@ func (p *DefaultProvider) ZonalSubnetsForLaunch(ctx context.Context, nodeClass *
 		}
 		zonalSubnets[subnet.Zone] = &Subnet{ID: subnet.ID, Zone: subnet.Zone, ZoneID: subnet.ZoneID, AvailableIPAddressCount: availableIPAddressCount[subnet.ID]}
 	}
+	if len(zonalSubnets) == 0 {
+		if len(exhausted) != 0 {
+			return nil, fmt.Errorf("%w, %s", ErrSubnetsExhausted, utils.PrettySlice(append(exhausted, belowMinimum...), 5))
+		}
+		return nil, fmt.Errorf("no subnets have at least %d available IP addresses, %s", minAvailableIPAddresses, utils.PrettySlice(belowMinimum, 5))
+	}
 
 	for _, subnet := range zonalSubnets {
+		if subnet.IPv6Native {
+			continue
+		}
 		predictedIPsUsed := p.minPods(instanceTypes, scheduling.NewRequirements(
 			scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, capacityType),
 			scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, subnet.Zone),
@@ -232,6 +317,15 @@ func (p *DefaultProvider) UpdateInflightIPs(createFleetInput *ec2.CreateFleetInp
 	}
 }
 
+// MarkExhausted excludes subnets that ran out of free IP addresses during a launch from the next launches, so that they're
+// retried in the other subnets of the EC2NodeClass
+func (p *DefaultProvider) MarkExhausted(ctx context.Context, subnetIDs ...string) {
+	for _, id := range subnetIDs {
+		log.FromContext(ctx).WithValues("id", id, "ttl", awscache.ExhaustedSubnetsTTL).V(1).Info("removing exhausted subnet from launches")
+		p.exhaustedSubnetsCache.SetDefault(id, struct{}{})
+	}
+}
+
 func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
 	p.Lock()
 	//nolint: staticcheck
@@ -255,7 +349,7 @@ func (p *DefaultProvider) minPods(instanceTypes []*cloudprovider.InstanceType, r
 	return int32(pods)
 }
 
-func getFilterSets(terms []v1.SubnetSelectorTerm) (res [][]ec2types.Filter) {
+func getFilterSets(terms []v1.SubnetSelectorTerm) (res []filterSet, err error) {
 	idFilter := ec2types.Filter{Name: aws.String("subnet-id")}
 	for _, term := range terms {
 		switch {
@@ -263,6 +357,21 @@ func getFilterSets(terms []v1.SubnetSelectorTerm) (res [][]ec2types.Filter) {
 			idFilter.Values = append(idFilter.Values, term.ID)
 		default:
 			var filters []ec2types.Filter
+			var containingCIDR string
+			if term.CIDR != "" {
+				prefix, err := netip.ParsePrefix(term.CIDR)
+				if err != nil {
+					return nil, fmt.Errorf("parsing subnet selector cidr %q, %w", term.CIDR, err)
+				}
+				if lo.FromPtr(term.CIDRMatch) == v1.CIDRMatchContains {
+					containingCIDR = prefix.Masked().String()
+				} else {
+					filters = append(filters, ec2types.Filter{
+						Name:   aws.String(lo.Ternary(prefix.Addr().Is4(), "cidr-block", "ipv6-cidr-block-association.ipv6-cidr-block")),
+						Values: []string{prefix.String()},
+					})
+				}
+			}
 			for k, v := range term.Tags {
 				if v == "*" {
 					filters = append(filters, ec2types.Filter{
@@ -276,11 +385,64 @@ func getFilterSets(terms []v1.SubnetSelectorTerm) (res [][]ec2types.Filter) {
 					})
 				}
 			}
-			res = append(res, filters)
+			res = append(res, filterSet{Filters: filters, ContainingCIDR: containingCIDR})
 		}
 	}
 	if len(idFilter.Values) > 0 {
-		res = append(res, []ec2types.Filter{idFilter})
+		res = append(res, filterSet{Filters: []ec2types.Filter{idFilter}})
 	}
-	return res
+	return res, nil
+}
+
+// contains returns whether one of the subnet's CIDR blocks is contained in the filter set's containing CIDR block
+func (f filterSet) contains(subnet ec2types.Subnet) bool {
+	if f.ContainingCIDR == "" {
+		return true
+	}
+	return cidrContains(netip.MustParsePrefix(f.ContainingCIDR), subnet)
+}
+
+// cidrContains returns whether one of the subnet's CIDR blocks is contained in the containing CIDR block
+func cidrContains(containing netip.Prefix, subnet ec2types.Subnet) bool {
+	cidrs := append([]string{lo.FromPtr(subnet.CidrBlock)}, lo.Map(subnet.Ipv6CidrBlockAssociationSet, func(a ec2types.SubnetIpv6CidrBlockAssociation, _ int) string {
+		return lo.FromPtr(a.Ipv6CidrBlock)
+	})...)
+	return lo.ContainsBy(cidrs, func(cidr string) bool {
+		prefix, err := netip.ParsePrefix(cidr)
+		return err == nil && prefix.Bits() >= containing.Bits() && containing.Contains(prefix.Addr())
+	})
+}
+
+// exclusionFilter returns a function which returns whether a subnet is matched by every field of one of the exclusion
+// terms
+func exclusionFilter(terms []v1.SubnetExclusionTerm) (func(ec2types.Subnet) bool, error) {
+	containing := make([]*netip.Prefix, len(terms))
+	for i, term := range terms {
+		if term.CIDR == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(term.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("parsing subnet exclusion cidr %q, %w", term.CIDR, err)
+		}
+		containing[i] = lo.ToPtr(prefix.Masked())
+	}
+	return func(subnet ec2types.Subnet) bool {
+		tags := lo.SliceToMap(subnet.Tags, func(t ec2types.Tag) (string, string) { return lo.FromPtr(t.Key), lo.FromPtr(t.Value) })
+		for i, term := range terms {
+			if term.ID != "" && term.ID != lo.FromPtr(subnet.SubnetId) {
+				continue
+			}
+			if containing[i] != nil && !cidrContains(*containing[i], subnet) {
+				continue
+			}
+			if lo.EveryBy(lo.Entries(term.Tags), func(e lo.Entry[string, string]) bool {
+				value, ok := tags[e.Key]
+				return ok && (e.Value == "*" || e.Value == value)
+			}) {
+				return true
+			}
+		}
+		return false
+	}, nil
 }
@@ -22,6 +22,7 @@ import (
 
 	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
 
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/samber/lo"
 
@@ -230,6 +231,73 @@ var _ = Describe("SubnetProvider", func() {
 			}, subnets)
 		})
 	})
+	Context("CIDR", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{
+				{SubnetId: lo.ToPtr("subnet-primary"), AvailabilityZone: lo.ToPtr("test-zone-1a"), CidrBlock: lo.ToPtr("10.0.1.0/24"),
+					Tags: []ec2types.Tag{{Key: lo.ToPtr("foo"), Value: lo.ToPtr("bar")}}},
+				{SubnetId: lo.ToPtr("subnet-secondary-1"), AvailabilityZone: lo.ToPtr("test-zone-1a"), CidrBlock: lo.ToPtr("10.42.0.0/20"),
+					Tags: []ec2types.Tag{{Key: lo.ToPtr("foo"), Value: lo.ToPtr("bar")}}},
+				{SubnetId: lo.ToPtr("subnet-secondary-2"), AvailabilityZone: lo.ToPtr("test-zone-1b"), CidrBlock: lo.ToPtr("10.42.16.0/20")},
+				{SubnetId: lo.ToPtr("subnet-ipv6"), AvailabilityZone: lo.ToPtr("test-zone-1b"), Ipv6CidrBlockAssociationSet: []ec2types.SubnetIpv6CidrBlockAssociation{
+					{Ipv6CidrBlock: lo.ToPtr("2600:1f14:abc:de00::/64")},
+				}},
+			}})
+		})
+		DescribeTable("should discover subnets by cidr",
+			func(term v1.SubnetSelectorTerm, expected ...string) {
+				nodeClass.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{term}
+				subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
+				Expect(err).To(BeNil())
+				Expect(lo.Map(subnets, func(s ec2types.Subnet, _ int) string { return lo.FromPtr(s.SubnetId) })).To(ConsistOf(expected))
+			},
+			Entry("exact", v1.SubnetSelectorTerm{CIDR: "10.42.0.0/20"}, "subnet-secondary-1"),
+			Entry("exact ipv6", v1.SubnetSelectorTerm{CIDR: "2600:1f14:abc:de00::/64"}, "subnet-ipv6"),
+			Entry("exact without a match", v1.SubnetSelectorTerm{CIDR: "10.42.0.0/16"}),
+			Entry("contains", v1.SubnetSelectorTerm{CIDR: "10.42.0.0/16", CIDRMatch: lo.ToPtr(v1.CIDRMatchContains)}, "subnet-secondary-1", "subnet-secondary-2"),
+			Entry("contains with host bits set", v1.SubnetSelectorTerm{CIDR: "10.42.1.1/16", CIDRMatch: lo.ToPtr(v1.CIDRMatchContains)}, "subnet-secondary-1", "subnet-secondary-2"),
+			Entry("contains a larger block", v1.SubnetSelectorTerm{CIDR: "10.42.0.0/21", CIDRMatch: lo.ToPtr(v1.CIDRMatchContains)}),
+			Entry("contains ipv6", v1.SubnetSelectorTerm{CIDR: "2600:1f14:abc::/48", CIDRMatch: lo.ToPtr(v1.CIDRMatchContains)}, "subnet-ipv6"),
+			Entry("contains and tags", v1.SubnetSelectorTerm{CIDR: "10.0.0.0/8", CIDRMatch: lo.ToPtr(v1.CIDRMatchContains), Tags: map[string]string{"foo": "bar"}}, "subnet-primary", "subnet-secondary-1"),
+		)
+	})
+	Context("Exclusion", func() {
+		BeforeEach(func() {
+			awsEnv.EC2API.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{
+				{SubnetId: lo.ToPtr("subnet-private-1"), AvailabilityZone: lo.ToPtr("test-zone-1a"), CidrBlock: lo.ToPtr("10.0.1.0/24"),
+					Tags: []ec2types.Tag{{Key: lo.ToPtr("foo"), Value: lo.ToPtr("bar")}}},
+				{SubnetId: lo.ToPtr("subnet-private-2"), AvailabilityZone: lo.ToPtr("test-zone-1b"), CidrBlock: lo.ToPtr("10.0.2.0/24"),
+					Tags: []ec2types.Tag{{Key: lo.ToPtr("foo"), Value: lo.ToPtr("bar")}}},
+				{SubnetId: lo.ToPtr("subnet-firewall"), AvailabilityZone: lo.ToPtr("test-zone-1a"), CidrBlock: lo.ToPtr("10.0.255.0/28"),
+					Tags: []ec2types.Tag{{Key: lo.ToPtr("foo"), Value: lo.ToPtr("bar")}, {Key: lo.ToPtr("role"), Value: lo.ToPtr("firewall")}}},
+				{SubnetId: lo.ToPtr("subnet-tgw"), AvailabilityZone: lo.ToPtr("test-zone-1b"), CidrBlock: lo.ToPtr("10.0.255.16/28"),
+					Tags: []ec2types.Tag{{Key: lo.ToPtr("foo"), Value: lo.ToPtr("bar")}, {Key: lo.ToPtr("role"), Value: lo.ToPtr("tgw")}}},
+			}})
+			nodeClass.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{Tags: map[string]string{"foo": "bar"}}}
+		})
+		DescribeTable("should exclude subnets",
+			func(terms []v1.SubnetExclusionTerm, expected ...string) {
+				nodeClass.Spec.SubnetExclusionTerms = terms
+				subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
+				Expect(err).To(BeNil())
+				Expect(lo.Map(subnets, func(s ec2types.Subnet, _ int) string { return lo.FromPtr(s.SubnetId) })).To(ConsistOf(expected))
+			},
+			Entry("by id", []v1.SubnetExclusionTerm{{ID: "subnet-tgw"}}, "subnet-private-1", "subnet-private-2", "subnet-firewall"),
+			Entry("by tags", []v1.SubnetExclusionTerm{{Tags: map[string]string{"role": "firewall"}}}, "subnet-private-1", "subnet-private-2", "subnet-tgw"),
+			Entry("by tag keys", []v1.SubnetExclusionTerm{{Tags: map[string]string{"role": "*"}}}, "subnet-private-1", "subnet-private-2"),
+			Entry("by cidr", []v1.SubnetExclusionTerm{{CIDR: "10.0.255.0/24"}}, "subnet-private-1", "subnet-private-2"),
+			Entry("by cidr and tags", []v1.SubnetExclusionTerm{{CIDR: "10.0.255.0/24", Tags: map[string]string{"role": "tgw"}}}, "subnet-private-1", "subnet-private-2", "subnet-firewall"),
+			Entry("by any term", []v1.SubnetExclusionTerm{{ID: "subnet-private-1"}, {Tags: map[string]string{"role": "*"}}}, "subnet-private-2"),
+		)
+		It("should apply exclusions to cached subnets", func() {
+			_, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			nodeClass.Spec.SubnetExclusionTerms = []v1.SubnetExclusionTerm{{Tags: map[string]string{"role": "*"}}}
+			subnets, err := awsEnv.SubnetProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			Expect(lo.Map(subnets, func(s ec2types.Subnet, _ int) string { return lo.FromPtr(s.SubnetId) })).To(ConsistOf("subnet-private-1", "subnet-private-2"))
+		})
+	})
 	Context("Provider Cache", func() {
 		It("should resolve subnets from cache that are filtered by id", func() {
 			expectedSubnets := awsEnv.EC2API.DescribeSubnetsOutput.Clone().Subnets
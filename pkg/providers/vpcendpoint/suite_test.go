@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vpcendpoint_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var ec2api *fake.EC2API
+var vpcEndpointProvider *vpcendpoint.DefaultProvider
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "VPCEndpointProvider")
+}
+
+var _ = BeforeEach(func() {
+	ec2api = fake.NewEC2API()
+	vpcEndpointProvider = vpcendpoint.NewDefaultProvider("cn-north-1", ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+	ec2api.DescribeSubnetsOutput.Set(&ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{
+		{SubnetId: lo.ToPtr("subnet-1a"), AvailabilityZone: lo.ToPtr("cn-north-1a")},
+		{SubnetId: lo.ToPtr("subnet-1b"), AvailabilityZone: lo.ToPtr("cn-north-1b")},
+	}})
+	ec2api.DescribeVpcEndpointsBehavior.Output.Set(&ec2.DescribeVpcEndpointsOutput{VpcEndpoints: []ec2types.VpcEndpoint{
+		{ServiceName: lo.ToPtr("com.amazonaws.cn-north-1.s3"), VpcEndpointType: ec2types.VpcEndpointTypeGateway},
+		{ServiceName: lo.ToPtr("cn.com.amazonaws.cn-north-1.ecr.api"), VpcEndpointType: ec2types.VpcEndpointTypeInterface, SubnetIds: []string{"subnet-1a"}},
+		{ServiceName: lo.ToPtr("cn.com.amazonaws.cn-north-1.ecr.dkr"), VpcEndpointType: ec2types.VpcEndpointTypeInterface, SubnetIds: []string{"subnet-1a", "subnet-1b"}},
+	}})
+})
+
+var _ = Describe("VPCEndpointProvider", func() {
+	It("should return the services which aren't reachable from each zone", func() {
+		missing, err := vpcEndpointProvider.MissingServices(ctx, "vpc-test", []string{"s3", "ecr.api", "ecr.dkr", "sts"}, []string{"cn-north-1a", "cn-north-1b", "cn-north-1c"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(missing).To(Equal(map[string][]string{
+			"cn-north-1a": {"sts"},
+			"cn-north-1b": {"ecr.api", "sts"},
+			"cn-north-1c": {"ecr.api", "ecr.dkr", "sts"},
+		}))
+	})
+	It("should omit zones from which every service is reachable", func() {
+		missing, err := vpcEndpointProvider.MissingServices(ctx, "vpc-test", []string{"s3", "ecr.dkr"}, []string{"cn-north-1a", "cn-north-1b"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(missing).To(BeEmpty())
+	})
+	It("should cache the endpoints for each VPC", func() {
+		_, err := vpcEndpointProvider.MissingServices(ctx, "vpc-test", []string{"s3"}, []string{"cn-north-1a"})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = vpcEndpointProvider.MissingServices(ctx, "vpc-test", []string{"sts"}, []string{"cn-north-1b"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ec2api.DescribeVpcEndpointsBehavior.Calls()).To(Equal(1))
+	})
+})
@@ -0,0 +1,152 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vpcendpoint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+type Provider interface {
+	// MissingServices returns the services which aren't reachable through a VPC endpoint from each of the given zones
+	// of the VPC. Zones from which every service is reachable are omitted.
+	MissingServices(ctx context.Context, vpcID string, services []string, zones []string) (map[string][]string, error)
+}
+
+// coverage is the set of zones from which a service is reachable through the VPC's endpoints
+type coverage struct {
+	// allZones is true when the service has a gateway endpoint, which is reachable from every zone in the VPC
+	allZones bool
+	zones    sets.Set[string]
+}
+
+type DefaultProvider struct {
+	sync.Mutex
+	region string
+	ec2api sdk.EC2API
+	cache  *cache.Cache
+}
+
+func NewDefaultProvider(region string, ec2api sdk.EC2API, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		region: region,
+		ec2api: ec2api,
+		cache:  cache,
+	}
+}
+
+func (p *DefaultProvider) MissingServices(ctx context.Context, vpcID string, services []string, zones []string) (map[string][]string, error) {
+	endpoints, err := p.endpoints(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	missing := map[string][]string{}
+	for _, zone := range zones {
+		for _, service := range services {
+			c, ok := endpoints[service]
+			if !ok || (!c.allZones && !c.zones.Has(zone)) {
+				missing[zone] = append(missing[zone], service)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// endpoints returns the coverage of each service with an available endpoint in the VPC. Services are identified by
+// the suffix of their service name following the region, e.g. com.amazonaws.us-west-2.ecr.api is identified as ecr.api.
+func (p *DefaultProvider) endpoints(ctx context.Context, vpcID string) (map[string]coverage, error) {
+	p.Lock()
+	defer p.Unlock()
+	if endpoints, ok := p.cache.Get(vpcID); ok {
+		return endpoints.(map[string]coverage), nil
+	}
+	var vpcEndpoints []ec2types.VpcEndpoint
+	paginator := ec2.NewDescribeVpcEndpointsPaginator(p.ec2api, &ec2.DescribeVpcEndpointsInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+			{Name: aws.String("vpc-endpoint-state"), Values: []string{string(ec2types.StateAvailable)}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing vpc endpoints, %w", err)
+		}
+		vpcEndpoints = append(vpcEndpoints, page.VpcEndpoints...)
+	}
+	subnetZones, err := p.subnetZones(ctx, lo.Uniq(lo.FlatMap(vpcEndpoints, func(e ec2types.VpcEndpoint, _ int) []string {
+		return e.SubnetIds
+	})))
+	if err != nil {
+		return nil, err
+	}
+	endpoints := map[string]coverage{}
+	prefix := fmt.Sprintf(".%s.", p.region)
+	for _, e := range vpcEndpoints {
+		_, service, ok := strings.Cut(lo.FromPtr(e.ServiceName), prefix)
+		if !ok {
+			continue
+		}
+		c, ok := endpoints[service]
+		if !ok {
+			c = coverage{zones: sets.New[string]()}
+		}
+		switch e.VpcEndpointType {
+		case ec2types.VpcEndpointTypeGateway:
+			c.allZones = true
+		case ec2types.VpcEndpointTypeInterface:
+			for _, id := range e.SubnetIds {
+				if zone, ok := subnetZones[id]; ok {
+					c.zones.Insert(zone)
+				}
+			}
+		}
+		endpoints[service] = c
+	}
+	p.cache.SetDefault(vpcID, endpoints)
+	return endpoints, nil
+}
+
+// subnetZones returns the zone of each of the subnets that interface endpoints have network interfaces in
+func (p *DefaultProvider) subnetZones(ctx context.Context, ids []string) (map[string]string, error) {
+	zones := map[string]string{}
+	if len(ids) == 0 {
+		return zones, nil
+	}
+	paginator := ec2.NewDescribeSubnetsPaginator(p.ec2api, &ec2.DescribeSubnetsInput{
+		Filters: []ec2types.Filter{{Name: aws.String("subnet-id"), Values: ids}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing vpc endpoint subnets, %w", err)
+		}
+		for _, subnet := range page.Subnets {
+			zones[lo.FromPtr(subnet.SubnetId)] = lo.FromPtr(subnet.AvailabilityZone)
+		}
+	}
+	return zones, nil
+}
@@ -0,0 +1,85 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zone_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var ec2api *fake.EC2API
+var zoneProvider *zone.DefaultProvider
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ZoneProvider")
+}
+
+var _ = BeforeEach(func() {
+	ec2api = fake.NewEC2API()
+	zoneProvider = zone.NewDefaultProvider(ec2api)
+})
+
+var _ = Describe("ZoneProvider", func() {
+	It("should map zone names to zone IDs", func() {
+		Expect(zoneProvider.UpdateZones(ctx)).To(Succeed())
+		id, ok := zoneProvider.ID("test-zone-1b")
+		Expect(ok).To(BeTrue())
+		Expect(id).To(Equal("tstz1-1b"))
+		name, ok := zoneProvider.Name("tstz1-1alocal")
+		Expect(ok).To(BeTrue())
+		Expect(name).To(Equal("test-zone-1a-local"))
+		_, ok = zoneProvider.ID("test-zone-1d")
+		Expect(ok).To(BeFalse())
+	})
+	It("should detect opt-in zones which aren't enabled", func() {
+		ec2api.DescribeAvailabilityZonesOutput.Set(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []ec2types.AvailabilityZone{
+			{ZoneName: lo.ToPtr("test-zone-1a"), ZoneId: lo.ToPtr("tstz1-1a"), ZoneType: lo.ToPtr("availability-zone"), OptInStatus: ec2types.AvailabilityZoneOptInStatusOptInNotRequired},
+			{ZoneName: lo.ToPtr("test-zone-1a-local"), ZoneId: lo.ToPtr("tstz1-1alocal"), ZoneType: lo.ToPtr("local-zone"), OptInStatus: ec2types.AvailabilityZoneOptInStatusOptedIn},
+			{ZoneName: lo.ToPtr("test-zone-1b-local"), ZoneId: lo.ToPtr("tstz1-1blocal"), ZoneType: lo.ToPtr("local-zone"), OptInStatus: ec2types.AvailabilityZoneOptInStatusNotOptedIn},
+		}})
+		Expect(zoneProvider.UpdateZones(ctx)).To(Succeed())
+		Expect(zoneProvider.List()).To(ConsistOf(
+			zone.Zone{Name: "test-zone-1a", ID: "tstz1-1a", Type: "availability-zone", Enabled: true},
+			zone.Zone{Name: "test-zone-1a-local", ID: "tstz1-1alocal", Type: "local-zone", Enabled: true},
+			zone.Zone{Name: "test-zone-1b-local", ID: "tstz1-1blocal", Type: "local-zone", Enabled: false},
+		))
+	})
+	It("should keep the previous zones when the zones can't be described", func() {
+		Expect(zoneProvider.UpdateZones(ctx)).To(Succeed())
+		ec2api.NextError.Set(&smithy.GenericAPIError{Code: "InternalError"})
+		Expect(zoneProvider.UpdateZones(ctx)).ToNot(Succeed())
+		Expect(zoneProvider.List()).To(HaveLen(4))
+	})
+	It("should not fail when unauthorized to describe zones", func() {
+		ec2api.NextError.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+		Expect(zoneProvider.UpdateZones(ctx)).To(Succeed())
+		Expect(zoneProvider.List()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zone
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+)
+
+type Zone struct {
+	Name string
+	ID   string
+	// Type is the type of the zone, e.g. availability-zone, local-zone, or wavelength-zone
+	Type string
+	// GroupName is the zone group of the zone. Local Zones are listed by the pricing API under their zone group.
+	GroupName string
+	// Enabled is false for opt-in zones, such as Local Zones, which the account hasn't opted into
+	Enabled bool
+}
+
+type Provider interface {
+	// ID returns the zone ID for the given zone name. Zone names are mapped to different physical zones in each account,
+	// while zone IDs are consistent across accounts.
+	ID(string) (string, bool)
+	// Name returns the zone name for the given zone ID
+	Name(string) (string, bool)
+	// List returns every zone in the region, including zones which aren't enabled for the account
+	List() []Zone
+	UpdateZones(context.Context) error
+}
+
+type DefaultProvider struct {
+	sync.RWMutex
+
+	ec2api sdk.EC2API
+	cm     *pretty.ChangeMonitor
+	zones  []Zone
+}
+
+func NewDefaultProvider(ec2api sdk.EC2API) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api: ec2api,
+		cm:     pretty.NewChangeMonitor(),
+	}
+}
+
+func (p *DefaultProvider) ID(name string) (string, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	z, ok := lo.Find(p.zones, func(z Zone) bool { return z.Name == name })
+	return z.ID, ok
+}
+
+func (p *DefaultProvider) Name(id string) (string, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	z, ok := lo.Find(p.zones, func(z Zone) bool { return z.ID == id })
+	return z.Name, ok
+}
+
+func (p *DefaultProvider) List() []Zone {
+	p.RLock()
+	defer p.RUnlock()
+	return slices.Clone(p.zones)
+}
+
+// UpdateZones discovers the zones in the region. Zones which require opting in are included regardless of whether the
+// account has opted in, so that requirements which reference them can be reported as not enabled rather than unknown.
+func (p *DefaultProvider) UpdateZones(ctx context.Context) error {
+	out, err := p.ec2api.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{
+		AllAvailabilityZones: aws.Bool(true),
+	})
+	if err != nil {
+		// Zone discovery is best effort, the mapping is only used for validation
+		if awserrors.IsUnauthorizedOperationError(err) {
+			if p.cm.HasChanged("zones-unauthorized", true) {
+				log.FromContext(ctx).V(1).Info("unable to describe availability zones, zone requirements won't be validated")
+			}
+			return nil
+		}
+		return fmt.Errorf("describing availability zones, %w", err)
+	}
+	zones := lo.Map(out.AvailabilityZones, func(az ec2types.AvailabilityZone, _ int) Zone {
+		return Zone{
+			Name:      lo.FromPtr(az.ZoneName),
+			ID:        lo.FromPtr(az.ZoneId),
+			Type:      lo.FromPtr(az.ZoneType),
+			GroupName: lo.FromPtr(az.GroupName),
+			// Zones which don't require opting in are always enabled
+			Enabled: az.OptInStatus != ec2types.AvailabilityZoneOptInStatusNotOptedIn,
+		}
+	})
+
+	p.Lock()
+	defer p.Unlock()
+	p.zones = zones
+	if p.cm.HasChanged("zones", zones) {
+		log.FromContext(ctx).WithValues("zones", lo.SliceToMap(zones, func(z Zone) (string, string) {
+			return z.Name, z.ID
+		})).V(1).Info("discovered zone mapping")
+	}
+	return nil
+}
+
+func (p *DefaultProvider) Reset() {
+	p.Lock()
+	defer p.Unlock()
+	p.zones = nil
+}
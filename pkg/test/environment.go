@@ -29,8 +29,11 @@ import (
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/batcher"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/health"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accountsettings"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
@@ -38,10 +41,13 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/reservedinstance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	ssmp "github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	coretest "sigs.k8s.io/karpenter/pkg/test"
@@ -59,40 +65,55 @@ type Environment struct {
 	Clock *clock.FakeClock
 
 	// API
-	EC2API     *fake.EC2API
-	EKSAPI     *fake.EKSAPI
-	SSMAPI     *fake.SSMAPI
-	IAMAPI     *fake.IAMAPI
-	PricingAPI *fake.PricingAPI
+	EC2API          *fake.EC2API
+	EKSAPI          *fake.EKSAPI
+	SSMAPI          *fake.SSMAPI
+	IAMAPI          *fake.IAMAPI
+	PricingAPI      *fake.PricingAPI
+	SavingsPlansAPI *fake.SavingsPlansAPI
+	Route53API      *fake.Route53API
+	ImageBuilderAPI *fake.ImageBuilderAPI
+	STSAPI          *fake.STSAPI
 
 	// Cache
 	EC2Cache                             *cache.Cache
-	InstanceTypeCache                    *cache.Cache
+	InstanceTypeCache                    *awscache.LRU
 	OfferingCache                        *cache.Cache
 	UnavailableOfferingsCache            *awscache.UnavailableOfferings
 	LaunchTemplateCache                  *cache.Cache
 	SubnetCache                          *cache.Cache
 	AvailableIPAdressCache               *cache.Cache
+	ExhaustedSubnetsCache                *cache.Cache
 	AssociatePublicIPAddressCache        *cache.Cache
 	SecurityGroupCache                   *cache.Cache
 	InstanceProfileCache                 *cache.Cache
 	SSMCache                             *cache.Cache
+	AMIParameterCache                    *cache.Cache
 	DiscoveredCapacityCache              *cache.Cache
 	CapacityReservationCache             *cache.Cache
 	CapacityReservationAvailabilityCache *cache.Cache
 	ValidationCache                      *cache.Cache
+	AccountSettingsCache                 *cache.Cache
+	VPCEndpointCache                     *cache.Cache
 
 	// Providers
+	AccountSettingsProvider     *accountsettings.DefaultProvider
 	CapacityReservationProvider *capacityreservation.DefaultProvider
+	ReservedInstanceProvider    *reservedinstance.DefaultProvider
+	ZoneProvider                *zone.DefaultProvider
 	InstanceTypesResolver       *instancetype.DefaultResolver
 	InstanceTypesProvider       *instancetype.DefaultProvider
 	InstanceProvider            *instance.DefaultProvider
 	SubnetProvider              *subnet.DefaultProvider
+	VPCEndpointProvider         *vpcendpoint.DefaultProvider
 	SecurityGroupProvider       *securitygroup.DefaultProvider
 	InstanceProfileProvider     *instanceprofile.DefaultProvider
 	PricingProvider             *pricing.DefaultProvider
 	AMIProvider                 *amifamily.DefaultProvider
 	AMIResolver                 *amifamily.DefaultResolver
+	AMIHashStore                *amifamily.HashStore
+	InvalidationBus             *awscache.InvalidationBus
+	HealthTracker               *health.Tracker
 	VersionProvider             *version.DefaultProvider
 	LaunchTemplateProvider      *launchtemplate.DefaultProvider
 }
@@ -109,25 +130,35 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 
 	// cache
 	ec2Cache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
-	instanceTypeCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	instanceTypeCache := awscache.NewLRU(awscache.InstanceTypesCacheName, awscache.DefaultTTL, 0, 0, instancetype.EstimateInstanceTypesSize)
 	offeringCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	discoveredCapacityCache := cache.New(awscache.DiscoveredCapacityCacheTTL, awscache.DefaultCleanupInterval)
-	unavailableOfferingsCache := awscache.NewUnavailableOfferings()
+	invalidationBus := awscache.NewInvalidationBus()
+	healthTracker := health.NewTracker(clock)
+	unavailableOfferingsCache := awscache.NewUnavailableOfferings(invalidationBus)
 	launchTemplateCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	subnetCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	availableIPAdressCache := cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval)
+	exhaustedSubnetsCache := cache.New(awscache.ExhaustedSubnetsTTL, awscache.UnavailableOfferingsCleanupInterval)
 	associatePublicIPAddressCache := cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval)
 	securityGroupCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	instanceProfileCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	ssmCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	amiParameterCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	capacityReservationCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	capacityReservationAvailabilityCache := cache.New(24*time.Hour, awscache.DefaultCleanupInterval)
 	validationCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	vpcEndpointCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	accountSettingsCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	fakePricingAPI := &fake.PricingAPI{}
+	fakeSavingsPlansAPI := &fake.SavingsPlansAPI{}
+	imageBuilderAPI := &fake.ImageBuilderAPI{}
 
 	// Providers
-	pricingProvider := pricing.NewDefaultProvider(ctx, fakePricingAPI, ec2api, fake.DefaultRegion)
-	subnetProvider := subnet.NewDefaultProvider(ec2api, subnetCache, availableIPAdressCache, associatePublicIPAddressCache)
+	zoneProvider := zone.NewDefaultProvider(ec2api)
+	pricingProvider := pricing.NewDefaultProvider(ctx, fakePricingAPI, ec2api, fakeSavingsPlansAPI, zoneProvider, fake.DefaultRegion)
+	subnetProvider := subnet.NewDefaultProvider(ec2api, subnetCache, availableIPAdressCache, associatePublicIPAddressCache, exhaustedSubnetsCache)
+	vpcEndpointProvider := vpcendpoint.NewDefaultProvider(fake.DefaultRegion, ec2api, vpcEndpointCache)
 	securityGroupProvider := securitygroup.NewDefaultProvider(ec2api, securityGroupCache)
 	versionProvider := version.NewDefaultProvider(env.KubernetesInterface, eksapi)
 	// Ensure we're able to hydrate the version before starting any reliant controllers.
@@ -136,11 +167,15 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 	lo.Must0(versionProvider.UpdateVersion(ctx))
 	instanceProfileProvider := instanceprofile.NewDefaultProvider(fake.DefaultRegion, iamapi, instanceProfileCache)
 	ssmProvider := ssmp.NewDefaultProvider(ssmapi, ssmCache)
-	amiProvider := amifamily.NewDefaultProvider(clock, versionProvider, ssmProvider, ec2api, ec2Cache)
+	amiParameterProvider := ssmp.NewDefaultProvider(ssmapi, amiParameterCache)
+	amiProvider := amifamily.NewDefaultProvider(clock, versionProvider, ssmProvider, amiParameterProvider, ec2api, imageBuilderAPI, ec2Cache)
 	amiResolver := amifamily.NewDefaultResolver()
+	amiHashStore := amifamily.NewHashStore()
 	instanceTypesResolver := instancetype.NewDefaultResolver(fake.DefaultRegion)
 	capacityReservationProvider := capacityreservation.NewProvider(ec2api, clock, capacityReservationCache, capacityReservationAvailabilityCache)
-	instanceTypesProvider := instancetype.NewDefaultProvider(instanceTypeCache, offeringCache, discoveredCapacityCache, ec2api, subnetProvider, pricingProvider, capacityReservationProvider, unavailableOfferingsCache, instanceTypesResolver)
+	accountSettingsProvider := accountsettings.NewDefaultProvider(ec2api, accountSettingsCache)
+	reservedInstanceProvider := reservedinstance.NewDefaultProvider(ec2api)
+	instanceTypesProvider := instancetype.NewDefaultProvider(instanceTypeCache, offeringCache, discoveredCapacityCache, ec2api, subnetProvider, pricingProvider, capacityReservationProvider, reservedInstanceProvider, unavailableOfferingsCache, instanceTypesResolver, amiHashStore, invalidationBus)
 	launchTemplateProvider := launchtemplate.NewDefaultProvider(
 		ctx,
 		launchTemplateCache,
@@ -153,6 +188,8 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 		make(chan struct{}),
 		net.ParseIP("10.0.100.10"),
 		"https://test-cluster",
+		env.Client,
+		"default",
 	)
 	instanceProvider := instance.NewDefaultProvider(
 		ctx,
@@ -160,18 +197,25 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 		ec2api,
 		unavailableOfferingsCache,
 		subnetProvider,
+		securityGroupProvider,
 		launchTemplateProvider,
 		capacityReservationProvider,
+		reservedInstanceProvider,
+		batcher.NewLaunchLimiter(0, 0),
 	)
 
 	return &Environment{
 		Clock: clock,
 
-		EC2API:     ec2api,
-		EKSAPI:     eksapi,
-		SSMAPI:     ssmapi,
-		IAMAPI:     iamapi,
-		PricingAPI: fakePricingAPI,
+		EC2API:          ec2api,
+		EKSAPI:          eksapi,
+		SSMAPI:          ssmapi,
+		IAMAPI:          iamapi,
+		PricingAPI:      fakePricingAPI,
+		SavingsPlansAPI: fakeSavingsPlansAPI,
+		Route53API:      &fake.Route53API{},
+		ImageBuilderAPI: imageBuilderAPI,
+		STSAPI:          &fake.STSAPI{},
 
 		EC2Cache:          ec2Cache,
 		InstanceTypeCache: instanceTypeCache,
@@ -180,27 +224,38 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 		LaunchTemplateCache:                  launchTemplateCache,
 		SubnetCache:                          subnetCache,
 		AvailableIPAdressCache:               availableIPAdressCache,
+		ExhaustedSubnetsCache:                exhaustedSubnetsCache,
 		AssociatePublicIPAddressCache:        associatePublicIPAddressCache,
 		SecurityGroupCache:                   securityGroupCache,
 		InstanceProfileCache:                 instanceProfileCache,
 		UnavailableOfferingsCache:            unavailableOfferingsCache,
 		SSMCache:                             ssmCache,
+		AMIParameterCache:                    amiParameterCache,
 		DiscoveredCapacityCache:              discoveredCapacityCache,
 		CapacityReservationCache:             capacityReservationCache,
 		CapacityReservationAvailabilityCache: capacityReservationAvailabilityCache,
 		ValidationCache:                      validationCache,
+		AccountSettingsCache:                 accountSettingsCache,
+		VPCEndpointCache:                     vpcEndpointCache,
 
+		AccountSettingsProvider:     accountSettingsProvider,
 		CapacityReservationProvider: capacityReservationProvider,
+		ReservedInstanceProvider:    reservedInstanceProvider,
+		ZoneProvider:                zoneProvider,
 		InstanceTypesResolver:       instanceTypesResolver,
 		InstanceTypesProvider:       instanceTypesProvider,
 		InstanceProvider:            instanceProvider,
 		SubnetProvider:              subnetProvider,
+		VPCEndpointProvider:         vpcEndpointProvider,
 		SecurityGroupProvider:       securityGroupProvider,
 		LaunchTemplateProvider:      launchTemplateProvider,
 		InstanceProfileProvider:     instanceProfileProvider,
 		PricingProvider:             pricingProvider,
 		AMIProvider:                 amiProvider,
 		AMIResolver:                 amiResolver,
+		AMIHashStore:                amiHashStore,
+		InvalidationBus:             invalidationBus,
+		HealthTracker:               healthTracker,
 		VersionProvider:             versionProvider,
 	}
 }
@@ -212,8 +267,16 @@ func (env *Environment) Reset() {
 	env.SSMAPI.Reset()
 	env.IAMAPI.Reset()
 	env.PricingAPI.Reset()
+	env.SavingsPlansAPI.Reset()
+	env.Route53API.Reset()
+	env.ImageBuilderAPI.Reset()
+	env.STSAPI.Reset()
 	env.PricingProvider.Reset()
 	env.InstanceTypesProvider.Reset()
+	env.AMIHashStore.Reset()
+	env.HealthTracker.Reset()
+	env.ReservedInstanceProvider.Reset()
+	env.ZoneProvider.Reset()
 
 	env.EC2Cache.Flush()
 	env.UnavailableOfferingsCache.Flush()
@@ -222,12 +285,16 @@ func (env *Environment) Reset() {
 	env.SubnetCache.Flush()
 	env.AssociatePublicIPAddressCache.Flush()
 	env.AvailableIPAdressCache.Flush()
+	env.ExhaustedSubnetsCache.Flush()
 	env.SecurityGroupCache.Flush()
 	env.InstanceProfileCache.Flush()
 	env.SSMCache.Flush()
+	env.AMIParameterCache.Flush()
 	env.DiscoveredCapacityCache.Flush()
 	env.CapacityReservationCache.Flush()
 	env.ValidationCache.Flush()
+	env.AccountSettingsCache.Flush()
+	env.VPCEndpointCache.Flush()
 	mfs, err := crmetrics.Registry.Gather()
 	if err != nil {
 		for _, mf := range mfs {
@@ -16,6 +16,7 @@ package test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/imdario/mergo"
 	"github.com/samber/lo"
@@ -24,14 +25,48 @@ import (
 )
 
 type OptionsFields struct {
-	ClusterCABundle         *string
-	ClusterName             *string
-	ClusterEndpoint         *string
-	IsolatedVPC             *bool
-	EKSControlPlane         *bool
-	VMMemoryOverheadPercent *float64
-	InterruptionQueue       *string
-	ReservedENIs            *int
+	ClusterCABundle                 *string
+	ClusterName                     *string
+	ClusterEndpoint                 *string
+	IsolatedVPC                     *bool
+	EKSControlPlane                 *bool
+	VMMemoryOverheadPercent         *float64
+	InterruptionQueue               *string
+	ReservedENIs                    *int
+	InstanceTypesCacheMaxEntries    *int
+	InstanceTypesCacheMaxBytes      *int64
+	SavingsPlansPricing             *bool
+	ReservedInstanceCoverage        *bool
+	PricingOverridesConfigMap       *string
+	TagKeyPrefix                    *string
+	SpotPricePercentile             *int
+	BulkPricingURL                  *string
+	BulkPricingRefreshInterval      *time.Duration
+	RequiredVPCEndpoints            *string
+	ReservedCapacityOnDemandPricing *bool
+	PricingSnapshotConfigMap        *string
+	NodeDNSHostedZoneID             *string
+	NodeDNSDomain                   *string
+	DeterministicOfferingSelection  *bool
+	IncludeVolumeCost               *bool
+	PricingProvider                 *string
+	ProviderInfoConfigMap           *string
+	PricingEndpoint                 *string
+	IncludeLicenseCost              *bool
+	PricingRefreshInterval          *time.Duration
+	PricingRefreshJitter            *float64
+	PricingRetryBackoff             *time.Duration
+	PricingAPIEndpoint              *string
+	MaxDescribedImages              *int
+	SecureMetricsBindAddress        *string
+	SecureMetricsCertDir            *string
+	SecureMetricsClientCAFile       *string
+	AMIParameterRoleARN             *string
+	MaxConcurrentLaunches           *int
+	MaxLaunchesPerMinute            *int
+	SpotInterruptionDrainDelay      *time.Duration
+	PodENI                          *bool
+	PrefixDelegation                *bool
 }
 
 func Options(overrides ...OptionsFields) *options.Options {
@@ -42,13 +77,47 @@ func Options(overrides ...OptionsFields) *options.Options {
 		}
 	}
 	return &options.Options{
-		ClusterCABundle:         lo.FromPtrOr(opts.ClusterCABundle, ""),
-		ClusterName:             lo.FromPtrOr(opts.ClusterName, "test-cluster"),
-		ClusterEndpoint:         lo.FromPtrOr(opts.ClusterEndpoint, "https://test-cluster"),
-		IsolatedVPC:             lo.FromPtrOr(opts.IsolatedVPC, false),
-		EKSControlPlane:         lo.FromPtrOr(opts.EKSControlPlane, false),
-		VMMemoryOverheadPercent: lo.FromPtrOr(opts.VMMemoryOverheadPercent, 0.075),
-		InterruptionQueue:       lo.FromPtrOr(opts.InterruptionQueue, ""),
-		ReservedENIs:            lo.FromPtrOr(opts.ReservedENIs, 0),
+		ClusterCABundle:                 lo.FromPtrOr(opts.ClusterCABundle, ""),
+		ClusterName:                     lo.FromPtrOr(opts.ClusterName, "test-cluster"),
+		ClusterEndpoint:                 lo.FromPtrOr(opts.ClusterEndpoint, "https://test-cluster"),
+		IsolatedVPC:                     lo.FromPtrOr(opts.IsolatedVPC, false),
+		EKSControlPlane:                 lo.FromPtrOr(opts.EKSControlPlane, false),
+		VMMemoryOverheadPercent:         lo.FromPtrOr(opts.VMMemoryOverheadPercent, 0.075),
+		InterruptionQueue:               lo.FromPtrOr(opts.InterruptionQueue, ""),
+		ReservedENIs:                    lo.FromPtrOr(opts.ReservedENIs, 0),
+		InstanceTypesCacheMaxEntries:    lo.FromPtrOr(opts.InstanceTypesCacheMaxEntries, 256),
+		InstanceTypesCacheMaxBytes:      lo.FromPtrOr(opts.InstanceTypesCacheMaxBytes, 0),
+		SavingsPlansPricing:             lo.FromPtrOr(opts.SavingsPlansPricing, false),
+		ReservedInstanceCoverage:        lo.FromPtrOr(opts.ReservedInstanceCoverage, false),
+		PricingOverridesConfigMap:       lo.FromPtrOr(opts.PricingOverridesConfigMap, ""),
+		TagKeyPrefix:                    lo.FromPtrOr(opts.TagKeyPrefix, ""),
+		SpotPricePercentile:             lo.FromPtrOr(opts.SpotPricePercentile, 0),
+		BulkPricingURL:                  lo.FromPtrOr(opts.BulkPricingURL, ""),
+		BulkPricingRefreshInterval:      lo.FromPtrOr(opts.BulkPricingRefreshInterval, 24*time.Hour),
+		RequiredVPCEndpoints:            lo.FromPtrOr(opts.RequiredVPCEndpoints, ""),
+		ReservedCapacityOnDemandPricing: lo.FromPtrOr(opts.ReservedCapacityOnDemandPricing, false),
+		PricingSnapshotConfigMap:        lo.FromPtrOr(opts.PricingSnapshotConfigMap, ""),
+		NodeDNSHostedZoneID:             lo.FromPtrOr(opts.NodeDNSHostedZoneID, ""),
+		NodeDNSDomain:                   lo.FromPtrOr(opts.NodeDNSDomain, ""),
+		DeterministicOfferingSelection:  lo.FromPtrOr(opts.DeterministicOfferingSelection, false),
+		IncludeVolumeCost:               lo.FromPtrOr(opts.IncludeVolumeCost, false),
+		PricingProvider:                 lo.FromPtrOr(opts.PricingProvider, "aws"),
+		ProviderInfoConfigMap:           lo.FromPtrOr(opts.ProviderInfoConfigMap, ""),
+		PricingEndpoint:                 lo.FromPtrOr(opts.PricingEndpoint, ""),
+		IncludeLicenseCost:              lo.FromPtrOr(opts.IncludeLicenseCost, false),
+		PricingRefreshInterval:          lo.FromPtrOr(opts.PricingRefreshInterval, 12*time.Hour),
+		PricingRefreshJitter:            lo.FromPtrOr(opts.PricingRefreshJitter, 0),
+		PricingRetryBackoff:             lo.FromPtrOr(opts.PricingRetryBackoff, 0),
+		PricingAPIEndpoint:              lo.FromPtrOr(opts.PricingAPIEndpoint, ""),
+		MaxDescribedImages:              lo.FromPtrOr(opts.MaxDescribedImages, 10000),
+		SecureMetricsBindAddress:        lo.FromPtrOr(opts.SecureMetricsBindAddress, ""),
+		SecureMetricsCertDir:            lo.FromPtrOr(opts.SecureMetricsCertDir, ""),
+		SecureMetricsClientCAFile:       lo.FromPtrOr(opts.SecureMetricsClientCAFile, ""),
+		AMIParameterRoleARN:             lo.FromPtrOr(opts.AMIParameterRoleARN, ""),
+		MaxConcurrentLaunches:           lo.FromPtrOr(opts.MaxConcurrentLaunches, 0),
+		MaxLaunchesPerMinute:            lo.FromPtrOr(opts.MaxLaunchesPerMinute, 0),
+		SpotInterruptionDrainDelay:      lo.FromPtrOr(opts.SpotInterruptionDrainDelay, 0),
+		PodENI:                          lo.FromPtrOr(opts.PodENI, false),
+		PrefixDelegation:                lo.FromPtrOr(opts.PrefixDelegation, false),
 	}
 }
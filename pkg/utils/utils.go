@@ -20,9 +20,11 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	corev1 "k8s.io/api/core/v1"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
@@ -87,9 +89,31 @@ func WithDefaultFloat64(key string, def float64) float64 {
 	return f
 }
 
-func GetTags(nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.NodeClaim, clusterName string) (map[string]string, error) {
+// NodeClassName returns the name of the EC2NodeClass the NodeClaim was launched with, which is one of its NodePool's
+// fallback EC2NodeClasses if it couldn't be launched with its nodeClassRef
+func NodeClassName(nodeClaim *karpv1.NodeClaim) string {
+	if name, ok := nodeClaim.Annotations[v1.AnnotationFallbackNodeClass]; ok {
+		return name
+	}
+	return lo.FromPtr(nodeClaim.Spec.NodeClassRef).Name
+}
+
+// FallbackNodeClassNames returns the EC2NodeClasses the NodePool falls back to, in the order they're tried
+func FallbackNodeClassNames(nodePool *karpv1.NodePool) []string {
+	names := lo.Map(strings.Split(nodePool.Annotations[v1.AnnotationFallbackNodeClasses], ","), func(name string, _ int) string {
+		return strings.TrimSpace(name)
+	})
+	return lo.Without(lo.Uniq(lo.Compact(names)), lo.FromPtr(nodePool.Spec.Template.Spec.NodeClassRef).Name)
+}
+
+func GetTags(nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.NodeClaim, clusterName, tagKeyPrefix string) (map[string]string, error) {
+	ownershipTagKeys := lo.Map(v1.OwnershipTagKeys, func(key string, _ int) string { return PrefixedTagKey(tagKeyPrefix, key) })
 	var invalidTags []string
 	for key := range nodeClass.Spec.Tags {
+		if lo.Contains(ownershipTagKeys, key) {
+			invalidTags = append(invalidTags, key)
+			continue
+		}
 		for _, exp := range v1.RestrictedTagPatterns {
 			if exp.MatchString(key) {
 				invalidTags = append(invalidTags, key)
@@ -105,9 +129,95 @@ func GetTags(nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.NodeClaim, clusterNam
 	}
 	staticTags := map[string]string{
 		fmt.Sprintf("kubernetes.io/cluster/%s", clusterName): "owned",
-		karpv1.NodePoolLabelKey:                              nodeClaim.Labels[karpv1.NodePoolLabelKey],
+		PrefixedTagKey(tagKeyPrefix, v1.NodePoolTagKey):      nodeClaim.Labels[karpv1.NodePoolLabelKey],
 		v1.EKSClusterNameTagKey:                              clusterName,
-		v1.LabelNodeClass:                                    nodeClass.Name,
+		PrefixedTagKey(tagKeyPrefix, v1.NodeClassTagKey):     nodeClass.Name,
 	}
 	return lo.Assign(nodeClass.Spec.Tags, staticTags), nil
 }
+
+// PrefixedTagKey replaces the domain of an ownership tag key with the given prefix, e.g. karpenter.sh/nodepool becomes
+// example.com/karpenter/nodepool for the prefix example.com/karpenter. The key is returned unchanged if the prefix is
+// empty.
+func PrefixedTagKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return fmt.Sprintf("%s/%s", prefix, key[strings.LastIndex(key, "/")+1:])
+}
+
+// NameTagTemplateData is the data that an EC2NodeClass's Name tag template is rendered with
+type NameTagTemplateData struct {
+	NodeName     string
+	NodeClaim    string
+	NodePool     string
+	NodeClass    string
+	Zone         string
+	ZoneID       string
+	InstanceType string
+	CapacityType string
+	InstanceID   string
+}
+
+func NewNameTagTemplateData(nodeClaim *karpv1.NodeClaim) NameTagTemplateData {
+	// The instance ID is left empty if the NodeClaim hasn't been launched, in the same way as its node name
+	instanceID, _ := ParseInstanceID(nodeClaim.Status.ProviderID)
+	return NameTagTemplateData{
+		NodeName:     nodeClaim.Status.NodeName,
+		NodeClaim:    nodeClaim.Name,
+		NodePool:     nodeClaim.Labels[karpv1.NodePoolLabelKey],
+		NodeClass:    lo.FromPtr(nodeClaim.Spec.NodeClassRef).Name,
+		Zone:         nodeClaim.Labels[corev1.LabelTopologyZone],
+		ZoneID:       nodeClaim.Labels[v1.LabelTopologyZoneID],
+		InstanceType: nodeClaim.Labels[corev1.LabelInstanceTypeStable],
+		CapacityType: nodeClaim.Labels[karpv1.CapacityTypeLabelKey],
+		InstanceID:   instanceID,
+	}
+}
+
+// RenderNameTag renders a Name tag template. An error is returned if the template references an unknown field, or
+// if the result isn't a valid tag value.
+func RenderNameTag(tmpl string, data NameTagTemplateData) (string, error) {
+	t, err := template.New("name").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing name tag template, %w", err)
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("rendering name tag template, %w", err)
+	}
+	name := strings.TrimSpace(sb.String())
+	if name == "" {
+		return "", fmt.Errorf("rendering name tag template, rendered an empty name")
+	}
+	if len(name) > 256 {
+		return "", fmt.Errorf("rendering name tag template, rendered a name longer than 256 characters")
+	}
+	return name, nil
+}
+
+// UserDataTemplateData is the data that an EC2NodeClass's templated userData is rendered with. Launch templates are
+// shared by the NodeClaims with the same labels, so only the fields which are common to them are included.
+type UserDataTemplateData struct {
+	ClusterName     string
+	ClusterEndpoint string
+	NodeClass       string
+	NodePool        string
+	CapacityType    string
+	Labels          map[string]string
+	// InstanceTypes are the names of the instance types that the launch template may be launched with
+	InstanceTypes []string
+}
+
+// RenderUserData renders a templated userData. An error is returned if the template references an unknown field.
+func RenderUserData(tmpl string, data UserDataTemplateData) (string, error) {
+	t, err := template.New("userData").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing userData template, %w", err)
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("rendering userData template, %w", err)
+	}
+	return sb.String(), nil
+}
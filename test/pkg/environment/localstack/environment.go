@@ -0,0 +1,164 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstack
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/savingsplans"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/env"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing" //nolint:stylecheck
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/reservedinstance"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/zone"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+)
+
+const (
+	// EndpointEnvVar is the environment variable that enables the LocalStack suites. It's set to the edge
+	// endpoint of a running LocalStack (or moto server) instance, e.g. http://localhost:4566.
+	EndpointEnvVar = "LOCALSTACK_ENDPOINT"
+
+	defaultRegion          = "us-east-1"
+	defaultClusterName     = "localstack"
+	defaultClusterEndpoint = "https://localstack.cluster.local"
+	defaultClusterCIDR     = "10.100.0.0/16"
+)
+
+// Environment wires the provider layer of the AWS cloud provider to a LocalStack endpoint. Unlike the
+// e2e environment, it doesn't require a Kubernetes cluster or an AWS account: the providers are
+// exercised directly against the emulated EC2 API.
+type Environment struct {
+	context.Context
+
+	Region          string
+	ClusterName     string
+	ClusterEndpoint string
+
+	EC2API *ec2.Client
+
+	SubnetProvider         *subnet.DefaultProvider
+	SecurityGroupProvider  *securitygroup.DefaultProvider
+	PricingProvider        *pricing.DefaultProvider
+	InstanceTypesProvider  *instancetype.DefaultProvider
+	LaunchTemplateProvider *launchtemplate.DefaultProvider
+}
+
+// NewEnvironment builds the providers against the endpoint in LOCALSTACK_ENDPOINT.
+func NewEnvironment(t *testing.T) *Environment {
+	endpoint := lo.Must(os.LookupEnv(EndpointEnvVar))
+	ctx := TestContextWithLogger(t)
+	clusterName := env.GetString("CLUSTER_NAME", defaultClusterName)
+	clusterEndpoint := env.GetString("CLUSTER_ENDPOINT", defaultClusterEndpoint)
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+		ClusterName:     lo.ToPtr(clusterName),
+		ClusterEndpoint: lo.ToPtr(clusterEndpoint),
+	}))
+
+	cfg := lo.Must(config.LoadDefaultConfig(ctx,
+		config.WithRegion(env.GetString("AWS_REGION", defaultRegion)),
+		config.WithBaseEndpoint(endpoint),
+		// LocalStack doesn't validate credentials, but the SDK refuses to sign requests without them
+		config.WithCredentialsProvider(aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test", Source: "LocalStack"}, nil
+		})),
+	))
+	ec2api := ec2.NewFromConfig(cfg)
+
+	subnetProvider := subnet.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.ExhaustedSubnetsTTL, awscache.UnavailableOfferingsCleanupInterval))
+	securityGroupProvider := securitygroup.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+	// The Price List API isn't emulated, so the pricing provider serves the static prices that ship with the binary
+	pricingProvider := pricing.NewDefaultProvider(ctx, pricing.NewAPI(cfg), ec2api, savingsplans.NewFromConfig(cfg), zone.NewDefaultProvider(ec2api), cfg.Region)
+	capacityReservationProvider := capacityreservation.NewProvider(
+		ec2api,
+		clock.RealClock{},
+		cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval),
+		cache.New(awscache.CapacityReservationAvailabilityTTL, awscache.DefaultCleanupInterval),
+	)
+	instanceTypesProvider := instancetype.NewDefaultProvider(
+		awscache.NewLRU(awscache.InstanceTypesCacheName, awscache.InstanceTypesZonesAndOfferingsTTL, 0, 0, instancetype.EstimateInstanceTypesSize),
+		cache.New(awscache.InstanceTypesZonesAndOfferingsTTL, awscache.DefaultCleanupInterval),
+		cache.New(awscache.DiscoveredCapacityCacheTTL, awscache.DefaultCleanupInterval),
+		ec2api,
+		subnetProvider,
+		pricingProvider,
+		capacityReservationProvider,
+		reservedinstance.NewDefaultProvider(ec2api),
+		awscache.NewUnavailableOfferings(nil),
+		instancetype.NewDefaultResolver(cfg.Region),
+		amifamily.NewHashStore(),
+		nil,
+	)
+	launchTemplateProvider := launchtemplate.NewDefaultProvider(
+		ctx,
+		cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval),
+		ec2api,
+		eks.NewFromConfig(cfg),
+		amifamily.NewDefaultResolver(),
+		securityGroupProvider,
+		subnetProvider,
+		nil,
+		// Launch templates aren't hydrated from the API since there's no leader election in this environment
+		make(chan struct{}),
+		nil,
+		clusterEndpoint,
+		nil,
+		"",
+	)
+	// Cluster CIDR discovery goes through the EKS DescribeCluster API, which requires a real control plane
+	launchTemplateProvider.ClusterCIDR.Store(lo.ToPtr(env.GetString("CLUSTER_CIDR", defaultClusterCIDR)))
+
+	return &Environment{
+		Context:         ctx,
+		Region:          cfg.Region,
+		ClusterName:     clusterName,
+		ClusterEndpoint: clusterEndpoint,
+
+		EC2API: ec2api,
+
+		SubnetProvider:         subnetProvider,
+		SecurityGroupProvider:  securityGroupProvider,
+		PricingProvider:        pricingProvider,
+		InstanceTypesProvider:  instanceTypesProvider,
+		LaunchTemplateProvider: launchTemplateProvider,
+	}
+}
+
+// DiscoveryTags returns the tags that fixtures created by the environment are discoverable by.
+func (env *Environment) DiscoveryTags() map[string]string {
+	return map[string]string{"karpenter.sh/discovery": env.ClusterName}
+}
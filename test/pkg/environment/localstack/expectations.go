@@ -0,0 +1,149 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Network is a VPC with a subnet per zone and a security group, all tagged with the environment's
+// discovery tags.
+type Network struct {
+	VPCID            string
+	SubnetIDs        []string
+	SecurityGroupIDs []string
+}
+
+// ExpectNetwork creates a tagged VPC, subnets in up to three zones and a security group. The resources
+// are deleted when the calling spec completes.
+func (env *Environment) ExpectNetwork() *Network {
+	GinkgoHelper()
+	suffix := coretest.RandomName()
+	zones, err := env.EC2API.DescribeAvailabilityZones(env, &ec2.DescribeAvailabilityZonesInput{})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(zones.AvailabilityZones).ToNot(BeEmpty())
+
+	network := &Network{}
+	vpc, err := env.EC2API.CreateVpc(env, &ec2.CreateVpcInput{
+		CidrBlock:         aws.String("10.0.0.0/16"),
+		TagSpecifications: env.tagSpecifications(ec2types.ResourceTypeVpc, suffix),
+	})
+	Expect(err).ToNot(HaveOccurred())
+	network.VPCID = lo.FromPtr(vpc.Vpc.VpcId)
+	DeferCleanup(func(ctx context.Context) {
+		_, err := env.EC2API.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: aws.String(network.VPCID)})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	for i, zone := range lo.Slice(zones.AvailabilityZones, 0, 3) {
+		subnet, err := env.EC2API.CreateSubnet(env, &ec2.CreateSubnetInput{
+			VpcId:             aws.String(network.VPCID),
+			AvailabilityZone:  zone.ZoneName,
+			CidrBlock:         aws.String(fmt.Sprintf("10.0.%d.0/24", i)),
+			TagSpecifications: env.tagSpecifications(ec2types.ResourceTypeSubnet, suffix),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		network.SubnetIDs = append(network.SubnetIDs, lo.FromPtr(subnet.Subnet.SubnetId))
+	}
+	DeferCleanup(func(ctx context.Context) {
+		for _, id := range network.SubnetIDs {
+			_, err := env.EC2API.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: aws.String(id)})
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	securityGroup, err := env.EC2API.CreateSecurityGroup(env, &ec2.CreateSecurityGroupInput{
+		GroupName:         aws.String(fmt.Sprintf("karpenter-%s", suffix)),
+		Description:       aws.String("Karpenter LocalStack suite"),
+		VpcId:             aws.String(network.VPCID),
+		TagSpecifications: env.tagSpecifications(ec2types.ResourceTypeSecurityGroup, suffix),
+	})
+	Expect(err).ToNot(HaveOccurred())
+	network.SecurityGroupIDs = append(network.SecurityGroupIDs, lo.FromPtr(securityGroup.GroupId))
+	DeferCleanup(func(ctx context.Context) {
+		for _, id := range network.SecurityGroupIDs {
+			_, err := env.EC2API.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: aws.String(id)})
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+	return network
+}
+
+// ExpectImageID returns the ID of an x86_64 image from the images LocalStack registers at startup.
+func (env *Environment) ExpectImageID() string {
+	GinkgoHelper()
+	out, err := env.EC2API.DescribeImages(env, &ec2.DescribeImagesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("architecture"), Values: []string{string(ec2types.ArchitectureValuesX8664)}}},
+	})
+	Expect(err).ToNot(HaveOccurred())
+	Expect(out.Images).ToNot(BeEmpty())
+	return lo.FromPtr(out.Images[0].ImageId)
+}
+
+// ExpectResolvedStatus populates the EC2NodeClass status the way the nodeclass controller would, using
+// the subnet and security group providers. Since AMI discovery depends on SSM parameters that LocalStack
+// doesn't serve, the status AMIs are set to the given image.
+func (env *Environment) ExpectResolvedStatus(nodeClass *v1.EC2NodeClass, imageID string) {
+	GinkgoHelper()
+	subnets, err := env.SubnetProvider.List(env, nodeClass)
+	Expect(err).ToNot(HaveOccurred())
+	nodeClass.Status.Subnets = lo.Map(subnets, func(s ec2types.Subnet, _ int) v1.Subnet {
+		return v1.Subnet{ID: lo.FromPtr(s.SubnetId), Zone: lo.FromPtr(s.AvailabilityZone), ZoneID: lo.FromPtr(s.AvailabilityZoneId)}
+	})
+	securityGroups, err := env.SecurityGroupProvider.List(env, nodeClass)
+	Expect(err).ToNot(HaveOccurred())
+	nodeClass.Status.SecurityGroups = lo.Map(securityGroups, func(sg ec2types.SecurityGroup, _ int) v1.SecurityGroup {
+		return v1.SecurityGroup{ID: lo.FromPtr(sg.GroupId), Name: lo.FromPtr(sg.GroupName)}
+	})
+	nodeClass.Status.AMIs = []v1.AMI{{
+		ID: imageID,
+		Requirements: []corev1.NodeSelectorRequirement{
+			{Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.ArchitectureAmd64}},
+		},
+	}}
+	nodeClass.Status.InstanceProfile = fmt.Sprintf("%s-%s", env.ClusterName, nodeClass.Name)
+}
+
+// ExpectInstanceTypesHydrated refreshes the instance type and offering caches from the emulated API.
+func (env *Environment) ExpectInstanceTypesHydrated() {
+	GinkgoHelper()
+	Expect(env.InstanceTypesProvider.UpdateInstanceTypes(env)).To(Succeed())
+	Expect(env.InstanceTypesProvider.UpdateInstanceTypeOfferings(env)).To(Succeed())
+}
+
+func (env *Environment) tagSpecifications(resourceType ec2types.ResourceType, suffix string) []ec2types.TagSpecification {
+	tags := lo.Assign(env.DiscoveryTags(), map[string]string{"Name": fmt.Sprintf("karpenter-%s", suffix)})
+	return []ec2types.TagSpecification{{
+		ResourceType: resourceType,
+		Tags: lo.MapToSlice(tags, func(k, v string) ec2types.Tag {
+			return ec2types.Tag{Key: aws.String(k), Value: aws.String(v)}
+		}),
+	}}
+}
@@ -0,0 +1,153 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstack_test
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Providers", func() {
+	var nodeClass *v1.EC2NodeClass
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass(v1.EC2NodeClass{
+			Spec: v1.EC2NodeClassSpec{
+				AMISelectorTerms: []v1.AMISelectorTerm{{Alias: "al2023@latest"}},
+				SubnetSelectorTerms: lo.Map(network.SubnetIDs, func(id string, _ int) v1.SubnetSelectorTerm {
+					return v1.SubnetSelectorTerm{ID: id}
+				}),
+				SecurityGroupSelectorTerms: lo.Map(network.SecurityGroupIDs, func(id string, _ int) v1.SecurityGroupSelectorTerm {
+					return v1.SecurityGroupSelectorTerm{ID: id}
+				}),
+			},
+		})
+	})
+	Context("Subnets", func() {
+		It("should discover subnets by ID", func() {
+			subnets, err := env.SubnetProvider.List(env, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(subnets, func(s ec2types.Subnet, _ int) string { return lo.FromPtr(s.SubnetId) })).To(ConsistOf(network.SubnetIDs))
+		})
+		It("should discover subnets by tag", func() {
+			nodeClass.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{Tags: env.DiscoveryTags()}}
+			subnets, err := env.SubnetProvider.List(env, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(subnets, func(s ec2types.Subnet, _ int) string { return lo.FromPtr(s.SubnetId) })).To(ContainElements(network.SubnetIDs))
+		})
+	})
+	Context("Security Groups", func() {
+		It("should discover security groups by ID", func() {
+			securityGroups, err := env.SecurityGroupProvider.List(env, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(securityGroups, func(sg ec2types.SecurityGroup, _ int) string { return lo.FromPtr(sg.GroupId) })).To(ConsistOf(network.SecurityGroupIDs))
+		})
+		It("should discover security groups by tag", func() {
+			nodeClass.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{{Tags: env.DiscoveryTags()}}
+			securityGroups, err := env.SecurityGroupProvider.List(env, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(securityGroups, func(sg ec2types.SecurityGroup, _ int) string { return lo.FromPtr(sg.GroupId) })).To(ContainElements(network.SecurityGroupIDs))
+		})
+	})
+	Context("Instance Types", func() {
+		BeforeEach(func() {
+			env.ExpectResolvedStatus(nodeClass, imageID)
+			env.ExpectInstanceTypesHydrated()
+		})
+		It("should only return offerings in the zones of the resolved subnets", func() {
+			instanceTypes, err := env.InstanceTypesProvider.List(env, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceTypes).ToNot(BeEmpty())
+			zones := sets.New(lo.Map(nodeClass.Status.Subnets, func(s v1.Subnet, _ int) string { return s.Zone })...)
+			for _, it := range instanceTypes {
+				for _, of := range it.Offerings {
+					Expect(zones.Has(of.Zone())).To(BeTrue(), "%s has an offering in %s", it.Name, of.Zone())
+				}
+			}
+		})
+		It("should price on-demand offerings", func() {
+			instanceTypes, err := env.InstanceTypesProvider.List(env, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			priced := lo.Filter(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool {
+				return lo.SomeBy(it.Offerings, func(of *cloudprovider.Offering) bool {
+					return of.CapacityType() == karpv1.CapacityTypeOnDemand && of.Price > 0
+				})
+			})
+			Expect(priced).ToNot(BeEmpty())
+		})
+	})
+	Context("Launch Templates", func() {
+		BeforeEach(func() {
+			env.ExpectResolvedStatus(nodeClass, imageID)
+			env.ExpectInstanceTypesHydrated()
+			DeferCleanup(func(ctx context.Context) {
+				Expect(env.LaunchTemplateProvider.DeleteAll(env, nodeClass)).To(Succeed())
+			})
+		})
+		It("should create launch templates with the resolved image and security groups", func() {
+			instanceTypes, err := env.InstanceTypesProvider.List(env, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			launchTemplates, err := env.LaunchTemplateProvider.EnsureAll(env, nodeClass, coretest.NodeClaim(), instanceTypes, karpv1.CapacityTypeOnDemand, map[string]string{
+				v1.EKSClusterNameTagKey: env.ClusterName,
+				v1.NodeClassTagKey:      nodeClass.Name,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(launchTemplates).ToNot(BeEmpty())
+			for _, lt := range launchTemplates {
+				Expect(lt.ImageID).To(Equal(imageID))
+				out, err := env.EC2API.DescribeLaunchTemplateVersions(env, &ec2.DescribeLaunchTemplateVersionsInput{
+					LaunchTemplateName: aws.String(lt.Name),
+					Versions:           []string{"$Latest"},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(out.LaunchTemplateVersions).To(HaveLen(1))
+				data := out.LaunchTemplateVersions[0].LaunchTemplateData
+				Expect(lo.FromPtr(data.ImageId)).To(Equal(imageID))
+				Expect(data.SecurityGroupIds).To(ConsistOf(network.SecurityGroupIDs))
+				Expect(data.UserData).ToNot(BeNil())
+			}
+		})
+		It("should delete the launch templates for the EC2NodeClass", func() {
+			instanceTypes, err := env.InstanceTypesProvider.List(env, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			launchTemplates, err := env.LaunchTemplateProvider.EnsureAll(env, nodeClass, coretest.NodeClaim(), instanceTypes, karpv1.CapacityTypeOnDemand, map[string]string{
+				v1.EKSClusterNameTagKey: env.ClusterName,
+				v1.NodeClassTagKey:      nodeClass.Name,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(env.LaunchTemplateProvider.DeleteAll(env, nodeClass)).To(Succeed())
+			out, err := env.EC2API.DescribeLaunchTemplates(env, &ec2.DescribeLaunchTemplatesInput{
+				Filters: []ec2types.Filter{{Name: aws.String("launch-template-name"), Values: lo.Map(launchTemplates, func(lt *launchtemplate.LaunchTemplate, _ int) string { return lt.Name })}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(out.LaunchTemplates).To(BeEmpty())
+		})
+	})
+})
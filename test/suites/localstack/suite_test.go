@@ -0,0 +1,45 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localstack_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aws/karpenter-provider-aws/test/pkg/environment/localstack"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var env *localstack.Environment
+var network *localstack.Network
+var imageID string
+
+func TestLocalStack(t *testing.T) {
+	if _, ok := os.LookupEnv(localstack.EndpointEnvVar); !ok {
+		t.Skipf("%s is not set, skipping the LocalStack suite", localstack.EndpointEnvVar)
+	}
+	RegisterFailHandler(Fail)
+	BeforeSuite(func() {
+		env = localstack.NewEnvironment(t)
+	})
+	RunSpecs(t, "LocalStack")
+}
+
+var _ = BeforeEach(func() {
+	network = env.ExpectNetwork()
+	imageID = env.ExpectImageID()
+})